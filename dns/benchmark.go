@@ -0,0 +1,121 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ResolverBenchmark has the metrics computed by [BenchmarkResolver].
+type ResolverBenchmark struct {
+	// Queries is the number of queries sent, one per domain.
+	Queries int
+	// Successes is the number of queries that got a response with RCode success (NOERROR).
+	// Queries that failed, timed out, or got another RCode, such as NXDOMAIN or SERVFAIL, are not successes.
+	Successes int
+	// SuccessRate is Successes / Queries.
+	SuccessRate float64
+	// P50, P90 and P99 are the latency percentiles of the successful queries, using the nearest-rank method.
+	// They are zero if no query succeeded.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// BenchmarkResolver queries the resolver for each of the domains with the given question type, with up to
+// concurrency queries in flight, and reports the success rate and latency percentiles.
+//
+// The latency of a query is the time from the call to [Resolver.Query] until it returns, so it includes the connection
+// setup for resolvers that create a new connection per query. It returns an error if the arguments are invalid or the
+// context is done before all the queries finish.
+func BenchmarkResolver(ctx context.Context, resolver Resolver, domains []string, qtype dnsmessage.Type, concurrency int) (*ResolverBenchmark, error) {
+	if resolver == nil {
+		return nil, errors.New("argument resolver must not be nil")
+	}
+	if concurrency < 1 {
+		return nil, errors.New("argument concurrency must be positive")
+	}
+	questions := make([]dnsmessage.Question, len(domains))
+	for i, domain := range domains {
+		q, err := NewQuestion(domain, qtype)
+		if err != nil {
+			return nil, err
+		}
+		questions[i] = *q
+	}
+
+	var mu sync.Mutex
+	latencies := make([]time.Duration, 0, len(questions))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, q := range questions {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func(q dnsmessage.Question) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				start := time.Now()
+				msg, err := resolver.Query(ctx, q)
+				latency := time.Since(start)
+				if err != nil || msg.RCode != dnsmessage.RCodeSuccess {
+					return
+				}
+				mu.Lock()
+				latencies = append(latencies, latency)
+				mu.Unlock()
+			}(q)
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result := &ResolverBenchmark{
+		Queries:   len(questions),
+		Successes: len(latencies),
+		P50:       percentile(latencies, 50),
+		P90:       percentile(latencies, 90),
+		P99:       percentile(latencies, 99),
+	}
+	if result.Queries > 0 {
+		result.SuccessRate = float64(result.Successes) / float64(result.Queries)
+	}
+	return result, nil
+}
+
+// percentile returns the p-th percentile of the sorted values with the nearest-rank method, or zero if empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestBenchmarkResolver(t *testing.T) {
+	// Domain "N.ok.example." responds after N milliseconds. Domains "fail" and "nx" are failures.
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		label := strings.Split(q.Name.String(), ".")[0]
+		switch label {
+		case "fail":
+			return nil, errors.New("query failed")
+		case "nx":
+			return &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}}, nil
+		}
+		delayMs, err := strconv.Atoi(label)
+		require.NoError(t, err)
+		time.Sleep(time.Duration(delayMs) * time.Millisecond)
+		return &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeSuccess}}, nil
+	})
+	domains := []string{"fail.example", "nx.example"}
+	for i := 1; i <= 100; i++ {
+		domains = append(domains, fmt.Sprintf("%d.ok.example", i))
+	}
+
+	result, err := BenchmarkResolver(context.Background(), resolver, domains, dnsmessage.TypeA, 50)
+	require.NoError(t, err)
+	require.Equal(t, 102, result.Queries)
+	require.Equal(t, 100, result.Successes)
+	require.InDelta(t, 100.0/102.0, result.SuccessRate, 1e-9)
+	// The measured latencies include the scheduling overhead, which depends on the machine load, so we only check
+	// the lower bounds given by the sleeps and the ordering of the percentiles.
+	require.GreaterOrEqual(t, result.P50, 50*time.Millisecond)
+	require.GreaterOrEqual(t, result.P90, 90*time.Millisecond)
+	require.GreaterOrEqual(t, result.P99, 99*time.Millisecond)
+	require.LessOrEqual(t, result.P50, result.P90)
+	require.LessOrEqual(t, result.P90, result.P99)
+}
+
+func TestBenchmarkResolver_NoSuccess(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return nil, errors.New("query failed")
+	})
+	result, err := BenchmarkResolver(context.Background(), resolver, []string{"example.com"}, dnsmessage.TypeA, 1)
+	require.NoError(t, err)
+	require.Equal(t, &ResolverBenchmark{Queries: 1}, result)
+}
+
+func TestBenchmarkResolver_InvalidArgs(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return &dnsmessage.Message{}, nil
+	})
+	_, err := BenchmarkResolver(context.Background(), nil, []string{"example.com"}, dnsmessage.TypeA, 1)
+	require.Error(t, err)
+	_, err = BenchmarkResolver(context.Background(), resolver, []string{"example.com"}, dnsmessage.TypeA, 0)
+	require.Error(t, err)
+	_, err = BenchmarkResolver(context.Background(), resolver, []string{strings.Repeat("a", 300)}, dnsmessage.TypeA, 1)
+	require.Error(t, err)
+}
+
+func TestBenchmarkResolver_Cancelled(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return &dnsmessage.Message{}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := BenchmarkResolver(ctx, resolver, []string{"example.com"}, dnsmessage.TypeA, 1)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPercentile(t *testing.T) {
+	var values []time.Duration
+	for i := 1; i <= 10; i++ {
+		values = append(values, time.Duration(i))
+	}
+	require.Equal(t, time.Duration(5), percentile(values, 50))
+	require.Equal(t, time.Duration(9), percentile(values, 90))
+	require.Equal(t, time.Duration(10), percentile(values, 99))
+	require.Equal(t, time.Duration(1), percentile(values, 0))
+	require.Equal(t, time.Duration(0), percentile(nil, 50))
+}
@@ -0,0 +1,672 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ErrValidationFailed is returned by the [Resolver] created with [NewValidatingResolver] when the
+// response fails DNSSEC validation, which may indicate tampering.
+var ErrValidationFailed = errors.New("DNSSEC validation failed")
+
+var errWildcardExpansion = errors.New("wildcard expansions are not supported without a proof of non-existence")
+
+// DNSSEC resource record types, as per https://datatracker.ietf.org/doc/html/rfc4034.
+// The [dnsmessage] package doesn't define them, and returns their bodies as [dnsmessage.UnknownResource].
+const (
+	TypeDS     dnsmessage.Type = 43
+	TypeRRSIG  dnsmessage.Type = 46
+	TypeDNSKEY dnsmessage.Type = 48
+)
+
+// DSRecord is a Delegation Signer record, which identifies a DNSKEY by its digest, as per
+// https://datatracker.ietf.org/doc/html/rfc4034#section-5. It's used for the trust anchors.
+type DSRecord struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     []byte
+}
+
+// RootTrustAnchors returns the DS records of the root zone key-signing keys, as published by IANA at
+// https://data.iana.org/root-anchors/root-anchors.xml.
+func RootTrustAnchors() []DSRecord {
+	return []DSRecord{
+		// KSK-2017.
+		{KeyTag: 20326, Algorithm: 8, DigestType: 2, Digest: mustDecodeHex("E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D")},
+		// KSK-2024.
+		{KeyTag: 38696, Algorithm: 8, DigestType: 2, Digest: mustDecodeHex("683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16")},
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Maximum number of zones to walk up when validating the chain of trust.
+const maxChainDepth = 16
+
+type dnskey struct {
+	flags     uint16
+	algorithm uint8
+	publicKey []byte
+	// The wire format of the record data, for the DS digest.
+	rdata []byte
+	tag   uint16
+}
+
+// Zone Key flag, as per https://datatracker.ietf.org/doc/html/rfc4034#section-2.1.1.
+const dnskeyFlagZone = 0x0100
+
+type rrsig struct {
+	typeCovered dnsmessage.Type
+	algorithm   uint8
+	labels      uint8
+	originalTTL uint32
+	expiration  uint32
+	inception   uint32
+	keyTag      uint16
+	signerName  string
+	signature   []byte
+	// The record data without the signature, and with the signer name in canonical form.
+	signedFields []byte
+}
+
+type zoneKeys struct {
+	keys   []dnskey
+	expiry time.Time
+}
+
+type validatingResolver struct {
+	base    Resolver
+	anchors []DSRecord
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]zoneKeys
+}
+
+// NewValidatingResolver creates a [Resolver] that validates the DNSSEC signatures of the answers from the base resolver,
+// following the chain of trust from the trust anchors of the root zone, usually [RootTrustAnchors]. It sets the DO bit
+// with [WithDNSSECOK], and queries the DNSKEY and DS records it needs through the base resolver. The validated zone keys
+// are cached.
+//
+// Responses that fail validation, including answers without signatures, return an error that matches
+// [ErrValidationFailed]. That means names in unsigned zones can't be resolved, since proving that a zone is
+// unsigned is not supported. Similarly, the proofs of non-existence (NSEC and NSEC3) are not validated: responses
+// without answers, such as NXDOMAIN, are only accepted if the base resolver set the Authenticated Data (AD) bit, so use
+// a trusted validating resolver over an encrypted transport if you need them. For the same reason, answers expanded from a
+// wildcard fail validation, since they are only secure along with a proof that the queried name doesn't exist.
+// Only the answer section is validated.
+//
+// The supported algorithms are RSA/SHA-256, RSA/SHA-512, ECDSA P-256/SHA-256, ECDSA P-384/SHA-384 and Ed25519.
+func NewValidatingResolver(base Resolver, trustAnchors []DSRecord) Resolver {
+	return &validatingResolver{
+		base:    base,
+		anchors: append([]DSRecord{}, trustAnchors...),
+		now:     time.Now,
+		cache:   make(map[string]zoneKeys),
+	}
+}
+
+// Query implements [Resolver].
+func (r *validatingResolver) Query(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+	ctx = WithDNSSECOK(ctx)
+	response, err := r.base.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.validateAnswers(ctx, response); err != nil {
+		return nil, &nestedError{ErrValidationFailed, err}
+	}
+	return response, nil
+}
+
+type rrsetKey struct {
+	name   string
+	rrType dnsmessage.Type
+}
+
+// splitRRsets groups the records into RRsets, and returns them along with the parsed signatures.
+func splitRRsets(records []dnsmessage.Resource) (map[rrsetKey][]dnsmessage.Resource, []rrsig, error) {
+	rrsets := make(map[rrsetKey][]dnsmessage.Resource)
+	var sigs []rrsig
+	for _, record := range records {
+		if record.Header.Type == TypeRRSIG {
+			sig, err := parseRRSIG(record.Body)
+			if err != nil {
+				return nil, nil, err
+			}
+			sigs = append(sigs, *sig)
+			continue
+		}
+		key := rrsetKey{lowerASCII(record.Header.Name.String()), record.Header.Type}
+		rrsets[key] = append(rrsets[key], record)
+	}
+	return rrsets, sigs, nil
+}
+
+// sigsFor returns the signatures covering the given RRset.
+func sigsFor(sigs []rrsig, key rrsetKey) []rrsig {
+	var matching []rrsig
+	for _, sig := range sigs {
+		if sig.typeCovered == key.rrType && isSubdomain(key.name, sig.signerName) {
+			matching = append(matching, sig)
+		}
+	}
+	return matching
+}
+
+func (r *validatingResolver) validateAnswers(ctx context.Context, response *dnsmessage.Message) error {
+	// The RRSIG owner names are not checked here, since we verify the signatures over the owner name of each RRset.
+	rrsets, sigs, err := splitRRsets(response.Answers)
+	if err != nil {
+		return err
+	}
+	if len(rrsets) == 0 {
+		if response.Header.AuthenticData {
+			return nil
+		}
+		return errors.New("response has no answers, and the resolver did not authenticate it")
+	}
+	for key, records := range rrsets {
+		if err := r.verifyRRset(ctx, key, records, sigsFor(sigs, key), 0); err != nil {
+			return fmt.Errorf("invalid %v RRset for %v: %w", key.rrType, key.name, err)
+		}
+	}
+	return nil
+}
+
+// verifyRRset checks that one of sigs is a valid signature of the RRset, made with a key of the signer zone
+// that is validated up to the trust anchors.
+func (r *validatingResolver) verifyRRset(ctx context.Context, key rrsetKey, records []dnsmessage.Resource, sigs []rrsig, depth int) error {
+	if len(sigs) == 0 {
+		return errors.New("missing signature")
+	}
+	var errs []error
+	for _, sig := range sigs {
+		keys, err := r.zoneKeys(ctx, sig.signerName, depth)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, zoneKey := range keys {
+			if zoneKey.tag != sig.keyTag || zoneKey.algorithm != sig.algorithm {
+				continue
+			}
+			err := verifySignature(zoneKey, sig, key.name, records, r.now())
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return errors.New("no key matches the signatures")
+	}
+	return errors.Join(errs...)
+}
+
+// zoneKeys returns the validated zone keys of the zone.
+func (r *validatingResolver) zoneKeys(ctx context.Context, zone string, depth int) ([]dnskey, error) {
+	if depth > maxChainDepth {
+		return nil, errors.New("chain of trust is too long")
+	}
+	r.mu.Lock()
+	cached, ok := r.cache[zone]
+	r.mu.Unlock()
+	if ok && r.now().Before(cached.expiry) {
+		return cached.keys, nil
+	}
+
+	response, err := r.queryRecords(ctx, zone, TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	rrsets, sigs, err := splitRRsets(response.Answers)
+	if err != nil {
+		return nil, err
+	}
+	key := rrsetKey{zone, TypeDNSKEY}
+	records := rrsets[key]
+	var keys []dnskey
+	for _, record := range records {
+		zoneKey, err := parseDNSKEY(record.Body)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *zoneKey)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no DNSKEY records for zone %v", zone)
+	}
+
+	var trusted []DSRecord
+	if zone == "." {
+		trusted = r.anchors
+	} else {
+		trusted, err = r.delegationSigners(ctx, zone, depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The DNSKEY RRset must be signed by a key that matches a trusted DS record.
+	var errs []error
+	for _, sig := range sigsFor(sigs, key) {
+		if sig.signerName != zone {
+			continue
+		}
+		for _, signingKey := range keys {
+			if signingKey.tag != sig.keyTag || signingKey.algorithm != sig.algorithm || !matchesAnyDS(zone, signingKey, trusted) {
+				continue
+			}
+			if err := verifySignature(signingKey, sig, zone, records, r.now()); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			var zoneKeyList []dnskey
+			for _, k := range keys {
+				if k.flags&dnskeyFlagZone != 0 {
+					zoneKeyList = append(zoneKeyList, k)
+				}
+			}
+			expiry := r.now().Add(time.Duration(sig.originalTTL) * time.Second)
+			if sigExpiry := time.Unix(int64(sig.expiration), 0); sigExpiry.Before(expiry) {
+				expiry = sigExpiry
+			}
+			r.mu.Lock()
+			r.cache[zone] = zoneKeys{keys: zoneKeyList, expiry: expiry}
+			r.mu.Unlock()
+			return zoneKeyList, nil
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no trusted key signs the DNSKEY RRset of zone %v", zone)
+	}
+	return nil, fmt.Errorf("failed to validate DNSKEY RRset of zone %v: %w", zone, errors.Join(errs...))
+}
+
+// delegationSigners returns the validated DS records of the zone, from its parent zone.
+func (r *validatingResolver) delegationSigners(ctx context.Context, zone string, depth int) ([]DSRecord, error) {
+	response, err := r.queryRecords(ctx, zone, TypeDS)
+	if err != nil {
+		return nil, err
+	}
+	rrsets, sigs, err := splitRRsets(response.Answers)
+	if err != nil {
+		return nil, err
+	}
+	key := rrsetKey{zone, TypeDS}
+	records := rrsets[key]
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no DS records for zone %v, which may be unsigned", zone)
+	}
+	if err := r.verifyRRset(ctx, key, records, sigsFor(sigs, key), depth); err != nil {
+		return nil, fmt.Errorf("invalid DS RRset for zone %v: %w", zone, err)
+	}
+	dsRecords := make([]DSRecord, 0, len(records))
+	for _, record := range records {
+		ds, err := parseDS(record.Body)
+		if err != nil {
+			return nil, err
+		}
+		dsRecords = append(dsRecords, *ds)
+	}
+	return dsRecords, nil
+}
+
+func (r *validatingResolver) queryRecords(ctx context.Context, name string, rrType dnsmessage.Type) (*dnsmessage.Message, error) {
+	q, err := NewQuestion(name, rrType)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.base.Query(ctx, *q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %v for %v: %w", rrType, name, err)
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("failed to query %v for %v: %w", rrType, name, &RCodeError{response.RCode})
+	}
+	return response, nil
+}
+
+// matchesAnyDS returns whether the key has the digest of one of the DS records.
+func matchesAnyDS(zone string, key dnskey, dsRecords []DSRecord) bool {
+	for _, ds := range dsRecords {
+		if ds.KeyTag != key.tag || ds.Algorithm != key.algorithm {
+			continue
+		}
+		data := append(canonicalNameWire(zone), key.rdata...)
+		var digest []byte
+		switch ds.DigestType {
+		case 2:
+			sum := sha256.Sum256(data)
+			digest = sum[:]
+		case 4:
+			sum := sha512.Sum384(data)
+			digest = sum[:]
+		default:
+			continue
+		}
+		if bytes.Equal(digest, ds.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature verifies the signature of the RRset of the given owner name, as per
+// https://datatracker.ietf.org/doc/html/rfc4035#section-5.3.
+func verifySignature(key dnskey, sig rrsig, owner string, records []dnsmessage.Resource, now time.Time) error {
+	// Use serial number arithmetic, as per https://datatracker.ietf.org/doc/html/rfc4034#section-3.1.5.
+	nowSerial := uint32(now.Unix())
+	if int32(nowSerial-sig.inception) < 0 {
+		return errors.New("signature is not valid yet")
+	}
+	if int32(sig.expiration-nowSerial) < 0 {
+		return errors.New("signature expired")
+	}
+	data, err := signedData(sig, owner, records)
+	if err != nil {
+		return err
+	}
+	switch sig.algorithm {
+	case 8, 10:
+		pub, err := parseRSAPublicKey(key.publicKey)
+		if err != nil {
+			return err
+		}
+		hash := crypto.SHA256
+		if sig.algorithm == 10 {
+			hash = crypto.SHA512
+		}
+		h := hash.New()
+		h.Write(data)
+		return rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig.signature)
+	case 13, 14:
+		curve, hash := elliptic.P256(), crypto.SHA256
+		if sig.algorithm == 14 {
+			curve, hash = elliptic.P384(), crypto.SHA384
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(key.publicKey) != 2*size || len(sig.signature) != 2*size {
+			return errors.New("invalid ECDSA key or signature size")
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(key.publicKey[:size]),
+			Y:     new(big.Int).SetBytes(key.publicKey[size:]),
+		}
+		h := hash.New()
+		h.Write(data)
+		r := new(big.Int).SetBytes(sig.signature[:size])
+		s := new(big.Int).SetBytes(sig.signature[size:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	case 15:
+		if len(key.publicKey) != ed25519.PublicKeySize {
+			return errors.New("invalid Ed25519 key size")
+		}
+		if !ed25519.Verify(ed25519.PublicKey(key.publicKey), data, sig.signature) {
+			return errors.New("invalid Ed25519 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %v", sig.algorithm)
+	}
+}
+
+// signedData returns the data covered by the signature, as per https://datatracker.ietf.org/doc/html/rfc4034#section-3.1.8.1.
+func signedData(sig rrsig, owner string, records []dnsmessage.Resource) ([]byte, error) {
+	labels := nameLabels(owner)
+	switch {
+	case int(sig.labels) == len(labels):
+	case int(sig.labels) == len(labels)-1 && labels[0] == "*":
+		// The owner is the wildcard name itself, and the "*" label is not counted.
+	case int(sig.labels) < len(labels):
+		// The records were expanded from a wildcard, as per https://datatracker.ietf.org/doc/html/rfc4035#section-5.3.4.
+		// That's only secure with a proof that the queried name doesn't exist, which we don't validate.
+		return nil, errWildcardExpansion
+	default:
+		return nil, errors.New("signature has more labels than the owner name")
+	}
+	ownerWire := labelsWire(labels)
+
+	rdatas := make([][]byte, 0, len(records))
+	for _, record := range records {
+		rdata, err := canonicalRData(record.Body)
+		if err != nil {
+			return nil, err
+		}
+		rdatas = append(rdatas, rdata)
+	}
+	// Sort and remove duplicates, as per https://datatracker.ietf.org/doc/html/rfc4034#section-6.3.
+	sort.Slice(rdatas, func(i, j int) bool { return bytes.Compare(rdatas[i], rdatas[j]) < 0 })
+
+	data := append([]byte{}, sig.signedFields...)
+	for i, rdata := range rdatas {
+		if i > 0 && bytes.Equal(rdata, rdatas[i-1]) {
+			continue
+		}
+		data = append(data, ownerWire...)
+		data = binary.BigEndian.AppendUint16(data, uint16(records[0].Header.Type))
+		data = binary.BigEndian.AppendUint16(data, uint16(records[0].Header.Class))
+		data = binary.BigEndian.AppendUint32(data, sig.originalTTL)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(rdata)))
+		data = append(data, rdata...)
+	}
+	return data, nil
+}
+
+// canonicalRData returns the canonical wire format of the record data, as per
+// https://datatracker.ietf.org/doc/html/rfc4034#section-6.2.
+func canonicalRData(body dnsmessage.ResourceBody) ([]byte, error) {
+	switch rr := body.(type) {
+	case *dnsmessage.AResource:
+		return rr.A[:], nil
+	case *dnsmessage.AAAAResource:
+		return rr.AAAA[:], nil
+	case *dnsmessage.CNAMEResource:
+		return canonicalNameWire(rr.CNAME.String()), nil
+	case *dnsmessage.NSResource:
+		return canonicalNameWire(rr.NS.String()), nil
+	case *dnsmessage.PTRResource:
+		return canonicalNameWire(rr.PTR.String()), nil
+	case *dnsmessage.MXResource:
+		return append(binary.BigEndian.AppendUint16(nil, rr.Pref), canonicalNameWire(rr.MX.String())...), nil
+	case *dnsmessage.SRVResource:
+		data := binary.BigEndian.AppendUint16(nil, rr.Priority)
+		data = binary.BigEndian.AppendUint16(data, rr.Weight)
+		data = binary.BigEndian.AppendUint16(data, rr.Port)
+		return append(data, canonicalNameWire(rr.Target.String())...), nil
+	case *dnsmessage.SOAResource:
+		data := append(canonicalNameWire(rr.NS.String()), canonicalNameWire(rr.MBox.String())...)
+		for _, v := range []uint32{rr.Serial, rr.Refresh, rr.Retry, rr.Expire, rr.MinTTL} {
+			data = binary.BigEndian.AppendUint32(data, v)
+		}
+		return data, nil
+	case *dnsmessage.TXTResource:
+		var data []byte
+		for _, txt := range rr.TXT {
+			data = append(append(data, byte(len(txt))), txt...)
+		}
+		return data, nil
+	case *dnsmessage.UnknownResource:
+		return rr.Data, nil
+	default:
+		return nil, fmt.Errorf("unsupported record type %T", body)
+	}
+}
+
+func parseRRSIG(body dnsmessage.ResourceBody) (*rrsig, error) {
+	unknown, ok := body.(*dnsmessage.UnknownResource)
+	if !ok || unknown.Type != TypeRRSIG {
+		return nil, fmt.Errorf("resource body is not RRSIG: %T", body)
+	}
+	data := unknown.Data
+	if len(data) < 18 {
+		return nil, errors.New("RRSIG record is too short")
+	}
+	signer, n, err := parseUncompressedName(data[18:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid RRSIG signer name: %w", err)
+	}
+	signer = lowerASCII(signer)
+	sig := &rrsig{
+		typeCovered: dnsmessage.Type(binary.BigEndian.Uint16(data)),
+		algorithm:   data[2],
+		labels:      data[3],
+		originalTTL: binary.BigEndian.Uint32(data[4:]),
+		expiration:  binary.BigEndian.Uint32(data[8:]),
+		inception:   binary.BigEndian.Uint32(data[12:]),
+		keyTag:      binary.BigEndian.Uint16(data[16:]),
+		signerName:  signer,
+		signature:   data[18+n:],
+	}
+	sig.signedFields = append(append([]byte{}, data[:18]...), canonicalNameWire(signer)...)
+	return sig, nil
+}
+
+func parseDNSKEY(body dnsmessage.ResourceBody) (*dnskey, error) {
+	unknown, ok := body.(*dnsmessage.UnknownResource)
+	if !ok || unknown.Type != TypeDNSKEY {
+		return nil, fmt.Errorf("resource body is not DNSKEY: %T", body)
+	}
+	data := unknown.Data
+	if len(data) < 4 {
+		return nil, errors.New("DNSKEY record is too short")
+	}
+	return &dnskey{
+		flags:     binary.BigEndian.Uint16(data),
+		algorithm: data[3],
+		publicKey: data[4:],
+		rdata:     data,
+		tag:       keyTag(data),
+	}, nil
+}
+
+func parseDS(body dnsmessage.ResourceBody) (*DSRecord, error) {
+	unknown, ok := body.(*dnsmessage.UnknownResource)
+	if !ok || unknown.Type != TypeDS {
+		return nil, fmt.Errorf("resource body is not DS: %T", body)
+	}
+	data := unknown.Data
+	if len(data) < 4 {
+		return nil, errors.New("DS record is too short")
+	}
+	return &DSRecord{
+		KeyTag:     binary.BigEndian.Uint16(data),
+		Algorithm:  data[2],
+		DigestType: data[3],
+		Digest:     data[4:],
+	}, nil
+}
+
+// keyTag computes the key tag of the DNSKEY record data, as per https://datatracker.ietf.org/doc/html/rfc4034#appendix-B.
+func keyTag(rdata []byte) uint16 {
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac & 0xFFFF)
+}
+
+// parseRSAPublicKey parses an RSA public key in the format of https://datatracker.ietf.org/doc/html/rfc3110#section-2.
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	if len(data) < 1 {
+		return nil, errors.New("RSA key is too short")
+	}
+	expLen, offset := int(data[0]), 1
+	if expLen == 0 {
+		if len(data) < 3 {
+			return nil, errors.New("RSA key is too short")
+		}
+		expLen, offset = int(binary.BigEndian.Uint16(data[1:])), 3
+	}
+	if expLen == 0 || expLen > 4 || len(data) <= offset+expLen {
+		return nil, errors.New("invalid RSA key exponent")
+	}
+	exponent := new(big.Int).SetBytes(data[offset : offset+expLen])
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(data[offset+expLen:]), E: int(exponent.Int64())}, nil
+}
+
+// isSubdomain returns whether name is equal to or a subdomain of zone. Both must be lower-case and fully-qualified.
+func isSubdomain(name, zone string) bool {
+	return zone == "." || name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// nameLabels returns the labels of the fully-qualified name, in lower case.
+func nameLabels(name string) []string {
+	name = strings.TrimSuffix(lowerASCII(name), ".")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, ".")
+}
+
+func labelsWire(labels []string) []byte {
+	var data []byte
+	for _, label := range labels {
+		data = append(append(data, byte(len(label))), label...)
+	}
+	return append(data, 0)
+}
+
+// canonicalNameWire returns the uncompressed wire format of the name in lower case.
+func canonicalNameWire(name string) []byte {
+	return labelsWire(nameLabels(name))
+}
+
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
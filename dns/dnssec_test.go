@@ -0,0 +1,355 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testZone signs records with an Ed25519 key-signing key.
+type testZone struct {
+	t       *testing.T
+	name    string
+	private ed25519.PrivateKey
+	dnskey  []byte
+}
+
+func newTestZone(t *testing.T, name string) *testZone {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	// Flags: Zone Key and Secure Entry Point. Protocol 3. Algorithm 15.
+	dnskey := append([]byte{0x01, 0x01, 3, 15}, public...)
+	return &testZone{t: t, name: name, private: private, dnskey: dnskey}
+}
+
+func (z *testZone) ds() DSRecord {
+	digest := sha256.Sum256(append(canonicalNameWire(z.name), z.dnskey...))
+	return DSRecord{KeyTag: keyTag(z.dnskey), Algorithm: 15, DigestType: 2, Digest: digest[:]}
+}
+
+func (z *testZone) dsResource() dnsmessage.Resource {
+	ds := z.ds()
+	data := binary.BigEndian.AppendUint16(nil, ds.KeyTag)
+	data = append(append(data, ds.Algorithm, ds.DigestType), ds.Digest...)
+	return makeUnknownResource(z.name, TypeDS, data)
+}
+
+func (z *testZone) dnskeyResource() dnsmessage.Resource {
+	return makeUnknownResource(z.name, TypeDNSKEY, z.dnskey)
+}
+
+// sign returns the RRSIG for the RRset made by the records.
+func (z *testZone) sign(records ...dnsmessage.Resource) dnsmessage.Resource {
+	owner := records[0].Header.Name.String()
+	now := uint32(time.Now().Unix())
+	data := binary.BigEndian.AppendUint16(nil, uint16(records[0].Header.Type))
+	labels := nameLabels(owner)
+	if len(labels) > 0 && labels[0] == "*" {
+		// The wildcard label is not counted.
+		labels = labels[1:]
+	}
+	data = append(data, 15, byte(len(labels)))
+	data = binary.BigEndian.AppendUint32(data, 300)
+	data = binary.BigEndian.AppendUint32(data, now+3600)
+	data = binary.BigEndian.AppendUint32(data, now-3600)
+	data = binary.BigEndian.AppendUint16(data, keyTag(z.dnskey))
+	data = append(data, canonicalNameWire(z.name)...)
+	sig, err := parseRRSIG(&dnsmessage.UnknownResource{Type: TypeRRSIG, Data: data})
+	require.NoError(z.t, err)
+	signed, err := signedData(*sig, owner, records)
+	require.NoError(z.t, err)
+	return makeUnknownResource(owner, TypeRRSIG, append(data, ed25519.Sign(z.private, signed)...))
+}
+
+func makeUnknownResource(name string, rrType dnsmessage.Type, data []byte) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: rrType, Class: dnsmessage.ClassINET, TTL: 300},
+		Body:   &dnsmessage.UnknownResource{Type: rrType, Data: data},
+	}
+}
+
+func makeAResource(name string, ip [4]byte) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+		Body:   &dnsmessage.AResource{A: ip},
+	}
+}
+
+// newSignedResolver returns a resolver for a signed root and "example." zone, and the root trust anchors.
+func newSignedResolver(t *testing.T) (Resolver, []DSRecord, *int) {
+	root := newTestZone(t, ".")
+	example := newTestZone(t, "example.")
+	www := makeAResource("www.example.", [4]byte{192, 0, 2, 1})
+	tampered := makeAResource("tampered.example.", [4]byte{192, 0, 2, 2})
+	wildcard := makeAResource("*.example.", [4]byte{192, 0, 2, 5})
+	wildcardSig := example.sign(wildcard)
+	records := map[string][]dnsmessage.Resource{
+		".":                 {root.dnskeyResource(), root.sign(root.dnskeyResource())},
+		"example.":          {example.dnskeyResource(), example.sign(example.dnskeyResource())},
+		"example./DS":       {example.dsResource(), root.sign(example.dsResource())},
+		"www.example.":      {www, example.sign(www)},
+		"tampered.example.": {makeAResource("tampered.example.", [4]byte{192, 0, 2, 66}), example.sign(tampered)},
+		"unsigned.example.": {makeAResource("unsigned.example.", [4]byte{192, 0, 2, 3})},
+		"rogue.example.":    {makeAResource("rogue.example.", [4]byte{192, 0, 2, 4}), newTestZone(t, "example.").sign(makeAResource("rogue.example.", [4]byte{192, 0, 2, 4}))},
+		"*.example.":        {wildcard, wildcardSig},
+		// The answer expanded from the wildcard, with the wildcard signature, but without a proof of non-existence.
+		"expanded.example.": {makeAResource("expanded.example.", [4]byte{192, 0, 2, 5}), wildcardSig},
+	}
+	queries := 0
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		require.True(t, DNSSECOK(ctx))
+		queries++
+		key := q.Name.String()
+		if q.Type == TypeDS {
+			key += "/DS"
+		}
+		answers, ok := records[key]
+		if !ok {
+			return &dnsmessage.Message{Header: dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeNameError}, Questions: []dnsmessage.Question{q}}, nil
+		}
+		return &dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Questions: []dnsmessage.Question{q}, Answers: answers}, nil
+	})
+	return resolver, []DSRecord{root.ds()}, &queries
+}
+
+func TestValidatingResolver_Valid(t *testing.T) {
+	base, anchors, queries := newSignedResolver(t)
+	resolver := NewValidatingResolver(base, anchors)
+	q, err := NewQuestion("www.example.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	response, err := resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+	require.Len(t, response.Answers, 2)
+	require.Equal(t, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}, response.Answers[0].Body)
+	// Answer, DNSKEY "example.", DS "example.", DNSKEY ".".
+	require.Equal(t, 4, *queries)
+
+	// The zone keys are cached.
+	_, err = resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+	require.Equal(t, 5, *queries)
+}
+
+func TestValidatingResolver_Tampered(t *testing.T) {
+	base, anchors, _ := newSignedResolver(t)
+	resolver := NewValidatingResolver(base, anchors)
+	for _, name := range []string{"tampered.example.", "unsigned.example.", "rogue.example."} {
+		t.Run(name, func(t *testing.T) {
+			q, err := NewQuestion(name, dnsmessage.TypeA)
+			require.NoError(t, err)
+			_, err = resolver.Query(context.Background(), *q)
+			require.ErrorIs(t, err, ErrValidationFailed)
+		})
+	}
+}
+
+func TestValidatingResolver_Wildcard(t *testing.T) {
+	base, anchors, _ := newSignedResolver(t)
+	resolver := NewValidatingResolver(base, anchors)
+
+	// The wildcard record itself is valid.
+	q, err := NewQuestion("*.example.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	_, err = resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+
+	// Expanded answers need a proof of non-existence, which is not supported.
+	q, err = NewQuestion("expanded.example.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	_, err = resolver.Query(context.Background(), *q)
+	require.ErrorIs(t, err, ErrValidationFailed)
+	require.ErrorIs(t, err, errWildcardExpansion)
+}
+
+func TestValidatingResolver_WrongTrustAnchor(t *testing.T) {
+	base, _, _ := newSignedResolver(t)
+	resolver := NewValidatingResolver(base, []DSRecord{newTestZone(t, ".").ds()})
+	q, err := NewQuestion("www.example.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	_, err = resolver.Query(context.Background(), *q)
+	require.ErrorIs(t, err, ErrValidationFailed)
+}
+
+func TestValidatingResolver_NoAnswers(t *testing.T) {
+	q, err := NewQuestion("missing.example.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	for _, authenticated := range []bool{false, true} {
+		base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+			return &dnsmessage.Message{Header: dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeNameError, AuthenticData: authenticated}}, nil
+		})
+		_, err := NewValidatingResolver(base, RootTrustAnchors()).Query(context.Background(), *q)
+		if authenticated {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, ErrValidationFailed)
+		}
+	}
+}
+
+func TestKeyTag(t *testing.T) {
+	// The root KSK-2017, with tag 20326.
+	rdata := append([]byte{0x01, 0x01, 3, 8}, mustDecodeBase64(t, "AwEAAaz/tAm8yTn4Mfeh5eyI96WSVexTBAvkMgJzkKTOiW1vkIbzxeF3+/4RgWOq7HrxRixHlFlExOLAJr5emLvN7SWXgnLh4+B5xQlNVz8Og8kvArMtNROxVQuCaSnIDdD5LKyWbRd2n9WGe2R8PzgCmr3EgVLrjyBxWezF0jLHwVN8efS3rCj/EWgvIWgb9tarpVUDK/b58Da+sqqls3eNbuv7pr+eoZG+SrDK6nWeL3c6H5Apxz7LjVc1uTIdsIXxuOLYA4/ilBmSVIzuDWfdRUfhHdY6+cn8HFRm+2hM8AnXGXws9555KrUB5qihylGa8subX2Nn6UwNR1AkUTV74bU=")...)
+	require.Equal(t, uint16(20326), keyTag(rdata))
+	require.True(t, matchesAnyDS(".", dnskey{algorithm: 8, rdata: rdata, tag: keyTag(rdata)}, RootTrustAnchors()))
+}
+
+// Signed RRsets for "www.example.net. 3600 IN A" from the examples in
+// https://datatracker.ietf.org/doc/html/rfc5702#section-6 (RSA) and https://datatracker.ietf.org/doc/html/rfc6605#section-6 (ECDSA).
+var rfcSignatureVectors = []struct {
+	name       string
+	algorithm  uint8
+	dnskey     string
+	flags      uint16
+	keyTag     uint16
+	ip         [4]byte
+	expiration string
+	inception  string
+	signature  string
+	// The DS digest of the key, if the RFC has it.
+	dsDigestType uint8
+	dsDigest     string
+	// Whether the RSA key is shorter than the 1024 bits that crypto/rsa requires since Go 1.24.
+	shortRSAKey bool
+}{
+	{
+		name:        "RSASHA256",
+		algorithm:   8,
+		flags:       256,
+		dnskey:      "AwEAAcFcGsaxxdgiuuGmCkVImy4h99CqT7jwY3pexPGcnUFtR2Fh36BponcwtkZ4cAgtvd4Qs8PkxUdp6p/DlUmObdk=",
+		keyTag:      9033,
+		ip:          [4]byte{192, 0, 2, 91},
+		expiration:  "20300101000000",
+		inception:   "20000101000000",
+		signature:   "kRCOH6u7l0QGy9qpC9l1sLncJcOKFLJ7GhiUOibu4teYp5VE9RncriShZNz85mwlMgNEacFYK/lPtPiVYP4bwg==",
+		shortRSAKey: true,
+	},
+	{
+		name:       "RSASHA512",
+		algorithm:  10,
+		flags:      256,
+		dnskey:     "AwEAAdHoNTOW+et86KuJOWRDp1pndvwb6Y83nSVXXyLA3DLroROUkN6X0O6pnWnjJQujX/AyhqFDxj13tOnD9u/1kTg7cV6rklMrZDtJCQ5PCl/D7QNPsgVsMu1J2Q8gpMpztNFLpPBz1bWXjDtaR7ZQBlZ3PFY12ZTSncorffcGmhOL",
+		keyTag:     3740,
+		ip:         [4]byte{192, 0, 2, 91},
+		expiration: "20300101000000",
+		inception:  "20000101000000",
+		signature:  "tsb4wnjRUDnB1BUi+t6TMTXThjVnG+eCkWqjvvjhzQL1d0YRoOe0CbxrVDYd0xDtsuJRaeUw1ep94PzEWzr0iGYgZBWm/zpq+9fOuagYJRfDqfReKBzMweOLDiNa8iP5g9vMhpuv6OPlvpXwm9Sa9ZXIbNl1MBGk0fthPgxdDLw=",
+	},
+	{
+		name:         "ECDSAP256SHA256",
+		algorithm:    13,
+		flags:        257,
+		dnskey:       "GojIhhXUN/u4v54ZQqGSnyhWJwaubCvTmeexv7bR6edbkrSqQpF64cYbcB7wNcP+e+MAnLr+Wi9xMWyQLc8NAA==",
+		keyTag:       55648,
+		ip:           [4]byte{192, 0, 2, 1},
+		expiration:   "20100909100439",
+		inception:    "20100812100439",
+		signature:    "qx6wLYqmh+l9oCKTN6qIc+bw6ya+KJ8oMz0YP107epXAyGmt+3SNruPFKG7tZoLBLlUzGGus7ZwmwWep666VCw==",
+		dsDigestType: 2,
+		dsDigest:     "b4c8c1fe2e7477127b27115656ad6256f424625bf5c1e2770ce6d6e37df61d17",
+	},
+	{
+		name:         "ECDSAP384SHA384",
+		algorithm:    14,
+		flags:        257,
+		dnskey:       "xKYaNhWdGOfJ+nPrL8/arkwf2EY3MDJ+SErKivBVSum1w/egsXvSADtNJhyem5RCOpgQ6K8X1DRSEkrbYQ+OB+v8/uX45NBwY8rp65F6Glur8I/mlVNgF6W/qTI37m40",
+		keyTag:       10771,
+		ip:           [4]byte{192, 0, 2, 1},
+		expiration:   "20100909102025",
+		inception:    "20100812102025",
+		signature:    "/L5hDKIvGDyI1fcARX3z65qrmPsVz73QD1Mr5CEqOiLP95hxQouuroGCeZOvzFaxsT8Glr74hbavRKayJNuydCuzWTSSPdz7wnqXL5bdcJzusdnI0RSMROxxwGipWcJm",
+		dsDigestType: 4,
+		dsDigest:     "72d7b62976ce06438e9c0bf319013cf801f09ecc84b8d7e9495f27e305c6a9b0563a9b5f4d288405c3008a946df983d6",
+	},
+}
+
+func TestVerifySignature_RFCVectors(t *testing.T) {
+	parseTime := func(s string) uint32 {
+		ts, err := time.Parse("20060102150405", s)
+		require.NoError(t, err)
+		return uint32(ts.Unix())
+	}
+	for _, tc := range rfcSignatureVectors {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.shortRSAKey {
+				godebug := "rsa1024min=0"
+				if current := os.Getenv("GODEBUG"); current != "" {
+					godebug = current + "," + godebug
+				}
+				t.Setenv("GODEBUG", godebug)
+			}
+			rdata := binary.BigEndian.AppendUint16(nil, tc.flags)
+			rdata = append(append(rdata, 3, tc.algorithm), mustDecodeBase64(t, tc.dnskey)...)
+			key, err := parseDNSKEY(&dnsmessage.UnknownResource{Type: TypeDNSKEY, Data: rdata})
+			require.NoError(t, err)
+			require.Equal(t, tc.keyTag, key.tag)
+			if tc.dsDigest != "" {
+				digest, err := hex.DecodeString(tc.dsDigest)
+				require.NoError(t, err)
+				ds := DSRecord{KeyTag: tc.keyTag, Algorithm: tc.algorithm, DigestType: tc.dsDigestType, Digest: digest}
+				require.True(t, matchesAnyDS("example.net.", *key, []DSRecord{ds}))
+			}
+
+			data := binary.BigEndian.AppendUint16(nil, uint16(dnsmessage.TypeA))
+			data = append(data, tc.algorithm, 3)
+			data = binary.BigEndian.AppendUint32(data, 3600)
+			data = binary.BigEndian.AppendUint32(data, parseTime(tc.expiration))
+			data = binary.BigEndian.AppendUint32(data, parseTime(tc.inception))
+			data = binary.BigEndian.AppendUint16(data, tc.keyTag)
+			data = append(data, canonicalNameWire("example.net.")...)
+			sig, err := parseRRSIG(&dnsmessage.UnknownResource{Type: TypeRRSIG, Data: append(data, mustDecodeBase64(t, tc.signature)...)})
+			require.NoError(t, err)
+
+			now := time.Unix(int64(parseTime(tc.inception)), 0).Add(time.Hour)
+			record := makeAResource("WWW.Example.NET.", tc.ip)
+			record.Header.TTL = 3600
+			require.NoError(t, verifySignature(*key, *sig, "WWW.Example.NET.", []dnsmessage.Resource{record}, now))
+
+			tampered := makeAResource("www.example.net.", [4]byte{192, 0, 2, 2})
+			require.Error(t, verifySignature(*key, *sig, "www.example.net.", []dnsmessage.Resource{tampered}, now))
+			require.ErrorContains(t, verifySignature(*key, *sig, "www.example.net.", []dnsmessage.Resource{record}, time.Unix(int64(parseTime(tc.expiration)), 0).Add(time.Hour)), "expired")
+		})
+	}
+}
+
+func mustDecodeBase64(t *testing.T, s string) []byte {
+	b, err := base64.StdEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestAppendRequest_DNSSECOK(t *testing.T) {
+	q, err := NewQuestion("example.com.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	for _, dnssecOK := range []bool{false, true} {
+		buf, err := appendRequest(0, *q, dnssecOK, nil)
+		require.NoError(t, err)
+		var request dnsmessage.Message
+		require.NoError(t, request.Unpack(buf))
+		require.Len(t, request.Additionals, 1)
+		require.Equal(t, dnssecOK, request.Additionals[0].Header.DNSSECAllowed())
+	}
+}
@@ -38,6 +38,36 @@ Typically you will want to use custom DNS resolution to establish connections to
 and the given dialer to establish connections. The dialer efficiently performs resolutions and connection attempts
 in parallel, as per the [Happy Eyeballs v2] algorithm.
 
+# Looking Up Addresses
+
+If you just need the IP addresses of a host, [LookupIP] queries the A and AAAA records in parallel, follows the
+CNAMEs, and returns the addresses in the order recommended by Happy Eyeballs. Errors from the resolver response
+code can be checked with [ErrNXDomain] and [ErrServFail].
+
+# Service Binding Records
+
+The [golang.org/x/net/dns/dnsmessage] package returns SVCB and HTTPS records as opaque bodies. [QuerySVCB] queries them and returns them
+parsed as [SVCBRecord], with the ALPN, port, IP hints and ECH config. You can also use [ParseSVCBResource] to parse
+the answers of your own queries for [TypeSVCB] or [TypeHTTPS].
+
+# DNSSEC Validation
+
+Encrypted transports protect the queries from the network, but you still have to trust the resolver. [NewValidatingResolver]
+wraps a resolver to validate the [DNSSEC] signatures of the answers up to the trust anchors, usually [RootTrustAnchors],
+so that tampered answers fail with [ErrValidationFailed]. To get the signatures from your own queries, use a context
+created with [WithDNSSECOK].
+
+# Bulk Lookups
+
+When many goroutines resolve the same names, [NewSingleflightResolver] deduplicates the concurrent identical queries,
+so only one of them is sent and all the callers share its result. [NewRateLimitedResolver] limits the queries per second
+sent to a resolver, to reduce its load and avoid triggering rate-based blocking during bulk scans.
+
+# Benchmarking Resolvers
+
+[BenchmarkResolver] queries a resolver for a list of domains and reports the success rate and the latency
+percentiles, so you can compare resolvers on a given network.
+
 [Domain Name System]: https://datatracker.ietf.org/doc/html/rfc1034
 [commonly used for network-level filtering]: https://datatracker.ietf.org/doc/html/rfc9505#section-5.1.1
 [DNS-over-UDP]: https://datatracker.ietf.org/doc/html/rfc1035#section-4.2.1
@@ -45,5 +75,6 @@ in parallel, as per the [Happy Eyeballs v2] algorithm.
 [DNS-over-TLS]: https://datatracker.ietf.org/doc/html/rfc7858
 [DNS-over-HTTPS]: https://datatracker.ietf.org/doc/html/rfc8484
 [Happy Eyeballs v2]: https://datatracker.ietf.org/doc/html/rfc8305
+[DNSSEC]: https://datatracker.ietf.org/doc/html/rfc4033
 */
 package dns
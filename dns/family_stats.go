@@ -0,0 +1,169 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// FamilyStats are aggregate counters of the IP family used by the Happy Eyeballs connections of a
+// [transport.StreamDialer] created with [NewStreamDialer]. They help quantify how broken IPv6 is on a network.
+//
+// A connection attempt is considered failed only if it failed before another attempt won, and not because it was
+// cancelled. Attempts that were still in progress when another attempt won are considered to have lost the race.
+type FamilyStats struct {
+	// IPv6Wins is the number of dials that established the connection over IPv6.
+	IPv6Wins uint64
+	// IPv4Wins is the number of dials that established the connection over IPv4.
+	IPv4Wins uint64
+	// IPv4FallbacksAfterIPv6Failure is the number of IPv4 wins where all the IPv6 attempts failed.
+	// This is the fallback that indicates broken IPv6.
+	IPv4FallbacksAfterIPv6Failure uint64
+	// IPv4WinsOverSlowIPv6 is the number of IPv4 wins where some IPv6 attempt had not failed, but lost the race.
+	IPv4WinsOverSlowIPv6 uint64
+	// IPv6FallbacksAfterIPv4Failure is the number of IPv6 wins where all the IPv4 attempts failed.
+	IPv6FallbacksAfterIPv4Failure uint64
+	// IPv6WinsOverSlowIPv4 is the number of IPv6 wins where some IPv4 attempt had not failed, but lost the race.
+	IPv6WinsOverSlowIPv4 uint64
+	// Failures is the number of dials that failed to establish a connection.
+	Failures uint64
+}
+
+// WithFamilyStats makes the dialer call callback after every dial with the updated aggregate [FamilyStats].
+// The callback is called synchronously, before DialStream returns, so it should be fast.
+func WithFamilyStats(callback func(FamilyStats)) StreamDialerOption {
+	return func(config *streamDialerConfig) {
+		config.onFamilyStats = callback
+	}
+}
+
+// familyAttempts tracks the connection attempts of a single dial.
+type familyAttempts struct {
+	mu sync.Mutex
+	// done is set when the dial finishes, after which attempts are no longer recorded.
+	done bool
+	// winner is the address of the first successful attempt.
+	winner                   netip.Addr
+	ip6Attempts, ip6Failures int
+	ip4Attempts, ip4Failures int
+}
+
+func (a *familyAttempts) start(ip netip.Addr) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.done {
+		return
+	}
+	if ip.Is4() || ip.Is4In6() {
+		a.ip4Attempts++
+	} else {
+		a.ip6Attempts++
+	}
+}
+
+func (a *familyAttempts) finish(ip netip.Addr, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.done {
+		return
+	}
+	if err == nil {
+		if !a.winner.IsValid() {
+			a.winner = ip
+		}
+		return
+	}
+	// Attempts cancelled because another attempt won lost the race, even if they finish before the dial does.
+	if a.winner.IsValid() || errors.Is(err, context.Canceled) {
+		return
+	}
+	if ip.Is4() || ip.Is4In6() {
+		a.ip4Failures++
+	} else {
+		a.ip6Failures++
+	}
+}
+
+type familyAttemptsKey struct{}
+
+// familyStatsDialer is a [transport.StreamDialer] that computes [FamilyStats] for a Happy Eyeballs dialer.
+type familyStatsDialer struct {
+	dialer   transport.StreamDialer
+	callback func(FamilyStats)
+	mu       sync.Mutex
+	stats    FamilyStats
+}
+
+// newFamilyStatsDialer wraps heDialer and its inner dialer to track the attempts of each dial.
+func newFamilyStatsDialer(heDialer *transport.HappyEyeballsStreamDialer, callback func(FamilyStats)) transport.StreamDialer {
+	innerDialer := heDialer.Dialer
+	heDialer.Dialer = transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		attempts, _ := ctx.Value(familyAttemptsKey{}).(*familyAttempts)
+		addrPort, parseErr := netip.ParseAddrPort(addr)
+		if attempts == nil || parseErr != nil {
+			return innerDialer.DialStream(ctx, addr)
+		}
+		attempts.start(addrPort.Addr())
+		conn, err := innerDialer.DialStream(ctx, addr)
+		attempts.finish(addrPort.Addr(), err)
+		return conn, err
+	})
+	return &familyStatsDialer{dialer: heDialer, callback: callback}
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *familyStatsDialer) DialStream(ctx context.Context, addr string) (transport.StreamConn, error) {
+	attempts := &familyAttempts{}
+	conn, err := d.dialer.DialStream(context.WithValue(ctx, familyAttemptsKey{}, attempts), addr)
+	attempts.mu.Lock()
+	attempts.done = true
+	attempts.mu.Unlock()
+
+	d.mu.Lock()
+	switch {
+	case err != nil:
+		d.stats.Failures++
+	case !attempts.winner.IsValid():
+		// The connection didn't go through the attempts, so there's no family to report.
+	case attempts.winner.Is4() || attempts.winner.Is4In6():
+		d.stats.IPv4Wins++
+		if attempts.ip6Attempts > 0 {
+			if attempts.ip6Failures == attempts.ip6Attempts {
+				d.stats.IPv4FallbacksAfterIPv6Failure++
+			} else {
+				d.stats.IPv4WinsOverSlowIPv6++
+			}
+		}
+	default:
+		d.stats.IPv6Wins++
+		if attempts.ip4Attempts > 0 {
+			if attempts.ip4Failures == attempts.ip4Attempts {
+				d.stats.IPv6FallbacksAfterIPv4Failure++
+			} else {
+				d.stats.IPv6WinsOverSlowIPv4++
+			}
+		}
+	}
+	stats := d.stats
+	d.mu.Unlock()
+
+	d.callback(stats)
+	return conn, err
+}
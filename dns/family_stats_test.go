@@ -0,0 +1,58 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFamilyAttempts_CancelledAfterWinner(t *testing.T) {
+	ip4 := netip.MustParseAddr("127.0.0.1")
+	ip6 := netip.MustParseAddr("::1")
+	var attempts familyAttempts
+	attempts.start(ip6)
+	attempts.start(ip4)
+	attempts.finish(ip4, nil)
+	// The losing attempt finishes before the dial does, with an error other than the cancellation.
+	attempts.finish(ip6, errors.New("connection reset"))
+	require.Equal(t, ip4, attempts.winner)
+	require.Equal(t, 0, attempts.ip6Failures)
+}
+
+func TestFamilyAttempts_Cancelled(t *testing.T) {
+	ip6 := netip.MustParseAddr("::1")
+	var attempts familyAttempts
+	attempts.start(ip6)
+	attempts.finish(ip6, fmt.Errorf("dial failed: %w", context.Canceled))
+	require.Equal(t, 0, attempts.ip6Failures)
+}
+
+func TestFamilyAttempts_Failure(t *testing.T) {
+	ip4 := netip.MustParseAddr("127.0.0.1")
+	ip6 := netip.MustParseAddr("::1")
+	var attempts familyAttempts
+	attempts.start(ip6)
+	attempts.finish(ip6, errors.New("network unreachable"))
+	attempts.start(ip4)
+	attempts.finish(ip4, nil)
+	require.Equal(t, ip4, attempts.winner)
+	require.Equal(t, 1, attempts.ip6Failures)
+}
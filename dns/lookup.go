@@ -0,0 +1,156 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var (
+	// ErrNXDomain is matched by the [RCodeError] for a response saying the domain doesn't exist.
+	ErrNXDomain = errors.New("domain does not exist")
+	// ErrServFail is matched by the [RCodeError] for a response saying the resolver failed to resolve the domain.
+	ErrServFail = errors.New("resolver failure")
+)
+
+// RCodeError is returned by [LookupIP] when the resolver responds with an error code.
+// You can use [errors.Is] with [ErrNXDomain] and [ErrServFail] to check for the common cases.
+type RCodeError struct {
+	RCode dnsmessage.RCode
+}
+
+func (e *RCodeError) Error() string {
+	return fmt.Sprintf("got %v (%d)", e.RCode.String(), e.RCode)
+}
+
+func (e *RCodeError) Is(target error) bool {
+	switch target {
+	case ErrNXDomain:
+		return e.RCode == dnsmessage.RCodeNameError
+	case ErrServFail:
+		return e.RCode == dnsmessage.RCodeServerFailure
+	default:
+		return false
+	}
+}
+
+// Maximum number of CNAMEs to follow, to protect against loops.
+const maxCNAMEChain = 8
+
+// resolveIP queries resolver for the addresses of type rrType of hostname, following the CNAMEs.
+// The CNAME targets are queried again if the response doesn't have their addresses.
+func resolveIP(ctx context.Context, resolver Resolver, rrType dnsmessage.Type, hostname string) ([]netip.Addr, error) {
+	q, err := NewQuestion(hostname, rrType)
+	if err != nil {
+		return nil, err
+	}
+	for queries := 0; queries <= maxCNAMEChain; queries++ {
+		response, err := resolver.Query(ctx, *q)
+		if err != nil {
+			return nil, err
+		}
+		if response.RCode != dnsmessage.RCodeSuccess {
+			return nil, &RCodeError{response.RCode}
+		}
+		name := q.Name
+		ips := []netip.Addr{}
+		// The answers are usually in chain order, but that's not guaranteed, so we iterate until the name is stable.
+		for changed, hops := true, 0; changed && hops <= maxCNAMEChain; hops++ {
+			changed = false
+			ips = ips[:0]
+			for _, answer := range response.Answers {
+				if !equalASCIIName(answer.Header.Name, name) {
+					continue
+				}
+				switch rr := answer.Body.(type) {
+				case *dnsmessage.CNAMEResource:
+					if answer.Header.Type == dnsmessage.TypeCNAME && !equalASCIIName(rr.CNAME, name) {
+						name = rr.CNAME
+						changed = true
+					}
+				case *dnsmessage.AResource:
+					if rrType == dnsmessage.TypeA {
+						ips = append(ips, netip.AddrFrom4(rr.A))
+					}
+				case *dnsmessage.AAAAResource:
+					if rrType == dnsmessage.TypeAAAA {
+						ips = append(ips, netip.AddrFrom16(rr.AAAA))
+					}
+				}
+				if changed {
+					break
+				}
+			}
+		}
+		if len(ips) > 0 || equalASCIIName(name, q.Name) {
+			return ips, nil
+		}
+		// The response has the CNAME, but not the addresses of the target. Query the target.
+		q.Name = name
+	}
+	return nil, fmt.Errorf("too many CNAMEs for %v", hostname)
+}
+
+// LookupIP returns the IPv4 and IPv6 addresses of host, using resolver to query the A and AAAA records in parallel.
+// It follows CNAMEs. The addresses are interleaved by family, starting with IPv6, as recommended for connection
+// attempts by [Happy Eyeballs v2]. If host is an IP address, it's returned as is.
+//
+// The lookup fails if no address is found. If the resolver responded with an error code, the error is an
+// [RCodeError], which matches [ErrNXDomain] or [ErrServFail] with [errors.Is].
+//
+// [Happy Eyeballs v2]: https://datatracker.ietf.org/doc/html/rfc8305#section-4
+func LookupIP(ctx context.Context, resolver Resolver, host string) ([]netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{ip}, nil
+	}
+	type result struct {
+		ips []netip.Addr
+		err error
+	}
+	ip6Ch := make(chan result, 1)
+	go func() {
+		ips, err := resolveIP(ctx, resolver, dnsmessage.TypeAAAA, host)
+		ip6Ch <- result{ips, err}
+	}()
+	ip4s, err4 := resolveIP(ctx, resolver, dnsmessage.TypeA, host)
+	ip6Result := <-ip6Ch
+	ip6s, err6 := ip6Result.ips, ip6Result.err
+
+	ips := make([]netip.Addr, 0, len(ip4s)+len(ip6s))
+	for i := 0; i < len(ip4s) || i < len(ip6s); i++ {
+		if i < len(ip6s) {
+			ips = append(ips, ip6s[i])
+		}
+		if i < len(ip4s) {
+			ips = append(ips, ip4s[i])
+		}
+	}
+	if len(ips) > 0 {
+		return ips, nil
+	}
+	if err4 == nil && err6 == nil {
+		return nil, fmt.Errorf("no addresses found for %v", host)
+	}
+	// Prefer the IPv4 error, since some resolvers fail on AAAA queries.
+	if err4 != nil {
+		return nil, err4
+	}
+	return nil, err6
+}
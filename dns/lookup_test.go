@@ -0,0 +1,166 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newRecordsResolver returns a resolver that answers with the records of the given type in records,
+// and the CNAMEs in cnames, keyed by owner name.
+func newRecordsResolver(records map[string][]string, cnames map[string]string) Resolver {
+	return FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		resp := &dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Questions: []dnsmessage.Question{q}}
+		name := q.Name.String()
+		if _, ok := records[name]; !ok {
+			if _, ok := cnames[name]; !ok {
+				resp.RCode = dnsmessage.RCodeNameError
+				return resp, nil
+			}
+		}
+		// Put the CNAMEs last, to check that the order doesn't matter.
+		var cnameAnswers []dnsmessage.Resource
+		for target, ok := cnames[name]; ok; target, ok = cnames[name] {
+			cnameAnswers = append(cnameAnswers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeCNAME, Class: q.Class},
+				Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(target)},
+			})
+			name = target
+		}
+		for _, record := range records[name] {
+			ip := netip.MustParseAddr(record)
+			header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: q.Type, Class: q.Class}
+			if q.Type == dnsmessage.TypeA && ip.Is4() {
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: ip.As4()}})
+			}
+			if q.Type == dnsmessage.TypeAAAA && ip.Is6() {
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: ip.As16()}})
+			}
+		}
+		resp.Answers = append(resp.Answers, cnameAnswers...)
+		return resp, nil
+	})
+}
+
+func TestLookupIP_Interleaved(t *testing.T) {
+	resolver := newRecordsResolver(map[string][]string{
+		"example.com.": {"192.0.2.1", "192.0.2.2", "192.0.2.3", "2001:db8::1", "2001:db8::2"},
+	}, nil)
+	ips, err := LookupIP(context.Background(), resolver, "example.com")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{
+		netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("2001:db8::2"), netip.MustParseAddr("192.0.2.2"),
+		netip.MustParseAddr("192.0.2.3"),
+	}, ips)
+}
+
+func TestLookupIP_CNAME(t *testing.T) {
+	resolver := newRecordsResolver(map[string][]string{
+		"target.example.": {"192.0.2.1"},
+	}, map[string]string{
+		"www.example.com.": "cdn.example.",
+		"cdn.example.":     "target.example.",
+	})
+	ips, err := LookupIP(context.Background(), resolver, "www.example.com")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.0.2.1")}, ips)
+}
+
+func TestLookupIP_CNAMEQueriedSeparately(t *testing.T) {
+	base := newRecordsResolver(map[string][]string{"target.example.": {"2001:db8::1"}}, nil)
+	var queried []string
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		if q.Name.String() != "www.example.com." {
+			if q.Type == dnsmessage.TypeAAAA {
+				queried = append(queried, q.Name.String())
+			}
+			return base.Query(ctx, q)
+		}
+		// Only return the CNAME.
+		return &dnsmessage.Message{
+			Header:    dnsmessage.Header{Response: true},
+			Questions: []dnsmessage.Question{q},
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeCNAME, Class: q.Class},
+				Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("target.example.")},
+			}},
+		}, nil
+	})
+	ips, err := LookupIP(context.Background(), resolver, "www.example.com")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::1")}, ips)
+	require.Equal(t, []string{"target.example."}, queried)
+}
+
+func TestLookupIP_CNAMELoop(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		target := "b.example."
+		if q.Name.String() == "b.example." {
+			target = "a.example."
+		}
+		return &dnsmessage.Message{
+			Header:    dnsmessage.Header{Response: true},
+			Questions: []dnsmessage.Question{q},
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeCNAME, Class: q.Class},
+				Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(target)},
+			}},
+		}, nil
+	})
+	_, err := LookupIP(context.Background(), resolver, "a.example")
+	require.Error(t, err)
+}
+
+func TestLookupIP_IPLiteral(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return nil, errors.New("IP literals must not be resolved")
+	})
+	ips, err := LookupIP(context.Background(), resolver, "2001:db8::1")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::1")}, ips)
+}
+
+func TestLookupIP_NXDomain(t *testing.T) {
+	resolver := newRecordsResolver(nil, nil)
+	_, err := LookupIP(context.Background(), resolver, "missing.example")
+	require.ErrorIs(t, err, ErrNXDomain)
+	require.NotErrorIs(t, err, ErrServFail)
+	var rcodeErr *RCodeError
+	require.ErrorAs(t, err, &rcodeErr)
+	require.Equal(t, dnsmessage.RCodeNameError, rcodeErr.RCode)
+}
+
+func TestLookupIP_ServFail(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return &dnsmessage.Message{Header: dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeServerFailure}}, nil
+	})
+	_, err := LookupIP(context.Background(), resolver, "example.com")
+	require.ErrorIs(t, err, ErrServFail)
+	require.NotErrorIs(t, err, ErrNXDomain)
+}
+
+func TestLookupIP_NoAddresses(t *testing.T) {
+	resolver := newRecordsResolver(map[string][]string{"example.com.": {}}, nil)
+	_, err := LookupIP(context.Background(), resolver, "example.com")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrNXDomain)
+}
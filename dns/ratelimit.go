@@ -0,0 +1,92 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type rateLimitedResolver struct {
+	base  Resolver
+	qps   float64
+	burst float64
+
+	mu sync.Mutex
+	// tokens is the number of queries that can be sent right away. It's negative if there are queries waiting.
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitedResolver creates a [Resolver] that sends at most qps queries per second with the base resolver, on
+// average, to reduce the load on the resolver during bulk lookups and avoid triggering rate-based blocking. It's a
+// token bucket that allows bursts of up to one second of queries, or one query if qps is less than one.
+//
+// Queries over the limit wait for their turn, or return the context error if the context is done first.
+// A qps of zero or less disables the limit, and returns the base resolver.
+func NewRateLimitedResolver(base Resolver, qps float64) Resolver {
+	if qps <= 0 {
+		return base
+	}
+	burst := qps
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedResolver{base: base, qps: qps, burst: burst, tokens: burst}
+}
+
+// reserve takes a token and returns how long to wait before it can be used.
+func (r *rateLimitedResolver) reserve() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.qps
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.qps * float64(time.Second))
+}
+
+// cancel returns a token that was reserved but not used.
+func (r *rateLimitedResolver) cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens++
+}
+
+// Query implements [Resolver].
+func (r *rateLimitedResolver) Query(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+	if wait := r.reserve(); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			r.cancel()
+			return nil, ctx.Err()
+		}
+	}
+	return r.base.Query(ctx, q)
+}
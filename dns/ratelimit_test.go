@@ -0,0 +1,63 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestRateLimitedResolver(t *testing.T) {
+	var queries atomic.Int32
+	base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		queries.Add(1)
+		return &dnsmessage.Message{}, nil
+	})
+	resolver := NewRateLimitedResolver(base, 100)
+	q, err := NewQuestion("example.com.", dnsmessage.TypeA)
+	require.NoError(t, err)
+
+	// The burst of 100 queries goes right away, and the next 20 take about 200ms.
+	start := time.Now()
+	for i := 0; i < 120; i++ {
+		_, err := resolver.Query(context.Background(), *q)
+		require.NoError(t, err)
+	}
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	require.Equal(t, int32(120), queries.Load())
+
+	// Queries over the limit fail if the context is done first.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		if _, err = resolver.Query(ctx, *q); err != nil {
+			break
+		}
+	}
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimitedResolver_Disabled(t *testing.T) {
+	base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return &dnsmessage.Message{}, nil
+	})
+	_, ok := NewRateLimitedResolver(base, 0).(FuncResolver)
+	require.True(t, ok)
+}
@@ -17,6 +17,7 @@ package dns
 import (
 	"bytes"
 	"context"
+	cryptorand "crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -86,6 +87,21 @@ func NewQuestion(domain string, qtype dnsmessage.Type) (*dnsmessage.Question, er
 	}, nil
 }
 
+type dnssecOKKey struct{}
+
+// WithDNSSECOK returns a context that asks the resolvers in this package to set the DNSSEC OK (DO) bit in the
+// queries, so that the responses include the DNSSEC records, as per https://datatracker.ietf.org/doc/html/rfc3225.
+// Custom [Resolver] implementations can use [DNSSECOK] to honor it.
+func WithDNSSECOK(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dnssecOKKey{}, true)
+}
+
+// DNSSECOK returns whether the context was created with [WithDNSSECOK].
+func DNSSECOK(ctx context.Context) bool {
+	dnssecOK, _ := ctx.Value(dnssecOKKey{}).(bool)
+	return dnssecOK
+}
+
 // Maximum UDP message size that we support.
 // The value is taken from https://dnsflagday.net/2020/, which says:
 // "An EDNS buffer size of 1232 bytes will avoid fragmentation on nearly all current networks.
@@ -94,7 +110,8 @@ func NewQuestion(domain string, qtype dnsmessage.Type) (*dnsmessage.Question, er
 const maxUDPMessageSize = 1232
 
 // appendRequest appends the bytes a DNS request using the id and question to buf.
-func appendRequest(id uint16, q dnsmessage.Question, buf []byte) ([]byte, error) {
+// If dnssecOK is set, it sets the DO bit to request the DNSSEC records.
+func appendRequest(id uint16, q dnsmessage.Question, dnssecOK bool, buf []byte) ([]byte, error) {
 	b := dnsmessage.NewBuilder(buf, dnsmessage.Header{ID: id, RecursionDesired: true})
 	if err := b.StartQuestions(); err != nil {
 		return nil, fmt.Errorf("start questions failed: %w", err)
@@ -108,7 +125,7 @@ func appendRequest(id uint16, q dnsmessage.Question, buf []byte) ([]byte, error)
 
 	var rh dnsmessage.ResourceHeader
 	// Set the maximum payload size we support, as per https://datatracker.ietf.org/doc/html/rfc6891#section-4.3
-	if err := rh.SetEDNS0(maxUDPMessageSize, dnsmessage.RCodeSuccess, false); err != nil {
+	if err := rh.SetEDNS0(maxUDPMessageSize, dnsmessage.RCodeSuccess, dnssecOK); err != nil {
 		return nil, fmt.Errorf("set EDNS(0) failed: %w", err)
 	}
 	if err := b.OPTResource(rh, dnsmessage.OPTResource{}); err != nil {
@@ -144,6 +161,17 @@ func equalASCIIName(x, y dnsmessage.Name) bool {
 	return true
 }
 
+// IDMismatchError is returned when the ID of a response doesn't match the ID of the query, which
+// may indicate a spoofed response.
+type IDMismatchError struct {
+	QueryID    uint16
+	ResponseID uint16
+}
+
+func (e *IDMismatchError) Error() string {
+	return fmt.Sprintf("message id does not match. Expected %v, got %v", e.QueryID, e.ResponseID)
+}
+
 func checkResponse(reqID uint16, reqQues dnsmessage.Question, respHdr dnsmessage.Header, respQs []dnsmessage.Question) error {
 	if !respHdr.Response {
 		return errors.New("response bit not set")
@@ -151,7 +179,7 @@ func checkResponse(reqID uint16, reqQues dnsmessage.Question, respHdr dnsmessage
 
 	// https://datatracker.ietf.org/doc/html/rfc5452#section-4.3
 	if reqID != respHdr.ID {
-		return fmt.Errorf("message id does not match. Expected %v, got %v", reqID, respHdr.ID)
+		return &IDMismatchError{QueryID: reqID, ResponseID: respHdr.ID}
 	}
 
 	// https://datatracker.ietf.org/doc/html/rfc5452#section-4.2
@@ -166,11 +194,11 @@ func checkResponse(reqID uint16, reqQues dnsmessage.Question, respHdr dnsmessage
 	return nil
 }
 
-// queryDatagram implements a DNS query over a datagram protocol.
-func queryDatagram(conn io.ReadWriter, q dnsmessage.Question) (*dnsmessage.Message, error) {
+// queryDatagram implements a DNS query over a datagram protocol, using the given message id.
+// Responses that don't match the query, including the id, are ignored, since they could be injected.
+func queryDatagram(conn io.ReadWriter, id uint16, q dnsmessage.Question, dnssecOK bool) (*dnsmessage.Message, error) {
 	// Reference: https://cs.opensource.google/go/go/+/master:src/net/dnsclient_unix.go?q=func:dnsPacketRoundTrip&ss=go%2Fgo
-	id := uint16(rand.Uint32())
-	buf, err := appendRequest(id, q, make([]byte, 0, maxUDPMessageSize))
+	buf, err := appendRequest(id, q, dnssecOK, make([]byte, 0, maxUDPMessageSize))
 	if err != nil {
 		return nil, &nestedError{ErrBadRequest, fmt.Errorf("append request failed: %w", err)}
 	}
@@ -203,10 +231,10 @@ func queryDatagram(conn io.ReadWriter, q dnsmessage.Question) (*dnsmessage.Messa
 }
 
 // queryStream implements a DNS query over a stream protocol. It frames the messages by prepending them with a 2-byte length prefix.
-func queryStream(conn io.ReadWriter, q dnsmessage.Question) (*dnsmessage.Message, error) {
+func queryStream(conn io.ReadWriter, q dnsmessage.Question, dnssecOK bool) (*dnsmessage.Message, error) {
 	// Reference: https://cs.opensource.google/go/go/+/master:src/net/dnsclient_unix.go?q=func:dnsStreamRoundTrip&ss=go%2Fgo
 	id := uint16(rand.Uint32())
-	buf, err := appendRequest(id, q, make([]byte, 2, 514))
+	buf, err := appendRequest(id, q, dnssecOK, make([]byte, 2, 514))
 	if err != nil {
 		return nil, &nestedError{ErrBadRequest, fmt.Errorf("append request failed: %w", err)}
 	}
@@ -247,7 +275,10 @@ func queryStream(conn io.ReadWriter, q dnsmessage.Question) (*dnsmessage.Message
 func ensurePort(address string, defaultPort string) string {
 	host, port, err := net.SplitHostPort(address)
 	if err != nil {
-		// Failed to parse as host:port. Assume address is a host.
+		// Failed to parse as host:port. Assume address is a host, possibly a bracketed IPv6 literal.
+		if len(address) >= 2 && address[0] == '[' && address[len(address)-1] == ']' {
+			address = address[1 : len(address)-1]
+		}
 		return net.JoinHostPort(address, defaultPort)
 	}
 	if port == "" {
@@ -256,12 +287,64 @@ func ensurePort(address string, defaultPort string) string {
 	return address
 }
 
+// IDGenerator returns the message ID to use for a new DNS query.
+type IDGenerator func() uint16
+
+// RandomID is an [IDGenerator] that returns cryptographically random IDs, as recommended by
+// [RFC 5452] to make spoofed responses harder to forge.
+//
+// [RFC 5452]: https://datatracker.ietf.org/doc/html/rfc5452#section-9.2
+func RandomID() uint16 {
+	var b [2]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		// The crypto source should never fail. Fall back to the default source just in case.
+		return uint16(rand.Uint32())
+	}
+	return binary.BigEndian.Uint16(b[:])
+}
+
+// FixedID returns an [IDGenerator] that always returns id. It's meant for tests that need predictable
+// messages. Don't use it in production, since it makes spoofing responses trivial.
+func FixedID(id uint16) IDGenerator {
+	return func() uint16 { return id }
+}
+
+// UDPResolverOption configures the [Resolver] created by [NewUDPResolver].
+type UDPResolverOption func(*udpResolverConfig)
+
+type udpResolverConfig struct {
+	newID IDGenerator
+}
+
+// WithIDGenerator sets how the message IDs of the queries are generated. The default is [RandomID].
+func WithIDGenerator(newID IDGenerator) UDPResolverOption {
+	return func(config *udpResolverConfig) {
+		config.newID = newID
+	}
+}
+
 // NewUDPResolver creates a [Resolver] that implements the DNS-over-UDP protocol, using a [transport.PacketDialer] for transport.
-// It uses a different port for every request.
+// It dials a new connection for every request, so that each query can use a different source port, which together with the
+// random message ID makes off-path spoofing harder, as per [RFC 5452]. Whether the port actually changes depends on pd:
+// a [transport.UDPDialer] creates a new socket for every connection, and picks the port at random if
+// [transport.UDPDialer.RandomizeSourcePort] is set. Dialers that reuse a socket or a proxy association across connections
+// save resources, but send all the queries from the same source port, leaving only the 16-bit message ID to defend against
+// spoofing. Prefer an encrypted resolver in hostile networks.
+//
+// Responses are only accepted if their message ID and question match the query. Other responses are ignored,
+// and reported as an [IDMismatchError] if no valid response arrives before the context is done.
 //
 // [DNS-over-UDP]: https://datatracker.ietf.org/doc/html/rfc1035#section-4.2.1
-func NewUDPResolver(pd transport.PacketDialer, resolverAddr string) Resolver {
+// [RFC 5452]: https://datatracker.ietf.org/doc/html/rfc5452#section-9.2
+func NewUDPResolver(pd transport.PacketDialer, resolverAddr string, options ...UDPResolverOption) Resolver {
 	resolverAddr = ensurePort(resolverAddr, "53")
+	config := udpResolverConfig{newID: RandomID}
+	for _, option := range options {
+		option(&config)
+	}
+	if config.newID == nil {
+		config.newID = RandomID
+	}
 	return FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
 		conn, err := pd.DialPacket(ctx, resolverAddr)
 		if err != nil {
@@ -271,7 +354,8 @@ func NewUDPResolver(pd transport.PacketDialer, resolverAddr string) Resolver {
 		if deadline, ok := ctx.Deadline(); ok {
 			conn.SetDeadline(deadline)
 		}
-		return queryDatagram(conn, q)
+		defer unblockOnDone(ctx, conn)()
+		return queryDatagram(conn, config.newID(), q, DNSSECOK(ctx))
 	})
 }
 
@@ -289,7 +373,22 @@ func (r *streamResolver) Query(ctx context.Context, q dnsmessage.Question) (*dns
 	if deadline, ok := ctx.Deadline(); ok {
 		conn.SetDeadline(deadline)
 	}
-	return queryStream(conn, q)
+	defer unblockOnDone(ctx, conn)()
+	return queryStream(conn, q, DNSSECOK(ctx))
+}
+
+// unblockOnDone unblocks the reads and writes of conn when ctx is done, by moving its deadline to now.
+// It returns a function that stops watching ctx.
+func unblockOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stopCh:
+		}
+	}()
+	return func() { close(stopCh) }
 }
 
 // NewTCPResolver creates a [Resolver] that implements the [DNS-over-TCP] protocol, using a [transport.StreamDialer] for transport.
@@ -354,7 +453,7 @@ func NewHTTPSResolver(sd transport.StreamDialer, resolverAddr string, url string
 	}
 	return FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
 		// Prepare request.
-		buf, err := appendRequest(0, q, make([]byte, 0, 512))
+		buf, err := appendRequest(0, q, DNSSECOK(ctx), make([]byte, 0, 512))
 		if err != nil {
 			return nil, &nestedError{ErrBadRequest, fmt.Errorf("append request failed: %w", err)}
 		}
@@ -15,6 +15,7 @@
 package dns
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -23,7 +24,9 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/dns/dnsmessage"
 )
@@ -78,7 +81,7 @@ func Test_appendRequest(t *testing.T) {
 
 	id := uint16(1234)
 	offset := 2
-	buf, err := appendRequest(id, *q, make([]byte, offset))
+	buf, err := appendRequest(id, *q, false, make([]byte, offset))
 	require.NoError(t, err)
 	require.Equal(t, make([]byte, offset), buf[:offset])
 
@@ -155,7 +158,10 @@ func Test_checkResponse(t *testing.T) {
 		badHdr := expectedHdr
 		badHdr.ID = reqID + 1
 		err := checkResponse(reqID, reqQ, badHdr, expectedQs)
-		require.Error(t, err)
+		var idErr *IDMismatchError
+		require.ErrorAs(t, err, &idErr)
+		require.Equal(t, reqID, idErr.QueryID)
+		require.Equal(t, reqID+1, idErr.ResponseID)
 	})
 	t.Run("NoQuestions", func(t *testing.T) {
 		err := checkResponse(reqID, reqQ, expectedHdr, []dnsmessage.Question{})
@@ -210,7 +216,7 @@ func testDatagramExchange(t *testing.T, server func(request dnsmessage.Message,
 	require.NoError(t, err)
 	clientDone := make(chan queryResult)
 	go func() {
-		msg, err := queryDatagram(front, *q)
+		msg, err := queryDatagram(front, RandomID(), *q, false)
 		clientDone <- queryResult{msg, err}
 	}()
 	// Read request.
@@ -222,7 +228,7 @@ func testDatagramExchange(t *testing.T, server func(request dnsmessage.Message,
 	var reqMsg dnsmessage.Message
 	reqMsg.Unpack(buf)
 	reqID := reqMsg.ID
-	expectedBuf, err := appendRequest(reqID, *q, make([]byte, 0, 512))
+	expectedBuf, err := appendRequest(reqID, *q, false, make([]byte, 0, 512))
 	require.NoError(t, err)
 	require.Equal(t, expectedBuf, buf)
 
@@ -281,7 +287,7 @@ func Test_queryDatagram(t *testing.T) {
 		require.NoError(t, err)
 		clientDone := make(chan queryResult)
 		go func() {
-			msg, err := queryDatagram(front, *q)
+			msg, err := queryDatagram(front, RandomID(), *q, false)
 			clientDone <- queryResult{msg, err}
 		}()
 		// Wait for queryDatagram.
@@ -295,7 +301,7 @@ func Test_queryDatagram(t *testing.T) {
 		require.NoError(t, err)
 		clientDone := make(chan queryResult)
 		go func() {
-			msg, err := queryDatagram(front, *q)
+			msg, err := queryDatagram(front, RandomID(), *q, false)
 			clientDone <- queryResult{msg, err}
 		}()
 		back.Read(make([]byte, 521))
@@ -307,13 +313,111 @@ func Test_queryDatagram(t *testing.T) {
 	})
 }
 
+// runUDPResolverServer runs a UDP server that handles one query with the given function.
+func runUDPResolverServer(t *testing.T, handle func(req dnsmessage.Message) []dnsmessage.Message) string {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, maxUDPMessageSize)
+		n, clientAddr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var req dnsmessage.Message
+		if err := req.Unpack(buf[:n]); err != nil {
+			return
+		}
+		for _, resp := range handle(req) {
+			respBuf, err := resp.Pack()
+			if err != nil {
+				return
+			}
+			conn.WriteTo(respBuf, clientAddr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNewUDPResolver_FixedID(t *testing.T) {
+	var reqID uint16
+	addr := runUDPResolverServer(t, func(req dnsmessage.Message) []dnsmessage.Message {
+		reqID = req.ID
+		resp, err := newMessageResponse(req, &dnsmessage.AAAAResource{AAAA: [16]byte(net.IPv6loopback)}, 100)
+		require.NoError(t, err)
+		return []dnsmessage.Message{resp}
+	})
+	resolver := NewUDPResolver(&transport.UDPDialer{}, addr, WithIDGenerator(FixedID(1234)))
+	q, err := NewQuestion("example.com.", dnsmessage.TypeAAAA)
+	require.NoError(t, err)
+	msg, err := resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1234), reqID)
+	require.Equal(t, uint16(1234), msg.ID)
+}
+
+func TestNewUDPResolver_IgnoresMismatchedID(t *testing.T) {
+	addr := runUDPResolverServer(t, func(req dnsmessage.Message) []dnsmessage.Message {
+		resp, err := newMessageResponse(req, &dnsmessage.AAAAResource{AAAA: [16]byte(net.IPv6loopback)}, 100)
+		require.NoError(t, err)
+		spoofed := resp
+		spoofed.ID = req.ID + 1
+		spoofed.Answers = []dnsmessage.Resource{{
+			Header: resp.Answers[0].Header,
+			Body:   &dnsmessage.AAAAResource{AAAA: [16]byte(net.IPv6unspecified)},
+		}}
+		return []dnsmessage.Message{spoofed, resp}
+	})
+	resolver := NewUDPResolver(&transport.UDPDialer{}, addr)
+	q, err := NewQuestion("example.com.", dnsmessage.TypeAAAA)
+	require.NoError(t, err)
+	msg, err := resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+	require.Len(t, msg.Answers, 1)
+	require.Equal(t, &dnsmessage.AAAAResource{AAAA: [16]byte(net.IPv6loopback)}, msg.Answers[0].Body)
+}
+
+func TestNewUDPResolver_OnlyMismatchedID(t *testing.T) {
+	addr := runUDPResolverServer(t, func(req dnsmessage.Message) []dnsmessage.Message {
+		resp, err := newMessageResponse(req, &dnsmessage.AAAAResource{AAAA: [16]byte(net.IPv6loopback)}, 100)
+		require.NoError(t, err)
+		resp.ID = req.ID + 1
+		return []dnsmessage.Message{resp}
+	})
+	resolver := NewUDPResolver(&transport.UDPDialer{}, addr, WithIDGenerator(FixedID(1)))
+	q, err := NewQuestion("example.com.", dnsmessage.TypeAAAA)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = resolver.Query(ctx, *q)
+	require.ErrorIs(t, err, ErrReceive)
+	var idErr *IDMismatchError
+	require.ErrorAs(t, err, &idErr)
+	require.Equal(t, uint16(1), idErr.QueryID)
+	require.Equal(t, uint16(2), idErr.ResponseID)
+}
+
+func TestNewUDPResolver_Cancel(t *testing.T) {
+	// The server never responds.
+	addr := runUDPResolverServer(t, func(req dnsmessage.Message) []dnsmessage.Message { return nil })
+	resolver := NewUDPResolver(&transport.UDPDialer{}, addr)
+	q, err := NewQuestion("example.com.", dnsmessage.TypeAAAA)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = resolver.Query(ctx, *q)
+	require.ErrorIs(t, err, ErrReceive)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
 func testStreamExchange(t *testing.T, server func(request dnsmessage.Message, conn net.Conn)) (*dnsmessage.Message, error) {
 	front, back := net.Pipe()
 	q, err := NewQuestion("example.com.", dnsmessage.TypeAAAA)
 	require.NoError(t, err)
 	clientDone := make(chan queryResult)
 	go func() {
-		msg, err := queryStream(front, *q)
+		msg, err := queryStream(front, *q, false)
 		clientDone <- queryResult{msg, err}
 	}()
 	// Read request.
@@ -327,7 +431,7 @@ func testStreamExchange(t *testing.T, server func(request dnsmessage.Message, co
 	var reqMsg dnsmessage.Message
 	reqMsg.Unpack(buf)
 	reqID := reqMsg.ID
-	expectedBuf, err := appendRequest(reqID, *q, make([]byte, 0, 512))
+	expectedBuf, err := appendRequest(reqID, *q, false, make([]byte, 0, 512))
 	require.NoError(t, err)
 	require.Equal(t, expectedBuf, buf)
 
@@ -416,7 +520,7 @@ func Test_queryStream(t *testing.T) {
 		require.NoError(t, err)
 		clientDone := make(chan queryResult)
 		go func() {
-			msg, err := queryStream(front, *q)
+			msg, err := queryStream(front, *q, false)
 			clientDone <- queryResult{msg, err}
 		}()
 		// Wait for client.
@@ -430,7 +534,7 @@ func Test_queryStream(t *testing.T) {
 		require.NoError(t, err)
 		clientDone := make(chan queryResult)
 		go func() {
-			msg, err := queryStream(front, *q)
+			msg, err := queryStream(front, *q, false)
 			clientDone <- queryResult{msg, err}
 		}()
 		back.Read(make([]byte, 521))
@@ -452,4 +556,8 @@ func Test_ensurePort(t *testing.T) {
 	require.Equal(t, "[2001:4860:4860::8888]:8080", ensurePort("[2001:4860:4860::8888]:8080", "443"))
 	require.Equal(t, "[2001:4860:4860::8888]:443", ensurePort("2001:4860:4860::8888", "443"))
 	require.Equal(t, "[2001:4860:4860::8888]:443", ensurePort("[2001:4860:4860::8888]:", "443"))
+	require.Equal(t, "[2001:4860:4860::8888]:443", ensurePort("[2001:4860:4860::8888]", "443"))
+	require.Equal(t, "[fe80::1%eth0]:443", ensurePort("fe80::1%eth0", "443"))
+	require.Equal(t, "[fe80::1%eth0]:443", ensurePort("[fe80::1%eth0]", "443"))
+	require.Equal(t, "[fe80::1%eth0]:53", ensurePort("[fe80::1%eth0]:53", "443"))
 }
@@ -0,0 +1,136 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type singleflightKey struct {
+	name     string
+	qtype    dnsmessage.Type
+	qclass   dnsmessage.Class
+	dnssecOK bool
+}
+
+// singleflightCall is a query in flight, shared by the callers waiting for it.
+type singleflightCall struct {
+	done     chan struct{}
+	response *dnsmessage.Message
+	err      error
+	// waiters is the number of callers waiting for the query. It's protected by the resolver lock.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+type singleflightResolver struct {
+	base Resolver
+
+	mu    sync.Mutex
+	calls map[singleflightKey]*singleflightCall
+}
+
+// NewSingleflightResolver creates a [Resolver] that deduplicates concurrent identical queries, so that only one of
+// them is sent with the base resolver and all the callers get its result. Queries are identical if they have the same
+// name, ignoring the case, type and class, and the same [DNSSECOK] setting. The responses are not cached: a query
+// that starts after the shared one finished is sent again.
+//
+// The shared query uses the values and deadline of the context of the first caller, and is canceled only when all the
+// callers waiting for it are done. Each caller gets its own copy of the response message, but the resource bodies are
+// shared, so they must not be modified.
+func NewSingleflightResolver(base Resolver) Resolver {
+	return &singleflightResolver{base: base, calls: make(map[singleflightKey]*singleflightCall)}
+}
+
+// detachedContext has the values of the parent context, but is not canceled with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}       { return nil }
+func (c detachedContext) Err() error                  { return nil }
+func (c detachedContext) Value(key any) any           { return c.parent.Value(key) }
+
+// Query implements [Resolver].
+func (r *singleflightResolver) Query(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+	key := singleflightKey{strings.ToLower(q.Name.String()), q.Type, q.Class, DNSSECOK(ctx)}
+	r.mu.Lock()
+	call, ok := r.calls[key]
+	if !ok {
+		call = &singleflightCall{done: make(chan struct{})}
+		var queryCtx context.Context = detachedContext{ctx}
+		if deadline, ok := ctx.Deadline(); ok {
+			queryCtx, call.cancel = context.WithDeadline(queryCtx, deadline)
+		} else {
+			queryCtx, call.cancel = context.WithCancel(queryCtx)
+		}
+		r.calls[key] = call
+		go r.run(queryCtx, key, call, q)
+	}
+	call.waiters++
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return copyMessage(call.response), nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			// New queries must not join the canceled call.
+			if r.calls[key] == call {
+				delete(r.calls, key)
+			}
+		}
+		r.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (r *singleflightResolver) run(ctx context.Context, key singleflightKey, call *singleflightCall, q dnsmessage.Question) {
+	call.response, call.err = r.base.Query(ctx, q)
+	call.cancel()
+	r.mu.Lock()
+	// The key may have been taken by a new call if this one was canceled.
+	if r.calls[key] == call {
+		delete(r.calls, key)
+	}
+	r.mu.Unlock()
+	close(call.done)
+}
+
+// copyMessage returns a copy of the message with its own sections, so that callers can modify them.
+func copyMessage(msg *dnsmessage.Message) *dnsmessage.Message {
+	if msg == nil {
+		return nil
+	}
+	return &dnsmessage.Message{
+		Header:      msg.Header,
+		Questions:   append([]dnsmessage.Question(nil), msg.Questions...),
+		Answers:     append([]dnsmessage.Resource(nil), msg.Answers...),
+		Authorities: append([]dnsmessage.Resource(nil), msg.Authorities...),
+		Additionals: append([]dnsmessage.Resource(nil), msg.Additionals...),
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// waitForWaiters waits until the number of callers waiting for any query is n.
+func waitForWaiters(t *testing.T, r *singleflightResolver, n int) {
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		waiters := 0
+		for _, call := range r.calls {
+			waiters += call.waiters
+		}
+		return waiters == n
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestSingleflightResolver_Deduplicates(t *testing.T) {
+	var queries atomic.Int32
+	release := make(chan struct{})
+	base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		queries.Add(1)
+		<-release
+		return &dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Questions: []dnsmessage.Question{q}}, nil
+	})
+	resolver := NewSingleflightResolver(base)
+
+	var wg sync.WaitGroup
+	responses := make([]*dnsmessage.Message, 10)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "example.com."
+			if i%2 == 0 {
+				name = "EXAMPLE.com."
+			}
+			q, err := NewQuestion(name, dnsmessage.TypeA)
+			require.NoError(t, err)
+			responses[i], err = resolver.Query(context.Background(), *q)
+			require.NoError(t, err)
+		}(i)
+	}
+	waitForWaiters(t, resolver.(*singleflightResolver), len(responses))
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), queries.Load())
+	for _, response := range responses {
+		require.NotNil(t, response)
+		require.Len(t, response.Questions, 1)
+	}
+	// Each caller gets its own message.
+	require.NotSame(t, responses[0], responses[1])
+
+	// Queries after the shared one finished are sent again.
+	q, err := NewQuestion("example.com.", dnsmessage.TypeA)
+	require.NoError(t, err)
+	_, err = resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), queries.Load())
+}
+
+func TestSingleflightResolver_DifferentQueries(t *testing.T) {
+	var queries atomic.Int32
+	release := make(chan struct{})
+	base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		queries.Add(1)
+		<-release
+		return &dnsmessage.Message{}, nil
+	})
+	resolver := NewSingleflightResolver(base)
+
+	var wg sync.WaitGroup
+	query := func(ctx context.Context, name string, qtype dnsmessage.Type) {
+		defer wg.Done()
+		q, err := NewQuestion(name, qtype)
+		require.NoError(t, err)
+		_, err = resolver.Query(ctx, *q)
+		require.NoError(t, err)
+	}
+	wg.Add(4)
+	go query(context.Background(), "example.com.", dnsmessage.TypeA)
+	go query(context.Background(), "example.com.", dnsmessage.TypeAAAA)
+	go query(context.Background(), "example.org.", dnsmessage.TypeA)
+	go query(WithDNSSECOK(context.Background()), "example.com.", dnsmessage.TypeA)
+	waitForWaiters(t, resolver.(*singleflightResolver), 4)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(4), queries.Load())
+}
+
+func TestSingleflightResolver_Cancel(t *testing.T) {
+	queryCanceled := make(chan struct{})
+	release := make(chan struct{})
+	base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		select {
+		case <-release:
+			return &dnsmessage.Message{}, nil
+		case <-ctx.Done():
+			close(queryCanceled)
+			return nil, ctx.Err()
+		}
+	})
+	resolver := NewSingleflightResolver(base)
+	q, err := NewQuestion("example.com.", dnsmessage.TypeA)
+	require.NoError(t, err)
+
+	// The first caller gives up, but the second still gets the response.
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := resolver.Query(ctx, *q)
+		firstDone <- err
+	}()
+	secondDone := make(chan error)
+	go func() {
+		_, err := resolver.Query(context.Background(), *q)
+		secondDone <- err
+	}()
+	waitForWaiters(t, resolver.(*singleflightResolver), 2)
+	cancel()
+	require.ErrorIs(t, <-firstDone, context.Canceled)
+	close(release)
+	require.NoError(t, <-secondDone)
+
+	// The shared query is canceled when all the callers give up.
+	release = make(chan struct{})
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		_, err := resolver.Query(ctx, *q)
+		firstDone <- err
+	}()
+	waitForWaiters(t, resolver.(*singleflightResolver), 1)
+	cancel()
+	require.ErrorIs(t, <-firstDone, context.Canceled)
+	<-queryCanceled
+}
+
+func TestSingleflightResolver_QueryAfterCancel(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	base := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		if calls.Add(1) == 1 {
+			// The first query only returns after the test is done, even if canceled.
+			<-release
+			return nil, ctx.Err()
+		}
+		return &dnsmessage.Message{}, nil
+	})
+	defer close(release)
+	resolver := NewSingleflightResolver(base)
+	q, err := NewQuestion("example.com.", dnsmessage.TypeA)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := resolver.Query(ctx, *q)
+		firstDone <- err
+	}()
+	waitForWaiters(t, resolver.(*singleflightResolver), 1)
+	cancel()
+	require.ErrorIs(t, <-firstDone, context.Canceled)
+
+	// The canceled query is still running, but a new caller must not join it.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = resolver.Query(ctx, *q)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), calls.Load())
+}
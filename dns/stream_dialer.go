@@ -17,50 +17,56 @@ package dns
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/netip"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"golang.org/x/net/dns/dnsmessage"
 )
 
-func resolveIP(ctx context.Context, resolver Resolver, rrType dnsmessage.Type, hostname string) ([]netip.Addr, error) {
-	ips := []netip.Addr{}
-	q, err := NewQuestion(hostname, rrType)
-	if err != nil {
-		return nil, err
+// resolveIPv4First returns the IPv4 addresses of the host, or the IPv6 addresses if it has no IPv4 address.
+func resolveIPv4First(ctx context.Context, resolver Resolver, hostname string) ([]netip.Addr, error) {
+	ips, err := resolveIP(ctx, resolver, dnsmessage.TypeA, hostname)
+	if err == nil && len(ips) > 0 {
+		return ips, nil
 	}
-	response, err := resolver.Query(ctx, *q)
-	if err != nil {
-		return nil, err
+	ips6, err6 := resolveIP(ctx, resolver, dnsmessage.TypeAAAA, hostname)
+	if err6 == nil && len(ips6) > 0 {
+		return ips6, nil
 	}
-	if response.RCode != dnsmessage.RCodeSuccess {
-		return nil, fmt.Errorf("got %v (%d)", response.RCode.String(), response.RCode)
-	}
-	for _, answer := range response.Answers {
-		if answer.Header.Type != rrType {
-			continue
-		}
-		if rr, ok := answer.Body.(*dnsmessage.AResource); ok {
-			ips = append(ips, netip.AddrFrom4(rr.A))
-		}
-		if rr, ok := answer.Body.(*dnsmessage.AAAAResource); ok {
-			ips = append(ips, netip.AddrFrom16(rr.AAAA))
-		}
-	}
-	return ips, nil
+	return nil, errors.Join(err, err6)
+}
+
+// StreamDialerOption configures the [transport.StreamDialer] created by [NewStreamDialer].
+type StreamDialerOption func(*streamDialerConfig)
+
+type streamDialerConfig struct {
+	onFamilyStats func(FamilyStats)
 }
 
 // NewStreamDialer creates a [transport.StreamDialer] that uses Happy Eyeballs v2 to establish a connection.
 // It uses resolver to map host names to IP addresses, and the given dialer to attempt connections.
-func NewStreamDialer(resolver Resolver, dialer transport.StreamDialer) (transport.StreamDialer, error) {
+//
+// The given dialer only gets IP addresses, and the dial fails if the resolver can't resolve the host, so host names
+// never leak to the system resolver. To force all the resolutions of a dialer chain through the resolver, use the
+// returned dialer as the base of the dialers that rewrite the destination address, like an address override, so the
+// rewritten host is resolved by the resolver. A dialer that rewrites the address after the resolution may pass a host
+// name to a base dialer that resolves it with the system resolver, like [transport.TCPDialer].
+//
+// If the dialer declares that its dial errors don't reflect the reachability of the destination, as per
+// [transport.ReportsConnectFailure], Happy Eyeballs can't fall back after a failed attempt. In that case, it only
+// dials the IPv4 addresses, which are more widely reachable, and only uses IPv6 if the host has no IPv4 address.
+func NewStreamDialer(resolver Resolver, dialer transport.StreamDialer, options ...StreamDialerOption) (transport.StreamDialer, error) {
 	if resolver == nil {
 		return nil, errors.New("resolver must not be nil")
 	}
 	if dialer == nil {
 		return nil, errors.New("dialer must not be nil")
 	}
-	return &transport.HappyEyeballsStreamDialer{
+	var config streamDialerConfig
+	for _, option := range options {
+		option(&config)
+	}
+	heDialer := &transport.HappyEyeballsStreamDialer{
 		Dialer: dialer,
 		Resolve: transport.NewParallelHappyEyeballsResolveFunc(
 			func(ctx context.Context, hostname string) ([]netip.Addr, error) {
@@ -70,5 +76,16 @@ func NewStreamDialer(resolver Resolver, dialer transport.StreamDialer) (transpor
 				return resolveIP(ctx, resolver, dnsmessage.TypeA, hostname)
 			},
 		),
-	}, nil
+	}
+	if !transport.ReportsConnectFailure(dialer) {
+		heDialer.Resolve = transport.NewParallelHappyEyeballsResolveFunc(
+			func(ctx context.Context, hostname string) ([]netip.Addr, error) {
+				return resolveIPv4First(ctx, resolver, hostname)
+			},
+		)
+	}
+	if config.onFamilyStats == nil {
+		return heDialer, nil
+	}
+	return newFamilyStatsDialer(heDialer, config.onFamilyStats), nil
 }
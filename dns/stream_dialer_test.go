@@ -25,8 +25,9 @@ import (
 	"golang.org/x/net/dns/dnsmessage"
 )
 
-func TestNewStreamDialer(t *testing.T) {
-	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+// newLocalhostResolver returns a resolver that resolves any name to ::1 and 127.0.0.1.
+func newLocalhostResolver() Resolver {
+	return FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
 		resp := new(dnsmessage.Message)
 		resp.Header.Response = true
 		resp.Questions = []dnsmessage.Question{q}
@@ -46,6 +47,10 @@ func TestNewStreamDialer(t *testing.T) {
 		resp.Additionals = []dnsmessage.Resource{}
 		return resp, nil
 	})
+}
+
+func TestNewStreamDialer(t *testing.T) {
+	resolver := newLocalhostResolver()
 	addrs := []string{}
 	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
 		addrs = append(addrs, addr)
@@ -59,6 +64,80 @@ func TestNewStreamDialer(t *testing.T) {
 	require.Equal(t, []string{"[::1]:8080", "127.0.0.1:8080"}, addrs)
 }
 
+// proxyDialer is a [transport.StreamDialer] that succeeds before connecting to the destination, like Shadowsocks.
+type proxyDialer struct {
+	transport.FuncStreamDialer
+}
+
+func (d proxyDialer) ReportsConnectFailure() bool {
+	return false
+}
+
+func TestNewStreamDialer_NoConnectFailure(t *testing.T) {
+	addrs := []string{}
+	baseDialer := proxyDialer{func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		addrs = append(addrs, addr)
+		return nil, errors.New("not implemented")
+	}}
+	dialer, err := NewStreamDialer(newLocalhostResolver(), baseDialer)
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "localhost:8080")
+	require.Error(t, err)
+	require.Equal(t, []string{"127.0.0.1:8080"}, addrs)
+}
+
+func TestNewStreamDialer_NoConnectFailureIPv6Only(t *testing.T) {
+	ipv6Resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		if q.Type == dnsmessage.TypeA {
+			return &dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Questions: []dnsmessage.Question{q}}, nil
+		}
+		return newLocalhostResolver().Query(ctx, q)
+	})
+	addrs := []string{}
+	baseDialer := proxyDialer{func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		addrs = append(addrs, addr)
+		return nil, nil
+	}}
+	dialer, err := NewStreamDialer(ipv6Resolver, baseDialer)
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "localhost:8080")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[::1]:8080"}, addrs)
+}
+
+func TestNewStreamDialer_IPLiterals(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return nil, errors.New("IP literals must not be resolved")
+	})
+	for _, addr := range []string{"127.0.0.1:53", "[::1]:53", "[fe80::1%eth0]:53"} {
+		var dialed string
+		baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			dialed = addr
+			return nil, nil
+		})
+		dialer, err := NewStreamDialer(resolver, baseDialer)
+		require.NoError(t, err)
+		_, err = dialer.DialStream(context.Background(), addr)
+		require.NoError(t, err, addr)
+		require.Equal(t, addr, dialed)
+	}
+}
+
+func TestNewStreamDialer_ResolutionFailure(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return nil, errors.New("resolution failed")
+	})
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		// Dialing the host name would let the base dialer resolve it with the system resolver.
+		t.Errorf("unexpected dial to %v", addr)
+		return nil, nil
+	})
+	dialer, err := NewStreamDialer(resolver, baseDialer)
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "example.com:443")
+	require.Error(t, err)
+}
+
 func TestNewStreamDialer_NoResolver(t *testing.T) {
 	_, err := NewStreamDialer(nil, &transport.TCPDialer{})
 	require.Error(t, err)
@@ -68,3 +147,57 @@ func TestNewStreamDialer_NoDialer(t *testing.T) {
 	_, err := NewStreamDialer(FuncResolver(nil), nil)
 	require.Error(t, err)
 }
+
+func TestNewStreamDialer_FamilyStatsIPv6Failure(t *testing.T) {
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		if addr == "[::1]:8080" {
+			return nil, errors.New("IPv6 is broken")
+		}
+		return nil, nil
+	})
+	var statsList []FamilyStats
+	dialer, err := NewStreamDialer(newLocalhostResolver(), baseDialer, WithFamilyStats(func(stats FamilyStats) {
+		statsList = append(statsList, stats)
+	}))
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "localhost:8080")
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "localhost:8080")
+	require.NoError(t, err)
+	require.Equal(t, []FamilyStats{
+		{IPv4Wins: 1, IPv4FallbacksAfterIPv6Failure: 1},
+		{IPv4Wins: 2, IPv4FallbacksAfterIPv6Failure: 2},
+	}, statsList)
+}
+
+func TestNewStreamDialer_FamilyStatsSlowIPv6(t *testing.T) {
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		if addr == "[::1]:8080" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	})
+	var lastStats FamilyStats
+	dialer, err := NewStreamDialer(newLocalhostResolver(), baseDialer, WithFamilyStats(func(stats FamilyStats) {
+		lastStats = stats
+	}))
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "localhost:8080")
+	require.NoError(t, err)
+	require.Equal(t, FamilyStats{IPv4Wins: 1, IPv4WinsOverSlowIPv6: 1}, lastStats)
+}
+
+func TestNewStreamDialer_FamilyStatsFailure(t *testing.T) {
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return nil, errors.New("not implemented")
+	})
+	var lastStats FamilyStats
+	dialer, err := NewStreamDialer(newLocalhostResolver(), baseDialer, WithFamilyStats(func(stats FamilyStats) {
+		lastStats = stats
+	}))
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "localhost:8080")
+	require.Error(t, err)
+	require.Equal(t, FamilyStats{Failures: 1}, lastStats)
+}
@@ -0,0 +1,221 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resource record types for service binding, as per https://datatracker.ietf.org/doc/html/rfc9460.
+// The [dnsmessage] package doesn't define them, and returns their bodies as [dnsmessage.UnknownResource].
+const (
+	TypeSVCB  dnsmessage.Type = 64
+	TypeHTTPS dnsmessage.Type = 65
+)
+
+// SvcParamKey identifies a parameter of a [SVCBRecord].
+type SvcParamKey uint16
+
+// The SvcParamKeys defined in https://datatracker.ietf.org/doc/html/rfc9460#section-14.3.2.
+const (
+	SvcParamMandatory     SvcParamKey = 0
+	SvcParamALPN          SvcParamKey = 1
+	SvcParamNoDefaultALPN SvcParamKey = 2
+	SvcParamPort          SvcParamKey = 3
+	SvcParamIPv4Hint      SvcParamKey = 4
+	SvcParamECH           SvcParamKey = 5
+	SvcParamIPv6Hint      SvcParamKey = 6
+)
+
+// SVCBRecord is the parsed body of a SVCB or HTTPS resource record.
+type SVCBRecord struct {
+	// Priority is 0 for AliasMode records, and the preference of the endpoint otherwise. Lower is preferred.
+	Priority uint16
+	// Target is the fully-qualified domain name of the endpoint, or "." to indicate the owner name of the record.
+	Target string
+	// ALPN is the list of the protocols supported by the endpoint, in addition to the default ones, unless
+	// NoDefaultALPN is set.
+	ALPN          []string
+	NoDefaultALPN bool
+	// Port is the port of the endpoint, or zero if the default port for the scheme should be used.
+	Port     uint16
+	IPv4Hint []netip.Addr
+	IPv6Hint []netip.Addr
+	// ECHConfigList is the ECHConfigList to use for Encrypted Client Hello, or nil if not supported.
+	ECHConfigList []byte
+	// Params has the raw value of all the parameters, including the ones parsed above and the unknown ones.
+	Params map[SvcParamKey][]byte
+}
+
+// IsAlias returns whether the record is in AliasMode, which means the service is at Target.
+func (r *SVCBRecord) IsAlias() bool {
+	return r.Priority == 0
+}
+
+// ParseSVCBResource parses the body of a SVCB or HTTPS resource, as returned by [Resolver.Query].
+func ParseSVCBResource(body dnsmessage.ResourceBody) (*SVCBRecord, error) {
+	unknown, ok := body.(*dnsmessage.UnknownResource)
+	if !ok || (unknown.Type != TypeSVCB && unknown.Type != TypeHTTPS) {
+		return nil, fmt.Errorf("resource body is not SVCB or HTTPS: %T", body)
+	}
+	return ParseSVCB(unknown.Data)
+}
+
+// ParseSVCB parses the wire format of the RDATA of a SVCB or HTTPS record, as per
+// https://datatracker.ietf.org/doc/html/rfc9460#section-2.2.
+func ParseSVCB(data []byte) (*SVCBRecord, error) {
+	if len(data) < 2 {
+		return nil, errors.New("record is too short")
+	}
+	record := &SVCBRecord{Priority: binary.BigEndian.Uint16(data), Params: map[SvcParamKey][]byte{}}
+	target, n, err := parseUncompressedName(data[2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+	record.Target = target
+	params := data[2+n:]
+	lastKey := -1
+	for len(params) > 0 {
+		if len(params) < 4 {
+			return nil, errors.New("parameter is too short")
+		}
+		key := SvcParamKey(binary.BigEndian.Uint16(params))
+		valueLen := int(binary.BigEndian.Uint16(params[2:]))
+		if len(params) < 4+valueLen {
+			return nil, fmt.Errorf("value of parameter %v is too short", key)
+		}
+		if int(key) <= lastKey {
+			return nil, fmt.Errorf("parameter %v is out of order", key)
+		}
+		lastKey = int(key)
+		value := params[4 : 4+valueLen]
+		params = params[4+valueLen:]
+		record.Params[key] = value
+		if err := record.setParam(key, value); err != nil {
+			return nil, fmt.Errorf("invalid parameter %v: %w", key, err)
+		}
+	}
+	return record, nil
+}
+
+// setParam sets the field for the parameter, if it's a known one.
+func (r *SVCBRecord) setParam(key SvcParamKey, value []byte) error {
+	switch key {
+	case SvcParamALPN:
+		for len(value) > 0 {
+			idLen := int(value[0])
+			if idLen == 0 || len(value) < 1+idLen {
+				return errors.New("invalid protocol id")
+			}
+			r.ALPN = append(r.ALPN, string(value[1:1+idLen]))
+			value = value[1+idLen:]
+		}
+	case SvcParamNoDefaultALPN:
+		if len(value) != 0 {
+			return errors.New("value must be empty")
+		}
+		r.NoDefaultALPN = true
+	case SvcParamPort:
+		if len(value) != 2 {
+			return errors.New("value must have 2 bytes")
+		}
+		r.Port = binary.BigEndian.Uint16(value)
+	case SvcParamIPv4Hint:
+		if len(value) == 0 || len(value)%4 != 0 {
+			return errors.New("value must be a list of IPv4 addresses")
+		}
+		for ; len(value) > 0; value = value[4:] {
+			r.IPv4Hint = append(r.IPv4Hint, netip.AddrFrom4([4]byte(value[:4])))
+		}
+	case SvcParamECH:
+		r.ECHConfigList = value
+	case SvcParamIPv6Hint:
+		if len(value) == 0 || len(value)%16 != 0 {
+			return errors.New("value must be a list of IPv6 addresses")
+		}
+		for ; len(value) > 0; value = value[16:] {
+			r.IPv6Hint = append(r.IPv6Hint, netip.AddrFrom16([16]byte(value[:16])))
+		}
+	}
+	return nil
+}
+
+// parseUncompressedName parses a domain name in wire format without compression, as required for the
+// SVCB target, returning the name and the number of bytes consumed.
+func parseUncompressedName(data []byte) (string, int, error) {
+	var labels []string
+	offset := 0
+	for {
+		if offset >= len(data) {
+			return "", 0, errors.New("name is truncated")
+		}
+		labelLen := int(data[offset])
+		offset++
+		if labelLen == 0 {
+			break
+		}
+		if labelLen > 63 {
+			return "", 0, errors.New("label is too long or compressed")
+		}
+		if offset+labelLen > len(data) {
+			return "", 0, errors.New("label is truncated")
+		}
+		labels = append(labels, string(data[offset:offset+labelLen]))
+		offset += labelLen
+	}
+	return strings.Join(labels, ".") + ".", offset, nil
+}
+
+// QuerySVCB queries resolver for the records of type qtype ([TypeSVCB] or [TypeHTTPS]) of domain, and returns the
+// parsed records sorted by priority. Use [TypeHTTPS] to get the ALPN and the ECH config for HTTPS origins.
+func QuerySVCB(ctx context.Context, resolver Resolver, domain string, qtype dnsmessage.Type) ([]*SVCBRecord, error) {
+	if qtype != TypeSVCB && qtype != TypeHTTPS {
+		return nil, fmt.Errorf("query type must be SVCB or HTTPS, found %v", qtype)
+	}
+	q, err := NewQuestion(domain, qtype)
+	if err != nil {
+		return nil, err
+	}
+	response, err := resolver.Query(ctx, *q)
+	if err != nil {
+		return nil, err
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, &RCodeError{response.RCode}
+	}
+	var records []*SVCBRecord
+	for _, answer := range response.Answers {
+		if answer.Header.Type != qtype {
+			continue
+		}
+		record, err := ParseSVCBResource(answer.Body)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Priority < records[j].Priority
+	})
+	return records, nil
+}
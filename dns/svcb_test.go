@@ -0,0 +1,104 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// httpsRData is an HTTPS record with priority 1, target "." and the alpn, port, ipv4hint, ech and ipv6hint params.
+var httpsRData = []byte{
+	0x00, 0x01, // Priority
+	0x00,                                             // Target "."
+	0x00, 0x01, 0x00, 0x06, 2, 'h', '3', 2, 'h', '2', // alpn=h3,h2
+	0x00, 0x03, 0x00, 0x02, 0x20, 0xfb, // port=8443
+	0x00, 0x04, 0x00, 0x08, 192, 0, 2, 1, 192, 0, 2, 2, // ipv4hint=192.0.2.1,192.0.2.2
+	0x00, 0x05, 0x00, 0x03, 0xfe, 0x0d, 0x00, // ech
+	0x00, 0x06, 0x00, 0x10, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, // ipv6hint=2001:db8::1
+	0x01, 0x00, 0x00, 0x01, 0xaa, // key256=0xaa
+}
+
+func TestParseSVCB(t *testing.T) {
+	record, err := ParseSVCB(httpsRData)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), record.Priority)
+	require.False(t, record.IsAlias())
+	require.Equal(t, ".", record.Target)
+	require.Equal(t, []string{"h3", "h2"}, record.ALPN)
+	require.False(t, record.NoDefaultALPN)
+	require.Equal(t, uint16(8443), record.Port)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("192.0.2.2")}, record.IPv4Hint)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::1")}, record.IPv6Hint)
+	require.Equal(t, []byte{0xfe, 0x0d, 0x00}, record.ECHConfigList)
+	require.Equal(t, []byte{0xaa}, record.Params[256])
+	require.Len(t, record.Params, 6)
+}
+
+func TestParseSVCB_Alias(t *testing.T) {
+	record, err := ParseSVCB([]byte{0, 0, 3, 'c', 'd', 'n', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0})
+	require.NoError(t, err)
+	require.True(t, record.IsAlias())
+	require.Equal(t, "cdn.example.", record.Target)
+	require.Empty(t, record.Params)
+}
+
+func TestParseSVCB_Invalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":             {},
+		"truncated target":  {0, 1, 3, 'c', 'd'},
+		"compressed target": {0, 1, 0xc0, 0x0c},
+		"truncated param":   {0, 1, 0, 0, 1, 0, 4, 2, 'h'},
+		"out of order":      {0, 1, 0, 0, 3, 0, 2, 0, 80, 0, 1, 0, 3, 2, 'h', '2'},
+		"duplicate":         {0, 1, 0, 0, 3, 0, 2, 0, 80, 0, 3, 0, 2, 0, 81},
+		"bad port":          {0, 1, 0, 0, 3, 0, 1, 80},
+		"bad ipv4hint":      {0, 1, 0, 0, 4, 0, 3, 1, 2, 3},
+		"bad alpn":          {0, 1, 0, 0, 1, 0, 1, 0},
+	} {
+		_, err := ParseSVCB(data)
+		require.Error(t, err, name)
+	}
+}
+
+func TestQuerySVCB(t *testing.T) {
+	resolver := FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		require.Equal(t, TypeHTTPS, q.Type)
+		// Go through the wire format, to check that dnsmessage keeps the record data.
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+		require.NoError(t, builder.StartQuestions())
+		require.NoError(t, builder.Question(q))
+		require.NoError(t, builder.StartAnswers())
+		header := dnsmessage.ResourceHeader{Name: q.Name, Type: TypeHTTPS, Class: q.Class}
+		require.NoError(t, builder.UnknownResource(header, dnsmessage.UnknownResource{Type: TypeHTTPS, Data: httpsRData}))
+		require.NoError(t, builder.UnknownResource(header, dnsmessage.UnknownResource{Type: TypeHTTPS, Data: []byte{0, 0, 0}}))
+		buf, err := builder.Finish()
+		require.NoError(t, err)
+		var msg dnsmessage.Message
+		require.NoError(t, msg.Unpack(buf))
+		return &msg, nil
+	})
+	records, err := QuerySVCB(context.Background(), resolver, "example.com", TypeHTTPS)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.True(t, records[0].IsAlias())
+	require.Equal(t, []string{"h3", "h2"}, records[1].ALPN)
+
+	_, err = QuerySVCB(context.Background(), resolver, "example.com", dnsmessage.TypeA)
+	require.Error(t, err)
+}
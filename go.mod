@@ -11,6 +11,7 @@ require (
 	github.com/things-go/go-socks5 v0.0.5
 	golang.org/x/crypto v0.18.0
 	golang.org/x/net v0.20.0
+	lukechampine.com/blake3 v1.2.1
 )
 
 require (
@@ -22,6 +23,7 @@ require (
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
 	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 	github.com/kevinburke/ssh_config v0.0.0-20190725054713-01f96b0aa0cd // indirect
+	github.com/klauspost/cpuid/v2 v2.0.12 // indirect
 	github.com/mitchellh/go-homedir v1.1.0 // indirect
 	github.com/otiai10/copy v1.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -30,6 +30,13 @@ To create a [network.PacketProxy] that handles DNS requests locally:
 
 This `proxy` can then be used in, for example, lwip2transport.ConfigureDevice.
 
+To answer the A and AAAA questions about some domain names directly, without a TCP round trip, create the
+[network.PacketProxy] with a hosts map instead. The other DNS requests are still truncated:
+
+	proxy, err := dnstruncate.NewPacketProxyWithHosts(map[string][]netip.Addr{
+		"proxy.example.com": {netip.MustParseAddr("203.0.113.1")},
+	})
+
 [go-tun2socks' dnsfallback.NewUDPHandler]: https://github.com/eycorsican/go-tun2socks/blob/master/proxy/dnsfallback/udp.go
 */
 package dnstruncate
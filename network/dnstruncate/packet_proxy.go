@@ -19,10 +19,12 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"strings"
 	"sync/atomic"
 
 	"github.com/Jigsaw-Code/outline-sdk/internal/slicepool"
 	"github.com/Jigsaw-Code/outline-sdk/network"
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 // From [RFC 1035], the DNS message header contains the following fields:
@@ -59,6 +61,8 @@ const (
 	dnsQDCntEndByte    = 5           // The ending byte (inclusive) of QDCOUNT
 	dnsARCntStartByte  = 6           // The starting byte of ANCOUNT
 	dnsARCntEndByte    = 7           // The ending byte (inclusive) of ANCOUNT
+
+	hostsAnswerTTL = 60 // The TTL in seconds of the answers from the hosts map
 )
 
 // packetBufferPool is used to create buffers to modify DNS requests
@@ -68,6 +72,8 @@ var packetBufferPool = slicepool.MakePool(dnsUdpMaxMsgLen)
 //
 // Multiple goroutines may invoke methods on a dnsTruncateProxy simultaneously.
 type dnsTruncateProxy struct {
+	// hosts maps lowercase fully-qualified domain names to their addresses.
+	hosts map[string][]netip.Addr
 }
 
 // dnsTruncateRequestHandler is a network.PacketRequestSender that handles DNS requests in UDP protocol locally,
@@ -78,6 +84,7 @@ type dnsTruncateProxy struct {
 type dnsTruncateRequestHandler struct {
 	closed     atomic.Bool
 	respWriter network.PacketResponseReceiver
+	hosts      map[string][]netip.Addr
 }
 
 // Compilation guard against interface implementation
@@ -94,6 +101,31 @@ func NewPacketProxy() (network.PacketProxy, error) {
 	return &dnsTruncateProxy{}, nil
 }
 
+// NewPacketProxyWithHosts is like [NewPacketProxy], but it answers the A and AAAA questions about the domain names in
+// hosts directly over UDP, with the given addresses. Other DNS requests get the TC (truncated) bit set as usual.
+//
+// The domain names are case-insensitive and may have a trailing dot. If a domain name has no address of the requested
+// type, the answer is empty.
+func NewPacketProxyWithHosts(hosts map[string][]netip.Addr) (network.PacketProxy, error) {
+	normalized := make(map[string][]netip.Addr, len(hosts))
+	for name, addrs := range hosts {
+		if name == "" {
+			return nil, errors.New("host name must not be empty")
+		}
+		normalized[normalizeHostName(name)] = append(normalized[normalizeHostName(name)], addrs...)
+	}
+	return &dnsTruncateProxy{hosts: normalized}, nil
+}
+
+// normalizeHostName returns the lowercase fully-qualified form of name.
+func normalizeHostName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
 // NewSession implements [network.PacketProxy].NewSession(). It creates a new [network.PacketRequestSender] that will
 // set the TC (truncated) bit and write the response to `respWriter`.
 func (p *dnsTruncateProxy) NewSession(respWriter network.PacketResponseReceiver) (network.PacketRequestSender, error) {
@@ -102,6 +134,7 @@ func (p *dnsTruncateProxy) NewSession(respWriter network.PacketResponseReceiver)
 	}
 	return &dnsTruncateRequestHandler{
 		respWriter: respWriter,
+		hosts:      p.hosts,
 	}, nil
 }
 
@@ -135,6 +168,15 @@ func (h *dnsTruncateRequestHandler) WriteTo(p []byte, destination netip.AddrPort
 	buf := slice.Acquire()
 	defer slice.Release()
 
+	if len(h.hosts) > 0 {
+		if resp, ok := h.answerFromHosts(p, buf[:0]); ok {
+			if _, err := h.respWriter.WriteFrom(resp, net.UDPAddrFromAddrPort(destination)); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+
 	// We need to copy p into buf because "WriteTo must not modify p, even temporarily".
 	n := copy(buf, p)
 
@@ -152,3 +194,63 @@ func (h *dnsTruncateRequestHandler) WriteTo(p []byte, destination netip.AddrPort
 
 	return h.respWriter.WriteFrom(buf[:n], net.UDPAddrFromAddrPort(destination))
 }
+
+// answerFromHosts appends to buf the response to the DNS request in p, if it's a single A or AAAA question about a
+// domain name in the hosts map. It returns false if the request can't be answered from the hosts map.
+func (h *dnsTruncateRequestHandler) answerFromHosts(p []byte, buf []byte) ([]byte, bool) {
+	var parser dnsmessage.Parser
+	reqHeader, err := parser.Start(p)
+	if err != nil || reqHeader.Response || reqHeader.OpCode != 0 {
+		return nil, false
+	}
+	questions, err := parser.AllQuestions()
+	if err != nil || len(questions) != 1 {
+		return nil, false
+	}
+	q := questions[0]
+	if q.Class != dnsmessage.ClassINET || (q.Type != dnsmessage.TypeA && q.Type != dnsmessage.TypeAAAA) {
+		return nil, false
+	}
+	addrs, ok := h.hosts[strings.ToLower(q.Name.String())]
+	if !ok {
+		return nil, false
+	}
+
+	builder := dnsmessage.NewBuilder(buf, dnsmessage.Header{
+		ID:                 reqHeader.ID,
+		Response:           true,
+		Authoritative:      true,
+		RecursionDesired:   reqHeader.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              dnsmessage.RCodeSuccess,
+	})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, false
+	}
+	if err := builder.Question(q); err != nil {
+		return nil, false
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, false
+	}
+	rrHeader := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: hostsAnswerTTL}
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		switch {
+		case q.Type == dnsmessage.TypeA && addr.Is4():
+			err = builder.AResource(rrHeader, dnsmessage.AResource{A: addr.As4()})
+		case q.Type == dnsmessage.TypeAAAA && addr.Is6():
+			err = builder.AAAAResource(rrHeader, dnsmessage.AAAAResource{AAAA: addr.As16()})
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+	resp, err := builder.Finish()
+	if err != nil || len(resp) > dnsUdpMaxMsgLen {
+		// Let the caller retry over TCP if the answer doesn't fit in a UDP message.
+		return nil, false
+	}
+	return resp, true
+}
@@ -141,6 +141,67 @@ func TestMultipleWriteToRaceCondition(t *testing.T) {
 	require.NoError(t, session.Close())
 }
 
+func TestHostsAreAnsweredDirectly(t *testing.T) {
+	p, err := NewPacketProxyWithHosts(map[string][]netip.Addr{
+		"Proxy.Example.com": {netip.MustParseAddr("1.2.3.4"), netip.MustParseAddr("2001:db8::1")},
+		"v4.example.com.":   {netip.MustParseAddr("5.6.7.8")},
+	})
+	require.NoError(t, err)
+	session := newInstantDNSSessionWithProxyForTest(t, p)
+	defer session.Close()
+	resolverAddr := netip.MustParseAddrPort("1.2.3.4:53")
+
+	queryRaw := func(name string, qtype layers.DNSType) []byte {
+		req := layers.DNS{
+			ID:        0x1357,
+			RD:        true,
+			QDCount:   1,
+			Questions: []layers.DNSQuestion{{Name: []byte(name), Type: qtype, Class: layers.DNSClassIN}},
+		}
+		buf := gopacket.NewSerializeBuffer()
+		require.NoError(t, req.SerializeTo(buf, gopacket.SerializeOptions{}))
+		respBytes, err := session.Query(buf.Bytes(), resolverAddr)
+		require.NoError(t, err)
+		return respBytes
+	}
+	query := func(name string, qtype layers.DNSType) *layers.DNS {
+		respBytes := queryRaw(name, qtype)
+		var resp layers.DNS
+		require.NoError(t, resp.DecodeFromBytes(respBytes, gopacket.NilDecodeFeedback))
+		require.Equal(t, uint16(0x1357), resp.ID)
+		require.True(t, resp.QR)
+		return &resp
+	}
+
+	resp := query("proxy.example.com", layers.DNSTypeA)
+	require.False(t, resp.TC)
+	require.Equal(t, layers.DNSResponseCodeNoErr, resp.ResponseCode)
+	require.Len(t, resp.Answers, 1)
+	require.Equal(t, "1.2.3.4", resp.Answers[0].IP.String())
+
+	resp = query("PROXY.example.com", layers.DNSTypeAAAA)
+	require.False(t, resp.TC)
+	require.Len(t, resp.Answers, 1)
+	require.Equal(t, "2001:db8::1", resp.Answers[0].IP.String())
+
+	// No IPv6 addresses, so the answer is empty.
+	resp = query("v4.example.com", layers.DNSTypeAAAA)
+	require.False(t, resp.TC)
+	require.Equal(t, layers.DNSResponseCodeNoErr, resp.ResponseCode)
+	require.Empty(t, resp.Answers)
+
+	// Names not in the map and other types are truncated.
+	respBytes := queryRaw("www.google.com", layers.DNSTypeA)
+	require.NotZero(t, respBytes[dnsUdpAnswerByte]&dnsUdpTruncatedBit)
+	respBytes = queryRaw("proxy.example.com", layers.DNSTypeMX)
+	require.NotZero(t, respBytes[dnsUdpAnswerByte]&dnsUdpTruncatedBit)
+}
+
+func TestNewPacketProxyWithEmptyHostNameReturnsError(t *testing.T) {
+	_, err := NewPacketProxyWithHosts(map[string][]netip.Addr{"": {netip.MustParseAddr("1.2.3.4")}})
+	require.Error(t, err)
+}
+
 /********** Test utilities **********/
 
 func createProxyForTest(t *testing.T) network.PacketProxy {
@@ -217,7 +278,10 @@ type instantPacketSession struct {
 }
 
 func newInstantDNSSessionForTest(t *testing.T) *instantPacketSession {
-	p := createProxyForTest(t)
+	return newInstantDNSSessionWithProxyForTest(t, createProxyForTest(t))
+}
+
+func newInstantDNSSessionWithProxyForTest(t *testing.T, p network.PacketProxy) *instantPacketSession {
 	s := &instantPacketSession{
 		t: t,
 	}
@@ -0,0 +1,198 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ioDevice is an [IPDevice] that reads and writes IP packets from an [io.ReadWriteCloser].
+type ioDevice struct {
+	rw     io.ReadWriteCloser
+	mtu    int
+	closed atomic.Bool
+
+	// readMu protects readBuf and pending. readBuf receives the data that doesn't go directly into the caller's
+	// buffer, and pending holds the bytes of readBuf that haven't been returned yet.
+	readMu  sync.Mutex
+	readBuf []byte
+	pending []byte
+}
+
+var _ IPDevice = (*ioDevice)(nil)
+
+// NewIODevice creates an [IPDevice] from rw, which reads and writes IP packets, like the file descriptor of a TUN
+// device (e.g. the VPN file descriptor given by Android or iOS). mtu is the maximum size of the packets.
+//
+// Each Read returns exactly one IP packet. rw may return one packet per Read, like a TUN device, or split and merge
+// packets, like a stream: the device uses the length in the IPv4 or IPv6 header to find the packet boundaries. Read
+// discards the bytes of the packets that don't fit in the buffer, and returns [ErrMsgSize] if a packet is bigger than
+// mtu. It returns an error if the data is not an IP packet. In both cases Read drops the data it has read, so a stream
+// may lose the packet boundaries.
+//
+// Each Write writes b as a single packet, and rejects packets bigger than mtu with [ErrMsgSize]. After Close, Read
+// returns [io.EOF] and Write returns [ErrClosed].
+func NewIODevice(rw io.ReadWriteCloser, mtu int) (IPDevice, error) {
+	if rw == nil {
+		return nil, errors.New("rw must not be nil")
+	}
+	if mtu <= 0 {
+		return nil, errors.New("mtu must be greater than 0")
+	}
+	return &ioDevice{rw: rw, mtu: mtu}, nil
+}
+
+func (d *ioDevice) MTU() int {
+	return d.mtu
+}
+
+func (d *ioDevice) Read(p []byte) (int, error) {
+	if d.closed.Load() {
+		return 0, io.EOF
+	}
+	d.readMu.Lock()
+	defer d.readMu.Unlock()
+
+	if len(d.pending) == 0 && len(p) >= d.mtu {
+		// Fast path for readers that return one packet per Read, like TUN devices.
+		n, err := d.read(p)
+		if size, _ := ipPacketSize(p[:n]); n > 0 && size == n {
+			return n, err
+		}
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		d.pending = append(d.buffer()[:0], p[:n]...)
+	}
+	for {
+		size, err := ipPacketSize(d.pending)
+		if err != nil {
+			d.pending = nil
+			return 0, err
+		}
+		if size > d.mtu {
+			d.pending = nil
+			return 0, ErrMsgSize
+		}
+		if size > 0 && len(d.pending) >= size {
+			n := copy(p, d.pending[:size])
+			d.pending = d.pending[size:]
+			return n, nil
+		}
+		// We need more data. Move the pending bytes to the start of the buffer to make room.
+		buf := d.buffer()
+		d.pending = buf[:copy(buf, d.pending)]
+		n, err := d.read(buf[len(d.pending):])
+		d.pending = buf[:len(d.pending)+n]
+		if err != nil {
+			if err == io.EOF && len(d.pending) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			d.pending = nil
+			return 0, err
+		}
+	}
+}
+
+// buffer returns readBuf, allocating it if needed. The caller must hold readMu.
+func (d *ioDevice) buffer() []byte {
+	if d.readBuf == nil {
+		// The buffer must fit the headers we need to find the packet size, even if the MTU is smaller.
+		size := d.mtu
+		if size < ipv6HeaderSize {
+			size = ipv6HeaderSize
+		}
+		d.readBuf = make([]byte, size)
+	}
+	return d.readBuf
+}
+
+// read reads from rw, skipping empty reads, and returns [io.EOF] if the device is closed.
+func (d *ioDevice) read(p []byte) (int, error) {
+	for {
+		n, err := d.rw.Read(p)
+		if d.closed.Load() {
+			return 0, io.EOF
+		}
+		if n == 0 && err == nil {
+			// Not a packet, try again.
+			continue
+		}
+		return n, err
+	}
+}
+
+// ipPacketSize returns the size of the IP packet at the start of b, according to its header, or 0 if b doesn't have
+// enough bytes to tell.
+func ipPacketSize(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	switch version := b[0] >> 4; version {
+	case 4:
+		if len(b) < 4 {
+			return 0, nil
+		}
+		size := int(binary.BigEndian.Uint16(b[2:4]))
+		if size < ipv4MinHeaderSize {
+			return 0, fmt.Errorf("invalid IPv4 total length %v", size)
+		}
+		return size, nil
+	case 6:
+		if len(b) < 6 {
+			return 0, nil
+		}
+		return ipv6HeaderSize + int(binary.BigEndian.Uint16(b[4:6])), nil
+	default:
+		return 0, fmt.Errorf("invalid IP version %v", version)
+	}
+}
+
+const (
+	ipv4MinHeaderSize = 20
+	ipv6HeaderSize    = 40
+)
+
+func (d *ioDevice) Write(b []byte) (int, error) {
+	if d.closed.Load() {
+		return 0, ErrClosed
+	}
+	if len(b) > d.mtu {
+		return 0, ErrMsgSize
+	}
+	n, err := d.rw.Write(b)
+	if d.closed.Load() {
+		return n, ErrClosed
+	}
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+func (d *ioDevice) Close() error {
+	if d.closed.Swap(true) {
+		return ErrClosed
+	}
+	return d.rw.Close()
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIODeviceReadWrite(t *testing.T) {
+	devSide, remoteSide := net.Pipe()
+	defer remoteSide.Close()
+	dev, err := NewIODevice(devSide, 48)
+	require.NoError(t, err)
+	require.Equal(t, 48, dev.MTU())
+
+	packet1 := newTestIPv4Packet("packet1")
+	packet2 := newTestIPv4Packet("packet02")
+	packet3 := newTestIPv6Packet("packet3")
+	go func() {
+		remoteSide.Write(packet1)
+		remoteSide.Write(packet2)
+		remoteSide.Write(packet3)
+	}()
+	buf := make([]byte, 48)
+	n, err := dev.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, packet1, buf[:n])
+
+	// The excess bytes of the packet are discarded.
+	n, err = dev.Read(buf[:3])
+	require.NoError(t, err)
+	require.Equal(t, packet2[:3], buf[:n])
+	n, err = dev.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, packet3, buf[:n])
+
+	go func() {
+		n, err := dev.Write([]byte("reply"))
+		require.NoError(t, err)
+		require.Equal(t, 5, n)
+	}()
+	n, err = remoteSide.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "reply", string(buf[:n]))
+
+	n, err = dev.Write(make([]byte, 49))
+	require.ErrorIs(t, err, ErrMsgSize)
+	require.Zero(t, n)
+}
+
+func TestIODeviceReadStream(t *testing.T) {
+	devSide, remoteSide := net.Pipe()
+	defer remoteSide.Close()
+	dev, err := NewIODevice(devSide, 1500)
+	require.NoError(t, err)
+
+	packet1 := newTestIPv4Packet("packet1")
+	packet2 := newTestIPv6Packet("packet02")
+	packet3 := newTestIPv4Packet("packet3")
+	go func() {
+		// Two packets in a single write, then a packet split across writes, with a partial header.
+		remoteSide.Write(append(append([]byte{}, packet1...), packet2...))
+		remoteSide.Write(packet3[:2])
+		remoteSide.Write(packet3[2:10])
+		remoteSide.Write(packet3[10:])
+	}()
+	buf := make([]byte, 1500)
+	for _, packet := range [][]byte{packet1, packet2, packet3} {
+		n, err := dev.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, packet, buf[:n])
+	}
+
+	// The stream ends in the middle of a packet.
+	go func() {
+		remoteSide.Write(packet1[:10])
+		remoteSide.Close()
+	}()
+	_, err = dev.Read(buf)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestIODeviceReadInvalidPacket(t *testing.T) {
+	devSide, remoteSide := net.Pipe()
+	defer remoteSide.Close()
+	dev, err := NewIODevice(devSide, 30)
+	require.NoError(t, err)
+
+	go func() {
+		remoteSide.Write([]byte{0xff, 0xff, 0xff, 0xff})
+		remoteSide.Write(newTestIPv4Packet("packet"))
+		remoteSide.Write(newTestIPv4Packet("a packet bigger than the MTU"))
+	}()
+	buf := make([]byte, 30)
+	_, err = dev.Read(buf)
+	require.ErrorContains(t, err, "invalid IP version")
+	// The reader preserves the packet boundaries, so the next packet is read correctly.
+	n, err := dev.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, newTestIPv4Packet("packet"), buf[:n])
+	_, err = dev.Read(buf)
+	require.ErrorIs(t, err, ErrMsgSize)
+}
+
+func TestIODeviceClose(t *testing.T) {
+	devSide, remoteSide := net.Pipe()
+	defer remoteSide.Close()
+	dev, err := NewIODevice(devSide, 1500)
+	require.NoError(t, err)
+
+	readErr := make(chan error)
+	go func() {
+		_, err := dev.Read(make([]byte, 1500))
+		readErr <- err
+	}()
+	require.NoError(t, dev.Close())
+	require.ErrorIs(t, <-readErr, io.EOF)
+
+	_, err = dev.Read(make([]byte, 1500))
+	require.ErrorIs(t, err, io.EOF)
+	_, err = dev.Write([]byte("packet"))
+	require.ErrorIs(t, err, ErrClosed)
+	require.ErrorIs(t, dev.Close(), ErrClosed)
+}
+
+func TestNewIODeviceInvalidArguments(t *testing.T) {
+	_, err := NewIODevice(nil, 1500)
+	require.Error(t, err)
+	devSide, _ := net.Pipe()
+	_, err = NewIODevice(devSide, 0)
+	require.Error(t, err)
+}
+
+// newTestIPv4Packet returns an IPv4 packet with a minimal header and the given payload.
+func newTestIPv4Packet(payload string) []byte {
+	packet := make([]byte, 20, 20+len(payload))
+	packet[0] = 0x45
+	binary.BigEndian.PutUint16(packet[2:4], uint16(20+len(payload)))
+	return append(packet, payload...)
+}
+
+// newTestIPv6Packet returns an IPv6 packet with the given payload.
+func newTestIPv6Packet(payload string) []byte {
+	packet := make([]byte, 40, 40+len(payload))
+	packet[0] = 0x60
+	binary.BigEndian.PutUint16(packet[4:6], uint16(len(payload)))
+	return append(packet, payload...)
+}
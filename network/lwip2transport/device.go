@@ -53,7 +53,8 @@ var inst *lwIPDevice = nil
 // [lwIP library] to perform the translation.
 //
 // LwIP device must be a singleton object due to limitations in [lwIP library]. If you try to call ConfigureDevice more
-// than once, we will Close the previous device and reconfigure it.
+// than once, we will Close the previous device and reconfigure it. You can also Close the device and call
+// ConfigureDevice again later, for example to turn a VPN off and on.
 //
 // To use a LwIP device:
 //  1. Call [ConfigureDevice] with two handlers for TCP and UDP traffic.
@@ -75,7 +76,7 @@ func ConfigureDevice(sd transport.StreamDialer, pp network.PacketProxy) (network
 	defer instMu.Unlock()
 
 	if inst != nil {
-		inst.Close()
+		inst.closeLocked()
 	}
 	inst = &lwIPDevice{
 		tcp:   newTCPHandler(sd),
@@ -94,17 +95,32 @@ func ConfigureDevice(sd transport.StreamDialer, pp network.PacketProxy) (network
 
 // Close implements [io.Closer] and [network.IPDevice]. It closes the device, rendering it unusable for I/O.
 //
+// Close aborts the TCP connections and closes the UDP sessions of this device. Closing a device that has been replaced
+// by a newer call to [ConfigureDevice] does nothing, since it's already closed.
+//
 // Close does not close other objects that are passed to this device, such as the [transport.StreamDialer],
 // [transport.PacketListener] or [io.Writer]. You are responsible for closing these objects yourself.
 func (d *lwIPDevice) Close() error {
+	instMu.Lock()
+	defer instMu.Unlock()
+	return d.closeLocked()
+}
+
+// closeLocked closes the device. The caller must hold instMu.
+func (d *lwIPDevice) closeLocked() error {
 	// make sure we don't close the channel twice
 	select {
 	case <-d.done:
 		return nil
 	default:
-		close(d.done)
-		return d.stack.Close()
 	}
+	close(d.done)
+	if inst == d {
+		inst = nil
+	}
+	err := d.stack.Close()
+	d.udp.closeAll()
+	return err
 }
 
 // MTU implements [network.IPDevice]. It returns the maximum buffer size of a single IP packet that can be processed by
@@ -17,8 +17,10 @@ package lwip2transport
 import (
 	"context"
 	"errors"
+	"io"
 	"net"
 	"os"
+	"sync"
 	"syscall"
 	"testing"
 
@@ -27,6 +29,47 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// TestReconfigureAfterClose must run before TestStackClosedWriteError, which closes the lwIP stack without closing
+// the device.
+func TestReconfigureAfterClose(t *testing.T) {
+	h := &errTcpUdpHandler{err: errors.New("not supported")}
+	first, err := ConfigureDevice(h, h)
+	require.NoError(t, err)
+	require.NoError(t, first.Close())
+	_, err = first.Write([]byte{0x01})
+	require.ErrorIs(t, err, network.ErrClosed)
+
+	second, err := ConfigureDevice(h, h)
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+	_, err = second.Write([]byte{0x01})
+	require.NotErrorIs(t, err, network.ErrClosed)
+
+	// Closing the old device again must not affect the new one.
+	require.NoError(t, first.Close())
+	_, err = second.Write([]byte{0x01})
+	require.NotErrorIs(t, err, network.ErrClosed)
+
+	// Reconfiguring closes the current device.
+	third, err := ConfigureDevice(h, h)
+	require.NoError(t, err)
+	_, err = second.Write([]byte{0x01})
+	require.ErrorIs(t, err, network.ErrClosed)
+
+	// Concurrent Close calls are safe.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			third.Close()
+		}()
+	}
+	wg.Wait()
+	_, err = third.Read(make([]byte, third.MTU()))
+	require.ErrorIs(t, err, io.EOF)
+}
+
 func TestStackClosedWriteError(t *testing.T) {
 	h := &errTcpUdpHandler{err: errors.New("not supported")}
 	t2s := reConfigurelwIPDeviceForTest(t, h, h)
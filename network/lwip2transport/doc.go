@@ -15,7 +15,7 @@
 /*
 The network/lwip2transport package translates between IP packets and TCP/UDP protocols. It uses a [modified lwIP go
 library], which is based on the original [lwIP library] (A Lightweight TCP/IP stack). The device is singleton, so only
-one instance can be created per process.
+one instance can be used at a time per process.
 
 To configure the instance with TCP/UDP handlers:
 
@@ -25,6 +25,14 @@ To configure the instance with TCP/UDP handlers:
 		// handle error
 	}
 
+The device lives until you Close it or call [ConfigureDevice] again, which closes the previous device first. A closed
+device can't be reused, but you can call [ConfigureDevice] again to get a new one, for example when a VPN is turned off
+and on:
+
+	t2s.Close()
+	// Later, with new handlers
+	t2s, err = lwip2transport.ConfigureDevice(tcpHandler, udpHandler)
+
 [modified lwIP go library]: https://github.com/eycorsican/go-tun2socks
 [lwIP library]: https://savannah.nongnu.org/projects/lwip/
 */
@@ -57,6 +57,7 @@ func (h *udpHandler) ReceiveTo(tunConn lwip.UDPConn, data []byte, destAddr *net.
 	reqSender, ok := h.senders[laddr]
 	if !ok {
 		if reqSender, err = h.newSession(tunConn); err != nil {
+			h.mu.Unlock()
 			return
 		}
 		h.senders[laddr] = reqSender
@@ -67,8 +68,8 @@ func (h *udpHandler) ReceiveTo(tunConn lwip.UDPConn, data []byte, destAddr *net.
 	return
 }
 
-// newSession creates a new PacketRequestSender related to conn. The caller needs to put the new PacketRequestSender
-// to the h.senders map.
+// newSession creates a new PacketRequestSender related to conn. The caller must hold h.mu, and needs to put the new
+// PacketRequestSender to the h.senders map.
 func (h *udpHandler) newSession(conn lwip.UDPConn) (network.PacketRequestSender, error) {
 	respWriter := &udpConnResponseWriter{
 		conn: conn,
@@ -76,7 +77,9 @@ func (h *udpHandler) newSession(conn lwip.UDPConn) (network.PacketRequestSender,
 	}
 	reqSender, err := h.proxy.NewSession(respWriter)
 	if err != nil {
-		respWriter.Close()
+		// We can't call respWriter.Close because the caller holds h.mu, and there's no session to clean up.
+		respWriter.closed.Store(true)
+		conn.Close()
 	}
 	return reqSender, err
 }
@@ -95,6 +98,20 @@ func (h *udpHandler) closeSession(conn lwip.UDPConn) error {
 	return err
 }
 
+// closeAll closes all the UDP sessions. It's called when the device is closed, because lwIP doesn't close the
+// sessions in the handler.
+func (h *udpHandler) closeAll() {
+	h.mu.Lock()
+	senders := h.senders
+	h.senders = make(map[string]network.PacketRequestSender, 8)
+	h.mu.Unlock()
+
+	// Closing a sender may close its response writer, which calls closeSession, so we must not hold the lock.
+	for _, reqSender := range senders {
+		reqSender.Close()
+	}
+}
+
 // The PacketResponseWriter that will write responses to the lwip network stack.
 type udpConnResponseWriter struct {
 	closed atomic.Bool
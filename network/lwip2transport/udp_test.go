@@ -85,3 +85,30 @@ func (*noopLwIPUDPConn) ReceiveTo(data []byte, addr *net.UDPAddr) error {
 func (*noopLwIPUDPConn) WriteFrom(data []byte, addr *net.UDPAddr) (int, error) {
 	return 0, nil
 }
+
+func TestUDPHandlerCloseAll(t *testing.T) {
+	proxy := &noopSingleSessionPacketProxy{}
+	h := newUDPHandler(proxy)
+
+	localAddr := net.UDPAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:60127"))
+	destAddr := net.UDPAddrFromAddrPort(netip.MustParseAddrPort("1.2.3.4:4321"))
+	err := h.ReceiveTo(&noopLwIPUDPConn{localAddr}, []byte{}, destAddr)
+	require.NoError(t, err)
+
+	h.closeAll()
+	require.Exactly(t, 1, proxy.closeCnt)
+	require.Empty(t, h.senders)
+}
+
+func TestUDPHandlerNewSessionErrorNoDeadlock(t *testing.T) {
+	proxy := &noopSingleSessionPacketProxy{respWriter: &udpConnResponseWriter{}}
+	h := newUDPHandler(proxy)
+
+	localAddr := net.UDPAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:60127"))
+	destAddr := net.UDPAddrFromAddrPort(netip.MustParseAddrPort("1.2.3.4:4321"))
+	err := h.ReceiveTo(&noopLwIPUDPConn{localAddr}, []byte{}, destAddr)
+	require.Error(t, err)
+
+	// The handler must still be usable.
+	h.closeAll()
+}
@@ -0,0 +1,215 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBackoff is returned by the [StreamDialer] created with [NewBackoffStreamDialer] when a dial is
+// short-circuited because the destination is backing off.
+var ErrBackoff = errors.New("destination is backing off after repeated failures")
+
+// BackoffPolicy configures the behavior of [NewBackoffStreamDialer].
+// Zero values are replaced by the defaults documented on each field.
+type BackoffPolicy struct {
+	// FailureThreshold is the number of consecutive failures to a destination that opens its circuit.
+	// Defaults to 1.
+	FailureThreshold int
+	// InitialBackoff is how long the circuit stays open after it first opens. Defaults to 1 second.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff window, which doubles each time a half-open probe fails. Defaults to 1 minute.
+	MaxBackoff time.Duration
+	// MaxDestinations bounds the number of destinations tracked. When the limit is reached, the least
+	// recently used destination is forgotten. Defaults to 1024.
+	MaxDestinations int
+}
+
+// backoffEntry is the failure state for a single destination.
+type backoffEntry struct {
+	addr      string
+	failures  int
+	backoff   time.Duration
+	openUntil time.Time
+	// probing is set while the single half-open probe is in flight.
+	probing bool
+}
+
+// backoffDialer is a [StreamDialer] that applies per-destination backoff.
+// Use [NewBackoffStreamDialer] to create new instances.
+type backoffDialer struct {
+	dialer StreamDialer
+	policy BackoffPolicy
+	now    func() time.Time
+
+	mu sync.Mutex
+	// lru holds *backoffEntry values, most recently used first.
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+var _ StreamDialer = (*backoffDialer)(nil)
+var _ ConnectFailureReporter = (*backoffDialer)(nil)
+
+/*
+NewBackoffStreamDialer creates a [StreamDialer] that tracks failures per destination address and stops dialing
+destinations that keep failing, so that a blocked destination is not hammered with connection attempts.
+
+Each destination behaves like a circuit breaker:
+
+  - Closed: dials go through. Each failure increments a counter, and any success resets it.
+    Once the counter reaches [BackoffPolicy].FailureThreshold, the circuit opens.
+  - Open: dials fail immediately with an error wrapping [ErrBackoff], without calling the base dialer,
+    until the backoff window expires.
+  - Half-open: after the window expires, a single dial is let through as a probe, while concurrent dials keep
+    failing fast. If the probe succeeds, the destination is forgotten and the circuit closes. If it fails,
+    the circuit opens again with double the previous window, up to [BackoffPolicy].MaxBackoff.
+
+Dials aborted because the caller's context is done are not counted as failures.
+
+Memory is bounded by [BackoffPolicy].MaxDestinations. Destinations are kept in an LRU list, and only destinations
+with failures are tracked.
+*/
+func NewBackoffStreamDialer(dialer StreamDialer, policy BackoffPolicy) (StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if policy.FailureThreshold < 0 || policy.InitialBackoff < 0 || policy.MaxBackoff < 0 || policy.MaxDestinations < 0 {
+		return nil, errors.New("backoff policy values must not be negative")
+	}
+	if policy.FailureThreshold == 0 {
+		policy.FailureThreshold = 1
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = 1 * time.Second
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = 1 * time.Minute
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+	if policy.MaxDestinations == 0 {
+		policy.MaxDestinations = 1024
+	}
+	return &backoffDialer{
+		dialer:  dialer,
+		policy:  policy,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *backoffDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
+	isProbe, err := d.admit(addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.dialer.DialStream(ctx, addr)
+	if err != nil && ctx.Err() == nil {
+		d.recordFailure(addr)
+	} else if err == nil {
+		d.recordSuccess(addr)
+	} else if isProbe {
+		// The probe was cancelled by the caller. Let another dial probe instead.
+		d.releaseProbe(addr)
+	}
+	return conn, err
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter] with the answer of the base dialer.
+func (d *backoffDialer) ReportsConnectFailure() bool {
+	return ReportsConnectFailure(d.dialer)
+}
+
+// admit decides whether a dial to addr can proceed. It returns whether the dial is the half-open probe.
+func (d *backoffDialer) admit(addr string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	element, ok := d.entries[addr]
+	if !ok {
+		return false, nil
+	}
+	d.lru.MoveToFront(element)
+	entry := element.Value.(*backoffEntry)
+	if entry.openUntil.IsZero() {
+		// Circuit is closed.
+		return false, nil
+	}
+	if remaining := entry.openUntil.Sub(d.now()); remaining > 0 {
+		return false, fmt.Errorf("dial to %v skipped for %v: %w", addr, remaining, ErrBackoff)
+	}
+	if entry.probing {
+		return false, fmt.Errorf("dial to %v skipped while probing: %w", addr, ErrBackoff)
+	}
+	entry.probing = true
+	return true, nil
+}
+
+func (d *backoffDialer) recordFailure(addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var entry *backoffEntry
+	if element, ok := d.entries[addr]; ok {
+		d.lru.MoveToFront(element)
+		entry = element.Value.(*backoffEntry)
+	} else {
+		entry = &backoffEntry{addr: addr}
+		d.entries[addr] = d.lru.PushFront(entry)
+		for d.lru.Len() > d.policy.MaxDestinations {
+			oldest := d.lru.Back()
+			d.lru.Remove(oldest)
+			delete(d.entries, oldest.Value.(*backoffEntry).addr)
+		}
+	}
+	entry.failures++
+	entry.probing = false
+	if entry.failures < d.policy.FailureThreshold {
+		return
+	}
+	if entry.backoff == 0 {
+		entry.backoff = d.policy.InitialBackoff
+	} else {
+		entry.backoff = 2 * entry.backoff
+		if entry.backoff > d.policy.MaxBackoff {
+			entry.backoff = d.policy.MaxBackoff
+		}
+	}
+	entry.openUntil = d.now().Add(entry.backoff)
+}
+
+func (d *backoffDialer) recordSuccess(addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.entries[addr]; ok {
+		d.lru.Remove(element)
+		delete(d.entries, addr)
+	}
+}
+
+func (d *backoffDialer) releaseProbe(addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.entries[addr]; ok {
+		element.Value.(*backoffEntry).probing = false
+	}
+}
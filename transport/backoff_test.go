@@ -0,0 +1,144 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBackoffDialer returns a backoff dialer with a fake clock, along with a function to advance the clock.
+func newTestBackoffDialer(t *testing.T, base StreamDialer, policy BackoffPolicy) (*backoffDialer, func(time.Duration)) {
+	sd, err := NewBackoffStreamDialer(base, policy)
+	require.NoError(t, err)
+	dialer := sd.(*backoffDialer)
+	now := time.Unix(0, 0)
+	dialer.now = func() time.Time { return now }
+	return dialer, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBackoffStreamDialer_NilDialer(t *testing.T) {
+	_, err := NewBackoffStreamDialer(nil, BackoffPolicy{})
+	require.Error(t, err)
+}
+
+func TestBackoffStreamDialer_OpensAndHalfOpens(t *testing.T) {
+	dialErr := errors.New("blocked")
+	baseDialer := collectStreamDialer{Dialer: newErrorStreamDialer(dialErr)}
+	dialer, advance := newTestBackoffDialer(t, &baseDialer, BackoffPolicy{
+		FailureThreshold: 2,
+		InitialBackoff:   10 * time.Second,
+		MaxBackoff:       15 * time.Second,
+	})
+	ctx := context.Background()
+
+	// Closed: failures below the threshold go through.
+	_, err := dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, dialErr)
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, dialErr)
+	require.Len(t, baseDialer.Addrs, 2)
+
+	// Open: dials fail fast without reaching the base dialer.
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, ErrBackoff)
+	require.Len(t, baseDialer.Addrs, 2)
+
+	// Other destinations are not affected.
+	_, err = dialer.DialStream(ctx, "other:443")
+	require.ErrorIs(t, err, dialErr)
+	require.Len(t, baseDialer.Addrs, 3)
+
+	// Half-open: a probe goes through after the window, and its failure doubles the window up to the max.
+	advance(10 * time.Second)
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, dialErr)
+	require.Len(t, baseDialer.Addrs, 4)
+	advance(14 * time.Second)
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, ErrBackoff)
+	advance(1 * time.Second)
+
+	// A successful probe closes the circuit.
+	baseDialer.Dialer = nilDialer
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.NoError(t, err)
+	baseDialer.Dialer = newErrorStreamDialer(dialErr)
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, dialErr)
+	require.Len(t, baseDialer.Addrs, 6)
+}
+
+func TestBackoffStreamDialer_SingleProbe(t *testing.T) {
+	probeStarted := make(chan struct{})
+	releaseProbe := make(chan struct{})
+	dialErr := errors.New("blocked")
+	var blockProbe bool
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		if blockProbe {
+			close(probeStarted)
+			<-releaseProbe
+		}
+		return nil, dialErr
+	})
+	dialer, advance := newTestBackoffDialer(t, base, BackoffPolicy{InitialBackoff: time.Second})
+	ctx := context.Background()
+
+	_, err := dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, dialErr)
+	advance(time.Second)
+
+	blockProbe = true
+	probeDone := make(chan error)
+	go func() {
+		_, err := dialer.DialStream(ctx, "blocked:443")
+		probeDone <- err
+	}()
+	<-probeStarted
+	_, err = dialer.DialStream(ctx, "blocked:443")
+	require.ErrorIs(t, err, ErrBackoff)
+	close(releaseProbe)
+	require.ErrorIs(t, <-probeDone, dialErr)
+}
+
+func TestBackoffStreamDialer_CancelledNotCounted(t *testing.T) {
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		return nil, ctx.Err()
+	})
+	dialer, _ := newTestBackoffDialer(t, base, BackoffPolicy{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := dialer.DialStream(ctx, "example.com:443")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, dialer.entries)
+}
+
+func TestBackoffStreamDialer_LRU(t *testing.T) {
+	dialer, _ := newTestBackoffDialer(t, newErrorStreamDialer(errors.New("fail")), BackoffPolicy{MaxDestinations: 2})
+	ctx := context.Background()
+	dialer.DialStream(ctx, "a:1")
+	dialer.DialStream(ctx, "b:1")
+	// Touch "a" so "b" becomes the least recently used.
+	dialer.DialStream(ctx, "a:1")
+	dialer.DialStream(ctx, "c:1")
+	require.Equal(t, 2, dialer.lru.Len())
+	require.Contains(t, dialer.entries, "a:1")
+	require.Contains(t, dialer.entries, "c:1")
+	require.NotContains(t, dialer.entries, "b:1")
+}
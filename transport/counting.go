@@ -0,0 +1,195 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// CountingStreamDialer is a [StreamDialer] that counts the bytes read and written by its connections.
+// Use [NewCountingStreamDialer] to create new instances.
+type CountingStreamDialer struct {
+	dialer       StreamDialer
+	connections  atomic.Int64
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+}
+
+var _ StreamDialer = (*CountingStreamDialer)(nil)
+var _ ConnectFailureReporter = (*CountingStreamDialer)(nil)
+
+// DialerStats are the aggregate statistics of the connections of a [CountingStreamDialer].
+type DialerStats struct {
+	// Connections is the number of connections established.
+	Connections int64
+	// BytesRead is the number of bytes read from all the connections.
+	BytesRead int64
+	// BytesWritten is the number of bytes written to all the connections.
+	BytesWritten int64
+}
+
+// ConnStats are the statistics of a [CountingStreamConn].
+type ConnStats struct {
+	// DialStart is when the dial started.
+	DialStart time.Time
+	// Connected is when the dial completed.
+	Connected time.Time
+	// FirstByte is when the first byte was read, or zero if nothing was read yet.
+	// FirstByte minus DialStart is the time to first byte.
+	FirstByte time.Time
+	// BytesRead is the number of bytes read from the connection.
+	BytesRead int64
+	// BytesWritten is the number of bytes written to the connection.
+	BytesWritten int64
+}
+
+// NewCountingStreamDialer creates a [CountingStreamDialer] that dials with the given dialer. The connections it
+// returns are [*CountingStreamConn]s.
+func NewCountingStreamDialer(dialer StreamDialer) (*CountingStreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	return &CountingStreamDialer{dialer: dialer}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream. The returned connection is a [*CountingStreamConn].
+func (d *CountingStreamDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
+	dialStart := time.Now()
+	conn, err := d.dialer.DialStream(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	d.connections.Add(1)
+	return &CountingStreamConn{StreamConn: conn, dialer: d, dialStart: dialStart, connected: time.Now()}, nil
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter] with the answer of the base dialer.
+func (d *CountingStreamDialer) ReportsConnectFailure() bool {
+	return ReportsConnectFailure(d.dialer)
+}
+
+// Stats returns the aggregate statistics of the connections dialed so far.
+func (d *CountingStreamDialer) Stats() DialerStats {
+	return DialerStats{
+		Connections:  d.connections.Load(),
+		BytesRead:    d.bytesRead.Load(),
+		BytesWritten: d.bytesWritten.Load(),
+	}
+}
+
+// CountingStreamConn is a [StreamConn] that counts the bytes read and written, and records the time of the first
+// byte read. It's created by [CountingStreamDialer]. Its ReadFrom and WriteTo use the ones of the wrapped connection,
+// if available, but WriteTo wraps the destination to count the bytes, which hides the destination's [io.ReaderFrom].
+type CountingStreamConn struct {
+	StreamConn
+	dialer       *CountingStreamDialer
+	dialStart    time.Time
+	connected    time.Time
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	firstByte    atomic.Pointer[time.Time]
+}
+
+var _ StreamConn = (*CountingStreamConn)(nil)
+var _ io.ReaderFrom = (*CountingStreamConn)(nil)
+var _ io.WriterTo = (*CountingStreamConn)(nil)
+
+// Stats returns the statistics of the connection.
+func (c *CountingStreamConn) Stats() ConnStats {
+	stats := ConnStats{
+		DialStart:    c.dialStart,
+		Connected:    c.connected,
+		BytesRead:    c.bytesRead.Load(),
+		BytesWritten: c.bytesWritten.Load(),
+	}
+	if firstByte := c.firstByte.Load(); firstByte != nil {
+		stats.FirstByte = *firstByte
+	}
+	return stats
+}
+
+func (c *CountingStreamConn) addRead(n int64) {
+	if n <= 0 {
+		return
+	}
+	if c.firstByte.Load() == nil {
+		now := time.Now()
+		c.firstByte.CompareAndSwap(nil, &now)
+	}
+	c.bytesRead.Add(n)
+	c.dialer.bytesRead.Add(n)
+}
+
+func (c *CountingStreamConn) addWritten(n int64) {
+	if n <= 0 {
+		return
+	}
+	c.bytesWritten.Add(n)
+	c.dialer.bytesWritten.Add(n)
+}
+
+func (c *CountingStreamConn) Read(b []byte) (int, error) {
+	n, err := c.StreamConn.Read(b)
+	c.addRead(int64(n))
+	return n, err
+}
+
+func (c *CountingStreamConn) Write(b []byte) (int, error) {
+	n, err := c.StreamConn.Write(b)
+	c.addWritten(int64(n))
+	return n, err
+}
+
+// ReadFrom implements [io.ReaderFrom], using the ReadFrom of the wrapped connection if available.
+func (c *CountingStreamConn) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	var err error
+	if rf, ok := c.StreamConn.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		// Hide our ReadFrom from io.Copy to avoid recursion.
+		n, err = io.Copy(struct{ io.Writer }{c.StreamConn}, r)
+	}
+	c.addWritten(n)
+	return n, err
+}
+
+// WriteTo implements [io.WriterTo], using the WriteTo of the wrapped connection if available.
+// The ReadFrom of w is not used, since w is wrapped to count the bytes.
+func (c *CountingStreamConn) WriteTo(w io.Writer) (int64, error) {
+	// Count the bytes as they are written, so the stats are updated while the copy is in progress.
+	cw := &countingWriter{Writer: w, conn: c}
+	if wt, ok := c.StreamConn.(io.WriterTo); ok {
+		return wt.WriteTo(cw)
+	}
+	// Hide our WriteTo from io.Copy to avoid recursion.
+	return io.Copy(cw, struct{ io.Reader }{c.StreamConn})
+}
+
+// countingWriter counts the bytes written to it as bytes read from conn.
+type countingWriter struct {
+	io.Writer
+	conn *CountingStreamConn
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.conn.addRead(int64(n))
+	return n, err
+}
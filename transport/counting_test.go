@@ -0,0 +1,104 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCountingTestDialer(t *testing.T) (*CountingStreamDialer, *net.TCPListener) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	d, err := NewCountingStreamDialer(&TCPDialer{})
+	require.NoError(t, err)
+	return d, listener
+}
+
+func TestCountingStreamDialer(t *testing.T) {
+	d, listener := newCountingTestDialer(t)
+	go func() {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Echo until the client closes its write end.
+		io.Copy(conn, conn)
+	}()
+
+	before := time.Now()
+	conn, err := d.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	countingConn, ok := conn.(*CountingStreamConn)
+	require.True(t, ok)
+	require.True(t, countingConn.Stats().FirstByte.IsZero())
+
+	n, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	// ReadFrom keeps the half-close working.
+	_, err = conn.(io.ReaderFrom).ReadFrom(bytes.NewReader([]byte(" world")))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+
+	var received bytes.Buffer
+	_, err = conn.(io.WriterTo).WriteTo(&received)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", received.String())
+	require.NoError(t, conn.Close())
+
+	stats := countingConn.Stats()
+	require.Equal(t, int64(11), stats.BytesRead)
+	require.Equal(t, int64(11), stats.BytesWritten)
+	require.False(t, stats.DialStart.Before(before))
+	require.False(t, stats.Connected.Before(stats.DialStart))
+	require.False(t, stats.FirstByte.Before(stats.Connected))
+
+	require.Equal(t, DialerStats{Connections: 1, BytesRead: 11, BytesWritten: 11}, d.Stats())
+}
+
+func TestCountingStreamDialerRead(t *testing.T) {
+	d, listener := newCountingTestDialer(t)
+	go func() {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("response"))
+		conn.Close()
+	}()
+
+	conn, err := d.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "response", string(data))
+	require.Equal(t, int64(8), conn.(*CountingStreamConn).Stats().BytesRead)
+	require.Equal(t, int64(8), d.Stats().BytesRead)
+}
+
+func TestNewCountingStreamDialerNil(t *testing.T) {
+	_, err := NewCountingStreamDialer(nil)
+	require.Error(t, err)
+}
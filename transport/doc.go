@@ -43,5 +43,11 @@ The [StreamDialer] and [PacketDialer] types create stream ([StreamConn]) and dat
 Dialers can also be nested. For example, a TLS Stream Dialer can use a TCP dialer to create a StreamConn backed by a TCP connection,
 then create a TLS StreamConn backed by the TCP StreamConn. A SOCKS5-over-TLS Dialer could use the TLS Dialer to create the TLS StreamConn
 to the proxy before doing the SOCKS5 connection to the target address.
+
+# Tracing
+
+To observe the connections at every layer, pass a context created with [WithTrace] to DialStream. The TCP, TLS, SOCKS5
+and Shadowsocks dialers report when they start dialing, connect, read the first byte and close. Custom dialers can
+support it with [TraceDialStream].
 */
 package transport
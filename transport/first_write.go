@@ -0,0 +1,129 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// maxFirstWriteBufferSize is the maximum amount of data buffered while a [FirstWriteFunc] asks for more.
+// It's enough for a TLS Client Hello or an HTTP request header.
+const maxFirstWriteBufferSize = 64 * 1024
+
+// FirstWriteFunc transforms the first bytes written to a stream into the fragments to write, in order, with one
+// write each. It returns nil if it needs more bytes to decide, for example to get a complete TLS record, in which case
+// it's called again once more data is written. It may reuse the input slice in the fragments.
+type FirstWriteFunc func(firstBytes []byte) [][]byte
+
+// firstWriteDialer is a [StreamDialer] that transforms the first write of its connections.
+type firstWriteDialer struct {
+	dialer    StreamDialer
+	transform FirstWriteFunc
+}
+
+var _ StreamDialer = (*firstWriteDialer)(nil)
+var _ ConnectFailureReporter = (*firstWriteDialer)(nil)
+
+// NewFirstWriteTransformer creates a [StreamDialer] whose connections transform their first write with transform,
+// and pass the subsequent writes through unchanged. It's the building block for the evasion strategies that only
+// act on the first write, like splitting or fragmenting the TLS Client Hello.
+//
+// The written data is buffered while transform returns nil. If it grows beyond 64 KiB, or the write end is closed
+// with [StreamConn.CloseWrite], the buffered data is written as is, without calling transform again.
+func NewFirstWriteTransformer(dialer StreamDialer, transform FirstWriteFunc) (StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if transform == nil {
+		return nil, errors.New("argument transform must not be nil")
+	}
+	return &firstWriteDialer{dialer: dialer, transform: transform}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *firstWriteDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
+	conn, err := d.dialer.DialStream(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return WrapConn(conn, conn, NewFirstWriteWriter(conn, d.transform)), nil
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter] with the answer of the base dialer.
+func (d *firstWriteDialer) ReportsConnectFailure() bool {
+	return ReportsConnectFailure(d.dialer)
+}
+
+// firstWriteWriter is the [io.Writer] returned by [NewFirstWriteWriter].
+type firstWriteWriter struct {
+	writer    io.Writer
+	transform FirstWriteFunc
+	buf       []byte
+	done      bool
+}
+
+var _ io.Writer = (*firstWriteWriter)(nil)
+
+// NewFirstWriteWriter creates an [io.Writer] that transforms the first write to writer with transform, as described
+// in [NewFirstWriteTransformer]. It has a Flush method to write the buffered data as is, which [WrapConn] calls on
+// [StreamConn.CloseWrite]. The writer is not safe for concurrent use.
+func NewFirstWriteWriter(writer io.Writer, transform FirstWriteFunc) io.Writer {
+	return &firstWriteWriter{writer: writer, transform: transform}
+}
+
+// Write implements [io.Writer]. It returns len(p) once the data is buffered or written. If writing the fragments
+// fails, it returns zero and the error, since it can't tell how much of p was sent.
+func (w *firstWriteWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return w.writer.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	fragments := w.transform(w.buf)
+	if fragments == nil {
+		if len(w.buf) <= maxFirstWriteBufferSize {
+			return len(p), nil
+		}
+		fragments = [][]byte{w.buf}
+	}
+	if err := w.writeFragments(fragments); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFragments writes each fragment with its own write and ends the buffering.
+func (w *firstWriteWriter) writeFragments(fragments [][]byte) error {
+	w.done = true
+	w.buf = nil
+	for _, fragment := range fragments {
+		if len(fragment) == 0 {
+			continue
+		}
+		if _, err := w.writer.Write(fragment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the buffered data as is, if any, and ends the buffering.
+func (w *firstWriteWriter) Flush() error {
+	if w.done || len(w.buf) == 0 {
+		return nil
+	}
+	return w.writeFragments([][]byte{w.buf})
+}
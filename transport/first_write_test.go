@@ -0,0 +1,128 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWriter records each write separately.
+type recordingWriter struct {
+	writes [][]byte
+	err    error
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// splitAfterNewline waits for a newline and splits the first bytes after it.
+func splitAfterNewline(firstBytes []byte) [][]byte {
+	i := bytes.IndexByte(firstBytes, '\n')
+	if i == -1 {
+		return nil
+	}
+	return [][]byte{firstBytes[:i+1], firstBytes[i+1:]}
+}
+
+func TestFirstWriteWriter_Transform(t *testing.T) {
+	inner := &recordingWriter{}
+	w := NewFirstWriteWriter(inner, func(firstBytes []byte) [][]byte {
+		return [][]byte{firstBytes[:2], firstBytes[2:]}
+	})
+	n, err := w.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	n, err = w.Write([]byte("world"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, [][]byte{[]byte("he"), []byte("llo"), []byte("world")}, inner.writes)
+}
+
+func TestFirstWriteWriter_BuffersUntilDecision(t *testing.T) {
+	inner := &recordingWriter{}
+	w := NewFirstWriteWriter(inner, splitAfterNewline)
+	n, err := w.Write([]byte("GET / "))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	require.Empty(t, inner.writes)
+	n, err = w.Write([]byte("HTTP/1.1\nHost"))
+	require.NoError(t, err)
+	require.Equal(t, 13, n)
+	require.Equal(t, [][]byte{[]byte("GET / HTTP/1.1\n"), []byte("Host")}, inner.writes)
+}
+
+func TestFirstWriteWriter_SkipsEmptyFragments(t *testing.T) {
+	inner := &recordingWriter{}
+	w := NewFirstWriteWriter(inner, splitAfterNewline)
+	_, err := w.Write([]byte("line\n"))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("line\n")}, inner.writes)
+}
+
+func TestFirstWriteWriter_BufferLimit(t *testing.T) {
+	inner := &recordingWriter{}
+	var calls int
+	w := NewFirstWriteWriter(inner, func(firstBytes []byte) [][]byte {
+		calls++
+		return nil
+	})
+	_, err := w.Write(make([]byte, maxFirstWriteBufferSize))
+	require.NoError(t, err)
+	require.Empty(t, inner.writes)
+	_, err = w.Write([]byte{1})
+	require.NoError(t, err)
+	require.Len(t, inner.writes, 1)
+	require.Len(t, inner.writes[0], maxFirstWriteBufferSize+1)
+	_, err = w.Write([]byte{2})
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, inner.writes[1])
+	require.Equal(t, 2, calls)
+}
+
+func TestFirstWriteWriter_Flush(t *testing.T) {
+	inner := &recordingWriter{}
+	w := NewFirstWriteWriter(inner, splitAfterNewline)
+	_, err := w.Write([]byte("partial"))
+	require.NoError(t, err)
+	require.NoError(t, w.(flusher).Flush())
+	require.Equal(t, [][]byte{[]byte("partial")}, inner.writes)
+	// Flushing again is a no-op.
+	require.NoError(t, w.(flusher).Flush())
+	require.Len(t, inner.writes, 1)
+}
+
+func TestFirstWriteWriter_Error(t *testing.T) {
+	writeErr := errors.New("write failed")
+	w := NewFirstWriteWriter(&recordingWriter{err: writeErr}, splitAfterNewline)
+	n, err := w.Write([]byte("line\nmore"))
+	require.ErrorIs(t, err, writeErr)
+	require.Equal(t, 0, n)
+}
+
+func TestNewFirstWriteTransformer_NilArguments(t *testing.T) {
+	_, err := NewFirstWriteTransformer(nil, splitAfterNewline)
+	require.Error(t, err)
+	_, err = NewFirstWriteTransformer(&TCPDialer{}, nil)
+	require.Error(t, err)
+}
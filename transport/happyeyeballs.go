@@ -124,8 +124,8 @@ func (d *HappyEyeballsStreamDialer) DialStream(ctx context.Context, addr string)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse address: %w", err)
 	}
-	if net.ParseIP(hostname) != nil {
-		// Host is already an IP address, just dial the address.
+	if _, err := netip.ParseAddr(hostname); err == nil {
+		// Host is already an IP address, possibly with an IPv6 zone, just dial the address.
 		return d.dial(ctx, addr)
 	}
 
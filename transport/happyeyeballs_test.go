@@ -63,6 +63,14 @@ func TestHappyEyeballsStreamDialer_DialStream(t *testing.T) {
 		require.Equal(t, []string{"[2001:4860:4860::8888]:53"}, baseDialer.Addrs)
 	})
 
+	t.Run("Works with IPv6 hosts with zone", func(t *testing.T) {
+		baseDialer := collectStreamDialer{Dialer: nilDialer}
+		dialer := HappyEyeballsStreamDialer{Dialer: &baseDialer}
+		_, err := dialer.DialStream(context.Background(), "[fe80::1%eth0]:53")
+		require.NoError(t, err)
+		require.Equal(t, []string{"[fe80::1%eth0]:53"}, baseDialer.Addrs)
+	})
+
 	t.Run("Prefer IPv6", func(t *testing.T) {
 		baseDialer := collectStreamDialer{Dialer: nilDialer}
 		dialer := HappyEyeballsStreamDialer{
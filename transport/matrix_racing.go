@@ -0,0 +1,188 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// DialSpec pairs a resolver with a transport, as one of the combinations raced by a [MatrixRacingDialer].
+// To race every resolver with every transport, add a DialSpec for each pair.
+type DialSpec struct {
+	// Resolve maps a host name to IP addresses. If nil, the host name is passed to the Dialer unresolved, which
+	// is what you want for transports that resolve remotely, like proxies.
+	Resolve func(ctx context.Context, hostname string) ([]netip.Addr, error)
+	// Dialer is the transport used to connect. If nil, a direct TCP connection is established.
+	Dialer StreamDialer
+}
+
+/*
+MatrixRacingDialer is a [StreamDialer] that races multiple combinations of resolvers and transports, given as
+[DialSpec]s, and returns the first usable connection. Combining resolver diversity and path diversity in a single
+race makes it very robust on hostile networks, where any given resolver or transport may be blocked.
+
+The attempts start in the order of the specs, with AttemptDelay between them, like [Happy Eyeballs]. If an attempt
+fails before the delay is over, the next one starts right away. Within an attempt, the resolved addresses are tried in
+order. The first connection that passes Validate wins, and the other attempts are cancelled. Connections that are
+established after the race is decided are closed. The race is also cancelled if the context passed to DialStream is
+done.
+
+Keep in mind the connection budget: a single DialStream can make a connection attempt for every resolved address of
+every spec, and send a DNS query for every spec. Order the specs by preference, so the most likely to succeed start
+first, and prefer a small number of specs.
+
+[Happy Eyeballs]: https://datatracker.ietf.org/doc/html/rfc8305
+*/
+type MatrixRacingDialer struct {
+	specs []DialSpec
+	// AttemptDelay is the delay before starting the next attempt. It defaults to 250ms.
+	AttemptDelay time.Duration
+	// Validate optionally checks that a new connection is usable, for example by performing a handshake.
+	// If it returns an error, the connection is closed and the attempt fails.
+	Validate func(ctx context.Context, conn StreamConn) error
+}
+
+var _ StreamDialer = (*MatrixRacingDialer)(nil)
+
+// NewMatrixRacingDialer creates a [MatrixRacingDialer] that races the given specs.
+func NewMatrixRacingDialer(specs []DialSpec) (*MatrixRacingDialer, error) {
+	if len(specs) == 0 {
+		return nil, errors.New("argument specs must not be empty")
+	}
+	return &MatrixRacingDialer{specs: append([]DialSpec{}, specs...), AttemptDelay: 250 * time.Millisecond}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *MatrixRacingDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	}
+	// Indicates to attempts that the race is done, so they don't get stuck.
+	ctx, raceDone := context.WithCancel(ctx)
+	defer raceDone()
+
+	type attemptResult struct {
+		Conn StreamConn
+		Err  error
+	}
+	resultCh := make(chan attemptResult)
+	var attemptErr error
+	var delayTimer *time.Timer
+	var delayCh <-chan time.Time
+	nextSpec := 0
+	startAttempt := func() {
+		specIndex := nextSpec
+		nextSpec++
+		go func(spec DialSpec) {
+			conn, err := d.attempt(ctx, spec, host, port)
+			if err != nil {
+				err = fmt.Errorf("spec %v: %w", specIndex, err)
+			}
+			select {
+			case <-ctx.Done():
+				if conn != nil {
+					conn.Close()
+				}
+			case resultCh <- attemptResult{conn, err}:
+			}
+		}(d.specs[specIndex])
+		if delayTimer != nil {
+			delayTimer.Stop()
+		}
+		if nextSpec < len(d.specs) {
+			delayTimer = time.NewTimer(d.AttemptDelay)
+			delayCh = delayTimer.C
+		} else {
+			delayTimer = nil
+			delayCh = nil
+		}
+	}
+	defer func() {
+		if delayTimer != nil {
+			delayTimer.Stop()
+		}
+	}()
+
+	startAttempt()
+	for pending := 1; pending > 0; {
+		select {
+		case <-delayCh:
+			pending++
+			startAttempt()
+
+		case result := <-resultCh:
+			pending--
+			if result.Err == nil {
+				return result.Conn, nil
+			}
+			attemptErr = errors.Join(attemptErr, result.Err)
+			// Don't wait for the delay if the attempt failed.
+			if nextSpec < len(d.specs) {
+				pending++
+				startAttempt()
+			}
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, attemptErr
+}
+
+// attempt resolves the host with the spec resolver and connects to the resolved addresses in order,
+// returning the first connection that passes validation.
+func (d *MatrixRacingDialer) attempt(ctx context.Context, spec DialSpec, host string, port string) (StreamConn, error) {
+	dialer := spec.Dialer
+	if dialer == nil {
+		dialer = &TCPDialer{}
+	}
+	addrs := []string{net.JoinHostPort(host, port)}
+	if spec.Resolve != nil && net.ParseIP(host) == nil {
+		ips, err := spec.Resolve(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve host: %w", err)
+		}
+		if len(ips) == 0 {
+			return nil, errors.New("address lookup returned no IPs")
+		}
+		addrs = addrs[:0]
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+		}
+	}
+	var dialErr error
+	for _, addr := range addrs {
+		conn, err := dialer.DialStream(ctx, addr)
+		if err != nil {
+			dialErr = errors.Join(dialErr, err)
+			continue
+		}
+		if d.Validate != nil {
+			if err := d.Validate(ctx, conn); err != nil {
+				conn.Close()
+				dialErr = errors.Join(dialErr, fmt.Errorf("connection validation failed: %w", err))
+				continue
+			}
+		}
+		return conn, nil
+	}
+	return nil, dialErr
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// namedConn is a fake [StreamConn] that records the address it was dialed with and whether it was closed.
+type namedConn struct {
+	StreamConn
+	addr   string
+	closed chan struct{}
+}
+
+func (c *namedConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func newNamedConnDialer() StreamDialer {
+	return FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		return &namedConn{addr: addr, closed: make(chan struct{})}, nil
+	})
+}
+
+func newStaticResolve(ips ...string) func(context.Context, string) ([]netip.Addr, error) {
+	return func(ctx context.Context, hostname string) ([]netip.Addr, error) {
+		addrs := make([]netip.Addr, 0, len(ips))
+		for _, ip := range ips {
+			addrs = append(addrs, netip.MustParseAddr(ip))
+		}
+		return addrs, nil
+	}
+}
+
+func TestMatrixRacingDialer_OnlyOneCombinationWorks(t *testing.T) {
+	blockedErr := errors.New("blocked")
+	// Only connections to 10.0.0.2 through the second transport work.
+	var mu sync.Mutex
+	var dialed []string
+	newDialer := func(works bool) StreamDialer {
+		return FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+			mu.Lock()
+			dialed = append(dialed, addr)
+			mu.Unlock()
+			if works && addr == "10.0.0.2:443" {
+				return &namedConn{addr: addr, closed: make(chan struct{})}, nil
+			}
+			return nil, blockedErr
+		})
+	}
+	badResolve := newStaticResolve("10.0.0.1")
+	goodResolve := newStaticResolve("10.0.0.1", "10.0.0.2")
+	failedResolve := func(ctx context.Context, hostname string) ([]netip.Addr, error) {
+		return nil, errors.New("resolver blocked")
+	}
+	dialer, err := NewMatrixRacingDialer([]DialSpec{
+		{Resolve: failedResolve, Dialer: newDialer(true)},
+		{Resolve: goodResolve, Dialer: newDialer(false)},
+		{Resolve: badResolve, Dialer: newDialer(true)},
+		{Resolve: goodResolve, Dialer: newDialer(true)},
+	})
+	require.NoError(t, err)
+	// Failures start the next attempt right away, so the delay doesn't matter.
+	dialer.AttemptDelay = time.Hour
+
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.2:443", conn.(*namedConn).addr)
+	require.Equal(t, []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.1:443", "10.0.0.1:443", "10.0.0.2:443"}, dialed)
+}
+
+func TestMatrixRacingDialer_Validate(t *testing.T) {
+	dialer, err := NewMatrixRacingDialer([]DialSpec{
+		{Resolve: newStaticResolve("10.0.0.1", "10.0.0.2")},
+		{Resolve: newStaticResolve("10.0.0.3")},
+	})
+	require.NoError(t, err)
+	for i := range dialer.specs {
+		dialer.specs[i].Dialer = newNamedConnDialer()
+	}
+	var rejected []*namedConn
+	dialer.Validate = func(ctx context.Context, conn StreamConn) error {
+		if conn.(*namedConn).addr != "10.0.0.3:443" {
+			rejected = append(rejected, conn.(*namedConn))
+			return errors.New("bad connection")
+		}
+		return nil
+	}
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.3:443", conn.(*namedConn).addr)
+	// The rejected connections were closed.
+	require.Len(t, rejected, 2)
+	for _, conn := range rejected {
+		<-conn.closed
+	}
+}
+
+func TestMatrixRacingDialer_StaggeredAndLateConnectionsClosed(t *testing.T) {
+	releaseSlow := make(chan struct{})
+	slowConn := &namedConn{addr: "slow", closed: make(chan struct{})}
+	slowDialer := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		// Ignore the context to simulate a connection that is established after the race is over.
+		<-releaseSlow
+		return slowConn, nil
+	})
+	dialer, err := NewMatrixRacingDialer([]DialSpec{
+		{Dialer: slowDialer},
+		{Dialer: newNamedConnDialer()},
+	})
+	require.NoError(t, err)
+	dialer.AttemptDelay = 10 * time.Millisecond
+
+	start := time.Now()
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), dialer.AttemptDelay)
+	require.Equal(t, "example.com:443", conn.(*namedConn).addr)
+
+	close(releaseSlow)
+	<-slowConn.closed
+}
+
+func TestMatrixRacingDialer_AllFail(t *testing.T) {
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+	dialer, err := NewMatrixRacingDialer([]DialSpec{
+		{Dialer: newErrorStreamDialer(err1)},
+		{Dialer: newErrorStreamDialer(err2)},
+	})
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "example.com:443")
+	require.ErrorIs(t, err, err1)
+	require.ErrorIs(t, err, err2)
+}
+
+func TestMatrixRacingDialer_Cancel(t *testing.T) {
+	blockingDialer := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	dialer, err := NewMatrixRacingDialer([]DialSpec{{Dialer: blockingDialer}, {Dialer: blockingDialer}})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = dialer.DialStream(ctx, "example.com:443")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewMatrixRacingDialer_NoSpecs(t *testing.T) {
+	_, err := NewMatrixRacingDialer(nil)
+	require.Error(t, err)
+}
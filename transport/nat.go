@@ -0,0 +1,282 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultNATTimeout is the default idle timeout of the mappings of [NewNATPacketListener], as recommended by
+// https://datatracker.ietf.org/doc/html/rfc4787#section-4.3.
+const DefaultNATTimeout = 5 * time.Minute
+
+// natPacketListener is a [PacketListener] that multiplexes the packets to many destinations over connections
+// from a [PacketDialer]. Use [NewNATPacketListener] to create new instances.
+type natPacketListener struct {
+	dialer      PacketDialer
+	idleTimeout time.Duration
+}
+
+var _ PacketListener = (*natPacketListener)(nil)
+
+// NewNATPacketListener creates a [PacketListener] that works like a NAT: the [net.PacketConn] it returns sends the
+// packets to each destination over a connection dialed with dialer on the first packet, and returns the packets
+// received on all those connections. The connection to a destination is closed when no packets are sent or received
+// for idleTimeout. If idleTimeout is zero, it uses [DefaultNATTimeout]. Like a socket with a full receive buffer,
+// it drops the received packets if too many are waiting to be read.
+//
+// Relays can use it to keep one mapping per client, by calling ListenPacket for each client address. The destination
+// addresses must be in a format supported by the dialer.
+func NewNATPacketListener(dialer PacketDialer, idleTimeout time.Duration) (PacketListener, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if idleTimeout < 0 {
+		return nil, errors.New("argument idleTimeout must not be negative")
+	}
+	if idleTimeout == 0 {
+		idleTimeout = DefaultNATTimeout
+	}
+	return &natPacketListener{dialer: dialer, idleTimeout: idleTimeout}, nil
+}
+
+// ListenPacket implements [PacketListener].ListenPacket.
+func (l *natPacketListener) ListenPacket(ctx context.Context) (net.PacketConn, error) {
+	return &natPacketConn{
+		dialer:         l.dialer,
+		idleTimeout:    l.idleTimeout,
+		mappings:       make(map[string]*natMapping),
+		packets:        make(chan natPacket, natQueueSize),
+		done:           make(chan struct{}),
+		deadlineChange: make(chan struct{}),
+	}, nil
+}
+
+// Number of received packets to queue until they are read.
+const natQueueSize = 64
+
+// natMapping is the connection to a destination.
+type natMapping struct {
+	conn       net.Conn
+	lastActive time.Time
+}
+
+type natPacket struct {
+	data []byte
+	addr net.Addr
+}
+
+// natPacketConn is the [net.PacketConn] returned by [natPacketListener].
+type natPacketConn struct {
+	dialer      PacketDialer
+	idleTimeout time.Duration
+	packets     chan natPacket
+	done        chan struct{}
+
+	mu       sync.Mutex
+	mappings map[string]*natMapping
+	closed   bool
+	// The read deadline. deadlineChange is closed and replaced when it changes.
+	readDeadline   time.Time
+	deadlineChange chan struct{}
+}
+
+var _ net.PacketConn = (*natPacketConn)(nil)
+
+// mapping returns the connection to addr, dialing it if needed.
+func (c *natPacketConn) mapping(addr string) (net.Conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	if m, ok := c.mappings[addr]; ok {
+		m.lastActive = time.Now()
+		c.mu.Unlock()
+		return m.conn, nil
+	}
+	c.mu.Unlock()
+
+	// Dial without the lock, since it may take a while.
+	conn, err := c.dialer.DialPacket(context.Background(), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+	if m, ok := c.mappings[addr]; ok {
+		// Another write created the mapping concurrently.
+		conn.Close()
+		m.lastActive = time.Now()
+		return m.conn, nil
+	}
+	m := &natMapping{conn: conn, lastActive: time.Now()}
+	c.mappings[addr] = m
+	go c.readLoop(addr, m)
+	return conn, nil
+}
+
+// readLoop forwards the packets from the mapping connection until it expires or fails.
+func (c *natPacketConn) readLoop(addr string, m *natMapping) {
+	defer func() {
+		c.mu.Lock()
+		if c.mappings[addr] == m {
+			delete(c.mappings, addr)
+		}
+		c.mu.Unlock()
+		m.conn.Close()
+	}()
+	remoteAddr := m.conn.RemoteAddr()
+	buf := make([]byte, 64*1024)
+	for {
+		c.mu.Lock()
+		expiry := m.lastActive.Add(c.idleTimeout)
+		c.mu.Unlock()
+		m.conn.SetReadDeadline(expiry)
+		n, err := m.conn.Read(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				c.mu.Lock()
+				expired := !time.Now().Before(m.lastActive.Add(c.idleTimeout))
+				c.mu.Unlock()
+				if !expired {
+					// A packet was sent in the meantime.
+					continue
+				}
+			}
+			return
+		}
+		c.mu.Lock()
+		m.lastActive = time.Now()
+		c.mu.Unlock()
+		// Drop the packet if the queue is full, like a socket with a full receive buffer, so that a slow reader
+		// doesn't block the expiration.
+		select {
+		case c.packets <- natPacket{data: append([]byte(nil), buf[:n]...), addr: remoteAddr}:
+		case <-c.done:
+			return
+		default:
+		}
+	}
+}
+
+// WriteTo implements [net.PacketConn].WriteTo.
+func (c *natPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	conn, err := c.mapping(addr.String())
+	if err != nil {
+		return 0, err
+	}
+	return conn.Write(b)
+}
+
+// ReadFrom implements [net.PacketConn].ReadFrom. It returns [io.ErrShortBuffer] if b is too small for the packet.
+func (c *natPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		select {
+		case <-c.done:
+			return 0, nil, net.ErrClosed
+		default:
+		}
+		c.mu.Lock()
+		deadline, deadlineChange := c.readDeadline, c.deadlineChange
+		c.mu.Unlock()
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timer = time.NewTimer(time.Until(deadline))
+			timeout = timer.C
+		}
+		var packet natPacket
+		var err error
+		retry := false
+		select {
+		case packet = <-c.packets:
+		case <-c.done:
+			err = net.ErrClosed
+		case <-timeout:
+			err = os.ErrDeadlineExceeded
+		case <-deadlineChange:
+			retry = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if retry {
+			// Try again with the new deadline.
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		n := copy(b, packet.data)
+		if n < len(packet.data) {
+			return n, packet.addr, io.ErrShortBuffer
+		}
+		return n, packet.addr, nil
+	}
+}
+
+// Close implements [net.PacketConn].Close. It closes all the mappings.
+func (c *natPacketConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.closed = true
+	close(c.done)
+	for _, m := range c.mappings {
+		m.conn.Close()
+	}
+	return nil
+}
+
+// LocalAddr implements [net.PacketConn].LocalAddr. It returns an unspecified address, since each mapping has its
+// own local address.
+func (c *natPacketConn) LocalAddr() net.Addr {
+	return &net.UDPAddr{}
+}
+
+// SetDeadline implements [net.PacketConn].SetDeadline.
+func (c *natPacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements [net.PacketConn].SetReadDeadline.
+func (c *natPacketConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	close(c.deadlineChange)
+	c.deadlineChange = make(chan struct{})
+	return nil
+}
+
+// SetWriteDeadline implements [net.PacketConn].SetWriteDeadline. It's a no-op, since writes don't block
+// on the other mappings.
+func (c *natPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startUDPEchoServer starts a server that echoes the packets back with a prefix.
+func startUDPEchoServer(t *testing.T, prefix string) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(append([]byte(prefix), buf[:n]...), addr)
+		}
+	}()
+	return conn
+}
+
+func TestNATPacketListener(t *testing.T) {
+	server1 := startUDPEchoServer(t, "1:")
+	defer server1.Close()
+	server2 := startUDPEchoServer(t, "2:")
+	defer server2.Close()
+
+	listener, err := NewNATPacketListener(&UDPDialer{}, 0)
+	require.NoError(t, err)
+	conn, err := listener.ListenPacket(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	buf := make([]byte, 1024)
+	for _, server := range []*net.UDPConn{server1, server2, server1} {
+		_, err = conn.WriteTo([]byte("ping"), server.LocalAddr())
+		require.NoError(t, err)
+		n, addr, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		require.Equal(t, server.LocalAddr().String(), addr.String())
+		require.Contains(t, []string{"1:ping", "2:ping"}, string(buf[:n]))
+	}
+	require.Len(t, conn.(*natPacketConn).mappings, 2)
+}
+
+func TestNATPacketListener_IdleTimeout(t *testing.T) {
+	server := startUDPEchoServer(t, "")
+	defer server.Close()
+
+	listener, err := NewNATPacketListener(&UDPDialer{}, 50*time.Millisecond)
+	require.NoError(t, err)
+	conn, err := listener.ListenPacket(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	natConn := conn.(*natPacketConn)
+	mappingCount := func() int {
+		natConn.mu.Lock()
+		defer natConn.mu.Unlock()
+		return len(natConn.mappings)
+	}
+
+	_, err = conn.WriteTo([]byte("ping"), server.LocalAddr())
+	require.NoError(t, err)
+	require.Equal(t, 1, mappingCount())
+	require.Eventually(t, func() bool { return mappingCount() == 0 }, time.Second, 10*time.Millisecond)
+
+	// A new mapping is created after the old one expired.
+	_, err = conn.WriteTo([]byte("ping"), server.LocalAddr())
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf[:n]))
+}
+
+func TestNATPacketListener_ReadDeadline(t *testing.T) {
+	listener, err := NewNATPacketListener(&UDPDialer{}, 0)
+	require.NoError(t, err)
+	conn, err := listener.ListenPacket(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Move the deadline while the read is blocked.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Hour)))
+	time.AfterFunc(20*time.Millisecond, func() { conn.SetReadDeadline(time.Now()) })
+	_, _, err = conn.ReadFrom(make([]byte, 10))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestNATPacketListener_Close(t *testing.T) {
+	server := startUDPEchoServer(t, "")
+	defer server.Close()
+	listener, err := NewNATPacketListener(&UDPDialer{}, 0)
+	require.NoError(t, err)
+	conn, err := listener.ListenPacket(context.Background())
+	require.NoError(t, err)
+	_, err = conn.WriteTo([]byte("ping"), server.LocalAddr())
+	require.NoError(t, err)
+
+	readErr := make(chan error)
+	go func() {
+		// Leave the response unread, then close.
+		time.Sleep(20 * time.Millisecond)
+		_, _, err := conn.ReadFrom(make([]byte, 10))
+		readErr <- err
+	}()
+	require.NoError(t, conn.Close())
+	require.ErrorIs(t, <-readErr, net.ErrClosed)
+	_, err = conn.WriteTo([]byte("ping"), server.LocalAddr())
+	require.ErrorIs(t, err, net.ErrClosed)
+	require.ErrorIs(t, conn.Close(), net.ErrClosed)
+}
+
+func TestNewNATPacketListener_Invalid(t *testing.T) {
+	_, err := NewNATPacketListener(nil, 0)
+	require.Error(t, err)
+	_, err = NewNATPacketListener(&UDPDialer{}, -time.Second)
+	require.Error(t, err)
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package obfs provides a transport that scrambles all the bytes of a stream with a keyed stream cipher, to remove the
+plaintext signatures of the protocol it carries, like the TLS handshake or an HTTP request line, from naive traffic
+inspection.
+
+The scrambling is NOT authenticated encryption: it doesn't protect the integrity of the data, and it doesn't
+authenticate the peer. An attacker can flip bits of the stream without being detected, and anyone who knows the key
+can read the data. Use it only to hide signatures, on top of a protocol that provides its own security if needed,
+like TLS or Shadowsocks.
+
+The transport needs a cooperating peer with the same key: use [NewStreamDialer] on the client, and [WrapConn] on the
+connections accepted by the server. Both directions are scrambled.
+
+# Format
+
+Each direction of the stream starts with a random 12-byte nonce, followed by the data XORed with the [ChaCha20]
+keystream for the nonce. The ChaCha20 key is the SHA-256 hash of the shared key. The random nonce makes the
+keystream different for every stream and direction, so the same data never looks the same on the wire.
+
+	+---------+---------------------+
+	|  nonce  |  scrambled data ... |
+	+---------+---------------------+
+	|   12    |         var         |
+	+---------+---------------------+
+
+The nonce is sent together with the first write, so the stream doesn't start with a distinct 12-byte segment.
+
+[ChaCha20]: https://datatracker.ietf.org/doc/html/rfc8439
+*/
+package obfs
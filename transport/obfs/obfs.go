@@ -0,0 +1,124 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obfs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// NonceSize is the size of the random nonce at the start of each direction of the stream.
+const NonceSize = chacha20.NonceSize
+
+// Key is the key shared by the client and the server, already derived from the secret.
+type Key struct {
+	cipherKey [32]byte
+}
+
+// NewKey creates a [Key] from the given secret, which must not be empty.
+func NewKey(secret []byte) (*Key, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret must not be empty")
+	}
+	return &Key{cipherKey: sha256.Sum256(secret)}, nil
+}
+
+func (k *Key) newCipher(nonce []byte) (*chacha20.Cipher, error) {
+	cipher, err := chacha20.NewUnauthenticatedCipher(k.cipherKey[:], nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher, nil
+}
+
+// obfsWriter is an [io.Writer] that scrambles the data written to it.
+type obfsWriter struct {
+	writer io.Writer
+	key    *Key
+	cipher *chacha20.Cipher
+	buf    []byte
+}
+
+var _ io.Writer = (*obfsWriter)(nil)
+
+// NewWriter creates an [io.Writer] that scrambles the data written to it with the key, and writes it to writer.
+// The first write is prefixed with a random nonce.
+func NewWriter(writer io.Writer, key *Key) io.Writer {
+	return &obfsWriter{writer: writer, key: key}
+}
+
+// Write implements [io.Writer]. The data is scrambled before it's written, so a failed write can't be retried
+// and the stream is broken.
+func (w *obfsWriter) Write(data []byte) (int, error) {
+	w.buf = w.buf[:0]
+	if w.cipher == nil {
+		nonce := make([]byte, NonceSize)
+		if _, err := rand.Read(nonce); err != nil {
+			return 0, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		cipher, err := w.key.newCipher(nonce)
+		if err != nil {
+			return 0, err
+		}
+		w.cipher = cipher
+		w.buf = append(w.buf, nonce...)
+	}
+	prefixLen := len(w.buf)
+	w.buf = append(w.buf, data...)
+	w.cipher.XORKeyStream(w.buf[prefixLen:], w.buf[prefixLen:])
+	n, err := w.writer.Write(w.buf)
+	if n < prefixLen {
+		return 0, err
+	}
+	return n - prefixLen, err
+}
+
+// obfsReader is an [io.Reader] that unscrambles the data read from it.
+type obfsReader struct {
+	reader io.Reader
+	key    *Key
+	cipher *chacha20.Cipher
+}
+
+var _ io.Reader = (*obfsReader)(nil)
+
+// NewReader creates an [io.Reader] that reads the stream sent by a writer created with [NewWriter] and the same key,
+// and returns the unscrambled data. It returns [io.ErrUnexpectedEOF] if the stream ends in the middle of the nonce.
+func NewReader(reader io.Reader, key *Key) io.Reader {
+	return &obfsReader{reader: reader, key: key}
+}
+
+// Read implements [io.Reader].
+func (r *obfsReader) Read(b []byte) (int, error) {
+	if r.cipher == nil {
+		nonce := make([]byte, NonceSize)
+		if _, err := io.ReadFull(r.reader, nonce); err != nil {
+			return 0, err
+		}
+		cipher, err := r.key.newCipher(nonce)
+		if err != nil {
+			return 0, err
+		}
+		r.cipher = cipher
+	}
+	n, err := r.reader.Read(b)
+	r.cipher.XORKeyStream(b[:n], b[:n])
+	return n, err
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obfs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T, secret string) *Key {
+	key, err := NewKey([]byte(secret))
+	require.NoError(t, err)
+	return key
+}
+
+func TestNewKey_Empty(t *testing.T) {
+	_, err := NewKey(nil)
+	require.Error(t, err)
+}
+
+func TestWriter_Scrambles(t *testing.T) {
+	key := newTestKey(t, "secret")
+	var buf bytes.Buffer
+	w := NewWriter(&buf, key)
+	data := []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	n, err := w.Write(data)
+	require.NoError(t, err)
+	require.Equal(t, len(data), n)
+	// The input is not modified.
+	require.Equal(t, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", string(data))
+	require.Equal(t, NonceSize+len(data), buf.Len())
+	require.NotContains(t, buf.String(), "HTTP")
+
+	// The same data looks different in every stream.
+	var buf2 bytes.Buffer
+	_, err = NewWriter(&buf2, key).Write(data)
+	require.NoError(t, err)
+	require.NotEqual(t, buf.Bytes(), buf2.Bytes())
+}
+
+func TestReader_Reassembly(t *testing.T) {
+	key := newTestKey(t, "secret")
+	var buf bytes.Buffer
+	w := NewWriter(&buf, key)
+	var expected []byte
+	for i := 0; i < 50; i++ {
+		data := bytes.Repeat([]byte{byte(i)}, i*7)
+		expected = append(expected, data...)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+	}
+
+	// Read one byte at a time, so the nonce and the writes get split.
+	data, err := io.ReadAll(NewReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes())), key))
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	// Read with a buffer that doesn't align with the writes.
+	r := NewReader(bytes.NewReader(buf.Bytes()), key)
+	data = nil
+	chunk := make([]byte, 13)
+	for {
+		n, err := r.Read(chunk)
+		data = append(data, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, expected, data)
+}
+
+func TestReader_WrongKey(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf, newTestKey(t, "secret")).Write([]byte("Hello"))
+	require.NoError(t, err)
+	data, err := io.ReadAll(NewReader(&buf, newTestKey(t, "other")))
+	require.NoError(t, err)
+	require.NotEqual(t, []byte("Hello"), data)
+}
+
+func TestReader_Truncated(t *testing.T) {
+	key := newTestKey(t, "secret")
+	data, err := io.ReadAll(NewReader(bytes.NewReader(nil), key))
+	require.NoError(t, err)
+	require.Empty(t, data)
+	_, err = io.ReadAll(NewReader(bytes.NewReader(make([]byte, NonceSize-1)), key))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obfs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// obfsDialer is a [transport.StreamDialer] that scrambles the streams.
+// Use [NewStreamDialer] to create new instances.
+type obfsDialer struct {
+	dialer transport.StreamDialer
+	key    *Key
+}
+
+var _ transport.StreamDialer = (*obfsDialer)(nil)
+var _ transport.ConnectFailureReporter = (*obfsDialer)(nil)
+
+// NewStreamDialer creates a [transport.StreamDialer] that scrambles the streams with the key, and unscrambles the data
+// it receives. The server must wrap its connections with [WrapConn] and the same key.
+func NewStreamDialer(dialer transport.StreamDialer, key *Key) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if key == nil {
+		return nil, errors.New("argument key must not be nil")
+	}
+	return &obfsDialer{dialer: dialer, key: key}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *obfsDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := WrapConn(innerConn, d.key)
+	if err != nil {
+		innerConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *obfsDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+// WrapConn returns a [transport.StreamConn] that scrambles the data written to conn and unscrambles the data read
+// from it. Servers use it on the accepted connections to talk to clients created with [NewStreamDialer].
+func WrapConn(conn transport.StreamConn, key *Key) (transport.StreamConn, error) {
+	if conn == nil {
+		return nil, errors.New("argument conn must not be nil")
+	}
+	if key == nil {
+		return nil, errors.New("argument key must not be nil")
+	}
+	return transport.WrapConn(conn, NewReader(conn, key), NewWriter(conn, key)), nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obfs
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamDialer(t *testing.T) {
+	key := newTestKey(t, "secret")
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		tcpConn, err := listener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		conn, err := WrapConn(tcpConn, key)
+		if err != nil {
+			tcpConn.Close()
+			return
+		}
+		defer conn.Close()
+		// Echo the data back, scrambled.
+		io.Copy(conn, conn)
+		conn.CloseWrite()
+	}()
+
+	dialer, err := NewStreamDialer(&transport.TCPDialer{}, key)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("Request"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(" in two writes"))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "Request in two writes", string(response))
+}
+
+func TestNewStreamDialer_NilArguments(t *testing.T) {
+	_, err := NewStreamDialer(nil, newTestKey(t, "secret"))
+	require.Error(t, err)
+	_, err = NewStreamDialer(&transport.TCPDialer{}, nil)
+	require.Error(t, err)
+}
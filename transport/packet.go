@@ -16,8 +16,12 @@ package transport
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
+	"syscall"
 )
 
 // PacketEndpoint represents an endpoint that can be used to establish packet connections (like UDP) to a fixed destination.
@@ -74,17 +78,67 @@ type PacketDialer interface {
 
 // UDPDialer is a [PacketDialer] that uses the standard [net.Dialer] to dial.
 // It provides a convenient way to use a [net.Dialer] when you need a [PacketDialer].
+//
+// Each call to DialPacket creates a new socket, bound to a new ephemeral source port.
 type UDPDialer struct {
 	Dialer net.Dialer
+	// RandomizeSourcePort makes DialPacket bind to a source port picked uniformly at random from the
+	// range 1024-65535, as recommended by RFC 6056, instead of letting the OS pick it. Some systems
+	// allocate ephemeral ports sequentially or from a small range, which makes it easier for off-path
+	// attackers to spoof responses, for example to plaintext DNS queries.
+	// It's ignored if the Dialer has a LocalAddr with a non-zero port.
+	RandomizeSourcePort bool
 }
 
 var _ PacketDialer = (*UDPDialer)(nil)
 
+// maxSourcePortAttempts is the number of random source ports to try before letting the OS pick one.
+const maxSourcePortAttempts = 10
+
 // DialPacket implements [PacketDialer].DialPacket.
 func (d *UDPDialer) DialPacket(ctx context.Context, addr string) (net.Conn, error) {
+	if !d.RandomizeSourcePort {
+		return d.Dialer.DialContext(ctx, "udp", addr)
+	}
+	localAddr, ok := d.Dialer.LocalAddr.(*net.UDPAddr)
+	if d.Dialer.LocalAddr != nil && (!ok || localAddr.Port != 0) {
+		return d.Dialer.DialContext(ctx, "udp", addr)
+	}
+	dialer := d.Dialer
+	for attempt := 0; attempt < maxSourcePortAttempts; attempt++ {
+		port, err := randomSourcePort()
+		if err != nil {
+			break
+		}
+		randomAddr := &net.UDPAddr{Port: port}
+		if localAddr != nil {
+			randomAddr.IP = localAddr.IP
+			randomAddr.Zone = localAddr.Zone
+		}
+		dialer.LocalAddr = randomAddr
+		conn, err := dialer.DialContext(ctx, "udp", addr)
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return conn, err
+		}
+	}
 	return d.Dialer.DialContext(ctx, "udp", addr)
 }
 
+// randomSourcePort returns a port in the range 1024-65535, using a cryptographically secure source.
+func randomSourcePort() (int, error) {
+	const minPort = 1024
+	var b [2]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
+		}
+		// Reject the ports below the range instead of using a modulo, which would make some ports more likely.
+		if port := int(binary.BigEndian.Uint16(b[:])); port >= minPort {
+			return port, nil
+		}
+	}
+}
+
 // PacketListenerDialer is a [PacketDialer] that connects to the destination using the specified [PacketListener].
 type PacketListenerDialer struct {
 	// The PacketListener that is used to create the net.PacketConn to bind on Dial. Must be non nil.
@@ -163,6 +163,48 @@ func TestUDPPacketDialer(t *testing.T) {
 	require.Equal(t, response, receivedResponse[:n])
 }
 
+func TestUDPPacketDialer_RandomizeSourcePort(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+
+	dialer := &UDPDialer{RandomizeSourcePort: true}
+	ports := make(map[int]bool)
+	for i := 0; i < 10; i++ {
+		conn, err := dialer.DialPacket(context.Background(), server.LocalAddr().String())
+		require.NoError(t, err)
+		port := conn.LocalAddr().(*net.UDPAddr).Port
+		require.GreaterOrEqual(t, port, 1024)
+		ports[port] = true
+
+		_, err = conn.Write([]byte("PING"))
+		require.NoError(t, err)
+		_, clientAddr, err := server.ReadFrom(make([]byte, 5))
+		require.NoError(t, err)
+		require.Equal(t, port, clientAddr.(*net.UDPAddr).Port)
+		conn.Close()
+	}
+	// Each dial should get a different port, except for unlikely collisions.
+	require.Greater(t, len(ports), 5)
+}
+
+func TestUDPPacketDialer_RandomizeSourcePortKeepsFixedPort(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+	// Find a free port.
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	localAddr := probe.LocalAddr().(*net.UDPAddr)
+	probe.Close()
+
+	dialer := &UDPDialer{Dialer: net.Dialer{LocalAddr: localAddr}, RandomizeSourcePort: true}
+	conn, err := dialer.DialPacket(context.Background(), server.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, localAddr.Port, conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
 // PacketListenerDialer
 
 func TestPacketListenerDialer(t *testing.T) {
@@ -0,0 +1,37 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package pad provides a transport that pads the stream into frames of a fixed or random size, to resist traffic analysis
+based on the size of the writes.
+
+Since the padding must be removed, the transport needs a cooperating peer: use [NewStreamDialer] on the client, and
+[WrapConn] on the connections accepted by the server. Both directions are padded.
+
+# Framing
+
+Each write is sent as one or more frames. A frame has a 4-byte header with the data length and the padding length,
+as 16-bit big-endian integers, followed by the data and by the padding, which is zeros:
+
+	+-------------+----------------+--------+-------------+
+	| data length | padding length |  data  |   padding   |
+	+-------------+----------------+--------+-------------+
+	|      2      |       2        |  var   |     var     |
+	+-------------+----------------+--------+-------------+
+
+The size of each frame, including the header, is picked at random between a minimum and a maximum size, which can be
+the same for a fixed block size. Writes that don't fit in one frame are split into multiple frames.
+The reader ignores the padding content, and accepts frames of any size.
+*/
+package pad
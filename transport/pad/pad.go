@@ -0,0 +1,155 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pad
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// HeaderSize is the size of the frame header.
+const HeaderSize = 4
+
+// MaxFrameSize is the maximum frame size the writer produces, including the header.
+const MaxFrameSize = HeaderSize + 0xFFFF
+
+// padWriter is an [io.Writer] that sends each write as padded frames.
+type padWriter struct {
+	writer  io.Writer
+	minSize int
+	maxSize int
+	frame   []byte
+}
+
+var _ io.Writer = (*padWriter)(nil)
+
+// NewWriter creates an [io.Writer] that sends the data written to it as frames, each with a size picked at random
+// between minSize and maxSize, inclusive. Use the same value for a fixed frame size. The sizes include the header,
+// so they must be larger than [HeaderSize], and no larger than [MaxFrameSize].
+func NewWriter(writer io.Writer, minSize, maxSize int) (io.Writer, error) {
+	if writer == nil {
+		return nil, errors.New("argument writer must not be nil")
+	}
+	if err := checkSizes(minSize, maxSize); err != nil {
+		return nil, err
+	}
+	return &padWriter{writer: writer, minSize: minSize, maxSize: maxSize, frame: make([]byte, 0, maxSize)}, nil
+}
+
+func checkSizes(minSize, maxSize int) error {
+	if minSize <= HeaderSize {
+		return fmt.Errorf("minimum size must be larger than the %v-byte header, found %v", HeaderSize, minSize)
+	}
+	if maxSize < minSize {
+		return fmt.Errorf("maximum size must not be lower than minimum size, found %v < %v", maxSize, minSize)
+	}
+	if maxSize > MaxFrameSize {
+		return fmt.Errorf("maximum size must be at most %v, found %v", MaxFrameSize, maxSize)
+	}
+	return nil
+}
+
+// nextFrameSize returns a random frame size in the configured range.
+func (w *padWriter) nextFrameSize() int {
+	size := w.minSize
+	if delta := w.maxSize - w.minSize; delta > 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(delta)+1))
+		if err == nil {
+			size += int(n.Int64())
+		}
+	}
+	return size
+}
+
+// Write implements [io.Writer]. It returns the number of bytes of data sent, which only counts the data of the frames
+// that were fully written.
+func (w *padWriter) Write(data []byte) (int, error) {
+	written := 0
+	for written < len(data) {
+		frameSize := w.nextFrameSize()
+		dataLen := len(data) - written
+		if dataLen > frameSize-HeaderSize {
+			dataLen = frameSize - HeaderSize
+		}
+		paddingLen := frameSize - HeaderSize - dataLen
+		frame := binary.BigEndian.AppendUint16(w.frame[:0], uint16(dataLen))
+		frame = binary.BigEndian.AppendUint16(frame, uint16(paddingLen))
+		frame = append(frame, data[written:written+dataLen]...)
+		frame = append(frame, make([]byte, paddingLen)...)
+		if _, err := w.writer.Write(frame); err != nil {
+			return written, err
+		}
+		written += dataLen
+	}
+	return written, nil
+}
+
+// padReader is an [io.Reader] that removes the framing and the padding.
+type padReader struct {
+	reader      io.Reader
+	header      [HeaderSize]byte
+	dataLeft    int
+	paddingLeft int
+}
+
+var _ io.Reader = (*padReader)(nil)
+
+// NewReader creates an [io.Reader] that reads the frames sent by a writer created with [NewWriter], and returns the
+// data without the padding. It returns [io.ErrUnexpectedEOF] if the stream ends in the middle of a frame.
+func NewReader(reader io.Reader) io.Reader {
+	return &padReader{reader: reader}
+}
+
+// Read implements [io.Reader].
+func (r *padReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for r.dataLeft == 0 {
+		if r.paddingLeft > 0 {
+			n, err := io.CopyN(io.Discard, r.reader, int64(r.paddingLeft))
+			r.paddingLeft -= int(n)
+			if err != nil {
+				return 0, unexpectedEOF(err)
+			}
+		}
+		if _, err := io.ReadFull(r.reader, r.header[:]); err != nil {
+			// An EOF before any header byte is the clean end of the stream.
+			return 0, err
+		}
+		r.dataLeft = int(binary.BigEndian.Uint16(r.header[:2]))
+		r.paddingLeft = int(binary.BigEndian.Uint16(r.header[2:]))
+	}
+	if len(b) > r.dataLeft {
+		b = b[:r.dataLeft]
+	}
+	n, err := r.reader.Read(b)
+	r.dataLeft -= n
+	if err == io.EOF && (r.dataLeft > 0 || r.paddingLeft > 0) {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
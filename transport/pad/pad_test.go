@@ -0,0 +1,151 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// frameSizes parses the frames in the stream and returns their sizes.
+func frameSizes(t *testing.T, stream []byte) []int {
+	var sizes []int
+	for len(stream) > 0 {
+		require.GreaterOrEqual(t, len(stream), HeaderSize)
+		size := HeaderSize + int(binary.BigEndian.Uint16(stream)) + int(binary.BigEndian.Uint16(stream[2:]))
+		require.GreaterOrEqual(t, len(stream), size)
+		sizes = append(sizes, size)
+		stream = stream[size:]
+	}
+	return sizes
+}
+
+func TestWriter_FixedSize(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, 16, 16)
+	require.NoError(t, err)
+	n, err := w.Write([]byte("Hello"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, append([]byte{0, 5, 0, 7, 'H', 'e', 'l', 'l', 'o'}, make([]byte, 7)...), buf.Bytes())
+
+	// Writes larger than a frame are split.
+	buf.Reset()
+	n, err = w.Write(bytes.Repeat([]byte{1}, 30))
+	require.NoError(t, err)
+	require.Equal(t, 30, n)
+	require.Equal(t, []int{16, 16, 16}, frameSizes(t, buf.Bytes()))
+}
+
+func TestWriter_RandomSize(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, 10, 20)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err := w.Write([]byte{byte(i)})
+		require.NoError(t, err)
+	}
+	sizes := frameSizes(t, buf.Bytes())
+	require.Len(t, sizes, 100)
+	for _, size := range sizes {
+		require.GreaterOrEqual(t, size, 10)
+		require.LessOrEqual(t, size, 20)
+	}
+}
+
+func TestNewWriter_InvalidSizes(t *testing.T) {
+	for _, sizes := range [][2]int{{0, 10}, {HeaderSize, 10}, {10, 9}, {10, MaxFrameSize + 1}} {
+		_, err := NewWriter(io.Discard, sizes[0], sizes[1])
+		require.Error(t, err, sizes)
+	}
+}
+
+// limitedWriter fails after accepting a number of writes.
+type limitedWriter struct {
+	bytes.Buffer
+	writesLeft int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if w.writesLeft == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.writesLeft--
+	return w.Buffer.Write(b)
+}
+
+func TestWriter_PartialWrite(t *testing.T) {
+	inner := &limitedWriter{writesLeft: 2}
+	w, err := NewWriter(inner, 10, 10)
+	require.NoError(t, err)
+	n, err := w.Write([]byte("0123456789ABCDEF"))
+	require.Error(t, err)
+	// Only the data in the two frames that were written is reported.
+	require.Equal(t, 12, n)
+
+	r := NewReader(bytes.NewReader(inner.Bytes()))
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789AB"), data)
+}
+
+func TestReader_Reassembly(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, 5, 64)
+	require.NoError(t, err)
+	var expected []byte
+	for i := 0; i < 50; i++ {
+		data := bytes.Repeat([]byte{byte(i)}, i*7)
+		expected = append(expected, data...)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+	}
+
+	// Read one byte at a time, so headers, data and padding get split.
+	data, err := io.ReadAll(NewReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes()))))
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	// Read with a small buffer, so frames need multiple reads.
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	data = nil
+	chunk := make([]byte, 3)
+	for {
+		n, err := r.Read(chunk)
+		data = append(data, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, expected, data)
+}
+
+func TestReader_Truncated(t *testing.T) {
+	frame := append([]byte{0, 5, 0, 3, 'H', 'e', 'l', 'l', 'o'}, 0, 0, 0)
+	for _, size := range []int{2, 6, 9, 11} {
+		_, err := io.ReadAll(NewReader(bytes.NewReader(frame[:size])))
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF, size)
+	}
+	data, err := io.ReadAll(NewReader(bytes.NewReader(frame)))
+	require.NoError(t, err)
+	require.Equal(t, []byte("Hello"), data)
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pad
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// padDialer is a [transport.StreamDialer] that pads the streams.
+// Use [NewStreamDialer] to create new instances.
+type padDialer struct {
+	dialer  transport.StreamDialer
+	minSize int
+	maxSize int
+}
+
+var _ transport.StreamDialer = (*padDialer)(nil)
+var _ transport.ConnectFailureReporter = (*padDialer)(nil)
+
+// NewStreamDialer creates a [transport.StreamDialer] that pads the streams into frames with a size between minSize and
+// maxSize, and strips the padding of the frames it receives. See [NewWriter] for the sizes.
+// The server must wrap its connections with [WrapConn].
+func NewStreamDialer(dialer transport.StreamDialer, minSize, maxSize int) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if err := checkSizes(minSize, maxSize); err != nil {
+		return nil, err
+	}
+	return &padDialer{dialer: dialer, minSize: minSize, maxSize: maxSize}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *padDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := WrapConn(innerConn, d.minSize, d.maxSize)
+	if err != nil {
+		innerConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *padDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+// WrapConn returns a [transport.StreamConn] that pads the data written to conn and strips the padding of the data read
+// from it. Servers use it on the accepted connections to talk to clients created with [NewStreamDialer].
+func WrapConn(conn transport.StreamConn, minSize, maxSize int) (transport.StreamConn, error) {
+	if conn == nil {
+		return nil, errors.New("argument conn must not be nil")
+	}
+	writer, err := NewWriter(conn, minSize, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return transport.WrapConn(conn, NewReader(conn), writer), nil
+}
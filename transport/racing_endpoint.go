@@ -0,0 +1,134 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+RacingStreamEndpoint is a [StreamEndpoint] that races connections to a list of endpoints, like multiple proxy servers,
+and returns the first successful connection. Use it for failover between equivalent servers. Unlike
+[HappyEyeballsStreamDialer], it doesn't resolve any host name.
+
+The attempts start in the order of the endpoints, with AttemptDelay between them. If an attempt fails before the delay
+is over, the next one starts right away. Connections that are established after the race is decided are closed.
+*/
+type RacingStreamEndpoint struct {
+	endpoints []StreamEndpoint
+	// AttemptDelay is the delay before starting the next attempt. It defaults to 250ms.
+	AttemptDelay time.Duration
+}
+
+var _ StreamEndpoint = (*RacingStreamEndpoint)(nil)
+
+// NewRacingStreamEndpoint creates a [RacingStreamEndpoint] that races the given endpoints, in order of preference.
+// You can use [*StreamDialerEndpoint] to connect to a fixed address with a [StreamDialer].
+func NewRacingStreamEndpoint(endpoints []StreamEndpoint) (*RacingStreamEndpoint, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("argument endpoints must not be empty")
+	}
+	for i, endpoint := range endpoints {
+		if endpoint == nil {
+			return nil, fmt.Errorf("endpoint %v must not be nil", i)
+		}
+	}
+	return &RacingStreamEndpoint{endpoints: append([]StreamEndpoint{}, endpoints...), AttemptDelay: 250 * time.Millisecond}, nil
+}
+
+// ConnectStream implements [StreamEndpoint].ConnectStream.
+func (e *RacingStreamEndpoint) ConnectStream(ctx context.Context) (StreamConn, error) {
+	conn, _, err := e.Race(ctx)
+	return conn, err
+}
+
+// Race is like ConnectStream, but it also returns the index of the endpoint that won the race, which is useful for
+// logging. The error joins the errors of all the attempts if none succeeds.
+func (e *RacingStreamEndpoint) Race(ctx context.Context) (StreamConn, int, error) {
+	// Indicates to attempts that the race is done, so they don't get stuck.
+	ctx, raceDone := context.WithCancel(ctx)
+	defer raceDone()
+
+	type attemptResult struct {
+		Conn  StreamConn
+		Index int
+		Err   error
+	}
+	resultCh := make(chan attemptResult)
+	var attemptErr error
+	var delayTimer *time.Timer
+	var delayCh <-chan time.Time
+	next := 0
+	startAttempt := func() {
+		index := next
+		next++
+		go func(endpoint StreamEndpoint) {
+			conn, err := endpoint.ConnectStream(ctx)
+			if err != nil {
+				err = fmt.Errorf("endpoint %v: %w", index, err)
+			}
+			select {
+			case <-ctx.Done():
+				if conn != nil {
+					conn.Close()
+				}
+			case resultCh <- attemptResult{conn, index, err}:
+			}
+		}(e.endpoints[index])
+		if delayTimer != nil {
+			delayTimer.Stop()
+		}
+		if next < len(e.endpoints) {
+			delayTimer = time.NewTimer(e.AttemptDelay)
+			delayCh = delayTimer.C
+		} else {
+			delayTimer = nil
+			delayCh = nil
+		}
+	}
+	defer func() {
+		if delayTimer != nil {
+			delayTimer.Stop()
+		}
+	}()
+
+	startAttempt()
+	for pending := 1; pending > 0; {
+		select {
+		case <-delayCh:
+			pending++
+			startAttempt()
+
+		case result := <-resultCh:
+			pending--
+			if result.Err == nil {
+				return result.Conn, result.Index, nil
+			}
+			attemptErr = errors.Join(attemptErr, result.Err)
+			// Don't wait for the delay if the attempt failed.
+			if next < len(e.endpoints) {
+				pending++
+				startAttempt()
+			}
+
+		case <-ctx.Done():
+			return nil, -1, ctx.Err()
+		}
+	}
+	return nil, -1, attemptErr
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRacingStreamEndpointFastestWins(t *testing.T) {
+	slowClosed := make(chan struct{})
+	slow := FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) {
+		time.Sleep(50 * time.Millisecond)
+		return &closeNotifyConn{closed: slowClosed}, nil
+	})
+	fastConn := &closeNotifyConn{closed: make(chan struct{})}
+	fast := FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) {
+		return fastConn, nil
+	})
+	e, err := NewRacingStreamEndpoint([]StreamEndpoint{slow, fast})
+	require.NoError(t, err)
+	e.AttemptDelay = 10 * time.Millisecond
+
+	conn, index, err := e.Race(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, index)
+	require.Same(t, fastConn, conn)
+
+	// The slow connection is closed after it's established.
+	select {
+	case <-slowClosed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("losing connection was not closed")
+	}
+}
+
+func TestRacingStreamEndpointFailover(t *testing.T) {
+	var started []int
+	failing := FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) {
+		started = append(started, 0)
+		return nil, errors.New("blocked")
+	})
+	workingConn := &closeNotifyConn{closed: make(chan struct{})}
+	working := FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) {
+		started = append(started, 1)
+		return workingConn, nil
+	})
+	e, err := NewRacingStreamEndpoint([]StreamEndpoint{failing, working})
+	require.NoError(t, err)
+	// The next attempt starts as soon as the previous fails, without waiting for the delay.
+	e.AttemptDelay = time.Hour
+
+	conn, err := e.ConnectStream(context.Background())
+	require.NoError(t, err)
+	require.Same(t, workingConn, conn)
+	require.Equal(t, []int{0, 1}, started)
+}
+
+func TestRacingStreamEndpointAllFail(t *testing.T) {
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+	e, err := NewRacingStreamEndpoint([]StreamEndpoint{
+		FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) { return nil, err1 }),
+		FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) { return nil, err2 }),
+	})
+	require.NoError(t, err)
+	_, index, err := e.Race(context.Background())
+	require.Equal(t, -1, index)
+	require.ErrorIs(t, err, err1)
+	require.ErrorIs(t, err, err2)
+}
+
+func TestRacingStreamEndpointCancelled(t *testing.T) {
+	e, err := NewRacingStreamEndpoint([]StreamEndpoint{
+		FuncStreamEndpoint(func(ctx context.Context) (StreamConn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = e.ConnectStream(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewRacingStreamEndpointInvalidArguments(t *testing.T) {
+	_, err := NewRacingStreamEndpoint(nil)
+	require.Error(t, err)
+	_, err = NewRacingStreamEndpoint([]StreamEndpoint{nil})
+	require.Error(t, err)
+}
+
+func ExampleRacingStreamEndpoint_Race() {
+	// Two servers of the same service. The first one is blocked.
+	blockedDialer := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		return nil, errors.New("blocked")
+	})
+	workingDialer := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		return &closeNotifyConn{closed: make(chan struct{})}, nil
+	})
+	servers := []*StreamDialerEndpoint{
+		{Dialer: blockedDialer, Address: "192.0.2.1:443"},
+		{Dialer: workingDialer, Address: "192.0.2.2:443"},
+	}
+	endpoints := make([]StreamEndpoint, 0, len(servers))
+	for _, server := range servers {
+		endpoints = append(endpoints, server)
+	}
+	racer, err := NewRacingStreamEndpoint(endpoints)
+	if err != nil {
+		panic(err)
+	}
+	conn, index, err := racer.Race(context.Background())
+	if err != nil {
+		fmt.Println("all servers failed:", err)
+		return
+	}
+	defer conn.Close()
+	fmt.Println("connected to", servers[index].Address)
+	// Output:
+	// connected to 192.0.2.2:443
+}
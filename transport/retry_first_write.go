@@ -0,0 +1,291 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxRetryBufferSize is the maximum amount of data the connections of [NewRetryFirstWriteDialer] buffer for the replay.
+// It's enough for a TLS ClientHello, which is the usual target of the blocking.
+const maxRetryBufferSize = 64 * 1024
+
+// retryFirstWriteDialer is a [StreamDialer] that re-dials and replays the first write on an early reset.
+type retryFirstWriteDialer struct {
+	dialer     StreamDialer
+	maxRetries int
+}
+
+var _ StreamDialer = (*retryFirstWriteDialer)(nil)
+var _ ConnectFailureReporter = (*retryFirstWriteDialer)(nil)
+
+// NewRetryFirstWriteDialer creates a [StreamDialer] whose connections recover from resets that happen before any data
+// is read, like the RST that DPI-based blocking often injects right after the TLS ClientHello. The connections buffer
+// what is written until the first successful read. If the connection is reset before that, they transparently re-dial
+// with the base dialer and replay the buffered data, up to maxRetries times per connection.
+//
+// The buffering stops after the first successful read, after [StreamConn.CloseRead] or [StreamConn.CloseWrite], or if
+// the written data exceeds 64 KiB, so that long-lived connections don't grow the memory usage. After that, resets are
+// returned as usual. The re-dials are not bound to the context of the original dial, but Close aborts them.
+//
+// The replay may deliver the same data more than once: the server may have received the data on the reset connection
+// before the reset, and it can't tell that the new connection is a retry. Only use it with protocols where that's safe,
+// like a TLS handshake, and not with requests that have side effects and no protection against replays.
+func NewRetryFirstWriteDialer(dialer StreamDialer, maxRetries int) (StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if maxRetries < 0 {
+		return nil, errors.New("maxRetries must not be negative")
+	}
+	return &retryFirstWriteDialer{dialer: dialer, maxRetries: maxRetries}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *retryFirstWriteDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
+	conn, err := d.dialer.DialStream(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	redialCtx, cancelRedial := context.WithCancel(context.Background())
+	return &retryFirstWriteConn{
+		dialer:       d.dialer,
+		redialCtx:    redialCtx,
+		cancelRedial: cancelRedial,
+		addr:         addr,
+		conn:         conn,
+		buffering:    true,
+		retriesLeft:  d.maxRetries,
+	}, nil
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter] with the answer of the base dialer.
+func (d *retryFirstWriteDialer) ReportsConnectFailure() bool {
+	return ReportsConnectFailure(d.dialer)
+}
+
+// retryFirstWriteConn is the [StreamConn] returned by [retryFirstWriteDialer].
+type retryFirstWriteConn struct {
+	dialer StreamDialer
+	addr   string
+	// redialCtx is canceled on Close, to abort a pending re-dial.
+	redialCtx    context.Context
+	cancelRedial context.CancelFunc
+
+	mu   sync.Mutex
+	conn StreamConn
+	// buffering is true while the writes are buffered for the replay, until the first successful read.
+	buffering   bool
+	buffer      []byte
+	retriesLeft int
+	closed      bool
+	// redialing is closed when the re-dial in progress finishes, and nil if there's none.
+	redialing chan struct{}
+	// pendingConn is the new connection of the re-dial in progress, before it replaces conn.
+	pendingConn StreamConn
+	// The deadlines to set on the new connections.
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+var _ StreamConn = (*retryFirstWriteConn)(nil)
+
+// isReset returns whether err is the result of a connection reset.
+func isReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// stopBuffering disables the buffering and the retries. It must be called with the lock held.
+func (c *retryFirstWriteConn) stopBuffering() {
+	c.buffering = false
+	c.buffer = nil
+}
+
+// waitRedial waits for the re-dial in progress, if any. It must be called with the lock held, which it releases while
+// waiting.
+func (c *retryFirstWriteConn) waitRedial() {
+	for c.redialing != nil {
+		done := c.redialing
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+	}
+}
+
+// redial replaces the failed connection with a new one where the buffer was replayed, if canRetry is true and there
+// are retries left. It returns whether failed was replaced, possibly by a concurrent call. In that case, the new
+// connection got all the data that was written before the failure.
+func (c *retryFirstWriteConn) redial(failed StreamConn, canRetry bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waitRedial()
+	if c.conn != failed {
+		return true
+	}
+	if !canRetry {
+		return false
+	}
+	done := make(chan struct{})
+	c.redialing = done
+	defer func() {
+		c.redialing = nil
+		c.pendingConn = nil
+		close(done)
+	}()
+	for c.buffering && !c.closed && c.retriesLeft > 0 {
+		c.retriesLeft--
+		// The writes wait for the re-dial, so the buffer doesn't change until it's done.
+		replay, writeDeadline := c.buffer, c.writeDeadline
+		c.mu.Unlock()
+		conn, err := c.dialer.DialStream(c.redialCtx, c.addr)
+		if err != nil {
+			c.mu.Lock()
+			return false
+		}
+		c.mu.Lock()
+		if c.closed {
+			conn.Close()
+			return false
+		}
+		c.pendingConn = conn
+		c.mu.Unlock()
+		conn.SetWriteDeadline(writeDeadline)
+		_, err = conn.Write(replay)
+		c.mu.Lock()
+		if err == nil && !c.closed {
+			conn.SetReadDeadline(c.readDeadline)
+			conn.SetWriteDeadline(c.writeDeadline)
+			c.conn = conn
+			failed.Close()
+			return true
+		}
+		conn.Close()
+		if !isReset(err) {
+			return false
+		}
+	}
+	return false
+}
+
+func (c *retryFirstWriteConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		conn, buffering := c.conn, c.buffering
+		c.mu.Unlock()
+		n, err := conn.Read(b)
+		if !buffering {
+			return n, err
+		}
+		if n > 0 || err == nil || !isReset(err) {
+			c.mu.Lock()
+			c.stopBuffering()
+			c.mu.Unlock()
+			return n, err
+		}
+		if !c.redial(conn, true) {
+			return n, err
+		}
+	}
+}
+
+func (c *retryFirstWriteConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	// Don't write to the failed connection while it's being replaced.
+	c.waitRedial()
+	conn, buffering := c.conn, c.buffering
+	if buffering {
+		if len(c.buffer)+len(b) > maxRetryBufferSize {
+			c.stopBuffering()
+			buffering = false
+		} else {
+			c.buffer = append(c.buffer, b...)
+		}
+	}
+	c.mu.Unlock()
+	n, err := conn.Write(b)
+	// If the connection was replaced, b was in the replayed buffer, even if the write failed because a concurrent
+	// re-dial closed the connection.
+	if buffering && err != nil && c.redial(conn, isReset(err)) {
+		return len(b), nil
+	}
+	return n, err
+}
+
+func (c *retryFirstWriteConn) CloseRead() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waitRedial()
+	c.stopBuffering()
+	return c.conn.CloseRead()
+}
+
+func (c *retryFirstWriteConn) CloseWrite() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waitRedial()
+	c.stopBuffering()
+	return c.conn.CloseWrite()
+}
+
+func (c *retryFirstWriteConn) Close() error {
+	c.cancelRedial()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.stopBuffering()
+	if c.pendingConn != nil {
+		// Abort the replay in progress.
+		c.pendingConn.Close()
+	}
+	return c.conn.Close()
+}
+
+func (c *retryFirstWriteConn) LocalAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.LocalAddr()
+}
+
+func (c *retryFirstWriteConn) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.RemoteAddr()
+}
+
+func (c *retryFirstWriteConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline, c.writeDeadline = t, t
+	return c.conn.SetDeadline(t)
+}
+
+func (c *retryFirstWriteConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *retryFirstWriteConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	return c.conn.SetWriteDeadline(t)
+}
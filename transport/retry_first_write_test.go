@@ -0,0 +1,237 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runResetServer runs a server that resets the given number of first connections after reading some data, and echoes the data
+// on the following ones. It returns the server address and the number of accepted connections.
+func runResetServer(t *testing.T, resets int) (string, *atomic.Int32) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			n := accepted.Add(1)
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				if int(n) <= resets {
+					conn.Read(buf)
+					// This forces a reset when the connection is closed.
+					conn.SetLinger(0)
+					return
+				}
+				io.CopyBuffer(conn, conn, buf)
+			}()
+		}
+	}()
+	return listener.Addr().String(), &accepted
+}
+
+func TestRetryFirstWriteDialer_Retries(t *testing.T) {
+	addr, accepted := runResetServer(t, 2)
+	dialer, err := NewRetryFirstWriteDialer(&TCPDialer{}, 2)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+	require.Equal(t, int32(3), accepted.Load())
+
+	// Later writes are not buffered, and reach the same connection.
+	_, err = conn.Write([]byte("world"))
+	require.NoError(t, err)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(buf))
+	require.Equal(t, int32(3), accepted.Load())
+}
+
+func TestRetryFirstWriteDialer_TooManyResets(t *testing.T) {
+	addr, accepted := runResetServer(t, 3)
+	dialer, err := NewRetryFirstWriteDialer(&TCPDialer{}, 2)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = conn.Read(make([]byte, 5))
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+	require.Equal(t, int32(3), accepted.Load())
+}
+
+func TestRetryFirstWriteDialer_NoRetries(t *testing.T) {
+	addr, accepted := runResetServer(t, 1)
+	dialer, err := NewRetryFirstWriteDialer(&TCPDialer{}, 0)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = conn.Read(make([]byte, 5))
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+	require.Equal(t, int32(1), accepted.Load())
+}
+
+func TestNewRetryFirstWriteDialer_InvalidArguments(t *testing.T) {
+	_, err := NewRetryFirstWriteDialer(nil, 1)
+	require.Error(t, err)
+	_, err = NewRetryFirstWriteDialer(&TCPDialer{}, -1)
+	require.Error(t, err)
+}
+
+// scriptedConn is a [StreamConn] whose writes are handled by onWrite.
+type scriptedConn struct {
+	StreamConn
+	onWrite   func(b []byte) (int, error)
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newScriptedConn(onWrite func(b []byte) (int, error)) *scriptedConn {
+	return &scriptedConn{onWrite: onWrite, closed: make(chan struct{})}
+}
+
+func (c *scriptedConn) Write(b []byte) (int, error) { return c.onWrite(b) }
+
+func (c *scriptedConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *scriptedConn) RemoteAddr() net.Addr               { return &net.TCPAddr{} }
+func (c *scriptedConn) SetDeadline(t time.Time) error      { return nil }
+func (c *scriptedConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *scriptedConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func TestRetryFirstWriteDialer_RedialDoesNotBlockConn(t *testing.T) {
+	first := newScriptedConn(func(b []byte) (int, error) { return 0, syscall.ECONNRESET })
+	redialStarted := make(chan struct{})
+	var dials atomic.Int32
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		if dials.Add(1) == 1 {
+			return first, nil
+		}
+		close(redialStarted)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	dialer, err := NewRetryFirstWriteDialer(base, 1)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+
+	writeDone := make(chan error, 1)
+	go func() {
+		_, err := conn.Write([]byte("hello"))
+		writeDone <- err
+	}()
+	<-redialStarted
+	// The connection is usable while the re-dial is pending.
+	done := make(chan struct{})
+	go func() {
+		conn.RemoteAddr()
+		conn.SetDeadline(time.Time{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection blocked by the re-dial")
+	}
+	require.NoError(t, conn.Close())
+	require.ErrorIs(t, <-writeDone, syscall.ECONNRESET)
+}
+
+func TestRetryFirstWriteDialer_ConcurrentWrites(t *testing.T) {
+	releaseFirst := make(chan struct{})
+	var writing sync.WaitGroup
+	writing.Add(2)
+	var first *scriptedConn
+	first = newScriptedConn(func(b []byte) (int, error) {
+		writing.Done()
+		if string(b) == "A" {
+			<-releaseFirst
+			return 0, syscall.ECONNRESET
+		}
+		// The other write is pending until the re-dial closes the connection.
+		<-first.closed
+		return 0, net.ErrClosed
+	})
+	replayed := make(chan string, 1)
+	second := newScriptedConn(func(b []byte) (int, error) {
+		replayed <- string(b)
+		return len(b), nil
+	})
+	var dials atomic.Int32
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		if dials.Add(1) == 1 {
+			return first, nil
+		}
+		return second, nil
+	})
+	dialer, err := NewRetryFirstWriteDialer(base, 1)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	results := make(chan error, 2)
+	for _, payload := range []string{"A", "B"} {
+		go func(payload string) {
+			n, err := conn.Write([]byte(payload))
+			if err == nil && n != len(payload) {
+				err = io.ErrShortWrite
+			}
+			results <- err
+		}(payload)
+	}
+	// Both writes are buffered before the reset.
+	writing.Wait()
+	close(releaseFirst)
+
+	// Both payloads were replayed, so both writes succeed, even the one that failed with the closed connection.
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+	require.Contains(t, []string{"AB", "BA"}, <-replayed)
+	require.Equal(t, int32(2), dials.Load())
+}
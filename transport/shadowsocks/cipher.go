@@ -19,11 +19,14 @@ import (
 	"crypto/cipher"
 	"crypto/md5"
 	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
 	"io"
 	"strings"
 
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
+	"lukechampine.com/blake3"
 )
 
 type cipherSpec struct {
@@ -31,6 +34,9 @@ type cipherSpec struct {
 	keySize     int
 	saltSize    int
 	tagSize     int
+	// is2022 indicates a cipher from the Shadowsocks 2022 edition, which uses
+	// BLAKE3 key derivation and a different header format.
+	is2022 bool
 }
 
 // List of supported AEAD ciphers, as specified at https://shadowsocks.org/guide/aead.html
@@ -41,15 +47,29 @@ var (
 	AES128GCM            = "AEAD_AES_128_GCM"
 )
 
+// List of supported Shadowsocks 2022 ciphers, as specified at
+// https://github.com/Shadowsocks-NET/shadowsocks-specs/blob/main/2022-1-shadowsocks-2022-edition.md
 var (
-	chacha20IETFPOLY1305Cipher = &cipherSpec{chacha20poly1305.New, chacha20poly1305.KeySize, 32, 16}
-	aes256GCMCipher            = &cipherSpec{newAesGCM, 32, 32, 16}
-	aes192GCMCipher            = &cipherSpec{newAesGCM, 24, 24, 16}
-	aes128GCMCipher            = &cipherSpec{newAesGCM, 16, 16, 16}
+	BLAKE3CHACHA20POLY1305 = "2022-blake3-chacha20-poly1305"
+	BLAKE3AES256GCM        = "2022-blake3-aes-256-gcm"
+	BLAKE3AES128GCM        = "2022-blake3-aes-128-gcm"
+)
+
+var (
+	chacha20IETFPOLY1305Cipher = &cipherSpec{chacha20poly1305.New, chacha20poly1305.KeySize, 32, 16, false}
+	aes256GCMCipher            = &cipherSpec{newAesGCM, 32, 32, 16, false}
+	aes192GCMCipher            = &cipherSpec{newAesGCM, 24, 24, 16, false}
+	aes128GCMCipher            = &cipherSpec{newAesGCM, 16, 16, 16, false}
+
+	blake3ChaCha20Poly1305Cipher = &cipherSpec{chacha20poly1305.New, chacha20poly1305.KeySize, 32, 16, true}
+	blake3AES256GCMCipher        = &cipherSpec{newAesGCM, 32, 32, 16, true}
+	blake3AES128GCMCipher        = &cipherSpec{newAesGCM, 16, 16, 16, true}
 )
 
 var supportedCiphers = [](string){CHACHA20IETFPOLY1305, AES256GCM, AES192GCM, AES128GCM}
 
+var supported2022Ciphers = [](string){BLAKE3CHACHA20POLY1305, BLAKE3AES256GCM, BLAKE3AES128GCM}
+
 // ErrUnsupportedCipher is returned by [CypherByName] when the named cipher is not supported.
 type ErrUnsupportedCipher struct {
 	// The name of the requested [Cipher]
@@ -76,6 +96,12 @@ func cipherByName(name string) (*cipherSpec, error) {
 		return aes192GCMCipher, nil
 	case "AEAD_AES_128_GCM", "AES-128-GCM":
 		return aes128GCMCipher, nil
+	case "2022-BLAKE3-CHACHA20-POLY1305":
+		return blake3ChaCha20Poly1305Cipher, nil
+	case "2022-BLAKE3-AES-256-GCM":
+		return blake3AES256GCMCipher, nil
+	case "2022-BLAKE3-AES-128-GCM":
+		return blake3AES128GCMCipher, nil
 	default:
 		return nil, ErrUnsupportedCipher{name}
 	}
@@ -105,11 +131,26 @@ func (c *EncryptionKey) TagSize() int {
 	return c.cipher.tagSize
 }
 
+// is2022 returns whether this key uses a Shadowsocks 2022 cipher.
+func (c *EncryptionKey) is2022() bool {
+	return c.cipher.is2022
+}
+
 var subkeyInfo = []byte("ss-subkey")
 
+// Key derivation context for Shadowsocks 2022 session subkeys.
+const subkeyContext2022 = "shadowsocks 2022 session subkey"
+
 // NewAEAD creates the AEAD for this cipher
 func (c *EncryptionKey) NewAEAD(salt []byte) (cipher.AEAD, error) {
 	sessionKey := make([]byte, c.cipher.keySize)
+	if c.cipher.is2022 {
+		// Key derivation as per the Shadowsocks 2022 spec: BLAKE3 over the PSK followed by the salt.
+		material := make([]byte, 0, len(c.secret)+len(salt))
+		material = append(append(material, c.secret...), salt...)
+		blake3.DeriveKey(sessionKey, subkeyContext2022, material)
+		return c.cipher.newInstance(sessionKey)
+	}
 	r := hkdf.New(sha1.New, c.secret, salt, subkeyInfo)
 	if _, err := io.ReadFull(r, sessionKey); err != nil {
 		return nil, err
@@ -140,6 +181,9 @@ func simpleEVPBytesToKey(data []byte, keyLen int) ([]byte, error) {
 // NewEncryptionKey creates a Cipher with a cipher name and a secret.
 // The cipher name must be the IETF name (as per https://www.iana.org/assignments/aead-parameters/aead-parameters.xhtml)
 // or the Shadowsocks alias from https://shadowsocks.org/guide/aead.html.
+//
+// The Shadowsocks 2022 ciphers (e.g. "2022-blake3-aes-256-gcm") are also supported. For those, the secret
+// must be the base64-encoded pre-shared key, with the same length as the cipher key.
 func NewEncryptionKey(cipherName string, secretText string) (*EncryptionKey, error) {
 	var key EncryptionKey
 	var err error
@@ -148,6 +192,17 @@ func NewEncryptionKey(cipherName string, secretText string) (*EncryptionKey, err
 		return nil, err
 	}
 
+	if key.cipher.is2022 {
+		key.secret, err = base64.StdEncoding.DecodeString(secretText)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode pre-shared key: %w", err)
+		}
+		if len(key.secret) != key.cipher.keySize {
+			return nil, fmt.Errorf("pre-shared key has length %v, expected %v", len(key.secret), key.cipher.keySize)
+		}
+		return &key, nil
+	}
+
 	// Key derivation as per https://shadowsocks.org/en/spec/AEAD-Ciphers.html
 	key.secret, err = simpleEVPBytesToKey([]byte(secretText), key.cipher.keySize)
 	if err != nil {
@@ -15,6 +15,8 @@
 package shadowsocks
 
 import (
+	"bytes"
+	"encoding/base64"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -97,3 +99,36 @@ func TestMaxTagSize(t *testing.T) {
 	}
 	require.Equal(t, maxTagSize, calculatedMax)
 }
+
+func TestSizes2022(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		keySize int
+	}{{BLAKE3CHACHA20POLY1305, 32}, {BLAKE3AES256GCM, 32}, {BLAKE3AES128GCM, 16}} {
+		key := makeTest2022Key(t, tc.name)
+		require.Equal(t, tc.keySize, key.SaltSize())
+		require.Equal(t, 16, key.TagSize())
+		require.True(t, key.is2022())
+		aead, err := key.NewAEAD(make([]byte, key.SaltSize()))
+		require.NoError(t, err)
+		require.Equal(t, key.TagSize(), aead.Overhead())
+	}
+}
+
+func TestNewEncryptionKey_2022InvalidPSK(t *testing.T) {
+	_, err := NewEncryptionKey(BLAKE3AES256GCM, "not base64!")
+	require.Error(t, err)
+	// 16-byte key for a 32-byte cipher.
+	_, err = NewEncryptionKey(BLAKE3AES256GCM, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	require.Error(t, err)
+}
+
+func TestNewAEAD_2022DerivesDistinctKeys(t *testing.T) {
+	key := makeTest2022Key(t, BLAKE3AES128GCM)
+	aead1, err := key.NewAEAD(bytes.Repeat([]byte{1}, 16))
+	require.NoError(t, err)
+	aead2, err := key.NewAEAD(bytes.Repeat([]byte{2}, 16))
+	require.NoError(t, err)
+	nonce := make([]byte, aead1.NonceSize())
+	require.NotEqual(t, aead1.Seal(nil, nonce, []byte("payload"), nil), aead2.Seal(nil, nonce, []byte("payload"), nil))
+}
@@ -16,6 +16,7 @@ package shadowsocks
 
 import (
 	"bytes"
+	"encoding/base64"
 	"io"
 	"testing"
 )
@@ -48,6 +49,20 @@ func makeTestKey(tb testing.TB) *EncryptionKey {
 	return key
 }
 
+// makeTest2022Key returns a key for the given Shadowsocks 2022 cipher with a fixed pre-shared key.
+func makeTest2022Key(tb testing.TB, cipherName string) *EncryptionKey {
+	spec, err := cipherByName(cipherName)
+	if err != nil {
+		tb.Fatalf("Failed to find cipher: %v", err)
+	}
+	psk := bytes.Repeat([]byte{0x42}, spec.keySize)
+	key, err := NewEncryptionKey(cipherName, base64.StdEncoding.EncodeToString(psk))
+	if err != nil {
+		tb.Fatalf("Failed to create key: %v", err)
+	}
+	return key
+}
+
 // makeTestPayload returns a slice of `size` arbitrary bytes.
 func makeTestPayload(size int) []byte {
 	payload := make([]byte, size)
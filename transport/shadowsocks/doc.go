@@ -50,6 +50,9 @@ completely random secret for every session, and delivering it to the client in a
 With Outline, that can be done via [Dynamic Keys]: when the Dynamic Key is requested, generate a new secret.
 The response is sent over TLS, which implements forward-secrecy.
 
+Servers built with this package should use a [ReplayCache], via [NewReaderWithReplayCache] and [UnpackWithReplayCache],
+to reject replayed salts, since replays are a common way of probing for Shadowsocks servers.
+
 [SOCKS5]: https://datatracker.ietf.org/doc/html/rfc1928
 [Outline Manager app]: https://getoutline.org/get-started/#step-1
 [outline-ss-server]: https://github.com/Jigsaw-Code/outline-ss-server?tab=readme-ov-file#how-to-run-it
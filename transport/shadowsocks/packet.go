@@ -22,6 +22,10 @@ import (
 // ErrShortPacket indicates that the destination packet given to Unpack is too short.
 var ErrShortPacket = errors.New("short packet")
 
+// Err2022Packet indicates that Pack or Unpack was given a Shadowsocks 2022 key. The 2022 packets have a
+// different format, with session IDs, which only the listener from [NewPacketListener] supports.
+var Err2022Packet = errors.New("unsupported Shadowsocks 2022 key")
+
 // Assumes all ciphers have NonceSize() <= 12.
 var zeroNonce [12]byte
 
@@ -29,7 +33,11 @@ var zeroNonce [12]byte
 // dst must be big enough to hold the encrypted packet.
 // If plaintext and dst overlap but are not aligned for in-place encryption, this
 // function will panic.
+// It fails with [Err2022Packet] for Shadowsocks 2022 keys.
 func Pack(dst, plaintext []byte, key *EncryptionKey) ([]byte, error) {
+	if key.is2022() {
+		return nil, Err2022Packet
+	}
 	saltSize := key.SaltSize()
 	if len(dst) < saltSize {
 		return nil, io.ErrShortBuffer
@@ -54,7 +62,11 @@ func Pack(dst, plaintext []byte, key *EncryptionKey) ([]byte, error) {
 // the decrypted payload or an error.
 // If dst is present, it is used to store the plaintext, and must have enough capacity.
 // If dst is nil, decryption proceeds in-place.
+// It fails with [Err2022Packet] for Shadowsocks 2022 keys.
 func Unpack(dst, pkt []byte, key *EncryptionKey) ([]byte, error) {
+	if key.is2022() {
+		return nil, Err2022Packet
+	}
 	saltSize := key.SaltSize()
 	if len(pkt) < saltSize {
 		return nil, ErrShortPacket
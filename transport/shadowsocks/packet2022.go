@@ -0,0 +1,212 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadowsocks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/shadowsocks/go-shadowsocks2/socks"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Size of the Shadowsocks 2022 UDP separate header: session ID and packet ID.
+const separateHeaderSize2022 = 8 + 8
+
+// packetConn2022 implements the client side of the Shadowsocks 2022 UDP protocol.
+// Each packetConn2022 is a session with a random session ID.
+type packetConn2022 struct {
+	net.Conn
+	key       *EncryptionKey
+	sessionID []byte
+	packetID  atomic.Uint64
+	// Used for the AES ciphers only.
+	headerBlock cipher.Block
+	sessionAEAD cipher.AEAD
+	// Used for the ChaCha20-Poly1305 cipher only.
+	xAEAD cipher.AEAD
+
+	// mu protects the cached server session.
+	mu              sync.Mutex
+	serverSessionID []byte
+	serverAEAD      cipher.AEAD
+}
+
+var _ net.PacketConn = (*packetConn2022)(nil)
+
+func newPacketConn2022(conn net.Conn, key *EncryptionKey) (*packetConn2022, error) {
+	c := &packetConn2022{Conn: conn, key: key, sessionID: make([]byte, 8)}
+	if _, err := rand.Read(c.sessionID); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	var err error
+	if key.cipher == blake3ChaCha20Poly1305Cipher {
+		c.xAEAD, err = chacha20poly1305.NewX(key.secret)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	c.headerBlock, err = aes.NewCipher(key.secret)
+	if err != nil {
+		return nil, err
+	}
+	c.sessionAEAD, err = key.NewAEAD(c.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *packetConn2022) WriteTo(b []byte, addr net.Addr) (int, error) {
+	socksTargetAddr := socks.ParseAddr(addr.String())
+	if socksTargetAddr == nil {
+		return 0, errors.New("failed to parse target address")
+	}
+	lazySlice := udpPool.LazySlice()
+	cipherBuf := lazySlice.Acquire()
+	defer lazySlice.Release()
+
+	// The plaintext consists of the separate header, followed by the main header
+	// (type, timestamp, padding length, no padding, target address) and the payload.
+	prefixSize := 0
+	if c.xAEAD != nil {
+		prefixSize = c.xAEAD.NonceSize()
+	}
+	if prefixSize+separateHeaderSize2022+1+8+2+len(socksTargetAddr)+len(b)+c.key.TagSize() > len(cipherBuf) {
+		return 0, io.ErrShortBuffer
+	}
+	plaintext := append(cipherBuf[prefixSize:prefixSize], c.sessionID...)
+	plaintext = binary.BigEndian.AppendUint64(plaintext, c.packetID.Add(1)-1)
+	plaintext = append(plaintext, headerTypeClient)
+	plaintext = binary.BigEndian.AppendUint64(plaintext, uint64(timeNow().Unix()))
+	plaintext = binary.BigEndian.AppendUint16(plaintext, 0)
+	plaintext = append(append(plaintext, socksTargetAddr...), b...)
+
+	var packet []byte
+	if c.xAEAD != nil {
+		nonce := cipherBuf[:prefixSize]
+		if _, err := rand.Read(nonce); err != nil {
+			return 0, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		packet = c.xAEAD.Seal(nonce, nonce, plaintext, nil)
+	} else {
+		separateHeader := plaintext[:separateHeaderSize2022]
+		nonce := append([]byte(nil), separateHeader[4:]...)
+		body := plaintext[separateHeaderSize2022:]
+		c.sessionAEAD.Seal(body[:0], nonce, body, nil)
+		c.headerBlock.Encrypt(separateHeader, separateHeader)
+		packet = plaintext[:len(plaintext)+c.sessionAEAD.Overhead()]
+	}
+	_, err := c.Conn.Write(packet)
+	return len(b), err
+}
+
+// serverAEADFor returns the AEAD for the given server session, caching the last one.
+func (c *packetConn2022) serverAEADFor(serverSessionID []byte) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serverAEAD != nil && bytes.Equal(c.serverSessionID, serverSessionID) {
+		return c.serverAEAD, nil
+	}
+	aead, err := c.key.NewAEAD(serverSessionID)
+	if err != nil {
+		return nil, err
+	}
+	c.serverSessionID = append(c.serverSessionID[:0], serverSessionID...)
+	c.serverAEAD = aead
+	return aead, nil
+}
+
+// unpack decrypts a server packet in-place and returns the main header and payload.
+func (c *packetConn2022) unpack(pkt []byte) ([]byte, error) {
+	if c.xAEAD != nil {
+		nonceSize := c.xAEAD.NonceSize()
+		if len(pkt) < nonceSize+separateHeaderSize2022+c.xAEAD.Overhead() {
+			return nil, ErrShortPacket
+		}
+		plaintext, err := c.xAEAD.Open(pkt[nonceSize:nonceSize], pkt[:nonceSize], pkt[nonceSize:], nil)
+		if err != nil {
+			return nil, err
+		}
+		return plaintext[separateHeaderSize2022:], nil
+	}
+	if len(pkt) < separateHeaderSize2022+c.key.TagSize() {
+		return nil, ErrShortPacket
+	}
+	separateHeader := pkt[:separateHeaderSize2022]
+	c.headerBlock.Decrypt(separateHeader, separateHeader)
+	aead, err := c.serverAEADFor(separateHeader[:8])
+	if err != nil {
+		return nil, err
+	}
+	body := pkt[separateHeaderSize2022:]
+	return aead.Open(body[:0], separateHeader[4:], body, nil)
+}
+
+func (c *packetConn2022) ReadFrom(b []byte) (int, net.Addr, error) {
+	lazySlice := udpPool.LazySlice()
+	cipherBuf := lazySlice.Acquire()
+	defer lazySlice.Release()
+	n, err := c.Conn.Read(cipherBuf)
+	if err != nil {
+		return 0, nil, err
+	}
+	buf, err := c.unpack(cipherBuf[:n])
+	if err != nil {
+		return 0, nil, err
+	}
+	// The main header consists of the type, timestamp, client session ID and padding.
+	if len(buf) < 1+8+8+2 {
+		return 0, nil, ErrShortPacket
+	}
+	if buf[0] != headerTypeServer {
+		return 0, nil, fmt.Errorf("invalid header type %v", buf[0])
+	}
+	if err := checkTimestamp2022(binary.BigEndian.Uint64(buf[1:9])); err != nil {
+		return 0, nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if !bytes.Equal(buf[9:17], c.sessionID) {
+		return 0, nil, errors.New("packet does not belong to this session")
+	}
+	paddingLen := int(binary.BigEndian.Uint16(buf[17:19]))
+	if len(buf) < 19+paddingLen {
+		return 0, nil, ErrShortPacket
+	}
+	buf = buf[19+paddingLen:]
+	socksSrcAddr := socks.SplitAddr(buf)
+	if socksSrcAddr == nil {
+		return 0, nil, errors.New("failed to read source address")
+	}
+	srcAddr, err := transport.MakeNetAddr("udp", socksSrcAddr.String())
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to convert incoming address: %w", err)
+	}
+	n = copy(b, buf[len(socksSrcAddr):]) // Strip the SOCKS source address
+	if len(b) < len(buf)-len(socksSrcAddr) {
+		return n, srcAddr, io.ErrShortBuffer
+	}
+	return n, srcAddr, nil
+}
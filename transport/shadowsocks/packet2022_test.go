@@ -0,0 +1,163 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadowsocks
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// Shadowsocks 2022 UDP packets for the PSK from makeVector2022Key and the vector2022Time timestamp, computed
+// with an implementation of the spec independent from this package. The server packet is from server session
+// f0f1f2f3f4f5f6f7, packet 5, to client session 1011121314151617, with source 1.2.3.4:53 and payload "pong".
+// The client packet is from client session 1011121314151617, packet 0, to 1.2.3.4:53 with payload "ping".
+// The ChaCha20-Poly1305 packets use the nonce 202122...37.
+var packetVectors2022 = []struct {
+	cipherName   string
+	serverPacket string
+	clientPacket string
+}{
+	{
+		cipherName: BLAKE3AES128GCM,
+		serverPacket: "e3091b89fb2f34132ce300bd762b1dac726e09172df9e9b715e725f6233119f091e5f2a17194936ca7410062d60e487b" +
+			"730913dad6b1e0d3bd91973bcdd6",
+		clientPacket: "67ea63a52087da3c3cd806544eaef9e3d3b59efb117cf5bbdc0af774e87d64ae8024cf1e8b2dbca815a8fcbbe47533ec" +
+			"4ecd52b9a4fc",
+	},
+	{
+		cipherName: BLAKE3AES256GCM,
+		serverPacket: "2c415399f924bd146ae58f6e332b4b3d9bb843ae975e0fcce42423715d429c64c912dd74b96fab44a84f8d79b3f72731" +
+			"90fa1659e8365b2c8853bc9d76e9",
+		clientPacket: "12770d973dde9c66ffd7cfd1141f33d21009d96bef561a96210102df9c476b3126d03aea5ad4c59c1f8351d292321d5a" +
+			"b6b4cf8711ee",
+	},
+	{
+		cipherName: BLAKE3CHACHA20POLY1305,
+		serverPacket: "202122232425262728292a2b2c2d2e2f303132333435363735cf133469a35aeb3fd5c09f02cb7274745d1879bd0bf8f7" +
+			"40fd5a5c68de4cf8c5f2cd2bab21eeee3c3dfef2ae127675fdd25311071993d2dd37e916504c",
+		clientPacket: "202122232425262728292a2b2c2d2e2f3031323334353637d52ff3d48943ba0b3fd5c09f02cb7271755d1879bd0bf8f7" +
+			"40ed4b4f7ac85aead2c7bd43c4445415ab4ca1f09afe7a0de3345981a360",
+	},
+}
+
+var (
+	vector2022ClientSessionID = []byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
+	vector2022UDPAddr         = "1.2.3.4:53"
+)
+
+// newVectorPacketConn2022 returns a client session with the fixed session ID used by the vectors.
+func newVectorPacketConn2022(t *testing.T, conn net.Conn, key *EncryptionKey) *packetConn2022 {
+	pc, err := newPacketConn2022(conn, key)
+	require.NoError(t, err)
+	pc.sessionID = vector2022ClientSessionID
+	if pc.xAEAD == nil {
+		pc.sessionAEAD, err = key.NewAEAD(pc.sessionID)
+		require.NoError(t, err)
+	}
+	return pc
+}
+
+func TestPacketConn2022_ReadFromKnownAnswer(t *testing.T) {
+	setVector2022Time(t)
+	for _, v := range packetVectors2022 {
+		t.Run(v.cipherName, func(t *testing.T) {
+			key := makeVector2022Key(t, v.cipherName)
+			client, server := net.Pipe()
+			defer server.Close()
+			pc := newVectorPacketConn2022(t, client, key)
+			defer pc.Close()
+			packet, err := hex.DecodeString(v.serverPacket)
+			require.NoError(t, err)
+			go server.Write(packet)
+
+			buf := make([]byte, 100)
+			n, addr, err := pc.ReadFrom(buf)
+			require.NoError(t, err)
+			require.Equal(t, vector2022UDPAddr, addr.String())
+			require.Equal(t, "pong", string(buf[:n]))
+		})
+	}
+}
+
+func TestPacketConn2022_ReadFromRejectsOtherSession(t *testing.T) {
+	setVector2022Time(t)
+	v := packetVectors2022[0]
+	key := makeVector2022Key(t, v.cipherName)
+	client, server := net.Pipe()
+	defer server.Close()
+	pc := newVectorPacketConn2022(t, client, key)
+	pc.sessionID = []byte("another1")
+	defer pc.Close()
+	packet, err := hex.DecodeString(v.serverPacket)
+	require.NoError(t, err)
+	go server.Write(packet)
+
+	_, _, err = pc.ReadFrom(make([]byte, 100))
+	require.ErrorContains(t, err, "does not belong to this session")
+}
+
+func TestPacketConn2022_WriteToKnownAnswer(t *testing.T) {
+	setVector2022Time(t)
+	for _, v := range packetVectors2022 {
+		t.Run(v.cipherName, func(t *testing.T) {
+			key := makeVector2022Key(t, v.cipherName)
+			client, server := net.Pipe()
+			defer server.Close()
+			pc := newVectorPacketConn2022(t, client, key)
+			defer pc.Close()
+			addr, err := transport.MakeNetAddr("udp", vector2022UDPAddr)
+			require.NoError(t, err)
+			go pc.WriteTo([]byte("ping"), addr)
+
+			buf := make([]byte, 100)
+			n, err := server.Read(buf)
+			require.NoError(t, err)
+			expected, err := hex.DecodeString(v.clientPacket)
+			require.NoError(t, err)
+			if pc.xAEAD == nil {
+				require.Equal(t, expected, buf[:n])
+				return
+			}
+			// The XChaCha20-Poly1305 nonce is random, so compare the plaintexts instead.
+			open := func(packet []byte) []byte {
+				nonceSize := pc.xAEAD.NonceSize()
+				plaintext, err := pc.xAEAD.Open(nil, packet[:nonceSize], packet[nonceSize:], nil)
+				require.NoError(t, err)
+				return plaintext
+			}
+			require.Equal(t, open(expected), open(buf[:n]))
+		})
+	}
+}
+
+func TestNewPacketConn_2022InvalidKey(t *testing.T) {
+	// A key that can't create the session must not panic, but fail on use.
+	key := &EncryptionKey{cipher: blake3AES256GCMCipher, secret: bytes.Repeat([]byte{1}, 5)}
+	client, server := net.Pipe()
+	defer server.Close()
+	pc := NewPacketConn(client, key)
+	defer pc.Close()
+	addr, err := transport.MakeNetAddr("udp", vector2022UDPAddr)
+	require.NoError(t, err)
+	_, err = pc.WriteTo([]byte("ping"), addr)
+	require.ErrorContains(t, err, "could not create session")
+	_, _, err = pc.ReadFrom(make([]byte, 100))
+	require.ErrorContains(t, err, "could not create session")
+}
@@ -54,6 +54,14 @@ func (c *packetListener) ListenPacket(ctx context.Context) (net.PacketConn, erro
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to endpoint: %w", err)
 	}
+	if c.key.is2022() {
+		conn, err := newPacketConn2022(proxyConn, c.key)
+		if err != nil {
+			proxyConn.Close()
+			return nil, fmt.Errorf("could not create session: %w", err)
+		}
+		return conn, nil
+	}
 	return NewPacketConn(proxyConn, c.key), nil
 }
 
@@ -68,11 +76,37 @@ var _ net.PacketConn = (*packetConn)(nil)
 // packets before writing/reading them to/from the underlying connection using the provided
 // encryption key.
 //
+// For Shadowsocks 2022 keys, the returned [net.PacketConn] is a new client session. If the session
+// can't be created, all reads and writes on the returned [net.PacketConn] fail with that error.
+//
 // Closing the returned [net.PacketConn] will also close the underlying [net.Conn].
 func NewPacketConn(conn net.Conn, key *EncryptionKey) net.PacketConn {
+	if key.is2022() {
+		pc, err := newPacketConn2022(conn, key)
+		if err != nil {
+			return &failedPacketConn{Conn: conn, err: fmt.Errorf("could not create session: %w", err)}
+		}
+		return pc
+	}
 	return &packetConn{Conn: conn, key: key}
 }
 
+// failedPacketConn is a [net.PacketConn] that fails all reads and writes with err.
+type failedPacketConn struct {
+	net.Conn
+	err error
+}
+
+var _ net.PacketConn = (*failedPacketConn)(nil)
+
+func (c *failedPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return 0, c.err
+}
+
+func (c *failedPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	return 0, nil, c.err
+}
+
 // WriteTo encrypts `b` and writes to `addr` through the proxy.
 func (c *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
 	socksTargetAddr := socks.ParseAddr(addr.String())
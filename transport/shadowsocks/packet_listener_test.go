@@ -141,3 +141,18 @@ func (pc *packetConnReadWriter) Read(b []byte) (n int, err error) {
 func (pc *packetConnReadWriter) Write(b []byte) (int, error) {
 	return pc.PacketConn.WriteTo(b, pc.targetAddr)
 }
+
+func TestShadowsocksPacketListener_ListenPacket2022(t *testing.T) {
+	// The packets are checked against known-answer vectors in the packetConn2022 tests.
+	for _, cipherName := range supported2022Ciphers {
+		t.Run(cipherName, func(t *testing.T) {
+			key := makeTest2022Key(t, cipherName)
+			d, err := NewPacketListener(transport.UDPEndpoint{Address: "127.0.0.1:9"}, key)
+			require.NoError(t, err)
+			conn, err := d.ListenPacket(context.Background())
+			require.NoError(t, err)
+			defer conn.Close()
+			require.IsType(t, &packetConn2022{}, conn)
+		})
+	}
+}
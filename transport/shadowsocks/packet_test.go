@@ -21,6 +21,15 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestPackUnpack_2022Key(t *testing.T) {
+	key := makeTest2022Key(t, BLAKE3AES256GCM)
+	buf := make([]byte, 1500)
+	_, err := Pack(buf, []byte("payload"), key)
+	require.ErrorIs(t, err, Err2022Packet)
+	_, err = Unpack(nil, buf, key)
+	require.ErrorIs(t, err, Err2022Packet)
+}
+
 // Microbenchmark for the performance of Shadowsocks UDP encryption.
 func BenchmarkPack(b *testing.B) {
 	b.StopTimer()
@@ -0,0 +1,118 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadowsocks
+
+import (
+	"errors"
+	"hash/maphash"
+	"io"
+	"sync"
+)
+
+// ErrReplayedSalt is returned when a salt was already seen by the [ReplayCache], which
+// indicates that the data was replayed, possibly by an active prober.
+var ErrReplayedSalt = errors.New("replayed salt")
+
+// ReplayCache remembers recently seen salts, so that servers can detect and drop replayed
+// connections and packets.
+//
+// It holds between capacity and 2*capacity salts: when the active set fills up, it becomes the
+// archive and the previous archive is discarded. Salts are stored as 64-bit keyed hashes, so
+// each entry takes a fixed amount of memory regardless of the salt size.
+type ReplayCache struct {
+	seed     maphash.Seed
+	capacity int
+
+	mu      sync.Mutex
+	active  map[uint64]struct{}
+	archive map[uint64]struct{}
+}
+
+// NewReplayCache creates a [ReplayCache] that remembers at least the last capacity salts.
+// A capacity of zero or less disables the cache, and [ReplayCache.Add] always succeeds.
+func NewReplayCache(capacity int) *ReplayCache {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &ReplayCache{
+		seed:     maphash.MakeSeed(),
+		capacity: capacity,
+		active:   make(map[uint64]struct{}, capacity),
+	}
+}
+
+// Add records the salt and returns true if it had not been seen before, or false if it's a replay.
+// It's safe to call Add from multiple goroutines.
+func (c *ReplayCache) Add(salt []byte) bool {
+	if c.capacity == 0 {
+		return true
+	}
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	h.Write(salt)
+	hash := h.Sum64()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.active[hash]; ok {
+		return false
+	}
+	if _, ok := c.archive[hash]; ok {
+		return false
+	}
+	if len(c.active) >= c.capacity {
+		c.archive = c.active
+		c.active = make(map[uint64]struct{}, c.capacity)
+	}
+	c.active[hash] = struct{}{}
+	return true
+}
+
+// NewReaderWithReplayCache is like [NewReader], but fails with [ErrReplayedSalt] if the salt of the
+// stream is found in the replay cache. The salt is only added to the cache after the first chunk is
+// authenticated, so that invalid data cannot pollute the cache.
+//
+// This is meant for the server side, which reads the client requests. A nil cache disables the check.
+// For Shadowsocks 2022 keys, it expects and validates the request header sent by [NewWriter].
+func NewReaderWithReplayCache(reader io.Reader, key *EncryptionKey, cache *ReplayCache) Reader {
+	cr := newChunkReader(reader, key)
+	cr.isServer = true
+	cr.replayCache = cache
+	return &readConverter{cr: cr}
+}
+
+// UnpackWithReplayCache is like [Unpack], but fails with [ErrReplayedSalt] if the salt of the
+// packet is found in the replay cache. The salt is only added to the cache if the packet
+// is authenticated. A nil cache disables the check.
+// Like Unpack, it fails with [Err2022Packet] for Shadowsocks 2022 keys.
+func UnpackWithReplayCache(dst, pkt []byte, key *EncryptionKey, cache *ReplayCache) ([]byte, error) {
+	if key.is2022() {
+		return nil, Err2022Packet
+	}
+	saltSize := key.SaltSize()
+	if len(pkt) < saltSize {
+		return nil, ErrShortPacket
+	}
+	// Copy the salt, since dst may overlap with the packet.
+	salt := append([]byte(nil), pkt[:saltSize]...)
+	plaintext, err := Unpack(dst, pkt, key)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil && !cache.Add(salt) {
+		return nil, ErrReplayedSalt
+	}
+	return plaintext, nil
+}
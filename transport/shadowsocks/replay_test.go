@@ -0,0 +1,151 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadowsocks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayCache_Add(t *testing.T) {
+	cache := NewReplayCache(10)
+	salt := []byte("salt1")
+	require.True(t, cache.Add(salt))
+	require.False(t, cache.Add(salt))
+	require.True(t, cache.Add([]byte("salt2")))
+}
+
+func TestReplayCache_Archive(t *testing.T) {
+	cache := NewReplayCache(2)
+	require.True(t, cache.Add([]byte{1}))
+	require.True(t, cache.Add([]byte{2}))
+	// This rotates {1, 2} into the archive.
+	require.True(t, cache.Add([]byte{3}))
+	require.False(t, cache.Add([]byte{1}))
+	require.True(t, cache.Add([]byte{4}))
+	// This discards {1, 2}.
+	require.True(t, cache.Add([]byte{5}))
+	require.True(t, cache.Add([]byte{1}))
+	require.False(t, cache.Add([]byte{4}))
+}
+
+func TestReplayCache_Disabled(t *testing.T) {
+	cache := NewReplayCache(0)
+	require.True(t, cache.Add([]byte{1}))
+	require.True(t, cache.Add([]byte{1}))
+}
+
+func TestReaderWithReplayCache(t *testing.T) {
+	key := makeTestKey(t)
+	var ciphertext bytes.Buffer
+	_, err := NewWriter(&ciphertext, key).Write([]byte("request"))
+	require.NoError(t, err)
+
+	cache := NewReplayCache(10)
+	reader := NewReaderWithReplayCache(bytes.NewReader(ciphertext.Bytes()), key, cache)
+	plaintext, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, []byte("request"), plaintext)
+
+	replayed := NewReaderWithReplayCache(bytes.NewReader(ciphertext.Bytes()), key, cache)
+	_, err = io.ReadAll(replayed)
+	require.ErrorIs(t, err, ErrReplayedSalt)
+}
+
+func TestReaderWithReplayCache_InvalidDataNotRecorded(t *testing.T) {
+	key := makeTestKey(t)
+	var ciphertext bytes.Buffer
+	_, err := NewWriter(&ciphertext, key).Write([]byte("request"))
+	require.NoError(t, err)
+	corrupted := append([]byte(nil), ciphertext.Bytes()...)
+	corrupted[key.SaltSize()] ^= 0xff
+
+	cache := NewReplayCache(10)
+	_, err = io.ReadAll(NewReaderWithReplayCache(bytes.NewReader(corrupted), key, cache))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrReplayedSalt)
+
+	// The salt of the corrupted stream must not block the genuine one.
+	_, err = io.ReadAll(NewReaderWithReplayCache(bytes.NewReader(ciphertext.Bytes()), key, cache))
+	require.NoError(t, err)
+}
+
+func TestUnpackWithReplayCache(t *testing.T) {
+	key := makeTestKey(t)
+	plaintext := []byte("packet")
+	pkt, err := Pack(make([]byte, 100), plaintext, key)
+	require.NoError(t, err)
+
+	cache := NewReplayCache(10)
+	got, err := UnpackWithReplayCache(nil, append([]byte(nil), pkt...), key, cache)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, got)
+
+	_, err = UnpackWithReplayCache(nil, append([]byte(nil), pkt...), key, cache)
+	require.ErrorIs(t, err, ErrReplayedSalt)
+}
+
+func TestUnpackWithReplayCache_2022Key(t *testing.T) {
+	key := makeTest2022Key(t, BLAKE3AES256GCM)
+	cache := NewReplayCache(10)
+	pkt := make([]byte, 100)
+	_, err := UnpackWithReplayCache(nil, pkt, key, cache)
+	require.ErrorIs(t, err, Err2022Packet)
+	require.True(t, cache.Add(pkt[:key.SaltSize()]))
+}
+
+func TestReaderWithReplayCache_2022(t *testing.T) {
+	for _, cipherName := range supported2022Ciphers {
+		t.Run(cipherName, func(t *testing.T) {
+			key := makeTest2022Key(t, cipherName)
+			var ciphertext bytes.Buffer
+			writer := NewWriter(&ciphertext, key)
+			_, err := writer.Write([]byte("request"))
+			require.NoError(t, err)
+			_, err = writer.Write([]byte(" more data"))
+			require.NoError(t, err)
+
+			cache := NewReplayCache(10)
+			reader := NewReaderWithReplayCache(bytes.NewReader(ciphertext.Bytes()), key, cache)
+			plaintext, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.Equal(t, []byte("request more data"), plaintext)
+
+			replayed := NewReaderWithReplayCache(bytes.NewReader(ciphertext.Bytes()), key, cache)
+			_, err = io.ReadAll(replayed)
+			require.ErrorIs(t, err, ErrReplayedSalt)
+		})
+	}
+}
+
+func TestReaderWithReplayCache_2022RejectsResponse(t *testing.T) {
+	key := makeTest2022Key(t, BLAKE3AES256GCM)
+	// A server response header has the server type, which the server must not accept as a request.
+	salt := make([]byte, key.SaltSize())
+	aead, err := key.NewAEAD(salt)
+	require.NoError(t, err)
+	header := make([]byte, 1+8+key.SaltSize()+2)
+	header[0] = headerTypeServer
+	binary.BigEndian.PutUint64(header[1:9], uint64(time.Now().Unix()))
+	ciphertext := append(salt, aead.Seal(nil, make([]byte, aead.NonceSize()), header[:requestHeaderSize2022], nil)...)
+
+	_, err = io.ReadAll(NewReaderWithReplayCache(bytes.NewReader(ciphertext), key, nil))
+	require.ErrorContains(t, err, "invalid request header type")
+}
@@ -17,6 +17,7 @@ package shadowsocks
 import (
 	"crypto/rand"
 	"errors"
+	"math/big"
 )
 
 // SaltGenerator generates unique salts to use in Shadowsocks connections.
@@ -64,3 +65,29 @@ func (g prefixSaltGenerator) GetSalt(salt []byte) error {
 func NewPrefixSaltGenerator(prefix []byte) SaltGenerator {
 	return prefixSaltGenerator{prefix}
 }
+
+// randomizedPrefixSaltGenerator generates salts with a prefix chosen at random from a list.
+type randomizedPrefixSaltGenerator struct {
+	prefixes [][]byte
+}
+
+func (g randomizedPrefixSaltGenerator) GetSalt(salt []byte) error {
+	if len(g.prefixes) == 0 {
+		return errors.New("no prefixes to choose from")
+	}
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(g.prefixes))))
+	if err != nil {
+		return err
+	}
+	return prefixSaltGenerator{g.prefixes[i.Int64()]}.GetSalt(salt)
+}
+
+// NewRandomizedPrefixSaltGenerator returns a SaltGenerator that works like the one from
+// [NewPrefixSaltGenerator], but picks the prefix at random from the given prefixes for each salt.
+// This makes the first bytes look like different protocol headers across connections.
+// Prefixes are chosen with equal probability, so you can repeat a prefix to give it more weight.
+//
+// The same entropy caveats of [NewPrefixSaltGenerator] apply, based on the longest prefix.
+func NewRandomizedPrefixSaltGenerator(prefixes ...[]byte) SaltGenerator {
+	return randomizedPrefixSaltGenerator{prefixes}
+}
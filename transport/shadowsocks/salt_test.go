@@ -96,3 +96,49 @@ func TestNilPrefix(t *testing.T) {
 		}
 	}
 }
+
+// Test that every prefix gets picked, and the remainder is random.
+func TestRandomizedPrefix(t *testing.T) {
+	prefixes := [][]byte{[]byte("\x16\x03\x01"), []byte("GET "), []byte("POST ")}
+	salter := NewRandomizedPrefixSaltGenerator(prefixes...)
+
+	seen := make(map[string]bool)
+	salt := make([]byte, 32)
+	for i := 0; i < 100; i++ {
+		if err := salter.GetSalt(salt); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, prefix := range prefixes {
+			if bytes.HasPrefix(salt, prefix) {
+				seen[string(prefix)] = true
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("salt %v has none of the prefixes", salt)
+		}
+	}
+	if len(seen) != len(prefixes) {
+		t.Errorf("expected all %v prefixes to be picked, got %v", len(prefixes), len(seen))
+	}
+
+	output := make([]byte, 32)
+	if err := setRandomBitsToOne(NewRandomizedPrefixSaltGenerator([]byte("abc")), output); err != nil {
+		t.Error(err)
+	}
+	for _, b := range output[3:] {
+		if b != 0xFF {
+			t.Error("unexpected zero bit")
+		}
+	}
+}
+
+func TestRandomizedPrefix_Errors(t *testing.T) {
+	if err := NewRandomizedPrefixSaltGenerator().GetSalt(make([]byte, 16)); err == nil {
+		t.Error("expected error with no prefixes")
+	}
+	if err := NewRandomizedPrefixSaltGenerator(make([]byte, 17)).GetSalt(make([]byte, 16)); err == nil {
+		t.Error("expected error with long prefix")
+	}
+}
@@ -18,9 +18,12 @@ import (
 	"bytes"
 	"crypto/cipher"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/internal/slicepool"
 )
@@ -28,10 +31,52 @@ import (
 // payloadSizeMask is the maximum size of payload in bytes, as per https://shadowsocks.org/guide/aead.html#tcp.
 const payloadSizeMask = 0x3FFF // 16*1024 - 1
 
+// maxPayloadSize2022 is the maximum size of payload in bytes for the Shadowsocks 2022 ciphers.
+const maxPayloadSize2022 = 0xFFFF
+
 // Buffer pool used for decrypting Shadowsocks streams.
 // The largest buffer we could need is for decrypting a max-length payload.
 var readBufPool = slicepool.MakePool(payloadSizeMask + maxTagSize)
 
+// Buffer pool used for decrypting Shadowsocks 2022 streams, which allow larger payloads.
+var readBufPool2022 = slicepool.MakePool(maxPayloadSize2022 + maxTagSize)
+
+// Header types used by Shadowsocks 2022.
+const (
+	headerTypeClient = 0
+	headerTypeServer = 1
+)
+
+// Size of the fixed-length Shadowsocks 2022 request header: type, timestamp and length.
+const requestHeaderSize2022 = 1 + 8 + 2
+
+// Maximum padding length allowed in the Shadowsocks 2022 request header.
+const maxPaddingLength2022 = 900
+
+// Maximum difference between the local clock and a Shadowsocks 2022 header timestamp.
+const maxTimestampDifference2022 = 30 * time.Second
+
+// timeNow returns the time used in the Shadowsocks 2022 header timestamps.
+// It's a variable so tests can check messages with fixed timestamps.
+var timeNow = time.Now
+
+// checkTimestamp2022 verifies that a Shadowsocks 2022 header timestamp is within the allowed window.
+func checkTimestamp2022(timestamp uint64) error {
+	diff := timeNow().Sub(time.Unix(int64(timestamp), 0))
+	if diff < -maxTimestampDifference2022 || diff > maxTimestampDifference2022 {
+		return fmt.Errorf("timestamp is off by %v", diff)
+	}
+	return nil
+}
+
+// appendRequestPadding2022 appends the padding length and a random amount of zero padding to b,
+// as expected after the target address in the Shadowsocks 2022 request header.
+func appendRequestPadding2022(b []byte) []byte {
+	paddingLen := 1 + rand.Intn(maxPaddingLength2022)
+	b = binary.BigEndian.AppendUint16(b, uint16(paddingLen))
+	return append(b, make([]byte, paddingLen)...)
+}
+
 // Writer is an [io.Writer] that also implements [io.ReaderFrom] to
 // allow for piping the data without extra allocations and copies.
 // The LazyWrite and Flush methods allow a header to be
@@ -65,6 +110,8 @@ var (
 
 // NewWriter creates a [Writer] that encrypts the given [io.Writer] using
 // the shadowsocks protocol with the given encryption key.
+//
+// With Shadowsocks 2022 keys, the [Writer] is client-only: it writes the client request header.
 func NewWriter(writer io.Writer, key *EncryptionKey) *Writer {
 	return &Writer{writer: writer, key: key, saltGenerator: RandomSaltGenerator}
 }
@@ -88,9 +135,9 @@ func (sw *Writer) init() (err error) {
 		}
 		sw.saltGenerator = nil // No longer needed, so release reference.
 		sw.counter = make([]byte, sw.aead.NonceSize())
-		// The maximum length message is the salt (first message only), length, length tag,
+		// The maximum length message is the salt (first message only), length (or header), length tag,
 		// payload, and payload tag.
-		sizeBufSize := 2 + sw.aead.Overhead()
+		sizeBufSize := sw.headerSize() + sw.aead.Overhead()
 		maxPayloadBufSize := payloadSizeMask + sw.aead.Overhead()
 		sw.buf = make([]byte, len(salt)+sizeBufSize+maxPayloadBufSize)
 		// Store the salt at the start of sw.buf.
@@ -99,6 +146,21 @@ func (sw *Writer) init() (err error) {
 	return nil
 }
 
+// salt returns the salt used by this writer. It's only valid after init().
+func (sw *Writer) salt() []byte {
+	return sw.buf[:sw.key.SaltSize()]
+}
+
+// headerSize returns the plaintext size of the message preceding the next payload.
+// That's the payload length, except for the first message of a Shadowsocks 2022
+// stream, which carries the fixed-length request header.
+func (sw *Writer) headerSize() int {
+	if sw.key.is2022() && isZero(sw.counter) {
+		return requestHeaderSize2022
+	}
+	return 2
+}
+
 // encryptBlock encrypts `plaintext` in-place.  The slice must have enough capacity
 // for the tag. Returns the total ciphertext length.
 func (sw *Writer) encryptBlock(plaintext []byte) int {
@@ -168,8 +230,9 @@ func (sw *Writer) buffers() (sizeBuf, payloadBuf []byte) {
 
 	// Each Shadowsocks-TCP message consists of a fixed-length size block,
 	// followed by a variable-length payload block.
-	sizeBuf = sw.buf[saltSize : saltSize+2]
-	payloadStart := saltSize + 2 + sw.aead.Overhead()
+	headerSize := sw.headerSize()
+	sizeBuf = sw.buf[saltSize : saltSize+headerSize]
+	payloadStart := saltSize + headerSize + sw.aead.Overhead()
 	payloadBuf = sw.buf[payloadStart : payloadStart+payloadSizeMask]
 	return
 }
@@ -181,7 +244,6 @@ func (sw *Writer) ReadFrom(r io.Reader) (int64, error) {
 	}
 	var written int64
 	var err error
-	_, payloadBuf := sw.buffers()
 
 	// Special case: one thread-safe read, if necessary
 	sw.mu.Lock()
@@ -194,7 +256,7 @@ func (sw *Writer) ReadFrom(r io.Reader) (int64, error) {
 		// The first pending+overhead bytes of payloadBuf are potentially
 		// in use, and may be modified on the flush thread.  Data after
 		// that is safe to use on this thread.
-		readBuf := sw.buf[saltsize+2+overhead+pending+overhead:]
+		readBuf := sw.buf[saltsize+sw.headerSize()+overhead+pending+overhead:]
 		var plaintextSize int
 		plaintextSize, err = r.Read(readBuf)
 		written = int64(plaintextSize)
@@ -210,6 +272,8 @@ func (sw *Writer) ReadFrom(r io.Reader) (int64, error) {
 
 	// Main transfer loop
 	for err == nil {
+		// The buffer layout changes after the first message in Shadowsocks 2022.
+		_, payloadBuf := sw.buffers()
 		sw.pending, err = r.Read(payloadBuf)
 		written += int64(sw.pending)
 		if flushErr := sw.flush(); flushErr != nil {
@@ -249,7 +313,12 @@ func (sw *Writer) flush() error {
 	}
 
 	sizeBuf, payloadBuf := sw.buffers()
-	binary.BigEndian.PutUint16(sizeBuf, uint16(sw.pending))
+	if len(sizeBuf) == requestHeaderSize2022 {
+		// The first message of a Shadowsocks 2022 request is the fixed-length header.
+		sizeBuf[0] = headerTypeClient
+		binary.BigEndian.PutUint64(sizeBuf[1:], uint64(timeNow().Unix()))
+	}
+	binary.BigEndian.PutUint16(sizeBuf[len(sizeBuf)-2:], uint16(sw.pending))
 	sizeBlockSize := sw.encryptBlock(sizeBuf)
 	payloadSize := sw.encryptBlock(payloadBuf[:sw.pending])
 	_, err := sw.writer.Write(sw.buf[start : saltSize+sizeBlockSize+payloadSize])
@@ -277,6 +346,18 @@ type chunkReader struct {
 	payloadSizeBuf []byte
 	// Holds a buffer for the payload and its AEAD tag, when needed.
 	payload slicepool.LazySlice
+	// Salt of the corresponding request, used to validate Shadowsocks 2022 responses.
+	// If nil, the response is not checked against the request.
+	requestSalt []byte
+	// Size of the first payload, if announced in the Shadowsocks 2022 response header.
+	firstPayloadSize int
+	hasFirstPayload  bool
+	// If set, the salt is checked against the cache once the first chunk is authenticated.
+	replayCache *ReplayCache
+	salt        []byte
+	// Whether this reader reads the client requests, on the server side. In Shadowsocks 2022, it determines
+	// whether the stream starts with a request or a response header.
+	isServer bool
 }
 
 // Reader is an [io.Reader] that also implements [io.WriterTo] to
@@ -288,13 +369,29 @@ type Reader interface {
 
 // NewReader creates a [Reader] that decrypts the given [io.Reader] using
 // the shadowsocks protocol with the given encryption key.
+//
+// With Shadowsocks 2022 keys, the [Reader] is client-only: it expects the server response header.
+// Use [NewReaderWithReplayCache] to read client requests on the server side.
 func NewReader(reader io.Reader, key *EncryptionKey) Reader {
-	return &readConverter{
-		cr: &chunkReader{
-			reader:  reader,
-			key:     key,
-			payload: readBufPool.LazySlice(),
-		},
+	return newReader(reader, key, nil)
+}
+
+// newReader is like [NewReader], but validates that Shadowsocks 2022 responses match the given request salt.
+func newReader(reader io.Reader, key *EncryptionKey, requestSalt []byte) Reader {
+	cr := newChunkReader(reader, key)
+	cr.requestSalt = requestSalt
+	return &readConverter{cr: cr}
+}
+
+func newChunkReader(reader io.Reader, key *EncryptionKey) *chunkReader {
+	pool := readBufPool
+	if key.is2022() {
+		pool = readBufPool2022
+	}
+	return &chunkReader{
+		reader:  reader,
+		key:     key,
+		payload: pool.LazySlice(),
 	}
 }
 
@@ -315,10 +412,81 @@ func (cr *chunkReader) init() (err error) {
 		}
 		cr.counter = make([]byte, cr.aead.NonceSize())
 		cr.payloadSizeBuf = make([]byte, 2+cr.aead.Overhead())
+		if cr.replayCache != nil {
+			cr.salt = salt
+		}
+		if cr.key.is2022() {
+			if cr.isServer {
+				return cr.readRequestHeader()
+			}
+			return cr.readResponseHeader()
+		}
 	}
 	return nil
 }
 
+// readRequestHeader reads and validates the fixed-length Shadowsocks 2022 request header,
+// which consists of the type, timestamp and length of the first payload.
+func (cr *chunkReader) readRequestHeader() error {
+	header := make([]byte, requestHeaderSize2022+cr.aead.Overhead())
+	if err := cr.readMessage(header); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read request header: %w", err)
+	}
+	if header[0] != headerTypeClient {
+		return fmt.Errorf("invalid request header type %v", header[0])
+	}
+	if err := checkTimestamp2022(binary.BigEndian.Uint64(header[1:9])); err != nil {
+		return fmt.Errorf("invalid request header: %w", err)
+	}
+	if err := cr.checkReplay(); err != nil {
+		return err
+	}
+	cr.firstPayloadSize = int(binary.BigEndian.Uint16(header[9:11]))
+	cr.hasFirstPayload = true
+	return nil
+}
+
+// checkReplay records the salt in the replay cache, if any, once the first message is authenticated.
+func (cr *chunkReader) checkReplay() error {
+	if cr.replayCache == nil {
+		return nil
+	}
+	if !cr.replayCache.Add(cr.salt) {
+		return ErrReplayedSalt
+	}
+	cr.replayCache = nil
+	cr.salt = nil
+	return nil
+}
+
+// readResponseHeader reads and validates the fixed-length Shadowsocks 2022 response header,
+// which consists of the type, timestamp, request salt and length of the first payload.
+func (cr *chunkReader) readResponseHeader() error {
+	saltSize := cr.key.SaltSize()
+	header := make([]byte, 1+8+saltSize+2+cr.aead.Overhead())
+	if err := cr.readMessage(header); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read response header: %w", err)
+	}
+	if header[0] != headerTypeServer {
+		return fmt.Errorf("invalid response header type %v", header[0])
+	}
+	if err := checkTimestamp2022(binary.BigEndian.Uint64(header[1:9])); err != nil {
+		return fmt.Errorf("invalid response header: %w", err)
+	}
+	if cr.requestSalt != nil && !bytes.Equal(header[9:9+saltSize], cr.requestSalt) {
+		return errors.New("response header does not match the request salt")
+	}
+	cr.firstPayloadSize = int(binary.BigEndian.Uint16(header[9+saltSize:]))
+	cr.hasFirstPayload = true
+	return nil
+}
+
 // readMessage reads, decrypts, and verifies a single AEAD ciphertext.
 // The ciphertext and tag (i.e. "overhead") must exactly fill `buf`,
 // and the decrypted message will be placed in buf[:len(buf)-overhead].
@@ -350,13 +518,27 @@ func (cr *chunkReader) ReadChunk() ([]byte, error) {
 	// encrypted messages.  The first message contains the payload length,
 	// and the second message is the payload.  Idle read threads will
 	// block here until the next chunk.
-	if err := cr.readMessage(cr.payloadSizeBuf); err != nil {
-		if err != io.EOF && err != io.ErrUnexpectedEOF {
-			err = fmt.Errorf("failed to read payload size: %w", err)
+	// In Shadowsocks 2022, the length of the first payload comes in the header instead.
+	var size int
+	if cr.hasFirstPayload {
+		size = cr.firstPayloadSize
+		cr.hasFirstPayload = false
+	} else {
+		if err := cr.readMessage(cr.payloadSizeBuf); err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				err = fmt.Errorf("failed to read payload size: %w", err)
+			}
+			return nil, err
+		}
+		// The first message is authentic, so the salt can be safely recorded.
+		if err := cr.checkReplay(); err != nil {
+			return nil, err
+		}
+		size = int(binary.BigEndian.Uint16(cr.payloadSizeBuf))
+		if !cr.key.is2022() {
+			size &= payloadSizeMask
 		}
-		return nil, err
 	}
-	size := int(binary.BigEndian.Uint16(cr.payloadSizeBuf) & payloadSizeMask)
 	sizeWithTag := size + cr.aead.Overhead()
 	payloadBuf := cr.payload.Acquire()
 	if cap(payloadBuf) < sizeWithTag {
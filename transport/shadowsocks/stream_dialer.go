@@ -62,6 +62,13 @@ type StreamDialer struct {
 }
 
 var _ transport.StreamDialer = (*StreamDialer)(nil)
+var _ transport.ConnectFailureReporter = (*StreamDialer)(nil)
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter]. It returns false, since the connection
+// is returned before the proxy connects to the target. See [StreamDialer.DialStream].
+func (c *StreamDialer) ReportsConnectFailure() bool {
+	return false
+}
 
 // DialStream implements StreamDialer.DialStream using a Shadowsocks server.
 //
@@ -79,6 +86,12 @@ var _ transport.StreamDialer = (*StreamDialer)(nil)
 // all in one packet. This makes the size of the initial packet hard to predict, avoiding packet size
 // fingerprinting. We can only get the application initial data if we return a connection first.
 func (c *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	return transport.TraceDialStream(ctx, "shadowsocks", remoteAddr, func() (transport.StreamConn, error) {
+		return c.dialStream(ctx, remoteAddr)
+	})
+}
+
+func (c *StreamDialer) dialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
 	socksTargetAddr := socks.ParseAddr(remoteAddr)
 	if socksTargetAddr == nil {
 		return nil, errors.New("failed to parse target address")
@@ -91,7 +104,12 @@ func (c *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (trans
 	if c.SaltGenerator != nil {
 		ssw.SetSaltGenerator(c.SaltGenerator)
 	}
-	_, err = ssw.LazyWrite(socksTargetAddr)
+	header := []byte(socksTargetAddr)
+	if c.key.is2022() {
+		// The Shadowsocks 2022 request header has padding after the target address.
+		header = appendRequestPadding2022(header)
+	}
+	_, err = ssw.LazyWrite(header)
 	if err != nil {
 		proxyConn.Close()
 		return nil, errors.New("failed to write target address")
@@ -99,6 +117,6 @@ func (c *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (trans
 	time.AfterFunc(c.ClientDataWait, func() {
 		ssw.Flush()
 	})
-	ssr := NewReader(proxyConn, c.key)
+	ssr := newReader(proxyConn, c.key, ssw.salt())
 	return transport.WrapConn(proxyConn, ssr, ssw), nil
 }
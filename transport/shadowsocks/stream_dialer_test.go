@@ -16,6 +16,7 @@ package shadowsocks
 
 import (
 	"context"
+	"encoding/binary"
 	"io"
 	"net"
 	"sync"
@@ -116,6 +117,52 @@ func TestStreamDialer_DialFastClose(t *testing.T) {
 	running.Wait()
 }
 
+func TestStreamDialer_CloseWriteSendsHeader(t *testing.T) {
+	key := makeTestKey(t)
+	proxy, running := startShadowsocksTCPEchoProxy(key, testTargetAddr, t)
+	d, err := NewStreamDialer(&transport.TCPEndpoint{Address: proxy.Addr().String()}, key)
+	require.NoError(t, err)
+	// Make sure the header is not sent by the timer.
+	d.ClientDataWait = time.Hour
+
+	conn, err := d.DialStream(context.Background(), testTargetAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	// The header must be sent before the EOF, so the proxy can read the target address.
+	require.NoError(t, conn.CloseWrite())
+	// The read end still works, and gets the EOF from the proxy.
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	proxy.Close()
+	running.Wait()
+}
+
+func TestStreamDialer_HalfClose(t *testing.T) {
+	key := makeTestKey(t)
+	proxy, running := startShadowsocksTCPEchoProxy(key, testTargetAddr, t)
+	d, err := NewStreamDialer(&transport.TCPEndpoint{Address: proxy.Addr().String()}, key)
+	require.NoError(t, err)
+
+	conn, err := d.DialStream(context.Background(), testTargetAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	payload := makeTestPayload(1024)
+	_, err = conn.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+	// The proxy only ends the echo after it reads the EOF.
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, payload, data)
+
+	proxy.Close()
+	running.Wait()
+}
+
 func TestStreamDialer_TCPPrefix(t *testing.T) {
 	prefix := []byte("test prefix")
 
@@ -228,3 +275,45 @@ func startShadowsocksTCPEchoProxy(key *EncryptionKey, expectedTgtAddr string, t
 	}()
 	return listener, &running
 }
+
+func TestStreamDialer_Dial2022(t *testing.T) {
+	for _, cipherName := range supported2022Ciphers {
+		t.Run(cipherName, func(t *testing.T) {
+			key := makeTest2022Key(t, cipherName)
+			listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+			require.NoError(t, err)
+			defer listener.Close()
+			received := make(chan []byte, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					received <- nil
+					return
+				}
+				defer conn.Close()
+				// The server reader is checked against known-answer vectors.
+				plaintext, _ := io.ReadAll(NewReaderWithReplayCache(conn, key, nil))
+				received <- plaintext
+			}()
+
+			d, err := NewStreamDialer(&transport.TCPEndpoint{Address: listener.Addr().String()}, key)
+			require.NoError(t, err)
+			conn, err := d.DialStream(context.Background(), testTargetAddr)
+			require.NoError(t, err)
+			_, err = conn.Write([]byte("first"))
+			require.NoError(t, err)
+			_, err = conn.Write([]byte(" second"))
+			require.NoError(t, err)
+			require.NoError(t, conn.CloseWrite())
+
+			plaintext := <-received
+			tgtAddr := socks.SplitAddr(plaintext)
+			require.Equal(t, testTargetAddr, tgtAddr.String())
+			plaintext = plaintext[len(tgtAddr):]
+			paddingLen := int(binary.BigEndian.Uint16(plaintext))
+			require.Greater(t, paddingLen, 0)
+			require.Equal(t, "first second", string(plaintext[2+paddingLen:]))
+			conn.Close()
+		})
+	}
+}
@@ -16,6 +16,8 @@ package shadowsocks
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"strings"
@@ -408,3 +410,112 @@ func BenchmarkWriter(b *testing.B) {
 	megabits := 8 * float64(b.N) * 1e-6
 	b.ReportMetric(megabits/(elapsed.Seconds()), "mbps")
 }
+
+// vector2022Time is the timestamp of the Shadowsocks 2022 known-answer vectors.
+var vector2022Time = time.Unix(1700000000, 0)
+
+// setVector2022Time makes the Shadowsocks 2022 code use vector2022Time as the current time until the test ends.
+func setVector2022Time(t *testing.T) {
+	timeNow = func() time.Time { return vector2022Time }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+// makeVector2022Key returns the key of the Shadowsocks 2022 known-answer vectors, with the PSK 010203...
+func makeVector2022Key(t *testing.T, cipherName string) *EncryptionKey {
+	spec, err := cipherByName(cipherName)
+	require.NoError(t, err)
+	psk := make([]byte, spec.keySize)
+	for i := range psk {
+		psk[i] = byte(i + 1)
+	}
+	key, err := NewEncryptionKey(cipherName, base64.StdEncoding.EncodeToString(psk))
+	require.NoError(t, err)
+	return key
+}
+
+// Shadowsocks 2022 streams for the PSK from makeVector2022Key and the vector2022Time timestamp, computed with an
+// implementation of the spec independent from this package. The request has salt 404142..., target
+// example.com:443, 4 bytes of padding, the initial payload "GET /" and a chunk with "more". The response has salt
+// 808182..., is for the request salt, and has the initial payload "hello" and a chunk with "world!".
+var streamVectors2022 = []struct {
+	cipherName string
+	request    string
+	response   string
+}{
+	{
+		cipherName: BLAKE3AES128GCM,
+		request: "404142434445464748494a4b4c4d4e4f2784685ce4ba000be65361214e951715e4ff507c379408f60ab34c3626630260" +
+			"a7754cdf76d1491ad68a11daf2da61b4d8056f9636f4082325d4dba617c477afcd318a538089fc87f01f6bde48765b14" +
+			"bc5174be421c0f4e5608e6081caf3f5f245086cfb8d519de24e768",
+		response: "808182838485868788898a8b8c8d8e8f0adc34178e9a9a854692d15e5acd7943b960416976bc16650a1604e9eb4662dd" +
+			"21117eb4db4ca5c1da50951fb1846a13f7a04325e9aa93862c5617d39cdd2e1efa3e25c827cc0daa2598f07eb33aaea3" +
+			"68bcb77cacbdc27d52037b31acb82b6a9600135c3f2d59fc",
+	},
+	{
+		cipherName: BLAKE3AES256GCM,
+		request: "404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f3a69c20fbad205f332d35b4345129409" +
+			"0ecade964f8d8a0c0e3ffd64b1ef46af841276f07a610a2c6c35b6d4159ad7842064da50d2aff24e77a6bc6f9bc912fa" +
+			"2bc6ba3c23790715c0a446d784e3b1578800820b5c3567a63a2b61e8cea9e60422a8678a3df09207abf323",
+		response: "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f448b90536a1096dd0f6a19d988d91499" +
+			"a7ede01d1518ae9fd112d27260af52766df05a6a9ecd5a320bf6746fbbbd39f556eee309ee69e509feb20e53ca52eb93" +
+			"ed43055290f89ab5efb24c6cc8cc6b79e49338fa2d10d9c758831fdc2fe7cfc13f6f133470e09fbac0829a7cc1b805b1" +
+			"45cb840c4888cd95",
+	},
+	{
+		cipherName: BLAKE3CHACHA20POLY1305,
+		request: "404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5feb9e7762bff6dc2748e6ea60e5da88ca" +
+			"e70d174186843fa30bf5537b7f9a3cf195053cc9c62018d1db2059d5b23b90b50897e869f20f926d8bf79b8edbacc348" +
+			"7b823568a91ea82b0e02d6e2ac366ce2f1d2f3f3937d2cada65c4841e45621df3aaabb76989b7a9789f9e6",
+		response: "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f3bc612487f1b55a2fff3421c240640a6" +
+			"e826ff44022156bdc123b3f70a014f4db65b6174f70fddea0d3c6257ad7b87474bcd5014726da0f75a688620c2f2840a" +
+			"687f21df1336013655ab670f4badde69bb5e8805e3ca2d85ca99aa357b3219809f81766ebf3fe75b1dca916896baf2a1" +
+			"b9ee5a0ef2d93146",
+	},
+}
+
+func TestReaderWithReplayCache_2022KnownAnswer(t *testing.T) {
+	setVector2022Time(t)
+	for _, v := range streamVectors2022 {
+		t.Run(v.cipherName, func(t *testing.T) {
+			key := makeVector2022Key(t, v.cipherName)
+			request, err := hex.DecodeString(v.request)
+			require.NoError(t, err)
+			plaintext, err := io.ReadAll(NewReaderWithReplayCache(bytes.NewReader(request), key, nil))
+			require.NoError(t, err)
+			// The variable-length header comes first: target address, padding length, padding and initial payload.
+			expected := append([]byte{3, 11}, "example.com"...)
+			expected = append(expected, 0x01, 0xbb, 0, 4, 0, 0, 0, 0)
+			expected = append(expected, "GET /more"...)
+			require.Equal(t, expected, plaintext)
+		})
+	}
+}
+
+func TestReader_2022KnownAnswer(t *testing.T) {
+	setVector2022Time(t)
+	for _, v := range streamVectors2022 {
+		t.Run(v.cipherName, func(t *testing.T) {
+			key := makeVector2022Key(t, v.cipherName)
+			response, err := hex.DecodeString(v.response)
+			require.NoError(t, err)
+			requestSalt, err := hex.DecodeString(v.request[:2*key.SaltSize()])
+			require.NoError(t, err)
+			plaintext, err := io.ReadAll(newReader(bytes.NewReader(response), key, requestSalt))
+			require.NoError(t, err)
+			require.Equal(t, "helloworld!", string(plaintext))
+
+			// The response must be for our request.
+			_, err = io.ReadAll(newReader(bytes.NewReader(response), key, make([]byte, key.SaltSize())))
+			require.ErrorContains(t, err, "does not match the request salt")
+		})
+	}
+}
+
+func TestReader_2022KnownAnswerExpired(t *testing.T) {
+	v := streamVectors2022[0]
+	key := makeVector2022Key(t, v.cipherName)
+	response, err := hex.DecodeString(v.response)
+	require.NoError(t, err)
+	_, err = io.ReadAll(NewReader(bytes.NewReader(response), key))
+	require.ErrorContains(t, err, "timestamp is off")
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package socks4 implements a SOCKS4 and SOCKS4a client.
+
+SOCKS4 only supports IPv4 destinations. SOCKS4a extends it so the proxy resolves domain names.
+IPv6 destinations are not supported by either version.
+
+See the [SOCKS4 protocol] and the [SOCKS4a extension].
+
+[SOCKS4 protocol]: https://www.openssh.com/txt/socks4.protocol
+[SOCKS4a extension]: https://www.openssh.com/txt/socks4a.protocol
+*/
+package socks4
+
+import "strconv"
+
+// ReplyCode is a byte-unsigned number that represents a SOCKS4 error as indicated in the CD field of the server reply.
+type ReplyCode byte
+
+// SOCKS4 reply codes, as enumerated in https://www.openssh.com/txt/socks4.protocol.
+const (
+	// ErrRequestRejected means the request was rejected or failed.
+	ErrRequestRejected = ReplyCode(91)
+	// ErrIdentdUnreachable means the request was rejected because the SOCKS server cannot connect to identd on the client.
+	ErrIdentdUnreachable = ReplyCode(92)
+	// ErrIdentdMismatch means the request was rejected because the client program and identd report different user-ids.
+	ErrIdentdMismatch = ReplyCode(93)
+)
+
+// replyGranted is the CD value that indicates the request was granted.
+const replyGranted = 90
+
+// cmdConnect is the CONNECT command code.
+const cmdConnect = 1
+
+var _ error = (ReplyCode)(0)
+
+// Error returns a human-readable description of the error, based on the SOCKS4 protocol.
+func (e ReplyCode) Error() string {
+	switch e {
+	case ErrRequestRejected:
+		return "request rejected or failed"
+	case ErrIdentdUnreachable:
+		return "request rejected because SOCKS server cannot connect to identd on the client"
+	case ErrIdentdMismatch:
+		return "request rejected because the client program and identd report different user-ids"
+	default:
+		return "reply code " + strconv.Itoa(int(e))
+	}
+}
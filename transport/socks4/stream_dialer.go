@@ -0,0 +1,136 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socks4
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// streamDialer is a [transport.StreamDialer] that connects through a SOCKS4 proxy.
+// Use [NewStreamDialer] to create new instances.
+type streamDialer struct {
+	se     transport.StreamEndpoint
+	userID string
+}
+
+var _ transport.StreamDialer = (*streamDialer)(nil)
+
+// NewStreamDialer creates a [transport.StreamDialer] that routes connections through the SOCKS4 proxy
+// listening at the given [transport.StreamEndpoint], identifying as userID, which can be empty.
+//
+// IPv4 destinations are sent as SOCKS4 requests. Domain names are sent as SOCKS4a requests, so the proxy
+// resolves them.
+func NewStreamDialer(endpoint transport.StreamEndpoint, userID string) (transport.StreamDialer, error) {
+	if endpoint == nil {
+		return nil, errors.New("argument endpoint must not be nil")
+	}
+	if strings.IndexByte(userID, 0) != -1 {
+		return nil, errors.New("userID must not contain NUL characters")
+	}
+	return &streamDialer{se: endpoint, userID: userID}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream using SOCKS4 or SOCKS4a.
+// The returned [error] will be of type [ReplyCode] if the server rejects the request, which
+// you can check against the error constants in this package using [errors.Is].
+func (d *streamDialer) DialStream(ctx context.Context, dstAddr string) (transport.StreamConn, error) {
+	req, err := d.appendConnectRequest(nil, dstAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SOCKS4 request: %w", err)
+	}
+	proxyConn, err := d.se.ConnectStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to SOCKS4 proxy: %w", err)
+	}
+	if err := request(proxyConn, req); err != nil {
+		proxyConn.Close()
+		return nil, err
+	}
+	return proxyConn, nil
+}
+
+// appendConnectRequest adds the CONNECT request for dstAddr to b.
+func (d *streamDialer) appendConnectRequest(b []byte, dstAddr string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(dstAddr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		return nil, errors.New("destination host must not be empty")
+	}
+	portNum, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	// +----+----+----+----+----+----+----+----+----+----+....+----+
+	// | VN | CD | DSTPORT |      DSTIP        | USERID       |NULL|
+	// +----+----+----+----+----+----+----+----+----+----+....+----+
+	//    1    1      2              4           variable       1
+	b = append(b, 4, cmdConnect)
+	b = binary.BigEndian.AppendUint16(b, uint16(portNum))
+	var domain string
+	if ip := net.ParseIP(host); ip != nil {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return nil, errors.New("SOCKS4 does not support IPv6 addresses")
+		}
+		b = append(b, ip4...)
+	} else {
+		if strings.IndexByte(host, 0) != -1 {
+			return nil, errors.New("domain name must not contain NUL characters")
+		}
+		// SOCKS4a: DSTIP is set to 0.0.0.x, with x non-zero, and the domain follows the USERID.
+		b = append(b, 0, 0, 0, 1)
+		domain = host
+	}
+	b = append(b, d.userID...)
+	b = append(b, 0)
+	if domain != "" {
+		b = append(b, domain...)
+		b = append(b, 0)
+	}
+	return b, nil
+}
+
+// request writes the request and reads the server reply.
+func request(conn io.ReadWriter, req []byte) error {
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to write SOCKS4 request: %w", err)
+	}
+	// +----+----+----+----+----+----+----+----+
+	// | VN | CD | DSTPORT |      DSTIP        |
+	// +----+----+----+----+----+----+----+----+
+	//    1    1      2              4
+	var reply [8]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return fmt.Errorf("failed to read SOCKS4 reply: %w", err)
+	}
+	if reply[0] != 0 {
+		return fmt.Errorf("invalid reply version %v. Expected 0", reply[0])
+	}
+	if reply[1] != replyGranted {
+		return ReplyCode(reply[1])
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socks4
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+type socks4Request struct {
+	Port   uint16
+	IP     netip.Addr
+	UserID string
+	Domain string
+}
+
+// runFakeServer serves a single SOCKS4 request, replies with replyCode, and echoes the data on success.
+func runFakeServer(t *testing.T, listener net.Listener, replyCode byte) <-chan socks4Request {
+	requests := make(chan socks4Request, 1)
+	go func() {
+		defer close(requests)
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var header [8]byte
+		_, err = io.ReadFull(reader, header[:])
+		require.NoError(t, err)
+		require.Equal(t, byte(4), header[0])
+		require.Equal(t, byte(cmdConnect), header[1])
+		req := socks4Request{
+			Port: binary.BigEndian.Uint16(header[2:4]),
+			IP:   netip.AddrFrom4([4]byte(header[4:8])),
+		}
+		userID, err := reader.ReadString(0)
+		require.NoError(t, err)
+		req.UserID = userID[:len(userID)-1]
+		if ip := req.IP.As4(); ip[0] == 0 && ip[1] == 0 && ip[2] == 0 && ip[3] != 0 {
+			domain, err := reader.ReadString(0)
+			require.NoError(t, err)
+			req.Domain = domain[:len(domain)-1]
+		}
+		requests <- req
+		_, err = conn.Write([]byte{0, replyCode, 0, 0, 0, 0, 0, 0})
+		require.NoError(t, err)
+		if replyCode == replyGranted {
+			io.Copy(conn, reader)
+		}
+	}()
+	return requests
+}
+
+func TestSOCKS4Dialer_NewStreamDialerNil(t *testing.T) {
+	dialer, err := NewStreamDialer(nil, "")
+	require.Nil(t, dialer)
+	require.Error(t, err)
+}
+
+func TestSOCKS4Dialer_Dial(t *testing.T) {
+	for _, tc := range []struct {
+		addr     string
+		expected socks4Request
+	}{
+		{"8.8.8.8:53", socks4Request{Port: 53, IP: netip.MustParseAddr("8.8.8.8"), UserID: "user"}},
+		{"example.com:443", socks4Request{Port: 443, IP: netip.MustParseAddr("0.0.0.1"), UserID: "user", Domain: "example.com"}},
+	} {
+		t.Run(tc.addr, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			requests := runFakeServer(t, listener, replyGranted)
+
+			dialer, err := NewStreamDialer(&transport.TCPEndpoint{Address: listener.Addr().String()}, "user")
+			require.NoError(t, err)
+			conn, err := dialer.DialStream(context.Background(), tc.addr)
+			require.NoError(t, err)
+			defer conn.Close()
+			require.Equal(t, tc.expected, <-requests)
+
+			_, err = conn.Write([]byte("Request"))
+			require.NoError(t, err)
+			require.NoError(t, conn.CloseWrite())
+			response, err := io.ReadAll(conn)
+			require.NoError(t, err)
+			require.Equal(t, "Request", string(response))
+		})
+	}
+}
+
+func TestSOCKS4Dialer_IPv6(t *testing.T) {
+	dialer, err := NewStreamDialer(&transport.TCPEndpoint{Address: "127.0.0.1:0"}, "")
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "[2001:4860:4860::8888]:443")
+	require.Error(t, err)
+}
+
+func TestSOCKS4Dialer_EmptyHost(t *testing.T) {
+	endpoint := transport.FuncStreamEndpoint(func(ctx context.Context) (transport.StreamConn, error) {
+		t.Error("unexpected connection to the proxy")
+		return nil, errors.New("unexpected connection")
+	})
+	dialer, err := NewStreamDialer(endpoint, "")
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), ":80")
+	require.Error(t, err)
+}
+
+func TestSOCKS4Dialer_DialError(t *testing.T) {
+	for _, replyCode := range []ReplyCode{ErrRequestRejected, ErrIdentdUnreachable, ErrIdentdMismatch} {
+		t.Run(strconv.Itoa(int(replyCode)), func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			requests := runFakeServer(t, listener, byte(replyCode))
+
+			dialer, err := NewStreamDialer(&transport.TCPEndpoint{Address: listener.Addr().String()}, "")
+			require.NoError(t, err)
+			_, err = dialer.DialStream(context.Background(), "example.com:443")
+			require.ErrorIs(t, err, replyCode)
+			<-requests
+		})
+	}
+}
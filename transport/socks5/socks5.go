@@ -39,6 +39,18 @@ const (
 	ErrAddressTypeNotSupported       = ReplyCode(0x08)
 )
 
+// AuthStatus is the non-zero STATUS byte the server returns when it rejects the username/password authentication,
+// as specified in https://datatracker.ietf.org/doc/html/rfc1929#section-2.
+// It allows callers to distinguish authentication failures from connection failures.
+type AuthStatus byte
+
+var _ error = (AuthStatus)(0)
+
+// Error returns a human-readable description of the authentication failure.
+func (s AuthStatus) Error() string {
+	return "username/password authentication failed with status " + strconv.Itoa(int(s))
+}
+
 // SOCKS5 commands, from https://datatracker.ietf.org/doc/html/rfc1928#section-4.
 const (
 	CmdConnect      = byte(1)
@@ -48,16 +48,19 @@ type Client struct {
 var _ transport.StreamDialer = (*Client)(nil)
 var _ transport.PacketListener = (*Client)(nil)
 
+// SetCredentials sets the credentials for the username/password authentication defined in
+// https://datatracker.ietf.org/doc/html/rfc1929. Each field must be between 1 and 255 bytes long.
+// If the server rejects the credentials, dials will fail with an [AuthStatus] error.
 func (c *Client) SetCredentials(username, password []byte) error {
 	if len(username) > 255 {
-		return errors.New("username exceeds 255 bytes")
+		return fmt.Errorf("username length = %v is over 255 bytes", len(username))
 	}
 	if len(username) == 0 {
 		return errors.New("username must be at least 1 byte")
 	}
 
 	if len(password) > 255 {
-		return errors.New("password exceeds 255 bytes")
+		return fmt.Errorf("password length = %v is over 255 bytes", len(password))
 	}
 	if len(password) == 0 {
 		return errors.New("password must be at least 1 byte")
@@ -169,7 +172,7 @@ func (c *Client) request(conn io.ReadWriter, cmd byte, dstAddr string) (*address
 			return nil, fmt.Errorf("invalid authentication version %v. Expected 1", buffer[2])
 		}
 		if buffer[3] != 0 {
-			return nil, fmt.Errorf("authentication failed: %v", buffer[3])
+			return nil, fmt.Errorf("authentication failed: %w", AuthStatus(buffer[3]))
 		}
 	default:
 		return nil, fmt.Errorf("unsupported SOCKS authentication method %v. Expected 2", buffer[1])
@@ -230,9 +233,11 @@ func (c *Client) connectAndRequest(ctx context.Context, cmd byte, dstAddr string
 // The returned [error] will be of type [ReplyCode] if the server sends a SOCKS error reply code, which
 // you can check against the error constants in this package using [errors.Is].
 func (c *Client) DialStream(ctx context.Context, dstAddr string) (transport.StreamConn, error) {
-	proxyConn, _, err := c.connectAndRequest(ctx, CmdConnect, dstAddr)
-	if err != nil {
-		return nil, err
-	}
-	return proxyConn, nil
+	return transport.TraceDialStream(ctx, "socks5", dstAddr, func() (transport.StreamConn, error) {
+		proxyConn, _, err := c.connectAndRequest(ctx, CmdConnect, dstAddr)
+		if err != nil {
+			return nil, err
+		}
+		return proxyConn, nil
+	})
 }
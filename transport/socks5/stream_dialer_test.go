@@ -15,6 +15,7 @@
 package socks5
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -243,5 +244,19 @@ func TestConnectWithAuth(t *testing.T) {
 	err = dialer.SetCredentials([]byte("testusername"), []byte("wrongpassword"))
 	require.NoError(t, err)
 	_, err = dialer.DialStream(context.Background(), address)
-	require.Error(t, err)
+	var authErr AuthStatus
+	require.ErrorAs(t, err, &authErr)
+	require.NotEqual(t, AuthStatus(0), authErr)
+}
+
+func TestSetCredentialsLengths(t *testing.T) {
+	client, err := NewClient(&transport.TCPEndpoint{Address: "unused:1080"})
+	require.NoError(t, err)
+	long := bytes.Repeat([]byte("a"), 256)
+
+	require.NoError(t, client.SetCredentials(long[:255], long[:255]))
+	require.ErrorContains(t, client.SetCredentials(long, []byte("p")), "username length = 256")
+	require.ErrorContains(t, client.SetCredentials([]byte("u"), long), "password length = 256")
+	require.Error(t, client.SetCredentials(nil, []byte("p")))
+	require.Error(t, client.SetCredentials([]byte("u"), nil))
 }
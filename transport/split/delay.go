@@ -0,0 +1,86 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+type dialerConfig struct {
+	delay time.Duration
+}
+
+// DialerOption configures the dialers created by [NewStreamDialer], [NewStreamDialerFunc] and [NewPatternDialer].
+type DialerOption func(config *dialerConfig)
+
+// WithDelay makes the dialers wait for delay between writing the fragments of a split write, so the fragments go out
+// in separate packets, and arrive far enough apart to defeat the filters that reassemble close packets.
+// The wait stops early if the connection is closed.
+func WithDelay(delay time.Duration) DialerOption {
+	return func(config *dialerConfig) {
+		config.delay = delay
+	}
+}
+
+func newDialerConfig(options []DialerOption) (*dialerConfig, error) {
+	config := &dialerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if config.delay < 0 {
+		return nil, errors.New("delay must not be negative")
+	}
+	return config, nil
+}
+
+// delayConn is a [transport.StreamConn] that waits between fragments, until the connection is closed.
+type delayConn struct {
+	transport.StreamConn
+	delay     time.Duration
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newDelayConn returns the connection and the function to wait between fragments, which is nil without a delay.
+func newDelayConn(conn transport.StreamConn, delay time.Duration) (transport.StreamConn, func() error) {
+	if delay == 0 {
+		return conn, nil
+	}
+	dc := &delayConn{StreamConn: conn, delay: delay, closed: make(chan struct{})}
+	return dc, dc.wait
+}
+
+// wait waits for the delay, or returns [net.ErrClosed] if the connection is closed first.
+func (c *delayConn) wait() error {
+	timer := time.NewTimer(c.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.closed:
+		return net.ErrClosed
+	}
+}
+
+// Close stops the pending waits and closes the connection.
+func (c *delayConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.StreamConn.Close()
+}
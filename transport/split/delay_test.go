@@ -0,0 +1,145 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite_Wait(t *testing.T) {
+	var innerWriter collectWrites
+	var waits int
+	wait := func() error {
+		// The wait happens after each fragment but the last.
+		require.Len(t, innerWriter.writes, waits+1)
+		waits++
+		return nil
+	}
+	splitWriter := newWriter(&innerWriter, NewRepeatedSplitIterator(RepeatedSplit{2, 1}), wait)
+	n, err := splitWriter.Write([]byte("Request"))
+	require.NoError(t, err)
+	require.Equal(t, 7, n)
+	require.Equal(t, [][]byte{[]byte("R"), []byte("e"), []byte("quest")}, innerWriter.writes)
+	require.Equal(t, 2, waits)
+}
+
+func TestWrite_WaitError(t *testing.T) {
+	var innerWriter collectWrites
+	errWait := errors.New("wait failed")
+	splitWriter := newWriter(&innerWriter, NewFixedSplitIterator(3), func() error { return errWait })
+	n, err := splitWriter.Write([]byte("Request"))
+	require.ErrorIs(t, err, errWait)
+	require.Equal(t, 3, n)
+	require.Equal(t, [][]byte{[]byte("Req")}, innerWriter.writes)
+}
+
+func TestReadFrom_Wait(t *testing.T) {
+	var innerWriter collectWrites
+	var waits int
+	splitWriter := newWriter(&collectWritesReaderFrom{&innerWriter}, NewFixedSplitIterator(3), func() error {
+		waits++
+		return nil
+	})
+	n, err := splitWriter.(*splitWriterReaderFrom).ReadFrom(bytes.NewReader([]byte("Request")))
+	require.NoError(t, err)
+	require.Equal(t, int64(7), n)
+	require.Equal(t, [][]byte{[]byte("Req"), []byte("uest")}, innerWriter.writes)
+	require.Equal(t, 1, waits)
+}
+
+func TestNewStreamDialer_NegativeDelay(t *testing.T) {
+	_, err := NewStreamDialer(&transport.TCPDialer{}, NewFixedSplitIterator(1), WithDelay(-time.Millisecond))
+	require.Error(t, err)
+	_, err = NewPatternDialer(&transport.TCPDialer{}, []byte("Host:"), 0, WithDelay(-time.Millisecond))
+	require.Error(t, err)
+}
+
+func TestStreamDialer_Delay(t *testing.T) {
+	var conns []*collectWritesConn
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		conn := &collectWritesConn{StreamConn: &fakeConn{}}
+		conns = append(conns, conn)
+		return conn, nil
+	})
+	const delay = 50 * time.Millisecond
+	dialer, err := NewStreamDialer(baseDialer, NewFixedSplitIterator(3), WithDelay(delay))
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "example.com:80")
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = conn.Write([]byte("Request"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), delay)
+	require.Equal(t, [][]byte{[]byte("Req"), []byte("uest")}, conns[0].writes)
+}
+
+func TestStreamDialer_DelayStopsOnClose(t *testing.T) {
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return &collectWritesConn{StreamConn: &fakeConn{}}, nil
+	})
+	dialer, err := NewPatternDialer(baseDialer, []byte("Host:"), 0, WithDelay(time.Hour))
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "example.com:80")
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		conn.Close()
+	}()
+	n, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	require.ErrorIs(t, err, net.ErrClosed)
+	require.Equal(t, len("GET / HTTP/1.1\r\n"), n)
+}
+
+// collectWritesReaderFrom is a [collectWrites] that implements [io.ReaderFrom] by collecting each read as a write.
+type collectWritesReaderFrom struct {
+	*collectWrites
+}
+
+func (w *collectWritesReaderFrom) ReadFrom(r io.Reader) (int64, error) {
+	var written int64
+	buf := make([]byte, 1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			w.Write(buf[:n])
+			written += int64(n)
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// fakeConn is a [transport.StreamConn] that does nothing.
+type fakeConn struct {
+	transport.StreamConn
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// patternDialer is a [transport.StreamDialer] that splits the first write right before a byte pattern.
+// Use [NewPatternDialer] to create new instances.
+type patternDialer struct {
+	dialer   transport.StreamDialer
+	pattern  []byte
+	fallback int64
+	config   *dialerConfig
+}
+
+var _ transport.StreamDialer = (*patternDialer)(nil)
+var _ transport.ConnectFailureReporter = (*patternDialer)(nil)
+
+// NewPatternDialer creates a [transport.StreamDialer] that splits the first write of the outgoing stream immediately
+// before the first occurrence of pattern, for example the "Host:" header of an HTTP request, or the server name in a
+// TLS Client Hello. Unlike a fixed split position, this works regardless of where the pattern lands.
+//
+// Only the first write is scanned. If the pattern is not found in it, or is at its very beginning, the first write
+// is split fallback bytes from the start instead. Use a fallback of zero to not split in that case.
+// Subsequent writes are passed through unmodified.
+func NewPatternDialer(dialer transport.StreamDialer, pattern []byte, fallback int64, options ...DialerOption) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if len(pattern) == 0 {
+		return nil, errors.New("argument pattern must not be empty")
+	}
+	if fallback < 0 {
+		return nil, errors.New("argument fallback must not be negative")
+	}
+	config, err := newDialerConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &patternDialer{dialer: dialer, pattern: bytes.Clone(pattern), fallback: fallback, config: config}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *patternDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, wait := newDelayConn(innerConn, d.config.delay)
+	w := &patternWriter{writer: innerConn, pattern: d.pattern, fallback: d.fallback, wait: wait}
+	return transport.WrapConn(conn, innerConn, w), nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *patternDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+// patternWriter is an [io.Writer] that splits the first write before pattern.
+type patternWriter struct {
+	writer   io.Writer
+	pattern  []byte
+	fallback int64
+	done     bool
+	// wait, if not nil, is called between the fragments.
+	wait func() error
+}
+
+var _ io.Writer = (*patternWriter)(nil)
+
+// Write implements io.Writer.
+func (w *patternWriter) Write(data []byte) (int, error) {
+	if w.done || len(data) == 0 {
+		return w.writer.Write(data)
+	}
+	w.done = true
+	split := int64(bytes.Index(data, w.pattern))
+	if split <= 0 {
+		split = w.fallback
+	}
+	if split <= 0 || split >= int64(len(data)) {
+		return w.writer.Write(data)
+	}
+	written, err := w.writer.Write(data[:split])
+	if err != nil {
+		return written, err
+	}
+	if w.wait != nil {
+		if err := w.wait(); err != nil {
+			return written, err
+		}
+	}
+	n, err := w.writer.Write(data[split:])
+	return written + n, err
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPatternDialer_InvalidArgs(t *testing.T) {
+	_, err := NewPatternDialer(nil, []byte("Host:"), 0)
+	require.Error(t, err)
+	_, err = NewPatternDialer(&transport.TCPDialer{}, nil, 0)
+	require.Error(t, err)
+	_, err = NewPatternDialer(&transport.TCPDialer{}, []byte("Host:"), -1)
+	require.Error(t, err)
+}
+
+func TestPatternWriter(t *testing.T) {
+	request := "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	cases := []struct {
+		msg      string
+		pattern  string
+		fallback int64
+		expected []string
+	}{
+		{"found", "Host:", 2, []string{"GET / HTTP/1.1\r\n", "Host: example.com\r\n\r\n"}},
+		{"found with different offset", "example", 0, []string{"GET / HTTP/1.1\r\nHost: ", "example.com\r\n\r\n"}},
+		{"not found", "Cookie:", 2, []string{"GE", "T / HTTP/1.1\r\nHost: example.com\r\n\r\n"}},
+		{"not found without fallback", "Cookie:", 0, []string{request}},
+		{"at start", "GET", 3, []string{"GET", " / HTTP/1.1\r\nHost: example.com\r\n\r\n"}},
+		{"fallback too long", "Cookie:", 1000, []string{request}},
+	}
+	for _, tc := range cases {
+		var innerWriter collectWrites
+		w := &patternWriter{writer: &innerWriter, pattern: []byte(tc.pattern), fallback: tc.fallback}
+		n, err := w.Write([]byte(request))
+		require.NoError(t, err, tc.msg)
+		require.Equal(t, len(request), n, tc.msg)
+		// Only the first write is split.
+		_, err = w.Write([]byte(request))
+		require.NoError(t, err, tc.msg)
+		expected := make([][]byte, 0, len(tc.expected)+1)
+		for _, write := range tc.expected {
+			expected = append(expected, []byte(write))
+		}
+		expected = append(expected, []byte(request))
+		require.Equal(t, expected, innerWriter.writes, tc.msg)
+	}
+}
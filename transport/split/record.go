@@ -0,0 +1,143 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+const recordHeaderLen = 5
+
+// recordAwareDialer is a [transport.StreamDialer] that splits the outgoing stream at a TLS record boundary.
+// Use [NewRecordAwareDialer] to create new instances.
+type recordAwareDialer struct {
+	dialer            transport.StreamDialer
+	splitAfterRecords int
+}
+
+var _ transport.StreamDialer = (*recordAwareDialer)(nil)
+var _ transport.ConnectFailureReporter = (*recordAwareDialer)(nil)
+
+// NewRecordAwareDialer creates a [transport.StreamDialer] that splits the outgoing stream after the first
+// splitAfterRecords TLS records, so the split is aligned to a record boundary. For example, use 1 to send the
+// record carrying the ClientHello in its own write.
+//
+// The dialer parses the 5-byte header of each outgoing record (content type, version, length) to find where
+// records end, even if records span multiple writes or a write carries multiple records. If the stream doesn't
+// look like TLS, that is, a header has an unknown content type or a major version other than 3, the dialer stops
+// parsing and passes the stream through unmodified.
+func NewRecordAwareDialer(dialer transport.StreamDialer, splitAfterRecords int) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if splitAfterRecords <= 0 {
+		return nil, errors.New("argument splitAfterRecords must be positive")
+	}
+	return &recordAwareDialer{dialer: dialer, splitAfterRecords: splitAfterRecords}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *recordAwareDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	return transport.WrapConn(innerConn, innerConn, newRecordWriter(innerConn, d.splitAfterRecords)), nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *recordAwareDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+// recordWriter is an [io.Writer] that splits the stream after a number of TLS records.
+type recordWriter struct {
+	writer io.Writer
+	// Records to complete before the split. Zero means we are done parsing.
+	recordsLeft int
+	header      [recordHeaderLen]byte
+	headerLen   int
+	bodyLeft    int
+}
+
+var _ io.Writer = (*recordWriter)(nil)
+
+func newRecordWriter(writer io.Writer, splitAfterRecords int) *recordWriter {
+	return &recordWriter{writer: writer, recordsLeft: splitAfterRecords}
+}
+
+// splitPoint consumes data and returns the offset of the split point in data, or -1 if it's not in data.
+func (w *recordWriter) splitPoint(data []byte) int {
+	for i := 0; i < len(data); {
+		if w.headerLen < recordHeaderLen {
+			n := copy(w.header[w.headerLen:], data[i:])
+			w.headerLen += n
+			i += n
+			if w.headerLen < recordHeaderLen {
+				return -1
+			}
+			if !isValidRecordHeader(w.header[:]) {
+				// Not TLS. Give up on splitting.
+				w.recordsLeft = 0
+				return -1
+			}
+			w.bodyLeft = int(binary.BigEndian.Uint16(w.header[3:]))
+		}
+		n := len(data) - i
+		if w.bodyLeft < n {
+			n = w.bodyLeft
+		}
+		w.bodyLeft -= n
+		i += n
+		if w.bodyLeft == 0 {
+			// Record complete.
+			w.headerLen = 0
+			w.recordsLeft--
+			if w.recordsLeft == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isValidRecordHeader returns whether the header has a known TLS content type and a major version of 3.
+func isValidRecordHeader(header []byte) bool {
+	// Content types: change_cipher_spec(20), alert(21), handshake(22), application_data(23).
+	return header[0] >= 20 && header[0] <= 23 && header[1] == 3
+}
+
+// Write implements io.Writer.
+func (w *recordWriter) Write(data []byte) (int, error) {
+	if w.recordsLeft == 0 {
+		return w.writer.Write(data)
+	}
+	split := w.splitPoint(data)
+	if split < 0 || split == len(data) {
+		// The split point, if any, is at the write boundary already.
+		return w.writer.Write(data)
+	}
+	written, err := w.writer.Write(data[:split])
+	if err != nil {
+		return written, err
+	}
+	n, err := w.writer.Write(data[split:])
+	return written + n, err
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// makeRecord returns a TLS record with the given content type and payload.
+func makeRecord(contentType byte, payload string) []byte {
+	return append([]byte{contentType, 3, 1, byte(len(payload) >> 8), byte(len(payload))}, payload...)
+}
+
+func TestNewRecordAwareDialer_InvalidArgs(t *testing.T) {
+	_, err := NewRecordAwareDialer(nil, 1)
+	require.Error(t, err)
+	_, err = NewRecordAwareDialer(&transport.TCPDialer{}, 0)
+	require.Error(t, err)
+}
+
+func TestRecordWriter_MultiRecordWrite(t *testing.T) {
+	hello := makeRecord(22, "ClientHello")
+	ccs := makeRecord(20, "\x01")
+	data := makeRecord(23, "Application data")
+	var innerWriter collectWrites
+	w := newRecordWriter(&innerWriter, 1)
+	n, err := w.Write(bytes.Join([][]byte{hello, ccs, data}, nil))
+	require.NoError(t, err)
+	require.Equal(t, len(hello)+len(ccs)+len(data), n)
+	require.Equal(t, [][]byte{hello, bytes.Join([][]byte{ccs, data}, nil)}, innerWriter.writes)
+}
+
+func TestRecordWriter_SplitAfterTwoRecords(t *testing.T) {
+	hello := makeRecord(22, "ClientHello")
+	ccs := makeRecord(20, "\x01")
+	data := makeRecord(23, "Application data")
+	var innerWriter collectWrites
+	w := newRecordWriter(&innerWriter, 2)
+	_, err := w.Write(bytes.Join([][]byte{hello, ccs, data}, nil))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{bytes.Join([][]byte{hello, ccs}, nil), data}, innerWriter.writes)
+}
+
+func TestRecordWriter_RecordsAcrossWrites(t *testing.T) {
+	hello := makeRecord(22, "ClientHello")
+	data := makeRecord(23, "Application data")
+	stream := bytes.Join([][]byte{hello, data}, nil)
+	var innerWriter collectWrites
+	w := newRecordWriter(&innerWriter, 1)
+	// Split the header and the body of the first record across writes.
+	for _, chunk := range [][]byte{stream[:3], stream[3:8], stream[8:20]} {
+		_, err := w.Write(chunk)
+		require.NoError(t, err)
+	}
+	_, err := w.Write(stream[20:])
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{stream[:3], stream[3:8], stream[8:len(hello)], stream[len(hello):20], stream[20:]}, innerWriter.writes)
+}
+
+func TestRecordWriter_SplitAtWriteBoundary(t *testing.T) {
+	hello := makeRecord(22, "ClientHello")
+	data := makeRecord(23, "Application data")
+	var innerWriter collectWrites
+	w := newRecordWriter(&innerWriter, 1)
+	_, err := w.Write(hello)
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Join([][]byte{data, data}, nil))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{hello, bytes.Join([][]byte{data, data}, nil)}, innerWriter.writes)
+}
+
+func TestRecordWriter_NonTLS(t *testing.T) {
+	var innerWriter collectWrites
+	w := newRecordWriter(&innerWriter, 1)
+	request := []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	_, err := w.Write(request)
+	require.NoError(t, err)
+	_, err = w.Write(request)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{request, request}, innerWriter.writes)
+}
@@ -24,21 +24,45 @@ import (
 // splitDialer is a [transport.StreamDialer] that implements the split strategy.
 // Use [NewStreamDialer] to create new instances.
 type splitDialer struct {
-	dialer    transport.StreamDialer
-	nextSplit SplitIterator
+	dialer       transport.StreamDialer
+	newNextSplit func() SplitIterator
+	config       *dialerConfig
 }
 
 var _ transport.StreamDialer = (*splitDialer)(nil)
+var _ transport.ConnectFailureReporter = (*splitDialer)(nil)
 
 // NewStreamDialer creates a [transport.StreamDialer] that splits the outgoing stream according to nextSplit.
-func NewStreamDialer(dialer transport.StreamDialer, nextSplit SplitIterator) (transport.StreamDialer, error) {
+// Only the writes to the server are split. The data from the server is not modified.
+func NewStreamDialer(dialer transport.StreamDialer, nextSplit SplitIterator, options ...DialerOption) (transport.StreamDialer, error) {
 	if dialer == nil {
 		return nil, errors.New("argument dialer must not be nil")
 	}
 	if nextSplit == nil {
 		return nil, errors.New("argument nextSplit must not be nil")
 	}
-	return &splitDialer{dialer: dialer, nextSplit: nextSplit}, nil
+	config, err := newDialerConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &splitDialer{dialer: dialer, newNextSplit: func() SplitIterator { return nextSplit }, config: config}, nil
+}
+
+// NewStreamDialerFunc creates a [transport.StreamDialer] that splits each outgoing stream according to a new
+// [SplitIterator] returned by newNextSplit. Use it with iterators that have state, such as the ones returned by
+// [NewRepeatedSplitIterator] and [NewRandomSplitIterator], so that every connection gets its own splits.
+func NewStreamDialerFunc(dialer transport.StreamDialer, newNextSplit func() SplitIterator, options ...DialerOption) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if newNextSplit == nil {
+		return nil, errors.New("argument newNextSplit must not be nil")
+	}
+	config, err := newDialerConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &splitDialer{dialer: dialer, newNextSplit: newNextSplit, config: config}, nil
 }
 
 // DialStream implements [transport.StreamDialer].DialStream.
@@ -47,5 +71,11 @@ func (d *splitDialer) DialStream(ctx context.Context, remoteAddr string) (transp
 	if err != nil {
 		return nil, err
 	}
-	return transport.WrapConn(innerConn, innerConn, NewWriter(innerConn, d.nextSplit)), nil
+	conn, wait := newDelayConn(innerConn, d.config.delay)
+	return transport.WrapConn(conn, innerConn, newWriter(innerConn, d.newNextSplit(), wait)), nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *splitDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
 }
@@ -0,0 +1,77 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package split
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// collectWritesConn is a [transport.StreamConn] that collects the writes.
+type collectWritesConn struct {
+	transport.StreamConn
+	collectWrites
+}
+
+func (c *collectWritesConn) Write(data []byte) (int, error) {
+	return c.collectWrites.Write(data)
+}
+
+func TestNewStreamDialerFunc(t *testing.T) {
+	var conns []*collectWritesConn
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		conn := &collectWritesConn{}
+		conns = append(conns, conn)
+		return conn, nil
+	})
+	dialer, err := NewStreamDialerFunc(baseDialer, func() SplitIterator { return NewRepeatedSplitIterator(RepeatedSplit{2, 3}) })
+	require.NoError(t, err)
+
+	// Each connection gets its own splits.
+	for i := 0; i < 2; i++ {
+		conn, err := dialer.DialStream(context.Background(), "example.com:80")
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("RequestRequest"))
+		require.NoError(t, err)
+	}
+	require.Len(t, conns, 2)
+	for _, conn := range conns {
+		require.Equal(t, [][]byte{[]byte("Req"), []byte("ues"), []byte("tRequest")}, conn.writes)
+	}
+}
+
+func TestStreamDialerReportsConnectFailure(t *testing.T) {
+	base := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return &collectWritesConn{}, nil
+	})
+	d, err := NewStreamDialer(base, NewFixedSplitIterator(1))
+	require.NoError(t, err)
+	require.True(t, transport.ReportsConnectFailure(d))
+
+	d, err = NewStreamDialer(unreportedFailureDialer{base}, NewFixedSplitIterator(1))
+	require.NoError(t, err)
+	require.False(t, transport.ReportsConnectFailure(d))
+}
+
+// unreportedFailureDialer is a [transport.StreamDialer] that declares its dial errors don't reflect the reachability
+// of the destination, like Shadowsocks.
+type unreportedFailureDialer struct {
+	transport.StreamDialer
+}
+
+func (d unreportedFailureDialer) ReportsConnectFailure() bool { return false }
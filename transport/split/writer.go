@@ -15,7 +15,9 @@
 package split
 
 import (
+	"crypto/rand"
 	"io"
+	"math/big"
 )
 
 type splitWriter struct {
@@ -23,6 +25,8 @@ type splitWriter struct {
 	// Bytes until the next split. This must always be > 0, unless splits are done.
 	nextSplitBytes    int64
 	nextSegmentLength func() int64
+	// wait, if not nil, is called between the fragments.
+	wait func() error
 }
 
 var _ io.Writer = (*splitWriter)(nil)
@@ -78,10 +82,59 @@ func NewRepeatedSplitIterator(splits ...RepeatedSplit) SplitIterator {
 	}
 }
 
+// RandomSplit represents a split sequence of count segments, each with a random length between MinBytes and
+// MaxBytes, inclusive.
+type RandomSplit struct {
+	Count    int
+	MinBytes int64
+	MaxBytes int64
+}
+
+// NewRandomSplitIterator is a helper function that returns a [SplitIterator] that returns split points according to
+// splits, picking each segment length at random from its range. This prevents a censor from learning a static split
+// signature. The lengths are picked with a cryptographically secure random generator.
+// Use it with [NewStreamDialerFunc] to pick new split points for each connection.
+func NewRandomSplitIterator(splits ...RandomSplit) SplitIterator {
+	// Make sure we don't edit the original slice.
+	cleanSplits := make([]RandomSplit, 0, len(splits))
+	// Remove no-op splits.
+	for _, split := range splits {
+		if split.Count > 0 && split.MaxBytes > 0 && split.MinBytes <= split.MaxBytes {
+			// A zero length would end the splits.
+			if split.MinBytes < 1 {
+				split.MinBytes = 1
+			}
+			cleanSplits = append(cleanSplits, split)
+		}
+	}
+	return func() int64 {
+		if len(cleanSplits) == 0 {
+			return 0
+		}
+		next := cleanSplits[0].MinBytes
+		if delta := cleanSplits[0].MaxBytes - cleanSplits[0].MinBytes; delta > 0 {
+			n, err := rand.Int(rand.Reader, big.NewInt(delta+1))
+			if err == nil {
+				next += n.Int64()
+			}
+		}
+		cleanSplits[0].Count -= 1
+		if cleanSplits[0].Count == 0 {
+			cleanSplits = cleanSplits[1:]
+		}
+		return next
+	}
+}
+
 // NewWriter creates a split Writer that calls the nextSegmentLength [SplitIterator] to determine the number bytes until the next split
 // point until it returns zero.
 func NewWriter(writer io.Writer, nextSegmentLength SplitIterator) io.Writer {
-	sw := &splitWriter{writer: writer, nextSegmentLength: nextSegmentLength}
+	return newWriter(writer, nextSegmentLength, nil)
+}
+
+// newWriter is like [NewWriter], but calls wait, if not nil, between the fragments.
+func newWriter(writer io.Writer, nextSegmentLength SplitIterator, wait func() error) io.Writer {
+	sw := &splitWriter{writer: writer, nextSegmentLength: nextSegmentLength, wait: wait}
 	sw.nextSplitBytes = nextSegmentLength()
 	if rf, ok := writer.(io.ReaderFrom); ok {
 		return &splitWriterReaderFrom{sw, rf}
@@ -104,6 +157,11 @@ func (w *splitWriterReaderFrom) ReadFrom(source io.Reader) (int64, error) {
 			// Source is done before the split happened. Return.
 			return written, err
 		}
+		if w.wait != nil {
+			if err := w.wait(); err != nil {
+				return written, err
+			}
+		}
 	}
 	n, err := w.rf.ReadFrom(source)
 	written += n
@@ -135,6 +193,11 @@ func (w *splitWriter) Write(data []byte) (written int, err error) {
 			return written, err
 		}
 		data = data[n:]
+		if w.wait != nil {
+			if err := w.wait(); err != nil {
+				return written, err
+			}
+		}
 	}
 	n, err := w.writer.Write(data)
 	written += n
@@ -217,3 +217,23 @@ func BenchmarkReadFrom(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+func TestRandomSplitIterator(t *testing.T) {
+	nextSplit := NewRandomSplitIterator(RandomSplit{0, 1, 2}, RandomSplit{2, 3, 10}, RandomSplit{1, 5, 4}, RandomSplit{1, 0, 1}, RandomSplit{1, 7, 7})
+	for i := 0; i < 2; i++ {
+		next := nextSplit()
+		require.GreaterOrEqual(t, next, int64(3))
+		require.LessOrEqual(t, next, int64(10))
+	}
+	require.Equal(t, int64(1), nextSplit())
+	require.Equal(t, int64(7), nextSplit())
+	require.Equal(t, int64(0), nextSplit())
+}
+
+func TestRandomSplitIteratorCoversRange(t *testing.T) {
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000; i++ {
+		seen[NewRandomSplitIterator(RandomSplit{1, 3, 5})()] = true
+	}
+	require.Equal(t, map[int64]bool{3: true, 4: true, 5: true}, seen)
+}
@@ -16,8 +16,11 @@ package transport
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net"
+	"time"
 )
 
 // StreamConn is a [net.Conn] that allows for closing only the reader or writer end of it, supporting half-open state.
@@ -59,11 +62,24 @@ func (dc *duplexConnAdaptor) ReadFrom(r io.Reader) (int64, error) {
 	return io.Copy(dc.w, r)
 }
 func (dc *duplexConnAdaptor) CloseWrite() error {
-	return dc.StreamConn.CloseWrite()
+	// Send the data buffered by the writer before the EOF, since it would be lost otherwise.
+	var flushErr error
+	if f, ok := dc.w.(flusher); ok {
+		flushErr = f.Flush()
+	}
+	return errors.Join(flushErr, dc.StreamConn.CloseWrite())
+}
+
+// flusher is implemented by writers that buffer data, like the Shadowsocks writer.
+type flusher interface {
+	Flush() error
 }
 
 // WrapConn wraps an existing [StreamConn] with a new [io.Reader] and [io.Writer], but preserves the original
 // [StreamConn].CloseRead and [StreamConn].CloseWrite.
+//
+// If the writer has a Flush() error method, CloseWrite calls it before closing the write end, so the peer gets
+// all the data before the EOF.
 func WrapConn(c StreamConn, r io.Reader, w io.Writer) StreamConn {
 	conn := c
 	// We special-case duplexConnAdaptor to avoid multiple levels of nesting.
@@ -135,16 +151,69 @@ type StreamDialer interface {
 // It provides a convenient way to use a [net.Dialer] when you need a [StreamDialer].
 type TCPDialer struct {
 	Dialer net.Dialer
+	// KeepAlive, if not nil, configures the TCP keepalive probes of the connections, overriding Dialer.KeepAlive.
+	KeepAlive *TCPKeepAliveConfig
+	// DisableNoDelay enables Nagle's algorithm, which Go disables by default. That reduces the number of small
+	// packets, at the cost of latency for interactive protocols.
+	DisableNoDelay bool
+}
+
+// TCPKeepAliveConfig configures the TCP keepalive probes, which detect dead peers and keep the NAT mappings alive.
+// The zero fields keep the values set by [net.Dialer]. The durations are rounded up to seconds.
+type TCPKeepAliveConfig struct {
+	// Idle is how long the connection must be idle before the first probe is sent.
+	Idle time.Duration
+	// Interval is the time between unanswered probes.
+	Interval time.Duration
+	// Count is the number of unanswered probes after which the connection is dropped.
+	Count int
 }
 
 var _ StreamDialer = (*TCPDialer)(nil)
 
 func (d *TCPDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
-	conn, err := d.Dialer.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return nil, err
+	return TraceDialStream(ctx, "tcp", addr, func() (StreamConn, error) {
+		conn, err := d.Dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		tcpConn := conn.(*net.TCPConn)
+		if err := d.setOptions(tcpConn); err != nil {
+			tcpConn.Close()
+			return nil, err
+		}
+		return tcpConn, nil
+	})
+}
+
+// setOptions applies the socket options after the connection is established, since the [net.Dialer] sets its own
+// keepalive options then, overriding the ones set in the Control function.
+func (d *TCPDialer) setOptions(conn *net.TCPConn) error {
+	if d.DisableNoDelay {
+		if err := conn.SetNoDelay(false); err != nil {
+			return fmt.Errorf("failed to disable TCP_NODELAY: %w", err)
+		}
 	}
-	return conn.(*net.TCPConn), nil
+	if d.KeepAlive != nil {
+		if err := conn.SetKeepAlive(true); err != nil {
+			return fmt.Errorf("failed to enable keepalive: %w", err)
+		}
+		if err := setKeepAliveConfig(conn, d.KeepAlive); err != nil {
+			return fmt.Errorf("failed to configure keepalive: %w", err)
+		}
+	}
+	return nil
+}
+
+// keepAliveSeconds returns the duration in seconds, rounded up, as expected by the socket options.
+func keepAliveSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter]. It returns true, since the dial only succeeds after
+// the TCP handshake with the destination.
+func (d *TCPDialer) ReportsConnectFailure() bool {
+	return true
 }
 
 // FuncStreamDialer is a [StreamDialer] that uses the given function to dial.
@@ -156,3 +225,23 @@ var _ StreamDialer = (*FuncStreamDialer)(nil)
 func (f FuncStreamDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
 	return f(ctx, addr)
 }
+
+// ConnectFailureReporter is an optional interface for dialers to declare whether their dial errors reliably reflect
+// the reachability of the destination. Algorithms like Happy Eyeballs rely on that to fall back to other addresses.
+//
+// Proxy dialers that return a connection before the proxy connects to the destination, like Shadowsocks, should
+// implement it and return false.
+type ConnectFailureReporter interface {
+	// ReportsConnectFailure returns true if a dial only succeeds once the destination is reachable.
+	ReportsConnectFailure() bool
+}
+
+// ReportsConnectFailure returns whether the dial errors of dialer reliably reflect the reachability of the destination,
+// as declared with [ConnectFailureReporter]. Dialers that don't implement [ConnectFailureReporter] are assumed to
+// report the failures.
+func ReportsConnectFailure(dialer any) bool {
+	if reporter, ok := dialer.(ConnectFailureReporter); ok {
+		return reporter.ReportsConnectFailure()
+	}
+	return true
+}
@@ -15,6 +15,7 @@
 package transport
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
@@ -24,6 +25,7 @@ import (
 	"syscall"
 	"testing"
 	"testing/iotest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -56,6 +58,21 @@ func TestFuncStreamDialer(t *testing.T) {
 	require.Equal(t, expectedErr, err)
 }
 
+// noConnectFailureDialer is a [StreamDialer] that declares it doesn't report connect failures.
+type noConnectFailureDialer struct {
+	FuncStreamDialer
+}
+
+func (d noConnectFailureDialer) ReportsConnectFailure() bool {
+	return false
+}
+
+func TestReportsConnectFailure(t *testing.T) {
+	require.True(t, ReportsConnectFailure(&TCPDialer{}))
+	require.True(t, ReportsConnectFailure(FuncStreamDialer(nil)))
+	require.False(t, ReportsConnectFailure(noConnectFailureDialer{}))
+}
+
 func TestNewTCPStreamDialerIPv4(t *testing.T) {
 	requestText := []byte("Request")
 	responseText := []byte("Response")
@@ -185,3 +202,34 @@ func Test_duplexConnAdaptor_PreferReadFrom(t *testing.T) {
 	require.Equal(t, 0, w.writeCalls)
 	require.Equal(t, int64(0), n)
 }
+
+func Test_duplexConnAdaptor_HalfClose(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.AcceptTCP()
+		require.NoError(t, err)
+		defer conn.Close()
+		// The client data ends with EOF, but we can still write the response.
+		request, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		_, err = conn.Write(append([]byte("response to "), request...))
+		require.NoError(t, err)
+	}()
+
+	baseConn, err := (&TCPDialer{}).DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	conn := WrapConn(baseConn, baseConn, bufio.NewWriter(baseConn))
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Write([]byte("request"))
+	require.NoError(t, err)
+	// CloseWrite must flush the buffered request before the EOF.
+	require.NoError(t, conn.CloseWrite())
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "response to request", string(response))
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package transport
+
+import (
+	"net"
+	"syscall"
+)
+
+// setKeepAliveConfig sets the keepalive options of the socket, skipping the zero fields.
+func setKeepAliveConfig(conn *net.TCPConn, config *TCPKeepAliveConfig) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if config.Idle > 0 {
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, keepAliveSeconds(config.Idle)); sockErr != nil {
+				return
+			}
+		}
+		if config.Interval > 0 {
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, keepAliveSeconds(config.Interval)); sockErr != nil {
+				return
+			}
+		}
+		if config.Count > 0 {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, config.Count)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
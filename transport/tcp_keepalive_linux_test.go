@@ -0,0 +1,69 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func getTCPOption(t *testing.T, conn *net.TCPConn, option int) int {
+	rawConn, err := conn.SyscallConn()
+	require.NoError(t, err)
+	var value int
+	var sockErr error
+	require.NoError(t, rawConn.Control(func(fd uintptr) {
+		value, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, option)
+	}))
+	require.NoError(t, sockErr)
+	return value
+}
+
+func TestTCPDialer_Options(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	dialer := &TCPDialer{
+		KeepAlive:      &TCPKeepAliveConfig{Idle: 30 * time.Second, Interval: 1500 * time.Millisecond, Count: 4},
+		DisableNoDelay: true,
+	}
+	conn, err := dialer.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	tcpConn := conn.(*net.TCPConn)
+	require.Equal(t, 0, getTCPOption(t, tcpConn, syscall.TCP_NODELAY))
+	require.Equal(t, 30, getTCPOption(t, tcpConn, syscall.TCP_KEEPIDLE))
+	require.Equal(t, 2, getTCPOption(t, tcpConn, syscall.TCP_KEEPINTVL))
+	require.Equal(t, 4, getTCPOption(t, tcpConn, syscall.TCP_KEEPCNT))
+}
+
+func TestTCPDialer_DefaultOptions(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	conn, err := (&TCPDialer{}).DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, 1, getTCPOption(t, conn.(*net.TCPConn), syscall.TCP_NODELAY))
+}
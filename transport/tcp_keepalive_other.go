@@ -0,0 +1,34 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin
+
+package transport
+
+import (
+	"errors"
+	"net"
+)
+
+// setKeepAliveConfig sets the idle time of the keepalive probes. The standard library also uses it as the
+// interval, and doesn't support setting the interval or the count separately on this platform.
+func setKeepAliveConfig(conn *net.TCPConn, config *TCPKeepAliveConfig) error {
+	if config.Interval > 0 || config.Count > 0 {
+		return errors.New("keepalive interval and count are not supported on this platform")
+	}
+	if config.Idle > 0 {
+		return conn.SetKeepAlivePeriod(config.Idle)
+	}
+	return nil
+}
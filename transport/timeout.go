@@ -0,0 +1,128 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// ErrDialTimeout is returned by the dialers created with [NewTimeoutStreamDialer] and [NewTimeoutPacketDialer] when
+// the dial doesn't complete within the timeout.
+var ErrDialTimeout = errors.New("dial timed out")
+
+// timeoutStreamDialer is a [StreamDialer] that bounds the dial duration.
+type timeoutStreamDialer struct {
+	dialer  StreamDialer
+	timeout time.Duration
+}
+
+var _ StreamDialer = (*timeoutStreamDialer)(nil)
+var _ ConnectFailureReporter = (*timeoutStreamDialer)(nil)
+
+// NewTimeoutStreamDialer creates a [StreamDialer] that fails with an error wrapping [ErrDialTimeout] if the base dialer
+// doesn't connect within timeout, even if the caller's context has no deadline. If the context has an earlier
+// deadline, that deadline applies instead and the context error is returned.
+//
+// The timeout is enforced even if the base dialer ignores the context. In that case, the connection established after
+// the timeout is closed.
+func NewTimeoutStreamDialer(dialer StreamDialer, timeout time.Duration) (StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &timeoutStreamDialer{dialer: dialer, timeout: timeout}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *timeoutStreamDialer) DialStream(ctx context.Context, addr string) (StreamConn, error) {
+	return dialWithTimeout(ctx, d.timeout, func(dialCtx context.Context) (StreamConn, error) {
+		return d.dialer.DialStream(dialCtx, addr)
+	})
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter] with the answer of the base dialer.
+func (d *timeoutStreamDialer) ReportsConnectFailure() bool {
+	return ReportsConnectFailure(d.dialer)
+}
+
+// timeoutPacketDialer is a [PacketDialer] that bounds the dial duration.
+type timeoutPacketDialer struct {
+	dialer  PacketDialer
+	timeout time.Duration
+}
+
+var _ PacketDialer = (*timeoutPacketDialer)(nil)
+
+// NewTimeoutPacketDialer is like [NewTimeoutStreamDialer], but for a [PacketDialer].
+func NewTimeoutPacketDialer(dialer PacketDialer, timeout time.Duration) (PacketDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &timeoutPacketDialer{dialer: dialer, timeout: timeout}, nil
+}
+
+// DialPacket implements [PacketDialer].DialPacket.
+func (d *timeoutPacketDialer) DialPacket(ctx context.Context, addr string) (net.Conn, error) {
+	return dialWithTimeout(ctx, d.timeout, func(dialCtx context.Context) (net.Conn, error) {
+		return d.dialer.DialPacket(dialCtx, addr)
+	})
+}
+
+// dialWithTimeout runs dial with a context that expires after timeout, and returns when dial returns or the context is
+// done, whichever happens first.
+func dialWithTimeout[C io.Closer](ctx context.Context, timeout time.Duration, dial func(context.Context) (C, error)) (C, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		conn C
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		conn, err := dial(dialCtx)
+		resultCh <- result{conn, err}
+	}()
+
+	var zero C
+	select {
+	case r := <-resultCh:
+		if r.err != nil && ctx.Err() == nil && errors.Is(dialCtx.Err(), context.DeadlineExceeded) {
+			return zero, fmt.Errorf("%w after %v: %w", ErrDialTimeout, timeout, r.err)
+		}
+		return r.conn, r.err
+	case <-dialCtx.Done():
+		// The base dialer didn't return in time. Close the connection if it's eventually established.
+		go func() {
+			if r := <-resultCh; r.err == nil {
+				r.conn.Close()
+			}
+		}()
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, fmt.Errorf("%w after %v", ErrDialTimeout, timeout)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutStreamDialerSuccess(t *testing.T) {
+	var dialDeadline time.Time
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		dialDeadline, _ = ctx.Deadline()
+		return &closeNotifyConn{closed: make(chan struct{})}, nil
+	})
+	d, err := NewTimeoutStreamDialer(base, time.Minute)
+	require.NoError(t, err)
+	conn, err := d.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+	conn.Close()
+	require.WithinDuration(t, time.Now().Add(time.Minute), dialDeadline, 5*time.Second)
+}
+
+func TestTimeoutStreamDialerTimeout(t *testing.T) {
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	d, err := NewTimeoutStreamDialer(base, 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = d.DialStream(context.Background(), "example.com:443")
+	require.ErrorIs(t, err, ErrDialTimeout)
+}
+
+func TestTimeoutStreamDialerIgnoredContext(t *testing.T) {
+	closed := make(chan struct{})
+	release := make(chan struct{})
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		// Ignores the context.
+		<-release
+		return &closeNotifyConn{closed: closed}, nil
+	})
+	d, err := NewTimeoutStreamDialer(base, 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = d.DialStream(context.Background(), "example.com:443")
+	require.ErrorIs(t, err, ErrDialTimeout)
+
+	// The late connection is closed.
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("late connection was not closed")
+	}
+}
+
+func TestTimeoutStreamDialerShorterContextDeadline(t *testing.T) {
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	d, err := NewTimeoutStreamDialer(base, time.Minute)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = d.DialStream(ctx, "example.com:443")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotErrorIs(t, err, ErrDialTimeout)
+}
+
+func TestTimeoutStreamDialerBaseError(t *testing.T) {
+	dialErr := errors.New("dial failed")
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		return nil, dialErr
+	})
+	d, err := NewTimeoutStreamDialer(base, time.Minute)
+	require.NoError(t, err)
+	_, err = d.DialStream(context.Background(), "example.com:443")
+	require.Equal(t, dialErr, err)
+}
+
+func TestTimeoutPacketDialer(t *testing.T) {
+	base := FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	d, err := NewTimeoutPacketDialer(base, 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = d.DialPacket(context.Background(), "8.8.8.8:53")
+	require.ErrorIs(t, err, ErrDialTimeout)
+}
+
+func TestNewTimeoutDialerInvalidArguments(t *testing.T) {
+	_, err := NewTimeoutStreamDialer(nil, time.Second)
+	require.Error(t, err)
+	_, err = NewTimeoutStreamDialer(&TCPDialer{}, 0)
+	require.Error(t, err)
+	_, err = NewTimeoutPacketDialer(nil, time.Second)
+	require.Error(t, err)
+	_, err = NewTimeoutPacketDialer(&UDPDialer{}, -time.Second)
+	require.Error(t, err)
+}
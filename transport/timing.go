@@ -0,0 +1,114 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// timingNormalizingDialer is a [StreamDialer] that delays the first write of each connection.
+// Use [NewTimingNormalizingDialer] to create new instances.
+type timingNormalizingDialer struct {
+	dialer   StreamDialer
+	target   time.Duration
+	now      func() time.Time
+	newTimer func(time.Duration) *time.Timer
+}
+
+var _ StreamDialer = (*timingNormalizingDialer)(nil)
+var _ ConnectFailureReporter = (*timingNormalizingDialer)(nil)
+
+// NewTimingNormalizingDialer creates a [StreamDialer] that normalizes the time between the start of the dial and the
+// first write on the connection to target, to defeat fingerprinting based on how long the handshake takes.
+// The first write is delayed until target has elapsed since DialStream was called. If that time has already elapsed,
+// the write goes through immediately. Closing the connection interrupts the delay, and the write returns
+// [net.ErrClosed].
+//
+// This adds latency to every connection, and is best-effort: it can't account for delays in the network stack,
+// and it doesn't hide handshakes that take longer than target.
+func NewTimingNormalizingDialer(dialer StreamDialer, target time.Duration) (StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if target < 0 {
+		return nil, errors.New("argument target must not be negative")
+	}
+	return &timingNormalizingDialer{dialer: dialer, target: target, now: time.Now, newTimer: time.NewTimer}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *timingNormalizingDialer) DialStream(ctx context.Context, raddr string) (StreamConn, error) {
+	firstWriteTime := d.now().Add(d.target)
+	conn, err := d.dialer.DialStream(ctx, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return &delayedWriteConn{
+		StreamConn:     conn,
+		firstWriteTime: firstWriteTime,
+		now:            d.now,
+		newTimer:       d.newTimer,
+		closed:         make(chan struct{}),
+	}, nil
+}
+
+// ReportsConnectFailure implements [ConnectFailureReporter] with the answer of the base dialer.
+func (d *timingNormalizingDialer) ReportsConnectFailure() bool {
+	return ReportsConnectFailure(d.dialer)
+}
+
+// delayedWriteConn is a [StreamConn] that waits until firstWriteTime before its first write, or until it's closed.
+type delayedWriteConn struct {
+	StreamConn
+	firstWriteTime time.Time
+	now            func() time.Time
+	newTimer       func(time.Duration) *time.Timer
+	writeOnce      sync.Once
+	closeOnce      sync.Once
+	closed         chan struct{}
+}
+
+var _ StreamConn = (*delayedWriteConn)(nil)
+
+func (c *delayedWriteConn) Write(b []byte) (int, error) {
+	var err error
+	c.writeOnce.Do(func() {
+		delay := c.firstWriteTime.Sub(c.now())
+		if delay <= 0 {
+			return
+		}
+		timer := c.newTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-c.closed:
+			err = net.ErrClosed
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return c.StreamConn.Write(b)
+}
+
+// Close closes the connection, interrupting the wait for the first write.
+func (c *delayedWriteConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.StreamConn.Close()
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeRecordingConn is a [StreamConn] that records the time of each write.
+type writeRecordingConn struct {
+	fakeConn
+	now        func() time.Time
+	writeTimes []time.Time
+}
+
+func (c *writeRecordingConn) Write(b []byte) (int, error) {
+	c.writeTimes = append(c.writeTimes, c.now())
+	return len(b), nil
+}
+
+// fakeClock is a clock that only advances when a timer is created or time is added.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTimer advances the clock by d and returns a timer that fires right away.
+func (c *fakeClock) newTimer(d time.Duration) *time.Timer {
+	c.advance(d)
+	return time.NewTimer(0)
+}
+
+func newTestTimingDialer(t *testing.T, target, handshakeTime time.Duration) (StreamDialer, *fakeClock, *writeRecordingConn) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	conn := &writeRecordingConn{now: clock.now}
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		clock.advance(handshakeTime)
+		return conn, nil
+	})
+	sd, err := NewTimingNormalizingDialer(base, target)
+	require.NoError(t, err)
+	dialer := sd.(*timingNormalizingDialer)
+	dialer.now = clock.now
+	dialer.newTimer = clock.newTimer
+	return dialer, clock, conn
+}
+
+func TestTimingNormalizingDialer_DelaysFirstWrite(t *testing.T) {
+	dialer, clock, recorder := newTestTimingDialer(t, 500*time.Millisecond, 100*time.Millisecond)
+	start := clock.now()
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("first"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("second"))
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{start.Add(500 * time.Millisecond), start.Add(500 * time.Millisecond)}, recorder.writeTimes)
+}
+
+func TestTimingNormalizingDialer_SlowHandshake(t *testing.T) {
+	dialer, clock, recorder := newTestTimingDialer(t, 500*time.Millisecond, 800*time.Millisecond)
+	start := clock.now()
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("first"))
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{start.Add(800 * time.Millisecond)}, recorder.writeTimes)
+}
+
+func TestTimingNormalizingDialer_CloseInterruptsDelay(t *testing.T) {
+	base := FuncStreamDialer(func(ctx context.Context, addr string) (StreamConn, error) {
+		return &writeRecordingConn{fakeConn: fakeConn{&closeNotifyConn{closed: make(chan struct{})}}, now: time.Now}, nil
+	})
+	dialer, err := NewTimingNormalizingDialer(base, time.Hour)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+
+	writeErr := make(chan error)
+	go func() {
+		_, err := conn.Write([]byte("first"))
+		writeErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, conn.Close())
+	select {
+	case err := <-writeErr:
+		require.ErrorIs(t, err, net.ErrClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write didn't return after Close")
+	}
+}
+
+func TestNewTimingNormalizingDialer_InvalidArguments(t *testing.T) {
+	_, err := NewTimingNormalizingDialer(nil, time.Second)
+	require.Error(t, err)
+	_, err = NewTimingNormalizingDialer(&TCPDialer{}, -time.Second)
+	require.Error(t, err)
+}
+
+// closeNotifyConn is a [StreamConn] that closes the channel when closed.
+type closeNotifyConn struct {
+	StreamConn
+	closed chan struct{}
+}
+
+func (c *closeNotifyConn) Close() error {
+	close(c.closed)
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package tls
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+func setECHConfigList(config *tls.Config, echConfigList []byte) error {
+	config.EncryptedClientHelloConfigList = echConfigList
+	return nil
+}
+
+// echRetryConfigList returns the retry_configs from the server if err is an ECH rejection that carries them.
+func echRetryConfigList(err error) ([]byte, bool) {
+	var echErr *tls.ECHRejectionError
+	if errors.As(err, &echErr) && len(echErr.RetryConfigList) > 0 {
+		return echErr.RetryConfigList, true
+	}
+	return nil, false
+}
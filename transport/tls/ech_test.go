@@ -0,0 +1,185 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Server-side ECH requires Go 1.24.
+//go:build go1.24
+
+package tls
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingConn records the bytes read from the connection.
+type recordingConn struct {
+	net.Conn
+	received bytes.Buffer
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.received.Write(b[:n])
+	return n, err
+}
+
+// newECHConfig returns a serialized ECHConfig for DHKEM(X25519, HKDF-SHA256), HKDF-SHA256 and AES-128-GCM.
+// See https://datatracker.ietf.org/doc/html/draft-ietf-tls-esni-18#section-4.
+func newECHConfig(t *testing.T, publicKey []byte, publicName string) []byte {
+	var contents []byte
+	contents = append(contents, 1)                             // config_id
+	contents = binary.BigEndian.AppendUint16(contents, 0x0020) // kem_id
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(publicKey)))
+	contents = append(contents, publicKey...)
+	contents = binary.BigEndian.AppendUint16(contents, 4)      // cipher_suites length
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001) // kdf_id
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001) // aead_id
+	contents = append(contents, 0)                             // maximum_name_length
+	contents = append(contents, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	contents = binary.BigEndian.AppendUint16(contents, 0) // extensions
+	config := binary.BigEndian.AppendUint16(nil, 0xfe0d)  // version
+	config = binary.BigEndian.AppendUint16(config, uint16(len(contents)))
+	return append(config, contents...)
+}
+
+func TestWithECHConfigList(t *testing.T) {
+	echKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	echConfig := newECHConfig(t, echKey.PublicKey().Bytes(), "public.example")
+	echConfigList := binary.BigEndian.AppendUint16(nil, uint16(len(echConfig)))
+	echConfigList = append(echConfigList, echConfig...)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type serverResult struct {
+		serverName string
+		received   []byte
+	}
+	resultCh := make(chan serverResult, 1)
+	go func() {
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		recorder := &recordingConn{Conn: conn}
+		var serverName string
+		serverConn := tls.Server(recorder, &tls.Config{
+			Certificates: []tls.Certificate{newSelfSignedCert(t, "secret.example", "public.example")},
+			EncryptedClientHelloKeys: []tls.EncryptedClientHelloKey{{
+				Config:     echConfig,
+				PrivateKey: echKey.Bytes(),
+			}},
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				serverName = hello.ServerName
+				return nil, nil
+			},
+		})
+		// The client will reject the self-signed certificate, so we ignore the handshake error.
+		serverConn.Handshake()
+		resultCh <- serverResult{serverName, recorder.received.Bytes()}
+	}()
+
+	sd, err := NewStreamDialer(&transport.TCPDialer{}, WithSNI("secret.example"), WithCertificateName("secret.example"), WithECHConfigList(echConfigList))
+	require.NoError(t, err)
+	_, err = sd.DialStream(context.Background(), listener.Addr().String())
+	var certErr x509.UnknownAuthorityError
+	require.ErrorAs(t, err, &certErr)
+
+	result := <-resultCh
+	// The server decrypted the inner Client Hello.
+	require.Equal(t, "secret.example", result.serverName)
+	// The real server name was not sent in the clear.
+	require.NotContains(t, string(result.received), "secret.example")
+	require.Contains(t, string(result.received), "public.example")
+}
+
+func TestECHRetry(t *testing.T) {
+	newConfigList := func(publicName string) ([]byte, *ecdh.PrivateKey) {
+		echKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		echConfig := newECHConfig(t, echKey.PublicKey().Bytes(), publicName)
+		echConfigList := binary.BigEndian.AppendUint16(nil, uint16(len(echConfig)))
+		return append(echConfigList, echConfig...), echKey
+	}
+	// The client has a stale config that the server no longer has the key for.
+	staleConfigList, _ := newConfigList("public.example")
+	currentConfigList, currentKey := newConfigList("public.example")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	cert := newSelfSignedCert(t, "secret.example", "public.example")
+	serverNamesCh := make(chan string, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			serverConn := tls.Server(conn, &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				EncryptedClientHelloKeys: []tls.EncryptedClientHelloKey{{
+					// Skip the 2-byte length of the list to get the config.
+					Config:      currentConfigList[2:],
+					PrivateKey:  currentKey.Bytes(),
+					SendAsRetry: true,
+				}},
+			})
+			if serverConn.Handshake() == nil {
+				serverNamesCh <- serverConn.ConnectionState().ServerName
+			} else {
+				serverNamesCh <- ""
+			}
+			serverConn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots.AddCert(leaf)
+	var retryConfigLists [][]byte
+	sd, err := NewStreamDialer(&transport.TCPDialer{},
+		WithSNI("secret.example"),
+		WithCertificateName("secret.example"),
+		WithECHConfigList(staleConfigList),
+		WithECHRetryCallback(func(retryConfigList []byte) {
+			retryConfigLists = append(retryConfigLists, retryConfigList)
+		}),
+		WithRootCAs(roots),
+	)
+	require.NoError(t, err)
+	conn, err := sd.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, [][]byte{currentConfigList}, retryConfigLists)
+	require.True(t, conn.(streamConn).ConnectionState().ECHAccepted)
+	// The server could not decrypt the first inner Client Hello, and decrypted the one in the retry.
+	require.NotEqual(t, "secret.example", <-serverNamesCh)
+	require.Equal(t, "secret.example", <-serverNamesCh)
+}
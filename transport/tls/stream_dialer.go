@@ -21,7 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strings"
+	"sync/atomic"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 )
@@ -35,23 +37,61 @@ type StreamDialer struct {
 }
 
 var _ transport.StreamDialer = (*StreamDialer)(nil)
+var _ transport.ConnectFailureReporter = (*StreamDialer)(nil)
 
 // NewStreamDialer creates a [StreamDialer] that wraps the connections from the baseDialer with TLS
 // configured with the given options.
+//
+// The connections of the dialer share a session cache, so that they can resume previous sessions with abbreviated
+// handshakes. Use [WithSessionCache] to use your own cache, or [WithSessionResumption] to disable resumption.
 func NewStreamDialer(baseDialer transport.StreamDialer, options ...ClientOption) (*StreamDialer, error) {
 	if baseDialer == nil {
 		return nil, errors.New("base dialer must not be nil")
 	}
+	// The user options go last, so they can override the default cache.
+	options = append([]ClientOption{WithSessionCache(tls.NewLRUClientSessionCache(0))}, options...)
 	return &StreamDialer{baseDialer, options}, nil
 }
 
+// TruncationReporter is implemented by the connections returned by [StreamDialer] and [WrapConn].
+//
+// A TLS peer must send a close_notify alert before closing the connection. Without it, an attacker or a middlebox
+// that injects a TCP FIN or RST can cut the stream short, and the application can't tell the data is incomplete.
+// This is known as a truncation attack. Like most TLS stacks, Go's [tls.Conn] returns [io.EOF] in both cases, because
+// many servers don't send close_notify, so use Truncated to tell them apart. Measurement tools can use it to flag
+// streams that were likely reset by DPI.
+type TruncationReporter interface {
+	// Truncated reports whether the underlying connection ended without a close_notify alert, because the peer
+	// closed it or because it failed, for example with a TCP RST. It returns false while the stream is still open,
+	// and after a clean close.
+	Truncated() bool
+}
+
+// ALPNReporter is implemented by the connections returned by [StreamDialer] and [WrapConn].
+//
+// Use it to check which application protocol the server picked from the list set with [WithALPN], for example to
+// speak HTTP/2 only if the server selected "h2".
+type ALPNReporter interface {
+	// NegotiatedProtocol returns the application protocol negotiated with ALPN, or the empty string if the
+	// client didn't offer any protocol or the server didn't select one.
+	NegotiatedProtocol() string
+}
+
 // streamConn wraps a [tls.Conn] to provide a [transport.StreamConn] interface.
 type streamConn struct {
 	*tls.Conn
 	innerConn transport.StreamConn
+	endConn   *endConn
 }
 
 var _ transport.StreamConn = (*streamConn)(nil)
+var _ TruncationReporter = (*streamConn)(nil)
+var _ ALPNReporter = (*streamConn)(nil)
+
+func newStreamConn(conn transport.StreamConn, config *tls.Config) streamConn {
+	endConn := &endConn{StreamConn: conn}
+	return streamConn{tls.Client(endConn, config), conn, endConn}
+}
 
 func (c streamConn) CloseWrite() error {
 	tlsErr := c.Conn.CloseWrite()
@@ -62,8 +102,46 @@ func (c streamConn) CloseRead() error {
 	return c.innerConn.CloseRead()
 }
 
+// Truncated implements [TruncationReporter].
+// The [tls.Conn] stops reading from the underlying connection once it gets a close_notify, so the underlying
+// connection only ends if the stream was cut without close_notify.
+func (c streamConn) Truncated() bool {
+	return c.endConn.ended.Load()
+}
+
+// NegotiatedProtocol implements [ALPNReporter].
+func (c streamConn) NegotiatedProtocol() string {
+	return c.Conn.ConnectionState().NegotiatedProtocol
+}
+
+// endConn records whether the stream of the wrapped connection has ended, with EOF or with a read error.
+// Timeouts and reads after a local Close don't end the stream.
+type endConn struct {
+	transport.StreamConn
+	ended atomic.Bool
+}
+
+func (c *endConn) Read(b []byte) (int, error) {
+	n, err := c.StreamConn.Read(b)
+	if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, net.ErrClosed) {
+		c.ended.Store(true)
+	}
+	return n, err
+}
+
 // DialStream implements [transport.StreamDialer].DialStream.
 func (d *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	return transport.TraceDialStream(ctx, "tls", remoteAddr, func() (transport.StreamConn, error) {
+		return d.dialStream(ctx, remoteAddr)
+	})
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *StreamDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+func (d *StreamDialer) dialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
 	host, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid address: %w", err)
@@ -75,7 +153,26 @@ func (d *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (trans
 	conn, err := WrapConn(ctx, innerConn, host, d.options...)
 	if err != nil {
 		innerConn.Close()
-		return nil, err
+		retryConfigList, ok := echRetryConfigList(err)
+		if !ok {
+			return nil, err
+		}
+		// The server rejected ECH and sent the configs to use instead. Retry once on a fresh connection, so that a
+		// server that keeps rejecting its own retry configs can't make us loop.
+		cfg := newClientConfig(host, d.options)
+		if cfg.ECHRetryCallback != nil {
+			cfg.ECHRetryCallback(retryConfigList)
+		}
+		innerConn, err = d.dialer.DialStream(ctx, remoteAddr)
+		if err != nil {
+			return nil, err
+		}
+		retryOptions := append(append([]ClientOption{}, d.options...), WithECHConfigList(retryConfigList))
+		conn, err = WrapConn(ctx, innerConn, host, retryOptions...)
+		if err != nil {
+			innerConn.Close()
+			return nil, err
+		}
 	}
 	return conn, nil
 }
@@ -92,16 +189,55 @@ type ClientConfig struct {
 	CertificateName string
 	// The protocol id list for protocol negotiation (ALPN).
 	NextProtos []string
-	// The cache for sessin resumption.
+	// The cache for session resumption.
 	SessionCache tls.ClientSessionCache
+	// Whether to disable session resumption. It also disables the session ticket extension.
+	SessionResumptionDisabled bool
+	// The serialized ECHConfigList for Encrypted Client Hello (ECH). If empty, ECH is not used.
+	ECHConfigList []byte
+	// The minimum and maximum TLS versions (e.g. [tls.VersionTLS12]). Zero means the Go default.
+	MinVersion uint16
+	MaxVersion uint16
+	// The TLS 1.0-1.2 cipher suites to offer. If nil, the Go default is used.
+	CipherSuites []uint16
+	// The elliptic curves for the key exchange, in preference order. If nil, the Go default is used.
+	CurvePreferences []tls.CurveID
+	// Whether to disable the session ticket extension.
+	SessionTicketsDisabled bool
+	// The function to call when the server rejects ECH and the [StreamDialer] retries with the server's retry_configs.
+	ECHRetryCallback func(retryConfigList []byte)
+	// The root certificates to validate the server certificate against. If nil, the system roots are used.
+	RootCAs *x509.CertPool
+}
+
+// newClientConfig returns the [ClientConfig] for the server name, after applying the options.
+func newClientConfig(serverName string, options []ClientOption) ClientConfig {
+	cfg := ClientConfig{ServerName: serverName, CertificateName: serverName}
+	normName := normalizeHost(serverName)
+	for _, option := range options {
+		option(normName, &cfg)
+	}
+	return cfg
 }
 
 // toStdConfig creates a [tls.Config] based on the configured parameters.
 func (cfg *ClientConfig) toStdConfig() *tls.Config {
+	sessionCache := cfg.SessionCache
+	if cfg.SessionResumptionDisabled {
+		sessionCache = nil
+	}
 	return &tls.Config{
 		ServerName:         cfg.ServerName,
 		NextProtos:         cfg.NextProtos,
-		ClientSessionCache: cfg.SessionCache,
+		ClientSessionCache: sessionCache,
+		MinVersion:         cfg.MinVersion,
+		MaxVersion:         cfg.MaxVersion,
+		CipherSuites:       cfg.CipherSuites,
+		CurvePreferences:   cfg.CurvePreferences,
+		// RootCAs is used to validate the certificate for the public name when the server rejects ECH.
+		RootCAs: cfg.RootCAs,
+		// Set SessionTicketsDisabled to not send the session ticket extension.
+		SessionTicketsDisabled: cfg.SessionTicketsDisabled || cfg.SessionResumptionDisabled,
 		// Set InsecureSkipVerify to skip the default validation we are
 		// replacing. This will not disable VerifyConnection.
 		InsecureSkipVerify: true,
@@ -112,6 +248,7 @@ func (cfg *ClientConfig) toStdConfig() *tls.Config {
 			// https://pkg.go.dev/crypto/tls#example-Config-VerifyConnection
 			opts := x509.VerifyOptions{
 				DNSName:       cfg.CertificateName,
+				Roots:         cfg.RootCAs,
 				Intermediates: x509.NewCertPool(),
 			}
 			for _, cert := range cs.PeerCertificates[1:] {
@@ -127,18 +264,21 @@ func (cfg *ClientConfig) toStdConfig() *tls.Config {
 type ClientOption func(serverName string, config *ClientConfig)
 
 // WrapConn wraps a [transport.StreamConn] in a TLS connection.
+// The returned connection implements [TruncationReporter].
 func WrapConn(ctx context.Context, conn transport.StreamConn, serverName string, options ...ClientOption) (transport.StreamConn, error) {
-	cfg := ClientConfig{ServerName: serverName, CertificateName: serverName}
-	normName := normalizeHost(serverName)
-	for _, option := range options {
-		option(normName, &cfg)
+	cfg := newClientConfig(serverName, options)
+	stdConfig := cfg.toStdConfig()
+	if len(cfg.ECHConfigList) > 0 {
+		if err := setECHConfigList(stdConfig, cfg.ECHConfigList); err != nil {
+			return nil, err
+		}
 	}
-	tlsConn := tls.Client(conn, cfg.toStdConfig())
+	tlsConn := newStreamConn(conn, stdConfig)
 	err := tlsConn.HandshakeContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return streamConn{tlsConn, conn}, nil
+	return tlsConn, nil
 }
 
 // WithSNI sets the host name for [Server Name Indication] (SNI).
@@ -165,6 +305,7 @@ func IfHost(matchHost string, option ClientOption) ClientOption {
 
 // WithALPN sets the protocol name list for [Application-Layer Protocol Negotiation] (ALPN).
 // The list of protocol IDs can be found in [IANA's registry].
+// Use [ALPNReporter] to get the protocol selected by the server.
 //
 // [Application-Layer Protocol Negotiation]: https://datatracker.ietf.org/doc/html/rfc7301
 // [IANA's registry]: https://www.iana.org/assignments/tls-extensiontype-values/tls-extensiontype-values.xhtml#alpn-protocol-ids
@@ -175,12 +316,89 @@ func WithALPN(protocolNameList []string) ClientOption {
 }
 
 // WithSessionCache sets the [tls.ClientSessionCache] to enable session resumption of TLS connections.
+// Share the cache between dialers to resume the sessions across them. Sessions are cached by the SNI.
+//
+// Resumption works with ECH: the session ticket is only sent in the encrypted inner Client Hello, so it doesn't reveal
+// the server to the network. Note that Go doesn't support sending early data (0-RTT) over TLS, so resumed connections
+// still take one round trip before the data is sent.
 func WithSessionCache(sessionCache tls.ClientSessionCache) ClientOption {
 	return func(_ string, config *ClientConfig) {
 		config.SessionCache = sessionCache
 	}
 }
 
+// WithSessionResumption enables or disables session resumption. Resumption is enabled by default in the [StreamDialer].
+// Disabling it also disables the session ticket extension, like [WithSessionTicketsDisabled], so that the Client Hello
+// doesn't reveal whether a session is being resumed. That's useful if the Client Hello must match a fingerprint.
+func WithSessionResumption(enabled bool) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.SessionResumptionDisabled = !enabled
+	}
+}
+
+// WithECHConfigList enables [Encrypted Client Hello] (ECH) with the given serialized ECHConfigList, which is
+// typically found in the "ech" parameter of the domain's HTTPS DNS record.
+// With ECH, the real server name is encrypted, and the SNI in the clear is the public name from the ECH config.
+// Note that the SNI set with [WithSNI] is the one that gets encrypted.
+//
+// ECH requires TLS 1.3 and Go 1.23 or later. If the server rejects ECH, the handshake fails with a
+// [tls.ECHRejectionError], which carries the server's RetryConfigList, if any, that you can use to try again.
+// The [StreamDialer] does that for you: it retries once on a new connection with the RetryConfigList, and returns the
+// error if the retry also fails. Use [WithECHRetryCallback] to learn about the retries.
+//
+// [Encrypted Client Hello]: https://datatracker.ietf.org/doc/draft-ietf-tls-esni/
+func WithECHConfigList(echConfigList []byte) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.ECHConfigList = echConfigList
+	}
+}
+
+// WithECHRetryCallback sets a function that the [StreamDialer] calls with the server's retry_configs when the server
+// rejects ECH and the dialer retries the handshake. This is useful for measurements.
+func WithECHRetryCallback(callback func(retryConfigList []byte)) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.ECHRetryCallback = callback
+	}
+}
+
+// WithMinVersion sets the minimum TLS version to accept, such as [tls.VersionTLS12].
+// Together with [WithMaxVersion], this changes the versions advertised in the Client Hello.
+func WithMinVersion(version uint16) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.MinVersion = version
+	}
+}
+
+// WithMaxVersion sets the maximum TLS version to offer, such as [tls.VersionTLS12].
+func WithMaxVersion(version uint16) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.MaxVersion = version
+	}
+}
+
+// WithCipherSuites sets the TLS 1.0-1.2 cipher suites to offer in the Client Hello.
+// Note that Go orders the cipher suites itself, ignoring the order of the list, and that
+// TLS 1.3 cipher suites are not configurable.
+func WithCipherSuites(cipherSuites []uint16) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.CipherSuites = cipherSuites
+	}
+}
+
+// WithCurvePreferences sets the elliptic curves to offer for the key exchange, in preference order.
+func WithCurvePreferences(curves []tls.CurveID) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.CurvePreferences = curves
+	}
+}
+
+// WithSessionTicketsDisabled disables the session ticket extension if disabled is true.
+func WithSessionTicketsDisabled(disabled bool) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.SessionTicketsDisabled = disabled
+	}
+}
+
 // WithCertificateName sets the hostname to be used for the certificate cerification.
 // If absent, defaults to the dialed hostname.
 func WithCertificateName(hostname string) ClientOption {
@@ -188,3 +406,11 @@ func WithCertificateName(hostname string) ClientOption {
 		config.CertificateName = hostname
 	}
 }
+
+// WithRootCAs sets the root certificates to validate the server certificate against, instead of the system roots.
+// Use it to connect to servers with certificates issued by a private certificate authority.
+func WithRootCAs(roots *x509.CertPool) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.RootCAs = roots
+	}
+}
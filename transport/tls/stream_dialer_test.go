@@ -16,8 +16,18 @@ package tls
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/stretchr/testify/require"
@@ -140,6 +150,25 @@ func TestWithALPN(t *testing.T) {
 	require.Equal(t, []string{"h2", "http/1.1"}, cfg.NextProtos)
 }
 
+func TestFingerprintOptions(t *testing.T) {
+	var cfg ClientConfig
+	for _, option := range []ClientOption{
+		WithMinVersion(tls.VersionTLS12),
+		WithMaxVersion(tls.VersionTLS13),
+		WithCipherSuites([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}),
+		WithCurvePreferences([]tls.CurveID{tls.X25519, tls.CurveP256}),
+		WithSessionTicketsDisabled(true),
+	} {
+		option("", &cfg)
+	}
+	stdConfig := cfg.toStdConfig()
+	require.Equal(t, uint16(tls.VersionTLS12), stdConfig.MinVersion)
+	require.Equal(t, uint16(tls.VersionTLS13), stdConfig.MaxVersion)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, stdConfig.CipherSuites)
+	require.Equal(t, []tls.CurveID{tls.X25519, tls.CurveP256}, stdConfig.CurvePreferences)
+	require.True(t, stdConfig.SessionTicketsDisabled)
+}
+
 // Make sure there are no connection leakage in DialStream
 func TestDialStreamCloseInnerConnOnError(t *testing.T) {
 	inner := &connCounterDialer{base: &transport.TCPDialer{}}
@@ -151,8 +180,235 @@ func TestDialStreamCloseInnerConnOnError(t *testing.T) {
 	require.Zero(t, inner.activeConns)
 }
 
+// Make sure we can tell a clean close_notify from a truncated stream.
+func TestTruncated(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		closeConn func(serverConn *tls.Conn, conn net.Conn)
+		truncated bool
+	}{
+		{
+			name:      "close_notify",
+			closeConn: func(serverConn *tls.Conn, conn net.Conn) { serverConn.Close() },
+		},
+		{
+			name: "FIN",
+			// Close the TCP connection without close_notify.
+			closeConn: func(serverConn *tls.Conn, conn net.Conn) { conn.Close() },
+			truncated: true,
+		},
+		{
+			name: "RST",
+			// Reset the TCP connection without close_notify.
+			closeConn: func(serverConn *tls.Conn, conn net.Conn) {
+				conn.(*net.TCPConn).SetLinger(0)
+				conn.Close()
+			},
+			truncated: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			go func() {
+				conn, err := listener.Accept()
+				require.NoError(t, err)
+				serverConn := tls.Server(conn, &tls.Config{
+					Certificates: []tls.Certificate{newSelfSignedCert(t, "example.com")},
+				})
+				serverConn.Write([]byte("response"))
+				tc.closeConn(serverConn, conn)
+			}()
+
+			innerConn, err := (&transport.TCPDialer{}).DialStream(context.Background(), listener.Addr().String())
+			require.NoError(t, err)
+			cfg := ClientConfig{ServerName: "example.com"}
+			stdConfig := cfg.toStdConfig()
+			// Skip the verification of the self-signed certificate.
+			stdConfig.VerifyConnection = nil
+			conn := newStreamConn(innerConn, stdConfig)
+			defer conn.Close()
+			require.False(t, conn.Truncated())
+
+			// With a RST, the response may be lost, and the read returns an error.
+			io.ReadAll(conn)
+			require.Equal(t, tc.truncated, conn.Truncated())
+		})
+	}
+}
+
+func TestTruncated_LocalCloseAndTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	innerConn, err := (&transport.TCPDialer{}).DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	conn := &endConn{StreamConn: innerConn}
+
+	// Timeouts don't end the stream.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.False(t, conn.ended.Load())
+
+	// Neither does closing the connection ourselves.
+	require.NoError(t, conn.Close())
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, net.ErrClosed)
+	require.False(t, conn.ended.Load())
+}
+
+func TestNegotiatedProtocol(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		alpn     []string
+		expected string
+	}{
+		{name: "HTTP1", alpn: []string{"http/1.1"}, expected: "http/1.1"},
+		{name: "Preference", alpn: []string{"h2", "http/1.1"}, expected: "h2"},
+		{name: "NoALPN", alpn: nil, expected: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				serverConn := tls.Server(conn, &tls.Config{
+					Certificates: []tls.Certificate{newSelfSignedCert(t, "example.com")},
+					NextProtos:   []string{"h2", "http/1.1"},
+				})
+				defer serverConn.Close()
+				serverConn.Handshake()
+			}()
+
+			innerConn, err := (&transport.TCPDialer{}).DialStream(context.Background(), listener.Addr().String())
+			require.NoError(t, err)
+			cfg := ClientConfig{ServerName: "example.com"}
+			WithALPN(tc.alpn)("example.com", &cfg)
+			stdConfig := cfg.toStdConfig()
+			// Skip the verification of the self-signed certificate.
+			stdConfig.VerifyConnection = nil
+			conn := newStreamConn(innerConn, stdConfig)
+			defer conn.Close()
+			require.NoError(t, conn.HandshakeContext(context.Background()))
+
+			var reporter ALPNReporter = conn
+			require.Equal(t, tc.expected, reporter.NegotiatedProtocol())
+		})
+	}
+}
+
+func TestHalfClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		serverConn := tls.Server(conn, &tls.Config{
+			Certificates: []tls.Certificate{newSelfSignedCert(t, "example.com")},
+		})
+		defer serverConn.Close()
+		// The close_notify from CloseWrite ends the request, but the connection stays open for the response.
+		request, err := io.ReadAll(serverConn)
+		require.NoError(t, err)
+		serverConn.Write(append([]byte("response to "), request...))
+	}()
+
+	innerConn, err := (&transport.TCPDialer{}).DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	cfg := ClientConfig{ServerName: "example.com"}
+	stdConfig := cfg.toStdConfig()
+	// Skip the verification of the self-signed certificate.
+	stdConfig.VerifyConnection = nil
+	conn := newStreamConn(innerConn, stdConfig)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("request"))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "response to request", string(response))
+}
+
+func TestSessionResumption(t *testing.T) {
+	cert := newSelfSignedCert(t, "example.com")
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	// The server config must be shared, so that the server can decrypt the session tickets it issued.
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			serverConn := tls.Server(conn, serverConfig)
+			// The client gets the session ticket after the handshake, when it reads.
+			serverConn.Write([]byte("response"))
+			serverConn.Close()
+		}
+	}()
+
+	dialTwice := func(options ...ClientOption) []bool {
+		options = append([]ClientOption{WithSNI("example.com"), WithCertificateName("example.com"), WithRootCAs(roots)}, options...)
+		sd, err := NewStreamDialer(&transport.TCPDialer{}, options...)
+		require.NoError(t, err)
+		var resumed []bool
+		for i := 0; i < 2; i++ {
+			conn, err := sd.DialStream(context.Background(), listener.Addr().String())
+			require.NoError(t, err)
+			_, err = io.ReadAll(conn)
+			require.NoError(t, err)
+			resumed = append(resumed, conn.(streamConn).ConnectionState().DidResume)
+			conn.Close()
+		}
+		return resumed
+	}
+
+	require.Equal(t, []bool{false, true}, dialTwice())
+	require.Equal(t, []bool{false, false}, dialTwice(WithSessionResumption(false)))
+	// Resumption can be enabled again after it's disabled.
+	require.Equal(t, []bool{false, true}, dialTwice(WithSessionResumption(false), WithSessionResumption(true)))
+}
+
+func TestWithSessionResumption(t *testing.T) {
+	cache := tls.NewLRUClientSessionCache(1)
+	cfg := newClientConfig("example.com", []ClientOption{WithSessionCache(cache), WithSessionResumption(false)})
+	stdConfig := cfg.toStdConfig()
+	require.Nil(t, stdConfig.ClientSessionCache)
+	require.True(t, stdConfig.SessionTicketsDisabled)
+}
+
 // Private test helpers
 
+func newSelfSignedCert(t *testing.T, names ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
 // connCounterDialer is a StreamDialer that counts the number of active StreamConns.
 type connCounterDialer struct {
 	base        transport.StreamDialer
@@ -176,3 +432,21 @@ func (c countedStreamConn) Close() error {
 	c.counter.activeConns--
 	return c.StreamConn.Close()
 }
+
+func TestStreamDialerReportsConnectFailure(t *testing.T) {
+	d, err := NewStreamDialer(&transport.TCPDialer{})
+	require.NoError(t, err)
+	require.True(t, transport.ReportsConnectFailure(d))
+
+	d, err = NewStreamDialer(unreportedFailureDialer{&transport.TCPDialer{}})
+	require.NoError(t, err)
+	require.False(t, transport.ReportsConnectFailure(d))
+}
+
+// unreportedFailureDialer is a [transport.StreamDialer] that declares its dial errors don't reflect the reachability
+// of the destination, like Shadowsocks.
+type unreportedFailureDialer struct {
+	transport.StreamDialer
+}
+
+func (d unreportedFailureDialer) ReportsConnectFailure() bool { return false }
@@ -29,6 +29,12 @@ This package offers convenient helper functions to create a TLS
     message]. It accepts a callback function that determines the split point,
     enabling advanced splitting logic such as splitting based on the SNI
     extension.
+  - [NewFixedOffsetsStreamDialer] and [NewStreamDialerMultiFunc] split the
+    [Client Hello message] into more than two records, which helps against
+    censors that only reassemble the first few records.
+  - [NewSNISplitDialer] creates a [transport.StreamDialer] that splits the
+    [Client Hello message] in the middle of the SNI hostname, so the hostname
+    straddles two records.
 
 [Circumventing the GFW with TLS Record Fragmentation]: https://upb-syssec.github.io/blog/2023/record-fragmentation/#tls-record-fragmentation
 [TLS records]: https://datatracker.ietf.org/doc/html/rfc8446#section-5.1
@@ -19,6 +19,8 @@ import (
 	"errors"
 	"io"
 	"net"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
 )
 
 // RecordLenFragFunc takes the length of the first [handshake record]'s content (without the 5-byte header),
@@ -140,6 +142,18 @@ func (w *recordLenFragWriter) Write(p []byte) (n int, err error) {
 	return n + m, e
 }
 
+// Flush writes the bytes buffered while waiting for a complete record header, which only happens if fewer than 5
+// bytes were written. The connections call it on CloseWrite, so those bytes are not lost.
+func (w *recordLenFragWriter) Flush() error {
+	if w.done || w.tlsHdr != nil || len(w.hdr) == 0 {
+		return nil
+	}
+	_, err := w.base.Write(w.hdr)
+	w.hdr = w.hdr[:0]
+	w.done = true
+	return err
+}
+
 // fixedLenReaderFrom optimizes for fixedLenWriter when the base [io.Writer] implements [io.ReaderFrom].
 type fixedLenReaderFrom struct {
 	*recordLenFragWriter
@@ -285,6 +299,15 @@ func writeN(dst io.Writer, p []byte, limit int) (int, error) {
 	return dst.Write(p)
 }
 
+// asTCPConn returns the TCP connection underlying dst, if any, so we can use writev even if the connection is traced.
+func asTCPConn(dst io.Writer) (*net.TCPConn, bool) {
+	conn, ok := dst.(net.Conn)
+	if !ok {
+		return nil, false
+	}
+	return transport.AsTCPConn(conn)
+}
+
 // writeBoth writes both p1 and p2 to dst in a single Write or writev call.
 // It returns the number of bytes that are written from p1 and p2, respectively.
 //
@@ -297,11 +320,11 @@ func writeBoth(dst io.Writer, p1 []byte, p2 []byte) (int, int, error) {
 	var nn int64
 	var err error
 
-	if _, ok := dst.(*net.TCPConn); ok {
+	if tcpConn, ok := asTCPConn(dst); ok {
 		// If the underlying writer implements writev system call
 		// UDPConn and IPConn also implement writev, but TLS is TCP so we only care about TCP
 		buf := net.Buffers{p1, p2}
-		nn, err = buf.WriteTo(dst)
+		nn, err = buf.WriteTo(tcpConn)
 	} else {
 		// We must allocate temporary buffer to hold both content and issue a single Write.
 		// This will add some pressure to GC because the temporary buffer will escape to heap.
@@ -0,0 +1,96 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsfrag
+
+import (
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	handshakeTypeClientHello uint8  = 1
+	extensionServerName      uint16 = 0
+	serverNameTypeHostName   uint8  = 0
+)
+
+// NewSNISplitDialer creates a [transport.StreamDialer] that fragments the [TLS Client Hello] record in the middle of
+// the hostname in the [server_name extension], so the SNI string straddles two TLS records.
+//
+// Like [NewStreamDialerFunc], the Client Hello is buffered until the full record is available, so it may span multiple
+// Writes. If the Client Hello has no server_name extension, or can't be parsed, the record is sent without
+// fragmentation.
+//
+// [TLS Client Hello]: https://datatracker.ietf.org/doc/html/rfc8446#section-4.1.2
+// [server_name extension]: https://datatracker.ietf.org/doc/html/rfc6066#section-3
+func NewSNISplitDialer(base transport.StreamDialer) (transport.StreamDialer, error) {
+	return NewStreamDialerFunc(base, splitInSNI)
+}
+
+// splitInSNI is a [FragFunc] that returns the index of the middle of the SNI hostname in the record,
+// or 0 if there is no SNI.
+func splitInSNI(record []byte) int {
+	start, hostLen, ok := findSNI(record)
+	if !ok || hostLen < 2 {
+		return 0
+	}
+	return start + hostLen/2
+}
+
+// findSNI returns the index and length of the first host_name in the server_name extension of the
+// Client Hello message in record.
+func findSNI(record []byte) (start int, length int, ok bool) {
+	s := cryptobyte.String(record)
+	var msgType uint8
+	var hello cryptobyte.String
+	if !s.ReadUint8(&msgType) || msgType != handshakeTypeClientHello || !s.ReadUint24LengthPrefixed(&hello) {
+		return 0, 0, false
+	}
+	var sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !hello.Skip(2+32) || // legacy_version and random
+		!hello.ReadUint8LengthPrefixed(&sessionID) ||
+		!hello.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!hello.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!hello.ReadUint16LengthPrefixed(&extensions) {
+		return 0, 0, false
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return 0, 0, false
+		}
+		if extType != extensionServerName {
+			continue
+		}
+		var nameList cryptobyte.String
+		if !extData.ReadUint16LengthPrefixed(&nameList) {
+			return 0, 0, false
+		}
+		for !nameList.Empty() {
+			var nameType uint8
+			var name cryptobyte.String
+			if !nameList.ReadUint8(&nameType) || !nameList.ReadUint16LengthPrefixed(&name) {
+				return 0, 0, false
+			}
+			if nameType == serverNameTypeHostName {
+				// The remaining bytes of the record follow the name.
+				end := len(record) - len(nameList) - len(extData) - len(extensions) - len(hello) - len(s)
+				return end - len(name), len(name), true
+			}
+		}
+		return 0, 0, false
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsfrag
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// captureClientHello returns the first TLS record sent by a Go TLS client with the given server name.
+func captureClientHello(t *testing.T, serverName string) []byte {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		tls.Client(clientConn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true}).Handshake()
+		clientConn.Close()
+	}()
+	header := make([]byte, recordHeaderLen)
+	_, err := io.ReadFull(serverConn, header)
+	require.NoError(t, err)
+	record := make([]byte, recordHeaderLen+int(tlsHandshakeRecordHeader(header).PayloadLen()))
+	copy(record, header)
+	_, err = io.ReadFull(serverConn, record[recordHeaderLen:])
+	require.NoError(t, err)
+	return record
+}
+
+func TestSNISplitDialer(t *testing.T) {
+	hello := captureClientHello(t, "www.example.com")
+	start, length, ok := findSNI(hello[recordHeaderLen:])
+	require.True(t, ok)
+	require.Equal(t, "www.example.com", string(hello[recordHeaderLen+start:recordHeaderLen+start+length]))
+
+	// Write the Client Hello in multiple pieces.
+	for _, chunks := range []net.Buffers{{hello}, {hello[:3], hello[3:60], hello[60:]}} {
+		inner := &collectStreamDialer{}
+		dialer, err := NewSNISplitDialer(inner)
+		require.NoError(t, err)
+		conn, err := dialer.DialStream(context.Background(), "www.example.com:443")
+		require.NoError(t, err)
+		assertCanWriteAll(t, conn, chunks)
+
+		sent := bytes.Join(inner.bufs, nil)
+		require.Len(t, sent, len(hello)+recordHeaderLen)
+		frag1Len := int(tlsHandshakeRecordHeader(sent).PayloadLen())
+		frag1 := sent[recordHeaderLen : recordHeaderLen+frag1Len]
+		frag2 := sent[2*recordHeaderLen+frag1Len:]
+		require.True(t, bytes.HasSuffix(frag1, []byte("www.exa")))
+		require.True(t, bytes.HasPrefix(frag2, []byte("mple.com")))
+		require.Equal(t, hello[recordHeaderLen:], append(frag1, frag2...))
+	}
+}
+
+func TestSNISplitDialer_NoSNI(t *testing.T) {
+	hello := captureClientHello(t, "")
+	_, _, ok := findSNI(hello[recordHeaderLen:])
+	require.False(t, ok)
+
+	inner := &collectStreamDialer{}
+	dialer, err := NewSNISplitDialer(inner)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "127.0.0.1:443")
+	require.NoError(t, err)
+	assertCanWriteAll(t, conn, net.Buffers{hello})
+	require.Equal(t, hello, bytes.Join(inner.bufs, nil))
+}
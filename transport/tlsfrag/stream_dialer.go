@@ -30,6 +30,14 @@ import (
 // [handshake record]: https://datatracker.ietf.org/doc/html/rfc8446#section-5.1
 type FragFunc func(record []byte) (n int)
 
+// MultiFragFunc is like [FragFunc], but returns multiple fragmentation point indexes, so the [handshake record] can be
+// split into more than two records. The record content record[cuts[i-1]:cuts[i]] goes in its own record. The indexes
+// may be in any order. Indexes that are ≤ 0 or ≥ len(record), or that repeat, are ignored, so that no empty record
+// is sent.
+//
+// [handshake record]: https://datatracker.ietf.org/doc/html/rfc8446#section-5.1
+type MultiFragFunc func(record []byte) (cuts []int)
+
 // NewStreamDialerFunc creates a [transport.StreamDialer] that intercepts the initial [TLS Client Hello]
 // [handshake record] and splits it into two separate records before sending them. The split point is determined by the
 // callback function frag. The dialer then adds appropriate headers to each record and transmits them sequentially
@@ -42,24 +50,58 @@ type FragFunc func(record []byte) (n int)
 // [TLS Client Hello]: https://datatracker.ietf.org/doc/html/rfc8446#section-4.1.2
 // [handshake record]: https://datatracker.ietf.org/doc/html/rfc8446#section-5.1
 func NewStreamDialerFunc(base transport.StreamDialer, frag FragFunc) (transport.StreamDialer, error) {
+	if frag == nil {
+		return nil, errors.New("frag function must not be nil")
+	}
+	return NewStreamDialerMultiFunc(base, toMultiFragFunc(frag))
+}
+
+// NewStreamDialerMultiFunc is like [NewStreamDialerFunc], but splits the initial [TLS Client Hello] [handshake record]
+// into as many records as the cut points returned by frag, plus one. This is useful against censors that only
+// reassemble the first few records.
+//
+// To split at fixed positions, use [NewFixedOffsetsStreamDialer].
+//
+// [TLS Client Hello]: https://datatracker.ietf.org/doc/html/rfc8446#section-4.1.2
+// [handshake record]: https://datatracker.ietf.org/doc/html/rfc8446#section-5.1
+func NewStreamDialerMultiFunc(base transport.StreamDialer, frag MultiFragFunc) (transport.StreamDialer, error) {
 	if base == nil {
 		return nil, errors.New("base dialer must not be nil")
 	}
 	if frag == nil {
 		return nil, errors.New("frag function must not be nil")
 	}
-	return transport.FuncStreamDialer(func(ctx context.Context, raddr string) (transport.StreamConn, error) {
-		baseConn, err := base.DialStream(ctx, raddr)
-		if err != nil {
-			return nil, err
-		}
-		conn, err := WrapConnFragFunc(baseConn, frag)
-		if err != nil {
-			baseConn.Close()
-			return nil, err
-		}
-		return conn, nil
-	}), nil
+	return &wrapConnDialer{base: base, wrapConn: func(baseConn transport.StreamConn) (transport.StreamConn, error) {
+		return WrapConnMultiFragFunc(baseConn, frag)
+	}}, nil
+}
+
+// wrapConnDialer is a [transport.StreamDialer] that wraps the connections of the base dialer with wrapConn.
+type wrapConnDialer struct {
+	base     transport.StreamDialer
+	wrapConn func(baseConn transport.StreamConn) (transport.StreamConn, error)
+}
+
+var _ transport.StreamDialer = (*wrapConnDialer)(nil)
+var _ transport.ConnectFailureReporter = (*wrapConnDialer)(nil)
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *wrapConnDialer) DialStream(ctx context.Context, raddr string) (transport.StreamConn, error) {
+	baseConn, err := d.base.DialStream(ctx, raddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.wrapConn(baseConn)
+	if err != nil {
+		baseConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *wrapConnDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.base)
 }
 
 // WrapConnFragFunc wraps the base [transport.StreamConn] and splits the first TLS Client Hello packet into two records
@@ -71,6 +113,15 @@ func NewStreamDialerFunc(base transport.StreamDialer, frag FragFunc) (transport.
 // If your goal is to simply fragment the Client Hello at a fixed position, [WrapConnFixedLen] is more efficient as it
 // won't allocate any additional buffers.
 func WrapConnFragFunc(base transport.StreamConn, frag FragFunc) (transport.StreamConn, error) {
+	if frag == nil {
+		return nil, errors.New("frag function must not be nil")
+	}
+	return WrapConnMultiFragFunc(base, toMultiFragFunc(frag))
+}
+
+// WrapConnMultiFragFunc is like [WrapConnFragFunc], but splits the first TLS Client Hello record into multiple records
+// according to the cut points returned by frag.
+func WrapConnMultiFragFunc(base transport.StreamConn, frag MultiFragFunc) (transport.StreamConn, error) {
 	w, err := newClientHelloFragWriter(base, frag)
 	if err != nil {
 		return nil, err
@@ -78,6 +129,24 @@ func WrapConnFragFunc(base transport.StreamConn, frag FragFunc) (transport.Strea
 	return transport.WrapConn(base, base, w), nil
 }
 
+// toMultiFragFunc adapts a [FragFunc] to a [MultiFragFunc] with a single cut point.
+func toMultiFragFunc(frag FragFunc) MultiFragFunc {
+	return func(record []byte) []int {
+		return []int{frag(record)}
+	}
+}
+
+// NewFixedOffsetsStreamDialer is a [transport.StreamDialer] that splits the [TLS handshake record] at each of the
+// given offsets of the record content, producing len(offsets)+1 records. For example, offsets 1, 2 and 3 send the
+// first three bytes of the Client Hello message in their own records, followed by a record with the rest.
+// Offsets that fall outside of the record content are ignored.
+//
+// [TLS handshake record]: https://datatracker.ietf.org/doc/html/rfc8446#section-5.1
+func NewFixedOffsetsStreamDialer(base transport.StreamDialer, offsets ...int) (transport.StreamDialer, error) {
+	cuts := append([]int{}, offsets...)
+	return NewStreamDialerMultiFunc(base, func(record []byte) []int { return cuts })
+}
+
 // NewFixedLenStreamDialer is a [transport.StreamDialer] that fragments the [TLS handshake record]. It splits the
 // record into two records based on the given splitLen. If splitLen is positive, the first piece will contain the
 // specified number of leading bytes from the original message. If it is negative, the second piece will contain
@@ -91,18 +160,9 @@ func NewFixedLenStreamDialer(base transport.StreamDialer, splitLen int) (transpo
 	if splitLen == 0 {
 		return base, nil
 	}
-	return transport.FuncStreamDialer(func(ctx context.Context, raddr string) (transport.StreamConn, error) {
-		baseConn, err := base.DialStream(ctx, raddr)
-		if err != nil {
-			return nil, err
-		}
-		conn, err := WrapConnFixedLen(baseConn, splitLen)
-		if err != nil {
-			baseConn.Close()
-			return nil, err
-		}
-		return conn, nil
-	}), nil
+	return &wrapConnDialer{base: base, wrapConn: func(baseConn transport.StreamConn) (transport.StreamConn, error) {
+		return WrapConnFixedLen(baseConn, splitLen)
+	}}, nil
 }
 
 // WrapConnFixedLen wraps the base [transport.StreamConn] and splits the first TLS Client Hello record into two records
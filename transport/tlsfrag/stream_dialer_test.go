@@ -15,6 +15,7 @@
 package tlsfrag
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
@@ -49,6 +50,75 @@ func TestStreamDialerFuncSplitsClientHello(t *testing.T) {
 	require.Equal(t, expected, inner.bufs)
 }
 
+// Make sure the first Client Hello is splitted into multiple records.
+func TestFixedOffsetsStreamDialerSplitsClientHello(t *testing.T) {
+	hello := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0x01, 0x00, 0x00, 0x03, 0xaa, 0xbb, 0xcc})
+	cipher := constructTLSRecord(t, layers.TLSChangeCipherSpec, 0x0303, []byte{0x01})
+
+	inner := &collectStreamDialer{}
+	d, err := NewFixedOffsetsStreamDialer(inner, 4, 1, 2)
+	require.NoError(t, err)
+	conn, err := d.DialStream(context.Background(), "ipinfo.io:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assertCanWriteAll(t, conn, net.Buffers{hello[:3], hello[3:], cipher, hello})
+
+	frag1 := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0x01})
+	frag2 := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0x00})
+	frag3 := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0x00, 0x03})
+	frag4 := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0xaa, 0xbb, 0xcc})
+	expected := net.Buffers{
+		bytes.Join([][]byte{frag1, frag2, frag3, frag4}, nil),
+		cipher, hello, // Unchanged
+	}
+	require.Equal(t, expected, inner.bufs)
+}
+
+// Make sure cut points on the record boundaries or repeated don't create empty records.
+func TestStreamDialerMultiFuncIgnoresBoundaryCuts(t *testing.T) {
+	payload := []byte{0x01, 0x00, 0x00, 0x03, 0xaa, 0xbb, 0xcc}
+	hello := constructTLSRecord(t, layers.TLSHandshake, 0x0301, payload)
+
+	cases := []struct {
+		msg      string
+		cuts     []int
+		expected []byte
+	}{
+		{
+			msg:      "only boundaries",
+			cuts:     []int{0, len(payload), -1, len(payload) + 1},
+			expected: hello,
+		},
+		{
+			msg:  "boundaries and repeated",
+			cuts: []int{len(payload), 3, 0, 3},
+			expected: append(
+				constructTLSRecord(t, layers.TLSHandshake, 0x0301, payload[:3]),
+				constructTLSRecord(t, layers.TLSHandshake, 0x0301, payload[3:])...),
+		},
+		{
+			msg:  "last byte",
+			cuts: []int{1, len(payload) - 1},
+			expected: bytes.Join([][]byte{
+				constructTLSRecord(t, layers.TLSHandshake, 0x0301, payload[:1]),
+				constructTLSRecord(t, layers.TLSHandshake, 0x0301, payload[1:len(payload)-1]),
+				constructTLSRecord(t, layers.TLSHandshake, 0x0301, payload[len(payload)-1:]),
+			}, nil),
+		},
+	}
+
+	for _, tc := range cases {
+		inner := &collectStreamDialer{}
+		d, err := NewStreamDialerMultiFunc(inner, func(record []byte) []int { return tc.cuts })
+		require.NoError(t, err, tc.msg)
+		conn, err := d.DialStream(context.Background(), "ipinfo.io:443")
+		require.NoError(t, err, tc.msg)
+		assertCanWriteAll(t, conn, net.Buffers{hello})
+		require.Equal(t, net.Buffers{tc.expected}, inner.bufs, tc.msg)
+	}
+}
+
 // Make sure we don't split if the first packet is not a Client Hello.
 func TestStreamDialerFuncDontSplitNonClientHello(t *testing.T) {
 	cases := []struct {
@@ -91,6 +161,27 @@ func TestStreamDialerFuncDontSplitNonClientHello(t *testing.T) {
 	}
 }
 
+// Make sure the data buffered while waiting for a complete Client Hello is sent on CloseWrite.
+func TestStreamDialerCloseWriteFlushesIncompleteClientHello(t *testing.T) {
+	hello := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0x01, 0x00, 0x00, 0x03, 0xdd, 0xee, 0xff})
+
+	inner := &collectStreamDialer{}
+	conn := assertCanDialFragFunc(t, inner, "ipinfo.io:443", func(payload []byte) int { return len(payload) / 2 })
+	defer conn.Close()
+	assertCanWriteAll(t, conn, net.Buffers{hello[:8]})
+	require.Empty(t, inner.bufs)
+	require.NoError(t, conn.CloseWrite())
+	require.Equal(t, net.Buffers{hello[:8]}, inner.bufs)
+
+	inner = &collectStreamDialer{}
+	conn = assertCanDialFixedLenFrag(t, inner, "ipinfo.io:443", 2)
+	defer conn.Close()
+	assertCanWriteAll(t, conn, net.Buffers{hello[:3]})
+	require.Empty(t, inner.bufs)
+	require.NoError(t, conn.CloseWrite())
+	require.Equal(t, net.Buffers{hello[:3]}, inner.bufs)
+}
+
 // Make sure only the first Client Hello is splitted by a fixed length.
 func TestFixedLenStreamDialerSplitsClientHello(t *testing.T) {
 	hello := constructTLSRecord(t, layers.TLSHandshake, 0x0301, []byte{0x01, 0x00, 0x00, 0x03, 0xaa, 0xbb, 0xcc})
@@ -247,3 +338,26 @@ func (c *collectStreamDialer) RemoteAddr() net.Addr               { return nil }
 func (c *collectStreamDialer) SetDeadline(t time.Time) error      { return errors.New("not supported") }
 func (c *collectStreamDialer) SetReadDeadline(t time.Time) error  { return errors.New("not supported") }
 func (c *collectStreamDialer) SetWriteDeadline(t time.Time) error { return errors.New("not supported") }
+
+func TestStreamDialerReportsConnectFailure(t *testing.T) {
+	base := &collectStreamDialer{}
+	d, err := NewFixedLenStreamDialer(base, 5)
+	require.NoError(t, err)
+	require.True(t, transport.ReportsConnectFailure(d))
+
+	d, err = NewFixedLenStreamDialer(unreportedFailureDialer{base}, 5)
+	require.NoError(t, err)
+	require.False(t, transport.ReportsConnectFailure(d))
+
+	d, err = NewStreamDialerFunc(unreportedFailureDialer{base}, func(record []byte) int { return 1 })
+	require.NoError(t, err)
+	require.False(t, transport.ReportsConnectFailure(d))
+}
+
+// unreportedFailureDialer is a [transport.StreamDialer] that declares its dial errors don't reflect the reachability
+// of the destination, like Shadowsocks.
+type unreportedFailureDialer struct {
+	transport.StreamDialer
+}
+
+func (d unreportedFailureDialer) ReportsConnectFailure() bool { return false }
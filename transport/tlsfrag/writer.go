@@ -18,16 +18,17 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"sort"
 )
 
-// clientHelloFragWriter intercepts the initial TLS Client Hello record and splits it into two TLS records based on the
-// return value of frag function. These fragmented records are then written to the base [io.Writer]. Subsequent packets
+// clientHelloFragWriter intercepts the initial TLS Client Hello record and splits it into multiple TLS records based on
+// the return value of frag function. These fragmented records are then written to the base [io.Writer]. Subsequent packets
 // are not modified and are directly transmitted through the base [io.Writer].
 type clientHelloFragWriter struct {
 	base io.Writer
 	// Indicates all splitted rcds have been already written to base
 	done bool
-	frag FragFunc
+	frag MultiFragFunc
 
 	// The buffer containing and parsing a TLS Client Hello record
 	helloBuf *clientHelloBuffer
@@ -47,8 +48,8 @@ var _ io.Writer = (*clientHelloFragWriter)(nil)
 var _ io.Writer = (*clientHelloFragReaderFrom)(nil)
 var _ io.ReaderFrom = (*clientHelloFragReaderFrom)(nil)
 
-// newClientHelloFragWriter creates a [io.Writer] that splits the first TLS Client Hello record into two records
-// based on the provided [FragFunc] callback.
+// newClientHelloFragWriter creates a [io.Writer] that splits the first TLS Client Hello record into multiple records
+// based on the provided [MultiFragFunc] callback.
 // It then writes these records and all subsequent messages to the base [io.Writer].
 // If the first message isn't a Client Hello, no splitting occurs and all messages are written directly to base.
 //
@@ -57,7 +58,7 @@ var _ io.ReaderFrom = (*clientHelloFragReaderFrom)(nil)
 //
 // If you just want to split the record at a fixed position (e.g., always at the 5th byte or 2nd from the last
 // byte), use [NewRecordLenFuncWriter]. It consumes less resources and is more efficient.
-func newClientHelloFragWriter(base io.Writer, frag FragFunc) (io.Writer, error) {
+func newClientHelloFragWriter(base io.Writer, frag MultiFragFunc) (io.Writer, error) {
 	if base == nil {
 		return nil, errors.New("base writer must not be nil")
 	}
@@ -76,7 +77,7 @@ func newClientHelloFragWriter(base io.Writer, frag FragFunc) (io.Writer, error)
 }
 
 // Write implements io.Writer.Write. It attempts to split the data received in the first one or more Write call(s)
-// into two TLS records if the data corresponds to a TLS Client Hello record.
+// into multiple TLS records if the data corresponds to a TLS Client Hello record.
 func (w *clientHelloFragWriter) Write(p []byte) (n int, err error) {
 	if !w.done {
 		// not yet splitted, append to the buffer
@@ -106,7 +107,7 @@ func (w *clientHelloFragWriter) Write(p []byte) (n int, err error) {
 	return
 }
 
-// ReadFrom implements io.ReaderFrom.ReadFrom. It attempts to split the first packet into two TLS records if the data
+// ReadFrom implements io.ReaderFrom.ReadFrom. It attempts to split the first packet into multiple TLS records if the data
 // corresponds to a TLS Client Hello record. And then copies the remaining data from r to the base io.Writer until EOF
 // or error.
 //
@@ -147,42 +148,77 @@ func (w *clientHelloFragWriter) copyHelloBufToRecord() {
 	w.helloBuf = nil // allows the GC to recycle the memory
 }
 
-// splitHelloBufToRecord splits w.helloBuf into two records and put them into w.record without allocations.
+// splitHelloBufToRecord splits w.helloBuf into multiple records at the cut points returned by w.frag and put them
+// into w.record.
 func (w *clientHelloFragWriter) splitHelloBufToRecord() {
 	original := w.helloBuf.Bytes()
 	content := original[recordHeaderLen:]
-	headLen := w.frag(content)
-	if headLen <= 0 || headLen >= len(content) {
+	cuts := normalizeCuts(w.frag(content), len(content))
+	if len(cuts) == 0 {
 		w.copyHelloBufToRecord()
 		return
 	}
-	tailLen := len(content) - headLen
-
-	//           |  header   |         payload         |  cap==len+5
-	// original: | <= (5) => | <= head => | <= tail => | <= (5) => |
-	//                       |            |\            \
-	//                       |            | \-------\    \-------\
-	//                       |            |          \            \
-	// splitted: | <= (5) => | <= head => | <= (5) => | <= tail => |
-	//           |  header1  |  payload1  |  header2  |  payload2  |
-	splitted := original[:len(original)+recordHeaderLen]
-	hdr1, _ := newTLSHandshakeRecordHeader(splitted[:recordHeaderLen])
-	hdr1.SetPayloadLen(uint16(headLen))
-
-	// Shift tail fragment to make space for record header.
-	tail := original[recordHeaderLen+headLen:]
-	payload2 := splitted[recordHeaderLen*2+headLen:]
-	copy(payload2, tail)
-
-	// Insert header for second fragment.
-	hdr2, _ := newTLSHandshakeRecordHeader(splitted[recordHeaderLen+headLen : recordHeaderLen*2+headLen])
-	copy(hdr2, hdr1)
-	hdr2.SetPayloadLen(uint16(tailLen))
+
+	// original: |  header  | frag 1 | frag 2 | ... | frag N |
+	// splitted: | header 1 | frag 1 | header 2 | frag 2 | ... | header N | frag N |
+	//
+	// The records are written in place: the fragments are shifted to the right, starting from the last one, to make
+	// space for the headers. The Client Hello buffer has room for one extra header, so it only grows for three or more
+	// records.
+	splitted := original
+	if extraLen := len(cuts) * recordHeaderLen; cap(splitted)-len(splitted) < extraLen {
+		splitted = make([]byte, len(original), len(original)+extraLen)
+		copy(splitted, original)
+	}
+	splitted = splitted[:len(original)+len(cuts)*recordHeaderLen]
+	var header [recordHeaderLen]byte
+	copy(header[:], original)
+	bounds := append(append([]int{0}, cuts...), len(content))
+	for i := len(bounds) - 2; i >= 0; i-- {
+		start, end := bounds[i], bounds[i+1]
+		recordStart := start + i*recordHeaderLen
+		copy(splitted[recordStart+recordHeaderLen:], splitted[recordHeaderLen+start:recordHeaderLen+end])
+		hdr, _ := newTLSHandshakeRecordHeader(splitted[recordStart : recordStart+recordHeaderLen])
+		copy(hdr, header[:])
+		hdr.SetPayloadLen(uint16(end - start))
+	}
 
 	w.record = bytes.NewBuffer(splitted)
 	w.helloBuf = nil // allows the GC to recycle the memory
 }
 
+// normalizeCuts returns the cut points sorted and deduplicated, dropping the ones that would create empty records,
+// that is, the ones ≤ 0 or ≥ contentLen.
+func normalizeCuts(cuts []int, contentLen int) []int {
+	sorted := make([]int, 0, len(cuts))
+	for _, cut := range cuts {
+		if cut > 0 && cut < contentLen {
+			sorted = append(sorted, cut)
+		}
+	}
+	sort.Ints(sorted)
+	normalized := sorted[:0]
+	for _, cut := range sorted {
+		if len(normalized) == 0 || cut != normalized[len(normalized)-1] {
+			normalized = append(normalized, cut)
+		}
+	}
+	return normalized
+}
+
+// Flush writes the data buffered while waiting for a complete Client Hello record, without splitting it.
+// The connections call it on CloseWrite, so the data is not lost if the Client Hello is incomplete.
+func (w *clientHelloFragWriter) Flush() error {
+	if w.done {
+		return nil
+	}
+	if w.record == nil {
+		w.copyHelloBufToRecord()
+	}
+	_, err := w.flushRecord()
+	return err
+}
+
 // flushRecord writes all bytes from w.record to base.
 func (w *clientHelloFragWriter) flushRecord() (int, error) {
 	n, err := io.Copy(w.base, w.record)
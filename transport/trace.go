@@ -0,0 +1,123 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Trace is a set of hooks to observe the stream connections created by the dialers in this module. Any of the hooks
+// may be nil. Use [WithTrace] to set it in the context passed to DialStream. Unlike [net/http/httptrace], it works
+// for any protocol on top of the connection.
+//
+// Dialers that use other dialers report their own dials too, so the hooks get the protocol of the dialer, such as
+// "tcp", "socks5", "shadowsocks" or "tls", and the address it dialed. A single DialStream call may trigger the hooks
+// for each layer. The hooks may be called from different goroutines.
+type Trace struct {
+	// DialStart is called when the dialer starts to dial the address.
+	DialStart func(protocol, address string)
+	// Connected is called when the dial finishes, with the error if it failed.
+	Connected func(protocol, address string, err error)
+	// FirstByte is called when the first byte is read from the connection.
+	FirstByte func(protocol, address string)
+	// Closed is called the first time the connection is closed, with the error returned by Close.
+	Closed func(protocol, address string, err error)
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context based on ctx that makes the dialers call the hooks of trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// ContextTrace returns the [Trace] set in the context with [WithTrace], or nil if none.
+func ContextTrace(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// TraceDialStream calls dial and reports it to the [Trace] in the context, if any. [StreamDialer] implementations use
+// it to support tracing. The protocol identifies the dialer, and address is the address being dialed.
+//
+// If the trace has the FirstByte or Closed hooks, the returned connection is wrapped to report those events, so it
+// doesn't implement other interfaces of the connection returned by dial, and can't be converted to its concrete type.
+// Use [AsTCPConn] to get the underlying TCP connection.
+func TraceDialStream(ctx context.Context, protocol, address string, dial func() (StreamConn, error)) (StreamConn, error) {
+	trace := ContextTrace(ctx)
+	if trace == nil {
+		return dial()
+	}
+	if trace.DialStart != nil {
+		trace.DialStart(protocol, address)
+	}
+	conn, err := dial()
+	if trace.Connected != nil {
+		trace.Connected(protocol, address, err)
+	}
+	if err != nil || (trace.FirstByte == nil && trace.Closed == nil) {
+		return conn, err
+	}
+	return &tracedConn{StreamConn: conn, trace: trace, protocol: protocol, address: address}, nil
+}
+
+// tracedConn is a [StreamConn] that reports the first byte read and the close to a [Trace].
+type tracedConn struct {
+	StreamConn
+	trace     *Trace
+	protocol  string
+	address   string
+	readOnce  sync.Once
+	closeOnce sync.Once
+}
+
+func (c *tracedConn) Read(b []byte) (int, error) {
+	n, err := c.StreamConn.Read(b)
+	if n > 0 && c.trace.FirstByte != nil {
+		c.readOnce.Do(func() { c.trace.FirstByte(c.protocol, c.address) })
+	}
+	return n, err
+}
+
+// Unwrap returns the traced connection.
+func (c *tracedConn) Unwrap() StreamConn {
+	return c.StreamConn
+}
+
+func (c *tracedConn) Close() error {
+	err := c.StreamConn.Close()
+	if c.trace.Closed != nil {
+		c.closeOnce.Do(func() { c.trace.Closed(c.protocol, c.address, err) })
+	}
+	return err
+}
+
+// AsTCPConn returns the [net.TCPConn] underlying conn, unwrapping the connections wrapped by [TraceDialStream].
+// Use it instead of a type assertion when you need the socket, for example to set socket options.
+// It returns false if conn is not a TCP connection.
+func AsTCPConn(conn net.Conn) (*net.TCPConn, bool) {
+	for {
+		switch c := conn.(type) {
+		case *net.TCPConn:
+			return c, true
+		case interface{ Unwrap() StreamConn }:
+			conn = c.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
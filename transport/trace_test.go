@@ -0,0 +1,119 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// traceRecorder records the trace events as strings.
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *traceRecorder) add(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *traceRecorder) trace() *Trace {
+	return &Trace{
+		DialStart: func(protocol, address string) { r.add("DialStart %v", protocol) },
+		Connected: func(protocol, address string, err error) { r.add("Connected %v %v", protocol, err != nil) },
+		FirstByte: func(protocol, address string) { r.add("FirstByte %v", protocol) },
+		Closed:    func(protocol, address string, err error) { r.add("Closed %v", protocol) },
+	}
+}
+
+func TestTraceDialStream_TCP(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("response"))
+	}()
+
+	var recorder traceRecorder
+	ctx := WithTrace(context.Background(), recorder.trace())
+	conn, err := (&TCPDialer{}).DialStream(ctx, listener.Addr().String())
+	require.NoError(t, err)
+	require.Equal(t, []string{"DialStart tcp", "Connected tcp false"}, recorder.events)
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "response", string(response))
+	require.NoError(t, conn.Close())
+	conn.Close()
+	require.Equal(t, []string{"DialStart tcp", "Connected tcp false", "FirstByte tcp", "Closed tcp"}, recorder.events)
+}
+
+func TestTraceDialStream_Error(t *testing.T) {
+	var recorder traceRecorder
+	ctx := WithTrace(context.Background(), recorder.trace())
+	dialErr := errors.New("dial failed")
+	_, err := TraceDialStream(ctx, "test", "example.com:443", func() (StreamConn, error) {
+		return nil, dialErr
+	})
+	require.ErrorIs(t, err, dialErr)
+	require.Equal(t, []string{"DialStart test", "Connected test true"}, recorder.events)
+}
+
+func TestTraceDialStream_NoConnHooks(t *testing.T) {
+	var connected bool
+	ctx := WithTrace(context.Background(), &Trace{Connected: func(string, string, error) { connected = true }})
+	conn := &net.TCPConn{}
+	traced, err := TraceDialStream(ctx, "test", "example.com:443", func() (StreamConn, error) { return conn, nil })
+	require.NoError(t, err)
+	require.True(t, connected)
+	// The connection is not wrapped if the trace doesn't need it.
+	require.Same(t, conn, traced)
+}
+
+func TestAsTCPConn(t *testing.T) {
+	conn := &net.TCPConn{}
+	ctx := WithTrace(context.Background(), &Trace{Closed: func(string, string, error) {}})
+	traced, err := TraceDialStream(ctx, "test", "example.com:443", func() (StreamConn, error) { return conn, nil })
+	require.NoError(t, err)
+	require.NotSame(t, conn, traced)
+
+	tcpConn, ok := AsTCPConn(traced)
+	require.True(t, ok)
+	require.Same(t, conn, tcpConn)
+	tcpConn, ok = AsTCPConn(conn)
+	require.True(t, ok)
+	require.Same(t, conn, tcpConn)
+	_, ok = AsTCPConn(&net.UDPConn{})
+	require.False(t, ok)
+}
+
+func TestContextTrace(t *testing.T) {
+	require.Nil(t, ContextTrace(context.Background()))
+	trace := &Trace{}
+	require.Same(t, trace, ContextTrace(WithTrace(context.Background(), trace)))
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// Handoff protocol constants. See [NewUnixHandoffStreamDialer].
+const (
+	unixHandoffVersion   = 1
+	unixHandoffModeRelay = 1
+	unixHandoffModeFD    = 2
+)
+
+// unixHandoffDialer is a [StreamDialer] that delegates the connection to a helper listening on a UNIX socket.
+// Use [NewUnixHandoffStreamDialer] to create new instances.
+type unixHandoffDialer struct {
+	socketPath string
+	passFD     bool
+}
+
+var _ StreamDialer = (*unixHandoffDialer)(nil)
+
+/*
+NewUnixHandoffStreamDialer creates a [StreamDialer] that hands off the connection to a local helper process
+listening on the UNIX domain socket at socketPath. This is useful to integrate with a helper that has privileges
+or network access the current process lacks.
+
+For each dial, a new connection is made to the socket and the client sends the request:
+
+	+-----+------+---------+----------+
+	| VER | MODE | ADDRLEN |   ADDR   |
+	+-----+------+---------+----------+
+	|  1  |  1   |    2    | Variable |
+	+-----+------+---------+----------+
+
+VER is 1. ADDRLEN is the big-endian length of ADDR, which is the "host:port" address to dial.
+MODE is 2 (FD passing) on Linux, and 1 (relay) on other platforms.
+
+The helper dials the address and replies with:
+
+	+--------+--------+----------+
+	| STATUS | MSGLEN |   MSG    |
+	+--------+--------+----------+
+	|   1    |   1    | Variable |
+	+--------+--------+----------+
+
+STATUS is 0 on success, in which case MSGLEN is 0. Otherwise MSG describes the failure.
+
+In FD passing mode, the helper attaches the file descriptor of the connected socket to the reply as an SCM_RIGHTS
+control message, and closes the UNIX socket connection. The returned [StreamConn] uses the received socket directly.
+In relay mode, the helper relays bytes between the UNIX socket connection and the destination, and the returned
+[StreamConn] is the UNIX socket connection itself.
+*/
+func NewUnixHandoffStreamDialer(socketPath string) (StreamDialer, error) {
+	if socketPath == "" {
+		return nil, errors.New("argument socketPath must not be empty")
+	}
+	return &unixHandoffDialer{socketPath: socketPath, passFD: unixHandoffSupportsFD}, nil
+}
+
+// DialStream implements [StreamDialer].DialStream.
+func (d *unixHandoffDialer) DialStream(ctx context.Context, raddr string) (StreamConn, error) {
+	if len(raddr) > math.MaxUint16 {
+		return nil, fmt.Errorf("address length = %v is over %v", len(raddr), math.MaxUint16)
+	}
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "unix", d.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to handoff socket: %w", err)
+	}
+	conn := netConn.(*net.UnixConn)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	mode := byte(unixHandoffModeRelay)
+	if d.passFD {
+		mode = unixHandoffModeFD
+	}
+	req := make([]byte, 0, 4+len(raddr))
+	req = append(req, unixHandoffVersion, mode)
+	req = binary.BigEndian.AppendUint16(req, uint16(len(raddr)))
+	req = append(req, raddr...)
+	if _, err := conn.Write(req); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handoff request: %w", err)
+	}
+
+	if d.passFD {
+		defer conn.Close()
+		return receiveHandoffFD(conn)
+	}
+	var status [1]byte
+	if _, err := io.ReadFull(conn, status[:]); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read handoff status: %w", err)
+	}
+	if err := readHandoffError(conn, status[0]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// readHandoffError reads the rest of the handoff reply and returns an error if the status is not success.
+func readHandoffError(r io.Reader, status byte) error {
+	var msgLen [1]byte
+	if _, err := io.ReadFull(r, msgLen[:]); err != nil {
+		return fmt.Errorf("failed to read handoff message length: %w", err)
+	}
+	msg := make([]byte, msgLen[0])
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return fmt.Errorf("failed to read handoff message: %w", err)
+	}
+	if status != 0 {
+		return fmt.Errorf("handoff failed with status %v: %s", status, msg)
+	}
+	return nil
+}
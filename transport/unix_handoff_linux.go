@@ -0,0 +1,77 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+const unixHandoffSupportsFD = true
+
+// receiveHandoffFD reads the handoff reply from conn and returns a [StreamConn] for the passed socket.
+func receiveHandoffFD(conn *net.UnixConn) (StreamConn, error) {
+	var status [1]byte
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(status[:], oob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handoff status: %w", err)
+	}
+	var fds []int
+	if oobn > 0 {
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse handoff control message: %w", err)
+		}
+		for _, msg := range msgs {
+			msgFDs, err := syscall.ParseUnixRights(&msg)
+			if err != nil {
+				continue
+			}
+			fds = append(fds, msgFDs...)
+		}
+	}
+	files := make([]*os.File, 0, len(fds))
+	for _, fd := range fds {
+		syscall.CloseOnExec(fd)
+		files = append(files, os.NewFile(uintptr(fd), "handoff"))
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	if err := readHandoffError(conn, status[0]); err != nil {
+		return nil, err
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("expected 1 handoff file descriptor, got %v", len(files))
+	}
+	// FileConn duplicates the descriptor, so we still close the file.
+	netConn, err := net.FileConn(files[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection from handoff descriptor: %w", err)
+	}
+	streamConn, ok := netConn.(StreamConn)
+	if !ok {
+		netConn.Close()
+		return nil, errors.New("handoff descriptor is not a stream socket")
+	}
+	return streamConn, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixHandoffStreamDialer_FD(t *testing.T) {
+	echo := startEchoServer(t)
+	socketPath := startHandoffServer(t, func(conn *net.UnixConn, target *net.TCPConn) error {
+		file, err := target.File()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, _, err = conn.WriteMsgUnix([]byte{0, 0}, syscall.UnixRights(int(file.Fd())), nil)
+		return err
+	})
+	dialer, err := NewUnixHandoffStreamDialer(socketPath)
+	require.NoError(t, err)
+	testHandoffEcho(t, dialer, echo.Addr().String())
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startEchoServer starts a TCP server that echoes back what it receives.
+func startEchoServer(t *testing.T) *net.TCPListener {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+				conn.CloseWrite()
+			}()
+		}
+	}()
+	return listener
+}
+
+// startHandoffServer starts a helper implementing the handoff protocol. It relays bytes for relay requests and
+// calls sendFD for FD passing requests.
+func startHandoffServer(t *testing.T, sendFD func(conn *net.UnixConn, target *net.TCPConn) error) string {
+	socketPath := filepath.Join(t.TempDir(), "helper.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.AcceptUnix()
+			if err != nil {
+				return
+			}
+			go serveHandoff(conn, sendFD)
+		}
+	}()
+	return socketPath
+}
+
+func serveHandoff(conn *net.UnixConn, sendFD func(conn *net.UnixConn, target *net.TCPConn) error) {
+	defer conn.Close()
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return
+	}
+	addr := make([]byte, binary.BigEndian.Uint16(header[2:]))
+	if _, err := io.ReadFull(conn, addr); err != nil {
+		return
+	}
+	netTarget, err := net.Dial("tcp", string(addr))
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > 255 {
+			msg = msg[:255]
+		}
+		conn.Write(append([]byte{1, byte(len(msg))}, msg...))
+		return
+	}
+	target := netTarget.(*net.TCPConn)
+	defer target.Close()
+	if header[1] == unixHandoffModeFD {
+		sendFD(conn, target)
+		return
+	}
+	if _, err := conn.Write([]byte{0, 0}); err != nil {
+		return
+	}
+	go func() {
+		io.Copy(target, conn)
+		target.CloseWrite()
+	}()
+	io.Copy(conn, target)
+}
+
+func testHandoffEcho(t *testing.T, dialer StreamDialer, addr string) {
+	conn, err := dialer.DialStream(context.Background(), addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("Request"))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "Request", string(response))
+}
+
+func TestUnixHandoffStreamDialer_Relay(t *testing.T) {
+	echo := startEchoServer(t)
+	socketPath := startHandoffServer(t, nil)
+	dialer := &unixHandoffDialer{socketPath: socketPath, passFD: false}
+	testHandoffEcho(t, dialer, echo.Addr().String())
+}
+
+func TestUnixHandoffStreamDialer_Error(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	// Close the listener so the helper fails to connect.
+	listener.Close()
+	socketPath := startHandoffServer(t, nil)
+	dialer := &unixHandoffDialer{socketPath: socketPath, passFD: false}
+	_, err = dialer.DialStream(context.Background(), listener.Addr().String())
+	require.ErrorContains(t, err, "handoff failed with status 1")
+}
+
+func TestNewUnixHandoffStreamDialer_EmptyPath(t *testing.T) {
+	_, err := NewUnixHandoffStreamDialer("")
+	require.Error(t, err)
+}
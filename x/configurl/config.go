@@ -18,7 +18,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -40,7 +42,10 @@ type TypeRegistry[ObjectType any] interface {
 type ExtensibleProvider[ObjectType comparable] struct {
 	// Instance to return when config is nil.
 	BaseInstance ObjectType
-	builders     map[string]BuildFunc[ObjectType]
+	// Logger, if set, gets debug events for the creation of each config part. Only the config types are logged,
+	// since the configs may have secrets.
+	Logger   *slog.Logger
+	builders map[string]BuildFunc[ObjectType]
 }
 
 var (
@@ -68,6 +73,16 @@ func (p *ExtensibleProvider[ObjectType]) RegisterType(subtype string, newInstanc
 	p.ensureBuildersMap()[subtype] = newInstance
 }
 
+// RegisteredTypes returns the sorted names of the registered subtypes, including the ones registered by the user.
+func (p *ExtensibleProvider[ObjectType]) RegisteredTypes() []string {
+	types := make([]string, 0, len(p.builders))
+	for subtype := range p.builders {
+		types = append(types, subtype)
+	}
+	sort.Strings(types)
+	return types
+}
+
 // NewInstance creates a new instance of ObjectType according to the config.
 func (p *ExtensibleProvider[ObjectType]) NewInstance(ctx context.Context, config *Config) (ObjectType, error) {
 	var zero ObjectType
@@ -82,19 +97,35 @@ func (p *ExtensibleProvider[ObjectType]) NewInstance(ctx context.Context, config
 	if !ok {
 		return zero, fmt.Errorf("config type '%v' is not registered", config.URL.Scheme)
 	}
-	return newInstance(ctx, config)
+	instance, err := newInstance(ctx, config)
+	if p.Logger != nil {
+		if err != nil {
+			p.Logger.DebugContext(ctx, "Failed to create config part", "type", config.URL.Scheme, "error", err)
+		} else {
+			p.Logger.DebugContext(ctx, "Created config part", "type", config.URL.Scheme, "instance", fmt.Sprintf("%T", instance))
+		}
+	}
+	return instance, err
 }
 
 // ParseConfig will parse a config given as a string and return the structured [Config].
+//
+// The whitespace around each part, including line breaks, is ignored. Lines starting with "#" are comments and are
+// removed before splitting the parts, and so are the parts starting with "#". A config without parts returns nil,
+// the direct config.
 func ParseConfig(configText string) (*Config, error) {
-	parts := strings.Split(strings.TrimSpace(configText), "|")
-	if len(parts) == 1 && parts[0] == "" {
+	parts := strings.Split(stripCommentLines(configText), "|")
+	if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
 		return nil, nil
 	}
 
 	var config *Config = nil
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "#") {
+			// Comment part.
+			continue
+		}
 		if part == "" {
 			return nil, errors.New("empty config part")
 		}
@@ -110,3 +141,19 @@ func ParseConfig(configText string) (*Config, error) {
 	}
 	return config, nil
 }
+
+// stripCommentLines removes the lines starting with "#", ignoring the leading whitespace. A "#" in the middle of a
+// line doesn't start a comment, since it's also the start of a URL fragment.
+func stripCommentLines(configText string) string {
+	if !strings.Contains(configText, "#") {
+		return configText
+	}
+	lines := strings.Split(configText, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
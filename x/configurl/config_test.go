@@ -0,0 +1,64 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig_Empty(t *testing.T) {
+	for _, configText := range []string{"", "  \n\t", "# Direct connection.", "\n  # Comment.\n"} {
+		config, err := ParseConfig(configText)
+		require.NoError(t, err, configText)
+		require.Nil(t, config, configText)
+	}
+}
+
+func TestParseConfig_Whitespace(t *testing.T) {
+	config, err := ParseConfig("  split:2 |\n\ttlsfrag:1\r\n|  tls  ")
+	require.NoError(t, err)
+	require.Equal(t, "tls:", config.URL.String())
+	require.Equal(t, "tlsfrag:1", config.BaseConfig.URL.String())
+	require.Equal(t, "split:2", config.BaseConfig.BaseConfig.URL.String())
+	require.Nil(t, config.BaseConfig.BaseConfig.BaseConfig)
+}
+
+func TestParseConfig_Comments(t *testing.T) {
+	config, err := ParseConfig(`
+# Server | with a pipe in the comment.
+ss://Y2hhY2hhMjAtaWV0Zi1wb2x5MTMwNTpzZWNyZXQ@example.com:1234#my-server
+  # Fragment the Client Hello.
+| tlsfrag:1 | # Trailing comment part.
+`)
+	require.NoError(t, err)
+	require.Equal(t, "tlsfrag:1", config.URL.String())
+	// The fragment is not a comment.
+	require.Equal(t, "my-server", config.BaseConfig.URL.Fragment)
+	require.Nil(t, config.BaseConfig.BaseConfig)
+
+	config, err = ParseConfig("split:2|#note|tls")
+	require.NoError(t, err)
+	require.Equal(t, "tls:", config.URL.String())
+	require.Equal(t, "split:2", config.BaseConfig.URL.String())
+}
+
+func TestParseConfig_EmptyPart(t *testing.T) {
+	for _, configText := range []string{"split:2||tls", "split:2|", "|tls", "split:2|\n# Comment.\n|tls"} {
+		_, err := ParseConfig(configText)
+		require.Error(t, err, configText)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/httpconnect"
+)
+
+func registerHTTPConnectStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		if config.URL.Host == "" {
+			return nil, fmt.Errorf("%v config must have the proxy address, as in %v://[HOST]:[PORT]", config.URL.Scheme, config.URL.Scheme)
+		}
+		sd, err := newSD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		var opts []httpconnect.ClientOption
+		if userInfo := config.URL.User; userInfo != nil {
+			password, _ := userInfo.Password()
+			opts = append(opts, httpconnect.WithBasicAuth(userInfo.Username(), password))
+		}
+		return httpconnect.NewConnectClient(sd, config.URL.Host, opts...)
+	})
+}
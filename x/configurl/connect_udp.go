@@ -0,0 +1,46 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/quic"
+)
+
+func registerConnectUDPPacketDialer(r TypeRegistry[transport.PacketDialer], typeID string, newPD BuildFunc[transport.PacketDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.PacketDialer, error) {
+		if config.URL.Host == "" {
+			return nil, fmt.Errorf("%v config must have the proxy address, as in %v://[HOST]:[PORT]", config.URL.Scheme, config.URL.Scheme)
+		}
+		query := config.URL.Query()
+		template := query.Get("template")
+		if len(query["template"]) > 1 {
+			return nil, fmt.Errorf("template option must has one value, found %v", len(query["template"]))
+		}
+		query.Del("template")
+		options, err := parseQUICOptions(query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		pd, err := newPD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		return quic.NewConnectUDPPacketDialer(pd, config.URL.Host, template, options...)
+	})
+}
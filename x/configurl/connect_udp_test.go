@@ -0,0 +1,45 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectUDP_Provider(t *testing.T) {
+	providers := NewDefaultProviders()
+	_, err := providers.NewPacketDialer(context.Background(), "connect-udp://proxy.example:443")
+	require.NoError(t, err)
+	_, err = providers.NewPacketDialer(context.Background(), "connect-udp://proxy.example:443?template=%2Fudp%2F%7Btarget_host%7D%2F%7Btarget_port%7D&sni=decoy.example")
+	require.NoError(t, err)
+
+	for _, config := range []string{
+		"connect-udp:proxy.example:443",
+		"connect-udp://proxy.example:443?template=%2Fudp",
+		"connect-udp://proxy.example:443?foo=bar",
+	} {
+		_, err = providers.NewPacketDialer(context.Background(), config)
+		require.Error(t, err, config)
+	}
+}
+
+func TestConnectUDP_Sanitize(t *testing.T) {
+	sanitized, err := SanitizeConfig("connect-udp://proxy.example:443?sni=decoy.example")
+	require.NoError(t, err)
+	require.Equal(t, "connect-udp://proxy.example:443?sni=decoy.example", sanitized)
+}
@@ -16,8 +16,11 @@ package configurl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/Jigsaw-Code/outline-sdk/x/disorder"
@@ -30,6 +33,13 @@ func registerDisorderDialer(r TypeRegistry[transport.StreamDialer], typeID strin
 			return nil, err
 		}
 		disorderPacketNStr := config.URL.Opaque
+		if strings.Contains(disorderPacketNStr, "=") {
+			packets, hopLimit, err := parseDisorderOptions(disorderPacketNStr)
+			if err != nil {
+				return nil, err
+			}
+			return disorder.NewMultiPacketStreamDialer(sd, packets, hopLimit)
+		}
 		disorderPacketN, err := strconv.Atoi(disorderPacketNStr)
 		if err != nil {
 			return nil, fmt.Errorf("disoder: could not parse splice position: %v", err)
@@ -37,3 +47,42 @@ func registerDisorderDialer(r TypeRegistry[transport.StreamDialer], typeID strin
 		return disorder.NewStreamDialer(sd, disorderPacketN)
 	})
 }
+
+// parseDisorderOptions parses the "packets=[PACKET_LIST]&ttl=[TTL]" disorder config.
+func parseDisorderOptions(query string) ([]int, int, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	var packets []int
+	hopLimit := 1
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "packets":
+			if len(values) != 1 {
+				return nil, 0, fmt.Errorf("packets option must has one value, found %v", len(values))
+			}
+			for _, packetStr := range strings.Split(values[0], ",") {
+				packet, err := strconv.Atoi(strings.TrimSpace(packetStr))
+				if err != nil {
+					return nil, 0, fmt.Errorf("disorder: could not parse packet number: %v", err)
+				}
+				packets = append(packets, packet)
+			}
+		case "ttl":
+			if len(values) != 1 {
+				return nil, 0, fmt.Errorf("ttl option must has one value, found %v", len(values))
+			}
+			hopLimit, err = strconv.Atoi(values[0])
+			if err != nil {
+				return nil, 0, fmt.Errorf("disorder: could not parse ttl: %v", err)
+			}
+		default:
+			return nil, 0, fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if len(packets) == 0 {
+		return nil, 0, errors.New("packets option is required")
+	}
+	return packets, hopLimit, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisorder_Options(t *testing.T) {
+	packets, hopLimit, err := parseDisorderOptions("packets=0,2&ttl=2")
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 2}, packets)
+	require.Equal(t, 2, hopLimit)
+
+	packets, hopLimit, err = parseDisorderOptions("packets=1")
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, packets)
+	require.Equal(t, 1, hopLimit)
+}
+
+func TestDisorder_InvalidOptions(t *testing.T) {
+	for _, query := range []string{"ttl=2", "packets=a", "packets=1&ttl=x", "packets=1&foo=bar"} {
+		_, _, err := parseDisorderOptions(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestDisorder_Provider(t *testing.T) {
+	providers := NewDefaultProviders()
+	_, err := providers.NewStreamDialer(context.Background(), "disorder:packets=0,2&ttl=2")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "disorder:1")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "disorder:packets=0&ttl=0")
+	require.Error(t, err)
+}
@@ -87,10 +87,7 @@ func newDO53Resolver(config url.URL, sd transport.StreamDialer, pd transport.Pac
 	if address == "" {
 		return nil, errors.New("must set an address")
 	}
-	_, _, err = net.SplitHostPort(address)
-	if err != nil {
-		address = net.JoinHostPort(address, "53")
-	}
+	address = ensurePort(address, "53")
 	udpResolver := dns.NewUDPResolver(pd, address)
 	tcpResolver := dns.NewTCPResolver(sd, address)
 	resolver := dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
@@ -139,11 +136,34 @@ func newDOHResolver(config url.URL, sd transport.StreamDialer) (dns.Resolver, er
 	if address == "" {
 		address = name
 	}
+	address = ensurePort(address, "443")
 	_, port, err := net.SplitHostPort(address)
 	if err != nil {
-		address = net.JoinHostPort(address, "443")
-		port = "443"
+		return nil, fmt.Errorf("invalid address: %w", err)
 	}
 	dohURL := url.URL{Scheme: "https", Host: net.JoinHostPort(name, port), Path: "/dns-query"}
 	return dns.NewHTTPSResolver(sd, address, dohURL.String()), nil
 }
+
+// ensurePort returns the address with defaultPort if it has no port. The host may be a bracketed IPv6 literal,
+// with or without a zone.
+func ensurePort(address string, defaultPort string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		// Failed to parse as host:port. Assume address is a host.
+		return net.JoinHostPort(trimIPv6Brackets(address), defaultPort)
+	}
+	if port == "" {
+		return net.JoinHostPort(host, defaultPort)
+	}
+	return address
+}
+
+// trimIPv6Brackets removes the brackets around an IPv6 literal, as in "[::1]", so it can be passed to
+// [net.JoinHostPort] without doubling them.
+func trimIPv6Brackets(host string) string {
+	if len(host) >= 2 && host[0] == '[' && host[len(host)-1] == ']' {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
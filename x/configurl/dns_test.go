@@ -0,0 +1,32 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ensurePort(t *testing.T) {
+	require.Equal(t, "example.com:53", ensurePort("example.com", "53"))
+	require.Equal(t, "example.com:853", ensurePort("example.com:853", "53"))
+	require.Equal(t, "[2001:db8::1]:53", ensurePort("2001:db8::1", "53"))
+	require.Equal(t, "[2001:db8::1]:53", ensurePort("[2001:db8::1]", "53"))
+	require.Equal(t, "[2001:db8::1]:53", ensurePort("[2001:db8::1]:", "53"))
+	require.Equal(t, "[2001:db8::1]:853", ensurePort("[2001:db8::1]:853", "53"))
+	require.Equal(t, "[fe80::1%eth0]:53", ensurePort("fe80::1%eth0", "53"))
+	require.Equal(t, "[fe80::1%eth0]:53", ensurePort("[fe80::1%eth0]", "53"))
+}
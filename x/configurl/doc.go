@@ -32,6 +32,27 @@ The configuration string is composed of parts separated by the `|` symbol, which
 For example, `A|B` means dialer `B` takes dialer `A` as its input.
 An empty string represents the direct TCP/UDP dialer, and is used as the input to the first cofigured dialer.
 
+The whitespace around each part, including line breaks, is ignored, so you can write one part per line. Lines starting
+with `#` are comments and are ignored. A part starting with `#` is also a comment, up to the next `|`. Other `#` are
+not comments, since they start the fragment of the URL. For example:
+
+	# Shadowsocks server.
+	ss://[USERINFO]@[HOST]:[PORT]
+	# Fragment the TLS Client Hello.
+	| tlsfrag:1
+
+For complex configs, you can also use a structured format in YAML or JSON, with [ParseYAMLConfig] or
+[ProviderContainer.NewStreamDialerFromYAML]. The config is the list of parts, where each part is a string in the pipe
+format or a map with the type and the value or parameters of the part. Nested configs can be lists too:
+
+	# Innermost part first.
+	- ss://[USERINFO]@[HOST]:[PORT]
+	- type: rotate
+	  params:
+	    config:
+	      - [split:2, tlsfrag:1]
+	      - {type: tlsfrag, value: 2}
+
 Each dialer configuration follows a URL format, where the scheme defines the type of Dialer. Supported formats are described below.
 
 # Proxy Protocols
@@ -46,6 +67,44 @@ SOCKS5 proxy (works with both stream and packet dialers, package [github.com/Jig
 
 USERINFO field is optional and only required if username and password authentication is used. It is in the format of username:password.
 
+When used as a packet dialer or listener, the client uses the SOCKS5 UDP ASSOCIATE command, so the proxy must support
+UDP. The association is set up over the stream connection to the proxy, and the UDP packets are sent to the relay
+address the proxy returns, using the input packet dialer. For example, a DNS-over-UDP test through a proxy can use
+"socks5://[HOST]:[PORT]" as the packet dialer config.
+
+SOCKS5-over-TLS proxy (works with both stream and packet dialers)
+
+Like socks5, but the stream connections to the proxy are wrapped in TLS, validated against HOST. The UDP packets are
+not encrypted.
+
+	socks5+tls://[USERINFO]@[HOST]:[PORT]
+
+HTTP CONNECT proxy (streams only, package [github.com/Jigsaw-Code/outline-sdk/x/httpconnect])
+
+Tunnels each stream through an HTTP proxy with the CONNECT method. USERINFO is optional, in the format of
+username:password, and is sent with the Basic scheme in the Proxy-Authorization header. Dialing fails with
+[github.com/Jigsaw-Code/outline-sdk/x/httpconnect.ErrProxyAuthRequired] if the proxy responds with 407.
+
+	connect://[USERINFO]@[HOST]:[PORT]
+
+MASQUE CONNECT-UDP proxy (packets only, package [github.com/Jigsaw-Code/outline-sdk/x/quic])
+
+Proxies UDP over HTTP/3 datagrams, as per RFC 9298. Each packet connection uses a new QUIC connection to the proxy,
+over the input packet dialer. The template parameter is the path of the URI template for the requests, with the
+{target_host} and {target_port} variables, and must be URL-encoded. It defaults to
+"/.well-known/masque/udp/{target_host}/{target_port}/". The sni, certname and alpn parameters work as in the QUIC
+transport.
+
+	connect-udp://[HOST]:[PORT]?template=[TEMPLATE]&sni=[SNI]&certname=[CERT_NAME]
+
+Tor onion services (streams only)
+
+Routes destinations ending in ".onion" through the Tor SOCKS5 proxy at the given host:port, and all other destinations
+through the input dialer. The onion name is sent to Tor unresolved, since onion names must never be resolved with
+local DNS. The input dialer is also used to connect to the Tor proxy.
+
+	onion:socks=[HOST]:[PORT]
+
 # Transports
 
 TLS transport (currently streams only, package [github.com/Jigsaw-Code/outline-sdk/transport/tls])
@@ -55,9 +114,91 @@ The certname parameter defines what name to validate against the server certific
 
 	tls:sni=[SNI]&certname=[CERT_NAME]
 
-WebSockets
+The alpn parameter is a comma-separated list of protocols to offer with [ALPN], in preference order, like h2,http/1.1.
+It's empty by default. The connections report the protocol selected by the server with
+[github.com/Jigsaw-Code/outline-sdk/transport/tls.ALPNReporter].
+
+	tls:alpn=[PROTOCOL_LIST]
+
+You can also shape the Client Hello fingerprint. The minver and maxver parameters set the TLS version range
+(1.0, 1.1, 1.2 or 1.3). The ciphers parameter is a comma-separated list of TLS 1.0-1.2 cipher suite names
+(e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256). The curves parameter is a comma-separated list of key exchange
+curves in preference order (X25519, P256, P384, P521). The sessiontickets parameter can be set to false to
+not send the session ticket extension.
+
+	tls:minver=[VERSION]&maxver=[VERSION]&ciphers=[CIPHER_LIST]&curves=[CURVE_LIST]&sessiontickets=[BOOL]
+
+The connections of a TLS transport share a session cache, so that later connections to the same server can resume the
+session with an abbreviated handshake. Set resume to 0 to disable resumption and the session ticket extension, for
+instance to keep a consistent Client Hello fingerprint. With ECH, the session ticket is only sent encrypted.
+Early data (0-RTT) is not supported.
+
+	tls:resume=[BOOL]
+
+uTLS transport (streams only, package [github.com/Jigsaw-Code/outline-sdk/x/utls])
+
+Like the TLS transport, but sends a Client Hello that mimics a browser, to resist fingerprinting. The profile
+parameter is required and selects the browser to mimic (e.g. chrome, chrome_106, firefox_105, safari, ios_14, edge,
+randomized). See [github.com/Jigsaw-Code/outline-sdk/x/utls.Profiles] for the full list. The sni and certname
+parameters work as in the TLS transport.
+
+	utls:profile=[PROFILE]&sni=[SNI]&certname=[CERT_NAME]
+
+QUIC transport (streams only, package [github.com/Jigsaw-Code/outline-sdk/x/quic])
+
+Sends each stream over a new QUIC connection, using the input packet dialer for the QUIC packets. The sni and certname
+parameters work as in the TLS transport. The alpn parameter is a comma-separated list of protocols to negotiate,
+which defaults to h3.
+
+	quic:sni=[SNI]&certname=[CERT_NAME]&alpn=[PROTOCOL_LIST]
+
+WebSockets (streams and packets, package [github.com/Jigsaw-Code/outline-sdk/x/websocket])
+
+Sends streams and packets over WebSocket connections to the tcp_path and udp_path of the server, as run by the
+ws2endpoint example. To keep idle connections alive and detect dead servers, set ping_interval to send ping frames
+periodically, as a Go duration like "30s". A connection fails if nothing is received within pong_timeout after a
+ping is due, which defaults to the ping interval. See [github.com/Jigsaw-Code/outline-sdk/x/websocket.KeepAliveConn].
+
+	ws:tcp_path=[PATH]&udp_path=[PATH]&ping_interval=[DURATION]&pong_timeout=[DURATION]
+
+The path parameter sets the path for both streams and packets, unless tcp_path or udp_path are also set. The host parameter
+sets the Host header of the handshake request, instead of the dialed address, which CDNs use to route the request.
+The data is sent in binary frames.
+
+	ws:path=[PATH]&host=[HOST]
+
+To connect to a Shadowsocks server that uses a WebSocket plugin like v2ray-plugin, put the WebSocket transport before
+the Shadowsocks one. The Shadowsocks connection then goes over a WebSocket connection to the Shadowsocks server address.
+Add the TLS transport first if the plugin runs in TLS mode:
+
+	ws:path=/|ss://[USERINFO]@[HOST]:[PORT]
+	tls:sni=[HOST]|ws:path=/&host=[HOST]|ss://[USERINFO]@[HOST]:[PORT]
+
+Padding (streams only, package [github.com/Jigsaw-Code/outline-sdk/transport/pad])
+
+Sends the streams in frames of SIZE bytes, padding or splitting the writes as needed, to hide the size of the writes from
+traffic analysis. The size can be a range MIN-MAX, in which case each frame size is picked at random. It needs a
+cooperating server that strips the padding, see [github.com/Jigsaw-Code/outline-sdk/transport/pad.WrapConn] for the framing.
+
+	pad:size=[SIZE]
+	pad:size=[MIN]-[MAX]
+
+Obfuscation (streams only, package [github.com/Jigsaw-Code/outline-sdk/transport/obfs])
 
-	ws:tcp_path=[PATH]&udp_path=[PATH]
+Scrambles all the bytes of the streams with a ChaCha20 keystream derived from KEY, to remove the plaintext signatures
+of the protocol it carries. It's NOT authenticated encryption: it doesn't protect the integrity of the data or
+authenticate the server, so use it on top of a secure protocol if needed. It needs a cooperating server with the same
+key, see [github.com/Jigsaw-Code/outline-sdk/transport/obfs.WrapConn].
+
+	obfs:key=[KEY]
+
+UNIX socket handoff (streams only, see [github.com/Jigsaw-Code/outline-sdk/transport.NewUnixHandoffStreamDialer])
+
+Hands off each connection to a local helper process listening on the UNIX domain socket at PATH. On Linux, the helper
+passes the connected socket back as a file descriptor (SCM_RIGHTS). On other platforms, the helper relays the bytes.
+It must be the first dialer in the config, since it doesn't use an input dialer.
+
+	unix:path=[PATH]
 
 # DNS Protection
 
@@ -82,9 +223,54 @@ This dialer configuration is helpful for testing and development or if you need
 resolution.
 The host parameter, if not empty, specifies the host to dial instead of the original host.
 The port parameter, if not empty, specifies the port to dial instead of the original port.
+If only one of them is set, the other part of the original address is kept.
+The host can be an IPv6 address, with or without brackets. An IPv6 zone must be URL-encoded, as in "fe80::1%25eth0".
 
 	override:host=[HOST]&port=[PORT]
 
+The host can be a template that references the original host as ${host}, for example to prepend a subdomain with
+"host=cdn.${host}". If the original address has no port, the override fails, unless the port parameter is set, in
+which case the whole original address is taken as the host.
+
+The do53 and doh dialers only pass IP addresses to their input dialer, so no host name is resolved by the system. When
+combining them with an address override, put the override after the resolution, as in
+"doh:name=[NAME]&address=[IP]|override:host=[HOST]", so the overridden host is also resolved with DNS-over-HTTPS.
+With the override first, its host is passed to the input dialer, which may resolve it with the system resolver.
+Use an IP address for the DNS server address, since it's dialed with the input dialer.
+
+# Routing
+
+Routing by destination (streams only)
+
+Picks the transport for each connection based on the destination host. Each rule has a comma-separated list of domains
+and CIDRs, and the config to use for the matching destinations. A domain matches itself and its subdomains. A CIDR
+only matches IP address destinations, since host names are not resolved for routing. The rules are checked in order,
+and the first match wins. Destinations that match no rule use the default config. The sub-configs use the input
+dialer as their base, and an empty sub-config uses the input dialer directly. The rule and default values must be
+URL-encoded, since the sub-configs have characters like "|", "&" and "=".
+
+	routing:rule=[MATCHES1]=[CONFIG1]&rule=[MATCHES2]=[CONFIG2]&...&default=[CONFIG]
+
+For example, to connect directly to local destinations and the private network, and use Shadowsocks for everything
+else, URL-encode the values of:
+
+	routing:rule=lan,10.0.0.0/8,192.168.0.0/16=&default=ss://[USERINFO]@[HOST]:[PORT]
+
+Rotation among configs (streams only)
+
+Picks one of several configs for each new connection, for example to spread the connections over different Shadowsocks
+servers or split strategies. The round-robin strategy, the default, uses the configs in order. The random strategy
+picks a config at random, with the probability given by the comma-separated weights, one per config, or with equal
+probability if omitted. If fallback is true, a failed dial is retried with the next configs in order, until one
+succeeds or all fail. As with routing, the sub-configs use the input dialer as their base and must be URL-encoded.
+
+	rotate:config=[CONFIG1]&config=[CONFIG2]&...&strategy=[round-robin|random]&weights=[WEIGHT1],[WEIGHT2],...&fallback=[BOOL]
+
+For example, to use one Shadowsocks server three times as often as the other, and try the other if the dial fails,
+URL-encode the config values of:
+
+	rotate:config=ss://[USERINFO]@[HOST1]:[PORT1]&config=ss://[USERINFO]@[HOST2]:[PORT2]&strategy=random&weights=3,1&fallback=true
+
 # Packet manipulation
 
 These strategies manipulate packets to bypass SNI-based blocking.
@@ -95,6 +281,29 @@ It takes a list of count*length pairs meaning splitting the sequence in count se
 
 	split:[COUNT1]*[LENGTH1],[COUNT2]*[LENGTH2],...
 
+A length can also be a range MIN-MAX, in which case each segment length is picked at random between MIN and MAX
+(inclusive) on each connection, so that the censor can't learn a static split signature. For example, "split:3-10"
+splits at a random offset between 3 and 10 bytes, and "split:2*1-5,100" makes two random splits followed by a fixed one.
+
+	split:[COUNT1]*[MIN1]-[MAX1],...
+
+The split transport splits the TCP writes, while the TLS fragmentation (tlsfrag) below splits the Client Hello into
+TLS records. They compose: "split:3-10|tlsfrag:1" first fragments the Client Hello into records, then splits the
+resulting stream at a random offset, so the record boundaries and the TCP segment boundaries differ.
+
+Alternatively, it can split the first write immediately before the first occurrence of a byte pattern, such as the HTTP
+Host header, regardless of its position. The pattern is a URL-encoded string, or hexadecimal bytes if it starts with "0x".
+If the pattern is not found in the first write, it splits after FALLBACK bytes instead, or not at all if omitted.
+
+	split:pattern=[PATTERN]&fallback=[FALLBACK]
+
+Only the writes to the server are split, not the data from the server. To make the fragments go out in separate
+packets that arrive apart, add a delay between them, as a Go duration like "10ms". Closing the connection stops the
+wait.
+
+	split:[SPLITS]&delay=[DURATION]
+	split:pattern=[PATTERN]&fallback=[FALLBACK]&delay=[DURATION]
+
 TLS fragmentation (streams only, package [github.com/Jigsaw-Code/outline-sdk/transport/tlsfrag]).
 
 The Client Hello record payload will be split into two fragments of size LENGTH and len(payload)-LENGTH if LENGTH>0.
@@ -115,11 +324,36 @@ connection.
 
 	disorder:[PACKET_NUMBER]
 
+To disorder multiple packets, list their numbers in the packets parameter. The ttl parameter sets the temporary TTL
+(IPv4) or hop limit (IPv6), which defaults to 1. Some routes need a TTL of 2 or 3 for the packets to be dropped past
+the network filter.
+
+	disorder:packets=[PACKET_NUMBER1],[PACKET_NUMBER2],...&ttl=[TTL]
+
 PACKET_NUMBER: The number of writes before the disorder action occurs. The
 disorder action triggers when the number of writes equals PACKET_NUMBER. If set
 to 0 (default), the disorder happens on the first write. If set to 1, it happens
 on the second write, and so on.
 
+Fake packets (streams only, Linux only, package [github.com/Jigsaw-Code/outline-sdk/x/tamper])
+
+Sends a decoy before the first bytes of the stream, with the TTL (IPv4) or hop limit (IPv6) set to TTL, so that the
+network filter sees the decoy, but the server doesn't. The server then gets the real data from the TCP
+re-transmission. The FAKE payload, typically a TLS Client Hello for an allowed domain, is a URL-encoded string, or
+hexadecimal bytes if it starts with "0x". Pick a TTL that is large enough for the decoy to reach the network filter,
+but small enough to not reach the server. On other platforms, this returns an error.
+
+	tamper:fake=[FAKE]&ttl=[TTL]
+
+HTTP Host rewriting (streams only, package [github.com/Jigsaw-Code/outline-sdk/x/httpheader])
+
+Replaces the Host header of the first plain HTTP request on each connection with HOST, to evade Host-based blocking.
+The request header is buffered until complete, so it's rewritten even if the request is split across multiple writes.
+Streams that don't start with an HTTP request, and the data after the first request header, pass through untouched.
+The server must accept the request with the decoy Host.
+
+	httpheader:host=[HOST]
+
 # Examples
 
 Packet splitting - To split outgoing streams on bytes 2 and 123, you can use:
@@ -141,7 +375,7 @@ of the resolver itself. This can be combines with a TCP split or TLS Record Frag
 
 SOCKS5-over-TLS, with domain-fronting - To tunnel SOCKS5 over TLS, and set the SNI to decoy.example.com, while still validating against your host name, use:
 
-	tls:sni=decoy.example.com&certname=[HOST]|socks5:[HOST]:[PORT]
+	tls:sni=decoy.example.com&certname=[HOST]|socks5://[HOST]:[PORT]
 
 Onion Routing with Shadowsocks - To route your traffic through three Shadowsocks servers, similar to [Onion Routing], use:
 
@@ -153,6 +387,16 @@ DPI Evasion - To add packet splitting to a Shadowsocks server for enhanced DPI e
 
 	split:2|ss://[USERINFO]@[HOST]:[PORT]
 
+Wrapping an existing dialer - The configs use the base instances of the [ProviderContainer] as the input to the first
+configured dialer, and an empty config returns the base instance itself. To layer a config on top of your own stream
+or packet dialer, which replaces the x/config WrapStreamDialer function, set the base instances:
+
+	p := configurl.NewDefaultProviders()
+	p.StreamDialers.BaseInstance = myStreamDialer
+	p.PacketDialers.BaseInstance = myPacketDialer
+	streamDialer, err := p.NewStreamDialer(ctx, "split:2|ss://[USERINFO]@[HOST]:[PORT]")
+	packetDialer, err := p.NewPacketDialer(ctx, "ss://[USERINFO]@[HOST]:[PORT]")
+
 Defining custom strategies - You can define your custom strategy by implementing and registering [BuildFunc[ObjectType]] functions:
 
 	// Create new config parser.
@@ -172,5 +416,6 @@ Defining custom strategies - You can define your custom strategy by implementing
 	dialer, err := p.NewStreamDialer(context.Background(), "custom://config")
 
 [Onion Routing]: https://en.wikipedia.org/wiki/Onion_routing
+[ALPN]: https://datatracker.ietf.org/doc/html/rfc7301
 */
 package configurl
@@ -0,0 +1,64 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/httpheader"
+)
+
+func registerHTTPHeaderStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		sd, err := newSD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		host, err := parseHTTPHeaderOptions(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		return httpheader.NewStreamDialer(sd, host)
+	})
+}
+
+// parseHTTPHeaderOptions parses the "host=[HOST]" httpheader config.
+func parseHTTPHeaderOptions(query string) (string, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+	var host string
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "host":
+			if len(values) != 1 {
+				return "", fmt.Errorf("host option must has one value, found %v", len(values))
+			}
+			host = values[0]
+		default:
+			return "", fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if host == "" {
+		return "", errors.New("host option is required")
+	}
+	return host, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHeader_Options(t *testing.T) {
+	host, err := parseHTTPHeaderOptions("host=decoy.com")
+	require.NoError(t, err)
+	require.Equal(t, "decoy.com", host)
+
+	for _, query := range []string{"", "host=", "host=a&host=b", "foo=bar", "%"} {
+		_, err := parseHTTPHeaderOptions(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestHTTPHeader_StreamDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	hosts := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			hosts <- err.Error()
+			return
+		}
+		hosts <- req.Host
+	}()
+
+	sd, err := NewDefaultProviders().NewStreamDialer(context.Background(), "httpheader:host=decoy.com")
+	require.NoError(t, err)
+	conn, err := sd.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: "))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("example.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "decoy.com", <-hosts)
+}
@@ -16,6 +16,7 @@ package configurl
 
 import (
 	"context"
+	"log/slog"
 	"net/url"
 	"strings"
 
@@ -42,16 +43,35 @@ func NewProviderContainer() *ProviderContainer {
 // RegisterDefaultProviders registers a set of default providers with the providers in [ProviderContainer].
 func RegisterDefaultProviders(c *ProviderContainer) *ProviderContainer {
 	// Please keep the list in alphabetical order.
+	registerHTTPConnectStreamDialer(&c.StreamDialers, "connect", c.StreamDialers.NewInstance)
+	registerConnectUDPPacketDialer(&c.PacketDialers, "connect-udp", c.PacketDialers.NewInstance)
+
 	registerDisorderDialer(&c.StreamDialers, "disorder", c.StreamDialers.NewInstance)
 	registerDO53StreamDialer(&c.StreamDialers, "do53", c.StreamDialers.NewInstance, c.PacketDialers.NewInstance)
 	registerDOHStreamDialer(&c.StreamDialers, "doh", c.StreamDialers.NewInstance)
 
+	registerHTTPHeaderStreamDialer(&c.StreamDialers, "httpheader", c.StreamDialers.NewInstance)
+
+	registerObfsStreamDialer(&c.StreamDialers, "obfs", c.StreamDialers.NewInstance)
+
+	registerOnionStreamDialer(&c.StreamDialers, "onion", c.StreamDialers.NewInstance)
+
 	registerOverrideStreamDialer(&c.StreamDialers, "override", c.StreamDialers.NewInstance)
 	registerOverridePacketDialer(&c.PacketDialers, "override", c.PacketDialers.NewInstance)
 
+	registerPadStreamDialer(&c.StreamDialers, "pad", c.StreamDialers.NewInstance)
+
+	registerQUICStreamDialer(&c.StreamDialers, "quic", c.PacketDialers.NewInstance)
+
+	registerRotateStreamDialer(&c.StreamDialers, "rotate", c.StreamDialers.NewInstance)
+	registerRoutingStreamDialer(&c.StreamDialers, "routing", c.StreamDialers.NewInstance)
+
 	registerSOCKS5StreamDialer(&c.StreamDialers, "socks5", c.StreamDialers.NewInstance)
 	registerSOCKS5PacketDialer(&c.PacketDialers, "socks5", c.StreamDialers.NewInstance, c.PacketDialers.NewInstance)
 	registerSOCKS5PacketListener(&c.PacketListeners, "socks5", c.StreamDialers.NewInstance, c.PacketDialers.NewInstance)
+	registerSOCKS5StreamDialer(&c.StreamDialers, "socks5+tls", c.StreamDialers.NewInstance)
+	registerSOCKS5PacketDialer(&c.PacketDialers, "socks5+tls", c.StreamDialers.NewInstance, c.PacketDialers.NewInstance)
+	registerSOCKS5PacketListener(&c.PacketListeners, "socks5+tls", c.StreamDialers.NewInstance, c.PacketDialers.NewInstance)
 
 	registerSplitStreamDialer(&c.StreamDialers, "split", c.StreamDialers.NewInstance)
 
@@ -59,21 +79,52 @@ func RegisterDefaultProviders(c *ProviderContainer) *ProviderContainer {
 	registerShadowsocksPacketDialer(&c.PacketDialers, "ss", c.PacketDialers.NewInstance)
 	registerShadowsocksPacketListener(&c.PacketListeners, "ss", c.PacketDialers.NewInstance)
 
+	registerTamperStreamDialer(&c.StreamDialers, "tamper", c.StreamDialers.NewInstance)
+
 	registerTLSStreamDialer(&c.StreamDialers, "tls", c.StreamDialers.NewInstance)
 
 	registerTLSFragStreamDialer(&c.StreamDialers, "tlsfrag", c.StreamDialers.NewInstance)
 
+	registerUnixHandoffStreamDialer(&c.StreamDialers, "unix")
+
+	registerUTLSStreamDialer(&c.StreamDialers, "utls", c.StreamDialers.NewInstance)
+
 	registerWebsocketStreamDialer(&c.StreamDialers, "ws", c.StreamDialers.NewInstance)
 	registerWebsocketPacketDialer(&c.PacketDialers, "ws", c.StreamDialers.NewInstance)
 
 	return c
 }
 
+// SetLogger sets the logger of all the providers in the container. See [ExtensibleProvider.Logger].
+// A nil logger disables the logging.
+func (p *ProviderContainer) SetLogger(logger *slog.Logger) {
+	p.StreamDialers.Logger = logger
+	p.PacketDialers.Logger = logger
+	p.PacketListeners.Logger = logger
+}
+
 // NewDefaultProviders creates a [ProviderContainer] with a set of default providers already registered.
 func NewDefaultProviders() *ProviderContainer {
 	return RegisterDefaultProviders(NewProviderContainer())
 }
 
+// ProviderDescription lists the config types registered in a [ProviderContainer] for each kind of object.
+type ProviderDescription struct {
+	StreamDialers   []string
+	PacketDialers   []string
+	PacketListeners []string
+}
+
+// Describe returns the config types currently registered for each kind of object, in alphabetical order.
+// You can use it to show the valid options to users, or to check configs before building them.
+func (p *ProviderContainer) Describe() ProviderDescription {
+	return ProviderDescription{
+		StreamDialers:   p.StreamDialers.RegisteredTypes(),
+		PacketDialers:   p.PacketDialers.RegisteredTypes(),
+		PacketListeners: p.PacketListeners.RegisteredTypes(),
+	}
+}
+
 // NewStreamDialer creates a [transport.StreamDialer] according to the config text.
 func (p *ProviderContainer) NewStreamDialer(ctx context.Context, configText string) (transport.StreamDialer, error) {
 	config, err := ParseConfig(configText)
@@ -102,6 +153,9 @@ func (p *ProviderContainer) NewPacketListener(ctx context.Context, configText st
 }
 
 // SanitizeConfig removes sensitive information from the given config so it can be safely be used in logging and debugging.
+// It replaces the user info of Shadowsocks, SOCKS5 and HTTP CONNECT URLs with "REDACTED", as well as the Shadowsocks prefix, and
+// drops the URL fragments, in all the parts, including the nested configs. Parts of unknown types are replaced by
+// "[TYPE]://UNKNOWN", since they may have secrets.
 func SanitizeConfig(configStr string) (string, error) {
 	config, err := ParseConfig(configStr)
 	if err != nil {
@@ -123,12 +177,22 @@ func SanitizeConfig(configStr string) (string, error) {
 			if err != nil {
 				return "", err
 			}
-		case "socks5":
+		case "rotate":
+			part, err = sanitizeRotateURL(config.URL)
+			if err != nil {
+				return "", err
+			}
+		case "routing":
+			part, err = sanitizeRoutingURL(config.URL)
+			if err != nil {
+				return "", err
+			}
+		case "connect", "socks5", "socks5+tls":
 			part, err = sanitizeSOCKS5URL(&config.URL)
 			if err != nil {
 				return "", err
 			}
-		case "override", "split", "tls", "tlsfrag":
+		case "connect-udp", "disorder", "do53", "doh", "httpheader", "onion", "override", "pad", "quic", "split", "tamper", "tls", "tlsfrag", "unix", "utls":
 			// No sanitization needed
 			part = config.URL.String()
 		default:
@@ -146,9 +210,11 @@ func SanitizeConfig(configStr string) (string, error) {
 
 func sanitizeSOCKS5URL(u *url.URL) (string, error) {
 	const redactedPlaceholder = "REDACTED"
+	// Replace both the username and the password.
 	if u.User != nil {
 		u.User = url.User(redactedPlaceholder)
-		return u.String(), nil
 	}
+	u.Fragment = ""
+	u.RawFragment = ""
 	return u.String(), nil
 }
@@ -0,0 +1,47 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport/obfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfs_Options(t *testing.T) {
+	key, err := parseObfsOptions("key=s3cr3t")
+	require.NoError(t, err)
+	expected, err := obfs.NewKey([]byte("s3cr3t"))
+	require.NoError(t, err)
+	require.Equal(t, expected, key)
+
+	for _, query := range []string{"", "key=", "key=a&key=b", "foo=bar"} {
+		_, err := parseObfsOptions(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestObfs_StreamDialer(t *testing.T) {
+	_, err := NewDefaultProviders().NewStreamDialer(context.Background(), "obfs:key=s3cr3t")
+	require.NoError(t, err)
+}
+
+func TestObfs_Sanitize(t *testing.T) {
+	sanitized, err := SanitizeConfig("obfs:key=s3cr3t")
+	require.NoError(t, err)
+	require.Equal(t, "obfs://UNKNOWN", sanitized)
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/transport/socks5"
+)
+
+func registerOnionStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		sd, err := newSD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		socksAddress, err := parseOnionSOCKSAddress(config.URL)
+		if err != nil {
+			return nil, err
+		}
+		torClient, err := socks5.NewClient(&transport.StreamDialerEndpoint{Dialer: sd, Address: socksAddress})
+		if err != nil {
+			return nil, err
+		}
+		return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, fmt.Errorf("address is not valid host:port: %w", err)
+			}
+			if isOnionHost(host) {
+				// The SOCKS5 client sends the domain name as is, so Tor resolves it.
+				return torClient.DialStream(ctx, addr)
+			}
+			return sd.DialStream(ctx, addr)
+		}), nil
+	})
+}
+
+// isOnionHost returns whether host is a Tor onion service name, as per https://datatracker.ietf.org/doc/html/rfc7686.
+func isOnionHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return strings.HasSuffix(host, ".onion")
+}
+
+func parseOnionSOCKSAddress(configURL url.URL) (string, error) {
+	values, err := url.ParseQuery(configURL.Opaque)
+	if err != nil {
+		return "", err
+	}
+	socksAddress := ""
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "socks":
+			if len(values) != 1 {
+				return "", fmt.Errorf("socks option must has one value, found %v", len(values))
+			}
+			socksAddress = values[0]
+		default:
+			return "", fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if socksAddress == "" {
+		return "", errors.New("socks option is required")
+	}
+	if _, _, err := net.SplitHostPort(socksAddress); err != nil {
+		return "", fmt.Errorf("socks address is not valid host:port: %w", err)
+	}
+	return socksAddress, nil
+}
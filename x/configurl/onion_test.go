@@ -0,0 +1,66 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnionStreamDialer(t *testing.T) {
+	var dialedAddrs []string
+	providers := NewDefaultProviders()
+	providers.StreamDialers.BaseInstance = transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		dialedAddrs = append(dialedAddrs, addr)
+		return nil, errors.New("not connected")
+	})
+	dialer, err := providers.NewStreamDialer(context.Background(), "onion:socks=127.0.0.1:9050")
+	require.NoError(t, err)
+
+	// Onion addresses go to the Tor SOCKS5 endpoint, not to local resolution.
+	_, err = dialer.DialStream(context.Background(), "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion:443")
+	require.Error(t, err)
+	require.Equal(t, []string{"127.0.0.1:9050"}, dialedAddrs)
+
+	// Everything else goes to the base dialer.
+	dialedAddrs = nil
+	_, err = dialer.DialStream(context.Background(), "example.com:443")
+	require.Error(t, err)
+	require.Equal(t, []string{"example.com:443"}, dialedAddrs)
+}
+
+func TestIsOnionHost(t *testing.T) {
+	require.True(t, isOnionHost("example.onion"))
+	require.True(t, isOnionHost("sub.Example.ONION."))
+	require.False(t, isOnionHost("onion"))
+	require.False(t, isOnionHost("example.onion.com"))
+	require.False(t, isOnionHost("example.com"))
+}
+
+func TestParseOnionSOCKSAddress(t *testing.T) {
+	config, err := ParseConfig("onion:")
+	require.NoError(t, err)
+	_, err = parseOnionSOCKSAddress(config.URL)
+	require.Error(t, err)
+
+	config, err = ParseConfig("onion:socks=localhost")
+	require.NoError(t, err)
+	_, err = parseOnionSOCKSAddress(config.URL)
+	require.Error(t, err)
+}
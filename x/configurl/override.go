@@ -18,7 +18,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/netip"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
@@ -64,6 +66,11 @@ func registerOverridePacketDialer(r TypeRegistry[transport.PacketDialer], typeID
 	})
 }
 
+// hostTemplateVar is replaced by the original host in the host override.
+const hostTemplateVar = "${host}"
+
+// newOverrideFromURL creates the address override function for the "host=[HOST]&port=[PORT]" override config.
+// Only the parts that are set are replaced, and the host may reference the original host with ${host}.
 func newOverrideFromURL(configURL url.URL) (func(string) (string, error), error) {
 	query := configURL.Opaque
 	values, err := url.ParseQuery(query)
@@ -77,27 +84,40 @@ func newOverrideFromURL(configURL url.URL) (func(string) (string, error), error)
 			if len(values) != 1 {
 				return nil, fmt.Errorf("host option must has one value, found %v", len(values))
 			}
-			hostOverride = values[0]
+			hostOverride = trimIPv6Brackets(values[0])
+			if strings.Contains(hostOverride, ":") && !strings.Contains(hostOverride, hostTemplateVar) {
+				if _, err := netip.ParseAddr(hostOverride); err != nil {
+					return nil, fmt.Errorf("host option must be a host name or IP address without port, found %v", values[0])
+				}
+			}
 		case "port":
 			if len(values) != 1 {
 				return nil, fmt.Errorf("port option must has one value, found %v", len(values))
 			}
 			portOverride = values[0]
+			if port, err := strconv.ParseUint(portOverride, 10, 16); err != nil || port == 0 {
+				return nil, fmt.Errorf("port option must be a number between 1 and 65535, found %v", portOverride)
+			}
 		default:
 			return nil, fmt.Errorf("unsupported option %v", key)
 		}
 	}
+	hasTemplate := strings.Contains(hostOverride, hostTemplateVar)
 	return func(address string) (string, error) {
 		// Optimization when we fully override the address.
-		if hostOverride != "" && portOverride != "" {
+		if hostOverride != "" && portOverride != "" && !hasTemplate {
 			return net.JoinHostPort(hostOverride, portOverride), nil
 		}
 		host, port, err := net.SplitHostPort(address)
 		if err != nil {
-			return "", fmt.Errorf("address is not valid host:port: %w", err)
+			if portOverride == "" {
+				return "", fmt.Errorf("address is not valid host:port: %w", err)
+			}
+			// The address has no port, but we are replacing it anyway. Take it as the host.
+			host = trimIPv6Brackets(address)
 		}
 		if hostOverride != "" {
-			host = hostOverride
+			host = strings.ReplaceAll(hostOverride, hostTemplateVar, host)
 		}
 		if portOverride != "" {
 			port = portOverride
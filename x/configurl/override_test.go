@@ -49,6 +49,79 @@ func Test_newOverrideFromURL(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "8.8.8.8:853", addr)
 	})
+	t.Run("IPv6 Override", func(t *testing.T) {
+		for _, host := range []string{"2001:db8::1", "[2001:db8::1]"} {
+			cfgUrl, err := url.Parse("override:host=" + url.QueryEscape(host))
+			require.NoError(t, err)
+			override, err := newOverrideFromURL(*cfgUrl)
+			require.NoError(t, err)
+			addr, err := override("www.youtube.com:443")
+			require.NoError(t, err)
+			require.Equal(t, "[2001:db8::1]:443", addr)
+		}
+	})
+	t.Run("IPv6 Zone Override", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:host=fe80::1%25eth0&port=853")
+		require.NoError(t, err)
+		override, err := newOverrideFromURL(*cfgUrl)
+		require.NoError(t, err)
+		addr, err := override("dns.google:53")
+		require.NoError(t, err)
+		require.Equal(t, "[fe80::1%eth0]:853", addr)
+	})
+	t.Run("Port Override keeps IPv6 zone", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:port=853")
+		require.NoError(t, err)
+		override, err := newOverrideFromURL(*cfgUrl)
+		require.NoError(t, err)
+		addr, err := override("[fe80::1%eth0]:53")
+		require.NoError(t, err)
+		require.Equal(t, "[fe80::1%eth0]:853", addr)
+	})
+	t.Run("Host with port", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:host=example.com:443")
+		require.NoError(t, err)
+		_, err = newOverrideFromURL(*cfgUrl)
+		require.Error(t, err)
+	})
+	t.Run("Host Template", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:host=cdn.${host}")
+		require.NoError(t, err)
+		override, err := newOverrideFromURL(*cfgUrl)
+		require.NoError(t, err)
+		addr, err := override("example.com:443")
+		require.NoError(t, err)
+		require.Equal(t, "cdn.example.com:443", addr)
+	})
+	t.Run("Host Template with Port", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:host=${host}.front.example&port=8443")
+		require.NoError(t, err)
+		override, err := newOverrideFromURL(*cfgUrl)
+		require.NoError(t, err)
+		addr, err := override("www:443")
+		require.NoError(t, err)
+		require.Equal(t, "www.front.example:8443", addr)
+	})
+	t.Run("Port Override without original port", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:port=853")
+		require.NoError(t, err)
+		override, err := newOverrideFromURL(*cfgUrl)
+		require.NoError(t, err)
+		addr, err := override("dns.google")
+		require.NoError(t, err)
+		require.Equal(t, "dns.google:853", addr)
+		addr, err = override("[2001:db8::1]")
+		require.NoError(t, err)
+		require.Equal(t, "[2001:db8::1]:853", addr)
+	})
+	t.Run("Host Override without original port", func(t *testing.T) {
+		cfgUrl, err := url.Parse("override:host=www.google.com")
+		require.NoError(t, err)
+		override, err := newOverrideFromURL(*cfgUrl)
+		require.NoError(t, err)
+		_, err = override("www.youtube.com")
+		require.Error(t, err)
+	})
 	t.Run("Invalid address", func(t *testing.T) {
 		t.Run("Host Override", func(t *testing.T) {
 			cfgUrl, err := url.Parse("override:host=www.google.com")
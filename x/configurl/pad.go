@@ -0,0 +1,76 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/transport/pad"
+)
+
+func registerPadStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		sd, err := newSD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		minSize, maxSize, err := parsePadOptions(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		return pad.NewStreamDialer(sd, minSize, maxSize)
+	})
+}
+
+// parsePadOptions parses the "size=[SIZE]" or "size=[MIN]-[MAX]" pad config.
+func parsePadOptions(query string) (int, int, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	var minSize, maxSize int
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "size":
+			if len(values) != 1 {
+				return 0, 0, fmt.Errorf("size option must has one value, found %v", len(values))
+			}
+			minText, maxText, isRange := strings.Cut(values[0], "-")
+			minSize, err = strconv.Atoi(minText)
+			if err != nil {
+				return 0, 0, fmt.Errorf("size is not a number: %v", minText)
+			}
+			maxSize = minSize
+			if isRange {
+				maxSize, err = strconv.Atoi(maxText)
+				if err != nil {
+					return 0, 0, fmt.Errorf("size is not a number: %v", maxText)
+				}
+			}
+		default:
+			return 0, 0, fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if minSize == 0 {
+		return 0, 0, errors.New("size option is required")
+	}
+	return minSize, maxSize, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPad_Options(t *testing.T) {
+	minSize, maxSize, err := parsePadOptions("size=512")
+	require.NoError(t, err)
+	require.Equal(t, 512, minSize)
+	require.Equal(t, 512, maxSize)
+
+	minSize, maxSize, err = parsePadOptions("size=256-1024")
+	require.NoError(t, err)
+	require.Equal(t, 256, minSize)
+	require.Equal(t, 1024, maxSize)
+
+	for _, query := range []string{"", "size=", "size=a", "size=10-b", "size=1&size=2", "foo=bar"} {
+		_, _, err := parsePadOptions(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestPad_InvalidSize(t *testing.T) {
+	_, err := NewDefaultProviders().NewStreamDialer(context.Background(), "pad:size=512-100")
+	require.Error(t, err)
+}
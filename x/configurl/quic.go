@@ -0,0 +1,74 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/quic"
+)
+
+func registerQUICStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newPD BuildFunc[transport.PacketDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		pd, err := newPD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		options, err := parseQUICOptions(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		return quic.NewStreamDialer(pd, options...)
+	})
+}
+
+// parseQUICOptions parses the "sni=[SNI]&certname=[CERT_NAME]&alpn=[PROTOCOL_LIST]" quic config.
+func parseQUICOptions(query string) ([]quic.ClientOption, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	options := []quic.ClientOption{}
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "sni":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("sni option must has one value, found %v", len(values))
+			}
+			options = append(options, quic.WithSNI(values[0]))
+		case "certname":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("certname option must has one value, found %v", len(values))
+			}
+			options = append(options, quic.WithCertificateName(values[0]))
+		case "alpn":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("alpn option must has one value, found %v", len(values))
+			}
+			protocols, err := parseALPN(values[0])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, quic.WithALPN(protocols))
+		default:
+			return nil, fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQUIC_Options(t *testing.T) {
+	options, err := parseQUICOptions("sni=decoy.example&certname=example.com&alpn=h3,echo")
+	require.NoError(t, err)
+	require.Len(t, options, 3)
+
+	options, err = parseQUICOptions("")
+	require.NoError(t, err)
+	require.Empty(t, options)
+}
+
+func TestQUIC_InvalidOptions(t *testing.T) {
+	for _, query := range []string{"sni=a&sni=b", "foo=bar", "%"} {
+		_, err := parseQUICOptions(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestQUIC_Provider(t *testing.T) {
+	providers := NewDefaultProviders()
+	_, err := providers.NewStreamDialer(context.Background(), "quic:sni=decoy.example")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "socks5://localhost:1080|quic:")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "quic:foo=bar")
+	require.Error(t, err)
+}
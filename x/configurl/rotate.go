@@ -0,0 +1,216 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+func registerRotateStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		rotateConfig, err := parseRotateConfig(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		dialers := make([]transport.StreamDialer, len(rotateConfig.configs))
+		for i, subConfigText := range rotateConfig.configs {
+			subConfig, err := ParseConfig(subConfigText)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rotate sub-config %q: %w", subConfigText, err)
+			}
+			dialers[i], err = newSD(ctx, chainConfig(subConfig, config.BaseConfig))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &rotateStreamDialer{dialers: dialers, config: rotateConfig}, nil
+	})
+}
+
+type rotateStreamDialer struct {
+	dialers []transport.StreamDialer
+	config  *rotateConfig
+	// next is the index of the next dialer for round-robin.
+	next atomic.Uint64
+}
+
+var _ transport.StreamDialer = (*rotateStreamDialer)(nil)
+
+// pick returns the index of the dialer to use for a new connection.
+func (d *rotateStreamDialer) pick() int {
+	if d.config.weights == nil {
+		return int((d.next.Add(1) - 1) % uint64(len(d.dialers)))
+	}
+	n := rand.Intn(d.config.totalWeight)
+	for i, weight := range d.config.weights {
+		if n < weight {
+			return i
+		}
+		n -= weight
+	}
+	// Not reached, since n < totalWeight.
+	return len(d.dialers) - 1
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+// It dials with the selected sub-dialer and, if fallback is enabled, with the following ones in order until one succeeds.
+func (d *rotateStreamDialer) DialStream(ctx context.Context, addr string) (transport.StreamConn, error) {
+	first := d.pick()
+	if !d.config.fallback {
+		return d.dialers[first].DialStream(ctx, addr)
+	}
+	var errs []error
+	for i := 0; i < len(d.dialers); i++ {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		conn, err := d.dialers[(first+i)%len(d.dialers)].DialStream(ctx, addr)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+type rotateConfig struct {
+	configs []string
+	// weights is nil for round-robin.
+	weights     []int
+	totalWeight int
+	fallback    bool
+}
+
+// parseRotateConfig parses the "config=[CONFIG]&config=...&strategy=[STRATEGY]&weights=[WEIGHTS]&fallback=[BOOL]"
+// rotate config.
+func parseRotateConfig(query string) (*rotateConfig, error) {
+	options, err := parseQueryOptions(query)
+	if err != nil {
+		return nil, err
+	}
+	result := &rotateConfig{}
+	var strategyValues, weightsValues, fallbackValues []string
+	for _, option := range options {
+		switch option.key {
+		case "config":
+			result.configs = append(result.configs, option.value)
+		case "strategy":
+			strategyValues = append(strategyValues, option.value)
+		case "weights":
+			weightsValues = append(weightsValues, option.value)
+		case "fallback":
+			fallbackValues = append(fallbackValues, option.value)
+		default:
+			return nil, fmt.Errorf("unsupported option %v", option.key)
+		}
+	}
+	strategy := "round-robin"
+	switch len(strategyValues) {
+	case 0:
+	case 1:
+		strategy = strings.ToLower(strategyValues[0])
+	default:
+		return nil, fmt.Errorf("strategy option must has one value, found %v", len(strategyValues))
+	}
+	var weightsText string
+	switch len(weightsValues) {
+	case 0:
+	case 1:
+		weightsText = weightsValues[0]
+	default:
+		return nil, fmt.Errorf("weights option must has one value, found %v", len(weightsValues))
+	}
+	switch len(fallbackValues) {
+	case 0:
+	case 1:
+		result.fallback, err = strconv.ParseBool(fallbackValues[0])
+		if err != nil {
+			return nil, fmt.Errorf("fallback value must be a boolean, found %q", fallbackValues[0])
+		}
+	default:
+		return nil, fmt.Errorf("fallback option must has one value, found %v", len(fallbackValues))
+	}
+	if len(result.configs) == 0 {
+		return nil, errors.New("rotate needs at least one config")
+	}
+	switch strategy {
+	case "round-robin":
+		if weightsText != "" {
+			return nil, errors.New("weights option is only supported with the random strategy")
+		}
+	case "random":
+		result.weights = make([]int, len(result.configs))
+		for i := range result.weights {
+			result.weights[i] = 1
+		}
+		if weightsText != "" {
+			weightTexts := strings.Split(weightsText, ",")
+			if len(weightTexts) != len(result.configs) {
+				return nil, fmt.Errorf("weights must have one value per config, found %v weights for %v configs", len(weightTexts), len(result.configs))
+			}
+			for i, weightText := range weightTexts {
+				weight, err := strconv.Atoi(strings.TrimSpace(weightText))
+				if err != nil || weight < 0 {
+					return nil, fmt.Errorf("weight must be a non-negative integer, found %q", weightText)
+				}
+				result.weights[i] = weight
+			}
+		}
+		for _, weight := range result.weights {
+			result.totalWeight += weight
+		}
+		if result.totalWeight == 0 {
+			return nil, errors.New("at least one weight must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported strategy %q, must be round-robin or random", strategy)
+	}
+	return result, nil
+}
+
+// sanitizeRotateURL sanitizes the sub-configs of a rotate config.
+func sanitizeRotateURL(u url.URL) (string, error) {
+	if _, err := parseRotateConfig(u.Opaque); err != nil {
+		return "", err
+	}
+	options, _ := parseQueryOptions(u.Opaque)
+	normalized := make(map[string][]string, len(options))
+	for _, option := range options {
+		normalized[option.key] = append(normalized[option.key], option.value)
+	}
+	var parts []string
+	for _, key := range []string{"config", "strategy", "weights", "fallback"} {
+		for _, value := range normalized[key] {
+			if key == "config" {
+				var err error
+				if value, err = SanitizeConfig(value); err != nil {
+					return "", err
+				}
+			}
+			parts = append(parts, key+"="+url.QueryEscape(value))
+		}
+	}
+	return u.Scheme + ":" + strings.Join(parts, "&"), nil
+}
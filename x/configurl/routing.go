@@ -0,0 +1,232 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+func registerRoutingStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		routingConfig, err := parseRoutingConfig(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		newSubDialer := func(subConfigText string) (transport.StreamDialer, error) {
+			subConfig, err := ParseConfig(subConfigText)
+			if err != nil {
+				return nil, fmt.Errorf("invalid routing sub-config %q: %w", subConfigText, err)
+			}
+			return newSD(ctx, chainConfig(subConfig, config.BaseConfig))
+		}
+		routes := make([]streamRoute, len(routingConfig.rules))
+		for i, rule := range routingConfig.rules {
+			routes[i].matcher = rule.matcher
+			routes[i].dialer, err = newSubDialer(rule.config)
+			if err != nil {
+				return nil, err
+			}
+		}
+		defaultDialer, err := newSubDialer(routingConfig.defaultConfig)
+		if err != nil {
+			return nil, err
+		}
+		return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, fmt.Errorf("address is not valid host:port: %w", err)
+			}
+			for _, route := range routes {
+				if route.matcher.matches(host) {
+					return route.dialer.DialStream(ctx, addr)
+				}
+			}
+			return defaultDialer.DialStream(ctx, addr)
+		}), nil
+	})
+}
+
+// chainConfig returns config with base as the base of its first part. It returns base if config is nil.
+func chainConfig(config *Config, base *Config) *Config {
+	if config == nil {
+		return base
+	}
+	last := config
+	for last.BaseConfig != nil {
+		last = last.BaseConfig
+	}
+	last.BaseConfig = base
+	return config
+}
+
+type streamRoute struct {
+	matcher hostMatcher
+	dialer  transport.StreamDialer
+}
+
+// hostMatcher matches hosts by domain suffix or, for IP addresses, by network prefix.
+type hostMatcher struct {
+	domains  []string
+	prefixes []netip.Prefix
+}
+
+func (m hostMatcher) matches(host string) bool {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		ip = ip.Unmap()
+		for _, prefix := range m.prefixes {
+			if prefix.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range m.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHostMatcher parses a comma-separated list of domains and CIDRs.
+func parseHostMatcher(text string) (hostMatcher, error) {
+	var m hostMatcher
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return hostMatcher{}, errors.New("empty domain or CIDR")
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return hostMatcher{}, fmt.Errorf("invalid CIDR %q: %w", item, err)
+			}
+			m.prefixes = append(m.prefixes, prefix.Masked())
+			continue
+		}
+		m.domains = append(m.domains, strings.Trim(strings.ToLower(item), "."))
+	}
+	return m, nil
+}
+
+type routingRule struct {
+	matchText string
+	matcher   hostMatcher
+	config    string
+}
+
+type routingConfig struct {
+	rules         []routingRule
+	defaultConfig string
+}
+
+// queryOption is an option of a config query, with the key in lower case.
+type queryOption struct {
+	key   string
+	value string
+}
+
+// parseQueryOptions parses the query like [url.ParseQuery], but returns the options in the order they appear, with the
+// keys in lower case. Unlike with [url.Values], the values of keys that only differ in case keep their order.
+func parseQueryOptions(query string) ([]queryOption, error) {
+	// Validate the query the same way as the other configs.
+	if _, err := url.ParseQuery(query); err != nil {
+		return nil, err
+	}
+	var options []queryOption
+	for _, part := range strings.Split(query, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		// The escaping was validated by url.ParseQuery.
+		key, _ = url.QueryUnescape(key)
+		value, _ = url.QueryUnescape(value)
+		options = append(options, queryOption{key: strings.ToLower(key), value: value})
+	}
+	return options, nil
+}
+
+// parseRoutingConfig parses the "rule=[MATCHES]=[CONFIG]&rule=...&default=[CONFIG]" routing config.
+func parseRoutingConfig(query string) (*routingConfig, error) {
+	options, err := parseQueryOptions(query)
+	if err != nil {
+		return nil, err
+	}
+	result := &routingConfig{}
+	var defaultConfigs []string
+	for _, option := range options {
+		switch option.key {
+		case "rule":
+			matchText, configText, ok := strings.Cut(option.value, "=")
+			if !ok {
+				return nil, fmt.Errorf("rule must have the format [MATCHES]=[CONFIG], found %q", option.value)
+			}
+			matcher, err := parseHostMatcher(matchText)
+			if err != nil {
+				return nil, err
+			}
+			result.rules = append(result.rules, routingRule{matchText, matcher, configText})
+		case "default":
+			defaultConfigs = append(defaultConfigs, option.value)
+		default:
+			return nil, fmt.Errorf("unsupported option %v", option.key)
+		}
+	}
+	if len(result.rules) == 0 {
+		return nil, errors.New("routing needs at least one rule")
+	}
+	switch len(defaultConfigs) {
+	case 0:
+	case 1:
+		result.defaultConfig = defaultConfigs[0]
+	default:
+		return nil, fmt.Errorf("default option must has one value, found %v", len(defaultConfigs))
+	}
+	return result, nil
+}
+
+// sanitizeRoutingURL sanitizes the sub-configs of a routing config.
+func sanitizeRoutingURL(u url.URL) (string, error) {
+	routingConfig, err := parseRoutingConfig(u.Opaque)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, rule := range routingConfig.rules {
+		sanitized, err := SanitizeConfig(rule.config)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "rule="+url.QueryEscape(rule.matchText+"="+sanitized))
+	}
+	if routingConfig.defaultConfig != "" {
+		sanitized, err := SanitizeConfig(routingConfig.defaultConfig)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "default="+url.QueryEscape(sanitized))
+	}
+	return u.Scheme + ":" + strings.Join(parts, "&"), nil
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 
@@ -90,13 +91,22 @@ type shadowsocksConfig struct {
 }
 
 func parseShadowsocksURL(url url.URL) (*shadowsocksConfig, error) {
-	// attempt to decode as SIP002 URI format and
-	// fall back to legacy base64 format if decoding fails
-	config, err := parseShadowsocksSIP002URL(url)
-	if err == nil {
-		return config, nil
+	// The legacy base64 format has the user info encoded in the host, so a URL with user info must be SIP002.
+	var config *shadowsocksConfig
+	var err error
+	if url.User != nil {
+		config, err = parseShadowsocksSIP002URL(url)
+	} else {
+		config, err = parseShadowsocksLegacyBase64URL(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Catch a missing or invalid port now, rather than when dialing.
+	if _, _, err := net.SplitHostPort(config.serverAddress); err != nil {
+		return nil, fmt.Errorf("server address is not valid host:port: %w", err)
 	}
-	return parseShadowsocksLegacyBase64URL(url)
+	return config, nil
 }
 
 // parseShadowsocksLegacyBase64URL parses URL based on legacy base64 format:
@@ -109,7 +119,8 @@ func parseShadowsocksLegacyBase64URL(url url.URL) (*shadowsocksConfig, error) {
 	decoded, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(url.Host)
 	if err != nil {
 		// If decoding fails, return the original url with error
-		return nil, fmt.Errorf("failed to decode host string [%v]: %w", url.String(), err)
+		// Don't include the URL in the error, since it has the secret.
+		return nil, fmt.Errorf("failed to decode host string: %w", err)
 	}
 	var fragment string
 	if url.Fragment != "" {
@@ -165,6 +176,9 @@ func parseShadowsocksSIP002URL(url url.URL) (*shadowsocksConfig, error) {
 	var cipherInfo string
 	if err == nil {
 		cipherInfo = string(decodedUserInfo)
+	} else if password, ok := url.User.Password(); ok {
+		// Plain user info must be percent-decoded, since Shadowsocks 2022 keys may contain '/'.
+		cipherInfo = url.User.Username() + ":" + password
 	} else {
 		cipherInfo = userInfo
 	}
@@ -204,8 +218,9 @@ func sanitizeShadowsocksURL(u url.URL) (string, error) {
 		return "", err
 	}
 	values := make(url.Values)
+	// The prefix can identify the server, so we only keep whether it's set.
 	if prefix := u.Query().Get("prefix"); prefix != "" {
-		values.Add("prefix", prefix)
+		values.Add("prefix", "REDACTED")
 	}
 	cleanURL := url.URL{
 		Scheme:   "ss",
@@ -15,6 +15,7 @@
 package configurl
 
 import (
+	"bytes"
 	"encoding/base64"
 	"net/url"
 	"testing"
@@ -35,7 +36,7 @@ func Test_sanitizeShadowsocksURL_withPrefix(t *testing.T) {
 	require.NoError(t, err)
 	sanitized, err := sanitizeShadowsocksURL(*ssURL)
 	require.NoError(t, err)
-	require.Equal(t, "ss://REDACTED@192.168.100.1:8888?prefix=foo", sanitized)
+	require.Equal(t, "ss://REDACTED@192.168.100.1:8888?prefix=REDACTED", sanitized)
 }
 
 func TestParseShadowsocksURLFullyEncoded(t *testing.T) {
@@ -101,6 +102,21 @@ func TestParseShadowsocksURLNoEncoding(t *testing.T) {
 	require.Equal(t, "example.com:1234", ssConfig.serverAddress)
 }
 
+func TestParseShadowsocksURL2022(t *testing.T) {
+	// The encoded key has '/' characters, which must be escaped.
+	psk := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 32))
+	configString := "ss://2022-blake3-aes-256-gcm:" + url.PathEscape(psk) + "@example.com:1234"
+	config, err := ParseConfig(configString)
+	require.NoError(t, err)
+	require.Nil(t, config.BaseConfig)
+
+	ssConfig, err := parseShadowsocksURL(config.URL)
+
+	require.NoError(t, err)
+	require.Equal(t, "example.com:1234", ssConfig.serverAddress)
+	require.Equal(t, 32, ssConfig.cryptoKey.SaltSize())
+}
+
 func TestParseShadowsocksURLInvalidCipherInfoFails(t *testing.T) {
 	configString := "ss://aes-256-gcm1234567@example.com:1234"
 	config, err := ParseConfig(configString)
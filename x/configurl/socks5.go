@@ -16,9 +16,12 @@ package configurl
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/Jigsaw-Code/outline-sdk/transport/socks5"
+	"github.com/Jigsaw-Code/outline-sdk/transport/tls"
 )
 
 func registerSOCKS5StreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
@@ -57,11 +60,23 @@ func registerSOCKS5PacketListener(r TypeRegistry[transport.PacketListener], type
 	})
 }
 
+// newSOCKS5Client creates the client for a "socks5://" or "socks5+tls://" config.
+// With "socks5+tls", the TCP connections to the proxy are wrapped in TLS. The UDP packets are not.
 func newSOCKS5Client(ctx context.Context, config Config, newSD BuildFunc[transport.StreamDialer]) (*socks5.Client, error) {
+	if config.URL.Host == "" {
+		// An opaque URL like "socks5:HOST:PORT" has no host, and would create a client with no proxy address.
+		return nil, fmt.Errorf("%v config must have the proxy address, as in %v://[HOST]:[PORT]", config.URL.Scheme, config.URL.Scheme)
+	}
 	sd, err := newSD(ctx, config.BaseConfig)
 	if err != nil {
 		return nil, err
 	}
+	if strings.ToLower(config.URL.Scheme) == "socks5+tls" {
+		sd, err = tls.NewStreamDialer(sd)
+		if err != nil {
+			return nil, err
+		}
+	}
 	endpoint := transport.StreamDialerEndpoint{Dialer: sd, Address: config.URL.Host}
 	client, err := socks5.NewClient(&endpoint)
 	if err != nil {
@@ -16,9 +16,13 @@ package configurl
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/Jigsaw-Code/outline-sdk/transport/split"
@@ -30,33 +34,137 @@ func registerSplitStreamDialer(r TypeRegistry[transport.StreamDialer], typeID st
 		if err != nil {
 			return nil, err
 		}
-		configText := config.URL.Opaque
-		splits := make([]split.RepeatedSplit, 0)
-		for _, part := range strings.Split(configText, ",") {
-			var count int
-			var bytes int64
-			subparts := strings.Split(strings.TrimSpace(part), "*")
-			switch len(subparts) {
-			case 1:
-				count = 1
-				bytes, err = strconv.ParseInt(subparts[0], 10, 64)
-				if err != nil {
-					return nil, fmt.Errorf("bytes is not a number: %v", subparts[0])
-				}
-			case 2:
-				count, err = strconv.Atoi(subparts[0])
-				if err != nil {
-					return nil, fmt.Errorf("count is not a number: %v", subparts[0])
-				}
-				bytes, err = strconv.ParseInt(subparts[1], 10, 64)
-				if err != nil {
-					return nil, fmt.Errorf("bytes is not a number: %v", subparts[1])
-				}
-			default:
-				return nil, fmt.Errorf("split format must be a comma-separated list of '[$COUNT*]$BYTES' (e.g. '100,5*2'). Got %v", part)
-			}
-			splits = append(splits, split.RepeatedSplit{Count: count, Bytes: bytes})
-		}
-		return split.NewStreamDialer(sd, split.NewRepeatedSplitIterator(splits...))
+		configText, delay, err := cutSplitDelay(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(configText, "=") {
+			pattern, fallback, err := parsePatternSplitConfig(configText)
+			if err != nil {
+				return nil, err
+			}
+			return split.NewPatternDialer(sd, pattern, fallback, split.WithDelay(delay))
+		}
+		splits, err := parseSplitConfig(configText)
+		if err != nil {
+			return nil, err
+		}
+		// Create a new iterator for each connection, so that each gets its own splits.
+		return split.NewStreamDialerFunc(sd, func() split.SplitIterator {
+			return split.NewRandomSplitIterator(splits...)
+		}, split.WithDelay(delay))
 	})
 }
+
+// cutSplitDelay removes the "delay=[DURATION]" option from the split config, and returns the rest of the config and
+// the delay. The option can follow both the split lengths and the pattern options.
+func cutSplitDelay(configText string) (string, time.Duration, error) {
+	var rest []string
+	var delay time.Duration
+	found := false
+	for _, option := range strings.Split(configText, "&") {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || !strings.EqualFold(key, "delay") {
+			rest = append(rest, option)
+			continue
+		}
+		if found {
+			return "", 0, errors.New("delay option must has one value, found 2 or more")
+		}
+		found = true
+		value, err := url.QueryUnescape(value)
+		if err != nil {
+			return "", 0, err
+		}
+		delay, err = time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return "", 0, fmt.Errorf("delay must be a non-negative duration, found %q", value)
+		}
+	}
+	return strings.Join(rest, "&"), delay, nil
+}
+
+// parseSplitConfig parses the "[COUNT1]*[LENGTH1],[COUNT2]*[LENGTH2],..." split config.
+// Each length can be a range "[MIN]-[MAX]" to pick it at random.
+func parseSplitConfig(configText string) ([]split.RandomSplit, error) {
+	splits := make([]split.RandomSplit, 0)
+	for _, part := range strings.Split(configText, ",") {
+		count := 1
+		var err error
+		lengthText := strings.TrimSpace(part)
+		subparts := strings.Split(lengthText, "*")
+		switch len(subparts) {
+		case 1:
+		case 2:
+			count, err = strconv.Atoi(subparts[0])
+			if err != nil {
+				return nil, fmt.Errorf("count is not a number: %v", subparts[0])
+			}
+			lengthText = subparts[1]
+		default:
+			return nil, fmt.Errorf("split format must be a comma-separated list of '[$COUNT*]$BYTES' or '[$COUNT*]$MIN-$MAX' (e.g. '100,5*2,3-10'). Got %v", part)
+		}
+		minBytesText, maxBytesText, isRange := strings.Cut(lengthText, "-")
+		minBytes, err := strconv.ParseInt(minBytesText, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bytes is not a number: %v", minBytesText)
+		}
+		maxBytes := minBytes
+		if isRange {
+			maxBytes, err = strconv.ParseInt(maxBytesText, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bytes is not a number: %v", maxBytesText)
+			}
+			if minBytes > maxBytes {
+				return nil, fmt.Errorf("split range must have min <= max. Got %v", lengthText)
+			}
+		}
+		splits = append(splits, split.RandomSplit{Count: count, MinBytes: minBytes, MaxBytes: maxBytes})
+	}
+	return splits, nil
+}
+
+// parsePatternSplitConfig parses the "pattern=[PATTERN]&fallback=[BYTES]" split config.
+// A pattern starting with "0x" is parsed as hexadecimal bytes.
+func parsePatternSplitConfig(configText string) ([]byte, int64, error) {
+	values, err := url.ParseQuery(configText)
+	if err != nil {
+		return nil, 0, err
+	}
+	var pattern []byte
+	var fallback int64
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "pattern":
+			if len(values) != 1 {
+				return nil, 0, fmt.Errorf("pattern option must has one value, found %v", len(values))
+			}
+			pattern, err = parseBytesValue(values[0])
+			if err != nil {
+				return nil, 0, fmt.Errorf("pattern is not valid hex: %w", err)
+			}
+		case "fallback":
+			if len(values) != 1 {
+				return nil, 0, fmt.Errorf("fallback option must has one value, found %v", len(values))
+			}
+			fallback, err = strconv.ParseInt(values[0], 10, 64)
+			if err != nil {
+				return nil, 0, fmt.Errorf("fallback is not a number: %v", values[0])
+			}
+		default:
+			return nil, 0, fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if len(pattern) == 0 {
+		return nil, 0, errors.New("pattern option is required")
+	}
+	return pattern, fallback, nil
+}
+
+// parseBytesValue returns the bytes of an option value. A value starting with "0x" is parsed as hexadecimal bytes.
+func parseBytesValue(value string) ([]byte, error) {
+	if hexValue, ok := strings.CutPrefix(value, "0x"); ok {
+		return hex.DecodeString(hexValue)
+	}
+	return []byte(value), nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport/split"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit_Pattern(t *testing.T) {
+	pattern, fallback, err := parsePatternSplitConfig("pattern=Host%3A&fallback=2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("Host:"), pattern)
+	require.Equal(t, int64(2), fallback)
+}
+
+func TestSplit_HexPattern(t *testing.T) {
+	pattern, fallback, err := parsePatternSplitConfig("pattern=0x160301")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x16, 0x03, 0x01}, pattern)
+	require.Equal(t, int64(0), fallback)
+}
+
+func TestSplit_InvalidPattern(t *testing.T) {
+	for _, configText := range []string{"pattern=0xzz", "fallback=2", "pattern=a&pattern=b", "pattern=a&fallback=x", "pattern=a&foo=b"} {
+		_, _, err := parsePatternSplitConfig(configText)
+		require.Error(t, err, configText)
+	}
+}
+
+func TestSplit_Provider(t *testing.T) {
+	providers := NewDefaultProviders()
+	_, err := providers.NewStreamDialer(context.Background(), "split:pattern=Host:")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "split:2,5*3")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "split:pattern=")
+	require.Error(t, err)
+}
+
+func TestSplit_Ranges(t *testing.T) {
+	splits, err := parseSplitConfig("100, 5*2,3-10,2*1-5")
+	require.NoError(t, err)
+	require.Equal(t, []split.RandomSplit{
+		{Count: 1, MinBytes: 100, MaxBytes: 100},
+		{Count: 5, MinBytes: 2, MaxBytes: 2},
+		{Count: 1, MinBytes: 3, MaxBytes: 10},
+		{Count: 2, MinBytes: 1, MaxBytes: 5},
+	}, splits)
+}
+
+func TestSplit_InvalidRanges(t *testing.T) {
+	for _, configText := range []string{"10-3", "3-", "a-3", "2*3*4", "x*3"} {
+		_, err := parseSplitConfig(configText)
+		require.Error(t, err, configText)
+	}
+}
+
+func TestSplit_Delay(t *testing.T) {
+	for _, tc := range []struct {
+		configText string
+		rest       string
+		delay      time.Duration
+	}{
+		{"2", "2", 0},
+		{"2&delay=10ms", "2", 10 * time.Millisecond},
+		{"DELAY=1s&2*1-5,100", "2*1-5,100", time.Second},
+		{"pattern=Host%3A&delay=5ms&fallback=2", "pattern=Host%3A&fallback=2", 5 * time.Millisecond},
+	} {
+		rest, delay, err := cutSplitDelay(tc.configText)
+		require.NoError(t, err, tc.configText)
+		require.Equal(t, tc.rest, rest, tc.configText)
+		require.Equal(t, tc.delay, delay, tc.configText)
+	}
+
+	for _, configText := range []string{"2&delay=10", "2&delay=-1ms", "2&delay=1ms&delay=2ms"} {
+		_, _, err := cutSplitDelay(configText)
+		require.Error(t, err, configText)
+	}
+
+	providers := NewDefaultProviders()
+	_, err := providers.NewStreamDialer(context.Background(), "split:2&delay=10ms")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "split:pattern=Host%3A&delay=10ms")
+	require.NoError(t, err)
+	_, err = providers.NewStreamDialer(context.Background(), "split:2&delay=x")
+	require.Error(t, err)
+}
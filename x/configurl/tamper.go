@@ -0,0 +1,80 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/tamper"
+)
+
+func registerTamperStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		sd, err := newSD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		fake, hopLimit, err := parseTamperOptions(config.URL.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		return tamper.NewFakeStreamDialer(sd, fake, hopLimit)
+	})
+}
+
+// parseTamperOptions parses the "fake=[PAYLOAD]&ttl=[TTL]" tamper config.
+func parseTamperOptions(query string) ([]byte, int, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	var fake []byte
+	var hopLimit int
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "fake":
+			if len(values) != 1 {
+				return nil, 0, fmt.Errorf("fake option must has one value, found %v", len(values))
+			}
+			fake, err = parseBytesValue(values[0])
+			if err != nil {
+				return nil, 0, fmt.Errorf("fake is not valid hex: %w", err)
+			}
+		case "ttl":
+			if len(values) != 1 {
+				return nil, 0, fmt.Errorf("ttl option must has one value, found %v", len(values))
+			}
+			hopLimit, err = strconv.Atoi(values[0])
+			if err != nil {
+				return nil, 0, fmt.Errorf("tamper: could not parse ttl: %v", err)
+			}
+		default:
+			return nil, 0, fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if len(fake) == 0 {
+		return nil, 0, errors.New("fake option is required")
+	}
+	if hopLimit == 0 {
+		return nil, 0, errors.New("ttl option is required")
+	}
+	return fake, hopLimit, nil
+}
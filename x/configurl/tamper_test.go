@@ -0,0 +1,55 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTamper_Options(t *testing.T) {
+	fake, hopLimit, err := parseTamperOptions("fake=0x160301&ttl=3")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x16, 0x03, 0x01}, fake)
+	require.Equal(t, 3, hopLimit)
+
+	fake, hopLimit, err = parseTamperOptions("fake=GET%20/&ttl=5")
+	require.NoError(t, err)
+	require.Equal(t, []byte("GET /"), fake)
+	require.Equal(t, 5, hopLimit)
+}
+
+func TestTamper_InvalidOptions(t *testing.T) {
+	for _, query := range []string{"ttl=3", "fake=abc", "fake=0xzz&ttl=3", "fake=abc&ttl=x", "fake=abc&ttl=3&foo=bar"} {
+		_, _, err := parseTamperOptions(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestTamper_Provider(t *testing.T) {
+	providers := NewDefaultProviders()
+	_, err := providers.NewStreamDialer(context.Background(), "tamper:fake=0x160301&ttl=3")
+	if runtime.GOOS == "linux" {
+		require.NoError(t, err)
+	} else {
+		require.ErrorIs(t, err, errors.ErrUnsupported)
+	}
+	_, err = providers.NewStreamDialer(context.Background(), "tamper:fake=0x160301&ttl=0")
+	require.Error(t, err)
+}
@@ -16,8 +16,11 @@ package configurl
 
 import (
 	"context"
+	gotls "crypto/tls"
+	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
@@ -57,6 +60,64 @@ func parseOptions(configURL url.URL) ([]tls.ClientOption, error) {
 				return nil, fmt.Errorf("certName option must has one value, found %v", len(values))
 			}
 			options = append(options, tls.WithCertificateName(values[0]))
+		case "alpn":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("alpn option must has one value, found %v", len(values))
+			}
+			protocols, err := parseALPN(values[0])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, tls.WithALPN(protocols))
+		case "minver", "maxver":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("%v option must has one value, found %v", key, len(values))
+			}
+			version, err := parseTLSVersion(values[0])
+			if err != nil {
+				return nil, err
+			}
+			if strings.ToLower(key) == "minver" {
+				options = append(options, tls.WithMinVersion(version))
+			} else {
+				options = append(options, tls.WithMaxVersion(version))
+			}
+		case "ciphers":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("ciphers option must has one value, found %v", len(values))
+			}
+			cipherSuites, err := parseCipherSuites(values[0])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, tls.WithCipherSuites(cipherSuites))
+		case "curves":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("curves option must has one value, found %v", len(values))
+			}
+			curves, err := parseCurves(values[0])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, tls.WithCurvePreferences(curves))
+		case "sessiontickets":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("sessiontickets option must has one value, found %v", len(values))
+			}
+			enabled, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("sessiontickets must be a boolean: %w", err)
+			}
+			options = append(options, tls.WithSessionTicketsDisabled(!enabled))
+		case "resume":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("resume option must has one value, found %v", len(values))
+			}
+			enabled, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("resume must be a boolean: %w", err)
+			}
+			options = append(options, tls.WithSessionResumption(enabled))
 		default:
 			return nil, fmt.Errorf("unsupported option %v", key)
 
@@ -64,3 +125,69 @@ func parseOptions(configURL url.URL) ([]tls.ClientOption, error) {
 	}
 	return options, nil
 }
+
+// parseALPN parses the comma-separated list of protocols of the alpn option, ignoring empty entries.
+func parseALPN(value string) ([]string, error) {
+	var protocols []string
+	for _, protocol := range strings.Split(value, ",") {
+		if protocol = strings.TrimSpace(protocol); protocol != "" {
+			protocols = append(protocols, protocol)
+		}
+	}
+	if len(protocols) == 0 {
+		return nil, errors.New("alpn option must have at least one protocol")
+	}
+	return protocols, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return gotls.VersionTLS10, nil
+	case "1.1":
+		return gotls.VersionTLS11, nil
+	case "1.2":
+		return gotls.VersionTLS12, nil
+	case "1.3":
+		return gotls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %v", version)
+	}
+}
+
+// parseCipherSuites parses a comma-separated list of cipher suite names, as returned by [gotls.CipherSuiteName].
+func parseCipherSuites(names string) ([]uint16, error) {
+	suitesByName := make(map[string]uint16)
+	for _, suite := range append(gotls.CipherSuites(), gotls.InsecureCipherSuites()...) {
+		suitesByName[suite.Name] = suite.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(names, ",") {
+		id, ok := suitesByName[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %v", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseCurves parses a comma-separated list of curve names.
+func parseCurves(names string) ([]gotls.CurveID, error) {
+	var curves []gotls.CurveID
+	for _, name := range strings.Split(names, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "x25519":
+			curves = append(curves, gotls.X25519)
+		case "p256":
+			curves = append(curves, gotls.CurveP256)
+		case "p384":
+			curves = append(curves, gotls.CurveP384)
+		case "p521":
+			curves = append(curves, gotls.CurveP521)
+		default:
+			return nil, fmt.Errorf("unsupported curve %v", name)
+		}
+	}
+	return curves, nil
+}
@@ -15,6 +15,7 @@
 package configurl
 
 import (
+	gotls "crypto/tls"
 	"testing"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport/tls"
@@ -67,6 +68,46 @@ func TestTLS_CertName(t *testing.T) {
 	require.Equal(t, "www.google.com", cfg.CertificateName)
 }
 
+func TestTLS_ALPN(t *testing.T) {
+	config, err := ParseConfig("tls:alpn=h2,http/1.1")
+	require.NoError(t, err)
+	options, err := parseOptions(config.URL)
+	require.NoError(t, err)
+	cfg := tls.ClientConfig{ServerName: "host", CertificateName: "host"}
+	for _, option := range options {
+		option("host", &cfg)
+	}
+	require.Equal(t, []string{"h2", "http/1.1"}, cfg.NextProtos)
+}
+
+func TestTLS_ALPNEmptyEntries(t *testing.T) {
+	config, err := ParseConfig("tls:alpn=h2,,%20http/1.1,")
+	require.NoError(t, err)
+	options, err := parseOptions(config.URL)
+	require.NoError(t, err)
+	cfg := tls.ClientConfig{ServerName: "host", CertificateName: "host"}
+	for _, option := range options {
+		option("host", &cfg)
+	}
+	require.Equal(t, []string{"h2", "http/1.1"}, cfg.NextProtos)
+}
+
+func TestTLS_EmptyALPN(t *testing.T) {
+	for _, value := range []string{"", ",", " , "} {
+		config, err := ParseConfig("tls:alpn=" + value)
+		require.NoError(t, err)
+		_, err = parseOptions(config.URL)
+		require.Error(t, err, "alpn=%q", value)
+	}
+}
+
+func TestTLS_MultipleALPN(t *testing.T) {
+	config, err := ParseConfig("tls:alpn=h2&alpn=http/1.1")
+	require.NoError(t, err)
+	_, err = parseOptions(config.URL)
+	require.Error(t, err)
+}
+
 func TestTLS_Combined(t *testing.T) {
 	config, err := ParseConfig("tls:SNI=sni.example.com&CertName=certname.example.com")
 	require.NoError(t, err)
@@ -86,3 +127,45 @@ func TestTLS_UnsupportedOption(t *testing.T) {
 	_, err = parseOptions(config.URL)
 	require.Error(t, err)
 }
+
+func TestTLS_Fingerprint(t *testing.T) {
+	config, err := ParseConfig("tls:minver=1.2&maxver=1.3&ciphers=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384&curves=X25519,P256&sessiontickets=false")
+	require.NoError(t, err)
+	options, err := parseOptions(config.URL)
+	require.NoError(t, err)
+	var cfg tls.ClientConfig
+	for _, option := range options {
+		option("host", &cfg)
+	}
+	require.Equal(t, uint16(gotls.VersionTLS12), cfg.MinVersion)
+	require.Equal(t, uint16(gotls.VersionTLS13), cfg.MaxVersion)
+	require.Equal(t, []uint16{gotls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, gotls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}, cfg.CipherSuites)
+	require.Equal(t, []gotls.CurveID{gotls.X25519, gotls.CurveP256}, cfg.CurvePreferences)
+	require.True(t, cfg.SessionTicketsDisabled)
+}
+
+func TestTLS_Resume(t *testing.T) {
+	for _, tc := range []struct {
+		configText string
+		disabled   bool
+	}{{"tls:", false}, {"tls:resume=1", false}, {"tls:resume=0", true}, {"tls:resume=false", true}} {
+		config, err := ParseConfig(tc.configText)
+		require.NoError(t, err)
+		options, err := parseOptions(config.URL)
+		require.NoError(t, err)
+		var cfg tls.ClientConfig
+		for _, option := range options {
+			option("host", &cfg)
+		}
+		require.Equal(t, tc.disabled, cfg.SessionResumptionDisabled, tc.configText)
+	}
+}
+
+func TestTLS_InvalidFingerprint(t *testing.T) {
+	for _, configText := range []string{"tls:minver=2.0", "tls:ciphers=FOO", "tls:curves=P999", "tls:sessiontickets=maybe", "tls:resume=maybe"} {
+		config, err := ParseConfig(configText)
+		require.NoError(t, err)
+		_, err = parseOptions(config.URL)
+		require.Error(t, err, configText)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+func registerUnixHandoffStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		if config.BaseConfig != nil {
+			return nil, errors.New("unix dialer does not take an input dialer")
+		}
+		socketPath, err := parseUnixHandoffPath(config.URL)
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewUnixHandoffStreamDialer(socketPath)
+	})
+}
+
+func parseUnixHandoffPath(configURL url.URL) (string, error) {
+	values, err := url.ParseQuery(configURL.Opaque)
+	if err != nil {
+		return "", err
+	}
+	socketPath := ""
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "path":
+			if len(values) != 1 {
+				return "", fmt.Errorf("path option must has one value, found %v", len(values))
+			}
+			socketPath = values[0]
+		default:
+			return "", fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if socketPath == "" {
+		return "", errors.New("path option is required")
+	}
+	return socketPath, nil
+}
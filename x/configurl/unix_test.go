@@ -0,0 +1,45 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnixHandoffPath(t *testing.T) {
+	config, err := ParseConfig("unix:path=/var/run/helper.sock")
+	require.NoError(t, err)
+	socketPath, err := parseUnixHandoffPath(config.URL)
+	require.NoError(t, err)
+	require.Equal(t, "/var/run/helper.sock", socketPath)
+
+	config, err = ParseConfig("unix:")
+	require.NoError(t, err)
+	_, err = parseUnixHandoffPath(config.URL)
+	require.Error(t, err)
+
+	config, err = ParseConfig("unix:path=/a&foo=bar")
+	require.NoError(t, err)
+	_, err = parseUnixHandoffPath(config.URL)
+	require.Error(t, err)
+}
+
+func TestUnixHandoffRejectsBaseConfig(t *testing.T) {
+	_, err := NewDefaultProviders().NewStreamDialer(context.Background(), "split:2|unix:path=/var/run/helper.sock")
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/utls"
+)
+
+func registerUTLSStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
+	r.RegisterType(typeID, func(ctx context.Context, config *Config) (transport.StreamDialer, error) {
+		sd, err := newSD(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		profile, options, err := parseUTLSOptions(config.URL)
+		if err != nil {
+			return nil, err
+		}
+		return utls.NewStreamDialer(sd, profile, options...)
+	})
+}
+
+func parseUTLSOptions(configURL url.URL) (string, []utls.ClientOption, error) {
+	values, err := url.ParseQuery(configURL.Opaque)
+	if err != nil {
+		return "", nil, err
+	}
+	var profile string
+	options := []utls.ClientOption{}
+	for key, values := range values {
+		switch strings.ToLower(key) {
+		case "profile":
+			if len(values) != 1 {
+				return "", nil, fmt.Errorf("profile option must has one value, found %v", len(values))
+			}
+			profile = values[0]
+		case "sni":
+			if len(values) != 1 {
+				return "", nil, fmt.Errorf("sni option must has one value, found %v", len(values))
+			}
+			options = append(options, utls.WithSNI(values[0]))
+		case "certname":
+			if len(values) != 1 {
+				return "", nil, fmt.Errorf("certName option must has one value, found %v", len(values))
+			}
+			options = append(options, utls.WithCertificateName(values[0]))
+		default:
+			return "", nil, fmt.Errorf("unsupported option %v", key)
+		}
+	}
+	if profile == "" {
+		return "", nil, errors.New("profile option is required")
+	}
+	return profile, options, nil
+}
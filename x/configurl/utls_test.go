@@ -0,0 +1,55 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/x/utls"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTLS_Options(t *testing.T) {
+	config, err := ParseConfig("utls:profile=chrome_106&sni=decoy.example.com&certname=host.example.com")
+	require.NoError(t, err)
+	profile, options, err := parseUTLSOptions(config.URL)
+	require.NoError(t, err)
+	require.Equal(t, "chrome_106", profile)
+	cfg := utls.ClientConfig{ServerName: "host", CertificateName: "host"}
+	for _, option := range options {
+		option("host", &cfg)
+	}
+	require.Equal(t, "decoy.example.com", cfg.ServerName)
+	require.Equal(t, "host.example.com", cfg.CertificateName)
+}
+
+func TestUTLS_MissingProfile(t *testing.T) {
+	config, err := ParseConfig("utls:sni=decoy.example.com")
+	require.NoError(t, err)
+	_, _, err = parseUTLSOptions(config.URL)
+	require.Error(t, err)
+}
+
+func TestUTLS_UnsupportedProfile(t *testing.T) {
+	_, err := NewDefaultProviders().NewStreamDialer(context.Background(), "utls:profile=netscape")
+	require.Error(t, err)
+}
+
+func TestUTLS_Provider(t *testing.T) {
+	dialer, err := NewDefaultProviders().NewStreamDialer(context.Background(), "utls:profile=firefox")
+	require.NoError(t, err)
+	require.IsType(t, &utls.StreamDialer{}, dialer)
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ConfigError reports the part of a pipe-separated config that is invalid.
+type ConfigError struct {
+	// Index is the position of the invalid part in the config, starting at 0 for the leftmost part.
+	Index int
+	// Part is the text of the invalid part. It may have secrets, so don't log it.
+	Part string
+	// Err is the reason the part is invalid.
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	// Don't include the part, since it may have secrets.
+	return fmt.Sprintf("invalid config part %v: %v", e.Index, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateConfig checks that configText is a valid stream dialer config for the default providers, without dialing.
+// See [ProviderContainer.ValidateConfig].
+func ValidateConfig(ctx context.Context, configText string) error {
+	return NewDefaultProviders().ValidateConfig(ctx, configText)
+}
+
+// ValidateConfig checks that configText is a valid stream dialer config, so you can validate the config users
+// enter as they type. It parses the config and builds it part by part, from left to right, and returns a [*ConfigError]
+// for the first part that fails. Building a config doesn't dial, so this doesn't open any sockets, as long as the
+// registered builders don't either. Errors that can only be found when dialing, such as an unreachable server,
+// are not reported.
+func (p *ProviderContainer) ValidateConfig(ctx context.Context, configText string) error {
+	// Split the parts as ParseConfig does, so the indices match.
+	parts := strings.Split(stripCommentLines(configText), "|")
+	if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
+		return nil
+	}
+	for i, part := range parts {
+		// Build the prefix up to this part, so an error means this part is invalid.
+		config, err := ParseConfig(strings.Join(parts[:i+1], "|"))
+		if err == nil {
+			_, err = p.StreamDialers.NewInstance(ctx, config)
+		}
+		if err != nil {
+			return &ConfigError{Index: i, Part: strings.TrimSpace(part), Err: err}
+		}
+	}
+	return nil
+}
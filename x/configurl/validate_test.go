@@ -0,0 +1,65 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	for _, configText := range []string{
+		"",
+		"split:2",
+		"override:host=127.0.0.1&port=8080|tlsfrag:1",
+		"ss://chacha20-ietf-poly1305:SECRET@example.com:1234?prefix=HTTP%2F1.1%20|split:2",
+		"# Comment.\n split:2 \n | # Comment part.\n",
+	} {
+		require.NoError(t, ValidateConfig(context.Background(), configText), configText)
+	}
+}
+
+func TestValidateConfig_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		configText string
+		index      int
+		part       string
+	}{
+		{"foo:bar", 0, "foo:bar"},
+		{"split:2||tls", 1, ""},
+		{"# Comment | with a pipe.\nsplit:2\n| tls:foo=bar", 1, "tls:foo=bar"},
+		{"split:2|tls:foo=bar", 1, "tls:foo=bar"},
+		{"split:2|override:port=abc", 1, "override:port=abc"},
+		{"override:port=70000", 0, "override:port=70000"},
+		{"tlsfrag:1|ss://chacha20-ietf-poly1305:SECRET@example.com", 1, "ss://chacha20-ietf-poly1305:SECRET@example.com"},
+		{"split:2|ss://chacha20-ietf-poly1305:SECRET@example.com:1234?prefix=%E2%82%AC", 1, "ss://chacha20-ietf-poly1305:SECRET@example.com:1234?prefix=%E2%82%AC"},
+	} {
+		err := ValidateConfig(context.Background(), tc.configText)
+		var configErr *ConfigError
+		require.ErrorAs(t, err, &configErr, tc.configText)
+		require.Equal(t, tc.index, configErr.Index, tc.configText)
+		require.Equal(t, tc.part, configErr.Part, tc.configText)
+		require.NotContains(t, err.Error(), "SECRET")
+	}
+}
+
+func TestProviderContainer_ValidateConfig(t *testing.T) {
+	providers := NewProviderContainer()
+	require.Error(t, providers.ValidateConfig(context.Background(), "split:2"))
+	registerSplitStreamDialer(&providers.StreamDialers, "split", providers.StreamDialers.NewInstance)
+	require.NoError(t, providers.ValidateConfig(context.Background(), "split:2"))
+}
@@ -18,17 +18,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
-	"golang.org/x/net/websocket"
+	"github.com/Jigsaw-Code/outline-sdk/x/websocket"
 )
 
 type wsConfig struct {
-	tcpPath string
-	udpPath string
+	tcpPath      string
+	udpPath      string
+	host         string
+	pingInterval time.Duration
+	pongTimeout  time.Duration
 }
 
 func parseWSConfig(configURL url.URL) (*wsConfig, error) {
@@ -38,37 +42,72 @@ func parseWSConfig(configURL url.URL) (*wsConfig, error) {
 		return nil, err
 	}
 	var cfg wsConfig
+	var path string
 	for key, values := range values {
 		switch strings.ToLower(key) {
+		case "path":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("path option must has one value, found %v", len(values))
+			}
+			path = values[0]
+		case "host":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("host option must has one value, found %v", len(values))
+			}
+			cfg.host = values[0]
 		case "tcp_path":
 			if len(values) != 1 {
-				return nil, fmt.Errorf("udp_path option must has one value, found %v", len(values))
+				return nil, fmt.Errorf("tcp_path option must has one value, found %v", len(values))
 			}
 			cfg.tcpPath = values[0]
 		case "udp_path":
 			if len(values) != 1 {
-				return nil, fmt.Errorf("tcp_path option must has one value, found %v", len(values))
+				return nil, fmt.Errorf("udp_path option must has one value, found %v", len(values))
 			}
 			cfg.udpPath = values[0]
+		case "ping_interval", "pong_timeout":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("%v option must has one value, found %v", key, len(values))
+			}
+			duration, err := time.ParseDuration(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("%v is not a valid duration: %w", key, err)
+			}
+			if duration <= 0 {
+				return nil, fmt.Errorf("%v must be positive, got %v", key, values[0])
+			}
+			if strings.ToLower(key) == "ping_interval" {
+				cfg.pingInterval = duration
+			} else {
+				cfg.pongTimeout = duration
+			}
 		default:
 			return nil, fmt.Errorf("unsupported option %v", key)
 		}
 	}
+	if cfg.pongTimeout > 0 && cfg.pingInterval == 0 {
+		return nil, errors.New("pong_timeout option requires ping_interval")
+	}
+	// The path applies to both streams and packets, unless they have their own.
+	if cfg.tcpPath == "" {
+		cfg.tcpPath = path
+	}
+	if cfg.udpPath == "" {
+		cfg.udpPath = path
+	}
 	return &cfg, nil
 }
 
-// wsToStreamConn converts a [websocket.Conn] to a [transport.StreamConn].
-type wsToStreamConn struct {
-	*websocket.Conn
-}
-
-func (c *wsToStreamConn) CloseRead() error {
-	// Nothing to do.
-	return nil
-}
-
-func (c *wsToStreamConn) CloseWrite() error {
-	return c.Close()
+// options returns the WebSocket dialer options for the config.
+func (c *wsConfig) options() []websocket.DialerOption {
+	var options []websocket.DialerOption
+	if c.host != "" {
+		options = append(options, websocket.WithHeaders(http.Header{"Host": []string{c.host}}))
+	}
+	if c.pingInterval != 0 {
+		options = append(options, websocket.WithKeepAlive(c.pingInterval, c.pongTimeout))
+	}
+	return options
 }
 
 func registerWebsocketStreamDialer(r TypeRegistry[transport.StreamDialer], typeID string, newSD BuildFunc[transport.StreamDialer]) {
@@ -82,26 +121,9 @@ func registerWebsocketStreamDialer(r TypeRegistry[transport.StreamDialer], typeI
 			return nil, err
 		}
 		if wsConfig.tcpPath == "" {
-			return nil, errors.New("must specify tcp_path")
+			return nil, errors.New("must specify path or tcp_path")
 		}
-		return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
-			wsURL := url.URL{Scheme: "ws", Host: addr, Path: wsConfig.tcpPath}
-			origin := url.URL{Scheme: "http", Host: addr}
-			wsCfg, err := websocket.NewConfig(wsURL.String(), origin.String())
-			if err != nil {
-				return nil, fmt.Errorf("failed to create websocket config: %w", err)
-			}
-			baseConn, err := sd.DialStream(ctx, addr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to connect to websocket endpoint: %w", err)
-			}
-			wsConn, err := websocket.NewClient(wsCfg, baseConn)
-			if err != nil {
-				baseConn.Close()
-				return nil, fmt.Errorf("failed to create websocket client: %w", err)
-			}
-			return &wsToStreamConn{wsConn}, nil
-		}), nil
+		return websocket.NewStreamDialer(sd, wsConfig.tcpPath, wsConfig.options()...)
 	})
 }
 
@@ -116,25 +138,8 @@ func registerWebsocketPacketDialer(r TypeRegistry[transport.PacketDialer], typeI
 			return nil, err
 		}
 		if wsConfig.udpPath == "" {
-			return nil, errors.New("must specify udp_path")
+			return nil, errors.New("must specify path or udp_path")
 		}
-		return transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
-			wsURL := url.URL{Scheme: "ws", Host: addr, Path: wsConfig.udpPath}
-			origin := url.URL{Scheme: "http", Host: addr}
-			wsCfg, err := websocket.NewConfig(wsURL.String(), origin.String())
-			if err != nil {
-				return nil, fmt.Errorf("failed to create websocket config: %w", err)
-			}
-			baseConn, err := sd.DialStream(ctx, addr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to connect to websocket endpoint: %w", err)
-			}
-			wsConn, err := websocket.NewClient(wsCfg, baseConn)
-			if err != nil {
-				baseConn.Close()
-				return nil, fmt.Errorf("failed to create websocket client: %w", err)
-			}
-			return wsConn, nil
-		}), nil
+		return websocket.NewPacketDialer(sd, wsConfig.udpPath, wsConfig.options()...)
 	})
 }
@@ -0,0 +1,131 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/transport/shadowsocks"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestParseWSConfig(t *testing.T) {
+	cfg, err := parseWSConfig(url.URL{Opaque: "tcp_path=/tcp&udp_path=/udp&ping_interval=30s&pong_timeout=5s"})
+	require.NoError(t, err)
+	require.Equal(t, &wsConfig{tcpPath: "/tcp", udpPath: "/udp", pingInterval: 30 * time.Second, pongTimeout: 5 * time.Second}, cfg)
+
+	cfg, err = parseWSConfig(url.URL{Opaque: "path=/ws&udp_path=/udp&host=cdn.example.com"})
+	require.NoError(t, err)
+	require.Equal(t, &wsConfig{tcpPath: "/ws", udpPath: "/udp", host: "cdn.example.com"}, cfg)
+
+	for _, opaque := range []string{
+		"path=/a&path=/b",
+		"tcp_path=/tcp&ping_interval=never",
+		"tcp_path=/tcp&ping_interval=-1s",
+		"tcp_path=/tcp&pong_timeout=5s",
+		"tcp_path=/tcp&ping_interval=1s&ping_interval=2s",
+		"tcp_path=/tcp&unknown=1",
+	} {
+		_, err := parseWSConfig(url.URL{Opaque: opaque})
+		require.Error(t, err, opaque)
+	}
+}
+
+func TestWebsocketStreamDialerWithPings(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/tcp", websocket.Handler(func(ws *websocket.Conn) {
+		io.Copy(ws, ws)
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	providers := NewDefaultProviders()
+	dialer, err := providers.NewStreamDialer(context.Background(), "ws:tcp_path=/tcp&ping_interval=10ms&pong_timeout=20ms")
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), serverURL.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Stay idle for longer than the pong timeout.
+	time.Sleep(100 * time.Millisecond)
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+	var _ transport.StreamConn = conn
+}
+
+// Shadowsocks over WebSocket, as deployed with the v2ray-plugin server plugin.
+func TestShadowsocksOverWebsocket(t *testing.T) {
+	key, err := shadowsocks.NewEncryptionKey("chacha20-ietf-poly1305", "secret")
+	require.NoError(t, err)
+	var gotHost string
+	mux := http.NewServeMux()
+	mux.Handle("/", websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			gotHost = r.Host
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ssReader := shadowsocks.NewReader(ws, key)
+			// Read the target address, which must be a domain name.
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(ssReader, header); err != nil || header[0] != 3 {
+				return
+			}
+			address := make([]byte, int(header[1])+2)
+			if _, err := io.ReadFull(ssReader, address); err != nil {
+				return
+			}
+			if string(address[:len(address)-2]) != "example.com" {
+				return
+			}
+			ssWriter := shadowsocks.NewWriter(ws, key)
+			io.Copy(ssWriter, ssReader)
+		},
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	providers := NewDefaultProviders()
+	config := "ws:path=/&host=cdn.example.com|ss://chacha20-ietf-poly1305:secret@" + serverURL.Host
+	dialer, err := providers.NewStreamDialer(context.Background(), config)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+	require.Equal(t, "cdn.example.com", gotHost)
+}
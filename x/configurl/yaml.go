@@ -0,0 +1,238 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAMLConfig parses a config in the structured format and returns the same [Config] as [ParseConfig].
+// Since YAML is a superset of JSON, it also parses JSON configs.
+//
+// The config is a list of parts, from the innermost to the outermost, as in the pipe format. Each part is either a
+// string in the pipe format, or a map with the type of the part and either its value or its parameters:
+//
+//	# Same as "ss://[USERINFO]@[HOST]:[PORT]|tlsfrag:1|tls:sni=www.example.com".
+//	- ss://[USERINFO]@[HOST]:[PORT]
+//	- type: tlsfrag
+//	  value: 1
+//	- type: tls
+//	  params:
+//	    sni: www.example.com
+//
+// The value is the text after the colon, as in "tlsfrag:1". The parameters are encoded as the query, as in
+// "tls:sni=www.example.com". A parameter can have a list of values, for repeated parameters. The values that are
+// lists or maps are nested configs, converted to the pipe format, so you can write the sub-configs of rotate or
+// routing in the structured format too. A config that is a single string is parsed as the pipe format.
+func ParseYAMLConfig(configYAML []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(configYAML, &root); err != nil {
+		return nil, fmt.Errorf("invalid YAML config: %w", err)
+	}
+	if len(root.Content) == 0 {
+		// Empty document.
+		return nil, nil
+	}
+	return parseConfigNode(root.Content[0])
+}
+
+// parseConfigNode parses a config, which is either a list of parts or a string in the pipe format.
+func parseConfigNode(node *yaml.Node) (*Config, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil, nil
+		}
+		return ParseConfig(node.Value)
+	case yaml.SequenceNode:
+		var config *Config
+		for i, partNode := range node.Content {
+			partConfig, err := parsePartNode(partNode)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config part %v at line %v: %w", i, partNode.Line, err)
+			}
+			config = chainConfig(partConfig, config)
+		}
+		return config, nil
+	default:
+		return nil, fmt.Errorf("config at line %v must be a list of parts or a string", node.Line)
+	}
+}
+
+// parsePartNode parses a part, which is either a string in the pipe format or a map with the type and the value
+// or parameters. Strings may have multiple parts, so the returned config may be a chain.
+func parsePartNode(node *yaml.Node) (*Config, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		config, err := ParseConfig(node.Value)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, errors.New("empty config part")
+		}
+		return config, nil
+	case yaml.MappingNode:
+	default:
+		return nil, errors.New("part must be a string or a map")
+	}
+
+	var partType, opaque string
+	var hasValue, hasParams bool
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "type":
+			if value.Kind != yaml.ScalarNode {
+				return nil, errors.New("type must be a string")
+			}
+			partType = strings.ToLower(strings.TrimSpace(value.Value))
+		case "value":
+			if value.Kind != yaml.ScalarNode {
+				return nil, errors.New("value must be a scalar")
+			}
+			if strings.Contains(value.Value, "|") {
+				return nil, errors.New("value must not contain |")
+			}
+			hasValue = true
+			opaque = value.Value
+		case "params":
+			if value.Kind != yaml.MappingNode {
+				return nil, errors.New("params must be a map")
+			}
+			params, err := parseParamsNode(value)
+			if err != nil {
+				return nil, err
+			}
+			hasParams = true
+			opaque = params.Encode()
+		default:
+			return nil, fmt.Errorf("unsupported key %q, must be type, value or params", key.Value)
+		}
+	}
+	if partType == "" {
+		return nil, errors.New("part must have a type")
+	}
+	if hasValue && hasParams {
+		return nil, errors.New("part cannot have both value and params")
+	}
+	return &Config{URL: url.URL{Scheme: partType, Opaque: opaque}}, nil
+}
+
+// parseParamsNode converts the parameters map to query values.
+func parseParamsNode(node *yaml.Node) (url.Values, error) {
+	params := url.Values{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if value.Kind == yaml.SequenceNode {
+			// Repeated parameter.
+			for _, item := range value.Content {
+				text, err := paramValue(item)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for param %v: %w", key, err)
+				}
+				params.Add(key, text)
+			}
+			continue
+		}
+		text, err := paramValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for param %v: %w", key, err)
+		}
+		params.Add(key, text)
+	}
+	return params, nil
+}
+
+// paramValue returns the text of a parameter value. Lists and maps are nested configs, returned in the pipe format.
+func paramValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "", errors.New("value must not be null")
+		}
+		return node.Value, nil
+	case yaml.SequenceNode:
+		config, err := parseConfigNode(node)
+		if err != nil {
+			return "", err
+		}
+		return configText(config), nil
+	case yaml.MappingNode:
+		config, err := parsePartNode(node)
+		if err != nil {
+			return "", err
+		}
+		return configText(config), nil
+	default:
+		return "", fmt.Errorf("unsupported value at line %v", node.Line)
+	}
+}
+
+// configText returns the config in the pipe format.
+func configText(config *Config) string {
+	var parts []string
+	for ; config != nil; config = config.BaseConfig {
+		parts = append(parts, config.URL.String())
+	}
+	// The parts are from the outermost to the innermost, but the pipe format goes the other way.
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "|")
+}
+
+// NewStreamDialerFromYAML creates a [transport.StreamDialer] according to the config in the structured format.
+// See [ParseYAMLConfig].
+func (p *ProviderContainer) NewStreamDialerFromYAML(ctx context.Context, configYAML []byte) (transport.StreamDialer, error) {
+	config, err := ParseYAMLConfig(configYAML)
+	if err != nil {
+		return nil, err
+	}
+	return p.StreamDialers.NewInstance(ctx, config)
+}
+
+// NewPacketDialerFromYAML creates a [transport.PacketDialer] according to the config in the structured format.
+// See [ParseYAMLConfig].
+func (p *ProviderContainer) NewPacketDialerFromYAML(ctx context.Context, configYAML []byte) (transport.PacketDialer, error) {
+	config, err := ParseYAMLConfig(configYAML)
+	if err != nil {
+		return nil, err
+	}
+	return p.PacketDialers.NewInstance(ctx, config)
+}
+
+// NewPacketListenerFromYAML creates a [transport.PacketListener] according to the config in the structured format.
+// See [ParseYAMLConfig].
+func (p *ProviderContainer) NewPacketListenerFromYAML(ctx context.Context, configYAML []byte) (transport.PacketListener, error) {
+	config, err := ParseYAMLConfig(configYAML)
+	if err != nil {
+		return nil, err
+	}
+	return p.PacketListeners.NewInstance(ctx, config)
+}
+
+// NewStreamDialerFromYAML creates a [transport.StreamDialer] according to the config in the structured format,
+// with the default providers. See [ParseYAMLConfig].
+func NewStreamDialerFromYAML(ctx context.Context, configYAML []byte) (transport.StreamDialer, error) {
+	return NewDefaultProviders().NewStreamDialerFromYAML(ctx, configYAML)
+}
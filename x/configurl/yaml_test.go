@@ -0,0 +1,112 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurl
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseYAMLConfig(t *testing.T) {
+	config, err := ParseYAMLConfig([]byte(`
+# Innermost part first.
+- ss://Y2hhY2hhMjAtaWV0Zi1wb2x5MTMwNTpzZWNyZXQ@example.com:1234
+- split:2|tlsfrag:1
+- type: TLS
+  params:
+    sni: www.example.com
+    certname: example.com
+- type: pad
+  value: 100-200
+`))
+	require.NoError(t, err)
+	require.Equal(t, "ss://Y2hhY2hhMjAtaWV0Zi1wb2x5MTMwNTpzZWNyZXQ@example.com:1234|split:2|tlsfrag:1|tls:certname=example.com&sni=www.example.com|pad:100-200", configText(config))
+}
+
+func TestParseYAMLConfig_JSON(t *testing.T) {
+	config, err := ParseYAMLConfig([]byte(`["split:2", {"type": "tlsfrag", "value": 1}, {"type": "tls", "params": {"sni": "www.example.com"}}]`))
+	require.NoError(t, err)
+	expected, err := ParseConfig("split:2|tlsfrag:1|tls:sni=www.example.com")
+	require.NoError(t, err)
+	require.Equal(t, expected, config)
+}
+
+func TestParseYAMLConfig_String(t *testing.T) {
+	config, err := ParseYAMLConfig([]byte(`"split:2|tlsfrag:1"`))
+	require.NoError(t, err)
+	require.Equal(t, "split:2|tlsfrag:1", configText(config))
+
+	for _, configYAML := range []string{"", "# Direct.", "null", "[]"} {
+		config, err = ParseYAMLConfig([]byte(configYAML))
+		require.NoError(t, err, configYAML)
+		require.Nil(t, config, configYAML)
+	}
+}
+
+func TestParseYAMLConfig_NestedConfigs(t *testing.T) {
+	config, err := ParseYAMLConfig([]byte(`
+- type: rotate
+  params:
+    config:
+      - split:2
+      - [split:3, {type: tlsfrag, value: 1}]
+      - {type: tlsfrag, value: 2}
+    strategy: random
+    weights: 3,1,1
+`))
+	require.NoError(t, err)
+	require.Equal(t, "rotate", config.URL.Scheme)
+	values, err := url.ParseQuery(config.URL.Opaque)
+	require.NoError(t, err)
+	require.Equal(t, []string{"split:2", "split:3|tlsfrag:1", "tlsfrag:2"}, values["config"])
+	require.Equal(t, "random", values.Get("strategy"))
+
+	dialer, err := NewDefaultProviders().NewStreamDialerFromYAML(context.Background(), []byte(`
+- type: rotate
+  params:
+    config: [[split:3, tlsfrag:1], split:2]
+`))
+	require.NoError(t, err)
+	require.Len(t, dialer.(*rotateStreamDialer).dialers, 2)
+}
+
+func TestParseYAMLConfig_Errors(t *testing.T) {
+	for _, configYAML := range []string{
+		"[",
+		"{type: tls}",
+		"- {value: 1}",
+		"- {type: tlsfrag, value: 1, params: {a: b}}",
+		"- {type: tls, sni: www.example.com}",
+		"- {type: tls, params: [sni]}",
+		"- {type: tls, params: {sni: null}}",
+		"- {type: split, value: 2|tls}",
+		"- [split:2]",
+		"- ''",
+		"- split:2||tls",
+	} {
+		_, err := ParseYAMLConfig([]byte(configYAML))
+		require.Error(t, err, configYAML)
+	}
+}
+
+func TestNewStreamDialerFromYAML(t *testing.T) {
+	_, err := NewStreamDialerFromYAML(context.Background(), []byte("- split:2\n- {type: tlsfrag, value: 1}"))
+	require.NoError(t, err)
+	_, err = NewStreamDialerFromYAML(context.Background(), []byte("- {type: unknown}"))
+	require.Error(t, err)
+}
@@ -18,19 +18,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/netip"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"golang.org/x/net/dns/dnsmessage"
 )
 
 // ConnectivityError captures the observed error of the connectivity test.
 type ConnectivityError struct {
-	// Which operation in the test that failed: "connect", "send" or "receive"
+	// Which operation in the test that failed: "connect", "send" or "receive", or "tls_handshake" and "http" for
+	// the HTTP tests
 	Op string
 	// The POSIX error, when available
 	PosixError string
+	// The platform-independent classification of the error
+	Code ConnectivityErrorCode
 	// The error observed for the action
 	Err error
 }
@@ -61,7 +68,7 @@ func makeConnectivityError(op string, err error) *ConnectivityError {
 	} else if isTimeout(err) {
 		code = "ETIMEDOUT"
 	}
-	return &ConnectivityError{Op: op, PosixError: code, Err: err}
+	return &ConnectivityError{Op: op, PosixError: code, Code: errorCode(op, code, err), Err: err}
 }
 
 // TestConnectivityWithResolver tests weather we can get a response from the given [Resolver]. It can be used
@@ -97,3 +104,135 @@ func TestConnectivityWithResolver(ctx context.Context, resolver dns.Resolver, te
 	}
 	return nil, nil
 }
+
+// ConnectivityAttempt is the result of the connectivity test with one of the IP addresses of the server.
+type ConnectivityAttempt struct {
+	// The IP address the attempt connected to. It's invalid if the server host failed to resolve.
+	IP netip.Addr
+	// When the attempt started
+	StartTime time.Time
+	// How long the attempt took
+	Duration time.Duration
+	// The error of the attempt, or nil if it succeeded
+	Err *ConnectivityError
+}
+
+// TestConnectivityWithResolverPerIP is like [TestConnectivityWithResolver], but runs the test end-to-end once for
+// each IP address of the server, instead of only the one the dialer picks, and returns one attempt per address.
+//
+// The newResolver function must create the resolver to test on top of the given dialers, which wrap streamDialer and
+// packetDialer. They resolve the first host they dial, and connect to its IP address for the current attempt, in
+// the order returned by the system resolver. Other hosts use the same position in their addresses, or their last
+// address. If the host fails to resolve, there's a single failed attempt.
+func TestConnectivityWithResolverPerIP(ctx context.Context, streamDialer transport.StreamDialer, packetDialer transport.PacketDialer, newResolver func(transport.StreamDialer, transport.PacketDialer) (dns.Resolver, error), testDomain string) ([]ConnectivityAttempt, error) {
+	if streamDialer == nil {
+		return nil, errors.New("argument streamDialer must not be nil")
+	}
+	if packetDialer == nil {
+		return nil, errors.New("argument packetDialer must not be nil")
+	}
+	pins := &ipPins{resolved: make(map[string][]netip.Addr)}
+	pinnedStreamDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		pinnedAddr, err := pins.pin(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return streamDialer.DialStream(ctx, pinnedAddr)
+	})
+	pinnedPacketDialer := transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		pinnedAddr, err := pins.pin(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return packetDialer.DialPacket(ctx, pinnedAddr)
+	})
+
+	var attempts []ConnectivityAttempt
+	for attempt := 0; attempt == 0 || attempt < pins.count(); attempt++ {
+		pins.setAttempt(attempt)
+		resolver, err := newResolver(pinnedStreamDialer, pinnedPacketDialer)
+		if err != nil {
+			return nil, err
+		}
+		startTime := time.Now()
+		result, err := TestConnectivityWithResolver(ctx, resolver, testDomain)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, ConnectivityAttempt{
+			IP:        pins.ip(attempt),
+			StartTime: startTime,
+			Duration:  time.Since(startTime),
+			Err:       result,
+		})
+	}
+	return attempts, nil
+}
+
+// ipPins selects the IP address to connect to on each attempt of [TestConnectivityWithResolverPerIP].
+type ipPins struct {
+	mu        sync.Mutex
+	attempt   int
+	firstHost string
+	resolved  map[string][]netip.Addr
+}
+
+func (p *ipPins) setAttempt(attempt int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempt = attempt
+}
+
+// count returns the number of IP addresses of the first host, or zero if it was not resolved.
+func (p *ipPins) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.resolved[p.firstHost])
+}
+
+// ip returns the IP address of the first host for the given attempt.
+func (p *ipPins) ip(attempt int) netip.Addr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	addrs := p.resolved[p.firstHost]
+	if len(addrs) == 0 {
+		return netip.Addr{}
+	}
+	return addrs[min(attempt, len(addrs)-1)]
+}
+
+// pin returns addr with the host replaced by its IP address for the current attempt.
+func (p *ipPins) pin(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("address is not valid host:port: %w", err)
+	}
+	p.mu.Lock()
+	addrs, ok := p.resolved[host]
+	p.mu.Unlock()
+	if !ok {
+		if ip, err := netip.ParseAddr(host); err == nil {
+			addrs = []netip.Addr{ip}
+		} else {
+			addrs, err = lookupNetIP(ctx, "ip", host)
+			if err == nil && len(addrs) == 0 {
+				err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		for i, ip := range addrs {
+			addrs[i] = ip.Unmap()
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !ok {
+		p.resolved[host] = addrs
+	}
+	if p.firstHost == "" {
+		p.firstHost = host
+	}
+	return net.JoinHostPort(addrs[min(p.attempt, len(addrs)-1)].String(), port), nil
+}
@@ -16,9 +16,15 @@ package connectivity
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
 	"errors"
 	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"os"
 	"runtime"
 	"sync"
@@ -78,6 +84,7 @@ func TestTestResolverStreamConnectivityRefused(t *testing.T) {
 	require.ErrorIs(t, result.Err, dns.ErrDial)
 	require.Equal(t, "connect", result.Op)
 	require.Equal(t, "ECONNREFUSED", result.PosixError)
+	require.Equal(t, ErrorCodeConnectionRefused, result.Code)
 
 	var sysErr *os.SyscallError
 	require.ErrorAs(t, result.Err, &sysErr)
@@ -114,6 +121,7 @@ func TestTestResolverStreamConnectivityReset(t *testing.T) {
 	require.Equalf(t, "receive", result.Op, "Wrong test operation. Error: %v", result.Err)
 	require.ErrorIs(t, result.Err, dns.ErrReceive)
 	require.Equal(t, "ECONNRESET", result.PosixError)
+	require.Equal(t, ErrorCodeConnectionReset, result.Code)
 
 	var sysErr *os.SyscallError
 	require.ErrorAs(t, result.Err, &sysErr)
@@ -210,4 +218,184 @@ func TestTestPacketPacketConnectivityOk(t *testing.T) {
 	require.Nil(t, result)
 }
 
+func TestTestPacketConnectivityOk(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		n, clientAddr, err := server.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		server.WriteTo(buf[:n], clientAddr)
+	}()
+
+	result, err := TestPacketConnectivity(context.Background(), &transport.UDPDialer{}, server.LocalAddr().String())
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
+
+func TestTestPacketConnectivityUnreachable(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ICMP unreachable is only reported on Linux")
+	}
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	// Close right away to ensure the port is closed.
+	server.Close()
+
+	result, err := TestPacketConnectivity(context.Background(), &transport.UDPDialer{}, server.LocalAddr().String())
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, "receive", result.Op)
+	require.Equal(t, "ECONNREFUSED", result.PosixError)
+}
+
+func TestTestPacketConnectivityTimeout(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+
+	// The server never responds.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	result, err := TestPacketConnectivity(ctx, &transport.UDPDialer{}, server.LocalAddr().String())
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, "receive", result.Op)
+	require.Equal(t, "ETIMEDOUT", result.PosixError)
+}
+
+// HTTP tests
+
+func TestTestStreamConnectivityWithHTTPOk(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	result, err := testStreamConnectivityWithHTTP(context.Background(), &transport.TCPDialer{}, server.URL, &tls.Config{RootCAs: rootCAs})
+	require.NoError(t, err)
+	require.Nil(t, result.Error)
+	require.Equal(t, http.StatusNoContent, result.StatusCode)
+	require.NotNil(t, result.TLS)
+	require.Equal(t, uint16(tls.VersionTLS13), result.TLS.Version)
+	require.NotZero(t, result.ConnectDuration)
+	require.NotZero(t, result.TLSHandshakeDuration)
+	require.NotZero(t, result.ResponseDuration)
+}
+
+func TestTestStreamConnectivityWithHTTPRefused(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IP{127, 0, 0, 1}})
+	require.NoError(t, err)
+	// Close right away to ensure the port is closed.
+	listener.Close()
+
+	result, err := TestStreamConnectivityWithHTTP(context.Background(), &transport.TCPDialer{}, "https://"+listener.Addr().String())
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	require.Equal(t, "connect", result.Error.Op)
+	require.Equal(t, "ECONNREFUSED", result.Error.PosixError)
+	require.Nil(t, result.TLS)
+}
+
+func TestTestStreamConnectivityWithHTTPHandshakeFailure(t *testing.T) {
+	// A plain HTTP server fails the TLS handshake.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	result, err := TestStreamConnectivityWithHTTP(context.Background(), &transport.TCPDialer{}, "https://"+server.Listener.Addr().String())
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	require.Equal(t, "tls_handshake", result.Error.Op)
+	require.Nil(t, result.TLS)
+	require.NotZero(t, result.ConnectDuration)
+}
+
+func TestTestStreamConnectivityWithHTTPInvalidURL(t *testing.T) {
+	_, err := TestStreamConnectivityWithHTTP(context.Background(), &transport.TCPDialer{}, "ftp://example.com")
+	require.Error(t, err)
+}
+
 // TODO: Add more tests
+
+// Per-IP tests
+
+// runTestDNSServer runs a DNS-over-TCP server that responds to every query with an empty answer.
+func runTestDNSServer(t *testing.T) *net.TCPListener {
+	var running sync.WaitGroup
+	listener := runTestTCPServer(t, func(conn *net.TCPConn) {
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		request := make([]byte, length)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		var msg dnsmessage.Message
+		require.NoError(t, msg.Unpack(request))
+		msg.Header.Response = true
+		response, err := msg.AppendPack(make([]byte, 2))
+		require.NoError(t, err)
+		binary.BigEndian.PutUint16(response, uint16(len(response)-2))
+		_, err = conn.Write(response)
+		require.NoError(t, err)
+	}, &running)
+	t.Cleanup(func() {
+		listener.Close()
+		running.Wait()
+	})
+	return listener
+}
+
+func TestTestConnectivityWithResolverPerIP(t *testing.T) {
+	listener := runTestDNSServer(t)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	originalLookup := lookupNetIP
+	defer func() { lookupNetIP = originalLookup }()
+	// Nothing listens on 127.0.0.2, so the second attempt fails.
+	lookupNetIP = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		require.Equal(t, "dns.test", host)
+		return []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("127.0.0.2")}, nil
+	}
+
+	attempts, err := TestConnectivityWithResolverPerIP(context.Background(), &transport.TCPDialer{}, &transport.UDPDialer{},
+		func(sd transport.StreamDialer, pd transport.PacketDialer) (dns.Resolver, error) {
+			return dns.NewTCPResolver(sd, net.JoinHostPort("dns.test", port)), nil
+		}, "example.com")
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+
+	require.Equal(t, netip.MustParseAddr("127.0.0.1"), attempts[0].IP)
+	require.Nil(t, attempts[0].Err)
+	require.NotZero(t, attempts[0].StartTime)
+
+	require.Equal(t, netip.MustParseAddr("127.0.0.2"), attempts[1].IP)
+	require.NotNil(t, attempts[1].Err)
+	require.Equal(t, "connect", attempts[1].Err.Op)
+	require.Equal(t, ErrorCodeConnectionRefused, attempts[1].Err.Code)
+}
+
+func TestTestConnectivityWithResolverPerIPLookupFailure(t *testing.T) {
+	originalLookup := lookupNetIP
+	defer func() { lookupNetIP = originalLookup }()
+	lookupNetIP = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	attempts, err := TestConnectivityWithResolverPerIP(context.Background(), &transport.TCPDialer{}, &transport.UDPDialer{},
+		func(sd transport.StreamDialer, pd transport.PacketDialer) (dns.Resolver, error) {
+			return dns.NewTCPResolver(sd, "dns.test:53"), nil
+		}, "example.com")
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	require.False(t, attempts[0].IP.IsValid())
+	require.NotNil(t, attempts[0].Err)
+	require.Equal(t, "connect", attempts[0].Err.Op)
+}
@@ -15,10 +15,81 @@
 package connectivity
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"syscall"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+)
+
+// ConnectivityErrorCode is a platform-independent classification of a connectivity error, stable for reporting.
+type ConnectivityErrorCode string
+
+const (
+	// The connection was refused by the destination, or a closed UDP port was reported by ICMP.
+	ErrorCodeConnectionRefused ConnectivityErrorCode = "CONNECTION_REFUSED"
+	// The connection was reset or aborted. A reset right after sending a TLS ClientHello is a common sign of
+	// blocking by RST injection.
+	ErrorCodeConnectionReset ConnectivityErrorCode = "CONNECTION_RESET"
+	// The operation timed out, for example because the packets were dropped.
+	ErrorCodeTimeout ConnectivityErrorCode = "TIMEOUT"
+	// The destination host is unreachable.
+	ErrorCodeHostUnreachable ConnectivityErrorCode = "HOST_UNREACHABLE"
+	// The destination network is unreachable, or the local network is down.
+	ErrorCodeNetworkUnreachable ConnectivityErrorCode = "NETWORK_UNREACHABLE"
+	// The connection was closed before the expected data was received.
+	ErrorCodeUnexpectedEOF ConnectivityErrorCode = "UNEXPECTED_EOF"
+	// The domain resolution failed, or the DNS response was invalid.
+	ErrorCodeDNSFailure ConnectivityErrorCode = "DNS_FAILURE"
+	// The TLS handshake failed for reasons other than the network, like an invalid certificate.
+	ErrorCodeTLSFailure ConnectivityErrorCode = "TLS_FAILURE"
+	// The error doesn't match any of the known classes.
+	ErrorCodeUnknown ConnectivityErrorCode = "UNKNOWN"
 )
 
+// posixErrorCodes maps the POSIX error names, as returned by errnoName, to their [ConnectivityErrorCode].
+// Windows errors are mapped to POSIX names by errnoName, so the mapping applies to all platforms.
+var posixErrorCodes = map[string]ConnectivityErrorCode{
+	"ECONNREFUSED": ErrorCodeConnectionRefused,
+	"ECONNRESET":   ErrorCodeConnectionReset,
+	"ECONNABORTED": ErrorCodeConnectionReset,
+	"EPIPE":        ErrorCodeConnectionReset,
+	"ETIMEDOUT":    ErrorCodeTimeout,
+	"EHOSTUNREACH": ErrorCodeHostUnreachable,
+	"EHOSTDOWN":    ErrorCodeHostUnreachable,
+	"ENETUNREACH":  ErrorCodeNetworkUnreachable,
+	"ENETDOWN":     ErrorCodeNetworkUnreachable,
+}
+
+// errorCode classifies the error of an operation, given the POSIX error name, if any.
+// Network errors take precedence, so that a reset during the TLS handshake is reported as a reset.
+func errorCode(op string, posixError string, err error) ConnectivityErrorCode {
+	if code, ok := posixErrorCodes[posixError]; ok {
+		return code
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorCodeUnexpectedEOF
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, dns.ErrBadResponse) {
+		return ErrorCodeDNSFailure
+	}
+	var alertErr tls.AlertError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if op == "tls_handshake" || errors.As(err, &alertErr) || errors.As(err, &recordErr) || errors.As(err, &certErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) {
+		return ErrorCodeTLSFailure
+	}
+	return ErrorCodeUnknown
+}
+
 func errnoName(errno syscall.Errno) string {
 	if name := systemErrnoName(errno); len(name) > 0 {
 		return name
@@ -0,0 +1,65 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestMakeConnectivityErrorCode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		op   string
+		err  error
+		code ConnectivityErrorCode
+	}{
+		{"refused", "connect", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorCodeConnectionRefused},
+		{"reset", "receive", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, ErrorCodeConnectionReset},
+		{"reset on handshake", "tls_handshake", fmt.Errorf("handshake: %w", syscall.ECONNRESET), ErrorCodeConnectionReset},
+		{"aborted", "send", syscall.ECONNABORTED, ErrorCodeConnectionReset},
+		{"broken pipe", "send", syscall.EPIPE, ErrorCodeConnectionReset},
+		{"errno timeout", "connect", syscall.ETIMEDOUT, ErrorCodeTimeout},
+		{"deadline", "receive", timeoutError{}, ErrorCodeTimeout},
+		{"host unreachable", "connect", syscall.EHOSTUNREACH, ErrorCodeHostUnreachable},
+		{"network unreachable", "connect", syscall.ENETUNREACH, ErrorCodeNetworkUnreachable},
+		{"eof", "receive", io.EOF, ErrorCodeUnexpectedEOF},
+		{"unexpected eof", "receive", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), ErrorCodeUnexpectedEOF},
+		{"dns lookup", "connect", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, ErrorCodeDNSFailure},
+		{"dns bad response", "receive", fmt.Errorf("%w: id mismatch", dns.ErrBadResponse), ErrorCodeDNSFailure},
+		{"tls alert", "http", fmt.Errorf("remote error: %w", tls.AlertError(40)), ErrorCodeTLSFailure},
+		{"tls handshake", "tls_handshake", errors.New("tls: first record does not look like a TLS handshake"), ErrorCodeTLSFailure},
+		{"unknown", "http", errors.New("something else"), ErrorCodeUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := makeConnectivityError(tc.op, tc.err)
+			require.Equal(t, tc.code, result.Code)
+		})
+	}
+}
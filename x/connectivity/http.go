@@ -0,0 +1,159 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// HTTPTestResult captures the outcome of an HTTP fetch test, with the timing of each phase.
+type HTTPTestResult struct {
+	// Time to establish the stream connection.
+	ConnectDuration time.Duration
+	// Time of the TLS handshake. Zero for http:// URLs.
+	TLSHandshakeDuration time.Duration
+	// Time from the connection being ready to getting the first byte of the response.
+	ResponseDuration time.Duration
+	// The TLS connection state, when the handshake completed. It has the negotiated version and ALPN protocol.
+	TLS *tls.ConnectionState
+	// The HTTP status code, when a response was received.
+	StatusCode int
+	// The error found, or nil if the fetch succeeded. The Op is "connect", "tls_handshake" or "http".
+	Error *ConnectivityError
+}
+
+// TestStreamConnectivityWithHTTP tests whether we can fetch the targetURL using the given [transport.StreamDialer].
+// It does not follow redirects, and only reads the response headers.
+// Invalid tests that cannot assert connectivity, like an invalid URL, will return (nil, error).
+// Valid tests will return (*HTTPTestResult, nil), where the result has the Error field set if the fetch failed,
+// with the phase that failed as the Op. That helps distinguish blocking at the TCP or TLS level, like a reset
+// triggered by the SNI, from errors of the upstream server.
+func TestStreamConnectivityWithHTTP(ctx context.Context, dialer transport.StreamDialer, targetURL string) (*HTTPTestResult, error) {
+	return testStreamConnectivityWithHTTP(ctx, dialer, targetURL, nil)
+}
+
+func testStreamConnectivityWithHTTP(ctx context.Context, dialer transport.StreamDialer, targetURL string, tlsConfig *tls.Config) (*HTTPTestResult, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %v", parsedURL.Scheme)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		// Default deadline is 5 seconds.
+		deadline := time.Now().Add(5 * time.Second)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		// Releases the timer.
+		defer cancel()
+	}
+
+	// The phases may run in a goroutine of the HTTP transport, which may outlive the request on errors,
+	// so they record their outcome in the phase variables, and not in the result.
+	var mu sync.Mutex
+	var phases HTTPTestResult
+	var connected, tlsDone bool
+	var connectErr, tlsErr error
+	var tlsStart, readyTime time.Time
+	httpTransport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			connectStart := time.Now()
+			conn, err := dialer.DialStream(ctx, addr)
+			mu.Lock()
+			defer mu.Unlock()
+			phases.ConnectDuration = time.Since(connectStart)
+			readyTime = time.Now()
+			connected = err == nil
+			connectErr = err
+			return conn, err
+		},
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}
+	defer httpTransport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: httpTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			phases.TLSHandshakeDuration = time.Since(tlsStart)
+			tlsDone = err == nil
+			readyTime = time.Now()
+			tlsErr = err
+			if err == nil {
+				phases.TLS = &state
+			}
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	mu.Lock()
+	defer mu.Unlock()
+	result := &HTTPTestResult{
+		ConnectDuration:      phases.ConnectDuration,
+		TLSHandshakeDuration: phases.TLSHandshakeDuration,
+		TLS:                  phases.TLS,
+	}
+	if err != nil {
+		// Attribute the error to the phase that didn't complete.
+		switch {
+		case connectErr != nil:
+			result.Error = makeConnectivityError("connect", connectErr)
+		case !connected:
+			result.Error = makeConnectivityError("connect", err)
+		case tlsErr != nil:
+			result.Error = makeConnectivityError("tls_handshake", tlsErr)
+		case parsedURL.Scheme == "https" && !tlsDone:
+			result.Error = makeConnectivityError("tls_handshake", err)
+		default:
+			result.Error = makeConnectivityError("http", err)
+		}
+		return result, nil
+	}
+	result.ResponseDuration = time.Since(readyTime)
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	return result, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+)
+
+// NewResolverFunc creates the [dns.Resolver] to test for the given resolver address and protocol, like "tcp" or "udp".
+type NewResolverFunc func(resolverAddr string, proto string) (dns.Resolver, error)
+
+// MatrixResult is the result of one test of [TestMatrix].
+type MatrixResult struct {
+	// The resolver address and protocol the test used
+	Resolver string
+	Proto    string
+	// When the test started
+	StartTime time.Time
+	// How long the test took
+	Duration time.Duration
+	// The error of the test, or nil if it succeeded. It has Op "setup" if the resolver could not be created.
+	Err *ConnectivityError
+}
+
+// ResolverSummary aggregates the results of the tests of one resolver in [TestMatrix].
+type ResolverSummary struct {
+	Resolver string
+	// The number of tests run with the resolver, one per protocol
+	Tests int
+	// The number of tests that succeeded
+	Successes int
+	// The average duration of the successful tests, or zero if none succeeded
+	AverageDuration time.Duration
+}
+
+// SuccessRate returns the fraction of the tests of the resolver that succeeded, between 0 and 1.
+func (s ResolverSummary) SuccessRate() float64 {
+	if s.Tests == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Tests)
+}
+
+// MatrixSummary is the outcome of [TestMatrix].
+type MatrixSummary struct {
+	// The results of all the tests, ordered by resolver and then protocol, as in the input
+	Results []MatrixResult
+	// The summary of each resolver, in the input order
+	Resolvers []ResolverSummary
+	// The resolver with the lowest average duration among the ones with the highest success rate,
+	// or empty if no test succeeded
+	FastestResolver string
+}
+
+// MatrixTestFunc runs the test of one resolver address and protocol of [RunMatrix]. It returns the observed
+// connectivity error, or nil if there's connectivity, and a non-nil error if the test could not run.
+type MatrixTestFunc func(ctx context.Context, resolverAddr string, proto string) (*ConnectivityError, error)
+
+// TestMatrix runs [TestConnectivityWithResolver] for every combination of resolver and protocol, and summarizes
+// the results. It creates the resolvers with newResolver, and runs up to concurrency tests in parallel. A concurrency
+// of zero or less runs the tests sequentially.
+//
+// If newResolver fails, the result of that test has an error with Op "setup", and the other tests still run.
+// The tests that can't assert connectivity make TestMatrix return an error and stop the pending tests.
+func TestMatrix(ctx context.Context, newResolver NewResolverFunc, resolvers []string, protocols []string, testDomain string, concurrency int) (*MatrixSummary, error) {
+	if newResolver == nil {
+		return nil, errors.New("argument newResolver must not be nil")
+	}
+	return RunMatrix(ctx, func(ctx context.Context, resolverAddr string, proto string) (*ConnectivityError, error) {
+		resolver, err := newResolver(resolverAddr, proto)
+		if err != nil {
+			return &ConnectivityError{Op: "setup", Err: err}, nil
+		}
+		return TestConnectivityWithResolver(ctx, resolver, testDomain)
+	}, resolvers, protocols, concurrency)
+}
+
+// RunMatrix is like [TestMatrix], but runs the given test for every combination of resolver and protocol. It's
+// useful for tests that need more than a [dns.Resolver], like [TestConnectivityWithResolverPerIP].
+//
+// The tests that return an error make RunMatrix return an error and stop the pending tests.
+func RunMatrix(ctx context.Context, test MatrixTestFunc, resolvers []string, protocols []string, concurrency int) (*MatrixSummary, error) {
+	if test == nil {
+		return nil, errors.New("argument test must not be nil")
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]MatrixResult, len(resolvers)*len(protocols))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	workers := make(chan struct{}, concurrency)
+	for ri, resolverAddr := range resolvers {
+		for pi, proto := range protocols {
+			result := &results[ri*len(protocols)+pi]
+			result.Resolver = resolverAddr
+			result.Proto = proto
+			select {
+			case workers <- struct{}{}:
+			case <-ctx.Done():
+				continue
+			}
+			if ctx.Err() != nil {
+				// A test failed while waiting for the worker.
+				<-workers
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-workers
+					wg.Done()
+				}()
+				result.StartTime = time.Now()
+				testErr, err := test(ctx, result.Resolver, result.Proto)
+				result.Duration = time.Since(result.StartTime)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("test of resolver %v over %v failed: %w", result.Resolver, result.Proto, err)
+						cancel()
+					})
+					return
+				}
+				result.Err = testErr
+			}()
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return summarizeMatrix(resolvers, protocols, results), nil
+}
+
+// summarizeMatrix aggregates the results, which are ordered by resolver and then protocol.
+func summarizeMatrix(resolvers []string, protocols []string, results []MatrixResult) *MatrixSummary {
+	summary := &MatrixSummary{Results: results, Resolvers: make([]ResolverSummary, len(resolvers))}
+	var best *ResolverSummary
+	for ri, resolverAddr := range resolvers {
+		resolverSummary := &summary.Resolvers[ri]
+		resolverSummary.Resolver = resolverAddr
+		var totalDuration time.Duration
+		for _, result := range results[ri*len(protocols) : (ri+1)*len(protocols)] {
+			resolverSummary.Tests++
+			if result.Err == nil {
+				resolverSummary.Successes++
+				totalDuration += result.Duration
+			}
+		}
+		if resolverSummary.Successes == 0 {
+			continue
+		}
+		resolverSummary.AverageDuration = totalDuration / time.Duration(resolverSummary.Successes)
+		if best == nil || resolverSummary.SuccessRate() > best.SuccessRate() ||
+			(resolverSummary.SuccessRate() == best.SuccessRate() && resolverSummary.AverageDuration < best.AverageDuration) {
+			best = resolverSummary
+		}
+	}
+	if best != nil {
+		summary.FastestResolver = best.Resolver
+	}
+	return summary
+}
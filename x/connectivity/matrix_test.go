@@ -0,0 +1,164 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newFakeResolver returns a resolver that responds after delay, or fails to connect if fail is set.
+func newFakeResolver(delay time.Duration, fail bool) dns.Resolver {
+	return dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if fail {
+			return nil, dns.ErrDial
+		}
+		return &dnsmessage.Message{}, nil
+	})
+}
+
+func TestTestMatrix(t *testing.T) {
+	newResolver := func(resolverAddr string, proto string) (dns.Resolver, error) {
+		switch {
+		case resolverAddr == "slow":
+			return newFakeResolver(50*time.Millisecond, false), nil
+		case resolverAddr == "fast":
+			return newFakeResolver(0, false), nil
+		case resolverAddr == "udp-only" && proto == "tcp":
+			return newFakeResolver(0, true), nil
+		default:
+			return newFakeResolver(0, false), nil
+		}
+	}
+	summary, err := TestMatrix(context.Background(), newResolver, []string{"slow", "fast", "udp-only"}, []string{"tcp", "udp"}, "example.com", 4)
+	require.NoError(t, err)
+
+	require.Len(t, summary.Results, 6)
+	require.Equal(t, "slow", summary.Results[0].Resolver)
+	require.Equal(t, "tcp", summary.Results[0].Proto)
+	require.Equal(t, "udp-only", summary.Results[4].Resolver)
+	require.Equal(t, "tcp", summary.Results[4].Proto)
+	require.NotNil(t, summary.Results[4].Err)
+	require.Equal(t, "connect", summary.Results[4].Err.Op)
+	require.Nil(t, summary.Results[5].Err)
+
+	require.Len(t, summary.Resolvers, 3)
+	require.Equal(t, ResolverSummary{Resolver: "slow", Tests: 2, Successes: 2, AverageDuration: summary.Resolvers[0].AverageDuration}, summary.Resolvers[0])
+	require.GreaterOrEqual(t, summary.Resolvers[0].AverageDuration, 50*time.Millisecond)
+	require.Equal(t, 1.0, summary.Resolvers[1].SuccessRate())
+	require.Equal(t, 0.5, summary.Resolvers[2].SuccessRate())
+	require.Equal(t, "fast", summary.FastestResolver)
+}
+
+func TestTestMatrix_Concurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	newResolver := func(resolverAddr string, proto string) (dns.Resolver, error) {
+		return dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				max := maxRunning.Load()
+				if n <= max || maxRunning.CompareAndSwap(max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return &dnsmessage.Message{}, nil
+		}), nil
+	}
+	resolvers := []string{"a", "b", "c", "d", "e", "f"}
+	summary, err := TestMatrix(context.Background(), newResolver, resolvers, []string{"tcp", "udp"}, "example.com", 3)
+	require.NoError(t, err)
+	require.Len(t, summary.Results, 12)
+	require.LessOrEqual(t, maxRunning.Load(), int32(3))
+	require.Greater(t, maxRunning.Load(), int32(1))
+}
+
+func TestTestMatrix_NoSuccess(t *testing.T) {
+	newResolver := func(resolverAddr string, proto string) (dns.Resolver, error) {
+		return newFakeResolver(0, true), nil
+	}
+	summary, err := TestMatrix(context.Background(), newResolver, []string{"a", "b"}, []string{"udp"}, "example.com", 0)
+	require.NoError(t, err)
+	require.Equal(t, "", summary.FastestResolver)
+	require.Equal(t, 0.0, summary.Resolvers[0].SuccessRate())
+	require.Equal(t, time.Duration(0), summary.Resolvers[0].AverageDuration)
+}
+
+func TestTestMatrix_NewResolverError(t *testing.T) {
+	newErr := errors.New("bad resolver")
+	newResolver := func(resolverAddr string, proto string) (dns.Resolver, error) {
+		if resolverAddr == "bad" {
+			return nil, newErr
+		}
+		return newFakeResolver(0, false), nil
+	}
+	summary, err := TestMatrix(context.Background(), newResolver, []string{"a", "bad", "b"}, []string{"udp"}, "example.com", 1)
+	require.NoError(t, err)
+	require.Len(t, summary.Results, 3)
+	require.Nil(t, summary.Results[0].Err)
+	require.NotNil(t, summary.Results[1].Err)
+	require.Equal(t, "setup", summary.Results[1].Err.Op)
+	require.ErrorIs(t, summary.Results[1].Err, newErr)
+	require.Nil(t, summary.Results[2].Err)
+	require.Equal(t, 0.0, summary.Resolvers[1].SuccessRate())
+	require.Equal(t, 1.0, summary.Resolvers[2].SuccessRate())
+}
+
+func TestRunMatrix_TestError(t *testing.T) {
+	testErr := errors.New("test failed to run")
+	var runs atomic.Int32
+	test := func(ctx context.Context, resolverAddr string, proto string) (*ConnectivityError, error) {
+		runs.Add(1)
+		if resolverAddr == "bad" {
+			return nil, testErr
+		}
+		return nil, nil
+	}
+	_, err := RunMatrix(context.Background(), test, []string{"bad", "a", "b", "c"}, []string{"udp"}, 1)
+	require.ErrorIs(t, err, testErr)
+	// The tests after the failure are not run.
+	require.Equal(t, int32(1), runs.Load())
+}
+
+func TestRunMatrix_Results(t *testing.T) {
+	test := func(ctx context.Context, resolverAddr string, proto string) (*ConnectivityError, error) {
+		if proto == "tcp" {
+			return &ConnectivityError{Op: "connect", Err: errors.New("refused")}, nil
+		}
+		return nil, nil
+	}
+	summary, err := RunMatrix(context.Background(), test, []string{"a", "b"}, []string{"tcp", "udp"}, 2)
+	require.NoError(t, err)
+	require.Len(t, summary.Results, 4)
+	require.Equal(t, "b", summary.Results[2].Resolver)
+	require.Equal(t, "tcp", summary.Results[2].Proto)
+	require.Equal(t, "connect", summary.Results[2].Err.Op)
+	require.Nil(t, summary.Results[3].Err)
+	require.Equal(t, 0.5, summary.Resolvers[0].SuccessRate())
+	require.Equal(t, 0.5, summary.Resolvers[1].SuccessRate())
+}
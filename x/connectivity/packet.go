@@ -0,0 +1,96 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// packetProbeInterval is how long to wait for a response before sending the probe again, since UDP packets may be lost.
+const packetProbeInterval = 1 * time.Second
+
+// newPacketProbe returns a DNS query for the root name servers, which is small and gets a response from any DNS server.
+func newPacketProbe() ([]byte, error) {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: dnsmessage.MustNewName("."), Type: dnsmessage.TypeNS, Class: dnsmessage.ClassINET},
+		},
+	}
+	return msg.Pack()
+}
+
+// TestPacketConnectivity tests whether we can get a UDP response from the DNS server at addr using the given
+// [transport.PacketDialer]. It opens a packet connection, sends a DNS query as a probe, and waits for any response,
+// resending the probe every second in case it's lost.
+// Invalid tests that cannot assert connectivity will return (nil, error).
+// Valid tests will return (*ConnectivityError, nil), where *ConnectivityError will be nil if there's connectivity or
+// a structure with details of the error found. No response results in a "receive" error with "ETIMEDOUT", and
+// an ICMP port unreachable message results in a "receive" error with "ECONNREFUSED", when reported by the system.
+func TestPacketConnectivity(ctx context.Context, dialer transport.PacketDialer, addr string) (*ConnectivityError, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		// Default deadline is 5 seconds.
+		deadline := time.Now().Add(5 * time.Second)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		// Releases the timer.
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+	probe, err := newPacketProbe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe: %w", err)
+	}
+
+	conn, err := dialer.DialPacket(ctx, addr)
+	if err != nil {
+		return makeConnectivityError("connect", err), nil
+	}
+	defer conn.Close()
+	// Unblock the read if the context is cancelled.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, 512)
+	for {
+		if _, err := conn.Write(probe); err != nil {
+			return makeConnectivityError("send", err), nil
+		}
+		readDeadline := time.Now().Add(packetProbeInterval)
+		if readDeadline.After(deadline) {
+			readDeadline = deadline
+		}
+		conn.SetReadDeadline(readDeadline)
+		_, err := conn.Read(buf)
+		if err == nil {
+			return nil, nil
+		}
+		if isTimeout(err) && ctx.Err() == nil {
+			// Probe or response may have been lost. Try again.
+			continue
+		}
+		return makeConnectivityError("receive", err), nil
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// TLSBlocking is the classification of the blocking of a TLS site, as returned by [DiagnoseTLSBlocking].
+type TLSBlocking string
+
+const (
+	// The site is reachable: the TLS handshake with the real SNI succeeds, and DNS returns working addresses.
+	TLSBlockingNone TLSBlocking = "OK"
+	// The connection to the IP address fails, or no TLS handshake succeeds with it, regardless of the SNI.
+	TLSBlockingIP TLSBlocking = "IP_BLOCKED"
+	// The TLS handshake fails with the real SNI, but reaches the server with a decoy SNI.
+	TLSBlockingSNI TLSBlocking = "SNI_BLOCKED"
+	// The site is reachable at the IP address, but the DNS resolution fails, or returns addresses that don't work.
+	TLSBlockingDNS TLSBlocking = "DNS_BLOCKED"
+)
+
+// The decoy SNI for [DiagnoseTLSBlocking]. It's unlikely to be blocked.
+const decoySNI = "www.example.com"
+
+// TLSBlockingDiagnosis is the result of [DiagnoseTLSBlocking], with the outcome of each step.
+type TLSBlockingDiagnosis struct {
+	Blocking TLSBlocking
+	// The error of the connection to the IP address, if it failed.
+	ConnectError *ConnectivityError
+	// The error of the TLS handshake with the real SNI, if it failed.
+	SNIError *ConnectivityError
+	// The error of the TLS handshake with the decoy SNI, if it ran and failed. A failure that reaches the server,
+	// like a TLS alert, counts as a success.
+	DecoyError *ConnectivityError
+	// The addresses the SNI resolved to, if the DNS step ran.
+	ResolvedAddresses []netip.Addr
+	// The error of the DNS step, if it failed.
+	DNSError *ConnectivityError
+}
+
+// Allows for overriding in tests.
+var lookupNetIP = net.DefaultResolver.LookupNetIP
+
+// DiagnoseTLSBlocking determines whether the TLS site with the given SNI, served at ip, is blocked, and how.
+// The ip can have a port, which defaults to 443. It runs these steps, stopping at the first that identifies the
+// blocking:
+//
+//  1. Connect to ip with dialer. If it fails, the site is [TLSBlockingIP].
+//  2. Do a TLS handshake with the real SNI. If it fails, do a TLS handshake with a decoy SNI: if that reaches the
+//     server, the site is [TLSBlockingSNI], otherwise it's [TLSBlockingIP].
+//  3. Resolve the SNI with the system resolver. If that fails, or the TLS handshake fails on the first address
+//     returned, when it's not ip, the site is [TLSBlockingDNS]. Otherwise it's [TLSBlockingNone].
+//
+// Each step uses a new connection. The certificates are not validated, since the goal is to detect blocking, not to
+// authenticate the server. Invalid inputs return an error.
+func DiagnoseTLSBlocking(ctx context.Context, dialer transport.StreamDialer, ip string, sni string) (*TLSBlockingDiagnosis, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	host, port, err := net.SplitHostPort(ip)
+	if err != nil {
+		host, port = ip, "443"
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address: %w", err)
+	}
+	if sni == "" {
+		return nil, errors.New("argument sni must not be empty")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		// Default deadline is 20 seconds, for all the steps.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 20*time.Second)
+		defer cancel()
+	}
+	address := net.JoinHostPort(addr.String(), port)
+	result := &TLSBlockingDiagnosis{}
+
+	conn, err := dialer.DialStream(ctx, address)
+	if err != nil {
+		result.ConnectError = makeConnectivityError("connect", err)
+		result.Blocking = TLSBlockingIP
+		return result, nil
+	}
+	conn.Close()
+
+	if result.SNIError = testTLSHandshake(ctx, dialer, address, sni); result.SNIError != nil {
+		result.DecoyError = testTLSHandshake(ctx, dialer, address, decoySNI)
+		if reachedServer(result.DecoyError) {
+			result.Blocking = TLSBlockingSNI
+		} else {
+			result.Blocking = TLSBlockingIP
+		}
+		return result, nil
+	}
+
+	result.ResolvedAddresses, err = lookupNetIP(ctx, "ip", sni)
+	if err == nil && len(result.ResolvedAddresses) == 0 {
+		err = &net.DNSError{Err: "no addresses", Name: sni, IsNotFound: true}
+	}
+	if err != nil {
+		result.DNSError = makeConnectivityError("resolve", err)
+		result.Blocking = TLSBlockingDNS
+		return result, nil
+	}
+	if resolved := result.ResolvedAddresses[0].Unmap(); resolved != addr.Unmap() {
+		resolvedAddress := net.JoinHostPort(resolved.String(), port)
+		if result.DNSError = testTLSHandshake(ctx, dialer, resolvedAddress, sni); result.DNSError != nil {
+			result.Blocking = TLSBlockingDNS
+			return result, nil
+		}
+	}
+	result.Blocking = TLSBlockingNone
+	return result, nil
+}
+
+// testTLSHandshake connects to address and does a TLS handshake with the given SNI.
+func testTLSHandshake(ctx context.Context, dialer transport.StreamDialer, address string, sni string) *ConnectivityError {
+	conn, err := dialer.DialStream(ctx, address)
+	if err != nil {
+		return makeConnectivityError("connect", err)
+	}
+	defer conn.Close()
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: sni, InsecureSkipVerify: true})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return makeConnectivityError("tls_handshake", err)
+	}
+	return nil
+}
+
+// reachedServer returns whether the TLS handshake got a response from the server, even if it failed.
+func reachedServer(err *ConnectivityError) bool {
+	return err == nil || err.Code == ErrorCodeTLSFailure
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivity
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// censorConn resets the connection when a write has the blocked SNI.
+type censorConn struct {
+	transport.StreamConn
+	blockedSNI string
+}
+
+func (c *censorConn) Write(b []byte) (int, error) {
+	if c.blockedSNI != "" && bytes.Contains(b, []byte(c.blockedSNI)) {
+		c.StreamConn.Close()
+		return 0, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.ECONNRESET)}
+	}
+	return c.StreamConn.Write(b)
+}
+
+// newCensorDialer returns a dialer that blocks the TLS handshakes with the given SNI.
+// An empty blockedSNI blocks nothing, and "*" blocks all the handshakes.
+func newCensorDialer(blockedSNI string) transport.StreamDialer {
+	var dialer transport.TCPDialer
+	return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		conn, err := dialer.DialStream(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		if blockedSNI == "*" {
+			// All ClientHellos have the TLS handshake record type and version.
+			return &censorConn{conn, "\x16\x03"}, nil
+		}
+		return &censorConn{conn, blockedSNI}, nil
+	})
+}
+
+func setLookupNetIP(t *testing.T, lookup func(ctx context.Context, network, host string) ([]netip.Addr, error)) {
+	original := lookupNetIP
+	lookupNetIP = lookup
+	t.Cleanup(func() { lookupNetIP = original })
+}
+
+func resolveTo(addrs ...string) func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	return func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		var result []netip.Addr
+		for _, addr := range addrs {
+			result = append(result, netip.MustParseAddr(addr))
+		}
+		return result, nil
+	}
+}
+
+func runTestTLSServer(t *testing.T) string {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	return server.Listener.Addr().String()
+}
+
+func TestDiagnoseTLSBlocking_OK(t *testing.T) {
+	address := runTestTLSServer(t)
+	setLookupNetIP(t, resolveTo("127.0.0.1"))
+
+	result, err := DiagnoseTLSBlocking(context.Background(), newCensorDialer(""), address, "site.example")
+	require.NoError(t, err)
+	require.Equal(t, TLSBlockingNone, result.Blocking)
+	require.Nil(t, result.ConnectError)
+	require.Nil(t, result.SNIError)
+	require.Nil(t, result.DNSError)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("127.0.0.1")}, result.ResolvedAddresses)
+}
+
+func TestDiagnoseTLSBlocking_SNIBlocked(t *testing.T) {
+	address := runTestTLSServer(t)
+	setLookupNetIP(t, resolveTo("127.0.0.1"))
+
+	result, err := DiagnoseTLSBlocking(context.Background(), newCensorDialer("site.example"), address, "site.example")
+	require.NoError(t, err)
+	require.Equal(t, TLSBlockingSNI, result.Blocking)
+	require.NotNil(t, result.SNIError)
+	require.Equal(t, "tls_handshake", result.SNIError.Op)
+	require.Equal(t, ErrorCodeConnectionReset, result.SNIError.Code)
+	require.Nil(t, result.DecoyError)
+}
+
+func TestDiagnoseTLSBlocking_IPBlockedOnConnect(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IP{127, 0, 0, 1}})
+	require.NoError(t, err)
+	// Close right away to ensure the port is closed.
+	require.NoError(t, listener.Close())
+
+	result, err := DiagnoseTLSBlocking(context.Background(), newCensorDialer(""), listener.Addr().String(), "site.example")
+	require.NoError(t, err)
+	require.Equal(t, TLSBlockingIP, result.Blocking)
+	require.NotNil(t, result.ConnectError)
+	require.Equal(t, ErrorCodeConnectionRefused, result.ConnectError.Code)
+}
+
+func TestDiagnoseTLSBlocking_IPBlockedOnHandshake(t *testing.T) {
+	address := runTestTLSServer(t)
+
+	result, err := DiagnoseTLSBlocking(context.Background(), newCensorDialer("*"), address, "site.example")
+	require.NoError(t, err)
+	require.Equal(t, TLSBlockingIP, result.Blocking)
+	require.Nil(t, result.ConnectError)
+	require.NotNil(t, result.SNIError)
+	require.NotNil(t, result.DecoyError)
+}
+
+func TestDiagnoseTLSBlocking_DNSBlocked(t *testing.T) {
+	address := runTestTLSServer(t)
+
+	t.Run("lookup failure", func(t *testing.T) {
+		setLookupNetIP(t, func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		})
+		result, err := DiagnoseTLSBlocking(context.Background(), newCensorDialer(""), address, "site.example")
+		require.NoError(t, err)
+		require.Equal(t, TLSBlockingDNS, result.Blocking)
+		require.NotNil(t, result.DNSError)
+		require.Equal(t, "resolve", result.DNSError.Op)
+	})
+
+	t.Run("bad address", func(t *testing.T) {
+		// Nothing listens on the test server port at 127.0.0.2.
+		setLookupNetIP(t, resolveTo("127.0.0.2"))
+		result, err := DiagnoseTLSBlocking(context.Background(), newCensorDialer(""), address, "site.example")
+		require.NoError(t, err)
+		require.Equal(t, TLSBlockingDNS, result.Blocking)
+		require.NotNil(t, result.DNSError)
+		require.Equal(t, "connect", result.DNSError.Op)
+	})
+}
+
+func TestDiagnoseTLSBlocking_InvalidArguments(t *testing.T) {
+	_, err := DiagnoseTLSBlocking(context.Background(), nil, "127.0.0.1", "site.example")
+	require.Error(t, err)
+	_, err = DiagnoseTLSBlocking(context.Background(), newCensorDialer(""), "site.example", "site.example")
+	require.Error(t, err)
+	_, err = DiagnoseTLSBlocking(context.Background(), newCensorDialer(""), "127.0.0.1", "")
+	require.Error(t, err)
+}
@@ -18,18 +18,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/Jigsaw-Code/outline-sdk/x/sockopt"
 )
 
 type disorderDialer struct {
-	dialer          transport.StreamDialer
-	disorderPacketN int
+	dialer   transport.StreamDialer
+	packets  []int
+	hopLimit int
 }
 
 var _ transport.StreamDialer = (*disorderDialer)(nil)
+var _ transport.ConnectFailureReporter = (*disorderDialer)(nil)
 
 // NewStreamDialer creates a [transport.StreamDialer].
 // It works like this:
@@ -46,7 +47,26 @@ func NewStreamDialer(dialer transport.StreamDialer, disorderPacketN int) (transp
 	if disorderPacketN < 0 {
 		return nil, fmt.Errorf("disorder argument must be >= 0, got %d", disorderPacketN)
 	}
-	return &disorderDialer{dialer: dialer, disorderPacketN: disorderPacketN}, nil
+	return NewMultiPacketStreamDialer(dialer, []int{disorderPacketN}, 1)
+}
+
+// NewMultiPacketStreamDialer creates a [transport.StreamDialer] like [NewStreamDialer], but sends each of the writes
+// with the given indexes out of order, and uses the given hopLimit instead of 1 while sending them. Some routes need
+// a hop limit of 2 or 3 for the packets to be dropped past the network filter, but before the server.
+// The hop limit is the TTL (IP_TTL) for IPv4 and the unicast hop limit (IPV6_UNICAST_HOPS) for IPv6.
+func NewMultiPacketStreamDialer(dialer transport.StreamDialer, packets []int, hopLimit int) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	for _, packet := range packets {
+		if packet < 0 {
+			return nil, fmt.Errorf("disorder packet must be >= 0, got %d", packet)
+		}
+	}
+	if hopLimit < 1 || hopLimit > 255 {
+		return nil, fmt.Errorf("hop limit must be between 1 and 255, got %d", hopLimit)
+	}
+	return &disorderDialer{dialer: dialer, packets: append([]int{}, packets...), hopLimit: hopLimit}, nil
 }
 
 // DialStream implements [transport.StreamDialer].DialStream.
@@ -56,7 +76,7 @@ func (d *disorderDialer) DialStream(ctx context.Context, remoteAddr string) (tra
 		return nil, err
 	}
 
-	tcpInnerConn, ok := innerConn.(*net.TCPConn)
+	tcpInnerConn, ok := transport.AsTCPConn(innerConn)
 	if !ok {
 		return nil, fmt.Errorf("disorder strategy: expected base dialer to return TCPConn")
 	}
@@ -65,7 +85,12 @@ func (d *disorderDialer) DialStream(ctx context.Context, remoteAddr string) (tra
 		return nil, err
 	}
 
-	dw := NewWriter(innerConn, tcpOptions, d.disorderPacketN)
+	dw := NewMultiPacketWriter(innerConn, tcpOptions, d.packets, d.hopLimit)
 
 	return transport.WrapConn(innerConn, innerConn, dw), nil
 }
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *disorderDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
@@ -22,33 +22,50 @@ import (
 )
 
 type disorderWriter struct {
-	conn             io.Writer
-	tcpOptions       sockopt.TCPOptions
-	writesToDisorder int
+	conn       io.Writer
+	tcpOptions sockopt.TCPOptions
+	// Indexes of the writes to disorder.
+	packets  map[int]bool
+	hopLimit int
+	// Index of the next write.
+	writeIndex int
 }
 
 var _ io.Writer = (*disorderWriter)(nil)
 
+// NewWriter creates an [io.Writer] that sends the runAtPacketN'th write out of order, by sending it with a hop
+// limit of 1. See [NewStreamDialer].
 func NewWriter(conn io.Writer, tcpOptions sockopt.TCPOptions, runAtPacketN int) io.Writer {
+	return NewMultiPacketWriter(conn, tcpOptions, []int{runAtPacketN}, 1)
+}
+
+// NewMultiPacketWriter creates an [io.Writer] that sends the writes with the given indexes out of order, by sending
+// them with the given hop limit. See [NewMultiPacketStreamDialer].
+func NewMultiPacketWriter(conn io.Writer, tcpOptions sockopt.TCPOptions, packets []int, hopLimit int) io.Writer {
 	// TODO: Support ReadFrom.
+	packetSet := make(map[int]bool, len(packets))
+	for _, packet := range packets {
+		packetSet[packet] = true
+	}
 	return &disorderWriter{
-		conn:             conn,
-		tcpOptions:       tcpOptions,
-		writesToDisorder: runAtPacketN,
+		conn:       conn,
+		tcpOptions: tcpOptions,
+		packets:    packetSet,
+		hopLimit:   hopLimit,
 	}
 }
 
 func (w *disorderWriter) Write(data []byte) (written int, err error) {
-	if w.writesToDisorder == 0 {
+	if w.packets[w.writeIndex] {
 		defaultHopLimit, err := w.tcpOptions.HopLimit()
 		if err != nil {
 			return 0, fmt.Errorf("failed to get the hop limit: %w", err)
 		}
 
-		// Setting number of hops to 1 will lead to data to get lost on host.
-		err = w.tcpOptions.SetHopLimit(1)
+		// Setting a low number of hops will lead to data to get lost before it reaches the server.
+		err = w.tcpOptions.SetHopLimit(w.hopLimit)
 		if err != nil {
-			return 0, fmt.Errorf("failed to set the hop limit to 1: %w", err)
+			return 0, fmt.Errorf("failed to set the hop limit to %d: %w", w.hopLimit, err)
 		}
 
 		defer func() {
@@ -69,8 +86,9 @@ func (w *disorderWriter) Write(data []byte) (written int, err error) {
 
 	// TODO: Wait for queued data to be sent by the kernel to the socket.
 
-	if w.writesToDisorder > -1 {
-		w.writesToDisorder -= 1
+	if len(w.packets) > 0 {
+		delete(w.packets, w.writeIndex)
+		w.writeIndex += 1
 	}
 	return n, err
 }
@@ -0,0 +1,166 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disorder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/sockopt"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTCPOptions is a fake [sockopt.TCPOptions] that records the hop limit of each write.
+type recordingTCPOptions struct {
+	hopLimit int
+}
+
+var _ sockopt.TCPOptions = (*recordingTCPOptions)(nil)
+
+func (o *recordingTCPOptions) HopLimit() (int, error) {
+	return o.hopLimit, nil
+}
+
+func (o *recordingTCPOptions) SetHopLimit(hopLimit int) error {
+	o.hopLimit = hopLimit
+	return nil
+}
+
+// hopLimitWriter records the hop limit in place for each write.
+type hopLimitWriter struct {
+	options   *recordingTCPOptions
+	hopLimits []int
+}
+
+func (w *hopLimitWriter) Write(data []byte) (int, error) {
+	w.hopLimits = append(w.hopLimits, w.options.hopLimit)
+	return len(data), nil
+}
+
+func TestMultiPacketWriter(t *testing.T) {
+	options := &recordingTCPOptions{hopLimit: 64}
+	inner := &hopLimitWriter{options: options}
+	w := NewMultiPacketWriter(inner, options, []int{0, 2}, 3)
+	for i := 0; i < 4; i++ {
+		_, err := w.Write([]byte("data"))
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int{3, 64, 3, 64}, inner.hopLimits)
+	require.Equal(t, 64, options.hopLimit)
+}
+
+func TestWriter(t *testing.T) {
+	options := &recordingTCPOptions{hopLimit: 64}
+	inner := &hopLimitWriter{options: options}
+	w := NewWriter(inner, options, 1)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("data"))
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int{64, 1, 64}, inner.hopLimits)
+}
+
+func TestNewMultiPacketStreamDialer_InvalidArgs(t *testing.T) {
+	_, err := NewMultiPacketStreamDialer(nil, []int{0}, 1)
+	require.Error(t, err)
+	_, err = NewMultiPacketStreamDialer(&transport.TCPDialer{}, []int{-1}, 1)
+	require.Error(t, err)
+	_, err = NewMultiPacketStreamDialer(&transport.TCPDialer{}, []int{0}, 0)
+	require.Error(t, err)
+}
+
+// Make sure the hop limit is set and restored on both IPv4 (IP_TTL) and IPv6 (IPV6_UNICAST_HOPS).
+// Loopback connections have no hops, so the data still arrives.
+func TestMultiPacketStreamDialer(t *testing.T) {
+	for _, network := range []struct{ Net, Addr string }{{"tcp4", "127.0.0.1:0"}, {"tcp6", "[::1]:0"}} {
+		listener, err := net.Listen(network.Net, network.Addr)
+		if err != nil {
+			t.Logf("Skipping %v: %v", network.Net, err)
+			continue
+		}
+		defer listener.Close()
+		receivedCh := make(chan []byte, 1)
+		go func() {
+			conn, err := listener.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+			received, _ := io.ReadAll(conn)
+			receivedCh <- received
+		}()
+
+		var hopLimits []int
+		var options sockopt.TCPOptions
+		baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			conn, err := (&transport.TCPDialer{}).DialStream(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			options, err = sockopt.NewTCPOptions(conn.(*net.TCPConn))
+			require.NoError(t, err)
+			return conn, nil
+		})
+		dialer, err := NewMultiPacketStreamDialer(baseDialer, []int{0, 1}, 2)
+		require.NoError(t, err)
+		conn, err := dialer.DialStream(context.Background(), listener.Addr().String())
+		require.NoError(t, err)
+		defaultHopLimit, err := options.HopLimit()
+		require.NoError(t, err)
+		for _, data := range []string{"Hello", " ", "world"} {
+			_, err = conn.Write([]byte(data))
+			require.NoError(t, err)
+			hopLimit, err := options.HopLimit()
+			require.NoError(t, err)
+			hopLimits = append(hopLimits, hopLimit)
+		}
+		require.NoError(t, conn.CloseWrite())
+		require.Equal(t, []int{defaultHopLimit, defaultHopLimit, defaultHopLimit}, hopLimits, network.Net)
+		require.True(t, bytes.Equal([]byte("Hello world"), <-receivedCh), network.Net)
+		conn.Close()
+	}
+}
+
+// The traced connections wrap the TCP connection, which must not prevent setting the hop limit.
+func TestMultiPacketStreamDialer_Trace(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+	receivedCh := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		received, _ := io.ReadAll(conn)
+		receivedCh <- received
+	}()
+
+	var closed bool
+	ctx := transport.WithTrace(context.Background(), &transport.Trace{
+		Closed: func(protocol, address string, err error) { closed = true },
+	})
+	dialer, err := NewMultiPacketStreamDialer(&transport.TCPDialer{}, []int{0}, 2)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(ctx, listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("Hello"))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+	require.Equal(t, []byte("Hello"), <-receivedCh)
+	require.NoError(t, conn.Close())
+	require.True(t, closed)
+}
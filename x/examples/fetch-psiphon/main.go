@@ -77,6 +77,9 @@ func main() {
 		log.Fatalf("Failed to create storage directory: %v", err)
 	}
 	debugLog.Printf("Using data store in %v\n", config.DataRootDirectory)
+	config.OnTunnelInfo = func(info psiphon.TunnelInfo) {
+		debugLog.Printf("Psiphon tunnel: protocol %v, region %v\n", info.Protocol, info.ServerRegion)
+	}
 
 	// Start the Psiphon dialer.
 	dialer := psiphon.GetSingletonDialer()
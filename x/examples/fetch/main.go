@@ -16,18 +16,22 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/textproto"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/x/configurl"
@@ -69,16 +73,50 @@ func overrideAddress(original string, newHost string, newPort string) (string, e
 	return net.JoinHostPort(host, port), nil
 }
 
+// methodHasBody returns whether requests with the given method usually have a body.
+func methodHasBody(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonTiming has the durations since the start of the request, in milliseconds.
+// The phases that don't apply, like the TLS handshake on reused or plain connections, are omitted.
+type jsonTiming struct {
+	ConnectedMs    float64 `json:"connected_ms,omitempty"`
+	TLSHandshakeMs float64 `json:"tls_handshake_ms,omitempty"`
+	FirstByteMs    float64 `json:"first_byte_ms,omitempty"`
+	TotalMs        float64 `json:"total_ms"`
+}
+
+// jsonOutput is the output of the -json flag.
+type jsonOutput struct {
+	Proto      string              `json:"proto"`
+	Status     string              `json:"status"`
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers"`
+	Timing     jsonTiming          `json:"timing"`
+	Body       string              `json:"body"`
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 func main() {
 	verboseFlag := flag.Bool("v", false, "Enable debug output")
 	tlsKeyLogFlag := flag.String("tls-key-log", "", "Filename to write the TLS key log to allow for decryption on Wireshark")
 	protoFlag := flag.String("proto", "h1", "HTTP version to use (h1, h2, h3)")
 	transportFlag := flag.String("transport", "", "Transport config")
 	addressFlag := flag.String("address", "", "Address to connect to. If empty, use the URL authority")
-	methodFlag := flag.String("method", "GET", "The HTTP method to use")
+	methodFlag := flag.String("method", "GET", "The HTTP method to use. For POST, PUT and PATCH, the body is read from stdin if it is not a terminal")
 	var headersFlag stringArrayFlagValue
 	flag.Var(&headersFlag, "H", "Raw HTTP Header line to add. It must not end in \\r\\n")
 	timeoutSecFlag := flag.Int("timeout", 5, "Timeout in seconds")
+	jsonFlag := flag.Bool("json", false, "Output a JSON object with the status, headers, timing and body of the response")
 
 	flag.Parse()
 
@@ -96,8 +134,8 @@ func main() {
 		var err error
 		overrideHost, overridePort, err = net.SplitHostPort(*addressFlag)
 		if err != nil {
-			// Fail to parse. Assume the flag is host only.
-			overrideHost = *addressFlag
+			// Fail to parse. Assume the flag is host only, possibly a bracketed IPv6 literal.
+			overrideHost = strings.TrimSuffix(strings.TrimPrefix(*addressFlag, "["), "]")
 			overridePort = ""
 		}
 	}
@@ -195,7 +233,17 @@ func main() {
 		os.Exit(1)
 	}
 
-	req, err := http.NewRequest(*methodFlag, url, nil)
+	var body io.Reader
+	if methodHasBody(*methodFlag) && !term.IsTerminal(int(os.Stdin.Fd())) {
+		// Read the whole body, so the request has a Content-Length instead of a chunked body.
+		bodyBytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			slog.Error("Failed to read request body from stdin", "error", err)
+			os.Exit(1)
+		}
+		body = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(*methodFlag, url, body)
 	if err != nil {
 		slog.Error("Failed to create request", "error", err)
 		os.Exit(1)
@@ -211,6 +259,28 @@ func main() {
 			req.Header.Add(name, value)
 		}
 	}
+
+	// The trace callbacks may run on other goroutines, so timingMu guards timing.
+	var timingMu sync.Mutex
+	var timing jsonTiming
+	start := time.Now()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			timingMu.Lock()
+			defer timingMu.Unlock()
+			timing.ConnectedMs = toMs(time.Since(start))
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			timingMu.Lock()
+			defer timingMu.Unlock()
+			timing.TLSHandshakeMs = toMs(time.Since(start))
+		},
+		GotFirstResponseByte: func() {
+			timingMu.Lock()
+			defer timingMu.Unlock()
+			timing.FirstByteMs = toMs(time.Since(start))
+		},
+	}))
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		slog.Error("HTTP request failed", "error", err)
@@ -226,6 +296,32 @@ func main() {
 		}
 	}
 
+	if *jsonFlag {
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			slog.Error("Read of page body failed", "error", err)
+			os.Exit(1)
+		}
+		timingMu.Lock()
+		timing.TotalMs = toMs(time.Since(start))
+		output := jsonOutput{
+			Proto:      resp.Proto,
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Header,
+			Timing:     timing,
+			Body:       string(respBody),
+		}
+		timingMu.Unlock()
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			slog.Error("Failed to write JSON output", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	_, err = io.Copy(os.Stdout, resp.Body)
 	fmt.Println()
 	if err != nil {
@@ -47,7 +47,7 @@ func rcodeToString(rcode dnsmessage.RCode) string {
 
 func main() {
 	verboseFlag := flag.Bool("v", false, "Enable debug output")
-	typeFlag := flag.String("type", "A", "The type of the query (A, AAAA, CNAME, NS or TXT).")
+	typeFlag := flag.String("type", "A", "The type of the query (A, AAAA, CNAME, NS, SOA, TXT, SVCB or HTTPS).")
 	resolverFlag := flag.String("resolver", "", "The address of the recursive DNS resolver to use in host:port format. If the port is missing, it's assumed to be 53")
 	transportFlag := flag.String("transport", "", "The transport for the connection to the recursive DNS resolver")
 	tcpFlag := flag.Bool("tcp", false, "Force TCP when querying the DNS resolver")
@@ -95,6 +95,10 @@ func main() {
 		qtype = dnsmessage.TypeSOA
 	case "TXT":
 		qtype = dnsmessage.TypeTXT
+	case "SVCB":
+		qtype = dns.TypeSVCB
+	case "HTTPS":
+		qtype = dns.TypeHTTPS
 	default:
 		log.Fatalf("Unsupported query type %v", *typeFlag)
 	}
@@ -134,6 +138,13 @@ func main() {
 			fmt.Printf("ns: %v email: %v minTTL: %v\n", soa.NS, soa.MBox, soa.MinTTL)
 		case dnsmessage.TypeTXT:
 			fmt.Println(strings.Join(answer.Body.(*dnsmessage.TXTResource).TXT, ", "))
+		case dns.TypeSVCB, dns.TypeHTTPS:
+			record, err := dns.ParseSVCBResource(answer.Body)
+			if err != nil {
+				log.Fatalf("Failed to parse %v record: %v", *typeFlag, err)
+			}
+			fmt.Printf("priority: %v target: %v alpn: %v port: %v ipv4hint: %v ipv6hint: %v ech: %v bytes\n",
+				record.Priority, record.Target, record.ALPN, record.Port, record.IPv4Hint, record.IPv6Hint, len(record.ECHConfigList))
 		default:
 			fmt.Println(answer.Body.GoString())
 		}
@@ -45,26 +45,12 @@ func (v *stringArrayFlagValue) Set(value string) error {
 	return nil
 }
 
-func supportsHappyEyeballs(dialer transport.StreamDialer) bool {
-	// Some proxy protocols, most notably Shadowsocks, can't communicate connection success.
-	// Our shadowsocks.StreamDialer will return a connection successfully as long as it can
-	// connect to the proxy server, regardless of whether it can connect to the target.
-	// This breaks HappyEyeballs.
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-	conn, err := dialer.DialStream(ctx, "invalid:0")
-	cancel()
-	if conn != nil {
-		conn.Close()
-	}
-	// If the dialer returns success on an invalid address, it doesn't support Happy Eyeballs.
-	return err != nil
-}
-
 func main() {
 	verboseFlag := flag.Bool("v", false, "Enable debug output")
 	addrFlag := flag.String("localAddr", "localhost:1080", "Local proxy address")
 	configFlag := flag.String("config", "config.yaml", "Address of the config file")
 	transportFlag := flag.String("transport", "", "The base transport for the connections")
+	concurrencyFlag := flag.Int("concurrency", 0, "Maximum number of strategies to test at the same time. Zero means no limit")
 	var domainsFlag stringArrayFlagValue
 	flag.Var(&domainsFlag, "domain", "The test domains to find strategies.")
 
@@ -95,7 +81,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Could not create stream dialer: %v", err)
 	}
-	if !supportsHappyEyeballs(streamDialer) {
+	// Some proxy protocols, most notably Shadowsocks, return the connection as soon as they connect to the proxy,
+	// regardless of whether the proxy can connect to the target. This breaks Happy Eyeballs.
+	if !transport.ReportsConnectFailure(streamDialer) {
 		fmt.Println("⚠️ Warning: base transport is not compatible with Happy Eyeballs. Disabling IPv6.")
 		innerDialer := streamDialer
 		// Disable IPv6 if the dialer doesn't support HappyEyballs.
@@ -111,10 +99,11 @@ func main() {
 		})
 	}
 	finder := smart.StrategyFinder{
-		LogWriter:    debugLog.Writer(),
-		TestTimeout:  5 * time.Second,
-		StreamDialer: streamDialer,
-		PacketDialer: packetDialer,
+		LogWriter:      debugLog.Writer(),
+		TestTimeout:    5 * time.Second,
+		StreamDialer:   streamDialer,
+		PacketDialer:   packetDialer,
+		MaxConcurrency: *concurrencyFlag,
 	}
 
 	fmt.Println("Finding strategy")
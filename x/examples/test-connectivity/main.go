@@ -16,7 +16,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -54,8 +53,19 @@ type testReport struct {
 	Transport string `json:"transport"`
 
 	// Observations
+	Time       time.Time `json:"time"`
+	DurationMs int64     `json:"duration_ms"`
+	// The error of the first attempt, or nil if any attempt succeeded.
+	Error    *errorJSON      `json:"error"`
+	Attempts []attemptReport `json:"attempts"`
+}
+
+// attemptReport is the result of the test with one of the IP addresses of the server.
+type attemptReport struct {
+	IP         string     `json:"ip,omitempty"`
 	Time       time.Time  `json:"time"`
 	DurationMs int64      `json:"duration_ms"`
+	Success    bool       `json:"success"`
 	Error      *errorJSON `json:"error"`
 }
 
@@ -79,6 +89,8 @@ type errorJSON struct {
 	Op string `json:"op,omitempty"`
 	// Posix error, when available
 	PosixError string `json:"posix_error,omitempty"`
+	// Platform-independent error classification
+	Code string `json:"code,omitempty"`
 	// TODO: remove IP addresses
 	Msg string `json:"msg,omitempty"`
 }
@@ -90,6 +102,7 @@ func makeErrorRecord(result *connectivity.ConnectivityError) *errorJSON {
 	var record = new(errorJSON)
 	record.Op = result.Op
 	record.PosixError = result.PosixError
+	record.Code = string(result.Code)
 	record.Msg = unwrapAll(result.Err).Error()
 	return record
 }
@@ -119,9 +132,9 @@ func init() {
 	}
 }
 func newTCPTraceDialer(
+	dialer transport.StreamDialer,
 	onDNS func(ctx context.Context, domain string) func(di httptrace.DNSDoneInfo),
 	onDial func(ctx context.Context, network, addr string, connErr error)) transport.StreamDialer {
-	dialer := &transport.TCPDialer{}
 	var onDNSDone func(di httptrace.DNSDoneInfo)
 	return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
 		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
@@ -143,8 +156,8 @@ func newTCPTraceDialer(
 }
 
 func newUDPTraceDialer(
+	dialer transport.PacketDialer,
 	onDNS func(ctx context.Context, domain string) func(di httptrace.DNSDoneInfo)) transport.PacketDialer {
-	dialer := &transport.UDPDialer{}
 	var onDNSDone func(di httptrace.DNSDoneInfo)
 	return transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
 		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
@@ -168,6 +181,7 @@ func main() {
 	domainFlag := flag.String("domain", "example.com.", "Domain name to resolve in the test")
 	resolverFlag := flag.String("resolver", "8.8.8.8,2001:4860:4860::8888", "Comma-separated list of addresses of DNS resolver to use for the test")
 	protoFlag := flag.String("proto", "tcp,udp", "Comma-separated list of the protocols to test. Must be \"tcp\", \"udp\", or a combination of them")
+	concurrencyFlag := flag.Int("concurrency", 1, "Maximum number of resolver and protocol combinations to test in parallel")
 	reportToFlag := flag.String("report-to", "", "URL to send JSON error reports to")
 	reportSuccessFlag := flag.Float64("report-success-rate", 0.1, "Report success to collector with this probability - must be between 0 and 1")
 	reportFailureFlag := flag.Float64("report-failure-rate", 1, "Report failure to collector with this probability - must be between 0 and 1")
@@ -224,42 +238,43 @@ func main() {
 	// Things to test:
 	// - TCP working. Where's the error?
 	// - UDP working
-	// - Different server IPs
 	// - Server IPv4 dial support
 	// - Server IPv6 dial support
 
-	success := false
-	jsonEncoder := json.NewEncoder(os.Stdout)
-	jsonEncoder.SetEscapeHTML(false)
+	resolverAddrs := make([]string, 0)
 	for _, resolverHost := range strings.Split(*resolverFlag, ",") {
-		resolverHost := strings.TrimSpace(resolverHost)
-		resolverAddress := net.JoinHostPort(resolverHost, "53")
-		for _, proto := range strings.Split(*protoFlag, ",") {
-			proto = strings.TrimSpace(proto)
-			var resolver dns.Resolver
-			var mu sync.Mutex
-			dnsReports := make([]dnsReport, 0)
-			tcpReports := make([]tcpReport, 0)
-			providers := configurl.NewDefaultProviders()
-			onDNS := func(ctx context.Context, domain string) func(di httptrace.DNSDoneInfo) {
-				dnsStart := time.Now()
-				return func(di httptrace.DNSDoneInfo) {
-					report := dnsReport{
-						QueryName:  domain,
-						Time:       dnsStart.UTC().Truncate(time.Second),
-						DurationMs: time.Since(dnsStart).Milliseconds(),
-					}
-					if di.Err != nil {
-						report.Error = di.Err.Error()
-					}
-					for _, ip := range di.Addrs {
-						report.AnswerIPs = append(report.AnswerIPs, ip.IP.String())
-					}
-					mu.Lock()
-					dnsReports = append(dnsReports, report)
-					mu.Unlock()
+		resolverAddrs = append(resolverAddrs, net.JoinHostPort(strings.TrimSpace(resolverHost), "53"))
+	}
+	protocols := make([]string, 0)
+	for _, proto := range strings.Split(*protoFlag, ",") {
+		protocols = append(protocols, strings.TrimSpace(proto))
+	}
+	test := func(ctx context.Context, resolverAddress string, proto string) (*connectivity.ConnectivityError, error) {
+		var mu sync.Mutex
+		dnsReports := make([]dnsReport, 0)
+		tcpReports := make([]tcpReport, 0)
+		onDNS := func(ctx context.Context, domain string) func(di httptrace.DNSDoneInfo) {
+			dnsStart := time.Now()
+			return func(di httptrace.DNSDoneInfo) {
+				report := dnsReport{
+					QueryName:  domain,
+					Time:       dnsStart.UTC().Truncate(time.Second),
+					DurationMs: time.Since(dnsStart).Milliseconds(),
+				}
+				if di.Err != nil {
+					report.Error = di.Err.Error()
 				}
+				for _, ip := range di.Addrs {
+					report.AnswerIPs = append(report.AnswerIPs, ip.IP.String())
+				}
+				mu.Lock()
+				dnsReports = append(dnsReports, report)
+				mu.Unlock()
 			}
+		}
+		// The test connects to each IP address of the server through the pinned base dialers.
+		newResolver := func(pinnedStreamDialer transport.StreamDialer, pinnedPacketDialer transport.PacketDialer) (dns.Resolver, error) {
+			providers := configurl.NewDefaultProviders()
 			providers.StreamDialers.BaseInstance = transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
 				hostname, _, err := net.SplitHostPort(addr)
 				if err != nil {
@@ -282,70 +297,92 @@ func main() {
 					tcpReports = append(tcpReports, report)
 					mu.Unlock()
 				}
-				return newTCPTraceDialer(onDNS, onDial).DialStream(ctx, addr)
+				return newTCPTraceDialer(pinnedStreamDialer, onDNS, onDial).DialStream(ctx, addr)
 			})
 			providers.PacketDialers.BaseInstance = transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
-				return newUDPTraceDialer(onDNS).DialPacket(ctx, addr)
+				return newUDPTraceDialer(pinnedPacketDialer, onDNS).DialPacket(ctx, addr)
 			})
 
 			switch proto {
 			case "tcp":
 				streamDialer, err := providers.NewStreamDialer(context.Background(), *transportFlag)
 				if err != nil {
-					slog.Error("Failed to create StreamDialer", "error", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("failed to create StreamDialer: %w", err)
 				}
-				resolver = dns.NewTCPResolver(streamDialer, resolverAddress)
-
+				return dns.NewTCPResolver(streamDialer, resolverAddress), nil
 			case "udp":
 				packetDialer, err := providers.NewPacketDialer(context.Background(), *transportFlag)
 				if err != nil {
-					slog.Error("Failed to create PacketDialer", "error", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("failed to create PacketDialer: %w", err)
 				}
-				resolver = dns.NewUDPResolver(packetDialer, resolverAddress)
+				return dns.NewUDPResolver(packetDialer, resolverAddress), nil
 			default:
-				slog.Error(`Invalid proto. Must be "tcp" or "udp"`, "proto", proto)
-				os.Exit(1)
-			}
-			startTime := time.Now()
-			result, err := connectivity.TestConnectivityWithResolver(context.Background(), resolver, *domainFlag)
-			if err != nil {
-				slog.Error("Connectivity test failed to run", "error", err)
-				os.Exit(1)
-			}
-			testDuration := time.Since(startTime)
-			if result == nil {
-				success = true
+				return nil, fmt.Errorf(`invalid proto %q. Must be "tcp" or "udp"`, proto)
 			}
-			slog.Debug("Test done", "proto", proto, "resolver", resolverAddress, "result", result)
-			sanitizedConfig, err := configurl.SanitizeConfig(*transportFlag)
-			if err != nil {
-				slog.Error("Failed to sanitize config", "error", err)
-				os.Exit(1)
+		}
+
+		startTime := time.Now()
+		attempts, err := connectivity.TestConnectivityWithResolverPerIP(ctx, &transport.TCPDialer{}, &transport.UDPDialer{}, newResolver, *domainFlag)
+		if err != nil {
+			return nil, err
+		}
+		testDuration := time.Since(startTime)
+		attemptReports := make([]attemptReport, 0, len(attempts))
+		// The test succeeds if any attempt succeeds. Otherwise, its error is the error of the first attempt.
+		attemptSuccess := false
+		for _, attempt := range attempts {
+			report := attemptReport{
+				Time:       attempt.StartTime.UTC().Truncate(time.Second),
+				DurationMs: attempt.Duration.Milliseconds(),
+				Success:    attempt.Err == nil,
+				Error:      makeErrorRecord(attempt.Err),
 			}
-			var r report.Report = connectivityReport{
-				Test: testReport{
-					Resolver: resolverAddress,
-					Proto:    proto,
-					Time:     startTime.UTC().Truncate(time.Second),
-					// TODO(fortuna): Add sanitized config:
-					Transport:  sanitizedConfig,
-					DurationMs: testDuration.Milliseconds(),
-					Error:      makeErrorRecord(result),
-				},
-				DNSQueries:     dnsReports,
-				TCPConnections: tcpReports,
+			if attempt.IP.IsValid() {
+				report.IP = attempt.IP.String()
 			}
-			if reportCollector != nil {
-				err = reportCollector.Collect(context.Background(), r)
-				if err != nil {
-					slog.Warn("Failed to collect report", "error", err)
-				}
+			attemptReports = append(attemptReports, report)
+			slog.Debug("Attempt done", "proto", proto, "resolver", resolverAddress, "ip", report.IP, "result", attempt.Err)
+			if attempt.Err == nil {
+				attemptSuccess = true
 			}
 		}
-		if !success {
-			os.Exit(1)
+		var result *connectivity.ConnectivityError
+		if !attemptSuccess && len(attempts) > 0 {
+			result = attempts[0].Err
+		}
+		slog.Debug("Test done", "proto", proto, "resolver", resolverAddress, "result", result)
+		sanitizedConfig, err := configurl.SanitizeConfig(*transportFlag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sanitize config: %w", err)
 		}
+		var r report.Report = connectivityReport{
+			Test: testReport{
+				Resolver: resolverAddress,
+				Proto:    proto,
+				Time:     startTime.UTC().Truncate(time.Second),
+				// TODO(fortuna): Add sanitized config:
+				Transport:  sanitizedConfig,
+				DurationMs: testDuration.Milliseconds(),
+				Error:      makeErrorRecord(result),
+				Attempts:   attemptReports,
+			},
+			DNSQueries:     dnsReports,
+			TCPConnections: tcpReports,
+		}
+		if reportCollector != nil {
+			err = reportCollector.Collect(ctx, r)
+			if err != nil {
+				slog.Warn("Failed to collect report", "error", err)
+			}
+		}
+		return result, nil
+	}
+	summary, err := connectivity.RunMatrix(context.Background(), test, resolverAddrs, protocols, *concurrencyFlag)
+	if err != nil {
+		slog.Error("Connectivity test failed to run", "error", err)
+		os.Exit(1)
+	}
+	if summary.FastestResolver == "" {
+		os.Exit(1)
 	}
 }
@@ -27,18 +27,29 @@ import (
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 	"github.com/Jigsaw-Code/outline-sdk/x/configurl"
+	wskeepalive "github.com/Jigsaw-Code/outline-sdk/x/websocket"
 	"golang.org/x/net/websocket"
 )
 
-type natConn struct {
-	net.Conn
-	mappingTimeout time.Duration
+// keepAlive wraps the connection to send pings, if enabled by the ping interval.
+func keepAlive(wsConn *websocket.Conn, pingInterval, pongTimeout time.Duration) net.Conn {
+	if pingInterval <= 0 {
+		return wsConn
+	}
+	conn, err := wskeepalive.NewKeepAliveConn(wsConn, pingInterval, pongTimeout)
+	if err != nil {
+		log.Printf("Failed to enable keepalives: %v\n", err)
+		return wsConn
+	}
+	return conn
 }
 
-// Consider ReadFrom/WriteTo
-func (c *natConn) Write(b []byte) (int, error) {
-	c.Conn.SetDeadline(time.Now().Add(c.mappingTimeout))
-	return c.Conn.Write(b)
+// closeWrite sends a WebSocket close frame without closing the connection, so the peer can still send data.
+func closeWrite(conn net.Conn) error {
+	if wc, ok := conn.(interface{ WriteClose(status int) error }); ok {
+		return wc.WriteClose(1000)
+	}
+	return conn.Close()
 }
 
 func main() {
@@ -47,6 +58,8 @@ func main() {
 	backendFlag := flag.String("backend", "", "Address of the endpoint to forward traffic to")
 	tcpPathFlag := flag.String("tcp_path", "/tcp", "Path where to run the WebSocket TCP forwarder")
 	udpPathFlag := flag.String("udp_path", "/udp", "Path where to run the WebSocket UDP forwarder")
+	pingIntervalFlag := flag.Duration("ping_interval", 0, "Interval to send WebSocket pings to keep idle connections alive. Zero disables the pings")
+	pongTimeoutFlag := flag.Duration("pong_timeout", 0, "Time to wait for a frame after a ping is due before closing the connection. Defaults to the ping interval")
 	flag.Parse()
 
 	if *backendFlag == "" {
@@ -70,7 +83,8 @@ func main() {
 		endpoint := transport.StreamDialerEndpoint{Dialer: dialer, Address: *backendFlag}
 		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Got stream request: %v\n", r)
-			handler := func(wsConn *websocket.Conn) {
+			handler := func(ws *websocket.Conn) {
+				wsConn := keepAlive(ws, *pingIntervalFlag, *pongTimeoutFlag)
 				targetConn, err := endpoint.ConnectStream(r.Context())
 				if err != nil {
 					log.Printf("Failed to upgrade: %v\n", err)
@@ -78,11 +92,16 @@ func main() {
 					return
 				}
 				defer targetConn.Close()
+				clientDone := make(chan struct{})
 				go func() {
+					defer close(clientDone)
 					io.Copy(targetConn, wsConn)
 					targetConn.CloseWrite()
 				}()
 				io.Copy(wsConn, targetConn)
+				// Send a close frame, so the client reads EOF, but keep forwarding the client data until it's done.
+				closeWrite(wsConn)
+				<-clientDone
 				wsConn.Close()
 			}
 			websocket.Server{Handler: handler}.ServeHTTP(w, r)
@@ -94,25 +113,50 @@ func main() {
 		if err != nil {
 			log.Fatalf("Could not create stream dialer: %v", err)
 		}
-		endpoint := transport.PacketDialerEndpoint{Dialer: dialer, Address: *backendFlag}
+		// The connections to the backend expire after the idle timeout recommended by RFC 4787.
+		natListener, err := transport.NewNATPacketListener(dialer, 0)
+		if err != nil {
+			log.Fatalf("Could not create packet listener: %v", err)
+		}
+		backendAddr, err := transport.MakeNetAddr("udp", *backendFlag)
+		if err != nil {
+			log.Fatalf("Invalid backend address %v: %v", *backendFlag, err)
+		}
 		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Got packet request: %v\n", r)
-			handler := func(wsConn *websocket.Conn) {
-				targetConn, err := endpoint.ConnectPacket(r.Context())
+			handler := func(ws *websocket.Conn) {
+				wsConn := keepAlive(ws, *pingIntervalFlag, *pongTimeoutFlag)
+				defer wsConn.Close()
+				targetConn, err := natListener.ListenPacket(r.Context())
 				if err != nil {
 					log.Printf("Failed to upgrade: %v\n", err)
 					w.WriteHeader(http.StatusBadGateway)
 					return
 				}
-				// Expire connetion after 5 minutes of idle time, as per
-				// https://datatracker.ietf.org/doc/html/rfc4787#section-4.3
-				targetConn = &natConn{targetConn, 5 * time.Minute}
+				// Each WebSocket message is a packet.
 				go func() {
-					io.Copy(targetConn, wsConn)
-					targetConn.Close()
+					defer targetConn.Close()
+					buf := make([]byte, 64*1024)
+					for {
+						n, err := wsConn.Read(buf)
+						if err != nil {
+							return
+						}
+						if _, err := targetConn.WriteTo(buf[:n], backendAddr); err != nil {
+							log.Printf("Failed to forward packet: %v\n", err)
+						}
+					}
 				}()
-				io.Copy(wsConn, targetConn)
-				wsConn.Close()
+				buf := make([]byte, 64*1024)
+				for {
+					n, _, err := targetConn.ReadFrom(buf)
+					if err != nil {
+						return
+					}
+					if _, err := wsConn.Write(buf[:n]); err != nil {
+						return
+					}
+				}
 			}
 			websocket.Server{Handler: handler}.ServeHTTP(w, r)
 		})
@@ -16,6 +16,7 @@ package httpconnect
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/Jigsaw-Code/outline-sdk/transport"
@@ -31,10 +32,16 @@ type connectClient struct {
 	proxyAddr string
 
 	headers http.Header
+	// Value of the Proxy-Authorization header, if set.
+	proxyAuth string
 }
 
 var _ transport.StreamDialer = (*connectClient)(nil)
 
+// ErrProxyAuthRequired is returned by DialStream when the proxy responds with
+// 407 Proxy Authentication Required, either because no credentials were given or because they were rejected.
+var ErrProxyAuthRequired = errors.New("proxy authentication required")
+
 type ClientOption func(c *connectClient)
 
 func NewConnectClient(dialer transport.StreamDialer, proxyAddr string, opts ...ClientOption) (transport.StreamDialer, error) {
@@ -59,6 +66,14 @@ func NewConnectClient(dialer transport.StreamDialer, proxyAddr string, opts ...C
 	return cc, nil
 }
 
+// WithBasicAuth authenticates to the proxy with the given credentials, using the Basic scheme
+// in the Proxy-Authorization header.
+func WithBasicAuth(username, password string) ClientOption {
+	return func(c *connectClient) {
+		c.proxyAuth = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+}
+
 // WithHeaders appends the given [headers] to the CONNECT request
 func WithHeaders(headers http.Header) ClientOption {
 	return func(c *connectClient) {
@@ -96,6 +111,9 @@ func (cc *connectClient) doConnect(ctx context.Context, remoteAddr string, conn
 	}
 	req.ContentLength = -1 // -1 means length unknown
 	mergeHeaders(req.Header, cc.headers)
+	if cc.proxyAuth != "" {
+		req.Header.Set("Proxy-Authorization", cc.proxyAuth)
+	}
 
 	tr := &http.Transport{
 		// TODO: HTTP/2 support with [http2.ConfigureTransport]
@@ -112,6 +130,10 @@ func (cc *connectClient) doConnect(ctx context.Context, remoteAddr string, conn
 	if err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w (challenge: %q)", ErrProxyAuthRequired, resp.Header.Get("Proxy-Authenticate"))
+	}
 	if resp.StatusCode != http.StatusOK {
 		_ = resp.Body.Close()
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
@@ -19,8 +19,8 @@ import (
 	"context"
 	"encoding/base64"
 	"github.com/Jigsaw-Code/outline-sdk/transport"
-	"github.com/Jigsaw-Code/outline-sdk/x/httpproxy"
 	"github.com/stretchr/testify/require"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -45,7 +45,7 @@ func TestConnectClientOk(t *testing.T) {
 	require.NoError(t, err)
 
 	tcpDialer := &transport.TCPDialer{Dialer: net.Dialer{}}
-	connectHandler := httpproxy.NewConnectHandler(tcpDialer)
+	connectHandler := newConnectHandler(tcpDialer, nil)
 	proxySrv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		require.Equal(t, "Basic "+creds, request.Header.Get("Proxy-Authorization"))
 		connectHandler.ServeHTTP(writer, request)
@@ -107,3 +107,76 @@ func TestConnectClientFail(t *testing.T) {
 	_, err = connClient.DialStream(context.Background(), targetURL)
 	require.Error(t, err, "unexpected status code: 400")
 }
+
+func TestConnectClientBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	targetSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetSrv.Close()
+	targetURL, err := url.Parse(targetSrv.URL)
+	require.NoError(t, err)
+
+	tcpDialer := &transport.TCPDialer{Dialer: net.Dialer{}}
+	proxySrv := httptest.NewServer(newConnectHandler(tcpDialer, func(user, pass string) bool {
+		return user == "username" && pass == "password"
+	}))
+	defer proxySrv.Close()
+	proxyURL, err := url.Parse(proxySrv.URL)
+	require.NoError(t, err, "Parse")
+
+	connClient, err := NewConnectClient(tcpDialer, proxyURL.Host, WithBasicAuth("username", "password"))
+	require.NoError(t, err, "NewConnectClient")
+	streamConn, err := connClient.DialStream(context.Background(), targetURL.Host)
+	require.NoError(t, err, "DialStream")
+	require.NoError(t, streamConn.Close())
+
+	connClient, err = NewConnectClient(tcpDialer, proxyURL.Host, WithBasicAuth("username", "wrong"))
+	require.NoError(t, err, "NewConnectClient")
+	_, err = connClient.DialStream(context.Background(), targetURL.Host)
+	require.ErrorIs(t, err, ErrProxyAuthRequired)
+
+	connClient, err = NewConnectClient(tcpDialer, proxyURL.Host)
+	require.NoError(t, err, "NewConnectClient")
+	_, err = connClient.DialStream(context.Background(), targetURL.Host)
+	require.ErrorIs(t, err, ErrProxyAuthRequired)
+}
+
+// newConnectHandler returns an HTTP CONNECT proxy handler that dials the targets with dialer.
+// If checkAuth is not nil, requests must have Basic credentials it accepts.
+// We don't use the httpproxy package because it imports this package.
+func newConnectHandler(dialer transport.StreamDialer, checkAuth func(user, pass string) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if checkAuth != nil {
+			proxyReq := &http.Request{Header: http.Header{"Authorization": r.Header["Proxy-Authorization"]}}
+			if user, pass, ok := proxyReq.BasicAuth(); !ok || !checkAuth(user, pass) {
+				w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+		}
+		targetConn, err := dialer.DialStream(r.Context(), r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer targetConn.Close()
+		clientConn, clientRW, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer clientConn.Close()
+		clientRW.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+		clientRW.Flush()
+		go func() {
+			io.Copy(targetConn, clientRW)
+			targetConn.CloseWrite()
+		}()
+		io.Copy(clientConn, targetConn)
+	})
+}
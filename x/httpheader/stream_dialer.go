@@ -0,0 +1,61 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpheader provides a [transport.StreamDialer] that rewrites the Host header of the first HTTP request
+// sent on each connection, to confuse DPI that blocks based on the HTTP Host. The rest of the stream is untouched.
+//
+// This only makes sense for servers that ignore the Host header, or that serve the target on the decoy host too,
+// as with domain fronting.
+package httpheader
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+type hostDialer struct {
+	dialer transport.StreamDialer
+	host   string
+}
+
+var _ transport.StreamDialer = (*hostDialer)(nil)
+var _ transport.ConnectFailureReporter = (*hostDialer)(nil)
+
+// NewStreamDialer creates a [transport.StreamDialer] that replaces the Host header of the first HTTP request
+// on each connection with host. See [NewWriter].
+func NewStreamDialer(dialer transport.StreamDialer, host string) (transport.StreamDialer, error) {
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if host == "" {
+		return nil, errors.New("argument host must not be empty")
+	}
+	return &hostDialer{dialer: dialer, host: host}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *hostDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	return transport.WrapConn(innerConn, innerConn, NewWriter(innerConn, d.host)), nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *hostDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpheader
+
+import (
+	"bytes"
+	"io"
+)
+
+// maxHeaderSize is how much we buffer while looking for the end of the request header.
+// Larger headers are passed through untouched.
+const maxHeaderSize = 16 * 1024
+
+// maxMethodSize is the longest request method we recognize.
+const maxMethodSize = 16
+
+var headerEnd = []byte("\r\n\r\n")
+
+type hostWriter struct {
+	writer io.Writer
+	host   string
+	// buf holds the start of the stream until the request header is complete.
+	buf  []byte
+	done bool
+}
+
+var _ io.Writer = (*hostWriter)(nil)
+
+// NewWriter creates an [io.Writer] that replaces the Host header of the first HTTP request written to it with host,
+// or adds one if missing. The writes are buffered until the request header is complete, so a request split across
+// multiple writes is still rewritten, and sent in a single write. Everything after the first request header is
+// written through untouched.
+//
+// If the stream doesn't start with an HTTP request, or the header is larger than 16 KiB, the data is written
+// untouched as soon as that is detected.
+//
+// The returned writer has a Flush() error method that writes the buffered data untouched if the request header is
+// incomplete. [transport.WrapConn] calls it on CloseWrite, so the data is not lost.
+func NewWriter(writer io.Writer, host string) io.Writer {
+	return &hostWriter{writer: writer, host: host}
+}
+
+// Write implements [io.Writer].
+func (w *hostWriter) Write(data []byte) (int, error) {
+	if w.done {
+		return w.writer.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	var out []byte
+	if end := bytes.Index(w.buf, headerEnd); end >= 0 {
+		out = append(replaceHost(w.buf[:end+len(headerEnd)], w.host), w.buf[end+len(headerEnd):]...)
+	} else if !looksLikeRequest(w.buf) || len(w.buf) > maxHeaderSize {
+		out = w.buf
+	} else {
+		return len(data), nil
+	}
+	w.done = true
+	w.buf = nil
+	if _, err := w.writer.Write(out); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush writes the data buffered while waiting for the end of the request header untouched, and ends the buffering.
+func (w *hostWriter) Flush() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	out := w.buf
+	w.buf = nil
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := w.writer.Write(out)
+	return err
+}
+
+// looksLikeRequest returns whether the data could be the start of an HTTP request, which begins with an
+// upper-case method followed by a space.
+func looksLikeRequest(data []byte) bool {
+	for i, b := range data {
+		if b == ' ' {
+			return i > 0
+		}
+		if b < 'A' || b > 'Z' || i >= maxMethodSize {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceHost returns the request header with the Host header replaced by host. The header must end with an empty line.
+func replaceHost(header []byte, host string) []byte {
+	lines := bytes.Split(header[:len(header)-len(headerEnd)], []byte("\r\n"))
+	hostLine := []byte("Host: " + host)
+	out := make([]byte, 0, len(header)+len(hostLine))
+	out = append(out, lines[0]...)
+	out = append(out, "\r\n"...)
+	found := false
+	for _, line := range lines[1:] {
+		name, _, ok := bytes.Cut(line, []byte(":"))
+		if ok && bytes.EqualFold(bytes.TrimSpace(name), []byte("Host")) {
+			if found {
+				// Drop duplicate Host headers, so the decoy is the only one.
+				continue
+			}
+			found = true
+			line = hostLine
+		}
+		out = append(out, line...)
+		out = append(out, "\r\n"...)
+	}
+	if !found {
+		out = append(out, hostLine...)
+		out = append(out, "\r\n"...)
+	}
+	return append(out, "\r\n"...)
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpheader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// collectWrites is a [io.Writer] that appends each write to a list of writes.
+type collectWrites struct {
+	writes [][]byte
+}
+
+func (w *collectWrites) Write(data []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), data...))
+	return len(data), nil
+}
+
+func writeAll(t *testing.T, host string, parts ...string) []string {
+	var inner collectWrites
+	w := NewWriter(&inner, host)
+	for _, part := range parts {
+		n, err := w.Write([]byte(part))
+		require.NoError(t, err)
+		require.Equal(t, len(part), n)
+	}
+	writes := make([]string, len(inner.writes))
+	for i, write := range inner.writes {
+		writes[i] = string(write)
+	}
+	return writes
+}
+
+func TestWriter_SingleWrite(t *testing.T) {
+	writes := writeAll(t, "decoy.com",
+		"GET / HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n",
+		"GET /second HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	require.Equal(t, []string{
+		"GET / HTTP/1.1\r\nHost: decoy.com\r\nAccept: */*\r\n\r\n",
+		"GET /second HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	}, writes)
+}
+
+func TestWriter_SplitRequest(t *testing.T) {
+	writes := writeAll(t, "decoy.com",
+		"GE", "T / HTTP/1.1\r\nHo", "st: example.com\r", "\n\r", "\nbody", "more body")
+	require.Equal(t, []string{
+		"GET / HTTP/1.1\r\nHost: decoy.com\r\n\r\nbody",
+		"more body",
+	}, writes)
+}
+
+func TestWriter_CaseInsensitiveAndDuplicates(t *testing.T) {
+	writes := writeAll(t, "decoy.com", "POST /x HTTP/1.1\r\nhOST :example.com\r\nHost: other.com\r\nX: y\r\n\r\n")
+	require.Equal(t, []string{"POST /x HTTP/1.1\r\nHost: decoy.com\r\nX: y\r\n\r\n"}, writes)
+}
+
+func TestWriter_MissingHost(t *testing.T) {
+	writes := writeAll(t, "decoy.com", "GET / HTTP/1.0\r\n\r\n")
+	require.Equal(t, []string{"GET / HTTP/1.0\r\nHost: decoy.com\r\n\r\n"}, writes)
+}
+
+func TestWriter_NotHTTP(t *testing.T) {
+	writes := writeAll(t, "decoy.com", "\x16\x03\x01", "Host: example.com\r\n\r\n")
+	require.Equal(t, []string{"\x16\x03\x01", "Host: example.com\r\n\r\n"}, writes)
+}
+
+func TestWriter_HeaderTooLarge(t *testing.T) {
+	large := "GET / HTTP/1.1\r\nX: " + string(make([]byte, maxHeaderSize)) + "\r\n"
+	writes := writeAll(t, "decoy.com", large, "Host: example.com\r\n\r\n")
+	require.Equal(t, []string{large, "Host: example.com\r\n\r\n"}, writes)
+}
+
+func TestWriter_Flush(t *testing.T) {
+	var inner collectWrites
+	w := NewWriter(&inner, "decoy.com")
+	_, err := w.Write([]byte("GET / HTTP/1.1\r\nHost: exa"))
+	require.NoError(t, err)
+	require.Empty(t, inner.writes)
+
+	f, ok := w.(interface{ Flush() error })
+	require.True(t, ok)
+	require.NoError(t, f.Flush())
+	_, err = w.Write([]byte("mple.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Flush())
+	require.Equal(t, [][]byte{[]byte("GET / HTTP/1.1\r\nHost: exa"), []byte("mple.com\r\n\r\n")}, inner.writes)
+}
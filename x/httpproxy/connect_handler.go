@@ -44,6 +44,12 @@ func (d *sanitizeErrorDialer) DialStream(ctx context.Context, addr string) (tran
 	if isCancelledError(err) {
 		return nil, context.Canceled
 	}
+	if errors.Is(err, errUpstreamProxyAuth) {
+		return nil, errUpstreamProxyAuth
+	}
+	if errors.Is(err, errUpstreamProxy) {
+		return nil, errUpstreamProxy
+	}
 	if err != nil {
 		return nil, errors.New("base dial failed")
 	}
@@ -84,6 +90,14 @@ func (h *connectHandler) ServeHTTP(proxyResp http.ResponseWriter, proxyReq *http
 		return
 	}
 	targetConn, err := dialer.DialStream(proxyReq.Context(), proxyReq.Host)
+	if errors.Is(err, errUpstreamProxyAuth) {
+		http.Error(proxyResp, "Upstream proxy authentication failed", http.StatusBadGateway)
+		return
+	}
+	if errors.Is(err, errUpstreamProxy) {
+		http.Error(proxyResp, "Upstream proxy failed", http.StatusBadGateway)
+		return
+	}
 	if err != nil {
 		http.Error(proxyResp, fmt.Sprintf("Failed to connect to %v: %v", proxyReq.Host, err), http.StatusServiceUnavailable)
 		return
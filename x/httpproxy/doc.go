@@ -15,14 +15,16 @@
 /*
 Package httpproxy provides HTTP handlers for routing HTTP traffic through a local web proxy.
 
+You can chain the proxy into another HTTP proxy, like a corporate proxy, with [WithUpstreamProxy].
+
 # Important Security Considerations
 
 This package is designed primarily for use with private, internal forward proxies typically integrated within an application.
 It is not suitable for public-facing proxies due to the following security concerns:
 
-  - Authentication: Public proxies must restrict access to only authorized users. This package does not provide built-in authentication mechanisms.
+  - Authentication: Public proxies must restrict access to only authorized users. [NewProxyHandlerWithAuth] provides basic authentication, which sends credentials in plain text unless the connection to the proxy is encrypted.
   - Probing Resistance: A public proxy should ideally not reveal its identity as a proxy, even under targeted probing. Implementing authentication can aid in this.
-  - Protection of Local Resources: The dialer used by the proxy handlers should prevent connections to both localhost and the local network to avoid unintended access by clients.
+  - Protection of Local Resources: The dialer used by the proxy handlers should prevent connections to both localhost and the local network to avoid unintended access by clients. You can also restrict the destination ports and hosts with [WithAllowedPorts] and [WithAllowedHosts].
   - Resource Limits:  Implement limits on resources (number of connections, time connected, memory used, etc.) per user.  This helps prevent denial-of-service attacks.
 
 If you intend to build a public-facing proxy, you will need to address these security issues using additional libraries or custom solutions.
@@ -53,6 +53,11 @@ func (h *forwardHandler) ServeHTTP(proxyResp http.ResponseWriter, proxyReq *http
 		return
 	}
 	defer targetResp.Body.Close()
+	if targetResp.StatusCode == http.StatusProxyAuthRequired {
+		// Only an upstream proxy responds with 407. Don't ask the client for credentials it doesn't have.
+		http.Error(proxyResp, "Upstream proxy authentication failed", http.StatusBadGateway)
+		return
+	}
 	for key, values := range targetResp.Header {
 		for _, value := range values {
 			proxyResp.Header().Add(key, value)
@@ -67,11 +72,15 @@ func (h *forwardHandler) ServeHTTP(proxyResp http.ResponseWriter, proxyReq *http
 
 // NewForwardHandler creates a [http.Handler] that handles absolute HTTP requests using the given [http.Client].
 func NewForwardHandler(dialer transport.StreamDialer) http.Handler {
-	dialContext := func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return &forwardHandler{http.Client{Transport: &http.Transport{DialContext: dialContextFunc(dialer)}}}
+}
+
+// dialContextFunc adapts the [transport.StreamDialer] to the [http.Transport].DialContext signature.
+func dialContextFunc(dialer transport.StreamDialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 		if !strings.HasPrefix(network, "tcp") {
 			return nil, fmt.Errorf("protocol not supported: %v", network)
 		}
 		return dialer.DialStream(ctx, addr)
 	}
-	return &forwardHandler{http.Client{Transport: &http.Transport{DialContext: dialContext}}}
 }
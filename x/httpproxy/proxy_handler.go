@@ -15,7 +15,14 @@
 package httpproxy
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
 )
@@ -26,11 +33,97 @@ type ProxyHandler struct {
 	FallbackHandler http.Handler
 	connectHandler  http.Handler
 	forwardHandler  http.Handler
+	// If not nil, proxy requests must have Proxy-Authorization credentials accepted by authFunc.
+	authFunc func(user, pass string) bool
+	// If not nil, only these destination ports are allowed.
+	allowedPorts map[int]bool
+	// If not nil, only the destination hosts it accepts are allowed.
+	allowHost func(host string) bool
+	// If not nil, requests are sent through this upstream HTTP proxy.
+	upstreamProxy *url.URL
+	// If not nil, the upstream proxy is invalid and all proxy requests fail with this error.
+	upstreamProxyErr error
+}
+
+// ProxyHandlerOption configures the [ProxyHandler] created by [NewProxyHandler].
+type ProxyHandlerOption func(h *ProxyHandler)
+
+// WithAllowedPorts restricts the destinations of the proxy requests to the given ports. Requests to other ports
+// get a 403 Forbidden response.
+func WithAllowedPorts(ports ...int) ProxyHandlerOption {
+	return func(h *ProxyHandler) {
+		h.allowedPorts = make(map[int]bool, len(ports))
+		for _, port := range ports {
+			h.allowedPorts[port] = true
+		}
+	}
+}
+
+// WithAllowedHosts restricts the destinations of the proxy requests to the hosts accepted by allowHost.
+// The host is a lowercase domain name or IP address, without the port. Requests to other hosts get a
+// 403 Forbidden response. Note that a domain may resolve to a local address, so use a dialer that blocks local
+// addresses if you need to protect local resources.
+func WithAllowedHosts(allowHost func(host string) bool) ProxyHandlerOption {
+	return func(h *ProxyHandler) {
+		h.allowHost = allowHost
+	}
+}
+
+var errDestinationNotAllowed = errors.New("destination not allowed")
+
+// checkDestination returns an error if the destination address is not allowed by the handler options.
+func (h *ProxyHandler) checkDestination(addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if h.allowedPorts != nil {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || !h.allowedPorts[port] {
+			return errDestinationNotAllowed
+		}
+	}
+	if h.allowHost != nil && !h.allowHost(strings.ToLower(host)) {
+		return errDestinationNotAllowed
+	}
+	return nil
+}
+
+// proxyRequestDestination returns the host:port destination of the proxy request.
+func proxyRequestDestination(proxyReq *http.Request) string {
+	if proxyReq.Method == http.MethodConnect {
+		return proxyReq.Host
+	}
+	if proxyReq.URL.Port() != "" {
+		return proxyReq.URL.Host
+	}
+	port := "80"
+	if strings.EqualFold(proxyReq.URL.Scheme, "https") {
+		port = "443"
+	}
+	return net.JoinHostPort(proxyReq.URL.Hostname(), port)
 }
 
 // ServeHTTP implements [http.Handler].ServeHTTP for CONNECT and absolute URL requests, using the internal [transport.StreamDialer].
 func (h *ProxyHandler) ServeHTTP(proxyResp http.ResponseWriter, proxyReq *http.Request) {
 	// TODO(fortuna): For public services (not local), we need authentication and drain on failures to avoid fingerprinting.
+	isProxyRequest := proxyReq.Method == http.MethodConnect || proxyReq.URL.Host != ""
+	if isProxyRequest && h.authFunc != nil {
+		user, pass, ok := parseProxyBasicAuth(proxyReq.Header.Get("Proxy-Authorization"))
+		if !ok || !h.authFunc(user, pass) {
+			proxyResp.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			http.Error(proxyResp, "Proxy authentication required", http.StatusProxyAuthRequired)
+			return
+		}
+		// The credentials are for the proxy, so don't forward them to the destination.
+		proxyReq.Header.Del("Proxy-Authorization")
+	}
+	if isProxyRequest && (h.allowedPorts != nil || h.allowHost != nil) {
+		if err := h.checkDestination(proxyRequestDestination(proxyReq)); err != nil {
+			http.Error(proxyResp, "Destination not allowed", http.StatusForbidden)
+			return
+		}
+	}
 	if proxyReq.Method == http.MethodConnect {
 		h.connectHandler.ServeHTTP(proxyResp, proxyReq)
 		return
@@ -46,11 +139,81 @@ func (h *ProxyHandler) ServeHTTP(proxyResp http.ResponseWriter, proxyReq *http.R
 	http.NotFound(proxyResp, proxyReq)
 }
 
+// parseProxyBasicAuth parses the credentials of a "Basic" Proxy-Authorization header value.
+func parseProxyBasicAuth(auth string) (user, pass string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
 // NewProxyHandler creates a [http.Handler] that works as a web proxy using the given dialer to deach the destination.
 // You can use [ProxyHandler].FallbackHandler to specify how to handle non-proxy requests.
-func NewProxyHandler(dialer transport.StreamDialer) *ProxyHandler {
-	return &ProxyHandler{
-		connectHandler: NewConnectHandler(dialer),
-		forwardHandler: NewForwardHandler(dialer),
+// Use the options to restrict the destinations of the requests.
+func NewProxyHandler(dialer transport.StreamDialer, options ...ProxyHandlerOption) *ProxyHandler {
+	h := &ProxyHandler{}
+	for _, option := range options {
+		option(h)
+	}
+	hasAllowlist := h.allowedPorts != nil || h.allowHost != nil
+	connectDialer := dialer
+	forwardTransport := &http.Transport{DialContext: dialContextFunc(dialer)}
+	if h.upstreamProxyErr != nil {
+		connectDialer = transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			return nil, h.upstreamProxyErr
+		})
+		forwardTransport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return nil, h.upstreamProxyErr
+		}
+	} else if h.upstreamProxy != nil {
+		// The URL was checked by WithUpstreamProxy, so this can't fail.
+		connectDialer, _ = newUpstreamConnectDialer(dialer, h.upstreamProxy)
+		forwardTransport.Proxy = func(req *http.Request) (*url.URL, error) {
+			// Also check the requests made by the handler, like on redirects.
+			if hasAllowlist {
+				if err := h.checkDestination(proxyRequestDestination(req)); err != nil {
+					return nil, err
+				}
+			}
+			return h.upstreamProxy, nil
+		}
+	}
+	if hasAllowlist {
+		// Also check the connections made by the handlers, like on redirects.
+		baseDialer := connectDialer
+		connectDialer = transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			if err := h.checkDestination(addr); err != nil {
+				return nil, err
+			}
+			return baseDialer.DialStream(ctx, addr)
+		})
+		if forwardTransport.Proxy == nil {
+			forwardTransport.DialContext = dialContextFunc(connectDialer)
+		}
+	}
+	h.connectHandler = NewConnectHandler(connectDialer)
+	h.forwardHandler = &forwardHandler{http.Client{Transport: forwardTransport}}
+	return h
+}
+
+// NewProxyHandlerWithAuth is like [NewProxyHandler], but it requires proxy requests to have "Basic" credentials in the
+// Proxy-Authorization header that authFunc accepts. Otherwise, it responds with 407 Proxy Authentication Required.
+// Both CONNECT and absolute URL requests are checked, but not the requests to the [ProxyHandler].FallbackHandler.
+// Consider using [crypto/subtle.ConstantTimeCompare] in authFunc to compare the credentials.
+//
+// If authFunc is nil, all proxy requests are rejected.
+//
+// Note that the credentials are sent in plain text, unless the connection to the proxy is encrypted.
+func NewProxyHandlerWithAuth(dialer transport.StreamDialer, authFunc func(user, pass string) bool, options ...ProxyHandlerOption) *ProxyHandler {
+	h := NewProxyHandler(dialer, options...)
+	if authFunc == nil {
+		authFunc = func(user, pass string) bool { return false }
 	}
+	h.authFunc = authFunc
+	return h
 }
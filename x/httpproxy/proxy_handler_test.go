@@ -0,0 +1,343 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpproxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandlerWithAuth(t *testing.T) {
+	var targetAuth []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetAuth = r.Header.Values("Proxy-Authorization")
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	h := NewProxyHandlerWithAuth(&transport.TCPDialer{}, func(user, pass string) bool {
+		return user == "user" && pass == "secret"
+	})
+	h.FallbackHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	t.Run("missing credentials", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, target.URL, nil))
+		require.Equal(t, http.StatusProxyAuthRequired, resp.Code)
+		require.Equal(t, `Basic realm="proxy"`, resp.Header().Get("Proxy-Authenticate"))
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		for _, auth := range []string{"Basic dXNlcjp3cm9uZw==", "Basic !!!", "Bearer token", "Basic dXNlcg=="} {
+			req := httptest.NewRequest(http.MethodGet, target.URL, nil)
+			req.Header.Set("Proxy-Authorization", auth)
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusProxyAuthRequired, resp.Code, auth)
+		}
+	})
+
+	t.Run("CONNECT invalid credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodConnect, "example.com:443", nil)
+		req.SetBasicAuth("user", "wrong")
+		req.Header.Set("Proxy-Authorization", req.Header.Get("Authorization"))
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusProxyAuthRequired, resp.Code)
+	})
+
+	t.Run("valid credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, target.URL, nil)
+		// The scheme is case-insensitive.
+		req.Header.Set("Proxy-Authorization", "basic dXNlcjpzZWNyZXQ=")
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "ok", resp.Body.String())
+		require.Empty(t, targetAuth)
+	})
+
+	t.Run("fallback not checked", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusTeapot, resp.Code)
+	})
+}
+
+func TestProxyHandlerWithNilAuth(t *testing.T) {
+	h := NewProxyHandlerWithAuth(transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return nil, errors.New("not implemented")
+	}), nil)
+	req := httptest.NewRequest(http.MethodConnect, "example.com:443", nil)
+	req.Header.Set("Proxy-Authorization", "Basic dXNlcjpzZWNyZXQ=")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusProxyAuthRequired, resp.Code)
+}
+
+func TestProxyHandlerWithAllowlist(t *testing.T) {
+	var dialedAddrs []string
+	dialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		dialedAddrs = append(dialedAddrs, addr)
+		return nil, errors.New("not implemented")
+	})
+	h := NewProxyHandler(dialer, WithAllowedPorts(443), WithAllowedHosts(func(host string) bool {
+		return host == "example.com"
+	}))
+
+	for _, tc := range []struct {
+		method string
+		target string
+		code   int
+	}{
+		{http.MethodConnect, "example.com:443", http.StatusServiceUnavailable},
+		{http.MethodConnect, "EXAMPLE.com:443", http.StatusServiceUnavailable},
+		{http.MethodConnect, "example.com:22", http.StatusForbidden},
+		{http.MethodConnect, "example.net:443", http.StatusForbidden},
+		{http.MethodConnect, "127.0.0.1:443", http.StatusForbidden},
+		{http.MethodGet, "http://example.com/", http.StatusForbidden},
+		{http.MethodGet, "http://example.com:443/", http.StatusServiceUnavailable},
+		{http.MethodGet, "https://example.net/", http.StatusForbidden},
+	} {
+		dialedAddrs = nil
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		if tc.code == http.StatusForbidden {
+			require.Equal(t, http.StatusForbidden, resp.Code, tc.target)
+			require.Empty(t, dialedAddrs, tc.target)
+		} else {
+			require.NotEqual(t, http.StatusForbidden, resp.Code, tc.target)
+			require.Len(t, dialedAddrs, 1, tc.target)
+		}
+	}
+}
+
+func TestProxyHandlerCheckDestination(t *testing.T) {
+	h := NewProxyHandler(&transport.TCPDialer{}, WithAllowedPorts(80, 443))
+	require.NoError(t, h.checkDestination("example.com:80"))
+	require.NoError(t, h.checkDestination("[::1]:443"))
+	require.ErrorIs(t, h.checkDestination("example.com:8080"), errDestinationNotAllowed)
+	require.Error(t, h.checkDestination("example.com"))
+
+	// The dialer used by the handlers also enforces the allowlist.
+	_, err := h.connectHandler.(*connectHandler).dialer.DialStream(context.Background(), "localhost:22")
+	require.Error(t, err)
+}
+
+func TestProxyHandlerWithUpstreamProxy(t *testing.T) {
+	var upstreamRequests int
+	upstream := httptest.NewServer(NewProxyHandlerWithAuth(&transport.TCPDialer{}, func(user, pass string) bool {
+		upstreamRequests++
+		return user == "user" && pass == "secret"
+	}))
+	defer upstream.Close()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("http ok"))
+	}))
+	defer target.Close()
+	tlsTarget := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("https ok"))
+	}))
+	defer tlsTarget.Close()
+
+	newClient := func(t *testing.T, upstreamURL *url.URL) *http.Client {
+		proxy := httptest.NewServer(NewProxyHandler(&transport.TCPDialer{}, WithUpstreamProxy(upstreamURL)))
+		t.Cleanup(proxy.Close)
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+		tr := tlsTarget.Client().Transport.(*http.Transport).Clone()
+		tr.Proxy = http.ProxyURL(proxyURL)
+		return &http.Client{Transport: tr}
+	}
+
+	t.Run("valid credentials", func(t *testing.T) {
+		upstreamURL, err := url.Parse(upstream.URL)
+		require.NoError(t, err)
+		upstreamURL.User = url.UserPassword("user", "secret")
+		client := newClient(t, upstreamURL)
+		upstreamRequests = 0
+
+		resp, err := client.Get(target.URL)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "http ok", string(body))
+
+		resp, err = client.Get(tlsTarget.URL)
+		require.NoError(t, err)
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, "https ok", string(body))
+
+		require.Equal(t, 2, upstreamRequests)
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		upstreamURL, err := url.Parse(upstream.URL)
+		require.NoError(t, err)
+		upstreamURL.User = url.UserPassword("user", "wrong")
+		client := newClient(t, upstreamURL)
+
+		resp, err := client.Get(target.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+		_, err = client.Get(tlsTarget.URL)
+		require.ErrorContains(t, err, "Bad Gateway")
+	})
+}
+
+func TestProxyHandlerWithUpstreamProxy_SpecialCharCredentials(t *testing.T) {
+	// Basic credentials can't have a colon in the user name, but can in the password.
+	const user, pass = "us@r name%", "p@ss:w%rd /"
+	var gotUser, gotPass string
+	upstream := httptest.NewServer(NewProxyHandlerWithAuth(&transport.TCPDialer{}, func(u, p string) bool {
+		gotUser, gotPass = u, p
+		return u == user && p == pass
+	}))
+	defer upstream.Close()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	upstreamURL.User = url.UserPassword(user, pass)
+	// Parse the escaped form, like a URL from a config string.
+	upstreamURL, err = url.Parse(upstreamURL.String())
+	require.NoError(t, err)
+	proxy := httptest.NewServer(NewProxyHandler(&transport.TCPDialer{}, WithUpstreamProxy(upstreamURL)))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get(target.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, user, gotUser)
+	require.Equal(t, pass, gotPass)
+
+	// The CONNECT path uses the same credentials.
+	gotUser, gotPass = "", ""
+	conn, err := proxy.Client().Transport.(*http.Transport).DialContext(context.Background(), "tcp", proxyURL.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+	targetAddr := target.Listener.Addr().String()
+	_, err = conn.Write([]byte("CONNECT " + targetAddr + " HTTP/1.1\r\nHost: " + targetAddr + "\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err = http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, user, gotUser)
+	require.Equal(t, pass, gotPass)
+}
+
+func TestProxyHandlerWithUpstreamProxy_UnsupportedScheme(t *testing.T) {
+	var targetRequests int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetRequests++
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	for _, rawURL := range []string{"socks5://localhost:1080", "ftp://localhost:21", "localhost:3128"} {
+		t.Run(rawURL, func(t *testing.T) {
+			upstreamURL, err := url.Parse(rawURL)
+			require.NoError(t, err)
+			h := NewProxyHandler(&transport.TCPDialer{}, WithUpstreamProxy(upstreamURL))
+			require.Error(t, h.upstreamProxyErr)
+
+			proxy := httptest.NewServer(h)
+			defer proxy.Close()
+			proxyURL, err := url.Parse(proxy.URL)
+			require.NoError(t, err)
+			client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+			resp, err := client.Get(target.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.NotEqual(t, http.StatusOK, resp.StatusCode)
+
+			// CONNECT requests must not go to the destination directly either.
+			_, err = h.connectHandler.(*connectHandler).dialer.DialStream(context.Background(), target.Listener.Addr().String())
+			require.Error(t, err)
+			require.Zero(t, targetRequests)
+		})
+	}
+}
+
+func TestUpstreamConnectDialerAuthRequired(t *testing.T) {
+	upstream := httptest.NewServer(NewProxyHandlerWithAuth(&transport.TCPDialer{}, func(user, pass string) bool {
+		return user == "user" && pass == "secret"
+	}))
+	defer upstream.Close()
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	upstreamURL.User = url.UserPassword("user", "wrong")
+
+	d, err := newUpstreamConnectDialer(&transport.TCPDialer{}, upstreamURL)
+	require.NoError(t, err)
+	_, err = d.DialStream(context.Background(), upstream.Listener.Addr().String())
+	require.ErrorIs(t, err, errUpstreamProxyAuth)
+	require.ErrorIs(t, err, errUpstreamProxy)
+}
+
+func TestUpstreamConnectDialerBufferedData(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != http.MethodConnect || req.Host != "example.com:443" {
+			return
+		}
+		// Send the response and the first destination bytes together.
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nhello"))
+	}()
+
+	d, err := newUpstreamConnectDialer(&transport.TCPDialer{}, &url.URL{Scheme: "http", Host: listener.Addr().String()})
+	require.NoError(t, err)
+	conn, err := d.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	defer conn.Close()
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+}
@@ -29,6 +29,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jigsaw-Code/outline-sdk/transport"
@@ -43,6 +44,9 @@ type Proxy struct {
 	port         int
 	proxyHandler *httpproxy.ProxyHandler
 	server       *http.Server
+	stats        *proxyStats
+	// dialer is the dialer for new proxy requests. It's swapped by SetDialer.
+	dialer atomic.Pointer[StreamDialer]
 }
 
 // Address returns the IP and port the server is bound to.
@@ -80,13 +84,47 @@ func (p *Proxy) AddURLProxy(path string, dialer *StreamDialer) {
 		log.Println("Called Proxy.AddURLProxy after Stop")
 		return
 	}
+	if dialer == nil || dialer.StreamDialer == nil {
+		// Warn and ignore, to keep the current handler.
+		log.Println("Called Proxy.AddURLProxy with nil dialer")
+		return
+	}
 	if len(path) == 0 || path[0] != '/' {
 		path = "/" + path
 	}
 	// TODO(fortuna): Add support for multiple paths. I tried http.ServeMux, but it does request sanitization,
 	// which breaks the URL extraction: https://pkg.go.dev/net/http#hdr-Request_sanitizing.
 	// We can consider forking http.StripPrefix to provide a fallback instead of NotFound, and chaing them.
-	p.proxyHandler.FallbackHandler = http.StripPrefix(path, httpproxy.NewPathHandler(dialer.StreamDialer))
+	p.proxyHandler.FallbackHandler = http.StripPrefix(path, httpproxy.NewPathHandler(p.stats.wrapDialer(dialer.StreamDialer)))
+}
+
+// SetDialer replaces the dialer the proxy uses to establish connections to the requested destinations, so you can
+// switch the transport strategy without restarting the proxy. Only new connections use the new dialer: the connections
+// already established are not affected, and the proxy keeps listening on the same address.
+// It's safe to call SetDialer while the proxy is handling requests. It doesn't change the dialer of [Proxy.AddURLProxy].
+func (p *Proxy) SetDialer(dialer *StreamDialer) {
+	if dialer == nil {
+		// Warn and ignore, to keep the current dialer.
+		log.Println("Called Proxy.SetDialer with nil dialer")
+		return
+	}
+	p.dialer.Store(dialer)
+}
+
+// dialStream dials addr with the current dialer.
+func (p *Proxy) dialStream(ctx context.Context, addr string) (transport.StreamConn, error) {
+	return p.dialer.Load().DialStream(ctx, addr)
+}
+
+// Stats returns the traffic statistics of the connections the proxy made to destinations since it started.
+func (p *Proxy) Stats() *ProxyStats {
+	return p.stats.snapshot()
+}
+
+// SetConnectionListener sets the listener to call when the proxy opens and closes connections to destinations.
+// Pass nil to remove the listener.
+func (p *Proxy) SetConnectionListener(listener ConnectionListener) {
+	p.stats.setListener(listener)
 }
 
 // Stop gracefully stops the proxy service, waiting for at most timeout seconds before forcefully closing it.
@@ -103,9 +141,31 @@ func (p *Proxy) Stop(timeoutSeconds int) {
 	p.server = nil
 }
 
+// ProxyOptions has the limits to protect the resources of the device running the proxy.
+// The zero value means no limits.
+type ProxyOptions struct {
+	// IdleTimeoutSeconds is how long a connection to a destination can go without reads or writes before it's closed.
+	// This prevents connections that the app doesn't close from leaking. Zero means no timeout.
+	IdleTimeoutSeconds int
+	// MaxConnections is the maximum number of requests in progress, including the CONNECT tunnels. The proxy
+	// responds to the requests over the limit with 503 Service Unavailable. Zero means no limit.
+	MaxConnections int
+}
+
 // RunProxy runs a local web proxy that listens on localAddress, and handles proxy requests by
 // establishing connections to requested destination using the [StreamDialer].
 func RunProxy(localAddress string, dialer *StreamDialer) (*Proxy, error) {
+	return RunProxyWithOptions(localAddress, dialer, nil)
+}
+
+// RunProxyWithOptions is like [RunProxy], but applies the limits in the given [ProxyOptions]. The options can be nil.
+func RunProxyWithOptions(localAddress string, dialer *StreamDialer, options *ProxyOptions) (*Proxy, error) {
+	if options == nil {
+		options = &ProxyOptions{}
+	}
+	if options.IdleTimeoutSeconds < 0 || options.MaxConnections < 0 {
+		return nil, errors.New("proxy options must not be negative")
+	}
 	listener, err := net.Listen("tcp", localAddress)
 	if err != nil {
 		return nil, fmt.Errorf("could not listen on address %v: %v", localAddress, err)
@@ -118,10 +178,17 @@ func RunProxy(localAddress string, dialer *StreamDialer) (*Proxy, error) {
 	// shutdown. This can lead to lingering connections. We'll create a base context, propagated to requests,
 	// that is cancelled on shutdown. This enables handlers to gracefully terminate requests and close connections.
 	serverCtx, cancelCtx := context.WithCancelCause(context.Background())
-	proxyHandler := httpproxy.NewProxyHandler(dialer)
+	stats := &proxyStats{idleTimeout: time.Duration(options.IdleTimeoutSeconds) * time.Second}
+	proxy := &Proxy{stats: stats}
+	proxy.dialer.Store(dialer)
+	proxyHandler := httpproxy.NewProxyHandler(stats.wrapDialer(transport.FuncStreamDialer(proxy.dialStream)))
 	proxyHandler.FallbackHandler = http.NotFoundHandler()
+	var handler http.Handler = proxyHandler
+	if options.MaxConnections > 0 {
+		handler = stats.limitRequests(proxyHandler, int64(options.MaxConnections))
+	}
 	server := &http.Server{
-		Handler: proxyHandler,
+		Handler: handler,
 		BaseContext: func(l net.Listener) context.Context {
 			return serverCtx
 		},
@@ -139,12 +206,11 @@ func RunProxy(localAddress string, dialer *StreamDialer) (*Proxy, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not parse proxy port '%v': %v", portStr, err)
 	}
-	return &Proxy{
-		host:         host,
-		port:         port,
-		server:       server,
-		proxyHandler: proxyHandler,
-	}, nil
+	proxy.host = host
+	proxy.port = port
+	proxy.server = server
+	proxy.proxyHandler = proxyHandler
+	return proxy, nil
 }
 
 // StreamDialer encapsulates the logic to create stream connections (like TCP).
@@ -157,13 +223,60 @@ var configModule = configurl.NewDefaultProviders()
 // NewStreamDialerFromConfig creates a [StreamDialer] based on the given config.
 // The config format is specified in https://pkg.go.dev/github.com/Jigsaw-Code/outline-sdk/x/config#hdr-Config_Format.
 func NewStreamDialerFromConfig(transportConfig string) (*StreamDialer, error) {
-	dialer, err := configModule.NewStreamDialer(context.Background(), transportConfig)
+	return newStreamDialerFromProviders(configModule, transportConfig)
+}
+
+func newStreamDialerFromProviders(providers *configurl.ProviderContainer, transportConfig string) (*StreamDialer, error) {
+	dialer, err := providers.NewStreamDialer(context.Background(), transportConfig)
 	if err != nil {
 		return nil, err
 	}
 	return &StreamDialer{dialer}, nil
 }
 
+// Providers creates dialers from configs, like [NewStreamDialerFromConfig], and lets you register your own config
+// types. It wraps a [configurl.ProviderContainer], which Go Mobile can't bind.
+type Providers struct {
+	providers *configurl.ProviderContainer
+}
+
+// NewProviders creates [Providers] with the default config types.
+func NewProviders() *Providers {
+	return &Providers{configurl.NewDefaultProviders()}
+}
+
+// StreamDialerFactory creates the [StreamDialer] for a config type registered with
+// [Providers.RegisterStreamDialerType]. You can implement it in your app.
+type StreamDialerFactory interface {
+	// NewStreamDialer creates the dialer for config, which is the part of the config for the registered type, like
+	// "mytype:param". The baseDialer is the dialer for the rest of the config, to connect through.
+	NewStreamDialer(config string, baseDialer *StreamDialer) (*StreamDialer, error)
+}
+
+// RegisterStreamDialerType registers the config type typeID, so configs like "typeID:..." create their dialers
+// with factory. It replaces any type already registered with typeID.
+func (p *Providers) RegisterStreamDialerType(typeID string, factory StreamDialerFactory) {
+	p.providers.StreamDialers.RegisterType(typeID, func(ctx context.Context, config *configurl.Config) (transport.StreamDialer, error) {
+		baseDialer, err := p.providers.StreamDialers.NewInstance(ctx, config.BaseConfig)
+		if err != nil {
+			return nil, err
+		}
+		dialer, err := factory.NewStreamDialer(config.URL.String(), &StreamDialer{baseDialer})
+		if err != nil {
+			return nil, err
+		}
+		if dialer == nil || dialer.StreamDialer == nil {
+			return nil, fmt.Errorf("factory for config type '%v' returned no dialer", typeID)
+		}
+		return dialer.StreamDialer, nil
+	})
+}
+
+// NewStreamDialerFromConfig creates a [StreamDialer] based on the given config, which may use the registered types.
+func (p *Providers) NewStreamDialerFromConfig(transportConfig string) (*StreamDialer, error) {
+	return newStreamDialerFromProviders(p.providers, transportConfig)
+}
+
 // LogWriter is used as a sink for logging.
 type LogWriter io.StringWriter
 
@@ -0,0 +1,186 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mobileproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// errorDialerFactory is a [StreamDialerFactory] that creates dialers that fail with err.
+type errorDialerFactory struct {
+	err     error
+	configs []string
+}
+
+func (f *errorDialerFactory) NewStreamDialer(config string, baseDialer *StreamDialer) (*StreamDialer, error) {
+	f.configs = append(f.configs, config)
+	if baseDialer == nil {
+		return nil, errors.New("missing base dialer")
+	}
+	return &StreamDialer{transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return nil, f.err
+	})}, nil
+}
+
+func TestProviders_RegisterStreamDialerType(t *testing.T) {
+	factory := &errorDialerFactory{err: errors.New("custom dialer")}
+	providers := NewProviders()
+	providers.RegisterStreamDialerType("custom", factory)
+
+	dialer, err := providers.NewStreamDialerFromConfig("split:2|custom:param")
+	require.NoError(t, err)
+	_, err = dialer.DialStream(context.Background(), "example.com:443")
+	require.ErrorIs(t, err, factory.err)
+	require.Equal(t, []string{"custom:param"}, factory.configs)
+
+	// The default types are still available.
+	_, err = providers.NewStreamDialerFromConfig("split:2")
+	require.NoError(t, err)
+
+	// Other providers don't have the custom type.
+	_, err = NewProviders().NewStreamDialerFromConfig("custom:")
+	require.Error(t, err)
+	_, err = NewStreamDialerFromConfig("custom:")
+	require.Error(t, err)
+}
+
+func TestProviders_FactoryReturnsNoDialer(t *testing.T) {
+	providers := NewProviders()
+	providers.RegisterStreamDialerType("nil", nilDialerFactory{})
+	_, err := providers.NewStreamDialerFromConfig("nil:")
+	require.Error(t, err)
+}
+
+type nilDialerFactory struct{}
+
+func (nilDialerFactory) NewStreamDialer(config string, baseDialer *StreamDialer) (*StreamDialer, error) {
+	return nil, nil
+}
+
+// newCountingDialer returns a [StreamDialer] that makes direct TCP connections and counts the dials.
+func newCountingDialer(dials *atomic.Int32) *StreamDialer {
+	return &StreamDialer{transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		dials.Add(1)
+		return (&transport.TCPDialer{}).DialStream(ctx, addr)
+	})}
+}
+
+func TestProxy_SetDialer(t *testing.T) {
+	target := newEchoListener(t)
+	defer target.Close()
+	var firstDials, secondDials atomic.Int32
+	proxy, err := RunProxy("127.0.0.1:0", newCountingDialer(&firstDials))
+	require.NoError(t, err)
+	defer proxy.Stop(1)
+	address := proxy.Address()
+
+	conn1, status := connectThroughProxy(t, address, target.Addr().String())
+	require.Equal(t, http.StatusOK, status)
+	defer conn1.Close()
+
+	proxy.SetDialer(newCountingDialer(&secondDials))
+	require.Equal(t, address, proxy.Address())
+	conn2, status := connectThroughProxy(t, address, target.Addr().String())
+	require.Equal(t, http.StatusOK, status)
+	conn2.Close()
+	require.Equal(t, int32(1), firstDials.Load())
+	require.Equal(t, int32(1), secondDials.Load())
+
+	// The connection established with the previous dialer still works.
+	_, err = conn1.Write([]byte("ping"))
+	require.NoError(t, err)
+	response := make([]byte, 4)
+	_, err = io.ReadFull(conn1, response)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(response))
+
+	// A nil dialer is ignored.
+	proxy.SetDialer(nil)
+	conn3, status := connectThroughProxy(t, address, target.Addr().String())
+	require.Equal(t, http.StatusOK, status)
+	conn3.Close()
+	require.Equal(t, int32(2), secondDials.Load())
+}
+
+func TestProxy_SetDialerConcurrent(t *testing.T) {
+	target := newEchoListener(t)
+	defer target.Close()
+	var dials atomic.Int32
+	proxy, err := RunProxy("127.0.0.1:0", newCountingDialer(&dials))
+	require.NoError(t, err)
+	defer proxy.Stop(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			proxy.SetDialer(newCountingDialer(&dials))
+		}()
+		go func() {
+			defer wg.Done()
+			conn, status := connectThroughProxy(t, proxy.Address(), target.Addr().String())
+			conn.Close()
+			require.Equal(t, http.StatusOK, status)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(10), dials.Load())
+}
+
+type fakeProtector struct {
+	protected []int
+	result    bool
+}
+
+func (p *fakeProtector) Protect(fd int) bool {
+	p.protected = append(p.protected, fd)
+	return p.result
+}
+
+func TestProtectControl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	protector := &fakeProtector{result: true}
+	dialer := &transport.TCPDialer{Dialer: net.Dialer{Control: protectControl(protector)}}
+	conn, err := dialer.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+	require.Len(t, protector.protected, 1)
+
+	protector.result = false
+	_, err = dialer.DialStream(context.Background(), listener.Addr().String())
+	require.Error(t, err)
+	require.Len(t, protector.protected, 2)
+}
+
+func TestNewInterfaceBinder_Invalid(t *testing.T) {
+	_, err := NewInterfaceBinder("")
+	require.Error(t, err)
+	_, err = NewInterfaceBinder("no-such-interface0")
+	require.Error(t, err)
+}
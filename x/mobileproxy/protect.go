@@ -0,0 +1,124 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mobileproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/configurl"
+	"github.com/Jigsaw-Code/outline-sdk/x/smart"
+)
+
+// SocketProtector protects the sockets of the outbound connections, so they bypass the VPN when the proxy runs on a
+// device with a VPN. Without it, the proxy traffic is routed back into the VPN, causing a routing loop.
+// On Android, implement it with VpnService.protect. Use [NewInterfaceBinder] to bind the sockets to a network
+// interface instead.
+type SocketProtector interface {
+	// Protect is called with the file descriptor of each new outbound socket, before it connects.
+	// It returns false if the socket could not be protected, which fails the connection.
+	Protect(fd int) bool
+}
+
+// protectControl returns a [net.Dialer] Control function that protects the sockets with protector.
+func protectControl(protector SocketProtector) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		protected := false
+		if err := c.Control(func(fd uintptr) {
+			protected = protector.Protect(int(fd))
+		}); err != nil {
+			return err
+		}
+		if !protected {
+			return fmt.Errorf("failed to protect socket for %v", address)
+		}
+		return nil
+	}
+}
+
+// newProtectedProviders returns config providers whose base dialers and listener protect their sockets.
+func newProtectedProviders(protector SocketProtector) *configurl.ProviderContainer {
+	control := protectControl(protector)
+	providers := configurl.NewDefaultProviders()
+	providers.StreamDialers.BaseInstance = &transport.TCPDialer{Dialer: net.Dialer{Control: control}}
+	providers.PacketDialers.BaseInstance = &transport.UDPDialer{Dialer: net.Dialer{Control: control}}
+	providers.PacketListeners.BaseInstance = &transport.UDPListener{ListenConfig: net.ListenConfig{Control: control}}
+	return providers
+}
+
+// NewStreamDialerFromConfigWithProtector is like [NewStreamDialerFromConfig], but protects the sockets of all the
+// outbound connections, including the ones to the proxy servers in the config, with protector. A nil protector
+// protects nothing.
+//
+// Host names are resolved with the system resolver, whose sockets are not protected. Use IP addresses or a DNS
+// transport in the config if the system resolver is not reachable outside the VPN.
+func NewStreamDialerFromConfigWithProtector(transportConfig string, protector SocketProtector) (*StreamDialer, error) {
+	if protector == nil {
+		return NewStreamDialerFromConfig(transportConfig)
+	}
+	return newStreamDialerFromProviders(newProtectedProviders(protector), transportConfig)
+}
+
+// NewSmartStreamDialerWithProtector is like [NewSmartStreamDialer], but protects the sockets of the strategy tests
+// and of the selected dialer with protector, as in [NewStreamDialerFromConfigWithProtector].
+func NewSmartStreamDialerWithProtector(testDomains *StringList, searchConfig string, logWriter LogWriter, protector SocketProtector) (*StreamDialer, error) {
+	if protector == nil {
+		return NewSmartStreamDialer(testDomains, searchConfig, logWriter)
+	}
+	control := protectControl(protector)
+	finder := smart.StrategyFinder{
+		LogWriter:    toWriter(logWriter),
+		TestTimeout:  5 * time.Second,
+		StreamDialer: &transport.TCPDialer{Dialer: net.Dialer{Control: control}},
+		PacketDialer: &transport.UDPDialer{Dialer: net.Dialer{Control: control}},
+	}
+	dialer, err := finder.NewDialer(context.Background(), testDomains.list, []byte(searchConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dialer: %w", err)
+	}
+	return &StreamDialer{dialer}, nil
+}
+
+// interfaceBinder is a [SocketProtector] that binds the sockets to a network interface.
+type interfaceBinder struct {
+	iface *net.Interface
+}
+
+// NewInterfaceBinder creates a [SocketProtector] that binds the sockets to the network interface with the given name,
+// like "wlan0", so the connections go out through that interface regardless of the routing table. It's supported on
+// Linux and Android, where it may need extra permissions, and on Apple platforms.
+func NewInterfaceBinder(interfaceName string) (SocketProtector, error) {
+	if interfaceName == "" {
+		return nil, errors.New("interface name must not be empty")
+	}
+	iface, err := net.InterfaceByName(interfaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interface %v: %w", interfaceName, err)
+	}
+	if err := checkBindToInterfaceSupported(); err != nil {
+		return nil, err
+	}
+	return &interfaceBinder{iface}, nil
+}
+
+// Protect implements [SocketProtector].
+func (b *interfaceBinder) Protect(fd int) bool {
+	return bindToInterface(fd, b.iface) == nil
+}
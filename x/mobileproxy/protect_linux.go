@@ -0,0 +1,31 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package mobileproxy
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+func checkBindToInterfaceSupported() error {
+	return nil
+}
+
+func bindToInterface(fd int, iface *net.Interface) error {
+	return unix.BindToDevice(fd, iface.Name)
+}
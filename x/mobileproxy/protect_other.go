@@ -0,0 +1,30 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin
+
+package mobileproxy
+
+import (
+	"errors"
+	"net"
+)
+
+func checkBindToInterfaceSupported() error {
+	return errors.ErrUnsupported
+}
+
+func bindToInterface(fd int, iface *net.Interface) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mobileproxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+)
+
+// ProxyStats has the traffic statistics of a [Proxy] since it started.
+// The fields are int64 so they are compatible with Go Mobile.
+type ProxyStats struct {
+	// BytesUploaded is the number of bytes sent to the destinations.
+	BytesUploaded int64
+	// BytesDownloaded is the number of bytes received from the destinations.
+	BytesDownloaded int64
+	// OpenConnections is the number of connections to destinations that are currently open.
+	OpenConnections int64
+	// TotalConnections is the number of connections to destinations that were established.
+	TotalConnections int64
+	// IdleTimeouts is the number of connections that were closed because they were idle for longer than
+	// [ProxyOptions].IdleTimeoutSeconds.
+	IdleTimeouts int64
+	// RejectedRequests is the number of requests rejected because of [ProxyOptions].MaxConnections.
+	RejectedRequests int64
+}
+
+// ConnectionListener receives the connection events from a [Proxy]. Implement it in your app to show live
+// statistics. The methods are called from the goroutines that handle the connections, so they must be fast
+// and thread-safe.
+type ConnectionListener interface {
+	// OnConnectionOpened is called after a connection to address is established.
+	OnConnectionOpened(address string)
+	// OnConnectionClosed is called after the connection to address is closed, with the bytes it transferred.
+	OnConnectionClosed(address string, bytesUploaded int64, bytesDownloaded int64)
+}
+
+// proxyStats tracks the statistics of the connections created by the wrapped dialers.
+type proxyStats struct {
+	openConnections  atomic.Int64
+	idleTimeouts     atomic.Int64
+	rejectedRequests atomic.Int64
+
+	// idleTimeout is how long a connection can go without reads or writes before it's closed. Zero means no timeout.
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	listener ConnectionListener
+	// dialers count the connections and bytes of the wrapped dialers.
+	dialers []*transport.CountingStreamDialer
+}
+
+func (s *proxyStats) snapshot() *ProxyStats {
+	stats := &ProxyStats{
+		OpenConnections:  s.openConnections.Load(),
+		IdleTimeouts:     s.idleTimeouts.Load(),
+		RejectedRequests: s.rejectedRequests.Load(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, dialer := range s.dialers {
+		dialerStats := dialer.Stats()
+		stats.BytesUploaded += dialerStats.BytesWritten
+		stats.BytesDownloaded += dialerStats.BytesRead
+		stats.TotalConnections += dialerStats.Connections
+	}
+	return stats
+}
+
+func (s *proxyStats) setListener(listener ConnectionListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener = listener
+}
+
+func (s *proxyStats) getListener() ConnectionListener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listener
+}
+
+// wrapDialer returns a [transport.StreamDialer] that counts the connections and bytes of dialer, which must not be nil.
+func (s *proxyStats) wrapDialer(dialer transport.StreamDialer) transport.StreamDialer {
+	countingDialer, err := transport.NewCountingStreamDialer(dialer)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	s.dialers = append(s.dialers, countingDialer)
+	s.mu.Unlock()
+	return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		conn, err := countingDialer.DialStream(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		s.openConnections.Add(1)
+		if listener := s.getListener(); listener != nil {
+			listener.OnConnectionOpened(addr)
+		}
+		statsConn := &statsConn{StreamConn: conn, counter: conn.(*transport.CountingStreamConn), stats: s, address: addr}
+		if s.idleTimeout > 0 {
+			// Hold idleMu so the timer can't read idleTimer before it's set.
+			statsConn.idleMu.Lock()
+			statsConn.idleTimer = time.AfterFunc(s.idleTimeout, func() {
+				if statsConn.isClosed() {
+					return
+				}
+				s.idleTimeouts.Add(1)
+				statsConn.Close()
+			})
+			statsConn.idleMu.Unlock()
+		}
+		return statsConn, nil
+	})
+}
+
+// statsConn is a [transport.StreamConn] that reports its bytes to the [ConnectionListener] when it's closed, and
+// closes itself when it's idle.
+type statsConn struct {
+	// StreamConn is the counter. It's embedded as an interface so its ReadFrom and WriteTo, which don't reset the
+	// idle timer, are hidden.
+	transport.StreamConn
+	counter *transport.CountingStreamConn
+	stats   *proxyStats
+	address string
+	// idleMu protects idleTimer and closed, so the idle timer is never reset after Close stops it.
+	idleMu sync.Mutex
+	// idleTimer closes the connection when it fires. It's nil if there's no idle timeout.
+	idleTimer *time.Timer
+	closed    bool
+	closeOnce sync.Once
+}
+
+func (c *statsConn) isClosed() bool {
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+	return c.closed
+}
+
+// resetIdleTimer restarts the idle timeout after some activity.
+func (c *statsConn) resetIdleTimer() {
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+	if c.idleTimer != nil && !c.closed {
+		c.idleTimer.Reset(c.stats.idleTimeout)
+	}
+}
+
+func (c *statsConn) Read(b []byte) (int, error) {
+	n, err := c.StreamConn.Read(b)
+	if n > 0 {
+		c.resetIdleTimer()
+	}
+	return n, err
+}
+
+func (c *statsConn) Write(b []byte) (int, error) {
+	n, err := c.StreamConn.Write(b)
+	if n > 0 {
+		c.resetIdleTimer()
+	}
+	return n, err
+}
+
+func (c *statsConn) Close() error {
+	c.idleMu.Lock()
+	c.closed = true
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+	c.idleMu.Unlock()
+	err := c.StreamConn.Close()
+	c.closeOnce.Do(func() {
+		c.stats.openConnections.Add(-1)
+		if listener := c.stats.getListener(); listener != nil {
+			connStats := c.counter.Stats()
+			listener.OnConnectionClosed(c.address, connStats.BytesWritten, connStats.BytesRead)
+		}
+	})
+	return err
+}
+
+// limitRequests returns a handler that responds with 503 Service Unavailable to the requests that arrive when
+// maxRequests are already in progress. With CONNECT, a request is in progress for as long as the tunnel is open.
+func (s *proxyStats) limitRequests(handler http.Handler, maxRequests int64) http.Handler {
+	var inProgress atomic.Int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inProgress.Add(1) > maxRequests {
+			inProgress.Add(-1)
+			s.rejectedRequests.Add(1)
+			http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+			return
+		}
+		defer inProgress.Add(-1)
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mobileproxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingListener struct {
+	mu     sync.Mutex
+	opened []string
+	closed chan [2]int64
+}
+
+func (l *recordingListener) OnConnectionOpened(address string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.opened = append(l.opened, address)
+}
+
+func (l *recordingListener) OnConnectionClosed(address string, bytesUploaded int64, bytesDownloaded int64) {
+	l.closed <- [2]int64{bytesUploaded, bytesDownloaded}
+}
+
+func TestProxyStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("response"))
+	}))
+	defer server.Close()
+
+	proxy, err := RunProxy("127.0.0.1:0", &StreamDialer{&transport.TCPDialer{}})
+	require.NoError(t, err)
+	defer proxy.Stop(1)
+	listener := &recordingListener{closed: make(chan [2]int64, 1)}
+	proxy.SetConnectionListener(listener)
+	require.Equal(t, &ProxyStats{}, proxy.Stats())
+
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:             http.ProxyURL(&url.URL{Scheme: "http", Host: proxy.Address()}),
+		DisableKeepAlives: true,
+	}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "response", string(body))
+
+	closed := <-listener.closed
+	require.Equal(t, []string{server.Listener.Addr().String()}, listener.opened)
+	require.Positive(t, closed[0])
+	require.Greater(t, closed[1], int64(len("response")))
+	require.Equal(t, &ProxyStats{
+		BytesUploaded:    closed[0],
+		BytesDownloaded:  closed[1],
+		OpenConnections:  0,
+		TotalConnections: 1,
+	}, proxy.Stats())
+}
+
+// connectThroughProxy sends a CONNECT request for targetAddress to the proxy, and returns the connection and the
+// response status code.
+func connectThroughProxy(t *testing.T, proxyAddress string, targetAddress string) (net.Conn, int) {
+	conn, err := net.Dial("tcp", proxyAddress)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("CONNECT " + targetAddress + " HTTP/1.1\r\nHost: " + targetAddress + "\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	return conn, resp.StatusCode
+}
+
+func newEchoListener(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestProxyOptions_MaxConnections(t *testing.T) {
+	target := newEchoListener(t)
+	defer target.Close()
+	proxy, err := RunProxyWithOptions("127.0.0.1:0", &StreamDialer{&transport.TCPDialer{}}, &ProxyOptions{MaxConnections: 1})
+	require.NoError(t, err)
+	defer proxy.Stop(1)
+
+	conn1, status := connectThroughProxy(t, proxy.Address(), target.Addr().String())
+	require.Equal(t, http.StatusOK, status)
+	conn2, status := connectThroughProxy(t, proxy.Address(), target.Addr().String())
+	conn2.Close()
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, int64(1), proxy.Stats().RejectedRequests)
+
+	// Closing the tunnel frees the slot.
+	conn1.Close()
+	require.Eventually(t, func() bool { return proxy.Stats().OpenConnections == 0 }, time.Second, 10*time.Millisecond)
+	conn3, status := connectThroughProxy(t, proxy.Address(), target.Addr().String())
+	conn3.Close()
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestProxyOptions_IdleTimeout(t *testing.T) {
+	target := newEchoListener(t)
+	defer target.Close()
+	proxy, err := RunProxyWithOptions("127.0.0.1:0", &StreamDialer{&transport.TCPDialer{}}, &ProxyOptions{IdleTimeoutSeconds: 1})
+	require.NoError(t, err)
+	defer proxy.Stop(1)
+
+	conn, status := connectThroughProxy(t, proxy.Address(), target.Addr().String())
+	require.Equal(t, http.StatusOK, status)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	response := make([]byte, 4)
+	_, err = io.ReadFull(conn, response)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(response))
+
+	// The proxy closes the tunnel after one idle second.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(response)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, int64(1), proxy.Stats().IdleTimeouts)
+	require.Eventually(t, func() bool { return proxy.Stats().OpenConnections == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestRunProxyWithOptions_Invalid(t *testing.T) {
+	_, err := RunProxyWithOptions("127.0.0.1:0", &StreamDialer{&transport.TCPDialer{}}, &ProxyOptions{MaxConnections: -1})
+	require.Error(t, err)
+}
+
+// nopStreamConn is a [transport.StreamConn] that accepts all writes.
+type nopStreamConn struct {
+	transport.StreamConn
+}
+
+func (nopStreamConn) Write(b []byte) (int, error) { return len(b), nil }
+
+func (nopStreamConn) Close() error { return nil }
+
+func TestStatsConn_NoIdleTimeoutAfterClose(t *testing.T) {
+	stats := &proxyStats{idleTimeout: 20 * time.Millisecond}
+	dialer := stats.wrapDialer(transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return nopStreamConn{}, nil
+	}))
+	conn, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+
+	// The writes keep resetting the idle timer while the connection is closed.
+	stop := make(chan struct{})
+	var writers sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					conn.Write([]byte{0})
+				}
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, conn.Close())
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	writers.Wait()
+
+	// A timer reset after Close would fire now and count an idle timeout.
+	time.Sleep(5 * stats.idleTimeout)
+	require.Equal(t, int64(0), stats.idleTimeouts.Load())
+	require.Equal(t, int64(0), stats.openConnections.Load())
+}
+
+func TestStatsConn_IdleTimeoutFiresDuringDial(t *testing.T) {
+	// The timer can fire before wrapDialer returns, so it must not race with the setup of the connection.
+	stats := &proxyStats{idleTimeout: time.Nanosecond}
+	dialer := stats.wrapDialer(transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return nopStreamConn{}, nil
+	}))
+	_, err := dialer.DialStream(context.Background(), "example.com:443")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return stats.idleTimeouts.Load() == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, int64(0), stats.openConnections.Load())
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/Jigsaw-Code/outline-sdk/x/configurl"
+)
+
+// Group manages several named proxy servers that start and stop together. For example, an app can run a direct
+// proxy and an evasion proxy on different ports.
+//
+// The lifecycle of a Group is:
+//  1. Add the servers with [Group.Add].
+//  2. Start all of them with [Group.Start]. If any of them fails to start, the ones already started are shut down.
+//  3. While running, get the address of each server with [Group.Addr].
+//  4. Stop all of them with [Group.Shutdown], which returns the errors of all servers.
+//
+// A Group can't be restarted after Shutdown. Create a new one instead.
+type Group struct {
+	providers *configurl.ProviderContainer
+
+	mu      sync.Mutex
+	names   []string
+	configs map[string]Config
+	servers map[string]*Server
+	started bool
+	stopped bool
+	wg      sync.WaitGroup
+	// Errors that made servers stop before Shutdown.
+	serveErrs []error
+}
+
+// NewGroup creates an empty [Group]. The transports are created using providers, or the
+// [configurl.NewDefaultProviders] if providers is nil.
+func NewGroup(providers *configurl.ProviderContainer) *Group {
+	return &Group{
+		providers: providers,
+		configs:   make(map[string]Config),
+		servers:   make(map[string]*Server),
+	}
+}
+
+// Add adds a server with the given name and config to the group. It must be called before [Group.Start].
+func (g *Group) Add(name string, config Config) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.started {
+		return errors.New("cannot add a server to a started group")
+	}
+	if _, ok := g.configs[name]; ok {
+		return fmt.Errorf("server %q already exists", name)
+	}
+	g.names = append(g.names, name)
+	g.configs[name] = config
+	return nil
+}
+
+// Start starts all the servers in the group. If any server fails to start, Start shuts down the servers it started
+// and returns the errors of all the servers that failed.
+func (g *Group) Start(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.started {
+		return errors.New("group already started")
+	}
+	g.started = true
+
+	var errs []error
+	for _, name := range g.names {
+		server, err := Listen(ctx, g.providers, g.configs[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to start server %q: %w", name, err))
+			continue
+		}
+		g.servers[name] = server
+	}
+	if len(errs) > 0 {
+		for _, server := range g.servers {
+			server.listener.Close()
+		}
+		g.servers = make(map[string]*Server)
+		g.stopped = true
+		return errors.Join(errs...)
+	}
+
+	for _, name := range g.names {
+		name, server := name, g.servers[name]
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			if err := server.Serve(); err != nil {
+				g.mu.Lock()
+				g.serveErrs = append(g.serveErrs, fmt.Errorf("server %q failed: %w", name, err))
+				g.mu.Unlock()
+			}
+		}()
+	}
+	return nil
+}
+
+// Addr returns the address the named server is listening on, or nil if the server is not running.
+func (g *Group) Addr(name string) net.Addr {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	server, ok := g.servers[name]
+	if !ok {
+		return nil
+	}
+	return server.Addr()
+}
+
+// Shutdown shuts down all the servers in the group, waiting for their active requests to finish until ctx is done.
+// It returns the errors from shutting down the servers, and from servers that had stopped with an error before.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	if g.stopped {
+		g.mu.Unlock()
+		return nil
+	}
+	g.stopped = true
+	servers := g.servers
+	g.servers = make(map[string]*Server)
+	g.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(servers))
+	for name, server := range servers {
+		name, server := name, server
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				errCh <- fmt.Errorf("failed to shut down server %q: %w", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	g.wg.Wait()
+	close(errCh)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	errs := g.serveErrs
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
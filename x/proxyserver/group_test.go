@@ -0,0 +1,96 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fetchViaProxy fetches targetURL using the HTTP proxy at proxyAddr.
+func fetchViaProxy(t *testing.T, proxyAddr net.Addr, targetURL string) string {
+	client := &http.Client{Transport: &http.Transport{
+		Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: proxyAddr.String()}),
+	}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(targetURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestGroup(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("target"))
+	}))
+	defer target.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+
+	group := NewGroup(nil)
+	require.NoError(t, group.Add("direct", Config{Address: "127.0.0.1:0"}))
+	// The override proxy sends all connections to the other server.
+	require.NoError(t, group.Add("override", Config{
+		Address:        "127.0.0.1:0",
+		Transport:      fmt.Sprintf("override:host=127.0.0.1&port=%v", other.Listener.Addr().(*net.TCPAddr).Port),
+		MaxConnections: 10,
+	}))
+	require.Error(t, group.Add("direct", Config{Address: "127.0.0.1:0"}))
+	require.Nil(t, group.Addr("direct"))
+
+	require.NoError(t, group.Start(context.Background()))
+	require.Error(t, group.Add("late", Config{Address: "127.0.0.1:0"}))
+	directAddr := group.Addr("direct")
+	overrideAddr := group.Addr("override")
+	require.NotNil(t, directAddr)
+	require.NotNil(t, overrideAddr)
+	require.NotEqual(t, directAddr.String(), overrideAddr.String())
+
+	require.Equal(t, "target", fetchViaProxy(t, directAddr, target.URL))
+	require.Equal(t, "other", fetchViaProxy(t, overrideAddr, target.URL))
+
+	require.NoError(t, group.Shutdown(context.Background()))
+	require.Nil(t, group.Addr("direct"))
+	_, err := net.Dial("tcp", directAddr.String())
+	require.Error(t, err)
+	_, err = net.Dial("tcp", overrideAddr.String())
+	require.Error(t, err)
+}
+
+func TestGroup_StartFailure(t *testing.T) {
+	group := NewGroup(nil)
+	require.NoError(t, group.Add("good", Config{Address: "127.0.0.1:0"}))
+	require.NoError(t, group.Add("bad-config", Config{Address: "127.0.0.1:0", Transport: "unknown:"}))
+	require.NoError(t, group.Add("bad-address", Config{Address: "invalid address"}))
+
+	err := group.Start(context.Background())
+	require.ErrorContains(t, err, `"bad-config"`)
+	require.ErrorContains(t, err, `"bad-address"`)
+	// The good server was shut down.
+	require.Nil(t, group.Addr("good"))
+	require.NoError(t, group.Shutdown(context.Background()))
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package proxyserver runs local HTTP proxy servers that reach destinations using a transport created from a
+// [configurl] config.
+//
+// Use [Listen] to run a single proxy, or a [Group] to run several independent proxies, each with its own address,
+// config and limits, that start and stop together.
+package proxyserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/Jigsaw-Code/outline-sdk/x/configurl"
+	"github.com/Jigsaw-Code/outline-sdk/x/httpproxy"
+	"golang.org/x/net/netutil"
+)
+
+// Config is the configuration of a proxy server.
+type Config struct {
+	// Address is the local address to listen on, like "localhost:8080". Use port 0 to pick any available port.
+	Address string
+	// Transport is the config of the transport used to reach destinations, in the [configurl] format.
+	// An empty Transport connects to destinations directly.
+	Transport string
+	// MaxConnections is the maximum number of concurrent client connections. Zero means no limit.
+	MaxConnections int
+}
+
+// Server is a local HTTP proxy server that supports CONNECT and absolute URL requests.
+type Server struct {
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+// Listen creates a [Server] listening on config.Address. The transport is created from config.Transport using
+// providers, or the [configurl.NewDefaultProviders] if providers is nil.
+// The server doesn't accept connections until you call [Server.Serve].
+func Listen(ctx context.Context, providers *configurl.ProviderContainer, config Config) (*Server, error) {
+	if providers == nil {
+		providers = configurl.NewDefaultProviders()
+	}
+	if config.MaxConnections < 0 {
+		return nil, fmt.Errorf("MaxConnections = %v must not be negative", config.MaxConnections)
+	}
+	dialer, err := providers.NewStreamDialer(ctx, config.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on address %v: %w", config.Address, err)
+	}
+	if config.MaxConnections > 0 {
+		listener = netutil.LimitListener(listener, config.MaxConnections)
+	}
+
+	// The default http.Server doesn't close hijacked connections or cancel in-flight request contexts during
+	// shutdown. We use a base context that is cancelled on shutdown, so handlers terminate the connections.
+	serverCtx, cancelCtx := context.WithCancelCause(context.Background())
+	httpServer := &http.Server{
+		Handler: httpproxy.NewProxyHandler(dialer),
+		BaseContext: func(l net.Listener) context.Context {
+			return serverCtx
+		},
+	}
+	httpServer.RegisterOnShutdown(func() {
+		cancelCtx(errors.New("server stopped"))
+	})
+	return &Server{listener: listener, httpServer: httpServer}, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve accepts and handles connections until the server is shut down. It returns nil after [Server.Shutdown],
+// or the error that made the server stop otherwise.
+func (s *Server) Serve() error {
+	err := s.httpServer.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for the active requests to finish, until ctx is done.
+// If ctx is done first, it closes the remaining connections and returns the context error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.httpServer.Close()
+		return err
+	}
+	return nil
+}
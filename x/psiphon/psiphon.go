@@ -47,6 +47,19 @@ type DialerConfig struct {
 
 	// Raw JSON config provided by Psiphon.
 	ProviderConfig json.RawMessage
+
+	// Called when the tunnel information changes, for example when Psiphon establishes a tunnel, or replaces it with
+	// a tunnel to another server. See [Dialer.TunnelInfo]. It must not block. Optional.
+	OnTunnelInfo func(info TunnelInfo)
+}
+
+// TunnelInfo describes the active Psiphon tunnel.
+type TunnelInfo struct {
+	// The region of the Psiphon server, as a two-letter country code, such as "US".
+	// It may be empty right after the tunnel is established, until Psiphon reports it.
+	ServerRegion string
+	// The tunnel protocol, such as "OSSH" or "QUIC-OSSH".
+	Protocol string
 }
 
 // Dialer is a [transport.StreamDialer] that uses Psiphon to connect to a destination.
@@ -56,6 +69,12 @@ type DialerConfig struct {
 // The Dialer must be configured first with [Dialer.Start] before it can be used, and [Dialer.Stop] must be
 // called before you can start it again with a new configuration. Dialer.Stop should be called
 // when you no longer need the Dialer in order to release resources.
+//
+// The Psiphon library keeps process-wide state, including the datastore and the notices, so it can only run one
+// tunnel per process. To compare configurations, run them one after the other, stopping the Dialer and starting it
+// with the next [DialerConfig], each with its own DataRootDirectory. To run them side by side, use separate
+// processes. Each tunnel has its own connections and goroutines, and its data directory holds the server lists it
+// downloads, which can take a few megabytes.
 type Dialer struct {
 	// Controls the Dialer state and Psiphon's global state.
 	mu sync.Mutex
@@ -63,6 +82,12 @@ type Dialer struct {
 	tunnel psiphonTunnel
 	// Used by Stop.
 	stop func()
+
+	// Protects the tunnel info, which is updated by the Psiphon notices. It's separate from mu because
+	// the notices may be delivered while mu is held.
+	infoMu    sync.Mutex
+	info      TunnelInfo
+	connected bool
 }
 
 type psiphonTunnel interface {
@@ -98,10 +123,13 @@ func getClientPlatform() string {
 	return "outline-sdk_" + goos + "_" + goarch
 }
 
+// noticeHandler gets the Psiphon notices, with their type and data.
+type noticeHandler func(noticeType string, data map[string]any)
+
 // Allows for overriding in tests.
-var startTunnel func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) = psiphonStartTunnel
+var startTunnel func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) = psiphonStartTunnel
 
-func psiphonStartTunnel(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+func psiphonStartTunnel(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 	if config == nil {
 		return nil, errors.New("config must not be nil")
 	}
@@ -117,7 +145,9 @@ func psiphonStartTunnel(ctx context.Context, config *DialerConfig) (psiphonTunne
 		DisableLocalHTTPProxy:  &trueValue,
 	}
 
-	return clientlib.StartTunnel(ctx, config.ProviderConfig, "", params, nil, nil)
+	return clientlib.StartTunnel(ctx, config.ProviderConfig, "", params, nil, func(notice clientlib.NoticeEvent) {
+		onNotice(notice.Type, notice.Data)
+	})
 }
 
 // Start configures and runs the Dialer. It must be called before you can use the Dialer. It returns when the tunnel is ready.
@@ -149,7 +179,14 @@ func (d *Dialer) Start(ctx context.Context, config *DialerConfig) error {
 
 		d.mu.Unlock()
 
-		tunnel, err := startTunnel(ctx, config)
+		var onTunnelInfo func(TunnelInfo)
+		if config != nil {
+			onTunnelInfo = config.OnTunnelInfo
+		}
+		defer d.clearTunnelInfo()
+		tunnel, err := startTunnel(ctx, config, func(noticeType string, data map[string]any) {
+			d.handleNotice(noticeType, data, onTunnelInfo)
+		})
 
 		d.mu.Lock()
 
@@ -175,6 +212,50 @@ func (d *Dialer) Start(ctx context.Context, config *DialerConfig) error {
 	return <-resultCh
 }
 
+// TunnelInfo returns the information of the active tunnel, and whether there is one. Psiphon may replace the tunnel
+// when it fails, so the information may change over time. Use [DialerConfig].OnTunnelInfo to be notified of changes.
+func (d *Dialer) TunnelInfo() (TunnelInfo, bool) {
+	d.infoMu.Lock()
+	defer d.infoMu.Unlock()
+	return d.info, d.connected
+}
+
+// handleNotice updates the tunnel info from the Psiphon notices, and calls onTunnelInfo if it changed.
+func (d *Dialer) handleNotice(noticeType string, data map[string]any, onTunnelInfo func(TunnelInfo)) {
+	d.infoMu.Lock()
+	info := d.info
+	connected := d.connected
+	switch noticeType {
+	case "ActiveTunnel":
+		info.Protocol, _ = data["protocol"].(string)
+		connected = true
+	case "ConnectedServerRegion":
+		info.ServerRegion, _ = data["serverRegion"].(string)
+	case "Tunnels":
+		// The count is a JSON number.
+		if count, ok := data["count"].(float64); ok && count == 0 {
+			info = TunnelInfo{}
+			connected = false
+		}
+	default:
+		d.infoMu.Unlock()
+		return
+	}
+	changed := info != d.info || connected != d.connected
+	d.info, d.connected = info, connected
+	d.infoMu.Unlock()
+	if changed && connected && onTunnelInfo != nil {
+		onTunnelInfo(info)
+	}
+}
+
+func (d *Dialer) clearTunnelInfo() {
+	d.infoMu.Lock()
+	defer d.infoMu.Unlock()
+	d.info = TunnelInfo{}
+	d.connected = false
+}
+
 // Stop stops the Dialer background processes, releasing resources and allowing it to be reconfigured.
 // It returns when the Dialer is completely stopped.
 func (d *Dialer) Stop() error {
@@ -202,9 +283,16 @@ type streamConn struct {
 var _ transport.StreamConn = (*streamConn)(nil)
 
 func (c streamConn) CloseWrite() error {
+	// Propagate the half-close if the tunneled connection supports it.
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
 	return nil
 }
 
 func (c streamConn) CloseRead() error {
+	if cr, ok := c.Conn.(interface{ CloseRead() error }); ok {
+		return cr.CloseRead()
+	}
 	return nil
 }
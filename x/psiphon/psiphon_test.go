@@ -43,7 +43,7 @@ func newTestConfig(tb testing.TB) (*DialerConfig, func()) {
 
 func TestDialer_Start_Successful(t *testing.T) {
 	dialer := GetSingletonDialer()
-	startTunnel = func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 		return &clientlib.PsiphonTunnel{}, nil
 	}
 	defer func() {
@@ -62,10 +62,78 @@ func TestDialer_Start_Successful(t *testing.T) {
 	require.NoError(t, dialer.Stop())
 }
 
+func TestDialer_RestartWithNewConfig(t *testing.T) {
+	var dataDirs []string
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
+		dataDirs = append(dataDirs, config.DataRootDirectory)
+		return &errorTunnel{}, nil
+	}
+	defer func() {
+		startTunnel = psiphonStartTunnel
+	}()
+
+	// Configurations are compared one after the other, since only one tunnel can run at a time.
+	ctx := context.Background()
+	dialer := GetSingletonDialer()
+	require.NoError(t, dialer.Start(ctx, &DialerConfig{DataRootDirectory: "first"}))
+	require.ErrorIs(t, dialer.Start(ctx, &DialerConfig{DataRootDirectory: "second"}), errAlreadyStarted)
+	require.NoError(t, dialer.Stop())
+	require.NoError(t, dialer.Start(ctx, &DialerConfig{DataRootDirectory: "second"}))
+	require.NoError(t, dialer.Stop())
+	require.Equal(t, []string{"first", "second"}, dataDirs)
+}
+
+func TestDialer_TunnelInfo(t *testing.T) {
+	notices := make(chan noticeHandler, 1)
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
+		notices <- onNotice
+		return &errorTunnel{}, nil
+	}
+	defer func() {
+		startTunnel = psiphonStartTunnel
+	}()
+
+	var updates []TunnelInfo
+	dialer := GetSingletonDialer()
+	_, ok := dialer.TunnelInfo()
+	require.False(t, ok)
+	require.NoError(t, dialer.Start(context.Background(), &DialerConfig{
+		OnTunnelInfo: func(info TunnelInfo) { updates = append(updates, info) },
+	}))
+	onNotice := <-notices
+	onNotice("ActiveTunnel", map[string]any{"protocol": "OSSH"})
+	onNotice("ConnectedServerRegion", map[string]any{"serverRegion": "US"})
+	onNotice("Info", map[string]any{"message": "ignored"})
+	info, ok := dialer.TunnelInfo()
+	require.True(t, ok)
+	require.Equal(t, TunnelInfo{ServerRegion: "US", Protocol: "OSSH"}, info)
+
+	// The tunnel is lost, and replaced.
+	onNotice("Tunnels", map[string]any{"count": float64(0)})
+	_, ok = dialer.TunnelInfo()
+	require.False(t, ok)
+	onNotice("ActiveTunnel", map[string]any{"protocol": "QUIC-OSSH"})
+	onNotice("ConnectedServerRegion", map[string]any{"serverRegion": "DE"})
+	info, ok = dialer.TunnelInfo()
+	require.True(t, ok)
+	require.Equal(t, TunnelInfo{ServerRegion: "DE", Protocol: "QUIC-OSSH"}, info)
+
+	require.Equal(t, []TunnelInfo{
+		{Protocol: "OSSH"},
+		{ServerRegion: "US", Protocol: "OSSH"},
+		{Protocol: "QUIC-OSSH"},
+		{ServerRegion: "DE", Protocol: "QUIC-OSSH"},
+	}, updates)
+
+	require.NoError(t, dialer.Stop())
+	_, ok = dialer.TunnelInfo()
+	require.False(t, ok)
+}
+
 func TestDialer_StopOnStart(t *testing.T) {
 	dialer := GetSingletonDialer()
 	startCalled := make(chan struct{})
-	startTunnel = func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 		startCalled <- struct{}{}
 		select {
 		case <-ctx.Done():
@@ -88,7 +156,7 @@ func TestDialer_StopOnStart(t *testing.T) {
 func TestDialer_StartOnStart(t *testing.T) {
 	dialer := GetSingletonDialer()
 	startCalled := make(chan struct{})
-	startTunnel = func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 		startCalled <- struct{}{}
 		select {
 		case <-ctx.Done():
@@ -104,7 +172,7 @@ func TestDialer_StartOnStart(t *testing.T) {
 		resultCh <- dialer.Start(context.Background(), nil)
 	}()
 	<-startCalled
-	startTunnel = func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 		return nil, errors.New("failed to start")
 	}
 	require.ErrorIs(t, dialer.Start(context.Background(), nil), errAlreadyStarted)
@@ -166,7 +234,7 @@ func TestDialer_DialStream(t *testing.T) {
 	require.ErrorIs(t, err, errNotStartedDial)
 
 	var tunnel errorTunnel
-	startTunnel = func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 		tunnel.stopped = false
 		return &tunnel, nil
 	}
@@ -199,7 +267,7 @@ func TestDialer_DialStream_Error(t *testing.T) {
 	tunnel := errorTunnel{
 		err: errors.New("failed to dial"),
 	}
-	startTunnel = func(ctx context.Context, config *DialerConfig) (psiphonTunnel, error) {
+	startTunnel = func(ctx context.Context, config *DialerConfig, onNotice noticeHandler) (psiphonTunnel, error) {
 		tunnel.stopped = false
 		return &tunnel, nil
 	}
@@ -0,0 +1,304 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	quicgo "github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// DefaultConnectUDPTemplate is the default URI template for CONNECT-UDP requests, as per
+// https://datatracker.ietf.org/doc/html/rfc9298#section-3.
+const DefaultConnectUDPTemplate = "/.well-known/masque/udp/{target_host}/{target_port}/"
+
+// ConnectUDPPacketDialer is a [transport.PacketDialer] that proxies UDP over HTTP/3 datagrams with
+// CONNECT-UDP ([RFC 9298]), as implemented by MASQUE proxies.
+//
+// Each dialed packet connection uses a new QUIC connection to the proxy.
+//
+// [RFC 9298]: https://datatracker.ietf.org/doc/html/rfc9298
+type ConnectUDPPacketDialer struct {
+	dialer    transport.PacketDialer
+	proxyAddr string
+	template  string
+	options   []ClientOption
+}
+
+var _ transport.PacketDialer = (*ConnectUDPPacketDialer)(nil)
+
+// NewConnectUDPPacketDialer creates a [ConnectUDPPacketDialer] that connects to the MASQUE proxy at proxyAddr
+// using packetDialer to carry the QUIC packets. The template is the path of the URI template for the requests,
+// with the {target_host} and {target_port} variables. If empty, it defaults to [DefaultConnectUDPTemplate].
+// The options configure the QUIC connection to the proxy. The server name defaults to the proxy host.
+func NewConnectUDPPacketDialer(packetDialer transport.PacketDialer, proxyAddr string, template string, options ...ClientOption) (*ConnectUDPPacketDialer, error) {
+	if packetDialer == nil {
+		return nil, errors.New("argument packetDialer must not be nil")
+	}
+	if _, _, err := net.SplitHostPort(proxyAddr); err != nil {
+		return nil, fmt.Errorf("invalid proxy address: %w", err)
+	}
+	if template == "" {
+		template = DefaultConnectUDPTemplate
+	}
+	if !strings.HasPrefix(template, "/") || !strings.Contains(template, "{target_host}") || !strings.Contains(template, "{target_port}") {
+		return nil, fmt.Errorf("template must be a path with {target_host} and {target_port}, found %q", template)
+	}
+	return &ConnectUDPPacketDialer{packetDialer, proxyAddr, template, options}, nil
+}
+
+// expandTemplate returns the request path for the given target, with the variables percent-encoded.
+func expandTemplate(template, host, port string) string {
+	// IPv6 addresses have colons, which must be escaped.
+	host = strings.ReplaceAll(url.PathEscape(host), ":", "%3A")
+	return strings.NewReplacer("{target_host}", host, "{target_port}", url.PathEscape(port)).Replace(template)
+}
+
+// DialPacket implements [transport.PacketDialer].DialPacket.
+// It connects to the proxy and asks it to relay the packets to remoteAddr. The context is only used for the setup.
+func (d *ConnectUDPPacketDialer) DialPacket(ctx context.Context, remoteAddr string) (net.Conn, error) {
+	targetHost, targetPort, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	netAddr, err := transport.MakeNetAddr("udp", remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	proxyHost, _, _ := net.SplitHostPort(d.proxyAddr)
+	proxyHost = strings.ToLower(proxyHost)
+	cfg := ClientConfig{ServerName: proxyHost, CertificateName: proxyHost, NextProtos: []string{"h3"}}
+	for _, option := range d.options {
+		option(&cfg)
+	}
+
+	packetConn, err := d.dialer.DialPacket(ctx, d.proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	quicConn, err := quicgo.Dial(ctx, &connectedPacketConn{packetConn}, packetConn.RemoteAddr(), cfg.toStdConfig(), &quicgo.Config{EnableDatagrams: true})
+	if err != nil {
+		packetConn.Close()
+		return nil, err
+	}
+	conn, err := d.connectUDP(ctx, quicConn, targetHost, targetPort)
+	if err != nil {
+		quicConn.CloseWithError(quicgo.ApplicationErrorCode(http3.ErrCodeNoError), "")
+		packetConn.Close()
+		return nil, err
+	}
+	conn.packetConn = packetConn
+	conn.remoteAddr = netAddr
+	go conn.readCapsules()
+	return conn, nil
+}
+
+// connectUDP sends the CONNECT-UDP request over the QUIC connection and checks the response.
+func (d *ConnectUDPPacketDialer) connectUDP(ctx context.Context, quicConn quicgo.Connection, targetHost, targetPort string) (*connectUDPConn, error) {
+	h3Transport := &http3.Transport{EnableDatagrams: true}
+	clientConn := h3Transport.NewClientConn(quicConn)
+	select {
+	case <-clientConn.ReceivedSettings():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-quicConn.Context().Done():
+		return nil, fmt.Errorf("connection closed before receiving the settings: %w", context.Cause(quicConn.Context()))
+	}
+	settings := clientConn.Settings()
+	if !settings.EnableExtendedConnect {
+		return nil, errors.New("proxy does not support Extended CONNECT")
+	}
+	if !settings.EnableDatagrams {
+		return nil, errors.New("proxy does not support HTTP datagrams")
+	}
+
+	requestURL, err := url.Parse("https://" + d.proxyAddr + expandTemplate(d.template, targetHost, targetPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request URL: %w", err)
+	}
+	stream, err := clientConn.OpenRequestStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request stream: %w", err)
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		Proto:  "connect-udp",
+		Host:   requestURL.Host,
+		Header: http.Header{http3.CapsuleProtocolHeader: []string{"?1"}},
+		URL:    requestURL,
+	}
+	if err := stream.SendRequestHeader(req.WithContext(ctx)); err != nil {
+		stream.CancelRead(quicgo.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	resp, err := stream.ReadResponse()
+	if err != nil {
+		stream.CancelRead(quicgo.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		stream.CancelRead(quicgo.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, fmt.Errorf("proxy responded with status %v", resp.Status)
+	}
+	return &connectUDPConn{stream: stream, quicConn: quicConn}, nil
+}
+
+// connectUDPConn is a [net.Conn] that sends and receives UDP payloads as HTTP datagrams on a CONNECT-UDP stream.
+type connectUDPConn struct {
+	stream     http3.RequestStream
+	quicConn   quicgo.Connection
+	packetConn net.Conn
+	remoteAddr net.Addr
+
+	mu           sync.Mutex
+	readDeadline time.Time
+	// cancelRead aborts a pending read when the read deadline changes.
+	cancelRead context.CancelFunc
+
+	closeOnce sync.Once
+}
+
+var _ net.Conn = (*connectUDPConn)(nil)
+
+// The Context ID for UDP payloads, as per https://datatracker.ietf.org/doc/html/rfc9298#section-4.
+const udpPayloadContextID = 0
+
+// readCapsules reads and skips the capsules sent by the proxy, as none are defined for CONNECT-UDP.
+// The connection is closed when the request stream ends.
+func (c *connectUDPConn) readCapsules() {
+	reader := quicvarint.NewReader(c.stream)
+	for {
+		_, capsuleReader, err := http3.ParseCapsule(reader)
+		if err != nil {
+			c.Close()
+			return
+		}
+		if _, err := io.Copy(io.Discard, capsuleReader); err != nil {
+			c.Close()
+			return
+		}
+	}
+}
+
+func (c *connectUDPConn) Read(b []byte) (int, error) {
+	for {
+		ctx, cancel, err := c.readContext()
+		if err != nil {
+			return 0, err
+		}
+		datagram, err := c.stream.ReceiveDatagram(ctx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return 0, os.ErrDeadlineExceeded
+			}
+			if errors.Is(err, context.Canceled) && c.quicConn.Context().Err() == nil {
+				// The deadline changed. Retry with the new deadline.
+				continue
+			}
+			return 0, err
+		}
+		contextID, n, err := quicvarint.Parse(datagram)
+		if err != nil || contextID != udpPayloadContextID {
+			// Drop datagrams we don't understand, as per the RFC.
+			continue
+		}
+		payload := datagram[n:]
+		copied := copy(b, payload)
+		if copied < len(payload) {
+			return copied, io.ErrShortBuffer
+		}
+		return copied, nil
+	}
+}
+
+// readContext returns a context for the next read that expires with the read deadline.
+func (c *connectUDPConn) readContext() (context.Context, context.CancelFunc, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.readDeadline.IsZero() && !time.Now().Before(c.readDeadline) {
+		return nil, nil, os.ErrDeadlineExceeded
+	}
+	var ctx context.Context
+	if c.readDeadline.IsZero() {
+		ctx, c.cancelRead = context.WithCancel(c.quicConn.Context())
+	} else {
+		ctx, c.cancelRead = context.WithDeadline(c.quicConn.Context(), c.readDeadline)
+	}
+	return ctx, c.cancelRead, nil
+}
+
+func (c *connectUDPConn) Write(b []byte) (int, error) {
+	datagram := make([]byte, 0, quicvarint.Len(udpPayloadContextID)+len(b))
+	datagram = quicvarint.Append(datagram, udpPayloadContextID)
+	datagram = append(datagram, b...)
+	if err := c.stream.SendDatagram(datagram); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the request stream, the QUIC connection and the underlying packet connection.
+func (c *connectUDPConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.stream.CancelRead(quicgo.StreamErrorCode(http3.ErrCodeNoError))
+		streamErr := c.stream.Close()
+		connErr := c.quicConn.CloseWithError(quicgo.ApplicationErrorCode(http3.ErrCodeNoError), "")
+		err = errors.Join(streamErr, connErr, c.packetConn.Close())
+	})
+	return err
+}
+
+func (c *connectUDPConn) LocalAddr() net.Addr {
+	return c.quicConn.LocalAddr()
+}
+
+func (c *connectUDPConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *connectUDPConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for Read, aborting any pending read.
+func (c *connectUDPConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	if c.cancelRead != nil {
+		c.cancelRead()
+	}
+	return nil
+}
+
+// SetWriteDeadline is a no-op, since datagrams are sent without blocking.
+func (c *connectUDPConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/require"
+)
+
+// startConnectUDPEchoProxy starts a MASQUE proxy that echoes the datagrams instead of relaying them.
+func startConnectUDPEchoProxy(t *testing.T, cert tls.Certificate, requests chan<- *http.Request) (net.PacketConn, *http3.Server) {
+	server := &http3.Server{
+		TLSConfig:       &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"h3"}},
+		EnableDatagrams: true,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests <- r
+			if r.Method != http.MethodConnect || r.Proto != "connect-udp" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set(http3.CapsuleProtocolHeader, "?1")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			stream := w.(http3.HTTPStreamer).HTTPStream()
+			for {
+				datagram, err := stream.ReceiveDatagram(r.Context())
+				if err != nil {
+					return
+				}
+				if err := stream.SendDatagram(datagram); err != nil {
+					return
+				}
+			}
+		}),
+	}
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(udpConn)
+	return udpConn, server
+}
+
+func TestConnectUDPPacketDialer(t *testing.T) {
+	cert := newSelfSignedCert(t, "example.com")
+	requests := make(chan *http.Request, 1)
+	udpConn, server := startConnectUDPEchoProxy(t, cert, requests)
+	defer udpConn.Close()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots.AddCert(leaf)
+	dialer, err := NewConnectUDPPacketDialer(&transport.UDPDialer{}, udpConn.LocalAddr().String(), "",
+		WithCertificateName("example.com"), WithRootCAs(roots))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialer.DialPacket(ctx, "[2001:db8::1]:53")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req := <-requests
+	require.Equal(t, "/.well-known/masque/udp/2001%3Adb8%3A%3A1/53/", req.URL.EscapedPath())
+	require.Equal(t, "?1", req.Header.Get(http3.CapsuleProtocolHeader))
+	require.Equal(t, "[2001:db8::1]:53", conn.RemoteAddr().String())
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 100)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf[:n]))
+
+	// Reads time out with the deadline.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	_, err = conn.Read(buf)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestConnectUDPPacketDialer_CustomTemplate(t *testing.T) {
+	cert := newSelfSignedCert(t, "example.com")
+	requests := make(chan *http.Request, 1)
+	udpConn, server := startConnectUDPEchoProxy(t, cert, requests)
+	defer udpConn.Close()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots.AddCert(leaf)
+	dialer, err := NewConnectUDPPacketDialer(&transport.UDPDialer{}, udpConn.LocalAddr().String(), "/{target_host}/{target_port}",
+		WithCertificateName("example.com"), WithRootCAs(roots))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialer.DialPacket(ctx, "example.com:53")
+	require.NoError(t, err)
+	conn.Close()
+	require.Equal(t, "/example.com/53", (<-requests).URL.Path)
+}
+
+func TestNewConnectUDPPacketDialer_InvalidTemplate(t *testing.T) {
+	_, err := NewConnectUDPPacketDialer(&transport.UDPDialer{}, "proxy.example:443", "/udp/{target_host}")
+	require.Error(t, err)
+	_, err = NewConnectUDPPacketDialer(&transport.UDPDialer{}, "proxy.example", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quic provides a [transport.StreamDialer] that sends each stream over a [QUIC] connection, using a
+// [transport.PacketDialer] to carry the QUIC packets.
+//
+// This lets you compose QUIC with the packet transports, for example to test QUIC-based circumvention through a
+// SOCKS5 proxy with UDP support. The TLS handshake is part of the QUIC handshake, so the server name is sent in the
+// SNI of the QUIC Initial packet.
+//
+// [QUIC]: https://datatracker.ietf.org/doc/html/rfc9000
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	quicgo "github.com/quic-go/quic-go"
+)
+
+// StreamDialer is a [transport.StreamDialer] that creates a QUIC connection for each dialed stream.
+type StreamDialer struct {
+	dialer  transport.PacketDialer
+	options []ClientOption
+}
+
+var _ transport.StreamDialer = (*StreamDialer)(nil)
+
+// NewStreamDialer creates a [StreamDialer] that establishes QUIC connections over the packet connections from
+// packetDialer, configured with the given options.
+func NewStreamDialer(packetDialer transport.PacketDialer, options ...ClientOption) (*StreamDialer, error) {
+	if packetDialer == nil {
+		return nil, errors.New("argument packetDialer must not be nil")
+	}
+	return &StreamDialer{packetDialer, options}, nil
+}
+
+// ClientConfig encodes the parameters for a QUIC client connection.
+type ClientConfig struct {
+	// The host name for the Server Name Indication (SNI).
+	ServerName string
+	// The hostname to use for certificate validation.
+	CertificateName string
+	// The protocol id list for protocol negotiation (ALPN). QUIC requires ALPN.
+	NextProtos []string
+	// The root certificates to validate the server certificate against. If nil, the system roots are used.
+	RootCAs *x509.CertPool
+}
+
+// ClientOption allows configuring the parameters to be used for a QUIC client connection.
+type ClientOption func(config *ClientConfig)
+
+// WithSNI sets the host name for the Server Name Indication (SNI). If absent, defaults to the dialed hostname.
+// Note that this only changes what is sent in the SNI, not what host is used for certificate verification.
+func WithSNI(hostName string) ClientOption {
+	return func(config *ClientConfig) {
+		config.ServerName = hostName
+	}
+}
+
+// WithCertificateName sets the hostname to be used for the certificate verification.
+// If absent, defaults to the dialed hostname.
+func WithCertificateName(hostName string) ClientOption {
+	return func(config *ClientConfig) {
+		config.CertificateName = hostName
+	}
+}
+
+// WithALPN sets the protocol name list for Application-Layer Protocol Negotiation (ALPN).
+// If absent, defaults to "h3".
+func WithALPN(protocolNameList []string) ClientOption {
+	return func(config *ClientConfig) {
+		config.NextProtos = protocolNameList
+	}
+}
+
+// WithRootCAs sets the root certificates to validate the server certificate against, instead of the system roots.
+// Use it to connect to servers with certificates issued by a private certificate authority.
+func WithRootCAs(roots *x509.CertPool) ClientOption {
+	return func(config *ClientConfig) {
+		config.RootCAs = roots
+	}
+}
+
+// toStdConfig creates a [tls.Config] based on the configured parameters.
+func (cfg *ClientConfig) toStdConfig() *tls.Config {
+	return &tls.Config{
+		ServerName: cfg.ServerName,
+		NextProtos: cfg.NextProtos,
+		RootCAs:    cfg.RootCAs,
+		// Set InsecureSkipVerify to skip the default validation we are
+		// replacing. This will not disable VerifyConnection.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			opts := x509.VerifyOptions{
+				DNSName:       cfg.CertificateName,
+				Roots:         cfg.RootCAs,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+// It creates a new QUIC connection and opens a bidirectional stream on it. Closing the stream closes the connection.
+func (d *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	host = strings.ToLower(host)
+	cfg := ClientConfig{ServerName: host, CertificateName: host, NextProtos: []string{"h3"}}
+	for _, option := range d.options {
+		option(&cfg)
+	}
+
+	packetConn, err := d.dialer.DialPacket(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	quicConn, err := quicgo.Dial(ctx, &connectedPacketConn{packetConn}, packetConn.RemoteAddr(), cfg.toStdConfig(), nil)
+	if err != nil {
+		packetConn.Close()
+		return nil, err
+	}
+	stream, err := quicConn.OpenStreamSync(ctx)
+	if err != nil {
+		quicConn.CloseWithError(0, "")
+		packetConn.Close()
+		return nil, err
+	}
+	return &streamConn{Stream: stream, conn: quicConn, packetConn: packetConn}, nil
+}
+
+// streamConn is a [transport.StreamConn] for a QUIC stream that owns its QUIC connection.
+type streamConn struct {
+	quicgo.Stream
+	conn       quicgo.Connection
+	packetConn net.Conn
+}
+
+var _ transport.StreamConn = (*streamConn)(nil)
+
+// CloseWrite closes the send direction of the stream, which sends a FIN to the peer.
+func (c *streamConn) CloseWrite() error {
+	return c.Stream.Close()
+}
+
+// CloseRead aborts receiving on the stream.
+func (c *streamConn) CloseRead() error {
+	c.Stream.CancelRead(0)
+	return nil
+}
+
+// Close closes the stream, the QUIC connection and the underlying packet connection.
+func (c *streamConn) Close() error {
+	c.Stream.CancelRead(0)
+	streamErr := c.Stream.Close()
+	connErr := c.conn.CloseWithError(0, "")
+	return errors.Join(streamErr, connErr, c.packetConn.Close())
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// connectedPacketConn adapts a connected [net.Conn] to the [net.PacketConn] that QUIC needs.
+// All packets are sent to, and received from, the connected address.
+type connectedPacketConn struct {
+	net.Conn
+}
+
+var _ net.PacketConn = (*connectedPacketConn)(nil)
+
+func (c *connectedPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, err := c.Conn.Read(p)
+	return n, c.Conn.RemoteAddr(), err
+}
+
+func (c *connectedPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	quicgo "github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamDialer(t *testing.T) {
+	cert := newSelfSignedCert(t, "example.com")
+	serverNameCh := make(chan string, 1)
+	listener, err := quicgo.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"echo"},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNameCh <- hello.ServerName
+			return nil, nil
+		},
+	}, nil)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		// Echo until the client closes its side, then close ours.
+		io.Copy(stream, stream)
+		stream.Close()
+	}()
+
+	roots := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots.AddCert(leaf)
+	dialer, err := NewStreamDialer(&transport.UDPDialer{},
+		WithSNI("decoy.example"), WithCertificateName("example.com"), WithALPN([]string{"echo"}), WithRootCAs(roots))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialer.DialStream(ctx, listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, "decoy.example", <-serverNameCh)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(response))
+}
+
+func TestStreamDialer_BadCertificate(t *testing.T) {
+	listener, err := quicgo.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{newSelfSignedCert(t, "example.com")},
+		NextProtos:   []string{"h3"},
+	}, nil)
+	require.NoError(t, err)
+	defer listener.Close()
+	go listener.Accept(context.Background())
+
+	dialer, err := NewStreamDialer(&transport.UDPDialer{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = dialer.DialStream(ctx, listener.Addr().String())
+	require.Error(t, err)
+}
+
+func TestNewStreamDialer_NilDialer(t *testing.T) {
+	_, err := NewStreamDialer(nil)
+	require.Error(t, err)
+}
+
+// Private test helpers
+
+func newSelfSignedCert(t *testing.T, names ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"runtime"
+	"time"
+)
+
+// Version is the version of the report package, sent in each [Envelope].
+// Bump it when the report format or the collection behavior changes.
+const Version = "0.1.0"
+
+// Envelope wraps a report with metadata about the environment it was created in,
+// so collectors receive consistent context regardless of the report type.
+type Envelope struct {
+	// OS is the operating system the report was created on, as in [runtime.GOOS].
+	OS string `json:"os"`
+	// Arch is the architecture the report was created on, as in [runtime.GOARCH].
+	Arch string `json:"arch"`
+	// Version is the [Version] of the report package that created the envelope.
+	Version string `json:"version"`
+	// Time is when the envelope was created, in UTC.
+	Time time.Time `json:"time"`
+	// Payload is the wrapped report.
+	Payload Report `json:"payload"`
+}
+
+var _ HasSuccess = (*Envelope)(nil)
+
+// NewEnvelope wraps the payload in an [Envelope] with the metadata for the current environment.
+func NewEnvelope(payload Report) Report {
+	return &Envelope{
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Version: Version,
+		Time:    time.Now().UTC(),
+		Payload: payload,
+	}
+}
+
+// IsSuccess returns the success status of the payload, so envelopes work with [SamplingCollector].
+// It returns false if the payload doesn't implement [HasSuccess].
+func (e *Envelope) IsSuccess() bool {
+	hs, ok := e.Payload.(HasSuccess)
+	return ok && hs.IsSuccess()
+}
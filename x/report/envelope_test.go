@@ -0,0 +1,55 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEnvelope(t *testing.T) {
+	payload := ConnectivityReport{
+		Connection: ConnectivitySetup{Proxy: "example.com:443", Proto: "tcp"},
+		DurationMs: 12,
+		Error:      ConnectivityError{Op: "connect", Msg: "refused"},
+	}
+	before := time.Now()
+	r := NewEnvelope(payload)
+	envelope, ok := r.(*Envelope)
+	require.True(t, ok)
+	require.Equal(t, runtime.GOOS, envelope.OS)
+	require.Equal(t, runtime.GOARCH, envelope.Arch)
+	require.Equal(t, Version, envelope.Version)
+	require.WithinRange(t, envelope.Time, before, time.Now())
+	require.Equal(t, payload, envelope.Payload)
+	// The success status comes from the payload.
+	require.False(t, envelope.IsSuccess())
+
+	jsonData, err := json.Marshal(r)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(jsonData, &decoded))
+	require.Equal(t, runtime.GOOS, decoded["os"])
+	require.Equal(t, Version, decoded["version"])
+	require.Equal(t, "connect", decoded["payload"].(map[string]any)["error"].(map[string]any)["operation"])
+}
+
+func TestEnvelopeIsSuccessWithoutHasSuccess(t *testing.T) {
+	require.False(t, NewEnvelope("not a HasSuccess").(HasSuccess).IsSuccess())
+}
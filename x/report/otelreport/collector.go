@@ -0,0 +1,140 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelreport provides a [report.Collector] that emits the reports as OpenTelemetry log records, so they can
+// be exported with the OTLP exporter to an existing OpenTelemetry pipeline.
+//
+// It's a separate package so that only the users of OpenTelemetry depend on it. The collector only uses the
+// OpenTelemetry Logs API. You configure the export with the SDK, for example:
+//
+//	exporter, err := otlploghttp.New(ctx, otlploghttp.WithEndpoint("collector.example.com"))
+//	...
+//	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+//	defer provider.Shutdown(ctx)
+//	collector := &otelreport.Collector{Logger: provider.Logger("github.com/Jigsaw-Code/outline-sdk/x/report")}
+package otelreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/x/report"
+	"go.opentelemetry.io/otel/log"
+)
+
+// Collector is a [report.Collector] that emits each report as an OpenTelemetry log record.
+//
+// The body of the record is the report converted to JSON and then to a log value, so JSON objects become maps,
+// arrays become slices and numbers become integers when they have no fraction. If the report implements
+// [report.HasSuccess], the record has a "report.success" attribute, and failures have the WARN severity instead of
+// INFO.
+//
+// Collect returns after the record is handed to the Logger. Exporting happens in the background, according to the
+// processor configured in the SDK, and export errors are reported by the SDK, not by Collect.
+type Collector struct {
+	Logger log.Logger
+}
+
+var _ report.Collector = (*Collector)(nil)
+
+// Collect implements [report.Collector].
+func (c *Collector) Collect(ctx context.Context, r report.Report) error {
+	if c.Logger == nil {
+		return errors.New("logger must not be nil")
+	}
+	severity := log.SeverityInfo
+	hs, hasSuccess := r.(report.HasSuccess)
+	if hasSuccess && !hs.IsSuccess() {
+		severity = log.SeverityWarn
+	}
+	var params log.EnabledParameters
+	params.SetSeverity(severity)
+	if !c.Logger.Enabled(ctx, params) {
+		return nil
+	}
+
+	body, err := reportValue(r)
+	if err != nil {
+		return &report.BadRequestError{Err: err}
+	}
+	var record log.Record
+	now := time.Now()
+	record.SetTimestamp(now)
+	record.SetObservedTimestamp(now)
+	record.SetSeverity(severity)
+	record.SetSeverityText(severity.String())
+	record.SetBody(body)
+	if hasSuccess {
+		record.AddAttributes(log.Bool("report.success", hs.IsSuccess()))
+	}
+	c.Logger.Emit(ctx, record)
+	return nil
+}
+
+// reportValue converts the report to a [log.Value] through its JSON representation.
+func reportValue(r report.Report) (log.Value, error) {
+	jsonData, err := json.Marshal(r)
+	if err != nil {
+		return log.Value{}, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return log.Value{}, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return jsonValue(data), nil
+}
+
+// jsonValue converts a value decoded from JSON with [json.Decoder.UseNumber] to a [log.Value].
+func jsonValue(data any) log.Value {
+	switch data := data.(type) {
+	case map[string]any:
+		// Sort the keys, so the records are deterministic.
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		kvs := make([]log.KeyValue, 0, len(keys))
+		for _, key := range keys {
+			kvs = append(kvs, log.KeyValue{Key: key, Value: jsonValue(data[key])})
+		}
+		return log.MapValue(kvs...)
+	case []any:
+		values := make([]log.Value, 0, len(data))
+		for _, item := range data {
+			values = append(values, jsonValue(item))
+		}
+		return log.SliceValue(values...)
+	case json.Number:
+		if i, err := data.Int64(); err == nil {
+			return log.Int64Value(i)
+		}
+		f, _ := data.Float64()
+		return log.Float64Value(f)
+	case string:
+		return log.StringValue(data)
+	case bool:
+		return log.BoolValue(data)
+	default:
+		// JSON null.
+		return log.Value{}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelreport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/x/report"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/logtest"
+)
+
+type testReport struct {
+	Resolver   string   `json:"resolver"`
+	DurationMs int64    `json:"durationMs"`
+	Ratio      float64  `json:"ratio"`
+	Error      *string  `json:"error"`
+	Tags       []string `json:"tags"`
+}
+
+func (r testReport) IsSuccess() bool {
+	return r.Error == nil
+}
+
+// emittedRecords returns the records emitted to the recorder.
+func emittedRecords(recorder *logtest.Recorder) []logtest.EmittedRecord {
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+	return records
+}
+
+func TestCollector(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	collector := &Collector{Logger: recorder.Logger("test")}
+	err := collector.Collect(context.Background(), testReport{
+		Resolver:   "8.8.8.8:53",
+		DurationMs: 12,
+		Ratio:      0.5,
+		Tags:       []string{"a", "b"},
+	})
+	require.NoError(t, err)
+
+	records := emittedRecords(recorder)
+	require.Len(t, records, 1)
+	record := records[0]
+	require.Equal(t, log.SeverityInfo, record.Severity())
+	require.False(t, record.Timestamp().IsZero())
+	expectedBody := log.MapValue(
+		log.Int64("durationMs", 12),
+		log.Empty("error"),
+		log.Float64("ratio", 0.5),
+		log.String("resolver", "8.8.8.8:53"),
+		log.Slice("tags", log.StringValue("a"), log.StringValue("b")),
+	)
+	require.True(t, expectedBody.Equal(record.Body()), "body: %v", record.Body())
+	var attrs []log.KeyValue
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+	require.Equal(t, []log.KeyValue{log.Bool("report.success", true)}, attrs)
+}
+
+func TestCollector_Failure(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	collector := &Collector{Logger: recorder.Logger("test")}
+	errMsg := "connection refused"
+	require.NoError(t, collector.Collect(context.Background(), testReport{Error: &errMsg}))
+
+	records := emittedRecords(recorder)
+	require.Len(t, records, 1)
+	require.Equal(t, log.SeverityWarn, records[0].Severity())
+}
+
+func TestCollector_NotHasSuccess(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	collector := &Collector{Logger: recorder.Logger("test")}
+	require.NoError(t, collector.Collect(context.Background(), map[string]any{"key": "value"}))
+
+	records := emittedRecords(recorder)
+	require.Len(t, records, 1)
+	require.Equal(t, log.SeverityInfo, records[0].Severity())
+	require.Equal(t, 0, records[0].AttributesLen())
+}
+
+func TestCollector_Disabled(t *testing.T) {
+	recorder := logtest.NewRecorder(logtest.WithEnabledFunc(func(context.Context, log.EnabledParameters) bool {
+		return false
+	}))
+	collector := &Collector{Logger: recorder.Logger("test")}
+	require.NoError(t, collector.Collect(context.Background(), testReport{}))
+	require.Empty(t, emittedRecords(recorder))
+}
+
+func TestCollector_BadReport(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	collector := &Collector{Logger: recorder.Logger("test")}
+	err := collector.Collect(context.Background(), make(chan int))
+	var badRequestErr *report.BadRequestError
+	require.ErrorAs(t, err, &badRequestErr)
+	require.Empty(t, emittedRecords(recorder))
+}
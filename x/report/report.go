@@ -4,6 +4,8 @@
 // It also defines a report type and a [HasSuccess] interface that is implemented by the report type.
 // The report type is used to represent a connectivity test report.
 // The [HasSuccess] interface is used to determine the success status of a report. This will be used to control [SamplingCollector] behavior.
+// Use [NewEnvelope] to wrap a report with metadata about the environment, like the OS and the package [Version].
+// Use [BatchingCollector] to send many reports in fewer requests.
 // The report package also defines a [BadRequestError] type that is used to represent an error that occurs when a sending the report to remote collector fails.
 package report
 
@@ -18,6 +20,8 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -50,10 +54,61 @@ type Collector interface {
 	Collect(context.Context, Report) error
 }
 
+// RateLimitError is returned by [RemoteCollector] when the server responds with HTTP 429 (Too Many Requests)
+// or 503 (Service Unavailable). The [RetryCollector] waits for RetryAfter before retrying.
+type RateLimitError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by the server in the Retry-After header, or zero if absent or invalid.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("http request failed with status code %d, retry after %v", e.StatusCode, e.RetryAfter)
+}
+
+// parseRetryAfter parses the value of the Retry-After header, which can be either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 // RemoteCollector represents a collector that communicates with a remote endpoint.
 type RemoteCollector struct {
-	HttpClient   *http.Client
+	HttpClient *http.Client
+	// Headers are added to each request, for example to authenticate with the collector endpoint.
+	Headers http.Header
+	// CollectorURL is the endpoint reports are sent to.
+	// Use [RemoteCollector.SetURL] to change it while the collector is in use.
 	CollectorURL *url.URL
+	mu           sync.RWMutex
+}
+
+// SetURL changes the endpoint that reports are sent to.
+// It's safe to call concurrently with [RemoteCollector.Collect]. Each Collect call reads the URL once when it
+// starts, so requests already in flight finish against the old URL, and calls that start after SetURL returns
+// use the new URL.
+func (c *RemoteCollector) SetURL(collectorURL *url.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.CollectorURL = collectorURL
+}
+
+// collectorURL returns the current endpoint.
+func (c *RemoteCollector) collectorURL() *url.URL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.CollectorURL
 }
 
 // Collect sends the given report to the remote collector.
@@ -109,18 +164,38 @@ func (c *SamplingCollector) Collect(ctx context.Context, report Report) error {
 	}
 }
 
+// defaultRetryMaxDelay is the cap of the delay requested by a [RateLimitError] if [RetryCollector].MaxDelay is zero,
+// so a misbehaving server can't stall the collector for hours.
+const defaultRetryMaxDelay = 5 * time.Minute
+
 // RetryCollector represents a collector that supports retrying failed operations.
 type RetryCollector struct {
 	Collector    Collector
 	MaxRetry     int
 	InitialDelay time.Duration
+	// MaxDelay caps the delay requested by a [RateLimitError]. If zero, the requested delay is capped at 5 minutes.
+	MaxDelay time.Duration
+}
+
+// rateLimitDelay returns the delay to wait before retrying after a [RateLimitError] with the given RetryAfter.
+func (c *RetryCollector) rateLimitDelay(retryAfter time.Duration) time.Duration {
+	maxDelay := c.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	delay := retryAfter + time.Duration(rand.Int63n(int64(retryAfter/10)+1))
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
 }
 
 // Collect collects the report by making multiple attempts with retries.
 // It uses the provided context and report to call the underlying collector's [Collect] method.
 // If a [BadRequestError] is encountered during the collection, it breaks the retry loop.
-// It sleeps for a specified duration between retries.
-// Returns an error if the maximum number of retries is exceeded.
+// It sleeps for a specified duration between retries. On a [RateLimitError] with a RetryAfter, it sleeps for
+// the requested delay plus up to 10% of random jitter, capped by MaxDelay, so clients don't retry in lockstep.
+// Returns an error if the maximum number of retries is exceeded, or the context error if the context is done.
 func (c *RetryCollector) Collect(ctx context.Context, report Report) error {
 	var e *BadRequestError
 	for i := 0; i < c.MaxRetry+1; i++ {
@@ -128,8 +203,18 @@ func (c *RetryCollector) Collect(ctx context.Context, report Report) error {
 		if err != nil {
 			if errors.As(err, &e) {
 				break
-			} else {
-				time.Sleep(time.Duration(math.Pow(2, float64(i))) * c.InitialDelay)
+			}
+			delay := time.Duration(math.Pow(2, float64(i))) * c.InitialDelay
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+				delay = c.rateLimitDelay(rateLimitErr.RetryAfter)
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
 			}
 		} else {
 			return nil
@@ -163,11 +248,16 @@ func (c *FallbackCollector) Collect(ctx context.Context, report Report) error {
 // It returns an error if there was a problem sending the report or reading the response.
 func (c *RemoteCollector) sendReport(ctx context.Context, jsonData []byte) error {
 	// TODO: return status code of HTTP response
-	req, err := http.NewRequest("POST", c.CollectorURL.String(), bytes.NewReader(jsonData))
+	req, err := http.NewRequest("POST", c.collectorURL().String(), bytes.NewReader(jsonData))
 	if err != nil {
 		return err
 	}
 
+	for key, values := range c.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	resp, err := c.HttpClient.Do(req.WithContext(ctx))
 	if err != nil {
@@ -178,6 +268,12 @@ func (c *RemoteCollector) sendReport(ctx context.Context, jsonData []byte) error
 	if err != nil {
 		return err
 	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return &RateLimitError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
 	if 400 <= resp.StatusCode && resp.StatusCode < 500 {
 		return &BadRequestError{
 			Err: fmt.Errorf("http request failed with status code %d", resp.StatusCode),
@@ -204,3 +300,97 @@ func (c *WriteCollector) Collect(ctx context.Context, report Report) error {
 	}
 	return nil
 }
+
+// BatchingCollector is a collector that buffers reports and sends them in batches to the underlying collector.
+// Each batch is collected as a single []Report, so a [RemoteCollector] sends it as a JSON array in one request.
+// Call [BatchingCollector.Close] on shutdown to send the buffered reports.
+type BatchingCollector struct {
+	Collector Collector
+	// MaxBatch is the number of buffered reports that triggers sending the batch.
+	// If zero, batches are only sent by the FlushInterval, Flush or Close.
+	MaxBatch int
+	// FlushInterval is the maximum time a report stays buffered before the batch is sent.
+	// If zero, batches are only sent when full, or by Flush or Close.
+	FlushInterval time.Duration
+
+	mu     sync.Mutex
+	batch  []Report
+	timer  *time.Timer
+	closed bool
+}
+
+// Collect adds the report to the batch. If the batch reaches MaxBatch reports, it sends the batch using the
+// given context, and returns the error of the underlying collector.
+func (c *BatchingCollector) Collect(ctx context.Context, report Report) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errors.New("collector is closed")
+	}
+	c.batch = append(c.batch, report)
+	if c.MaxBatch > 0 && len(c.batch) >= c.MaxBatch {
+		batch := c.takeBatchLocked()
+		c.mu.Unlock()
+		return c.sendBatch(ctx, batch)
+	}
+	c.scheduleFlushLocked()
+	c.mu.Unlock()
+	return nil
+}
+
+// Flush sends the buffered reports now.
+// If sending fails, the reports stay buffered, so they can be sent by a later flush.
+func (c *BatchingCollector) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	batch := c.takeBatchLocked()
+	c.mu.Unlock()
+	return c.sendBatch(ctx, batch)
+}
+
+// Close stops accepting reports and sends the buffered reports, using the given context.
+// If sending fails, the reports stay buffered, and you can retry with [BatchingCollector.Flush].
+func (c *BatchingCollector) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	batch := c.takeBatchLocked()
+	c.mu.Unlock()
+	return c.sendBatch(ctx, batch)
+}
+
+// scheduleFlushLocked starts the timer to flush the batch, if needed. It must be called with the lock held.
+func (c *BatchingCollector) scheduleFlushLocked() {
+	if c.FlushInterval <= 0 || c.timer != nil || c.closed || len(c.batch) == 0 {
+		return
+	}
+	c.timer = time.AfterFunc(c.FlushInterval, func() {
+		c.Flush(context.Background())
+	})
+}
+
+// takeBatchLocked removes the buffered reports and returns them. It must be called with the lock held.
+func (c *BatchingCollector) takeBatchLocked() []Report {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	batch := c.batch
+	c.batch = nil
+	return batch
+}
+
+// sendBatch collects the batch with the underlying collector. On failure, the batch is buffered again,
+// ahead of any reports collected since.
+func (c *BatchingCollector) sendBatch(ctx context.Context, batch []Report) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	err := c.Collector.Collect(ctx, batch)
+	if err != nil {
+		c.mu.Lock()
+		c.batch = append(batch, c.batch...)
+		c.scheduleFlushLocked()
+		c.mu.Unlock()
+		return fmt.Errorf("failed to send batch of %v reports: %w", len(batch), err)
+	}
+	return nil
+}
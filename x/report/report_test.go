@@ -17,12 +17,14 @@ package report
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -120,6 +122,38 @@ func TestSendReportSuccessfully(t *testing.T) {
 	require.Equal(t, string(expected), string(requestBody))
 }
 
+func TestRemoteCollectorSetURL(t *testing.T) {
+	newServer := func(hits *int) (*httptest.Server, *url.URL) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*hits++
+			fmt.Fprintln(w, "OK")
+		}))
+		u, err := url.Parse(ts.URL)
+		require.NoError(t, err)
+		return ts, u
+	}
+	var firstHits, secondHits int
+	first, firstURL := newServer(&firstHits)
+	defer first.Close()
+	second, secondURL := newServer(&secondHits)
+	defer second.Close()
+
+	c := RemoteCollector{
+		CollectorURL: firstURL,
+		HttpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+	var testReport = ConnectivityReport{Time: time.Now().UTC().Truncate(time.Second)}
+
+	require.NoError(t, c.Collect(context.Background(), testReport))
+	require.Equal(t, 1, firstHits)
+	require.Equal(t, 0, secondHits)
+
+	c.SetURL(secondURL)
+	require.NoError(t, c.Collect(context.Background(), testReport))
+	require.Equal(t, 1, firstHits)
+	require.Equal(t, 1, secondHits)
+}
+
 func TestSendReportUnsuccessfully(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(400)
@@ -293,6 +327,97 @@ func TestRetryCollector(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestRemoteCollectorHeaders(t *testing.T) {
+	var gotAuth, gotContentType string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotContentType = r.Header.Get("Content-Type")
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	c := RemoteCollector{
+		CollectorURL: u,
+		HttpClient:   ts.Client(),
+		Headers:      http.Header{"Authorization": []string{"Bearer key"}},
+	}
+	require.NoError(t, c.Collect(context.Background(), ConnectivitySetup{}))
+	require.Equal(t, "Bearer key", gotAuth)
+	require.Equal(t, "application/json; charset=utf-8", gotContentType)
+}
+
+func TestRetryCollectorRateLimit(t *testing.T) {
+	requestCount := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if requestCount == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	c := RetryCollector{
+		Collector:    &RemoteCollector{CollectorURL: u, HttpClient: ts.Client()},
+		MaxRetry:     1,
+		InitialDelay: time.Hour,
+		MaxDelay:     10 * time.Millisecond,
+	}
+	start := time.Now()
+	require.NoError(t, c.Collect(context.Background(), ConnectivitySetup{}))
+	require.Equal(t, 2, requestCount)
+	require.Less(t, time.Since(start), time.Minute)
+}
+
+func TestRetryCollectorRateLimitDelay(t *testing.T) {
+	c := RetryCollector{}
+	require.Equal(t, defaultRetryMaxDelay, c.rateLimitDelay(24*time.Hour))
+	delay := c.rateLimitDelay(10 * time.Second)
+	require.GreaterOrEqual(t, delay, 10*time.Second)
+	require.LessOrEqual(t, delay, 11*time.Second)
+
+	c.MaxDelay = time.Minute
+	require.Equal(t, time.Minute, c.rateLimitDelay(time.Hour))
+}
+
+func TestRemoteCollectorRateLimitError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	c := RemoteCollector{CollectorURL: u, HttpClient: ts.Client()}
+	err = c.Collect(context.Background(), ConnectivitySetup{})
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	require.Equal(t, http.StatusServiceUnavailable, rateLimitErr.StatusCode)
+	require.Equal(t, 7*time.Second, rateLimitErr.RetryAfter)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	require.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	require.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("", now))
+}
+
+func TestRetryCollectorContextCancelled(t *testing.T) {
+	failing := &recordingCollector{err: errors.New("unavailable")}
+	c := RetryCollector{Collector: failing, MaxRetry: 3, InitialDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, c.Collect(ctx, ConnectivitySetup{}), context.DeadlineExceeded)
+}
+
 func TestWriteCollector(t *testing.T) {
 	var testReport = ConnectivityReport{
 		Connection: nil,
@@ -338,3 +463,77 @@ func TestWriteCollectorToFile(t *testing.T) {
 		t.Errorf("Expected no error, but got: %v", err)
 	}
 }
+
+type recordingCollector struct {
+	mu      sync.Mutex
+	batches [][]Report
+	err     error
+}
+
+func (c *recordingCollector) Collect(ctx context.Context, report Report) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.batches = append(c.batches, report.([]Report))
+	return nil
+}
+
+func (c *recordingCollector) Batches() [][]Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]Report(nil), c.batches...)
+}
+
+func TestBatchingCollectorMaxBatch(t *testing.T) {
+	recorder := &recordingCollector{}
+	c := &BatchingCollector{Collector: recorder, MaxBatch: 2}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, c.Collect(context.Background(), i))
+	}
+	require.Equal(t, [][]Report{{0, 1}, {2, 3}}, recorder.Batches())
+
+	require.NoError(t, c.Close(context.Background()))
+	require.Equal(t, [][]Report{{0, 1}, {2, 3}, {4}}, recorder.Batches())
+	require.Error(t, c.Collect(context.Background(), 5))
+}
+
+func TestBatchingCollectorFlushInterval(t *testing.T) {
+	recorder := &recordingCollector{}
+	c := &BatchingCollector{Collector: recorder, FlushInterval: 10 * time.Millisecond}
+	require.NoError(t, c.Collect(context.Background(), "a"))
+	require.NoError(t, c.Collect(context.Background(), "b"))
+	require.Eventually(t, func() bool { return len(recorder.Batches()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, [][]Report{{"a", "b"}}, recorder.Batches())
+}
+
+func TestBatchingCollectorFailureKeepsReports(t *testing.T) {
+	recorder := &recordingCollector{err: errors.New("unavailable")}
+	c := &BatchingCollector{Collector: recorder, MaxBatch: 2}
+	require.NoError(t, c.Collect(context.Background(), 1))
+	require.Error(t, c.Collect(context.Background(), 2))
+	require.Error(t, c.Close(context.Background()))
+
+	recorder.mu.Lock()
+	recorder.err = nil
+	recorder.mu.Unlock()
+	require.NoError(t, c.Flush(context.Background()))
+	require.Equal(t, [][]Report{{1, 2}}, recorder.Batches())
+}
+
+func TestBatchingCollectorSendsJSONArray(t *testing.T) {
+	var received []ConnectivitySetup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	c := &BatchingCollector{Collector: &RemoteCollector{HttpClient: server.Client(), CollectorURL: u}, MaxBatch: 10}
+	require.NoError(t, c.Collect(context.Background(), ConnectivitySetup{Proto: "tcp"}))
+	require.NoError(t, c.Collect(context.Background(), ConnectivitySetup{Proto: "udp"}))
+	require.NoError(t, c.Close(context.Background()))
+	require.Equal(t, []ConnectivitySetup{{Proto: "tcp"}, {Proto: "udp"}}, received)
+}
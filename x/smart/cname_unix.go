@@ -40,7 +40,9 @@ func lookupCNAME(ctx context.Context, domain string) (string, error) {
 		err   error
 	}
 
-	results := make(chan result)
+	// getaddrinfo cannot be cancelled. The channel is buffered so the goroutine can exit
+	// when the lookup finishes, even if we stopped waiting for it.
+	results := make(chan result, 1)
 	go func() {
 		cname, err := lookupCNAMEBlocking(domain)
 		results <- result{cname, err}
@@ -0,0 +1,141 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// log emits an event to the Logger and the LogWriter, unless ctx is done, so the tests cancelled by the search
+// are not logged.
+func (f *StrategyFinder) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if ctx.Err() != nil {
+		return
+	}
+	f.logger().Log(ctx, level, msg, args...)
+}
+
+// logger returns the logger for the search events, which sends them to the Logger and, as text, to the LogWriter.
+func (f *StrategyFinder) logger() *slog.Logger {
+	f.loggerOnce.Do(func() {
+		var handlers multiHandler
+		if f.Logger != nil {
+			handlers = append(handlers, f.Logger.Handler())
+		}
+		if f.LogWriter != nil {
+			handlers = append(handlers, slog.NewTextHandler(f.LogWriter, &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					// The time makes the log harder to read, and the writer can add it if needed.
+					if len(groups) == 0 && a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+		}
+		f.searchLogger = slog.New(handlers)
+	})
+	return f.searchLogger
+}
+
+// multiHandler is a [slog.Handler] that sends the records to all its handlers.
+type multiHandler []slog.Handler
+
+var _ slog.Handler = (multiHandler)(nil)
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// newLoggingResolver wraps resolver to log the queries to logger.
+func newLoggingResolver(resolver dns.Resolver, logger *slog.Logger) dns.Resolver {
+	return dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		start := time.Now()
+		response, err := resolver.Query(ctx, q)
+		if err != nil {
+			logger.DebugContext(ctx, "DNS query failed", "name", q.Name.String(), "type", q.Type, "duration", time.Since(start), "error", err)
+			return nil, err
+		}
+		logger.DebugContext(ctx, "DNS query done", "name", q.Name.String(), "type", q.Type, "duration", time.Since(start),
+			"rcode", response.RCode, "answers", len(response.Answers))
+		return response, nil
+	})
+}
+
+// loggingStreamDialer is a [transport.StreamDialer] that logs the dials to logger, with msg as the message prefix.
+type loggingStreamDialer struct {
+	dialer transport.StreamDialer
+	logger *slog.Logger
+	msg    string
+}
+
+var _ transport.StreamDialer = (*loggingStreamDialer)(nil)
+var _ transport.ConnectFailureReporter = (*loggingStreamDialer)(nil)
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *loggingStreamDialer) DialStream(ctx context.Context, addr string) (transport.StreamConn, error) {
+	start := time.Now()
+	conn, err := d.dialer.DialStream(ctx, addr)
+	if err != nil {
+		d.logger.DebugContext(ctx, d.msg+" failed", "address", addr, "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	d.logger.DebugContext(ctx, d.msg+" succeeded", "address", addr, "duration", time.Since(start))
+	return conn, nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *loggingStreamDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
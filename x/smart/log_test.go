@@ -0,0 +1,106 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestStrategyFinderLog(t *testing.T) {
+	var text, structured bytes.Buffer
+	finder := &StrategyFinder{
+		LogWriter: &text,
+		Logger:    slog.New(slog.NewJSONHandler(&structured, &slog.HandlerOptions{Level: slog.LevelInfo})),
+	}
+
+	finder.log(context.Background(), slog.LevelDebug, "Strategy test started", "kind", "tls")
+	finder.log(context.Background(), slog.LevelInfo, "Selected TLS strategy", "strategy", "split:2")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	finder.log(ctx, slog.LevelInfo, "Cancelled")
+
+	require.Equal(t, "level=DEBUG msg=\"Strategy test started\" kind=tls\n"+
+		"level=INFO msg=\"Selected TLS strategy\" strategy=split:2\n", text.String())
+	// The Logger only gets the events enabled for its level.
+	require.NotContains(t, structured.String(), "Strategy test started")
+	require.Contains(t, structured.String(), `"msg":"Selected TLS strategy","strategy":"split:2"`)
+	require.NotContains(t, structured.String(), "Cancelled")
+}
+
+func TestStrategyFinderLog_NoOutput(t *testing.T) {
+	finder := &StrategyFinder{}
+	require.False(t, finder.logger().Enabled(context.Background(), slog.LevelError))
+	finder.log(context.Background(), slog.LevelInfo, "Selected TLS strategy")
+}
+
+func TestLoggingResolver(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	resolver := newLoggingResolver(dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		if q.Type == dnsmessage.TypeAAAA {
+			return nil, errors.New("not implemented")
+		}
+		return &dnsmessage.Message{}, nil
+	}), logger)
+
+	q, err := dns.NewQuestion("example.com", dnsmessage.TypeA)
+	require.NoError(t, err)
+	_, err = resolver.Query(context.Background(), *q)
+	require.NoError(t, err)
+	q, err = dns.NewQuestion("example.com", dnsmessage.TypeAAAA)
+	require.NoError(t, err)
+	_, err = resolver.Query(context.Background(), *q)
+	require.Error(t, err)
+
+	require.Contains(t, logs.String(), `msg="DNS query done" name=example.com. type=TypeA`)
+	require.Contains(t, logs.String(), `msg="DNS query failed" name=example.com. type=TypeAAAA`)
+}
+
+func TestLoggingStreamDialer(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	dialer := &loggingStreamDialer{
+		dialer: transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			return nil, errors.New("not implemented")
+		}),
+		logger: logger,
+		msg:    "Connection attempt",
+	}
+
+	_, err := dialer.DialStream(context.Background(), "127.0.0.1:8080")
+	require.Error(t, err)
+	require.Contains(t, logs.String(), `msg="Connection attempt failed" address=127.0.0.1:8080`)
+	require.True(t, transport.ReportsConnectFailure(dialer))
+	// The logging must not hide that the base dialer doesn't report connection failures.
+	dialer.dialer = unreportedFailureDialer{dialer.dialer}
+	require.False(t, transport.ReportsConnectFailure(dialer))
+}
+
+// unreportedFailureDialer is a [transport.StreamDialer] that declares its dial errors don't reflect the reachability
+// of the destination, like Shadowsocks.
+type unreportedFailureDialer struct {
+	transport.StreamDialer
+}
+
+func (d unreportedFailureDialer) ReportsConnectFailure() bool { return false }
@@ -0,0 +1,142 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/quic"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newResolvingPacketDialer creates a [transport.PacketDialer] that uses the resolver to map host names to IP addresses.
+// It tries the IPv4 addresses first, and the IPv6 addresses if the IPv4 query fails or has no addresses.
+func newResolvingPacketDialer(resolver dns.Resolver, dialer transport.PacketDialer) transport.PacketDialer {
+	return transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("address is not valid host:port: %w", err)
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialPacket(ctx, addr)
+		}
+		var ips []net.IP
+		var resolveErr error
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			q, err := dns.NewQuestion(makeFullyQualified(host), qtype)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create question: %w", err)
+			}
+			response, err := resolver.Query(ctx, *q)
+			if err != nil {
+				resolveErr = errors.Join(resolveErr, err)
+				continue
+			}
+			if ips = getIPs(response.Answers); len(ips) > 0 {
+				break
+			}
+		}
+		if len(ips) == 0 {
+			if resolveErr != nil {
+				return nil, fmt.Errorf("failed to resolve %v: %w", host, resolveErr)
+			}
+			return nil, fmt.Errorf("no IP address found for %v", host)
+		}
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialPacket(ctx, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = errors.Join(dialErr, err)
+		}
+		return nil, dialErr
+	})
+}
+
+// findQUIC returns a packet dialer with the QUIC strategy that works for all the testDomains, and the config of that strategy.
+func (f *StrategyFinder) findQUIC(ctx context.Context, testDomains []string, baseDialer transport.PacketDialer, quicConfig []string) (transport.PacketDialer, string, error) {
+	if len(quicConfig) == 0 {
+		return nil, "", errors.New("config for QUIC is empty. Please specify at least one transport")
+	}
+	var configModule = f.newConfigProviders()
+	configModule.PacketDialers.BaseInstance = baseDialer
+
+	raceStart := time.Now()
+	type SearchResult struct {
+		Dialer transport.PacketDialer
+		Config string
+	}
+	result, err := raceTests(ctx, 250*time.Millisecond, f.MaxConcurrency, quicConfig, func(ctx context.Context, transportCfg string) (*SearchResult, error) {
+		quicDialer, err := configModule.NewPacketDialer(ctx, transportCfg)
+		if err != nil {
+			return nil, fmt.Errorf("NewPacketDialer failed: %w", err)
+		}
+		// The QUIC stream dialer establishes a new QUIC connection for each stream, so it tests the QUIC handshake.
+		testDialer, err := quic.NewStreamDialer(quicDialer)
+		if err != nil {
+			return nil, err
+		}
+		for _, testDomain := range testDomains {
+			startTime := time.Now()
+
+			testAddr := net.JoinHostPort(testDomain, "443")
+			f.log(ctx, slog.LevelDebug, "Strategy test started", "kind", "quic", "strategy", transportCfg, "domain", testDomain)
+
+			testCtx, cancel := context.WithTimeout(ctx, f.TestTimeout)
+			testConn, err := testDialer.DialStream(testCtx, testAddr)
+			cancel()
+			if err != nil {
+				f.reportCtx(ctx, StrategyTestResult{Kind: "quic", Strategy: transportCfg, Domain: testDomain, Duration: time.Since(startTime), Error: err})
+				return nil, err
+			}
+			testConn.Close()
+			f.reportCtx(ctx, StrategyTestResult{Kind: "quic", Strategy: transportCfg, Domain: testDomain, Duration: time.Since(startTime)})
+		}
+		return &SearchResult{quicDialer, transportCfg}, nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("could not find QUIC strategy: %w", err)
+	}
+	f.log(ctx, slog.LevelInfo, "Selected QUIC strategy", "strategy", result.Config, "duration", time.Since(raceStart))
+	return newQUICPortDialer(baseDialer, result.Dialer), result.Config, nil
+}
+
+// newQUICPortDialer creates a [transport.PacketDialer] that uses quicDialer for port 443, where QUIC runs, and
+// baseDialer for the other ports.
+func newQUICPortDialer(baseDialer, quicDialer transport.PacketDialer) transport.PacketDialer {
+	return transport.FuncPacketDialer(func(ctx context.Context, raddr string) (net.Conn, error) {
+		_, portStr, err := net.SplitHostPort(raddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse address: %w", err)
+		}
+		portNum, err := net.DefaultResolver.LookupPort(ctx, "udp", portStr)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve port: %w", err)
+		}
+		selectedDialer := baseDialer
+		if portNum == 443 {
+			selectedDialer = quicDialer
+		}
+		return selectedDialer.DialPacket(ctx, raddr)
+	})
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/dns"
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	quicgo "github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestResolvingPacketDialer_IPv4(t *testing.T) {
+	resolver := newFakeResolver(map[dnsmessage.Type]fakeAnswer{
+		dnsmessage.TypeA:    {ip: net.IPv4(192, 0, 2, 1)},
+		dnsmessage.TypeAAAA: {ip: net.ParseIP("2001:db8::1")},
+	})
+	dialer := &recordingPacketDialer{}
+	_, err := newResolvingPacketDialer(resolver, dialer).DialPacket(context.Background(), "www.example.com:443")
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.1:443"}, dialer.addrs)
+}
+
+func TestResolvingPacketDialer_IPv6IfNoIPv4(t *testing.T) {
+	resolver := newFakeResolver(map[dnsmessage.Type]fakeAnswer{
+		dnsmessage.TypeA:    {},
+		dnsmessage.TypeAAAA: {ip: net.ParseIP("2001:db8::1")},
+	})
+	dialer := &recordingPacketDialer{}
+	_, err := newResolvingPacketDialer(resolver, dialer).DialPacket(context.Background(), "www.example.com:443")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[2001:db8::1]:443"}, dialer.addrs)
+}
+
+func TestResolvingPacketDialer_IPv6IfIPv4Fails(t *testing.T) {
+	resolver := newFakeResolver(map[dnsmessage.Type]fakeAnswer{
+		dnsmessage.TypeA:    {err: errors.New("A query failed")},
+		dnsmessage.TypeAAAA: {ip: net.ParseIP("2001:db8::1")},
+	})
+	dialer := &recordingPacketDialer{}
+	_, err := newResolvingPacketDialer(resolver, dialer).DialPacket(context.Background(), "www.example.com:443")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[2001:db8::1]:443"}, dialer.addrs)
+}
+
+func TestResolvingPacketDialer_AllQueriesFail(t *testing.T) {
+	errA := errors.New("A query failed")
+	errAAAA := errors.New("AAAA query failed")
+	resolver := newFakeResolver(map[dnsmessage.Type]fakeAnswer{
+		dnsmessage.TypeA:    {err: errA},
+		dnsmessage.TypeAAAA: {err: errAAAA},
+	})
+	dialer := &recordingPacketDialer{}
+	_, err := newResolvingPacketDialer(resolver, dialer).DialPacket(context.Background(), "www.example.com:443")
+	require.ErrorIs(t, err, errA)
+	require.ErrorIs(t, err, errAAAA)
+	require.Empty(t, dialer.addrs)
+}
+
+func TestResolvingPacketDialer_NoAddresses(t *testing.T) {
+	resolver := newFakeResolver(map[dnsmessage.Type]fakeAnswer{})
+	dialer := &recordingPacketDialer{}
+	_, err := newResolvingPacketDialer(resolver, dialer).DialPacket(context.Background(), "www.example.com:443")
+	require.ErrorContains(t, err, "no IP address found")
+	require.Empty(t, dialer.addrs)
+}
+
+func TestResolvingPacketDialer_IPAddress(t *testing.T) {
+	resolver := dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		return nil, errors.New("unexpected query")
+	})
+	dialer := &recordingPacketDialer{}
+	_, err := newResolvingPacketDialer(resolver, dialer).DialPacket(context.Background(), "192.0.2.1:443")
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.1:443"}, dialer.addrs)
+}
+
+func TestQUICPortDialer(t *testing.T) {
+	baseDialer := &recordingPacketDialer{}
+	quicDialer := &recordingPacketDialer{}
+	dialer := newQUICPortDialer(baseDialer, quicDialer)
+
+	_, err := dialer.DialPacket(context.Background(), "www.example.com:443")
+	require.NoError(t, err)
+	_, err = dialer.DialPacket(context.Background(), "www.example.com:53")
+	require.NoError(t, err)
+	_, err = dialer.DialPacket(context.Background(), "www.example.com:https")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"www.example.com:443", "www.example.com:https"}, quicDialer.addrs)
+	require.Equal(t, []string{"www.example.com:53"}, baseDialer.addrs)
+}
+
+func TestFindQUIC_EmptyConfig(t *testing.T) {
+	finder := &StrategyFinder{TestTimeout: time.Second}
+	_, _, err := finder.findQUIC(context.Background(), []string{"www.example.com"}, &transport.UDPDialer{}, nil)
+	require.Error(t, err)
+}
+
+func TestFindQUIC_NoStrategyWorks(t *testing.T) {
+	// The server certificate is self-signed, so the QUIC handshake fails for every strategy.
+	listener, err := quicgo.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{newSelfSignedCert(t, "www.example.com")},
+		NextProtos:   []string{"h3"},
+	}, nil)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			if _, err := listener.Accept(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+	// Send all the packets to the local server, regardless of the destination.
+	baseDialer := transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return (&transport.UDPDialer{}).DialPacket(ctx, listener.Addr().String())
+	})
+
+	var mu sync.Mutex
+	var results []StrategyTestResult
+	finder := &StrategyFinder{
+		TestTimeout: 5 * time.Second,
+		OnTestResult: func(result StrategyTestResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, result)
+		},
+	}
+	_, _, err = finder.findQUIC(context.Background(), []string{"www.example.com"}, baseDialer, []string{"", "override:port=443"})
+	require.ErrorContains(t, err, "could not find QUIC strategy")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, results, 2)
+	strategies := make([]string, 0, len(results))
+	for _, result := range results {
+		require.Equal(t, "quic", result.Kind)
+		require.Equal(t, "www.example.com", result.Domain)
+		require.Error(t, result.Error)
+		strategies = append(strategies, result.Strategy)
+	}
+	require.ElementsMatch(t, []string{"", "override:port=443"}, strategies)
+}
+
+// Private test helpers
+
+type fakeAnswer struct {
+	ip  net.IP
+	err error
+}
+
+// newFakeResolver creates a [dns.Resolver] that answers the queries with the answers for their types.
+// Types without an answer get an empty response.
+func newFakeResolver(answers map[dnsmessage.Type]fakeAnswer) dns.Resolver {
+	return dns.FuncResolver(func(ctx context.Context, q dnsmessage.Question) (*dnsmessage.Message, error) {
+		answer := answers[q.Type]
+		if answer.err != nil {
+			return nil, answer.err
+		}
+		response := &dnsmessage.Message{
+			Header:    dnsmessage.Header{Response: true},
+			Questions: []dnsmessage.Question{q},
+		}
+		if answer.ip == nil {
+			return response, nil
+		}
+		resource := dnsmessage.Resource{Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class}}
+		if ip4 := answer.ip.To4(); ip4 != nil {
+			resource.Body = &dnsmessage.AResource{A: [4]byte(ip4)}
+		} else {
+			resource.Body = &dnsmessage.AAAAResource{AAAA: [16]byte(answer.ip.To16())}
+		}
+		response.Answers = []dnsmessage.Resource{resource}
+		return response, nil
+	})
+}
+
+// recordingPacketDialer is a [transport.PacketDialer] that records the dialed addresses and returns a pipe.
+type recordingPacketDialer struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (d *recordingPacketDialer) DialPacket(ctx context.Context, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addrs = append(d.addrs, addr)
+	conn, _ := net.Pipe()
+	return conn, nil
+}
+
+func newSelfSignedCert(t *testing.T, names ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
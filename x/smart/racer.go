@@ -17,6 +17,7 @@ package smart
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 )
 
@@ -30,30 +31,52 @@ func newClosedChanel() <-chan struct{} {
 // raceTests will call the test function on each entry until it finds an entry for which the test returns nil error.
 // That entry is returned. A test is only started after the previous test finished or maxWait is done, whichever
 // happens first. That way you bound the wait for a test, and they may overlap.
-// The test function should make use of the context to stop doing work when the race is done and it is no longer needed.
-func raceTests[E any, R any](ctx context.Context, maxWait time.Duration, entries []E, test func(entry E) (R, error)) (R, error) {
+// If maxConcurrency is positive, at most maxConcurrency tests run at the same time, and a new test only starts
+// once a running one finishes.
+// The test function gets a context that is cancelled when the race is done, and must use it to stop doing work and
+// release its resources. raceTests only returns after all the tests it started have returned, so no test outlives the race.
+// If ctx is done before a test succeeds, it returns ctx.Err().
+func raceTests[E any, R any](ctx context.Context, maxWait time.Duration, maxConcurrency int, entries []E, test func(ctx context.Context, entry E) (R, error)) (R, error) {
 	type testResult struct {
 		Result R
 		Err    error
 	}
-	// Communicates the result of each test.
+	raceCtx, raceDone := context.WithCancel(ctx)
+	var running sync.WaitGroup
+	defer func() {
+		// Stop the tests still running and wait for them, so they don't leak.
+		raceDone()
+		running.Wait()
+	}()
+	// Communicates the result of each test. It has room for all the results, so tests never block on send.
 	resultChan := make(chan testResult, len(entries))
 	waitCh := newClosedChanel()
 
+	var empty R
 	next := 0
+	numRunning := 0
 	for toTest := len(entries); toTest > 0; {
+		// Prioritize the cancellation over starting new tests or processing results.
+		if err := ctx.Err(); err != nil {
+			return empty, err
+		}
+		// Don't start new tests while at the concurrency limit.
+		startCh := waitCh
+		if maxConcurrency > 0 && numRunning >= maxConcurrency {
+			startCh = nil
+		}
 		select {
 		// Search cancelled, quit.
 		case <-ctx.Done():
-			var empty R
 			return empty, ctx.Err()
 
 		// Ready to start testing another resolver.
-		case <-waitCh:
+		case <-startCh:
 			entry := entries[next]
 			next++
+			numRunning++
 
-			waitCtx, waitDone := context.WithTimeout(ctx, maxWait)
+			waitCtx, waitDone := context.WithTimeout(raceCtx, maxWait)
 			if next == len(entries) {
 				// Done with entries. No longer trigger on waitCh.
 				waitCh = nil
@@ -61,21 +84,27 @@ func raceTests[E any, R any](ctx context.Context, maxWait time.Duration, entries
 				waitCh = waitCtx.Done()
 			}
 
+			running.Add(1)
 			go func(entry E, testDone context.CancelFunc) {
+				defer running.Done()
 				defer testDone()
-				result, err := test(entry)
+				result, err := test(raceCtx, entry)
 				resultChan <- testResult{Result: result, Err: err}
 			}(entry, waitDone)
 
 		// Got a test result.
 		case result := <-resultChan:
 			toTest--
+			numRunning--
 			if result.Err != nil {
 				continue
 			}
 			return result.Result, nil
 		}
 	}
-	var empty R
+	// The tests may have failed because of the cancellation.
+	if err := ctx.Err(); err != nil {
+		return empty, err
+	}
 	return empty, errors.New("all tests failed")
 }
@@ -0,0 +1,123 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceTests_FirstSuccess(t *testing.T) {
+	var running atomic.Int32
+	result, err := raceTests(context.Background(), 10*time.Millisecond, 0, []int{1, 2, 3}, func(ctx context.Context, entry int) (int, error) {
+		running.Add(1)
+		defer running.Add(-1)
+		if entry != 2 {
+			// Only finish when the race is done.
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return entry, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, result)
+	require.Equal(t, int32(0), running.Load())
+}
+
+func TestRaceTests_AllFail(t *testing.T) {
+	_, err := raceTests(context.Background(), 10*time.Millisecond, 0, []int{1, 2, 3}, func(ctx context.Context, entry int) (int, error) {
+		return 0, errors.New("failed")
+	})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, context.Canceled)
+}
+
+func TestRaceTests_MaxConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	started := make(chan int)
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		// No wait between tests, so only the limit keeps them from all running at once.
+		_, err := raceTests(context.Background(), 0, 2, []int{1, 2, 3, 4, 5}, func(ctx context.Context, entry int) (int, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := maxRunning.Load()
+				if n <= old || maxRunning.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			started <- entry
+			<-release
+			return 0, errors.New("failed")
+		})
+		done <- err
+	}()
+
+	// The first two tests start right away.
+	require.ElementsMatch(t, []int{1, 2}, []int{<-started, <-started})
+	for next := 3; next <= 5; next++ {
+		// The next test only starts after a running one finishes.
+		select {
+		case entry := <-started:
+			require.FailNow(t, "test started over the concurrency limit", "entry %v", entry)
+		case <-time.After(20 * time.Millisecond):
+		}
+		release <- struct{}{}
+		require.Equal(t, next, <-started)
+	}
+	release <- struct{}{}
+	release <- struct{}{}
+	require.Error(t, <-done)
+	require.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestRaceTests_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started, running atomic.Int32
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := raceTests(ctx, 10*time.Millisecond, 0, []int{1, 2, 3}, func(ctx context.Context, entry int) (int, error) {
+		started.Add(1)
+		running.Add(1)
+		defer running.Add(-1)
+		// Never succeed before the cancellation.
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, int32(3), started.Load())
+	// All the tests must have returned.
+	require.Equal(t, int32(0), running.Load())
+}
+
+func TestRaceTests_CancelBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var started atomic.Int32
+	_, err := raceTests(ctx, 10*time.Millisecond, 0, []int{1, 2, 3}, func(ctx context.Context, entry int) (int, error) {
+		started.Add(1)
+		return entry, nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, int32(0), started.Load())
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"gopkg.in/yaml.v3"
+)
+
+// StrategyCache stores the strategy found by the [StrategyFinder], so it can be reused across launches.
+// Implementations may use any persistent storage, like a file or the app preferences.
+type StrategyCache interface {
+	// Get returns the value stored for the key, and whether it was found.
+	Get(key string) ([]byte, bool)
+	// Put stores the value for the key, replacing any previous value.
+	Put(key string, value []byte)
+}
+
+// strategyCacheKey is the key used to store the winning strategy in the [StrategyCache].
+const strategyCacheKey = "smart-strategy"
+
+// strategy describes the DNS and TLS strategies selected by the [StrategyFinder].
+type strategy struct {
+	DNS dnsEntryConfig `yaml:"dns"`
+	// TLS is the transport config of the TLS strategy, or nil if the config has no TLS strategies.
+	TLS *string `yaml:"tls,omitempty"`
+	// QUIC is the transport config of the QUIC strategy, or nil if it was not searched.
+	QUIC *string `yaml:"quic,omitempty"`
+}
+
+// cachedStrategy is the entry stored in the [StrategyCache].
+type cachedStrategy struct {
+	Strategy strategy `yaml:"strategy"`
+	// ConfigHash is the hash of the config the strategy was found from, to invalidate the entry when the config changes.
+	ConfigHash string `yaml:"config_hash"`
+	// TestDomains are the domains the strategy was validated against.
+	TestDomains []string  `yaml:"test_domains"`
+	SavedAt     time.Time `yaml:"saved_at"`
+}
+
+func hashConfig(configBytes []byte) string {
+	hash := sha256.Sum256(configBytes)
+	return hex.EncodeToString(hash[:])
+}
+
+// loadCachedStrategy returns the strategy in the cache, if there's one usable for the config and testDomains.
+func (f *StrategyFinder) loadCachedStrategy(cache StrategyCache, configHash string, testDomains []string) (*strategy, bool) {
+	value, ok := cache.Get(strategyCacheKey)
+	if !ok {
+		return nil, false
+	}
+	var entry cachedStrategy
+	if err := yaml.Unmarshal(value, &entry); err != nil {
+		f.log(context.Background(), slog.LevelWarn, "Ignoring invalid cached strategy", "error", err)
+		return nil, false
+	}
+	if entry.ConfigHash != configHash {
+		return nil, false
+	}
+	if f.CacheTTL > 0 && time.Since(entry.SavedAt) > f.CacheTTL {
+		return nil, false
+	}
+	for _, domain := range testDomains {
+		if !slices.Contains(entry.TestDomains, domain) {
+			return nil, false
+		}
+	}
+	return &entry.Strategy, true
+}
+
+func (f *StrategyFinder) storeCachedStrategy(cache StrategyCache, configHash string, testDomains []string, found *strategy) {
+	value, err := yaml.Marshal(cachedStrategy{
+		Strategy:    *found,
+		ConfigHash:  configHash,
+		TestDomains: testDomains,
+		SavedAt:     time.Now(),
+	})
+	if err != nil {
+		f.log(context.Background(), slog.LevelWarn, "Failed to serialize strategy", "error", err)
+		return
+	}
+	cache.Put(strategyCacheKey, value)
+}
+
+// NewDialerWithCache is like [StrategyFinder.NewDialer], but it first tries the strategy stored in the cache by a previous call,
+// and only searches the whole config if the cached strategy no longer works. The strategy that is found is stored in the cache.
+//
+// The cached strategy is only used if it was found with the same config and validated against all of the testDomains,
+// and it is not older than [StrategyFinder.CacheTTL].
+func (f *StrategyFinder) NewDialerWithCache(ctx context.Context, testDomains []string, configBytes []byte, cache StrategyCache) (transport.StreamDialer, error) {
+	if cache == nil {
+		return f.NewDialer(ctx, testDomains, configBytes)
+	}
+	var parsedConfig configConfig
+	err := yaml.Unmarshal(configBytes, &parsedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	// The QUIC strategies are not needed for the stream dialer.
+	parsedConfig.QUIC = nil
+	testDomains = makeFullyQualifiedDomains(testDomains)
+	configHash := hashConfig(configBytes)
+
+	if cached, ok := f.loadCachedStrategy(cache, configHash, testDomains); ok {
+		cachedConfig := configConfig{DNS: []dnsEntryConfig{cached.DNS}}
+		if cached.TLS != nil {
+			cachedConfig.TLS = []string{*cached.TLS}
+		}
+		f.log(ctx, slog.LevelDebug, "Validating cached strategy")
+		dialers, _, err := f.findStrategy(ctx, testDomains, cachedConfig)
+		if err == nil {
+			return dialers.StreamDialer, nil
+		}
+		f.log(ctx, slog.LevelWarn, "Cached strategy failed, searching the config", "error", err)
+	}
+
+	dialers, found, err := f.findStrategy(ctx, testDomains, parsedConfig)
+	if err != nil {
+		return nil, err
+	}
+	f.storeCachedStrategy(cache, configHash, testDomains, found)
+	return dialers.StreamDialer, nil
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+	"gopkg.in/yaml.v3"
+)
+
+// The DNS-only configs avoid the TLS test, which needs a server with a trusted certificate.
+var testCacheConfig = []byte(`
+dns:
+  - udp: {address: 192.0.2.1}
+  - udp: {address: 192.0.2.2}
+`)
+
+func TestNewDialerWithCache_Miss(t *testing.T) {
+	servers := newFakeDNSServers("192.0.2.2:53")
+	finder := newTestCacheFinder(servers)
+	cache := newMapStrategyCache()
+
+	dialer, err := finder.NewDialerWithCache(context.Background(), []string{"www.example.com"}, testCacheConfig, cache)
+	require.NoError(t, err)
+	require.NotNil(t, dialer)
+
+	entry := cache.entry(t)
+	require.NotNil(t, entry.Strategy.DNS.UDP)
+	require.Equal(t, "192.0.2.2", entry.Strategy.DNS.UDP.Address)
+	require.Equal(t, hashConfig(testCacheConfig), entry.ConfigHash)
+	require.Equal(t, []string{"www.example.com."}, entry.TestDomains)
+}
+
+func TestNewDialerWithCache_Hit(t *testing.T) {
+	servers := newFakeDNSServers("192.0.2.1:53", "192.0.2.2:53")
+	finder := newTestCacheFinder(servers)
+	cache := newMapStrategyCache()
+	cache.putStrategy(t, testCacheConfig, []string{"www.example.com."}, "192.0.2.2")
+
+	_, err := finder.NewDialerWithCache(context.Background(), []string{"www.example.com"}, testCacheConfig, cache)
+	require.NoError(t, err)
+	// Only the cached resolver is tested, even though the first one in the config also works.
+	require.Equal(t, 0, servers.dials("192.0.2.1:53"))
+	require.Greater(t, servers.dials("192.0.2.2:53"), 0)
+	require.Equal(t, "192.0.2.2", cache.entry(t).Strategy.DNS.UDP.Address)
+}
+
+func TestNewDialerWithCache_CachedStrategyFails(t *testing.T) {
+	// The cached resolver no longer works, so the config is searched again and the cache updated.
+	servers := newFakeDNSServers("192.0.2.1:53")
+	finder := newTestCacheFinder(servers)
+	cache := newMapStrategyCache()
+	cache.putStrategy(t, testCacheConfig, []string{"www.example.com."}, "192.0.2.2")
+
+	_, err := finder.NewDialerWithCache(context.Background(), []string{"www.example.com"}, testCacheConfig, cache)
+	require.NoError(t, err)
+	require.Greater(t, servers.dials("192.0.2.1:53"), 0)
+	require.Equal(t, "192.0.2.1", cache.entry(t).Strategy.DNS.UDP.Address)
+}
+
+func TestNewDialerWithCache_ConfigChanged(t *testing.T) {
+	servers := newFakeDNSServers("192.0.2.1:53", "192.0.2.2:53")
+	finder := newTestCacheFinder(servers)
+	cache := newMapStrategyCache()
+	cache.putStrategy(t, []byte("dns: [{udp: {address: 192.0.2.2}}]"), []string{"www.example.com."}, "192.0.2.2")
+
+	config := []byte("dns: [{udp: {address: 192.0.2.1}}]")
+	_, err := finder.NewDialerWithCache(context.Background(), []string{"www.example.com"}, config, cache)
+	require.NoError(t, err)
+	require.Equal(t, 0, servers.dials("192.0.2.2:53"))
+	entry := cache.entry(t)
+	require.Equal(t, "192.0.2.1", entry.Strategy.DNS.UDP.Address)
+	require.Equal(t, hashConfig(config), entry.ConfigHash)
+}
+
+func TestNewDialerWithCache_CorruptEntry(t *testing.T) {
+	servers := newFakeDNSServers("192.0.2.2:53")
+	finder := newTestCacheFinder(servers)
+	cache := newMapStrategyCache()
+	cache.Put(strategyCacheKey, []byte("strategy: [not a strategy"))
+
+	_, err := finder.NewDialerWithCache(context.Background(), []string{"www.example.com"}, testCacheConfig, cache)
+	require.NoError(t, err)
+	// The corrupt entry is replaced with the strategy found.
+	require.Equal(t, "192.0.2.2", cache.entry(t).Strategy.DNS.UDP.Address)
+}
+
+func TestNewDialerWithCache_NoStrategyWorks(t *testing.T) {
+	servers := newFakeDNSServers()
+	finder := newTestCacheFinder(servers)
+	cache := newMapStrategyCache()
+
+	_, err := finder.NewDialerWithCache(context.Background(), []string{"www.example.com"}, testCacheConfig, cache)
+	require.Error(t, err)
+	_, ok := cache.Get(strategyCacheKey)
+	require.False(t, ok)
+}
+
+// Private test helpers
+
+func newTestCacheFinder(servers *fakeDNSServers) *StrategyFinder {
+	return &StrategyFinder{
+		TestTimeout:  time.Second,
+		StreamDialer: &transport.TCPDialer{},
+		PacketDialer: servers,
+	}
+}
+
+// mapStrategyCache is a [StrategyCache] backed by a map.
+type mapStrategyCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+var _ StrategyCache = (*mapStrategyCache)(nil)
+
+func newMapStrategyCache() *mapStrategyCache {
+	return &mapStrategyCache{entries: make(map[string][]byte)}
+}
+
+func (c *mapStrategyCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.entries[key]
+	return value, ok
+}
+
+func (c *mapStrategyCache) Put(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = value
+}
+
+// putStrategy stores an entry for a strategy that uses the UDP resolver at dnsAddress.
+func (c *mapStrategyCache) putStrategy(t *testing.T, config []byte, testDomains []string, dnsAddress string) {
+	value, err := yaml.Marshal(cachedStrategy{
+		Strategy:    strategy{DNS: dnsEntryConfig{UDP: &udpEntryConfig{Address: dnsAddress}}},
+		ConfigHash:  hashConfig(config),
+		TestDomains: testDomains,
+		SavedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	c.Put(strategyCacheKey, value)
+}
+
+// entry returns the parsed entry in the cache.
+func (c *mapStrategyCache) entry(t *testing.T) cachedStrategy {
+	value, ok := c.Get(strategyCacheKey)
+	require.True(t, ok)
+	var entry cachedStrategy
+	require.NoError(t, yaml.Unmarshal(value, &entry))
+	return entry
+}
+
+// fakeDNSServers is a [transport.PacketDialer] that connects to in-process DNS servers at the given addresses.
+// Dials to other addresses fail.
+type fakeDNSServers struct {
+	mu        sync.Mutex
+	addresses map[string]bool
+	dialCount map[string]int
+}
+
+var _ transport.PacketDialer = (*fakeDNSServers)(nil)
+
+func newFakeDNSServers(addresses ...string) *fakeDNSServers {
+	servers := &fakeDNSServers{addresses: make(map[string]bool), dialCount: make(map[string]int)}
+	for _, addr := range addresses {
+		servers.addresses[addr] = true
+	}
+	return servers
+}
+
+func (s *fakeDNSServers) dials(addr string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dialCount[addr]
+}
+
+func (s *fakeDNSServers) DialPacket(ctx context.Context, addr string) (net.Conn, error) {
+	s.mu.Lock()
+	s.dialCount[addr]++
+	ok := s.addresses[addr]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("server unreachable")
+	}
+	clientConn, serverConn := net.Pipe()
+	go serveFakeDNS(serverConn)
+	return clientConn, nil
+}
+
+// serveFakeDNS answers the queries on conn like a working recursive resolver: A queries get a public IP, and
+// CNAME queries get no answers and the zone SOA.
+func serveFakeDNS(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 512)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			return
+		}
+		q := query.Questions[0]
+		response := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+			Questions: query.Questions,
+		}
+		switch q.Type {
+		case dnsmessage.TypeA:
+			response.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{198, 51, 100, 1}},
+			}}
+		case dnsmessage.TypeCNAME:
+			response.Authorities = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeSOA, Class: q.Class, TTL: 60},
+				Body: &dnsmessage.SOAResource{
+					NS:   dnsmessage.MustNewName("ns.example.com."),
+					MBox: dnsmessage.MustNewName("hostmaster.example.com."),
+				},
+			}}
+		}
+		responseBytes, err := response.Pack()
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(responseBytes); err != nil {
+			return
+		}
+	}
+}
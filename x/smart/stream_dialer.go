@@ -17,11 +17,14 @@ package smart
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -35,31 +38,84 @@ import (
 // go run ./x/examples/smart-proxy -v -localAddr=localhost:1080 --transport="" --domain www.rferl.org  --config=<(echo '{"dns": [{"https": {"name": "doh.sb"}}]}')
 
 type StrategyFinder struct {
-	TestTimeout  time.Duration
-	LogWriter    io.Writer
+	TestTimeout time.Duration
+	// LogWriter, if set, gets the events of the search as text, in the [slog.TextHandler] format.
+	LogWriter io.Writer
+	// Logger, if set, gets structured events of the search: a debug event for each strategy test, the selected
+	// strategies and warnings. It also gets the debug events of the DNS queries and dials, and of the config providers.
+	Logger       *slog.Logger
 	StreamDialer transport.StreamDialer
 	PacketDialer transport.PacketDialer
-	logMu        sync.Mutex
+	// CacheTTL is how long a strategy stored by [StrategyFinder.NewDialerWithCache] can be reused.
+	// Zero means the stored strategy doesn't expire.
+	CacheTTL time.Duration
+	// MaxConcurrency is the maximum number of strategies tested at the same time.
+	// Zero means no limit.
+	MaxConcurrency int
+	// OnTestResult, if set, is called with the result of each strategy test, so you can collect structured
+	// data about the search. It may be called concurrently.
+	OnTestResult func(StrategyTestResult)
+
+	loggerOnce   sync.Once
+	searchLogger *slog.Logger
+}
+
+// StrategyTestResult is the result of testing a strategy against one of the test domains.
+// It implements report.HasSuccess, so it can be sent to a report.Collector.
+type StrategyTestResult struct {
+	// Kind is the kind of strategy tested: "dns", "tls" or "quic".
+	Kind string
+	// Strategy is the description of the strategy. For TLS strategies, it's the transport config.
+	Strategy string
+	// Domain is the test domain.
+	Domain   string
+	Duration time.Duration
+	// Error is the test error, or nil if the strategy worked for the domain.
+	Error error
 }
 
-func (f *StrategyFinder) log(format string, a ...any) {
-	if f.LogWriter != nil {
-		f.logMu.Lock()
-		defer f.logMu.Unlock()
-		fmt.Fprintf(f.LogWriter, format, a...)
+// MarshalJSON implements [json.Marshaler], with the error as text and the duration in milliseconds.
+func (r StrategyTestResult) MarshalJSON() ([]byte, error) {
+	type jsonResult struct {
+		Kind       string `json:"kind"`
+		Strategy   string `json:"strategy"`
+		Domain     string `json:"domain"`
+		DurationMs int64  `json:"duration_ms"`
+		Error      string `json:"error,omitempty"`
 	}
+	result := jsonResult{Kind: r.Kind, Strategy: r.Strategy, Domain: r.Domain, DurationMs: r.Duration.Milliseconds()}
+	if r.Error != nil {
+		result.Error = r.Error.Error()
+	}
+	return json.Marshal(result)
 }
 
-// Only log if context is not done
-func (f *StrategyFinder) logCtx(ctx context.Context, format string, a ...any) {
-	if ctx != nil {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
+// IsSuccess implements report.HasSuccess.
+func (r StrategyTestResult) IsSuccess() bool {
+	return r.Error == nil
+}
+
+// reportCtx logs the result and calls OnTestResult if the context is not done, so results of tests cancelled by the
+// search are not reported.
+func (f *StrategyFinder) reportCtx(ctx context.Context, result StrategyTestResult) {
+	if ctx.Err() != nil {
+		return
+	}
+	args := []any{"kind", result.Kind, "strategy", result.Strategy, "domain", result.Domain, "duration", result.Duration}
+	if result.Error != nil {
+		args = append(args, "error", result.Error)
+	}
+	f.log(ctx, slog.LevelDebug, "Strategy test done", args...)
+	if f.OnTestResult != nil {
+		f.OnTestResult(result)
 	}
-	f.log(format, a...)
+}
+
+// newConfigProviders creates the config providers for the strategies, with the finder logger.
+func (f *StrategyFinder) newConfigProviders() *configurl.ProviderContainer {
+	providers := configurl.NewDefaultProviders()
+	providers.SetLogger(f.Logger)
+	return providers
 }
 
 type httpsEntryConfig struct {
@@ -95,8 +151,9 @@ type dnsEntryConfig struct {
 }
 
 type configConfig struct {
-	DNS []dnsEntryConfig `yaml:"dns,omitempty"`
-	TLS []string         `yaml:"tls,omitempty"`
+	DNS  []dnsEntryConfig `yaml:"dns,omitempty"`
+	TLS  []string         `yaml:"tls,omitempty"`
+	QUIC []string         `yaml:"quic,omitempty"`
 }
 
 // newDNSResolverFromEntry creates a [dns.Resolver] based on the config, returning the resolver and
@@ -163,6 +220,21 @@ type smartResolver struct {
 	dns.Resolver
 	ID     string
 	Secure bool
+	Entry  dnsEntryConfig
+}
+
+// dnsEntryID returns a single-line description of the entry, so the logs of concurrent tests remain readable.
+func dnsEntryID(entry dnsEntryConfig) (string, error) {
+	var node yaml.Node
+	if err := node.Encode(entry); err != nil {
+		return "", err
+	}
+	node.Style = yaml.FlowStyle
+	idBytes, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(idBytes)), nil
 }
 
 func (f *StrategyFinder) dnsConfigToResolver(dnsConfig []dnsEntryConfig) ([]*smartResolver, error) {
@@ -171,30 +243,27 @@ func (f *StrategyFinder) dnsConfigToResolver(dnsConfig []dnsEntryConfig) ([]*sma
 	}
 	rts := make([]*smartResolver, 0, len(dnsConfig))
 	for ei, entry := range dnsConfig {
-		idBytes, err := yaml.Marshal(entry)
+		id, err := dnsEntryID(entry)
 		if err != nil {
 			return nil, fmt.Errorf("cannot serialize entry %v: %w", ei, err)
 		}
-		id := string(idBytes)
 		resolver, isSecure, err := f.newDNSResolverFromEntry(entry)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process entry %v: %w", ei, err)
 		}
-		rts = append(rts, &smartResolver{Resolver: resolver, ID: id, Secure: isSecure})
+		rts = append(rts, &smartResolver{Resolver: resolver, ID: id, Secure: isSecure, Entry: entry})
 	}
 	return rts, nil
 }
 
-func (f *StrategyFinder) findDNS(ctx context.Context, testDomains []string, dnsConfig []dnsEntryConfig) (dns.Resolver, error) {
+func (f *StrategyFinder) findDNS(ctx context.Context, testDomains []string, dnsConfig []dnsEntryConfig) (*smartResolver, error) {
 	resolvers, err := f.dnsConfigToResolver(dnsConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, searchDone := context.WithCancel(ctx)
-	defer searchDone()
 	raceStart := time.Now()
-	resolver, err := raceTests(ctx, 250*time.Millisecond, resolvers, func(resolver *smartResolver) (*smartResolver, error) {
+	resolver, err := raceTests(ctx, 250*time.Millisecond, f.MaxConcurrency, resolvers, func(ctx context.Context, resolver *smartResolver) (*smartResolver, error) {
 		for _, testDomain := range testDomains {
 			select {
 			case <-ctx.Done():
@@ -202,17 +271,14 @@ func (f *StrategyFinder) findDNS(ctx context.Context, testDomains []string, dnsC
 			default:
 			}
 
-			f.logCtx(ctx, "🏃 run DNS: %v (domain: %v)\n", resolver.ID, testDomain)
+			f.log(ctx, slog.LevelDebug, "Strategy test started", "kind", "dns", "strategy", resolver.ID, "domain", testDomain)
 			startTime := time.Now()
 			ips, err := testDNSResolver(ctx, f.TestTimeout, resolver, testDomain)
 			duration := time.Since(startTime)
-
-			status := "ok ✅"
-			if err != nil {
-				status = fmt.Sprintf("%v ❌", err)
+			if err == nil {
+				f.log(ctx, slog.LevelDebug, "Resolved test domain", "strategy", resolver.ID, "domain", testDomain, "ips", ips)
 			}
-			// Only output log if the search is not done yet.
-			f.logCtx(ctx, "🏁 got DNS: %v (domain: %v), duration=%v, ips=%v, status=%v\n", resolver.ID, testDomain, duration, ips, status)
+			f.reportCtx(ctx, StrategyTestResult{Kind: "dns", Strategy: resolver.ID, Domain: testDomain, Duration: duration, Error: err})
 
 			if err != nil {
 				return nil, err
@@ -223,25 +289,24 @@ func (f *StrategyFinder) findDNS(ctx context.Context, testDomains []string, dnsC
 	if err != nil {
 		return nil, fmt.Errorf("could not find working resolver: %w", err)
 	}
-	f.log("🏆 selected DNS resolver %v in %0.2fs\n\n", resolver.ID, time.Since(raceStart).Seconds())
-	return resolver.Resolver, nil
+	f.log(ctx, slog.LevelInfo, "Selected DNS resolver", "strategy", resolver.ID, "duration", time.Since(raceStart))
+	return resolver, nil
 }
 
-func (f *StrategyFinder) findTLS(ctx context.Context, testDomains []string, baseDialer transport.StreamDialer, tlsConfig []string) (transport.StreamDialer, error) {
+// findTLS returns a dialer with the TLS strategy that works for all the testDomains, and the config of that strategy.
+func (f *StrategyFinder) findTLS(ctx context.Context, testDomains []string, baseDialer transport.StreamDialer, tlsConfig []string) (transport.StreamDialer, string, error) {
 	if len(tlsConfig) == 0 {
-		return nil, errors.New("config for TLS is empty. Please specify at least one transport")
+		return nil, "", errors.New("config for TLS is empty. Please specify at least one transport")
 	}
-	var configModule = configurl.NewDefaultProviders()
+	var configModule = f.newConfigProviders()
 	configModule.StreamDialers.BaseInstance = baseDialer
 
-	ctx, searchDone := context.WithCancel(ctx)
-	defer searchDone()
 	raceStart := time.Now()
 	type SearchResult struct {
 		Dialer transport.StreamDialer
 		Config string
 	}
-	result, err := raceTests(ctx, 250*time.Millisecond, tlsConfig, func(transportCfg string) (*SearchResult, error) {
+	result, err := raceTests(ctx, 250*time.Millisecond, f.MaxConcurrency, tlsConfig, func(ctx context.Context, transportCfg string) (*SearchResult, error) {
 		tlsDialer, err := configModule.NewStreamDialer(ctx, transportCfg)
 		if err != nil {
 			return nil, fmt.Errorf("WrapStreamDialer failed: %w", err)
@@ -250,30 +315,31 @@ func (f *StrategyFinder) findTLS(ctx context.Context, testDomains []string, base
 			startTime := time.Now()
 
 			testAddr := net.JoinHostPort(testDomain, "443")
-			f.logCtx(ctx, "🏃 run TLS: '%v' (domain: %v)\n", transportCfg, testDomain)
+			f.log(ctx, slog.LevelDebug, "Strategy test started", "kind", "tls", "strategy", transportCfg, "domain", testDomain)
 
-			ctx, cancel := context.WithTimeout(ctx, f.TestTimeout)
-			defer cancel()
-			testConn, err := tlsDialer.DialStream(ctx, testAddr)
+			testCtx, cancel := context.WithTimeout(ctx, f.TestTimeout)
+			testConn, err := tlsDialer.DialStream(testCtx, testAddr)
 			if err != nil {
-				f.logCtx(ctx, "🏁 got TLS: '%v' (domain: %v), duration=%v, dial_error=%v ❌\n", transportCfg, testDomain, time.Since(startTime), err)
+				cancel()
+				f.reportCtx(ctx, StrategyTestResult{Kind: "tls", Strategy: transportCfg, Domain: testDomain, Duration: time.Since(startTime), Error: err})
 				return nil, err
 			}
 			tlsConn := tls.Client(testConn, &tls.Config{ServerName: testDomain})
-			err = tlsConn.HandshakeContext(ctx)
+			err = tlsConn.HandshakeContext(testCtx)
 			tlsConn.Close()
+			cancel()
 			if err != nil {
-				f.logCtx(ctx, "🏁 got TLS: '%v' (domain: %v), duration=%v, handshake=%v ❌\n", transportCfg, testDomain, time.Since(startTime), err)
+				f.reportCtx(ctx, StrategyTestResult{Kind: "tls", Strategy: transportCfg, Domain: testDomain, Duration: time.Since(startTime), Error: err})
 				return nil, err
 			}
-			f.logCtx(ctx, "🏁 got TLS: '%v' (domain: %v), duration=%v, status=ok ✅\n", transportCfg, testDomain, time.Since(startTime))
+			f.reportCtx(ctx, StrategyTestResult{Kind: "tls", Strategy: transportCfg, Domain: testDomain, Duration: time.Since(startTime)})
 		}
 		return &SearchResult{tlsDialer, transportCfg}, nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("could not find TLS strategy: %w", err)
+		return nil, "", fmt.Errorf("could not find TLS strategy: %w", err)
 	}
-	f.log("🏆 selected TLS strategy '%v' in %0.2fs\n\n", result.Config, time.Since(raceStart).Seconds())
+	f.log(ctx, slog.LevelInfo, "Selected TLS strategy", "strategy", result.Config, "duration", time.Since(raceStart))
 	tlsDialer := result.Dialer
 	return transport.FuncStreamDialer(func(ctx context.Context, raddr string) (transport.StreamConn, error) {
 		_, portStr, err := net.SplitHostPort(raddr)
@@ -289,45 +355,115 @@ func (f *StrategyFinder) findTLS(ctx context.Context, testDomains []string, base
 			selectedDialer = tlsDialer
 		}
 		return selectedDialer.DialStream(ctx, raddr)
-	}), nil
+	}), result.Config, nil
 }
 
 // NewDialer uses the config in configBytes to search for a strategy that unblocks DNS and TLS for all of the testDomains, returning a dialer with the found strategy.
 // It returns an error if no strategy was found that unblocks the testDomains.
 // The testDomains must be domains with a TLS service running on port 443.
+// If ctx is cancelled, the search stops and NewDialer returns an error wrapping ctx.Err(), after all the strategy
+// tests are done and have closed their connections.
 func (f *StrategyFinder) NewDialer(ctx context.Context, testDomains []string, configBytes []byte) (transport.StreamDialer, error) {
 	var parsedConfig configConfig
 	err := yaml.Unmarshal(configBytes, &parsedConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse config: %v", err)
 	}
+	// The QUIC strategies are not needed for the stream dialer.
+	parsedConfig.QUIC = nil
+	dialers, _, err := f.findStrategy(ctx, makeFullyQualifiedDomains(testDomains), parsedConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dialers.StreamDialer, nil
+}
+
+// Dialers holds the dialers with the strategies found by the [StrategyFinder].
+type Dialers struct {
+	// StreamDialer uses the DNS strategy, and the TLS strategy for ports 443 and 853.
+	StreamDialer transport.StreamDialer
+	// PacketDialer uses the DNS strategy, and the QUIC strategy for port 443.
+	PacketDialer transport.PacketDialer
+}
+
+// NewDialers is like [StrategyFinder.NewDialer], but it also searches the QUIC strategies in the config, returning a
+// packet dialer in addition to the stream dialer. You can use the packet dialer for HTTP/3.
+// The testDomains must also have a QUIC service running on port 443 if the config has QUIC strategies.
+func (f *StrategyFinder) NewDialers(ctx context.Context, testDomains []string, configBytes []byte) (*Dialers, error) {
+	var parsedConfig configConfig
+	err := yaml.Unmarshal(configBytes, &parsedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	dialers, _, err := f.findStrategy(ctx, makeFullyQualifiedDomains(testDomains), parsedConfig)
+	return dialers, err
+}
 
-	// Make domain fully-qualified to prevent confusing domain search.
-	testDomains = append(make([]string, 0, len(testDomains)), testDomains...)
-	for di, domain := range testDomains {
-		testDomains[di] = makeFullyQualified(domain)
+// makeFullyQualifiedDomains returns a copy of domains with each domain made fully-qualified
+// to prevent confusing domain search.
+func makeFullyQualifiedDomains(domains []string) []string {
+	fqdns := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		fqdns = append(fqdns, makeFullyQualified(domain))
 	}
+	return fqdns
+}
 
-	resolver, err := f.findDNS(ctx, testDomains, parsedConfig.DNS)
+// findStrategy searches the strategies in the config, returning the dialers with the found strategy and its description.
+func (f *StrategyFinder) findStrategy(ctx context.Context, testDomains []string, config configConfig) (*Dialers, *strategy, error) {
+	resolver, err := f.findDNS(ctx, testDomains, config.DNS)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	found := &strategy{DNS: resolver.Entry}
+	dialers := &Dialers{}
 	var dnsDialer transport.StreamDialer
-	if resolver == nil {
+	var dnsPacketDialer transport.PacketDialer
+	if resolver.Resolver == nil {
 		if _, ok := f.StreamDialer.(*transport.TCPDialer); !ok {
-			return nil, fmt.Errorf("cannot use system resolver with base dialer of type %T", f.StreamDialer)
+			return nil, nil, fmt.Errorf("cannot use system resolver with base dialer of type %T", f.StreamDialer)
 		}
 		dnsDialer = f.StreamDialer
+		// The system resolver is used by the base packet dialer.
+		dnsPacketDialer = f.PacketDialer
 	} else {
-		resolver = newSimpleLRUCacheResolver(resolver, 100)
-		dnsDialer, err = dns.NewStreamDialer(resolver, f.StreamDialer)
+		if !transport.ReportsConnectFailure(f.StreamDialer) {
+			f.log(ctx, slog.LevelWarn, "Base dialer does not report connection failures, preferring IPv4 destinations")
+		}
+		cachedResolver := newSimpleLRUCacheResolver(resolver.Resolver, 100)
+		dialResolver, baseDialer := cachedResolver, f.StreamDialer
+		if f.Logger != nil {
+			dialResolver = newLoggingResolver(cachedResolver, f.Logger)
+			baseDialer = &loggingStreamDialer{dialer: f.StreamDialer, logger: f.Logger, msg: "Connection attempt"}
+		}
+		dnsDialer, err = dns.NewStreamDialer(dialResolver, baseDialer)
 		if err != nil {
-			return nil, fmt.Errorf("dns.NewStreamDialer failed: %w", err)
+			return nil, nil, fmt.Errorf("dns.NewStreamDialer failed: %w", err)
+		}
+		if f.Logger != nil {
+			dnsDialer = &loggingStreamDialer{dialer: dnsDialer, logger: f.Logger, msg: "Dial"}
 		}
+		dnsPacketDialer = newResolvingPacketDialer(cachedResolver, f.PacketDialer)
 	}
 
-	if len(parsedConfig.TLS) == 0 {
-		return dnsDialer, nil
+	dialers.StreamDialer = dnsDialer
+	if len(config.TLS) > 0 {
+		dialer, tlsConfig, err := f.findTLS(ctx, testDomains, dnsDialer, config.TLS)
+		if err != nil {
+			return nil, nil, err
+		}
+		dialers.StreamDialer = dialer
+		found.TLS = &tlsConfig
+	}
+
+	dialers.PacketDialer = dnsPacketDialer
+	if len(config.QUIC) > 0 {
+		dialer, quicConfig, err := f.findQUIC(ctx, testDomains, dnsPacketDialer, config.QUIC)
+		if err != nil {
+			return nil, nil, err
+		}
+		dialers.PacketDialer = dialer
+		found.QUIC = &quicConfig
 	}
-	return f.findTLS(ctx, testDomains, dnsDialer, parsedConfig.TLS)
+	return dialers, found, nil
 }
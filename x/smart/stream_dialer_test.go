@@ -0,0 +1,175 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDialer_Cancel(t *testing.T) {
+	// The dials only finish when their context is done, so the search never completes by itself.
+	var dialing atomic.Int32
+	blockingDial := func(ctx context.Context) error {
+		dialing.Add(1)
+		defer dialing.Add(-1)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	finder := &StrategyFinder{
+		TestTimeout: time.Minute,
+		StreamDialer: transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+			return nil, blockingDial(ctx)
+		}),
+		PacketDialer: transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return nil, blockingDial(ctx)
+		}),
+	}
+	config := []byte(`
+dns:
+  - tcp: {address: 192.0.2.1}
+  - udp: {address: 192.0.2.2}
+  - tls: {name: dns.example.com, address: 192.0.2.3}
+tls:
+  - ""
+  - split:1
+`)
+	baseGoroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := finder.NewDialer(ctx, []string{"www.example.com"}, config)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 5*time.Second)
+	// All the tests are done when NewDialer returns.
+	require.Equal(t, int32(0), dialing.Load())
+	// Give the runtime some time to clean up the goroutines of the timers and connections.
+	// We don't use require.Eventually because it runs the condition in a new goroutine.
+	for deadline := time.Now().Add(2 * time.Second); runtime.NumGoroutine() > baseGoroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.LessOrEqual(t, runtime.NumGoroutine(), baseGoroutines)
+}
+
+func TestNewDialer_MaxConcurrency(t *testing.T) {
+	// The DNS servers don't answer until released, so the tests pile up if the limit is not enforced.
+	var running, maxRunning, dials atomic.Int32
+	release := make(chan struct{})
+	finder := &StrategyFinder{
+		TestTimeout:    time.Minute,
+		MaxConcurrency: 2,
+		StreamDialer:   &transport.TCPDialer{},
+		PacketDialer: transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			dials.Add(1)
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := maxRunning.Load()
+				if n <= old || maxRunning.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil, errors.New("server unreachable")
+		}),
+	}
+	config := []byte(`
+dns:
+  - udp: {address: 192.0.2.1}
+  - udp: {address: 192.0.2.2}
+  - udp: {address: 192.0.2.3}
+  - udp: {address: 192.0.2.4}
+`)
+	// The search waits 250ms before starting each test, so all the tests would be running by now without the limit.
+	var dialsBeforeRelease atomic.Int32
+	time.AfterFunc(time.Second, func() {
+		dialsBeforeRelease.Store(dials.Load())
+		close(release)
+	})
+	_, err := finder.NewDialer(context.Background(), []string{"www.example.com"}, config)
+	require.Error(t, err)
+	require.Equal(t, int32(2), dialsBeforeRelease.Load())
+	require.Equal(t, int32(4), dials.Load())
+	require.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestNewDialer_OnTestResult(t *testing.T) {
+	// The first resolver is unreachable, and the second one works.
+	servers := newFakeDNSServers("192.0.2.2:53")
+	var mu sync.Mutex
+	var results []StrategyTestResult
+	finder := &StrategyFinder{
+		TestTimeout:  time.Second,
+		StreamDialer: &transport.TCPDialer{},
+		PacketDialer: servers,
+		OnTestResult: func(result StrategyTestResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, result)
+		},
+	}
+	config := []byte(`
+dns:
+  - udp: {address: 192.0.2.1}
+  - udp: {address: 192.0.2.2}
+`)
+	_, err := finder.NewDialer(context.Background(), []string{"www.example.com", "www.example.org"}, config)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The failed resolver stops at the first domain.
+	require.Len(t, results, 3)
+	require.Equal(t, "dns", results[0].Kind)
+	require.Equal(t, "{udp: {address: 192.0.2.1}}", results[0].Strategy)
+	require.Equal(t, "www.example.com.", results[0].Domain)
+	require.Error(t, results[0].Error)
+	require.False(t, results[0].IsSuccess())
+	for i, domain := range []string{"www.example.com.", "www.example.org."} {
+		result := results[i+1]
+		require.Equal(t, "dns", result.Kind)
+		require.Equal(t, "{udp: {address: 192.0.2.2}}", result.Strategy)
+		require.Equal(t, domain, result.Domain)
+		require.NoError(t, result.Error)
+		require.True(t, result.IsSuccess())
+		require.Greater(t, result.Duration, time.Duration(0))
+	}
+}
+
+func TestStrategyTestResult_MarshalJSON(t *testing.T) {
+	result := StrategyTestResult{Kind: "tls", Strategy: "split:1", Domain: "www.example.com.", Duration: 1500 * time.Millisecond, Error: errors.New("handshake failed")}
+	jsonBytes, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"kind":"tls","strategy":"split:1","domain":"www.example.com.","duration_ms":1500,"error":"handshake failed"}`, string(jsonBytes))
+
+	result.Error = nil
+	jsonBytes, err = json.Marshal(result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"kind":"tls","strategy":"split:1","domain":"www.example.com.","duration_ms":1500}`, string(jsonBytes))
+}
@@ -22,12 +22,16 @@
 //	| HTTP       | Yes     | Yes    | Yes    |
 //	| HTTPS      | Yes     | Yes    | Yes    |
 //	| SOCKS      | Yes(v4) | Yes(v5)| Yes(v5)|
+//	| PAC URL    | Yes     | Yes    | Yes    |
 //	+------------+---------+--------+--------+
 //
 // [SetWebProxy] implementation in this package sets up both system HTTP and HTTPS proxy settings when they are distinguished by the platform.
 //
 // [SetSOCKSProxy] method configures SOCKS proxy settings on the system.
 //
+// [SetPACProxy] configures the system to use the Proxy Auto-Config (PAC) file at the given URL, which lets you
+// route only some destinations through the proxy. Use [ClearPACProxy] to turn it off.
+//
 // Support for FTP Proxy setting was not included due to lack of adoption and usage.
 //
 // Username and password authentication is not supported because the intended usage it to connect to a
@@ -75,6 +79,20 @@
 //
 // after setting the proxy.
 //
+// To keep local traffic, like management and captive-portal checks, out of the proxy, set the bypass list with
+// [SetProxyBypass]. [DefaultProxyBypass] is a sensible default:
+//
+//	SetProxyBypass(DefaultProxyBypass)
+//
+// To avoid overwriting existing proxy settings, like a corporate proxy, you can save them with [GetWebProxy] or
+// [GetSOCKSProxy] before setting the proxy, and restore them with [RestoreWebProxy] or [RestoreSOCKSProxy] on exit:
+//
+//	saved, err := GetWebProxy()
+//	if err != nil {
+//		return err
+//	}
+//	defer RestoreWebProxy(saved)
+//
 // The section below provides platform-specific details on how the proxy settings are configured.
 //
 // # macOS
@@ -87,6 +105,23 @@
 //
 //	networksetup -setsecurewebproxy <networkservice> <domain> <portnumber>
 //
+// networksetup configures one network service at a time. By default, this package configures the service of the
+// default route interface. Use [WithNetworkServices] to select the services, or [WithAllActiveNetworkServices] to
+// configure all the services returned by [ListActiveNetworkServices], which is useful on Macs with multiple
+// interfaces, like Wi-Fi and Ethernet.
+//
+// To set the PAC URL, which also turns on the automatic proxy configuration:
+//
+//	networksetup -setautoproxyurl <networkservice> <url>
+//
+// [ClearPACProxy] turns it off with:
+//
+//	networksetup -setautoproxystate <networkservice> off
+//
+// The bypass list is set with:
+//
+//	networksetup -setproxybypassdomains <networkservice> <domain1> [domain2] [...]
+//
 // For more information, see the link [here].
 //
 // # Linux
@@ -102,12 +137,25 @@
 //	org.gnome.system.proxy.ftp
 //	org.gnome.system.proxy.socks
 //
+// For a PAC URL, the proxy mode is set to 'auto' instead of 'manual':
+//
+//	gsetting set org.gnome.system.proxy autoconfig-url 'http://127.0.0.1:8080/proxy.pac'
+//	gsetting set org.gnome.system.proxy mode 'auto'
+//
+// [ClearPACProxy] sets the mode to 'none' and clears the URL.
+//
+// The bypass list is set in org.gnome.system.proxy ignore-hosts.
+//
 // For more information, you can checkout the documentation for [gsettings] and its [configuration].
 //
 // # Windows
 //
 // On Windows, the package uses HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings + InternetSetOptionW
 // to setup proxy settings. For more information, you can checkout the documentation for [InternetSetOptionW].
+// The PAC URL is stored in the AutoConfigURL value of the same key, and [ClearPACProxy] deletes that value.
+// The bypass list is stored in the ProxyOverride value. Because it doesn't support CIDR notation, IPv4 prefixes are
+// converted to wildcards (e.g. "10.*") and IPv6 prefixes are dropped.
+// Windows applies the PAC file independently of the ProxyEnable flag used by the other proxy settings.
 //
 // [here]: https://keith.github.io/xcode-man-pages/networksetup.8.html
 // [gsettings]: https://github.com/GNOME/gsettings-desktop-schemas/blob/master/schemas/org.gnome.system.proxy.gschema.xml.in
@@ -0,0 +1,124 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sysproxy
+
+// Option customizes which system settings the proxy functions change.
+type Option func(o *options)
+
+type options struct {
+	networkServices          []string
+	allActiveNetworkServices bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithNetworkServices applies the settings to the given macOS network services (e.g. "Wi-Fi", "Ethernet"),
+// instead of the service of the default route interface. It's ignored on other platforms, which have system-wide settings.
+func WithNetworkServices(services ...string) Option {
+	return func(o *options) {
+		o.networkServices = services
+	}
+}
+
+// WithAllActiveNetworkServices applies the settings to all the macOS network services returned by
+// [ListActiveNetworkServices]. It's ignored on other platforms, which have system-wide settings.
+func WithAllActiveNetworkServices() Option {
+	return func(o *options) {
+		o.allActiveNetworkServices = true
+	}
+}
+
+// ProxySettings holds the system proxy settings of one proxy type, as returned by [GetWebProxy] and
+// [GetSOCKSProxy]. You can use it to restore the settings with [RestoreWebProxy] or [RestoreSOCKSProxy].
+type ProxySettings struct {
+	// Host is the IP address or hostname of the proxy. It may be empty if the proxy was never configured.
+	Host string
+	// Port is the port of the proxy. It may be empty if the proxy was never configured.
+	Port string
+	// Enabled tells whether the system uses the proxy.
+	Enabled bool
+	// mode is the raw system proxy mode on platforms where one mode applies to all the proxy types, like GNOME
+	// on Linux, where the mode can also select a PAC file. It's restored as is. It's empty on other platforms.
+	mode string
+}
+
+// GetWebProxy returns the current system web (HTTP & HTTPS) proxy settings.
+// It returns an error if the platform has different HTTP and HTTPS proxy settings.
+func GetWebProxy() (*ProxySettings, error) {
+	host, port, enabled, err := getWebProxy()
+	if err != nil {
+		return nil, err
+	}
+	mode, err := getProxyMode()
+	if err != nil {
+		return nil, err
+	}
+	return &ProxySettings{Host: host, Port: port, Enabled: enabled, mode: mode}, nil
+}
+
+// GetSOCKSProxy returns the current system SOCKS proxy settings.
+func GetSOCKSProxy() (*ProxySettings, error) {
+	host, port, enabled, err := getSOCKSProxy()
+	if err != nil {
+		return nil, err
+	}
+	mode, err := getProxyMode()
+	if err != nil {
+		return nil, err
+	}
+	return &ProxySettings{Host: host, Port: port, Enabled: enabled, mode: mode}, nil
+}
+
+// RestoreWebProxy re-applies web proxy settings previously returned by [GetWebProxy].
+func RestoreWebProxy(settings *ProxySettings, opts ...Option) error {
+	if settings.mode != "" {
+		return restoreWebProxyMode(settings)
+	}
+	if settings.Enabled {
+		return SetWebProxy(settings.Host, settings.Port, opts...)
+	}
+	return DisableWebProxy(opts...)
+}
+
+// RestoreSOCKSProxy re-applies SOCKS proxy settings previously returned by [GetSOCKSProxy].
+func RestoreSOCKSProxy(settings *ProxySettings, opts ...Option) error {
+	if settings.mode != "" {
+		return restoreSOCKSProxyMode(settings)
+	}
+	if settings.Enabled {
+		return SetSOCKSProxy(settings.Host, settings.Port, opts...)
+	}
+	return DisableSOCKSProxy(opts...)
+}
+
+// DefaultProxyBypass is a bypass list for [SetProxyBypass] with localhost, the loopback and link-local addresses,
+// the private networks and the .local domains. It keeps local and management traffic out of the proxy.
+var DefaultProxyBypass = []string{
+	"localhost",
+	"127.0.0.0/8",
+	"::1",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"169.254.0.0/16",
+	"fe80::/10",
+	"*.local",
+}
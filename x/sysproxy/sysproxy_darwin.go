@@ -19,6 +19,7 @@ package sysproxy
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -38,67 +39,170 @@ type proxySettings struct {
 	enabled bool
 }
 
-func SetWebProxy(host string, port string) error {
-	// Get the active network interface
-	activeInterface, err := getActiveNetworkInterface()
-	if err != nil {
-		return err
-	}
+func SetWebProxy(host string, port string, opts ...Option) error {
+	return forEachNetworkService(opts, func(service string) error {
+		// Set the web proxy and secure web proxy
+		if err := setProxySettings(proxyTypeHTTP, service, host, port); err != nil {
+			return err
+		}
+		return setProxySettings(proxyTypeHTTPS, service, host, port)
+	})
+}
 
-	// Set the web proxy and secure web proxy
-	if err := setProxySettings(proxyTypeHTTP, activeInterface, host, port); err != nil {
-		return err
-	}
-	if err := setProxySettings(proxyTypeHTTPS, activeInterface, host, port); err != nil {
-		// revert previous changes
-		return err
-	}
+func DisableWebProxy(opts ...Option) error {
+	return forEachNetworkService(opts, func(service string) error {
+		// disable the web proxy and secure web proxy
+		errHTTP := disableProxy(proxyTypeHTTP, service)
+		errHTTPs := disableProxy(proxyTypeHTTPS, service)
+		return errors.Join(errHTTP, errHTTPs)
+	})
+}
 
-	return nil
+func SetSOCKSProxy(host string, port string, opts ...Option) error {
+	return forEachNetworkService(opts, func(service string) error {
+		return setProxySettings(proxyTypeSOCKS, service, host, port)
+	})
 }
 
-func DisableWebProxy() error {
-	// Get the active network interface
-	activeInterface, err := getActiveNetworkInterface()
-	if err != nil {
-		return err
-	}
+func DisableSOCKSProxy(opts ...Option) error {
+	return forEachNetworkService(opts, func(service string) error {
+		return disableProxy(proxyTypeSOCKS, service)
+	})
+}
 
-	// disable the web proxy and secure web proxy
-	errHTTP := disableProxy(proxyTypeHTTP, activeInterface)
-	errHTTPs := disableProxy(proxyTypeHTTPS, activeInterface)
+func SetPACProxy(pacURL string, opts ...Option) error {
+	return forEachNetworkService(opts, func(service string) error {
+		// Setting the URL also turns on the automatic proxy configuration
+		// https://keith.github.io/xcode-man-pages/networksetup.8.html#setautoproxyurl
+		return exec.Command("networksetup", "-setautoproxyurl", service, pacURL).Run()
+	})
+}
+
+func ClearPACProxy(opts ...Option) error {
+	return forEachNetworkService(opts, func(service string) error {
+		// https://keith.github.io/xcode-man-pages/networksetup.8.html#setautoproxystate
+		return exec.Command("networksetup", "-setautoproxystate", service, "off").Run()
+	})
+}
 
-	return errors.Join(errHTTP, errHTTPs)
+func SetProxyBypass(hosts []string, opts ...Option) error {
+	args := hosts
+	if len(args) == 0 {
+		// networksetup clears the list with "Empty"
+		args = []string{"Empty"}
+	}
+	return forEachNetworkService(opts, func(service string) error {
+		// https://keith.github.io/xcode-man-pages/networksetup.8.html#setproxybypassdomains
+		return exec.Command("networksetup", append([]string{"-setproxybypassdomains", service}, args...)...).Run()
+	})
 }
 
-func SetSOCKSProxy(host string, port string) error {
-	// Get the active network interface
+// getProxyBypass returns the bypass list of the default network service.
+func getProxyBypass() ([]string, error) {
 	activeInterface, err := getActiveNetworkInterface()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	out, err := exec.Command("networksetup", "-getproxybypassdomains", activeInterface).Output()
+	if err != nil {
+		return nil, err
 	}
+	var hosts []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		// The output is a message instead when the list is empty.
+		if line == "" || strings.Contains(line, " ") {
+			continue
+		}
+		hosts = append(hosts, line)
+	}
+	return hosts, nil
+}
 
-	// Set the SOCKS proxy
-	if err := setProxySettings(proxyTypeSOCKS, activeInterface, host, port); err != nil {
-		return err
+// forEachNetworkService calls apply for each network service selected by the options, which is the service of the
+// default route interface if none is selected.
+func forEachNetworkService(opts []Option, apply func(service string) error) error {
+	o := newOptions(opts)
+	services := o.networkServices
+	if o.allActiveNetworkServices {
+		var err error
+		if services, err = ListActiveNetworkServices(); err != nil {
+			return err
+		}
+	}
+	if len(services) == 0 {
+		// Get the active network interface
+		activeInterface, err := getActiveNetworkInterface()
+		if err != nil {
+			return err
+		}
+		services = []string{activeInterface}
+	}
+	var errs []error
+	for _, service := range services {
+		if err := apply(service); err != nil {
+			errs = append(errs, fmt.Errorf("failed to configure network service %q: %w", service, err))
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// networkService is an entry of the networksetup -listnetworkserviceorder output.
+type networkService struct {
+	name    string
+	device  string
+	enabled bool
 }
 
-func DisableSOCKSProxy() error {
-	// Get the active network interface
-	activeInterface, err := getActiveNetworkInterface()
-	if err != nil {
-		return err
+// parseNetworkServiceOrder parses the output of networksetup -listnetworkserviceorder.
+// Example output:
+//
+//	An asterisk (*) denotes that a network service is disabled.
+//	(1) Wi-Fi
+//	(Hardware Port: Wi-Fi, Device: en0)
+//
+//	(*) Bluetooth PAN
+//	(Hardware Port: Bluetooth PAN, Device: en5)
+func parseNetworkServiceOrder(output string) []networkService {
+	serviceRe := regexp.MustCompile(`^\((\d+|\*)\) (.+)$`)
+	deviceRe := regexp.MustCompile(`Device: ([^)]*)\)`)
+	var services []networkService
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if matches := serviceRe.FindStringSubmatch(line); matches != nil {
+			services = append(services, networkService{name: matches[2], enabled: matches[1] != "*"})
+			continue
+		}
+		if matches := deviceRe.FindStringSubmatch(line); matches != nil && len(services) > 0 {
+			services[len(services)-1].device = strings.TrimSpace(matches[1])
+		}
 	}
+	return services
+}
 
-	// disable the SOCKS proxy
-	if err := disableProxy(proxyTypeSOCKS, activeInterface); err != nil {
-		return err
+// ListActiveNetworkServices returns the names of the enabled network services whose devices are up and have
+// an address, in the service order. Services without a device, like some VPNs, are not included.
+// https://keith.github.io/xcode-man-pages/networksetup.8.html#listnetworkserviceorder
+func ListActiveNetworkServices() ([]string, error) {
+	out, err := exec.Command("networksetup", "-listnetworkserviceorder").Output()
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	var active []string
+	for _, service := range parseNetworkServiceOrder(string(out)) {
+		if !service.enabled || service.device == "" {
+			continue
+		}
+		iface, err := net.InterfaceByName(service.device)
+		if err != nil || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if addrs, err := iface.Addrs(); err != nil || len(addrs) == 0 {
+			continue
+		}
+		active = append(active, service.name)
+	}
+	return active, nil
 }
 
 // getActiveNetworkInterface finds the active network interface using shell commands.
@@ -245,12 +349,55 @@ func parseProxySettings(commandOutput string) (*proxySettings, error) {
 			}
 		}
 	}
-	if host == "" || port == "" {
+	// The host may be empty if the proxy was never configured.
+	if enabled && (host == "" || port == "") {
 		return nil, fmt.Errorf("failed to parse host and port from output")
 	}
 	return &proxySettings{host: host, port: port, enabled: enabled}, nil
 }
 
+// parsePACSettings parses the output of networksetup -getautoproxyurl.
+// Example output:
+//
+//	URL: http://127.0.0.1:8080/proxy.pac
+//	Enabled: Yes
+func parsePACSettings(commandOutput string) (pacURL string, enabled bool, err error) {
+	lines := strings.Split(commandOutput, "\n")
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmedLine, "URL:"):
+			pacURL = strings.TrimSpace(strings.TrimPrefix(trimmedLine, "URL:"))
+			if pacURL == "(null)" {
+				pacURL = ""
+			}
+		case strings.HasPrefix(trimmedLine, "Enabled:"):
+			switch {
+			case strings.Contains(trimmedLine, "Yes"):
+				enabled = true
+			case strings.Contains(trimmedLine, "No"):
+				enabled = false
+			default:
+				return "", false, fmt.Errorf("failed to parse proxy status from output")
+			}
+		}
+	}
+	return pacURL, enabled, nil
+}
+
+func getPACProxy() (pacURL string, enabled bool, err error) {
+	activeInterface, err := getActiveNetworkInterface()
+	if err != nil {
+		return "", false, err
+	}
+
+	output, err := exec.Command("networksetup", "-getautoproxyurl", activeInterface).Output()
+	if err != nil {
+		return "", false, err
+	}
+	return parsePACSettings(string(output))
+}
+
 func getWebProxy() (host string, port string, enabled bool, err error) {
 	activeInterface, err := getActiveNetworkInterface()
 	if err != nil {
@@ -287,3 +434,18 @@ func getSOCKSProxy() (host string, port string, enabled bool, err error) {
 
 	return socksSettings.host, socksSettings.port, socksSettings.enabled, nil
 }
+
+// getProxyMode returns an empty mode, since each proxy type is enabled separately on this platform.
+func getProxyMode() (string, error) {
+	return "", nil
+}
+
+// restoreWebProxyMode is never called, since getProxyMode returns an empty mode.
+func restoreWebProxyMode(settings *ProxySettings) error {
+	return errors.New("proxy modes are not supported on this platform")
+}
+
+// restoreSOCKSProxyMode is never called, since getProxyMode returns an empty mode.
+func restoreSOCKSProxyMode(settings *ProxySettings) error {
+	return errors.New("proxy modes are not supported on this platform")
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sysproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworkServiceOrder(t *testing.T) {
+	output := `An asterisk (*) denotes that a network service is disabled.
+(1) Wi-Fi
+(Hardware Port: Wi-Fi, Device: en0)
+
+(2) USB 10/100/1000 LAN
+(Hardware Port: USB 10/100/1000 LAN, Device: en7)
+
+(*) Bluetooth PAN
+(Hardware Port: Bluetooth PAN, Device: en5)
+
+(3) My VPN
+(Hardware Port: com.example.vpn, Device: )
+`
+	require.Equal(t, []networkService{
+		{name: "Wi-Fi", device: "en0", enabled: true},
+		{name: "USB 10/100/1000 LAN", device: "en7", enabled: true},
+		{name: "Bluetooth PAN", device: "en5", enabled: false},
+		{name: "My VPN", device: "", enabled: true},
+	}, parseNetworkServiceOrder(output))
+}
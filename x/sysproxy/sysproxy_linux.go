@@ -31,7 +31,7 @@ const (
 	proxyTypeSOCKS ProxyType = "socks"
 )
 
-func SetWebProxy(host string, port string) error {
+func SetWebProxy(host string, port string, opts ...Option) error {
 	// Set HTTP and HTTPS proxy settings
 	if err := setProxySettings(proxyTypeHTTP, host, port); err != nil {
 		return err
@@ -45,11 +45,11 @@ func SetWebProxy(host string, port string) error {
 	return nil
 }
 
-func DisableWebProxy() error {
+func DisableWebProxy(opts ...Option) error {
 	return gnomeSettingsSetString("org.gnome.system.proxy", "mode", "none")
 }
 
-func SetSOCKSProxy(host string, port string) error {
+func SetSOCKSProxy(host string, port string, opts ...Option) error {
 	// Set SOCKS proxy settings
 	if err := setProxySettings(proxyTypeSOCKS, host, port); err != nil {
 		return err
@@ -60,10 +60,57 @@ func SetSOCKSProxy(host string, port string) error {
 	return nil
 }
 
-func DisableSOCKSProxy() error {
+func DisableSOCKSProxy(opts ...Option) error {
 	return gnomeSettingsSetString("org.gnome.system.proxy", "mode", "none")
 }
 
+func SetPACProxy(pacURL string, opts ...Option) error {
+	if err := gnomeSettingsSetString("org.gnome.system.proxy", "autoconfig-url", pacURL); err != nil {
+		return err
+	}
+	return gnomeSettingsSetString("org.gnome.system.proxy", "mode", "auto")
+}
+
+func ClearPACProxy(opts ...Option) error {
+	if err := gnomeSettingsSetString("org.gnome.system.proxy", "mode", "none"); err != nil {
+		return err
+	}
+	return gnomeSettingsSetString("org.gnome.system.proxy", "autoconfig-url", "")
+}
+
+func SetProxyBypass(hosts []string, opts ...Option) error {
+	return gnomeSettingsSetString("org.gnome.system.proxy", "ignore-hosts", formatGVariantStringArray(hosts))
+}
+
+// formatGVariantStringArray formats the strings as a GVariant array (e.g. ['localhost', '::1']).
+func formatGVariantStringArray(values []string) string {
+	if len(values) == 0 {
+		return "@as []"
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		quoted[i] = "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func getProxyBypass() ([]string, error) {
+	out, err := exec.Command("gsettings", "get", "org.gnome.system.proxy", "ignore-hosts").Output()
+	if err != nil {
+		return nil, err
+	}
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(out)), "@as"))
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.Trim(strings.TrimSpace(host), "'"); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
 func setManualMode() error {
 	return gnomeSettingsSetString("org.gnome.system.proxy", "mode", "manual")
 }
@@ -131,7 +178,7 @@ func getWebProxy() (host string, port string, enabled bool, err error) {
 		return "", "", false, errors.New("HTTP and HTTPS proxy settings are different")
 	}
 
-	return httpHost, httpPort, mode != "none", nil
+	return httpHost, httpPort, mode == "manual", nil
 }
 
 func getSOCKSProxy() (host string, port string, enabled bool, err error) {
@@ -150,7 +197,46 @@ func getSOCKSProxy() (host string, port string, enabled bool, err error) {
 		return "", "", false, err
 	}
 
-	return socksHost, socksPort, mode != "none", nil
+	return socksHost, socksPort, mode == "manual", nil
+}
+
+// getProxyMode returns the GNOME proxy mode ("none", "manual" or "auto"), which applies to all the proxy types.
+func getProxyMode() (string, error) {
+	return gnomeSettingsGetString("org.gnome.system.proxy", "mode")
+}
+
+// restoreWebProxyMode restores the web proxy address, and then the GNOME proxy mode as it was, so an "auto" mode
+// keeps using the PAC file.
+func restoreWebProxyMode(settings *ProxySettings) error {
+	if err := setProxySettings(proxyTypeHTTP, settings.Host, settings.Port); err != nil {
+		return err
+	}
+	if err := setProxySettings(proxyTypeHTTPS, settings.Host, settings.Port); err != nil {
+		return err
+	}
+	return gnomeSettingsSetString("org.gnome.system.proxy", "mode", settings.mode)
+}
+
+// restoreSOCKSProxyMode restores the SOCKS proxy address, and then the GNOME proxy mode as it was.
+func restoreSOCKSProxyMode(settings *ProxySettings) error {
+	if err := setProxySettings(proxyTypeSOCKS, settings.Host, settings.Port); err != nil {
+		return err
+	}
+	return gnomeSettingsSetString("org.gnome.system.proxy", "mode", settings.mode)
+}
+
+func getPACProxy() (pacURL string, enabled bool, err error) {
+	pacURL, err = gnomeSettingsGetString("org.gnome.system.proxy", "autoconfig-url")
+	if err != nil {
+		return "", false, err
+	}
+
+	mode, err := gnomeSettingsGetString("org.gnome.system.proxy", "mode")
+	if err != nil {
+		return "", false, err
+	}
+
+	return pacURL, mode == "auto", nil
 }
 
 func gnomeSettingsGetString(settings, key string) (string, error) {
@@ -158,3 +244,8 @@ func gnomeSettingsGetString(settings, key string) (string, error) {
 	trimmed := strings.TrimSpace(string(out))
 	return strings.Trim(string(trimmed), "'"), err
 }
+
+// ListActiveNetworkServices is only supported on macOS.
+func ListActiveNetworkServices() ([]string, error) {
+	return nil, errors.New("network services are only supported on macOS")
+}
@@ -0,0 +1,29 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && !android
+
+package sysproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatGVariantStringArray(t *testing.T) {
+	require.Equal(t, "['localhost', '127.0.0.0/8', '::1']", formatGVariantStringArray([]string{"localhost", "127.0.0.0/8", "::1"}))
+	require.Equal(t, `['it\'s', 'a\\b']`, formatGVariantStringArray([]string{"it's", `a\b`}))
+	require.Equal(t, "@as []", formatGVariantStringArray(nil))
+}
@@ -19,21 +19,64 @@ package sysproxy
 import "errors"
 
 // SetProxy does nothing on unsupported platforms.
-func SetWebProxy(ip string, port string) error {
+func SetWebProxy(ip string, port string, opts ...Option) error {
 	return errors.New("unsupported platform")
 }
 
 // SetProxy does nothing on unsupported platforms.
-func DisableWebProxy() error {
+func DisableWebProxy(opts ...Option) error {
 	return errors.New("unsupported platform")
 }
 
 // SetProxy does nothing on unsupported platforms.
-func SetSOCKSProxy(ip string, port string) error {
+func SetSOCKSProxy(ip string, port string, opts ...Option) error {
 	return errors.New("unsupported platform")
 }
 
 // SetProxy does nothing on unsupported platforms.
-func DisableSOCKSProxy() error {
+func DisableSOCKSProxy(opts ...Option) error {
+	return errors.New("unsupported platform")
+}
+
+// SetPACProxy does nothing on unsupported platforms.
+func SetPACProxy(pacURL string, opts ...Option) error {
+	return errors.New("unsupported platform")
+}
+
+// ClearPACProxy does nothing on unsupported platforms.
+func ClearPACProxy(opts ...Option) error {
+	return errors.New("unsupported platform")
+}
+
+func getWebProxy() (host string, port string, enabled bool, err error) {
+	return "", "", false, errors.New("unsupported platform")
+}
+
+func getSOCKSProxy() (host string, port string, enabled bool, err error) {
+	return "", "", false, errors.New("unsupported platform")
+}
+
+// getProxyMode returns an empty mode, since each proxy type is enabled separately on this platform.
+func getProxyMode() (string, error) {
+	return "", nil
+}
+
+// restoreWebProxyMode is never called, since getProxyMode returns an empty mode.
+func restoreWebProxyMode(settings *ProxySettings) error {
+	return errors.New("proxy modes are not supported on this platform")
+}
+
+// restoreSOCKSProxyMode is never called, since getProxyMode returns an empty mode.
+func restoreSOCKSProxyMode(settings *ProxySettings) error {
+	return errors.New("proxy modes are not supported on this platform")
+}
+
+// ListActiveNetworkServices is only supported on macOS.
+func ListActiveNetworkServices() ([]string, error) {
+	return nil, errors.New("unsupported platform")
+}
+
+// SetProxyBypass does nothing on unsupported platforms.
+func SetProxyBypass(hosts []string, opts ...Option) error {
 	return errors.New("unsupported platform")
 }
@@ -100,6 +100,79 @@ func TestClearSocksProxy(t *testing.T) {
 	require.Equal(t, false, enabled)
 }
 
+func TestGetAndRestoreWebProxy(t *testing.T) {
+	saved, err := GetWebProxy()
+	require.NoError(t, err)
+
+	host := generateRandomDomain()
+	port := strconv.Itoa(rand.Intn(65536))
+	err = SetWebProxy(host, port)
+	require.NoError(t, err)
+
+	settings, err := GetWebProxy()
+	require.NoError(t, err)
+	require.Equal(t, &ProxySettings{Host: host, Port: port, Enabled: true}, settings)
+
+	err = RestoreWebProxy(saved)
+	require.NoError(t, err)
+
+	restored, err := GetWebProxy()
+	require.NoError(t, err)
+	require.Equal(t, saved.Enabled, restored.Enabled)
+	if saved.Enabled {
+		require.Equal(t, saved, restored)
+	}
+}
+
+func TestGetAndRestoreSOCKSProxy(t *testing.T) {
+	saved, err := GetSOCKSProxy()
+	require.NoError(t, err)
+
+	err = RestoreSOCKSProxy(&ProxySettings{Host: "127.0.0.1", Port: "1080", Enabled: true})
+	require.NoError(t, err)
+
+	settings, err := GetSOCKSProxy()
+	require.NoError(t, err)
+	require.Equal(t, &ProxySettings{Host: "127.0.0.1", Port: "1080", Enabled: true}, settings)
+
+	err = RestoreSOCKSProxy(saved)
+	require.NoError(t, err)
+}
+
+func TestSetPACProxy(t *testing.T) {
+	pacURL := "http://" + generateRandomDomain() + "/proxy.pac"
+
+	err := SetPACProxy(pacURL)
+	require.NoError(t, err)
+
+	u, e, err := getPACProxy()
+	require.NoError(t, err)
+	require.Equal(t, pacURL, u)
+	require.Equal(t, true, e)
+
+	err = ClearPACProxy()
+	require.NoError(t, err)
+
+	_, e, err = getPACProxy()
+	require.NoError(t, err)
+	require.Equal(t, false, e)
+}
+
+func TestSetProxyBypass(t *testing.T) {
+	saved, err := getProxyBypass()
+	require.NoError(t, err)
+
+	err = SetProxyBypass([]string{"localhost", "*.example.com"})
+	require.NoError(t, err)
+
+	hosts, err := getProxyBypass()
+	require.NoError(t, err)
+	require.Equal(t, []string{"localhost", "*.example.com"}, hosts)
+
+	err = SetProxyBypass(saved)
+	require.NoError(t, err)
+}
+
 func generateRandomDomain() string {
 
 	// Define the characters allowed in the domain name
@@ -17,8 +17,11 @@
 package sysproxy
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"strconv"
 	"strings"
 
 	"golang.org/x/sys/windows"
@@ -47,7 +50,7 @@ const (
 	INTERNET_OPTION_REFRESH          = 37
 )
 
-func SetWebProxy(host string, port string) error {
+func SetWebProxy(host string, port string, opts ...Option) error {
 
 	settings := &proxySettings{
 		proxyServer:   net.JoinHostPort(host, port),
@@ -57,13 +60,13 @@ func SetWebProxy(host string, port string) error {
 	return setProxySettings(settings)
 }
 
-func DisableWebProxy() error {
+func DisableWebProxy(opts ...Option) error {
 	// disable proxy settings
 	return disableProxy()
 }
 
 // SetProxy does nothing on windows platforms.
-func SetSOCKSProxy(host string, port string) error {
+func SetSOCKSProxy(host string, port string, opts ...Option) error {
 	endpoint := fmt.Sprintf("socks=%s", net.JoinHostPort(host, port))
 	settings := &proxySettings{
 		proxyServer:   endpoint,
@@ -74,23 +77,138 @@ func SetSOCKSProxy(host string, port string) error {
 }
 
 // SetProxy does nothing on windows platforms.
-func DisableSOCKSProxy() error {
+func DisableSOCKSProxy(opts ...Option) error {
 	return disableProxy()
 }
 
-func setProxySettings(settings *proxySettings) error {
+func SetPACProxy(pacURL string, opts ...Option) error {
 	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.SET_VALUE)
 	if err != nil {
 		return err
 	}
 	defer key.Close()
 
-	if err = key.SetStringValue("ProxyServer", settings.proxyServer); err != nil {
+	if err = key.SetStringValue("AutoConfigURL", pacURL); err != nil {
+		return err
+	}
+
+	// Refresh the settings
+	return notifyWinInetProxySettingsChanged()
+}
+
+func ClearPACProxy(opts ...Option) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	// The automatic configuration is disabled when there's no AutoConfigURL
+	if err = key.DeleteValue("AutoConfigURL"); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+
+	// Refresh the settings
+	return notifyWinInetProxySettingsChanged()
+}
+
+func SetProxyBypass(hosts []string, opts ...Option) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	if err = key.SetStringValue("ProxyOverride", formatProxyOverride(hosts)); err != nil {
+		return err
+	}
+
+	// Refresh the settings
+	return notifyWinInetProxySettingsChanged()
+}
+
+// formatProxyOverride formats the hosts as a ProxyOverride registry value. WinINet doesn't support CIDR notation,
+// so IPv4 prefixes are converted to wildcards (e.g. "10.0.0.0/8" to "10.*"). Other prefixes are dropped.
+func formatProxyOverride(hosts []string) string {
+	var entries []string
+	for _, host := range hosts {
+		prefix, err := netip.ParsePrefix(host)
+		if err != nil {
+			entries = append(entries, host)
+			continue
+		}
+		entries = append(entries, ipv4PrefixWildcards(prefix)...)
+	}
+	return strings.Join(entries, ";")
+}
+
+// ipv4PrefixWildcards returns the WinINet wildcards that match the IPv4 prefix, rounding the prefix length up to
+// the byte boundary.
+func ipv4PrefixWildcards(prefix netip.Prefix) []string {
+	if !prefix.Addr().Is4() || prefix.Bits() == 0 {
+		return nil
+	}
+	addr := prefix.Masked().Addr().As4()
+	fixedBytes := (prefix.Bits() + 7) / 8
+	// Number of values of the last fixed byte covered by the prefix.
+	count := 1 << (fixedBytes*8 - prefix.Bits())
+	var wildcards []string
+	for i := 0; i < count; i++ {
+		parts := make([]string, 0, 4)
+		for b := 0; b < fixedBytes; b++ {
+			value := int(addr[b])
+			if b == fixedBytes-1 {
+				value += i
+			}
+			parts = append(parts, strconv.Itoa(value))
+		}
+		if fixedBytes < 4 {
+			parts = append(parts, "*")
+		}
+		wildcards = append(wildcards, strings.Join(parts, "."))
+	}
+	return wildcards
+}
+
+func getProxyBypass() ([]string, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	proxyOverride, _, err := key.GetStringValue("ProxyOverride")
+	if err == registry.ErrNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, host := range strings.Split(proxyOverride, ";") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+func setProxySettings(settings *proxySettings) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.SET_VALUE|registry.QUERY_VALUE)
+	if err != nil {
 		return err
 	}
-	if err = key.SetStringValue("ProxyOverride", settings.proxyOverride); err != nil {
+	defer key.Close()
+
+	if err = key.SetStringValue("ProxyServer", settings.proxyServer); err != nil {
 		return err
 	}
+	// Keep the bypass list if it was already set, for example with SetProxyBypass.
+	if _, _, err = key.GetStringValue("ProxyOverride"); err == registry.ErrNotExist {
+		if err = key.SetStringValue("ProxyOverride", settings.proxyOverride); err != nil {
+			return err
+		}
+	}
 	// Finally, enable the proxy
 	if err = key.SetDWordValue("ProxyEnable", uint32(1)); err != nil {
 		return err
@@ -143,51 +261,99 @@ func notifyWinInetProxySettingsChanged() error {
 
 	return nil
 }
-func getWebProxy() (host string, port string, enabled bool, err error) {
+func getPACProxy() (pacURL string, enabled bool, err error) {
 	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
 	if err != nil {
-		return "", "", false, err
+		return "", false, err
 	}
 	defer key.Close()
 
-	address, _, err := key.GetStringValue("ProxyServer")
-	if err != nil {
-		return "", "", false, err
-	}
-
-	// Read back the value of ProxyEnable
-	proxyEnable, _, err := key.GetIntegerValue("ProxyEnable")
-	if err != nil {
-		return "", "", false, err
+	pacURL, _, err = key.GetStringValue("AutoConfigURL")
+	if err == registry.ErrNotExist {
+		return "", false, nil
 	}
-
-	host, port, err = net.SplitHostPort(address)
 	if err != nil {
-		return "", "", false, err
+		return "", false, err
 	}
+	return pacURL, pacURL != "", nil
+}
 
-	return host, port, proxyEnable == 1, nil
+func getWebProxy() (host string, port string, enabled bool, err error) {
+	return getProxy("http")
 }
 
 func getSOCKSProxy() (host string, port string, enabled bool, err error) {
+	return getProxy("socks")
+}
+
+// getProxyMode returns an empty mode, since each proxy type is enabled separately on this platform.
+func getProxyMode() (string, error) {
+	return "", nil
+}
+
+// restoreWebProxyMode is never called, since getProxyMode returns an empty mode.
+func restoreWebProxyMode(settings *ProxySettings) error {
+	return errors.New("proxy modes are not supported on this platform")
+}
+
+// restoreSOCKSProxyMode is never called, since getProxyMode returns an empty mode.
+func restoreSOCKSProxyMode(settings *ProxySettings) error {
+	return errors.New("proxy modes are not supported on this platform")
+}
+
+// getProxy returns the proxy settings for the given protocol from the registry.
+func getProxy(protocol string) (host string, port string, enabled bool, err error) {
 	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
 	if err != nil {
 		return "", "", false, err
 	}
 	defer key.Close()
 
-	address, _, err := key.GetStringValue("ProxyServer")
-	h := strings.TrimPrefix(address, "socks=")
-
-	host, port, err = net.SplitHostPort(h)
-	if err != nil {
+	// The values don't exist if the proxy was never configured.
+	proxyServer, _, err := key.GetStringValue("ProxyServer")
+	if err != nil && err != registry.ErrNotExist {
 		return "", "", false, err
 	}
-	// Read back the value of ProxyEnable
 	proxyEnable, _, err := key.GetIntegerValue("ProxyEnable")
-	if err != nil {
+	if err != nil && err != registry.ErrNotExist {
 		return "", "", false, err
 	}
 
+	address := parseProxyServer(proxyServer, protocol)
+	if address == "" {
+		return "", "", false, nil
+	}
+	host, port, err = net.SplitHostPort(address)
+	if err != nil {
+		return "", "", false, err
+	}
 	return host, port, proxyEnable == 1, nil
 }
+
+// parseProxyServer returns the proxy address for the protocol in the ProxyServer registry value, or
+// an empty string if there's none. The value is either an address used for all protocols (e.g. "127.0.0.1:8080"),
+// or a list of per-protocol addresses (e.g. "http=127.0.0.1:8080;https=127.0.0.1:8080;socks=127.0.0.1:1080").
+func parseProxyServer(proxyServer string, protocol string) string {
+	var defaultAddress string
+	for _, entry := range strings.Split(proxyServer, ";") {
+		entry = strings.TrimSpace(entry)
+		name, address, found := strings.Cut(entry, "=")
+		if !found {
+			defaultAddress = entry
+			continue
+		}
+		if strings.EqualFold(name, protocol) {
+			return address
+		}
+	}
+	if protocol == "socks" {
+		// An address for all protocols doesn't apply to SOCKS.
+		return ""
+	}
+	return defaultAddress
+}
+
+// ListActiveNetworkServices is only supported on macOS.
+func ListActiveNetworkServices() ([]string, error) {
+	return nil, errors.New("network services are only supported on macOS")
+}
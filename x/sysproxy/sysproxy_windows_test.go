@@ -0,0 +1,38 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sysproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxyServer(t *testing.T) {
+	require.Equal(t, "127.0.0.1:8080", parseProxyServer("127.0.0.1:8080", "http"))
+	require.Equal(t, "", parseProxyServer("127.0.0.1:8080", "socks"))
+	require.Equal(t, "127.0.0.1:1080", parseProxyServer("socks=127.0.0.1:1080", "socks"))
+	require.Equal(t, "", parseProxyServer("socks=127.0.0.1:1080", "http"))
+	require.Equal(t, "proxy:80", parseProxyServer("http=proxy:80;https=proxy:443;socks=proxy:1080", "http"))
+	require.Equal(t, "proxy:1080", parseProxyServer("http=proxy:80; socks=proxy:1080", "socks"))
+	require.Equal(t, "", parseProxyServer("", "http"))
+}
+
+func TestFormatProxyOverride(t *testing.T) {
+	require.Equal(t, "localhost;127.*;::1;10.*;172.16.*;172.17.*;172.18.*;172.19.*;172.20.*;172.21.*;172.22.*;172.23.*;172.24.*;172.25.*;172.26.*;172.27.*;172.28.*;172.29.*;172.30.*;172.31.*;192.168.*;169.254.*;*.local",
+		formatProxyOverride(DefaultProxyBypass))
+	require.Equal(t, "1.2.3.4", formatProxyOverride([]string{"1.2.3.4/32"}))
+	require.Equal(t, "", formatProxyOverride(nil))
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tamper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/x/sockopt"
+	"golang.org/x/sys/unix"
+)
+
+const fakeSupported = true
+
+// maxFakeLen is the maximum decoy length. It must fit in a pipe with the default capacity.
+const maxFakeLen = 64 * 1024
+
+// maxSendWait is how long to wait for the kernel to send the decoy before replacing it with the real data.
+const maxSendWait = 500 * time.Millisecond
+
+// writeWithFake sends fake with the given hop limit, and then replaces it with data, which must have the same length,
+// for the re-transmissions.
+//
+// The decoy is written to a memory page that is passed to the socket by reference with vmsplice(2) and splice(2),
+// so the socket buffer holds the page itself, rather than a copy. Once the decoy is sent, we write the real data to
+// the page, so the kernel re-transmits the real data.
+func writeWithFake(conn *net.TCPConn, tcpOptions sockopt.TCPOptions, fake []byte, data []byte, hopLimit int) error {
+	pageSize := os.Getpagesize()
+	mem, err := unix.Mmap(-1, 0, (len(data)+pageSize-1)/pageSize*pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return fmt.Errorf("failed to map memory: %w", err)
+	}
+	// The socket buffer keeps a reference to the page after it's unmapped.
+	defer unix.Munmap(mem)
+	copy(mem, fake)
+
+	var pipe [2]int
+	if err := unix.Pipe2(pipe[:], unix.O_CLOEXEC); err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	defer unix.Close(pipe[0])
+	defer unix.Close(pipe[1])
+	for piped := 0; piped < len(data); {
+		iov := unix.Iovec{Base: &mem[piped]}
+		iov.SetLen(len(data) - piped)
+		n, err := unix.Vmsplice(pipe[1], []unix.Iovec{iov}, unix.SPLICE_F_GIFT)
+		if err != nil {
+			return fmt.Errorf("failed to vmsplice the decoy: %w", err)
+		}
+		piped += n
+	}
+
+	defaultHopLimit, err := tcpOptions.HopLimit()
+	if err != nil {
+		return fmt.Errorf("failed to get the hop limit: %w", err)
+	}
+	if err := tcpOptions.SetHopLimit(hopLimit); err != nil {
+		return fmt.Errorf("failed to set the hop limit to %d: %w", hopLimit, err)
+	}
+
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var spliceErr error
+	remaining := len(data)
+	err = rawConn.Write(func(fd uintptr) bool {
+		for remaining > 0 {
+			n, err := unix.Splice(pipe[0], nil, int(fd), nil, remaining, unix.SPLICE_F_NONBLOCK)
+			if errors.Is(err, unix.EAGAIN) {
+				// Wait for the socket to be writable.
+				return false
+			}
+			if err != nil {
+				spliceErr = fmt.Errorf("failed to splice the decoy: %w", err)
+				return true
+			}
+			remaining -= int(n)
+		}
+		return true
+	})
+	if err == nil {
+		err = spliceErr
+	}
+	if err == nil {
+		waitSent(rawConn)
+	}
+
+	// Replace the decoy with the real data for the re-transmissions.
+	copy(mem, data)
+	if restoreErr := tcpOptions.SetHopLimit(defaultHopLimit); restoreErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to set the hop limit %d: %w", defaultHopLimit, restoreErr))
+	}
+	return err
+}
+
+// waitSent waits until the kernel has sent all the data in the socket buffer, for at most maxSendWait.
+func waitSent(rawConn interface {
+	Control(f func(fd uintptr)) error
+}) {
+	deadline := time.Now().Add(maxSendWait)
+	for time.Now().Before(deadline) {
+		var info *unix.TCPInfo
+		var err error
+		rawConn.Control(func(fd uintptr) {
+			info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+		})
+		if err != nil || info.Notsent_bytes == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tamper provides transports that inject decoy packets into the TCP stream, so that middleboxes that inspect
+// the traffic get out of sync with the actual connection.
+package tamper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/Jigsaw-Code/outline-sdk/x/sockopt"
+)
+
+type fakeDialer struct {
+	dialer   transport.StreamDialer
+	fake     []byte
+	hopLimit int
+}
+
+var _ transport.StreamDialer = (*fakeDialer)(nil)
+var _ transport.ConnectFailureReporter = (*fakeDialer)(nil)
+
+// NewFakeStreamDialer creates a [transport.StreamDialer] that sends a decoy before the real data.
+// It works like this:
+// * On the first Write, the first len(fakePayload) bytes are replaced by fakePayload, and sent with the hop limit
+// (TTL for IPv4) set to hopLimit.
+// * The decoy packet is seen by the network filter, but is dropped before it reaches the server.
+// * The hop limit is restored, and the rest of the data is sent normally.
+// * The server notices the lost segment and requests its re-transmission. The kernel re-transmits it with the
+// real data, so the server receives the real stream, while the network filter has seen the decoy.
+//
+// Typically, fakePayload is a TLS Client Hello for an allowed server name. Pick a hopLimit that is large enough for
+// the packet to reach the filter, but small enough to not reach the server.
+//
+// Replacing the data of a segment after it was sent requires control over the socket buffer that is only available
+// on Linux, where it's done with vmsplice(2) and splice(2). On other platforms, NewFakeStreamDialer returns an error
+// that matches [errors.ErrUnsupported]. The base dialer must return [*net.TCPConn] connections.
+func NewFakeStreamDialer(dialer transport.StreamDialer, fakePayload []byte, hopLimit int) (transport.StreamDialer, error) {
+	if !fakeSupported {
+		return nil, fmt.Errorf("tamper: fake packets are %w on %v", errors.ErrUnsupported, runtime.GOOS)
+	}
+	if dialer == nil {
+		return nil, errors.New("argument dialer must not be nil")
+	}
+	if len(fakePayload) == 0 {
+		return nil, errors.New("argument fakePayload must not be empty")
+	}
+	if len(fakePayload) > maxFakeLen {
+		return nil, fmt.Errorf("fakePayload length = %v is over %v bytes", len(fakePayload), maxFakeLen)
+	}
+	if hopLimit < 1 || hopLimit > 255 {
+		return nil, fmt.Errorf("hop limit must be between 1 and 255, got %d", hopLimit)
+	}
+	return &fakeDialer{dialer: dialer, fake: append([]byte{}, fakePayload...), hopLimit: hopLimit}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+func (d *fakeDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	tcpInnerConn, ok := transport.AsTCPConn(innerConn)
+	if !ok {
+		innerConn.Close()
+		return nil, fmt.Errorf("tamper strategy: expected base dialer to return TCPConn")
+	}
+	tcpOptions, err := sockopt.NewTCPOptions(tcpInnerConn)
+	if err != nil {
+		innerConn.Close()
+		return nil, err
+	}
+	w := &fakeWriter{conn: tcpInnerConn, tcpOptions: tcpOptions, fake: d.fake, hopLimit: d.hopLimit}
+	return transport.WrapConn(innerConn, innerConn, w), nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *fakeDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+// fakeWriter is an [io.Writer] that sends the decoy on the first write.
+type fakeWriter struct {
+	conn       *net.TCPConn
+	tcpOptions sockopt.TCPOptions
+	fake       []byte
+	hopLimit   int
+	done       bool
+}
+
+var _ io.Writer = (*fakeWriter)(nil)
+
+func (w *fakeWriter) Write(data []byte) (int, error) {
+	if w.done || len(data) == 0 {
+		return w.conn.Write(data)
+	}
+	w.done = true
+	n := len(w.fake)
+	if n > len(data) {
+		n = len(data)
+	}
+	if err := writeWithFake(w.conn, w.tcpOptions, w.fake[:n], data[:n], w.hopLimit); err != nil {
+		return 0, err
+	}
+	m, err := w.conn.Write(data[n:])
+	return n + m, err
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tamper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFakeStreamDialer_InvalidArgs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		_, err := NewFakeStreamDialer(&transport.TCPDialer{}, []byte("fake"), 3)
+		require.ErrorIs(t, err, errors.ErrUnsupported)
+		return
+	}
+	_, err := NewFakeStreamDialer(nil, []byte("fake"), 3)
+	require.Error(t, err)
+	_, err = NewFakeStreamDialer(&transport.TCPDialer{}, nil, 3)
+	require.Error(t, err)
+	_, err = NewFakeStreamDialer(&transport.TCPDialer{}, []byte("fake"), 0)
+	require.Error(t, err)
+	_, err = NewFakeStreamDialer(&transport.TCPDialer{}, []byte("fake"), 256)
+	require.Error(t, err)
+}
+
+// On loopback, the decoy is not dropped. The receive queue references the same memory as the send queue, so the
+// server gets either the decoy or the real data in place of the first bytes, depending on when it reads them.
+func TestFakeStreamDialer_Loopback(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fake packets are only supported on Linux")
+	}
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer listener.Close()
+	receivedCh := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		received, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		receivedCh <- received
+	}()
+
+	dialer, err := NewFakeStreamDialer(&transport.TCPDialer{}, []byte("FAKE"), 3)
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	n, err := conn.Write([]byte("real data"))
+	require.NoError(t, err)
+	require.Equal(t, 9, n)
+	n, err = conn.Write([]byte(", more"))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	require.NoError(t, conn.CloseWrite())
+	received := string(<-receivedCh)
+	require.Contains(t, []string{"FAKE data, more", "real data, more"}, received)
+	conn.Close()
+}
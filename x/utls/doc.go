@@ -0,0 +1,28 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package utls provides a TLS transport that mimics the Client Hello of popular browsers, using [uTLS].
+
+Go's Client Hello is easy to fingerprint with techniques like JA3 and JA4. This transport sends a Client Hello
+that looks like the one from a browser, so the traffic blends in with regular browser traffic.
+Like [github.com/Jigsaw-Code/outline-sdk/transport/tls], it lets you override the SNI and validate the
+certificate against a different name, which is useful for domain fronting.
+
+Note that the browser Client Hello advertises the browser's ALPN protocols, typically h2 and http/1.1,
+so check the negotiated protocol if you use the connection for HTTP.
+
+[uTLS]: https://github.com/refraction-networking/utls
+*/
+package utls
@@ -0,0 +1,216 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	utls "github.com/refraction-networking/utls"
+)
+
+// profiles maps the supported profile names to the uTLS Client Hello they mimic.
+var profiles = map[string]utls.ClientHelloID{
+	"chrome":      utls.HelloChrome_Auto,
+	"chrome_102":  utls.HelloChrome_102,
+	"chrome_106":  utls.HelloChrome_106_Shuffle,
+	"firefox":     utls.HelloFirefox_Auto,
+	"firefox_102": utls.HelloFirefox_102,
+	"firefox_105": utls.HelloFirefox_105,
+	"safari":      utls.HelloSafari_Auto,
+	"safari_16":   utls.HelloSafari_16_0,
+	"ios":         utls.HelloIOS_Auto,
+	"ios_14":      utls.HelloIOS_14,
+	"edge":        utls.HelloEdge_Auto,
+	"edge_85":     utls.HelloEdge_85,
+	"randomized":  utls.HelloRandomized,
+}
+
+// Profiles returns the names of the supported Client Hello profiles, in alphabetical order.
+// The names without a version mimic the latest version supported.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupProfile(profile string) (utls.ClientHelloID, error) {
+	helloID, ok := profiles[strings.ToLower(profile)]
+	if !ok {
+		return utls.ClientHelloID{}, fmt.Errorf("unsupported profile %q. Supported profiles: %v", profile, strings.Join(Profiles(), ", "))
+	}
+	return helloID, nil
+}
+
+// HandshakeError is returned when the TLS handshake fails, to distinguish it from failures to establish
+// the underlying connection.
+type HandshakeError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *HandshakeError) Error() string {
+	return "TLS handshake failed: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying handshake error.
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// StreamDialer is a [transport.StreamDialer] that uses TLS with a browser Client Hello to wrap the inner StreamDialer.
+type StreamDialer struct {
+	dialer  transport.StreamDialer
+	helloID utls.ClientHelloID
+	options []ClientOption
+}
+
+var _ transport.StreamDialer = (*StreamDialer)(nil)
+var _ transport.ConnectFailureReporter = (*StreamDialer)(nil)
+
+// NewStreamDialer creates a [StreamDialer] that wraps the connections from the baseDialer with TLS, sending the
+// Client Hello of the given profile. See [Profiles] for the supported profile names.
+func NewStreamDialer(baseDialer transport.StreamDialer, profile string, options ...ClientOption) (*StreamDialer, error) {
+	if baseDialer == nil {
+		return nil, errors.New("base dialer must not be nil")
+	}
+	helloID, err := lookupProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamDialer{baseDialer, helloID, options}, nil
+}
+
+// DialStream implements [transport.StreamDialer].DialStream.
+// If the TLS handshake fails, the error is a [*HandshakeError].
+func (d *StreamDialer) DialStream(ctx context.Context, remoteAddr string) (transport.StreamConn, error) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	innerConn, err := d.dialer.DialStream(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := wrapConn(ctx, innerConn, host, d.helloID, d.options...)
+	if err != nil {
+		innerConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ReportsConnectFailure implements [transport.ConnectFailureReporter] with the answer of the base dialer.
+func (d *StreamDialer) ReportsConnectFailure() bool {
+	return transport.ReportsConnectFailure(d.dialer)
+}
+
+// ClientConfig encodes the parameters for a uTLS client connection.
+type ClientConfig struct {
+	// The host name for the Server Name Indication (SNI). If empty, the SNI extension is not sent.
+	ServerName string
+	// The hostname to use for certificate validation.
+	CertificateName string
+}
+
+// ClientOption allows configuring the parameters to be used for a client uTLS connection.
+type ClientOption func(serverName string, config *ClientConfig)
+
+// WithSNI sets the host name for Server Name Indication (SNI).
+// If absent, defaults to the dialed hostname.
+// Note that this only changes what is sent in the SNI, not what host is used for certificate verification.
+func WithSNI(hostName string) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.ServerName = hostName
+	}
+}
+
+// WithCertificateName sets the hostname to be used for the certificate verification.
+// If absent, defaults to the dialed hostname.
+func WithCertificateName(hostname string) ClientOption {
+	return func(_ string, config *ClientConfig) {
+		config.CertificateName = hostname
+	}
+}
+
+// streamConn wraps a [utls.UConn] to provide a [transport.StreamConn] interface.
+type streamConn struct {
+	*utls.UConn
+	innerConn transport.StreamConn
+}
+
+var _ transport.StreamConn = (*streamConn)(nil)
+
+func (c streamConn) CloseWrite() error {
+	tlsErr := c.UConn.CloseWrite()
+	return errors.Join(tlsErr, c.innerConn.CloseWrite())
+}
+
+func (c streamConn) CloseRead() error {
+	return c.innerConn.CloseRead()
+}
+
+// WrapConn wraps a [transport.StreamConn] in a TLS connection that sends the Client Hello of the given profile.
+// If the TLS handshake fails, the error is a [*HandshakeError].
+func WrapConn(ctx context.Context, conn transport.StreamConn, serverName string, profile string, options ...ClientOption) (transport.StreamConn, error) {
+	helloID, err := lookupProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	return wrapConn(ctx, conn, serverName, helloID, options...)
+}
+
+func wrapConn(ctx context.Context, conn transport.StreamConn, serverName string, helloID utls.ClientHelloID, options ...ClientOption) (transport.StreamConn, error) {
+	cfg := ClientConfig{ServerName: serverName, CertificateName: serverName}
+	normName := strings.ToLower(serverName)
+	for _, option := range options {
+		option(normName, &cfg)
+	}
+	uconn := utls.UClient(conn, &utls.Config{
+		ServerName: cfg.ServerName,
+		// Set InsecureSkipVerify to skip the default validation we are
+		// replacing. This will not disable VerifyConnection.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs utls.ConnectionState) error {
+			opts := x509.VerifyOptions{
+				DNSName:       cfg.CertificateName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}, helloID)
+	if cfg.ServerName == "" {
+		if err := uconn.RemoveSNIExtension(); err != nil {
+			return nil, err
+		}
+	}
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		return nil, &HandshakeError{err}
+	}
+	return streamConn{uconn, conn}, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// startTLSServer starts a TLS server with a self-signed certificate for certName.
+// It sends the SNI of each Client Hello it receives to the returned channel.
+func startTLSServer(t *testing.T, certName string) (net.Listener, <-chan string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: certName},
+		DNSNames:     []string{certName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	snis := make(chan string, 1)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			snis <- hello.ServerName
+			return nil, nil
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	return listener, snis
+}
+
+func TestProfiles(t *testing.T) {
+	for _, profile := range Profiles() {
+		_, err := NewStreamDialer(&transport.TCPDialer{}, profile)
+		require.NoError(t, err, profile)
+	}
+	_, err := NewStreamDialer(&transport.TCPDialer{}, "Chrome")
+	require.NoError(t, err)
+	_, err = NewStreamDialer(&transport.TCPDialer{}, "netscape")
+	require.Error(t, err)
+}
+
+func TestNewStreamDialer_NilDialer(t *testing.T) {
+	_, err := NewStreamDialer(nil, "chrome")
+	require.Error(t, err)
+}
+
+func TestDialStream_HandshakeError(t *testing.T) {
+	listener, snis := startTLSServer(t, "host.example")
+	dialer, err := NewStreamDialer(&transport.TCPDialer{}, "chrome", WithSNI("decoy.example"), WithCertificateName("host.example"))
+	require.NoError(t, err)
+
+	_, err = dialer.DialStream(context.Background(), listener.Addr().String())
+	// The certificate is self-signed, so validation must fail after the server sees the SNI override.
+	var handshakeErr *HandshakeError
+	require.ErrorAs(t, err, &handshakeErr)
+	var authorityErr x509.UnknownAuthorityError
+	require.ErrorAs(t, err, &authorityErr)
+	require.Equal(t, "decoy.example", <-snis)
+}
+
+func TestDialStream_NoSNI(t *testing.T) {
+	listener, snis := startTLSServer(t, "host.example")
+	dialer, err := NewStreamDialer(&transport.TCPDialer{}, "firefox", WithSNI(""))
+	require.NoError(t, err)
+
+	_, err = dialer.DialStream(context.Background(), listener.Addr().String())
+	var handshakeErr *HandshakeError
+	require.ErrorAs(t, err, &handshakeErr)
+	require.Equal(t, "", <-snis)
+}
+
+func TestDialStream_DialError(t *testing.T) {
+	dialErr := errors.New("dial failed")
+	baseDialer := transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		return nil, dialErr
+	})
+	dialer, err := NewStreamDialer(baseDialer, "chrome")
+	require.NoError(t, err)
+
+	_, err = dialer.DialStream(context.Background(), "example.com:443")
+	require.ErrorIs(t, err, dialErr)
+	var handshakeErr *HandshakeError
+	require.False(t, errors.As(err, &handshakeErr))
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"golang.org/x/net/websocket"
+)
+
+type dialerConfig struct {
+	headers      http.Header
+	subprotocols []string
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+}
+
+// DialerOption configures the dialers created by [NewStreamDialer] and [NewPacketDialer].
+type DialerOption func(config *dialerConfig)
+
+// WithHeaders sets extra headers to send in the WebSocket handshake request.
+// A "Host" header replaces the host of the request, which you can use for domain fronting.
+func WithHeaders(headers http.Header) DialerOption {
+	return func(config *dialerConfig) {
+		config.headers = headers
+	}
+}
+
+// WithSubprotocols sets the subprotocols to offer to the server, in order of preference.
+func WithSubprotocols(subprotocols ...string) DialerOption {
+	return func(config *dialerConfig) {
+		config.subprotocols = subprotocols
+	}
+}
+
+// WithKeepAlive makes the connections send ping frames, as per [NewKeepAliveConn].
+func WithKeepAlive(pingInterval time.Duration, pongTimeout time.Duration) DialerOption {
+	return func(config *dialerConfig) {
+		config.pingInterval = pingInterval
+		config.pongTimeout = pongTimeout
+	}
+}
+
+func newDialerConfig(sd transport.StreamDialer, options []DialerOption) (*dialerConfig, error) {
+	if sd == nil {
+		return nil, errors.New("argument sd must not be nil")
+	}
+	config := &dialerConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if config.pingInterval < 0 {
+		return nil, errors.New("ping interval must not be negative")
+	}
+	return config, nil
+}
+
+// NewStreamDialer creates a [transport.StreamDialer] that sends each stream over a new WebSocket connection to
+// the given path of the dialed address. The WebSocket connections run over the connections from sd, so you can
+// use a TLS dialer for wss:// endpoints.
+func NewStreamDialer(sd transport.StreamDialer, path string, options ...DialerOption) (transport.StreamDialer, error) {
+	config, err := newDialerConfig(sd, options)
+	if err != nil {
+		return nil, err
+	}
+	return transport.FuncStreamDialer(func(ctx context.Context, addr string) (transport.StreamConn, error) {
+		wsConn, err := dial(ctx, sd, addr, path, config)
+		if err != nil {
+			return nil, err
+		}
+		return &streamConn{wsConn}, nil
+	}), nil
+}
+
+// NewPacketDialer creates a [transport.PacketDialer] that sends each packet flow over a new WebSocket connection to
+// the given path of the dialed address, with one packet per frame. The WebSocket connections run over the
+// connections from sd.
+func NewPacketDialer(sd transport.StreamDialer, path string, options ...DialerOption) (transport.PacketDialer, error) {
+	config, err := newDialerConfig(sd, options)
+	if err != nil {
+		return nil, err
+	}
+	return transport.FuncPacketDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return dial(ctx, sd, addr, path, config)
+	}), nil
+}
+
+// dial performs the WebSocket client handshake over a new connection from sd to addr.
+func dial(ctx context.Context, sd transport.StreamDialer, addr string, path string, config *dialerConfig) (net.Conn, error) {
+	host := addr
+	if hostHeader := config.headers.Get("Host"); hostHeader != "" {
+		host = hostHeader
+	}
+	wsURL := url.URL{Scheme: "ws", Host: host, Path: path}
+	origin := url.URL{Scheme: "http", Host: host}
+	wsCfg, err := websocket.NewConfig(wsURL.String(), origin.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create websocket config: %w", err)
+	}
+	for key, values := range config.headers {
+		for _, value := range values {
+			wsCfg.Header.Add(key, value)
+		}
+	}
+	wsCfg.Protocol = config.subprotocols
+
+	baseConn, err := sd.DialStream(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to websocket endpoint: %w", err)
+	}
+	// The handshake doesn't take a context, so we bound it with the context deadline.
+	if deadline, ok := ctx.Deadline(); ok {
+		baseConn.SetDeadline(deadline)
+	}
+	wsConn, err := websocket.NewClient(wsCfg, baseConn)
+	if err != nil {
+		baseConn.Close()
+		return nil, fmt.Errorf("failed to create websocket client: %w", err)
+	}
+	baseConn.SetDeadline(time.Time{})
+	// The payloads are arbitrary bytes, not UTF-8 text, as expected by servers like v2ray-plugin.
+	wsConn.PayloadType = websocket.BinaryFrame
+	if config.pingInterval == 0 {
+		return wsConn, nil
+	}
+	keepAliveConn, err := NewKeepAliveConn(wsConn, config.pingInterval, config.pongTimeout)
+	if err != nil {
+		wsConn.Close()
+		return nil, err
+	}
+	return keepAliveConn, nil
+}
+
+// streamConn converts a WebSocket connection to a [transport.StreamConn].
+type streamConn struct {
+	net.Conn
+}
+
+var _ transport.StreamConn = (*streamConn)(nil)
+
+func (c *streamConn) CloseRead() error {
+	// Nothing to do.
+	return nil
+}
+
+// closeStatusNormal is the status code of a normal closure, as per RFC 6455 section 7.4.1.
+const closeStatusNormal = 1000
+
+// CloseWrite sends a close frame, which ends the peer reads with [io.EOF]. WebSockets don't support half-close,
+// but the connection stays open, so we can still read what the peer sends before it closes the connection too.
+// See https://datatracker.ietf.org/doc/html/rfc6455#section-5.5.1.
+func (c *streamConn) CloseWrite() error {
+	// Both *websocket.Conn and *KeepAliveConn have WriteClose.
+	if wc, ok := c.Conn.(interface{ WriteClose(status int) error }); ok {
+		return wc.WriteClose(closeStatusNormal)
+	}
+	return c.Close()
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Jigsaw-Code/outline-sdk/transport"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestStreamDialer(t *testing.T) {
+	var gotHost, gotHeader string
+	var gotProtocols []string
+	mux := http.NewServeMux()
+	mux.Handle("/tcp", websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			gotHost = r.Host
+			gotHeader = r.Header.Get("X-Test")
+			gotProtocols = config.Protocol
+			// Pick the second subprotocol.
+			config.Protocol = config.Protocol[1:2]
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			io.Copy(ws, ws)
+		},
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	dialer, err := NewStreamDialer(&transport.TCPDialer{}, "/tcp",
+		WithHeaders(http.Header{"Host": []string{"front.example"}, "X-Test": []string{"value"}}),
+		WithSubprotocols("a", "b"))
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), serverURL.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, "front.example", gotHost)
+	require.Equal(t, "value", gotHeader)
+	require.Equal(t, []string{"a", "b"}, gotProtocols)
+	require.Equal(t, []string{"b"}, conn.(*streamConn).Conn.(*websocket.Conn).Config().Protocol)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+	require.NoError(t, conn.CloseWrite())
+}
+
+func TestStreamDialer_BinaryFrames(t *testing.T) {
+	payloadTypes := make(chan byte, 1)
+	// A codec that reports the frame type.
+	frameTypeCodec := websocket.Codec{Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		payloadTypes <- payloadType
+		return nil
+	}}
+	mux := http.NewServeMux()
+	mux.Handle("/tcp", websocket.Handler(func(ws *websocket.Conn) {
+		frameTypeCodec.Receive(ws, nil)
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	dialer, err := NewStreamDialer(&transport.TCPDialer{}, "/tcp")
+	require.NoError(t, err)
+	conn, err := dialer.DialStream(context.Background(), serverURL.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{0xff, 0xfe})
+	require.NoError(t, err)
+	require.Equal(t, byte(websocket.BinaryFrame), <-payloadTypes)
+}
+
+func TestStreamDialer_HalfClose(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/tcp", websocket.Handler(func(ws *websocket.Conn) {
+		// The close frame from CloseWrite ends the request, and we can still write the response.
+		request, err := io.ReadAll(ws)
+		require.NoError(t, err)
+		ws.Write(append([]byte("response to "), request...))
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	for _, options := range [][]DialerOption{nil, {WithKeepAlive(time.Minute, 0)}} {
+		dialer, err := NewStreamDialer(&transport.TCPDialer{}, "/tcp", options...)
+		require.NoError(t, err)
+		conn, err := dialer.DialStream(context.Background(), serverURL.Host)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("request"))
+		require.NoError(t, err)
+		require.NoError(t, conn.CloseWrite())
+		response, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		require.Equal(t, "response to request", string(response))
+	}
+}
+
+func TestPacketDialer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/udp", websocket.Handler(func(ws *websocket.Conn) {
+		var packet []byte
+		for websocket.Message.Receive(ws, &packet) == nil {
+			websocket.Message.Send(ws, packet)
+		}
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	dialer, err := NewPacketDialer(&transport.TCPDialer{}, "/udp")
+	require.NoError(t, err)
+	conn, err := dialer.DialPacket(context.Background(), serverURL.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, packet := range []string{"first", "second"} {
+		_, err = conn.Write([]byte(packet))
+		require.NoError(t, err)
+		buf := make([]byte, 100)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, packet, string(buf[:n]))
+	}
+}
+
+func TestNewStreamDialerNil(t *testing.T) {
+	_, err := NewStreamDialer(nil, "/tcp")
+	require.Error(t, err)
+	_, err = NewPacketDialer(nil, "/udp")
+	require.Error(t, err)
+}
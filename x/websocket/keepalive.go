@@ -0,0 +1,163 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package websocket provides WebSocket client transports and utilities, built on [golang.org/x/net/websocket].
+package websocket
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// dataFrame is the part of a WebSocket frame reader used to read the frame payload.
+type dataFrame interface {
+	io.Reader
+	TrailerReader() io.Reader
+}
+
+// KeepAliveConn is a [websocket.Conn] that sends ping frames periodically, so intermediaries don't drop idle
+// connections, and that fails reads if no frame is received in time, to detect dead peers.
+//
+// Reads fail if no frame is received within the ping interval plus the pong timeout. Any frame received, including
+// the pong frames in response to the pings, extends the read deadline, so [KeepAliveConn.SetReadDeadline] must not
+// be used. Dead peers are only detected while a read is in progress.
+// As with [websocket.Conn], ping frames from the peer are answered with pong frames, unsolicited pong frames are
+// ignored, and a close frame ends the reads with [io.EOF].
+type KeepAliveConn struct {
+	*websocket.Conn
+	readTimeout time.Duration
+
+	// Protects frame, and serializes reads.
+	readMu sync.Mutex
+	frame  dataFrame
+
+	// Serializes writes, since pings change the payload type.
+	writeMu   sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewKeepAliveConn wraps the conn to send a ping frame every pingInterval, and to fail the reads if no frame is
+// received within pongTimeout after a ping is due. If pongTimeout is not positive, it defaults to pingInterval.
+func NewKeepAliveConn(conn *websocket.Conn, pingInterval time.Duration, pongTimeout time.Duration) (*KeepAliveConn, error) {
+	if conn == nil {
+		return nil, errors.New("argument conn must not be nil")
+	}
+	if pingInterval <= 0 {
+		return nil, errors.New("ping interval must be positive")
+	}
+	if pongTimeout <= 0 {
+		pongTimeout = pingInterval
+	}
+	c := &KeepAliveConn{
+		Conn:        conn,
+		readTimeout: pingInterval + pongTimeout,
+		done:        make(chan struct{}),
+	}
+	go c.pingLoop(pingInterval)
+	return c, nil
+}
+
+func (c *KeepAliveConn) extendReadDeadline() {
+	c.Conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+}
+
+func (c *KeepAliveConn) pingLoop(pingInterval time.Duration) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.writePing(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *KeepAliveConn) writePing() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	payloadType := c.Conn.PayloadType
+	c.Conn.PayloadType = websocket.PingFrame
+	defer func() { c.Conn.PayloadType = payloadType }()
+	_, err := c.Conn.Write(nil)
+	return err
+}
+
+// Read implements the [io.Reader] interface. It reads the payload of the data frames, handling the control frames.
+func (c *KeepAliveConn) Read(b []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	for {
+		if c.frame == nil {
+			// Wait for the next frame for at most the read timeout. Since we send pings, a live peer sends
+			// at least the pong frames in that time.
+			c.extendReadDeadline()
+			frame, err := c.Conn.NewFrameReader()
+			if err != nil {
+				return 0, err
+			}
+			// Replies to pings, ignores pongs and returns io.EOF on close frames.
+			next, err := c.Conn.HandleFrame(frame)
+			if err != nil {
+				return 0, err
+			}
+			if next == nil {
+				// Control frame.
+				continue
+			}
+			c.frame = next
+		}
+		n, err := c.frame.Read(b)
+		if err == io.EOF {
+			if trailer := c.frame.TrailerReader(); trailer != nil {
+				io.Copy(io.Discard, trailer)
+			}
+			c.frame = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Write implements the [io.Writer] interface. It writes the data as a frame of the connection's payload type.
+func (c *KeepAliveConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.Write(b)
+}
+
+// WriteClose sends a close frame with the given status code, without closing the connection. It's serialized with the
+// other writes.
+func (c *KeepAliveConn) WriteClose(status int) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteClose(status)
+}
+
+// Close stops the pings and closes the connection.
+func (c *KeepAliveConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return c.Conn.Close()
+}
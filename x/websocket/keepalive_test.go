@@ -0,0 +1,124 @@
+// Copyright 2025 The Outline Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func dialTestServer(t *testing.T, handler websocket.Handler) *websocket.Conn {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, err := websocket.Dial(wsURL, "", server.URL)
+	require.NoError(t, err)
+	return conn
+}
+
+func TestKeepAliveConnIdle(t *testing.T) {
+	// The echo server answers the pings while reading.
+	conn := dialTestServer(t, func(ws *websocket.Conn) {
+		io.Copy(ws, ws)
+	})
+	keepAlive, err := NewKeepAliveConn(conn, 10*time.Millisecond, 20*time.Millisecond)
+	require.NoError(t, err)
+	defer keepAlive.Close()
+
+	readResult := make(chan error, 1)
+	buf := make([]byte, 10)
+	go func() {
+		n, err := keepAlive.Read(buf)
+		if err == nil && string(buf[:n]) != "hello" {
+			err = io.ErrUnexpectedEOF
+		}
+		readResult <- err
+	}()
+	// Stay idle for several times the timeout, so the pongs must keep the connection alive.
+	time.Sleep(200 * time.Millisecond)
+	_, err = keepAlive.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, <-readResult)
+}
+
+func TestKeepAliveConnDeadPeer(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	// The server never reads, so it doesn't answer the pings.
+	conn := dialTestServer(t, func(ws *websocket.Conn) {
+		<-stop
+	})
+	keepAlive, err := NewKeepAliveConn(conn, 10*time.Millisecond, 20*time.Millisecond)
+	require.NoError(t, err)
+	defer keepAlive.Close()
+
+	start := time.Now()
+	_, err = keepAlive.Read(make([]byte, 10))
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	require.True(t, netErr.Timeout())
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestKeepAliveConnClose(t *testing.T) {
+	conn := dialTestServer(t, func(ws *websocket.Conn) {
+		// Sends a close frame.
+		ws.Close()
+	})
+	keepAlive, err := NewKeepAliveConn(conn, time.Minute, 0)
+	require.NoError(t, err)
+	defer keepAlive.Close()
+
+	_, err = keepAlive.Read(make([]byte, 10))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestKeepAliveConnWriteClose(t *testing.T) {
+	conn := dialTestServer(t, func(ws *websocket.Conn) {
+		// The close frame ends the request, and we can still write the response.
+		request, err := io.ReadAll(ws)
+		if err != nil {
+			return
+		}
+		ws.Write(append([]byte("response to "), request...))
+	})
+	// Ping often, so the close frame is written while the pings are in progress.
+	keepAlive, err := NewKeepAliveConn(conn, time.Millisecond, time.Minute)
+	require.NoError(t, err)
+	defer keepAlive.Close()
+
+	_, err = keepAlive.Write([]byte("request"))
+	require.NoError(t, err)
+	require.NoError(t, keepAlive.WriteClose(1000))
+	response, err := io.ReadAll(keepAlive)
+	require.NoError(t, err)
+	require.Equal(t, "response to request", string(response))
+}
+
+func TestNewKeepAliveConnInvalid(t *testing.T) {
+	_, err := NewKeepAliveConn(nil, time.Second, time.Second)
+	require.Error(t, err)
+	conn := dialTestServer(t, func(ws *websocket.Conn) {})
+	defer conn.Close()
+	_, err = NewKeepAliveConn(conn, 0, time.Second)
+	require.Error(t, err)
+}